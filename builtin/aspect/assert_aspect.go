@@ -0,0 +1,341 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package aspect
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sync"
+
+	"github.com/bittoy/rule/types"
+	"github.com/bittoy/rule/utils/cast"
+	"github.com/bittoy/rule/utils/maps"
+)
+
+var (
+	// Compile-time check AssertAspect implements types.CompletedAspect.
+	_ types.CompletedAspect = (*AssertAspect)(nil)
+	// Compile-time check NodeAssertAspect implements types.NodeAfterAspect.
+	_ types.NodeAfterAspect = (*NodeAssertAspect)(nil)
+)
+
+// Expectation is a value expected during a run, checked by AssertAspect.
+// With NodeId set it checks the node: Key is read from the node output, see types.RuleMsg.GetNodeOutput,
+// and Relation, when set, is compared with the relation the node routed to. Without NodeId, Key is
+// read from the chain output. Keys may be nested paths separated by dots, e.g. result.score.
+//
+// Expectation 是执行期间期望的值，由 AssertAspect 检查。设置 NodeId 时检查该节点：从节点输出中读取 Key，
+// 参见 types.RuleMsg.GetNodeOutput，设置 Relation 时与节点路由到的关系比较。未设置 NodeId 时从规则链输出中读取 Key。
+// 键可以是以 . 分隔的嵌套路径，例如 result.score。
+type Expectation struct {
+	// NodeId is the id of the checked node, empty for the chain output  被检查的节点 id，为空时检查规则链输出
+	NodeId string `json:"nodeId,omitempty"`
+	// Key is the checked output key, empty to only check Relation  被检查的输出键，为空时只检查 Relation
+	Key string `json:"key,omitempty"`
+	// Value is the expected value, numbers of any type are equal when their values are  期望的值，不同类型的数值按值比较
+	Value any `json:"value,omitempty"`
+	// Relation is the expected relation of the node  期望的节点关系
+	Relation string `json:"relation,omitempty"`
+}
+
+// AssertionFailure is an expectation a run did not meet.
+// AssertionFailure 是一次执行未满足的期望。
+type AssertionFailure struct {
+	// MsgId is the id of the message  消息 id
+	MsgId string
+	// Expectation is the unmet expectation  未满足的期望
+	Expectation Expectation
+	// Actual is the actual value, or the actual relation for a relation expectation  实际的值，或关系期望的实际关系
+	Actual any
+	// Reason describes the failure  失败原因
+	Reason string
+}
+
+// Error returns the description of the failure.
+// Error 返回失败的描述。
+func (f AssertionFailure) Error() string {
+	return fmt.Sprintf("msg %s: %s", f.MsgId, f.Reason)
+}
+
+// assertCheckedKey is the message attachment key of the node expectations already checked
+type assertCheckedKey struct{}
+
+// assertState holds the failures shared by the aspect instances created by New
+type assertState struct {
+	mu       sync.Mutex
+	failures []AssertionFailure
+}
+
+// AssertAspect checks the expectations of declarative chain tests during a run, e.g. "after node s5,
+// score should be 80", without a custom harness. It is a chain aspect checking the chain output
+// expectations when the chain completes; the node expectations are checked after the node runs by its
+// node aspect, see NodeAspect, and fail when the chain completes if the node did not run. The failures are
+// collected for all the engines created with the aspects, see Failures and Err. With FailFast the first
+// node failure of a message also fails its run.
+//
+// AssertAspect 在执行期间检查声明式规则链测试的期望，例如"节点 s5 之后 score 应为 80"，无需自定义测试工具。
+// 它是规则链切面，在规则链完成时检查规则链输出的期望；节点期望由其节点切面在节点执行后检查，参见 NodeAspect，
+// 节点未执行时在规则链完成时判定为失败。使用这些切面创建的所有引擎的失败都会被收集，参见 Failures 和 Err。
+// 设置 FailFast 时，消息的第一个节点失败也会使其执行失败。
+//
+// Usage:
+// 使用方法：
+//
+//	asserts := NewAssertAspect(
+//		Expectation{NodeId: "s5", Key: "score", Value: 80},
+//		Expectation{NodeId: "s6", Relation: "high"},
+//		Expectation{Key: "action", Value: "reject"},
+//	)
+//	chainEngine, err := engine.NewChainEngine(def, engine.WithAspects(asserts, asserts.NodeAspect()))
+//	...
+//	assert.Nil(t, asserts.Err())
+type AssertAspect struct {
+	// Expectations are the checked expectations  检查的期望
+	Expectations []Expectation
+	// FailFast fails the run of a message at its first failure  在消息的第一个失败时使其执行失败
+	FailFast bool
+
+	// state is shared with the instances created by New
+	state *assertState
+}
+
+// NewAssertAspect creates a new assert aspect checking the expectations.
+//
+// NewAssertAspect 创建检查给定期望的断言切面。
+func NewAssertAspect(expectations ...Expectation) *AssertAspect {
+	return &AssertAspect{Expectations: expectations, state: &assertState{}}
+}
+
+// Order returns the execution order of this aspect. Lower values execute earlier.
+// AssertAspect has order 950, so it checks the values left by the other aspects.
+//
+// Order 返回此切面的执行顺序。值越低，执行越早。
+// AssertAspect 的顺序为 950，因此检查的是其他切面处理后的值。
+func (aspect *AssertAspect) Order() int {
+	return 950
+}
+
+// New creates a new instance of the assert aspect with the same expectations, sharing the collected failures.
+//
+// New 创建具有相同期望的断言切面新实例，共享收集的失败。
+func (aspect *AssertAspect) New() types.Aspect {
+	if aspect.state == nil {
+		aspect.state = &assertState{}
+	}
+	return &AssertAspect{
+		Expectations: slices.Clone(aspect.Expectations),
+		FailFast:     aspect.FailFast,
+		state:        aspect.state,
+	}
+}
+
+// Type returns the unique identifier for this aspect type.
+//
+// Type 返回此切面类型的唯一标识符。
+func (aspect *AssertAspect) Type() string {
+	return "assert"
+}
+
+// PointCut applies the aspect to every chain.
+//
+// PointCut 应用于所有规则链。
+func (aspect *AssertAspect) PointCut(chainCtx types.ChainCtx, msg types.RuleMsg) bool {
+	return true
+}
+
+// NodeAspect returns the node aspect checking the node expectations, sharing the expectations and failures.
+//
+// NodeAspect 返回检查节点期望的节点切面，共享期望和失败。
+func (aspect *AssertAspect) NodeAspect() *NodeAssertAspect {
+	return &NodeAssertAspect{assert: aspect.New().(*AssertAspect)}
+}
+
+// afterNode checks the expectations of the node
+func (aspect *AssertAspect) afterNode(nodeCtx types.NodeCtx, msg types.RuleMsg, relationType string) (types.RuleMsg, error) {
+	checked, _ := msg.Attachment(assertCheckedKey{}).(map[int]bool)
+	var err error
+	for i, expectation := range aspect.Expectations {
+		if expectation.NodeId == "" || expectation.NodeId != nodeCtx.Id() {
+			continue
+		}
+		if checked == nil {
+			checked = map[int]bool{}
+			msg.SetAttachment(assertCheckedKey{}, checked)
+		}
+		checked[i] = true
+		if expectation.Relation != "" && expectation.Relation != relationType {
+			err = errors.Join(err, aspect.fail(msg, expectation, relationType, fmt.Sprintf("node %s routed to %s, expected %s", expectation.NodeId, relationType, expectation.Relation)))
+		}
+		if expectation.Key != "" {
+			err = errors.Join(err, aspect.check(msg, expectation, msg.GetNodeOutput(nodeCtx.Id())))
+		}
+	}
+	if aspect.FailFast {
+		return msg, err
+	}
+	return msg, nil
+}
+
+// Completed checks the chain output expectations and fails the node expectations whose node did not run.
+//
+// Completed 检查规则链输出的期望，并将节点未执行的节点期望判定为失败。
+func (aspect *AssertAspect) Completed(chainCtx types.ChainCtx, msg types.RuleMsg, err error) {
+	checked, _ := msg.Attachment(assertCheckedKey{}).(map[int]bool)
+	for i, expectation := range aspect.Expectations {
+		switch {
+		case expectation.NodeId == "":
+			_ = aspect.check(msg, expectation, msg.GetChainOutput())
+		case !checked[i]:
+			_ = aspect.fail(msg, expectation, nil, fmt.Sprintf("node %s did not run", expectation.NodeId))
+		}
+	}
+	msg.SetAttachment(assertCheckedKey{}, nil)
+}
+
+// Failures returns the failures collected so far.
+//
+// Failures 返回目前收集的失败。
+func (aspect *AssertAspect) Failures() []AssertionFailure {
+	if aspect.state == nil {
+		return nil
+	}
+	aspect.state.mu.Lock()
+	defer aspect.state.mu.Unlock()
+	return slices.Clone(aspect.state.failures)
+}
+
+// Err returns the failures collected so far joined in one error, nil when every expectation was met.
+//
+// Err 返回目前收集的所有失败合并而成的错误，所有期望都满足时返回 nil。
+func (aspect *AssertAspect) Err() error {
+	var errs []error
+	for _, failure := range aspect.Failures() {
+		errs = append(errs, failure)
+	}
+	return errors.Join(errs...)
+}
+
+// Reset clears the collected failures.
+//
+// Reset 清除收集的失败。
+func (aspect *AssertAspect) Reset() {
+	if aspect.state == nil {
+		return
+	}
+	aspect.state.mu.Lock()
+	defer aspect.state.mu.Unlock()
+	aspect.state.failures = nil
+}
+
+// check compares the value of the expectation key in output with the expected value
+func (aspect *AssertAspect) check(msg types.RuleMsg, expectation Expectation, output map[string]any) error {
+	var actual any
+	if output != nil {
+		actual = maps.Get(output, expectation.Key)
+	}
+	if assertEqual(expectation.Value, actual) {
+		return nil
+	}
+	where := "chain output"
+	if expectation.NodeId != "" {
+		where = "node " + expectation.NodeId
+	}
+	return aspect.fail(msg, expectation, actual, fmt.Sprintf("%s %s is %v, expected %v", where, expectation.Key, actual, expectation.Value))
+}
+
+// fail records a failure and returns it
+func (aspect *AssertAspect) fail(msg types.RuleMsg, expectation Expectation, actual any, reason string) error {
+	failure := AssertionFailure{MsgId: msg.Id(), Expectation: expectation, Actual: actual, Reason: reason}
+	if aspect.state != nil {
+		aspect.state.mu.Lock()
+		aspect.state.failures = append(aspect.state.failures, failure)
+		aspect.state.mu.Unlock()
+	}
+	return failure
+}
+
+// assertEqual reports whether the values are deeply equal, numbers of any type are compared by value
+func assertEqual(expected, actual any) bool {
+	if isNumber(expected) && isNumber(actual) {
+		a, err := cast.ToFloat64E(expected)
+		b, errActual := cast.ToFloat64E(actual)
+		return err == nil && errActual == nil && a == b
+	}
+	return reflect.DeepEqual(expected, actual)
+}
+
+// isNumber reports whether the value is a number or a json.Number
+func isNumber(value any) bool {
+	if number, ok := value.(json.Number); ok {
+		_, err := number.Float64()
+		return err == nil
+	}
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// NodeAssertAspect is the node aspect of an AssertAspect, checking the node expectations after the node runs.
+// Create it with AssertAspect.NodeAspect.
+//
+// NodeAssertAspect 是 AssertAspect 的节点切面，在节点执行后检查节点期望。通过 AssertAspect.NodeAspect 创建。
+type NodeAssertAspect struct {
+	// assert holds the expectations and the failures
+	assert *AssertAspect
+}
+
+// Order returns the execution order of this aspect, the same as AssertAspect.
+//
+// Order 返回此切面的执行顺序，与 AssertAspect 相同。
+func (aspect *NodeAssertAspect) Order() int {
+	return aspect.assert.Order()
+}
+
+// New creates a new instance of the node assert aspect, sharing the expectations and failures.
+//
+// New 创建节点断言切面的新实例，共享期望和失败。
+func (aspect *NodeAssertAspect) New() types.Aspect {
+	return aspect.assert.NodeAspect()
+}
+
+// Type returns the unique identifier for this aspect type.
+//
+// Type 返回此切面类型的唯一标识符。
+func (aspect *NodeAssertAspect) Type() string {
+	return "nodeAssert"
+}
+
+// PointCut applies the aspect to every node.
+//
+// PointCut 应用于所有节点。
+func (aspect *NodeAssertAspect) PointCut(nodeCtx types.NodeCtx, msg types.RuleMsg, relationType string) bool {
+	return true
+}
+
+// After checks the expectations of the node.
+//
+// After 检查节点的期望。
+func (aspect *NodeAssertAspect) After(nodeCtx types.NodeCtx, msg types.RuleMsg, relationType string) (types.RuleMsg, error) {
+	return aspect.assert.afterNode(nodeCtx, msg, relationType)
+}
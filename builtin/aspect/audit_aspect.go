@@ -0,0 +1,211 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package aspect
+
+import (
+	"hash/fnv"
+	"math"
+	"reflect"
+	"slices"
+
+	"github.com/bittoy/rule/types"
+)
+
+var (
+	// Compile-time check AuditAspect implements types.NodeBeforeAspect.
+	_ types.NodeBeforeAspect = (*AuditAspect)(nil)
+	// Compile-time check AuditAspect implements types.NodeAfterAspect.
+	_ types.NodeAfterAspect = (*AuditAspect)(nil)
+)
+
+// AuditRecord describes the changes a node made to the message input. Private variables
+// are listed with the types.PriVarsKey prefix, e.g. priVars.score.
+//
+// AuditRecord 描述节点对消息输入所做的修改。私有变量以 types.PriVarsKey 为前缀列出，例如 priVars.score。
+type AuditRecord struct {
+	// MsgId is the id of the message  消息 id
+	MsgId string
+	// NodeId is the id of the node  节点 id
+	NodeId string
+	// NodeType is the type of the node  节点类型
+	NodeType types.NodeType
+	// Relation is the relation the node routed to  节点路由到的关系
+	Relation string
+	// Added are the keys the node added  节点新增的键
+	Added []string
+	// Removed are the keys the node removed  节点删除的键
+	Removed []string
+	// Modified are the keys whose value the node changed  节点修改了值的键
+	Modified []string
+}
+
+// auditSnapshotKey is the message attachment key of the input snapshot taken before the node
+type auditSnapshotKey struct{}
+
+// AuditAspect is a node aspect that diffs the message input and private variables before and
+// after each node and passes what the node changed to Sink, to find out which node mutated
+// a field unexpectedly or to keep a compliance trail.
+//
+// AuditAspect 是一个节点切面，比较每个节点执行前后的消息输入和私有变量，并将节点所做的修改传给 Sink，
+// 用于找出意外修改字段的节点或保留合规审计记录。
+//
+// The diff is shallow: top-level keys of the input and of the private variables are compared,
+// values changed in place inside a nested map are not detected. Nodes that change nothing and
+// failing nodes produce no record. Messages are sampled by id, so either all nodes of a message
+// are audited or none.
+//
+// 比较是浅层的：只比较输入和私有变量的顶层键，嵌套映射中被原地修改的值不会被发现。
+// 没有修改的节点和出错的节点不产生记录。消息按 id 采样，因此一条消息的所有节点要么都被审计，要么都不审计。
+//
+// Usage:
+// 使用方法：
+//
+//	audit := NewAuditAspect(func(record AuditRecord) {
+//		log.Printf("audit: %+v", record)
+//	}, 0.1)
+//	engine, err := engine.NewChainEngine(def, engine.WithAspects(audit))
+type AuditAspect struct {
+	// Sink receives the audit records, it is called synchronously and must be safe for concurrent use
+	// Sink 接收审计记录，同步调用，必须支持并发调用
+	Sink func(record AuditRecord)
+	// SampleRate is the fraction of messages audited, between 0 and 1  审计消息的比例，介于 0 和 1 之间
+	SampleRate float64
+	// Filter restricts which nodes are audited, empty means all nodes  限制审计哪些节点，为空表示所有节点
+	Filter NodeFilter
+}
+
+// NewAuditAspect creates a new audit aspect passing the records of the sampled messages to sink.
+//
+// NewAuditAspect 创建新的审计切面，将被采样消息的记录传给 sink。
+func NewAuditAspect(sink func(record AuditRecord), sampleRate float64) *AuditAspect {
+	return &AuditAspect{
+		Sink:       sink,
+		SampleRate: sampleRate,
+	}
+}
+
+// Order returns the execution order of this aspect. Lower values execute earlier.
+// AuditAspect has order 1000, so the changes made by the other node before aspects are not
+// attributed to the node.
+//
+// Order 返回此切面的执行顺序。值越低，执行越早。
+// AuditAspect 的顺序为 1000，因此其他节点前置切面所做的修改不会被记在节点上。
+func (aspect *AuditAspect) Order() int {
+	return 1000
+}
+
+// New creates a new instance of the audit aspect with the same sink, sample rate and filter.
+//
+// New 创建具有相同 sink、采样率和过滤器的审计切面新实例。
+func (aspect *AuditAspect) New() types.Aspect {
+	return &AuditAspect{
+		Sink:       aspect.Sink,
+		SampleRate: aspect.SampleRate,
+		Filter:     aspect.Filter.Copy(),
+	}
+}
+
+// Type returns the unique identifier for this aspect type.
+//
+// Type 返回此切面类型的唯一标识符。
+func (aspect *AuditAspect) Type() string {
+	return "audit"
+}
+
+// PointCut applies the aspect to the nodes matched by Filter for the sampled messages.
+//
+// PointCut 对被采样的消息应用于 Filter 匹配的节点。
+func (aspect *AuditAspect) PointCut(nodeCtx types.NodeCtx, msg types.RuleMsg, relationType string) bool {
+	return aspect.Sink != nil && aspect.sampled(msg.Id()) && aspect.Filter.Match(nodeCtx)
+}
+
+// Before takes a snapshot of the input and private variables before the node runs.
+//
+// Before 在节点执行前保存输入和私有变量的快照。
+func (aspect *AuditAspect) Before(nodeCtx types.NodeCtx, msg types.RuleMsg, relationType string) (types.RuleMsg, error) {
+	msg.SetAttachment(auditSnapshotKey{}, auditSnapshot(msg))
+	return msg, nil
+}
+
+// After diffs the input and private variables against the snapshot and passes the changes to Sink.
+//
+// After 将输入和私有变量与快照比较，并将修改传给 Sink。
+func (aspect *AuditAspect) After(nodeCtx types.NodeCtx, msg types.RuleMsg, relationType string) (types.RuleMsg, error) {
+	before, ok := msg.Attachment(auditSnapshotKey{}).(map[string]any)
+	if !ok {
+		return msg, nil
+	}
+	msg.SetAttachment(auditSnapshotKey{}, nil)
+	record := AuditRecord{
+		MsgId:    msg.Id(),
+		NodeId:   nodeCtx.Id(),
+		NodeType: nodeCtx.Type(),
+		Relation: relationType,
+	}
+	after := auditSnapshot(msg)
+	for key, value := range after {
+		old, ok := before[key]
+		if !ok {
+			record.Added = append(record.Added, key)
+		} else if !reflect.DeepEqual(old, value) {
+			record.Modified = append(record.Modified, key)
+		}
+	}
+	for key := range before {
+		if _, ok := after[key]; !ok {
+			record.Removed = append(record.Removed, key)
+		}
+	}
+	if len(record.Added) == 0 && len(record.Removed) == 0 && len(record.Modified) == 0 {
+		return msg, nil
+	}
+	slices.Sort(record.Added)
+	slices.Sort(record.Removed)
+	slices.Sort(record.Modified)
+	aspect.Sink(record)
+	return msg, nil
+}
+
+// sampled reports whether the message id falls into the sample rate
+func (aspect *AuditAspect) sampled(msgId string) bool {
+	if aspect.SampleRate >= 1 {
+		return true
+	}
+	if aspect.SampleRate <= 0 {
+		return false
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(msgId))
+	return float64(h.Sum32()) < aspect.SampleRate*math.MaxUint32
+}
+
+// auditSnapshot returns the top-level input values and private variables in one flat map,
+// private variables are keyed with the types.PriVarsKey prefix
+func auditSnapshot(msg types.RuleMsg) map[string]any {
+	input := msg.GetInput()
+	priVars := msg.GetPrivateVars()
+	snapshot := make(map[string]any, len(input)+len(priVars))
+	for key, value := range input {
+		if key != types.PriVarsKey {
+			snapshot[key] = value
+		}
+	}
+	for key, value := range priVars {
+		snapshot[types.PriVarsKey+"."+key] = value
+	}
+	return snapshot
+}
@@ -18,9 +18,11 @@ package aspect
 
 import (
 	"fmt"
+	"strings"
 	"sync"
 
 	"github.com/bittoy/rule/types"
+	"github.com/bittoy/rule/utils/maps"
 )
 
 var (
@@ -161,6 +163,13 @@ func NewChainAggregationRules() *chainAggregationRules {
 		}
 		return nil
 	})
+	//分数区间检测
+	r.AddRule(func(config types.Config, def *types.ChainAggregation) error {
+		if def != nil {
+			return validateAggregationThresholds(def)
+		}
+		return nil
+	})
 	return r
 }
 
@@ -223,3 +232,25 @@ func checkChainAggregationCycles(chainAggregation *types.ChainAggregation) error
 	}
 	return nil
 }
+
+// validateAggregationThresholds checks that the threshold bands are sorted by strictly
+// ascending MinScore, so they don't overlap, and that every band has an action.
+//
+// validateAggregationThresholds 检查分数区间按 MinScore 严格升序排列（因此互不重叠），并且每个区间都有动作。
+func validateAggregationThresholds(chainAggregation *types.ChainAggregation) error {
+	var configuration types.ChainAggregationConfiguration
+	if err := maps.Map2Struct(chainAggregation.Configuration, &configuration); err != nil {
+		return err
+	}
+	thresholds := configuration.Aggregation.Thresholds
+	for i, band := range thresholds {
+		if strings.TrimSpace(band.Action) == "" {
+			return fmt.Errorf("%s 分数区间 %d 的 action 不能为空", chainAggregation.Id, i)
+		}
+		if i > 0 && band.MinScore <= thresholds[i-1].MinScore {
+			return fmt.Errorf("%s 分数区间必须按 minScore 严格升序排列且不能重叠，区间 %d 的 minScore %d 不大于前一个区间的 %d",
+				chainAggregation.Id, i, band.MinScore, thresholds[i-1].MinScore)
+		}
+	}
+	return nil
+}
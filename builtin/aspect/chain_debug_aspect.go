@@ -54,7 +54,15 @@ var (
 //
 // Debug logs are generated through the OnDebug callback configured in the rule context.
 // 调试日志通过规则上下文中配置的 OnDebug 回调生成。
+//
+// Set SampleRate to log only a fraction of the messages, so debugging can stay on in production.
+// 设置 SampleRate 只记录部分消息，以便在生产环境中保持调试开启。
 type ChainDebug struct {
+	// SampleRate is the fraction of messages logged, between 0 and 1, 0 logs every message.
+	// A message is drawn once, the node debug aspects log the same messages at the same rate
+	// SampleRate 记录消息的比例，介于 0 和 1 之间，为 0 时记录所有消息。
+	// 每条消息只抽样一次，采样率相同的节点调试切面记录相同的消息
+	SampleRate float64
 }
 
 // Order returns the execution order of this aspect. Higher values execute later.
@@ -72,7 +80,7 @@ func (aspect *ChainDebug) Order() int {
 // New 创建 Debug 切面的新实例。
 // 每个规则链都会获得自己的 Debug 切面实例。
 func (aspect *ChainDebug) New() types.Aspect {
-	return &ChainDebug{}
+	return &ChainDebug{SampleRate: aspect.SampleRate}
 }
 
 // Type returns the unique identifier for this aspect type.
@@ -82,13 +90,13 @@ func (aspect *ChainDebug) Type() string {
 	return "chainDebug"
 }
 
-// PointCut determines which nodes this aspect applies to.
-// The Debug aspect applies to all nodes unconditionally.
+// PointCut determines which messages this aspect applies to.
+// The Debug aspect applies to the messages sampled at SampleRate.
 //
-// PointCut 确定此切面应用于哪些节点。
-// Debug 切面无条件地应用于所有节点。
+// PointCut 确定此切面应用于哪些消息。
+// Debug 切面应用于按 SampleRate 采样的消息。
 func (aspect *ChainDebug) PointCut(chainCtx types.ChainCtx, msg types.RuleMsg) bool {
-	return true
+	return debugSampled(&msg, aspect.SampleRate)
 }
 
 // Before is executed before node processing. It logs the incoming message
@@ -97,7 +105,7 @@ func (aspect *ChainDebug) PointCut(chainCtx types.ChainCtx, msg types.RuleMsg) b
 // Before 在节点处理之前执行。它异步记录传入消息和上下文信息，避免阻塞执行。
 func (aspect *ChainDebug) Before(chainCtx types.ChainCtx, msg types.RuleMsg) (types.RuleMsg, error) {
 	//异步记录In日志
-	fmt.Println("Before:", chainCtx.Id(), chainCtx.Type(), msg.GetInput(), msg.GetChainOutput())
+	fmt.Println("Before:", chainCtx.Id(), chainCtx.Type(), chainCtx.Config().Redact(msg.GetInput()), chainCtx.Config().Redact(msg.GetChainOutput()))
 	return msg, nil
 }
 
@@ -107,6 +115,6 @@ func (aspect *ChainDebug) Before(chainCtx types.ChainCtx, msg types.RuleMsg) (ty
 // After 在节点处理之后执行。它记录传出消息和处理过程中发生的任何错误。
 func (aspect *ChainDebug) After(chainCtx types.ChainCtx, msg types.RuleMsg) (types.RuleMsg, error) {
 	//异步记录In日志
-	fmt.Println("After:", chainCtx.Id(), chainCtx.Type(), msg.GetInput(), msg.GetChainOutput())
+	fmt.Println("After:", chainCtx.Id(), chainCtx.Type(), chainCtx.Config().Redact(msg.GetInput()), chainCtx.Config().Redact(msg.GetChainOutput()))
 	return msg, nil
 }
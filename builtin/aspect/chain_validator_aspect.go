@@ -18,9 +18,15 @@ package aspect
 
 import (
 	"fmt"
+	"slices"
+	"strings"
 	"sync"
 
 	"github.com/bittoy/rule/types"
+	"github.com/bittoy/rule/utils/maps"
+
+	"github.com/expr-lang/expr/ast"
+	"github.com/expr-lang/expr/parser"
 )
 
 var (
@@ -113,7 +119,14 @@ func NewChainRules() *chainRules {
 	})
 	r.AddRule(func(config types.Config, def *types.Chain) error {
 		if def != nil {
-			return validateChainNode(def)
+			return validateChainNode(config.ComponentsRegistry, def)
+		}
+		return nil
+	})
+	//可达性检测
+	r.AddRule(func(config types.Config, def *types.Chain) error {
+		if def != nil {
+			return validateChainReachability(config.ComponentsRegistry, def)
 		}
 		return nil
 	})
@@ -196,11 +209,16 @@ func (r *chainRules) Rules() []func(config types.Config, def *types.Chain) error
 // 空间复杂度：O(V + E) 用于邻接表和度数跟踪
 
 func checkChainCycles(ruleChain *types.Chain) error {
-	hasCycle, path := checkCycles(ruleChain.Metadata.Connections)
+	c := &validationCollector{failFast: true}
+	collectChainCycles(ruleChain, c)
+	return c.err()
+}
+
+func collectChainCycles(ruleChain *types.Chain, c *validationCollector) {
+	hasCycle, path := checkCycles(ruleChain.Metadata.EnabledConnections())
 	if hasCycle {
-		return fmt.Errorf("cycle detected in rule chain ruleId:%s path:%v", ruleChain.Id, path)
+		c.add(path[len(path)-1], ValidationCategoryCycle, "cycle detected in rule chain ruleId:%s path:%v", ruleChain.Id, path)
 	}
-	return nil
 }
 
 func checkCycles(edges []types.NodeConnection) (bool, []string) {
@@ -249,13 +267,23 @@ func checkCycles(edges []types.NodeConnection) (bool, []string) {
 	return false, nil
 }
 
-func validateChainNode(chain *types.Chain) error {
+func validateChainNode(registry types.ComponentRegistry, chain *types.Chain) error {
+	c := &validationCollector{failFast: true}
+	collectChainNode(registry, chain, c)
+	return c.err()
+}
+
+// collectChainNode checks the connections of every node against the relations its component declares
+// through types.RelationsGetter in the registry, and the configuration of the switch and guard nodes
+func collectChainNode(registry types.ComponentRegistry, chain *types.Chain, c *validationCollector) {
 	var nodeRoutes = make(map[string][]types.RuleNodeRelation)
 	var nodes = make(map[string]struct{})
 	var hasStart bool
 	var hasEnd bool
-	if len(chain.Metadata.Nodes) == 0 || len(chain.Metadata.Connections) == 0 {
-		return fmt.Errorf("规则链中必须包含规则节点和消息拓扑节点")
+	connections := chain.Metadata.EnabledConnections()
+	if len(chain.Metadata.Nodes) == 0 || len(connections) == 0 {
+		c.add("", ValidationCategoryStructure, "规则链中必须包含规则节点和消息拓扑节点")
+		return
 	}
 
 	for _, node := range chain.Metadata.Nodes {
@@ -268,27 +296,51 @@ func validateChainNode(chain *types.Chain) error {
 		nodes[node.Id] = struct{}{}
 	}
 	if !hasStart {
-		return fmt.Errorf("%s 规则链中必须包含一个开始节点", chain.Id)
+		if c.add("", ValidationCategoryStructure, "%s 规则链中必须包含一个开始节点", chain.Id) {
+			return
+		}
 	}
 	if !hasEnd {
-		return fmt.Errorf("%s 规则链中必须包含一个结束节点", chain.Id)
+		if c.add("", ValidationCategoryStructure, "%s 规则链中必须包含一个结束节点", chain.Id) {
+			return
+		}
+	}
+	if err := chain.Metadata.ValidateRelationAliases(); err != nil {
+		if c.add("", ValidationCategoryStructure, "%s 规则链的关系别名无效: %s", chain.Id, err.Error()) {
+			return
+		}
+	}
+	if rootNodeId := chain.Metadata.RootNodeId; rootNodeId != "" {
+		if _, ok := nodes[rootNodeId]; !ok {
+			if c.add(rootNodeId, ValidationCategoryStructure, "%s 规则链的入口节点 %s 不存在", chain.Id, rootNodeId) {
+				return
+			}
+		}
 	}
 
-	for _, item := range chain.Metadata.Connections {
+	for _, item := range connections {
 		inNodeId := item.FromId
 		outNodeId := item.ToId
 
 		if _, ok := nodes[inNodeId]; !ok {
-			return fmt.Errorf("节点 %s 不存在", inNodeId)
+			if c.add(inNodeId, ValidationCategoryConnection, "节点 %s 不存在", inNodeId) {
+				return
+			}
+			continue
 		}
 		if _, ok := nodes[outNodeId]; !ok {
-			return fmt.Errorf("节点 %s 不存在", outNodeId)
+			if c.add(outNodeId, ValidationCategoryConnection, "节点 %s 不存在", outNodeId) {
+				return
+			}
+			continue
 		}
 
 		ruleNodeRelation := types.RuleNodeRelation{
 			InId:         inNodeId,
 			OutId:        outNodeId,
-			RelationType: item.Type,
+			RelationType: chain.Metadata.Relation(item.Type),
+			Condition:    strings.TrimSpace(item.Condition),
+			Priority:     item.Priority,
 		}
 		nodeRelations, ok := nodeRoutes[inNodeId]
 		if ok {
@@ -300,23 +352,88 @@ func validateChainNode(chain *types.Chain) error {
 	}
 
 	for _, node := range chain.Metadata.Nodes {
-		if node.Type == types.RuleSubTypeStart || node.Type == types.RuleSubTypeExprAssign {
-			if len(nodeRoutes[node.Id]) != 1 || nodeRoutes[node.Id][0].RelationType != types.DefaultRelationType {
-				return fmt.Errorf("节点 %s(%s) 必须有且仅有一个 default 连接，但当前有 %d 个连接", node.Id, node.Type, len(nodeRoutes[node.Id]))
+		// failure 连接是节点出错时的额外出口，不参与各节点类型的连接规则
+		// Failure connections are the extra exits of failing nodes, the node type rules ignore them
+		declared, hasDeclared := declaredRelations(registry, node.Type)
+		terminal := hasDeclared && len(declared) == 0
+		relations, failures := splitFailureRelations(nodeRoutes[node.Id])
+		if len(failures) > 0 && !terminal {
+			nodeRoutes[node.Id] = relations
+			if !chain.ContinueOnErr {
+				if c.add(node.Id, ValidationCategoryConnection, "节点 %s(%s) 的 failure 连接仅在规则链开启 continueOnErr 时有效", node.Id, node.Type) {
+					return
+				}
+			}
+			if len(unguardedRelations(failures)) > 1 {
+				if c.add(node.Id, ValidationCategoryConnection, "节点 %s(%s) 最多只能有一个无条件的 failure 连接", node.Id, node.Type) {
+					return
+				}
 			}
 		}
-		if node.Type == types.RuleSubTypeEnd {
+		// 带条件的连接是额外的候选分支，连接数量规则只针对无条件的连接
+		// Guarded connections are extra candidates, the connection count rules apply to unguarded connections only
+		unguarded := unguardedRelations(nodeRoutes[node.Id])
+		if !chain.Metadata.AllowAmbiguousConnections {
+			if relation, ok := ambiguousRelation(unguarded); ok {
+				if c.add(node.Id, ValidationCategoryConnection, "节点 %s(%s) 有多个优先级相同的无条件 %s 连接，需设置不同的 priority 或开启 allowAmbiguousConnections", node.Id, node.Type, relation.RelationType) {
+					return
+				}
+			}
+		}
+		defaultOnly := hasDeclared && slices.Equal(declared, []string{types.DefaultRelationType})
+		filter := hasDeclared && declaresExactly(declared, types.TrueRelationType, types.FalseRelationType)
+		if defaultOnly {
+			if len(unguarded) != 1 || unguarded[0].RelationType != types.DefaultRelationType {
+				if c.add(node.Id, ValidationCategoryConnection, "节点 %s(%s) 必须有且仅有一个 default 连接，但当前有 %d 个连接", node.Id, node.Type, len(unguarded)) {
+					return
+				}
+			}
+			for _, relation := range nodeRoutes[node.Id] {
+				if relation.RelationType != types.DefaultRelationType {
+					if c.add(node.Id, ValidationCategoryConnection, "节点 %s(%s) 只能有 default 连接，但当前有 %s 连接", node.Id, node.Type, relation.RelationType) {
+						return
+					}
+				}
+			}
+		}
+		if terminal {
 			if len(nodeRoutes[node.Id]) != 0 {
-				return fmt.Errorf("节点 %s(%s) 不能有连接，但当前有 %d 个连接", node.Id, node.Type, len(nodeRoutes[node.Id]))
+				if c.add(node.Id, ValidationCategoryConnection, "节点 %s(%s) 不能有连接，但当前有 %d 个连接", node.Id, node.Type, len(nodeRoutes[node.Id])) {
+					return
+				}
 			}
 		}
-		if node.Type == types.RuleSubTypeJsFilter || node.Type == types.RuleSubTypeExprFilter {
-			if len(nodeRoutes[node.Id]) != 2 {
-				return fmt.Errorf("节点 %s(%s) 必须有两个连接", node.Id, node.Type)
+		if filter {
+			routesErrors := routesEvalErrors(node)
+			if routesErrors {
+				if len(unguarded) != 3 {
+					if c.add(node.Id, ValidationCategoryConnection, "节点 %s(%s) 必须有三个连接", node.Id, node.Type) {
+						return
+					}
+				}
+				if !hasRelation(unguarded, types.ErrorRelationType) {
+					if c.add(node.Id, ValidationCategoryConnection, "节点 %s(%s) 必须有 %s 连接", node.Id, node.Type, types.ErrorRelationType) {
+						return
+					}
+				}
+			} else if len(unguarded) != 2 {
+				if c.add(node.Id, ValidationCategoryConnection, "节点 %s(%s) 必须有两个连接", node.Id, node.Type) {
+					return
+				}
+			}
+			for _, relation := range nodeRoutes[node.Id] {
+				if relation.RelationType == types.ErrorRelationType && routesErrors {
+					continue
+				}
+				if relation.RelationType != types.TrueRelationType && relation.RelationType != types.FalseRelationType {
+					if c.add(node.Id, ValidationCategoryConnection, "节点 %s(%s) 只能有true和false连接，但当前有 %s 连接", node.Id, node.Type, relation.RelationType) {
+						return
+					}
+				}
 			}
 			var hasTrue bool
 			var hasFalse bool
-			for _, relation := range nodeRoutes[node.Id] {
+			for _, relation := range unguarded {
 				if relation.RelationType == types.TrueRelationType {
 					hasTrue = true
 				}
@@ -325,27 +442,323 @@ func validateChainNode(chain *types.Chain) error {
 				}
 			}
 			if !hasFalse || !hasTrue {
-				return fmt.Errorf("节点 %s(%s) 必须有ture和false两个连接", node.Id, node.Type)
+				if c.add(node.Id, ValidationCategoryConnection, "节点 %s(%s) 必须有ture和false两个连接", node.Id, node.Type) {
+					return
+				}
+			}
+		}
+		if node.Type == types.RuleSubTypeRangeSwitch {
+			var bands struct {
+				Bands []types.RangeBand
+			}
+			_ = maps.Map2Struct(node.Configuration, &bands)
+			for _, band := range bands.Bands {
+				if !hasRelation(nodeRoutes[node.Id], strings.TrimSpace(band.Relation)) {
+					if c.add(node.Id, ValidationCategoryConnection, "节点 %s(%s) 的区间关系 %s 没有对应的连接", node.Id, node.Type, band.Relation) {
+						return
+					}
+				}
 			}
 		}
-		if node.Type == types.RuleSubTypeExprSwitch || node.Type == types.RuleSubTypeJsSwitch {
+		if node.Type == types.RuleSubTypeWeightedRoute {
+			var routes struct {
+				Routes []types.WeightedRoute
+			}
+			_ = maps.Map2Struct(node.Configuration, &routes)
+			for _, route := range routes.Routes {
+				if !hasRelation(nodeRoutes[node.Id], strings.TrimSpace(route.Relation)) {
+					if c.add(node.Id, ValidationCategoryConnection, "节点 %s(%s) 的分支关系 %s 没有对应的连接", node.Id, node.Type, route.Relation) {
+						return
+					}
+				}
+			}
+		}
+		if rejectRelation, ok := guardRelations[node.Type]; ok {
+			for _, relationType := range []string{types.DefaultRelationType, rejectRelation} {
+				if !hasRelation(unguarded, relationType) {
+					if c.add(node.Id, ValidationCategoryConnection, "节点 %s(%s) 必须有 %s 连接", node.Id, node.Type, relationType) {
+						return
+					}
+				}
+			}
+		}
+		// 静态分析开关节点可能返回的关系，与节点的连接交叉核对
+		// Cross-check the relations a switch can return, when they are all literals, with its connections
+		if returned, ok := switchRelations(node); ok {
+			for _, relationType := range returned {
+				relationType = chain.Metadata.Relation(relationType)
+				if !hasRelation(nodeRoutes[node.Id], relationType) {
+					if c.add(node.Id, ValidationCategoryConnection, "节点 %s(%s) 可能返回关系 %s，但没有对应的连接", node.Id, node.Type, relationType) {
+						return
+					}
+				}
+			}
+			for _, relation := range nodeRoutes[node.Id] {
+				if relation.RelationType != types.DefaultRelationType && !slices.ContainsFunc(returned, func(relationType string) bool {
+					return chain.Metadata.Relation(relationType) == relation.RelationType
+				}) {
+					if c.add(node.Id, ValidationCategoryConnection, "节点 %s(%s) 的 %s 连接不会被使用，节点不会返回该关系", node.Id, node.Type, relation.RelationType) {
+						return
+					}
+				}
+			}
+		}
+		// 声明的关系固定时，节点不会返回的关系的连接不会被使用
+		// With fixed declared relations, a connection of a relation the node never returns is never followed
+		if hasDeclared && !terminal && !defaultOnly && !filter && !slices.Contains(declared, types.DynamicRelationType) {
+			for _, relation := range nodeRoutes[node.Id] {
+				if !slices.ContainsFunc(declared, func(relationType string) bool {
+					return chain.Metadata.Relation(relationType) == relation.RelationType
+				}) {
+					if c.add(node.Id, ValidationCategoryConnection, "节点 %s(%s) 的 %s 连接不会被使用，节点不会返回该关系", node.Id, node.Type, relation.RelationType) {
+						return
+					}
+				}
+			}
+		}
+		if hasDeclared && !defaultOnly && slices.Contains(declared, types.DefaultRelationType) {
 			if len(nodeRoutes[node.Id]) == 0 {
-				return fmt.Errorf("节点 %s(%s) 必须有且仅有一个 default 连接，但当前没有任何连接", node.Id, node.Type)
+				if c.add(node.Id, ValidationCategoryConnection, "节点 %s(%s) 必须有且仅有一个 default 连接，但当前没有任何连接", node.Id, node.Type) {
+					return
+				}
 			}
 			var haveDefault bool
-			for _, ruleNodeRelation := range nodeRoutes[node.Id] {
+			for _, ruleNodeRelation := range unguarded {
 				if ruleNodeRelation.RelationType == types.DefaultRelationType {
 					haveDefault = true
 				}
 			}
 			if !haveDefault {
-				return fmt.Errorf("节点 %s(%s) 必须有且仅有一个 default 连接，但当前没有任何 default 连接", node.Id, node.Type)
+				if c.add(node.Id, ValidationCategoryConnection, "节点 %s(%s) 必须有且仅有一个 default 连接，但当前没有任何 default 连接", node.Id, node.Type) {
+					return
+				}
 			}
 		}
 	}
+}
+
+// validateChainReachability checks that every node is reachable from the start node
+// and that every node other than an end node has at least one outgoing connection.
+// The orphaned or dead-end node ids are included in the error.
+//
+// validateChainReachability 检查所有节点都能从开始节点到达，
+// 并且除结束节点外的每个节点至少有一个传出连接。错误中包含孤立或无出口的节点 id。
+func validateChainReachability(registry types.ComponentRegistry, chain *types.Chain) error {
+	c := &validationCollector{}
+	collectChainReachability(registry, chain, c)
+	var unreachable, deadEnds []string
+	for _, err := range c.errs {
+		switch err.Category {
+		case ValidationCategoryUnreachable:
+			unreachable = append(unreachable, err.NodeId)
+		case ValidationCategoryDeadEnd:
+			deadEnds = append(deadEnds, err.NodeId)
+		}
+	}
+	if len(unreachable) > 0 {
+		return fmt.Errorf("%s 规则链中存在从开始节点不可达的节点: %v", chain.Id, unreachable)
+	}
+	if len(deadEnds) > 0 {
+		return fmt.Errorf("%s 规则链中存在没有传出连接的非结束节点: %v", chain.Id, deadEnds)
+	}
 	return nil
 }
 
+func collectChainReachability(registry types.ComponentRegistry, chain *types.Chain, c *validationCollector) {
+	graph := map[string][]string{}
+	for _, e := range chain.Metadata.EnabledConnections() {
+		graph[e.FromId] = append(graph[e.FromId], e.ToId)
+	}
+
+	var queue []string
+	for _, node := range chain.Metadata.Nodes {
+		// a node configured as the root node is an entry point too
+		if node.Type == types.RuleSubTypeStart || node.Id == chain.Metadata.RootNodeId {
+			queue = append(queue, node.Id)
+		}
+	}
+	reached := map[string]bool{}
+	for _, id := range queue {
+		reached[id] = true
+	}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, next := range graph[id] {
+			if !reached[next] {
+				reached[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	for _, node := range chain.Metadata.Nodes {
+		if !reached[node.Id] {
+			c.add(node.Id, ValidationCategoryUnreachable, "节点 %s(%s) 从开始节点不可达", node.Id, node.Type)
+		}
+		if !isTerminalNode(registry, node.Type) && len(graph[node.Id]) == 0 {
+			c.add(node.Id, ValidationCategoryDeadEnd, "节点 %s(%s) 不是结束节点但没有传出连接", node.Id, node.Type)
+		}
+	}
+}
+
+// ambiguousRelation returns a relation sharing its type and priority with another one of the relations
+func ambiguousRelation(relations []types.RuleNodeRelation) (types.RuleNodeRelation, bool) {
+	type key struct {
+		relationType string
+		priority     int
+	}
+	seen := make(map[key]struct{}, len(relations))
+	for _, relation := range relations {
+		k := key{relation.RelationType, relation.Priority}
+		if _, ok := seen[k]; ok {
+			return relation, true
+		}
+		seen[k] = struct{}{}
+	}
+	return types.RuleNodeRelation{}, false
+}
+
+// isTerminalNode reports whether nodes of the type end the chain and take no connections,
+// their component declares no relation
+func isTerminalNode(registry types.ComponentRegistry, nodeType types.NodeType) bool {
+	relations, ok := declaredRelations(registry, nodeType)
+	return ok && len(relations) == 0
+}
+
+// declaredRelations returns the relations the component of the node type declares through types.RelationsGetter,
+// ok is false when the registry is nil, the type is not registered or the component declares nothing
+func declaredRelations(registry types.ComponentRegistry, nodeType types.NodeType) (relations []string, ok bool) {
+	if registry == nil {
+		return nil, false
+	}
+	node, ok := registry.GetComponent(nodeType)
+	if !ok {
+		return nil, false
+	}
+	getter, ok := node.(types.RelationsGetter)
+	if !ok {
+		return nil, false
+	}
+	return getter.Relations(), true
+}
+
+// declaresExactly reports whether the declared relations are the given relations, in any order
+func declaresExactly(declared []string, relations ...string) bool {
+	if len(declared) != len(relations) {
+		return false
+	}
+	for _, relation := range relations {
+		if !slices.Contains(declared, relation) {
+			return false
+		}
+	}
+	return true
+}
+
+// switchRelations returns the relations an exprSwitch or jsSwitch node can return, ok is false when
+// they cannot be determined statically: the node is of another type, a jsSwitch uses a script, or an
+// expr returns a value that is not a string literal
+func switchRelations(node *types.BaseInfo) (relations []string, ok bool) {
+	var config struct {
+		Script string
+		Cases  []types.Case
+	}
+	if err := maps.Map2Struct(node.Configuration, &config); err != nil {
+		return nil, false
+	}
+	script := strings.TrimSpace(config.Script)
+	switch {
+	case node.Type == types.RuleSubTypeExprSwitch && script != "":
+		return exprRelations(script)
+	case (node.Type == types.RuleSubTypeJsSwitch && script == "" || node.Type == types.RuleSubTypeExprSwitch) && len(config.Cases) > 0:
+		for _, item := range config.Cases {
+			relations = append(relations, item.Relation())
+		}
+		return relations, true
+	}
+	return nil, false
+}
+
+// exprRelations returns the string literals an expr script can evaluate to, following the branches of
+// conditional expressions, ok is false when the script can evaluate to another value
+func exprRelations(script string) ([]string, bool) {
+	tree, err := parser.Parse(script)
+	if err != nil {
+		return nil, false
+	}
+	var relations []string
+	var collect func(node ast.Node) bool
+	collect = func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.StringNode:
+			relations = append(relations, n.Value)
+			return true
+		case *ast.ConditionalNode:
+			return collect(n.Exp1) && collect(n.Exp2)
+		}
+		return false
+	}
+	if !collect(tree.Node) {
+		return nil, false
+	}
+	return relations, true
+}
+
+// guardRelations are the relations guard nodes route rejected messages to, a guard node
+// must connect both the default relation and its reject relation
+var guardRelations = map[types.NodeType]string{
+	types.RuleSubTypeSchema:  types.InvalidRelationType,
+	types.RuleSubTypeRequire: types.MissingRelationType,
+}
+
+// routesEvalErrors reports whether the node is an exprFilter routing its evaluation errors to the error relation
+func routesEvalErrors(node *types.BaseInfo) bool {
+	if node.Type != types.RuleSubTypeExprFilter {
+		return false
+	}
+	var config struct {
+		OnEvalError string
+	}
+	if err := maps.Map2Struct(node.Configuration, &config); err != nil {
+		return false
+	}
+	return config.OnEvalError == types.ErrorRelationType
+}
+
+// hasRelation reports whether one of the connections has the relation type
+func hasRelation(relations []types.RuleNodeRelation, relationType string) bool {
+	for _, relation := range relations {
+		if relation.RelationType == relationType {
+			return true
+		}
+	}
+	return false
+}
+
+// splitFailureRelations separates the failure connections from the other connections of a node
+func splitFailureRelations(relations []types.RuleNodeRelation) (others, failures []types.RuleNodeRelation) {
+	for _, relation := range relations {
+		if relation.RelationType == types.FailureRelationType {
+			failures = append(failures, relation)
+		} else {
+			others = append(others, relation)
+		}
+	}
+	return others, failures
+}
+
+// unguardedRelations returns the relations without a guard condition
+func unguardedRelations(relations []types.RuleNodeRelation) []types.RuleNodeRelation {
+	var unguarded []types.RuleNodeRelation
+	for _, relation := range relations {
+		if relation.Condition == "" {
+			unguarded = append(unguarded, relation)
+		}
+	}
+	return unguarded
+}
+
 func endNodes(edges []types.NodeConnection) []string {
 	fromSet := make(map[string]bool)
 	toSet := make(map[string]bool)
@@ -0,0 +1,146 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package aspect
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/bittoy/rule/types"
+)
+
+var (
+	// Compile-time check ConcurrencyLimitAspect implements types.ChainBeforeAspect.
+	_ types.ChainBeforeAspect = (*ConcurrencyLimitAspect)(nil)
+	// Compile-time check ConcurrencyLimitAspect implements types.CompletedAspect.
+	_ types.CompletedAspect = (*ConcurrencyLimitAspect)(nil)
+)
+
+// concurrencyPermitKey is the message attachment key of the semaphore the message holds a permit of,
+// keyed by chain id so that the child chains of an aggregation each keep their own permit
+type concurrencyPermitKey struct {
+	chainId string
+}
+
+// ConcurrencyLimitAspect is a chain aspect that caps how many messages a chain processes at the same time,
+// to protect chains calling downstream resources of limited capacity. Each chain id has its own semaphore
+// of Limit permits; a message arriving when all permits are taken is rejected with types.ErrChainAtCapacity
+// instead of waiting.
+//
+// ConcurrencyLimitAspect 是一个规则链切面，限制规则链同时处理的消息数，用于保护调用容量有限的下游资源的规则链。
+// 每个规则链 id 有各自容量为 Limit 的信号量；所有许可都被占用时到达的消息以 types.ErrChainAtCapacity 被拒绝，而不是等待。
+//
+// The permit is released in Completed rather than After, so that it is returned when a node fails mid-chain too.
+// Instances created by New share the semaphores, so the limit holds for a chain id across engines.
+// 许可在 Completed 而不是 After 中释放，因此节点中途出错时许可也会被归还。
+// 通过 New 创建的实例共享信号量，因此同一规则链 id 的限制跨引擎生效。
+//
+// Usage:
+// 使用方法：
+//
+//	limit := NewConcurrencyLimitAspect(10)
+//	engine, err := engine.NewChainEngine(def, engine.WithAspects(limit))
+type ConcurrencyLimitAspect struct {
+	// Limit is the maximum number of messages a chain processes at the same time, 0 or less means no limit
+	// Limit 是规则链同时处理的最大消息数，小于等于 0 表示不限制
+	Limit int
+
+	// semaphores holds the semaphore of each chain by chain id  按规则链 id 保存各规则链的信号量
+	semaphores *sync.Map
+}
+
+// NewConcurrencyLimitAspect creates a new concurrency limit aspect allowing limit messages per chain at the same time.
+//
+// NewConcurrencyLimitAspect 创建新的并发限制切面，每个规则链同时允许 limit 条消息。
+func NewConcurrencyLimitAspect(limit int) *ConcurrencyLimitAspect {
+	return &ConcurrencyLimitAspect{
+		Limit:      limit,
+		semaphores: &sync.Map{},
+	}
+}
+
+// Order returns the execution order of this aspect. Lower values execute earlier.
+// ConcurrencyLimitAspect has order 2, so rejected messages skip the other chain before aspects.
+//
+// Order 返回此切面的执行顺序。值越低，执行越早。
+// ConcurrencyLimitAspect 的顺序为 2，因此被拒绝的消息不会执行其他规则链前置切面。
+func (aspect *ConcurrencyLimitAspect) Order() int {
+	return 2
+}
+
+// New creates a new instance of the concurrency limit aspect with the same limit, sharing the semaphores.
+//
+// New 创建具有相同限制的并发限制切面新实例，共享信号量。
+func (aspect *ConcurrencyLimitAspect) New() types.Aspect {
+	if aspect.semaphores == nil {
+		aspect.semaphores = &sync.Map{}
+	}
+	return &ConcurrencyLimitAspect{
+		Limit:      aspect.Limit,
+		semaphores: aspect.semaphores,
+	}
+}
+
+// Type returns the unique identifier for this aspect type.
+//
+// Type 返回此切面类型的唯一标识符。
+func (aspect *ConcurrencyLimitAspect) Type() string {
+	return "concurrencyLimit"
+}
+
+// PointCut applies the aspect to every chain when a limit is set.
+//
+// PointCut 在设置了限制时应用于所有规则链。
+func (aspect *ConcurrencyLimitAspect) PointCut(chainCtx types.ChainCtx, msg types.RuleMsg) bool {
+	return aspect.Limit > 0 && aspect.semaphores != nil
+}
+
+// Before takes a permit of the chain semaphore, it returns types.ErrChainAtCapacity when none is left.
+//
+// Before 获取规则链信号量的许可，没有剩余许可时返回 types.ErrChainAtCapacity。
+func (aspect *ConcurrencyLimitAspect) Before(chainCtx types.ChainCtx, msg types.RuleMsg) (types.RuleMsg, error) {
+	sem := aspect.semaphore(chainCtx.Id())
+	select {
+	case sem <- struct{}{}:
+		msg.SetAttachment(concurrencyPermitKey{chainId: chainCtx.Id()}, sem)
+		return msg, nil
+	default:
+		return msg, fmt.Errorf("%w: chainId=%s limit=%d", types.ErrChainAtCapacity, chainCtx.Id(), aspect.Limit)
+	}
+}
+
+// Completed releases the permit taken by Before, whether the chain succeeded or failed.
+//
+// Completed 释放 Before 获取的许可，无论规则链成功或失败。
+func (aspect *ConcurrencyLimitAspect) Completed(chainCtx types.ChainCtx, msg types.RuleMsg, err error) {
+	key := concurrencyPermitKey{chainId: chainCtx.Id()}
+	sem, ok := msg.Attachment(key).(chan struct{})
+	if !ok {
+		return
+	}
+	msg.SetAttachment(key, nil)
+	<-sem
+}
+
+// semaphore returns the semaphore of the chain, creating it on first use
+func (aspect *ConcurrencyLimitAspect) semaphore(chainId string) chan struct{} {
+	if sem, ok := aspect.semaphores.Load(chainId); ok {
+		return sem.(chan struct{})
+	}
+	sem, _ := aspect.semaphores.LoadOrStore(chainId, make(chan struct{}, aspect.Limit))
+	return sem.(chan struct{})
+}
@@ -0,0 +1,44 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package aspect
+
+import (
+	"math/rand/v2"
+
+	"github.com/bittoy/rule/types"
+)
+
+// debugSampleKey is the message attachment key of the debug sampling draw
+type debugSampleKey struct{}
+
+// debugSampled reports whether the debug aspects log the message at the sample rate.
+// A rate of 0 or at least 1 logs every message. The random draw is made once per message
+// and attached to it, so the chain and node debug aspects agree on the messages they log.
+//
+// debugSampled 返回调试切面是否按采样率记录该消息。采样率为 0 或不小于 1 时记录所有消息。
+// 每条消息只抽样一次并附加到消息上，因此规则链和节点调试切面记录相同的消息。
+func debugSampled(msg *types.RuleMsg, sampleRate float64) bool {
+	if sampleRate <= 0 || sampleRate >= 1 {
+		return true
+	}
+	draw, ok := msg.Attachment(debugSampleKey{}).(float64)
+	if !ok {
+		draw = rand.Float64()
+		msg.SetAttachment(debugSampleKey{}, draw)
+	}
+	return draw < sampleRate
+}
@@ -0,0 +1,126 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package aspect
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/bittoy/rule/builtin/variable"
+	"github.com/bittoy/rule/types"
+)
+
+var (
+	// Compile-time check EnrichAspect implements types.ChainBeforeAspect.
+	_ types.ChainBeforeAspect = (*EnrichAspect)(nil)
+)
+
+// EnrichAspect is a chain aspect that resolves a declared set of variables through a
+// VariableCenter and merges them into the message input before the root node runs.
+// This centralizes enrichment so individual chains don't each need a resolve node.
+//
+// EnrichAspect 是一个规则链切面，在根节点运行之前通过 VariableCenter 解析声明的变量集，
+// 并将其合并到消息输入中。这样可以集中处理数据补全，无需每条规则链单独配置解析节点。
+//
+// Features:
+// 功能特性：
+//   - Per-message VarContext, variables share the request cache and TTL  每条消息独立的 VarContext，变量共享请求级缓存和 TTL
+//   - Optional failure on resolve errors  可选地在解析失败时终止消息
+//
+// Usage:
+// 使用方法：
+//
+//	center := variable.NewVariableCenter()
+//	center.RegisterFetcher("user", userFetcher)
+//	center.RegisterMeta(variable.VariableMeta{Key: "user.level", FetcherName: "user", Cached: true})
+//	enrich := NewEnrichAspect(center, []string{"user.level"}, true)
+//	engine, err := engine.NewChainEngine(def, engine.WithAspects(enrich))
+type EnrichAspect struct {
+	// Center resolves the variables  用于解析变量的变量中心
+	Center *variable.VariableCenter
+	// Keys are the variables merged into the message input  合并到消息输入中的变量
+	Keys []string
+	// FailOnError fails the message when a variable can't be resolved,
+	// otherwise the variable is skipped.
+	// FailOnError 为 true 时变量解析失败会终止消息，否则跳过该变量。
+	FailOnError bool
+}
+
+// NewEnrichAspect creates a new enrich aspect.
+//
+// NewEnrichAspect 创建新的数据补全切面。
+func NewEnrichAspect(center *variable.VariableCenter, keys []string, failOnError bool) *EnrichAspect {
+	return &EnrichAspect{
+		Center:      center,
+		Keys:        keys,
+		FailOnError: failOnError,
+	}
+}
+
+// Order returns the execution order of this aspect. Lower values execute earlier.
+// EnrichAspect has order 5, so the input is complete before other chain aspects run.
+//
+// Order 返回此切面的执行顺序。值越低，执行越早。
+// EnrichAspect 的顺序为 5，确保其他规则链切面运行前输入已补全。
+func (aspect *EnrichAspect) Order() int {
+	return 5
+}
+
+// New creates a new instance of the enrich aspect sharing the same VariableCenter.
+//
+// New 创建共享同一 VariableCenter 的数据补全切面新实例。
+func (aspect *EnrichAspect) New() types.Aspect {
+	return &EnrichAspect{
+		Center:      aspect.Center,
+		Keys:        slices.Clone(aspect.Keys),
+		FailOnError: aspect.FailOnError,
+	}
+}
+
+// Type returns the unique identifier for this aspect type.
+//
+// Type 返回此切面类型的唯一标识符。
+func (aspect *EnrichAspect) Type() string {
+	return "enrich"
+}
+
+// PointCut applies the aspect to every chain when a VariableCenter is configured.
+//
+// PointCut 在配置了 VariableCenter 时应用于所有规则链。
+func (aspect *EnrichAspect) PointCut(chainCtx types.ChainCtx, msg types.RuleMsg) bool {
+	return aspect.Center != nil && len(aspect.Keys) > 0
+}
+
+// Before resolves the declared variables and merges them into the message input.
+//
+// Before 解析声明的变量并将其合并到消息输入中。
+func (aspect *EnrichAspect) Before(chainCtx types.ChainCtx, msg types.RuleMsg) (types.RuleMsg, error) {
+	input := msg.GetInput()
+	vctx := variable.NewVarContext(input)
+	for _, key := range aspect.Keys {
+		val, err := aspect.Center.Get(context.Background(), vctx, key)
+		if err != nil {
+			if aspect.FailOnError {
+				return msg, fmt.Errorf("chain:%s enrich variable:%s error:%w", chainCtx.Id(), key, err)
+			}
+			continue
+		}
+		input[key] = val
+	}
+	return msg, nil
+}
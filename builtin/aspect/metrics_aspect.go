@@ -87,18 +87,30 @@ func (a *MetricsAspect) Order() int {
 	return 20
 }
 
+// Type returns the unique identifier for this aspect type.
+//
+// Type 返回此切面类型的唯一标识符。
+func (a *MetricsAspect) Type() string {
+	return "metrics"
+}
+
 // New creates a new instance of the metrics aspect for each rule engine.
-// Each new instance resets the metrics to start with clean counters.
+// Each new instance resets the metrics to start with clean counters, an aspect created
+// without metrics gives every instance its own metrics.
 //
 // New 为每个规则引擎创建指标切面的新实例。
-// 每个新实例重置指标以从干净的计数器开始。
+// 每个新实例重置指标以从干净的计数器开始，未设置指标的切面为每个实例创建各自的指标。
 func (a *MetricsAspect) New() types.Aspect {
-	if a.metrics == nil {
-		a.metrics = metrics.NewEngineMetrics()
+	// The aspect itself is not modified, engines are created concurrently from the same built-in aspect
+	// 不修改切面本身，多个引擎可能从同一个内置切面并发创建
+	m := a.metrics
+	if m == nil {
+		m = metrics.NewEngineMetrics()
+	} else {
+		m.Reset()
 	}
-	a.metrics.Reset()
 	return &MetricsAspect{
-		metrics: a.metrics,
+		metrics: m,
 	}
 }
 
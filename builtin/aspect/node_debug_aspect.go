@@ -52,9 +52,32 @@ var (
 //	config := types.NewConfig().WithAspects(&Debug{})
 //	engine := rulego.NewRuleEngine(config)
 //
+//	// Only debug exprSwitch nodes
+//	// 只调试 exprSwitch 节点
+//	debug := NewNodeDebug(NodeFilter{NodeTypes: []types.NodeType{types.RuleSubTypeExprSwitch}})
+//
+//	// Only debug 1% of the messages, at every node and chain
+//	// 只调试 1% 的消息，包括其经过的所有节点和规则链
+//	debugBundle := engine.NewAspectBundle("debug", &NodeDebug{SampleRate: 0.01}, &ChainDebug{SampleRate: 0.01})
+//
 // Debug logs are generated through the OnDebug callback configured in the rule context.
 // 调试日志通过规则上下文中配置的 OnDebug 回调生成。
 type NodeDebug struct {
+	// Filter restricts which nodes are logged, empty means all nodes
+	// Filter 限制记录哪些节点，为空表示所有节点
+	Filter NodeFilter
+	// SampleRate is the fraction of messages logged, between 0 and 1, 0 logs every message.
+	// A message is drawn once, so a sampled message is logged at every node it runs through
+	// SampleRate 记录消息的比例，介于 0 和 1 之间，为 0 时记录所有消息。
+	// 每条消息只抽样一次，被采样的消息在其经过的每个节点都会被记录
+	SampleRate float64
+}
+
+// NewNodeDebug creates a node debug aspect restricted by the given filter.
+//
+// NewNodeDebug 创建受给定过滤器限制的节点调试切面。
+func NewNodeDebug(filter NodeFilter) *NodeDebug {
+	return &NodeDebug{Filter: filter}
 }
 
 // Order returns the execution order of this aspect. Higher values execute later.
@@ -72,7 +95,7 @@ func (aspect *NodeDebug) Order() int {
 // New 创建 Debug 切面的新实例。
 // 每个规则链都会获得自己的 Debug 切面实例。
 func (aspect *NodeDebug) New() types.Aspect {
-	return &NodeDebug{}
+	return &NodeDebug{Filter: aspect.Filter.Copy(), SampleRate: aspect.SampleRate}
 }
 
 // Type returns the unique identifier for this aspect type.
@@ -83,12 +106,13 @@ func (aspect *NodeDebug) Type() string {
 }
 
 // PointCut determines which nodes this aspect applies to.
-// The Debug aspect applies to all nodes unconditionally.
+// The Debug aspect applies to the nodes matched by Filter, or all nodes when no filter is set,
+// for the messages sampled at SampleRate.
 //
 // PointCut 确定此切面应用于哪些节点。
-// Debug 切面无条件地应用于所有节点。
+// Debug 切面应用于 Filter 匹配的节点，未设置过滤器时应用于所有节点，且只记录按 SampleRate 采样的消息。
 func (aspect *NodeDebug) PointCut(nodeCtx types.NodeCtx, msg types.RuleMsg, relationType string) bool {
-	return true
+	return aspect.Filter.Match(nodeCtx) && debugSampled(&msg, aspect.SampleRate)
 }
 
 // Before is executed before node processing. It logs the incoming message
@@ -97,7 +121,7 @@ func (aspect *NodeDebug) PointCut(nodeCtx types.NodeCtx, msg types.RuleMsg, rela
 // Before 在节点处理之前执行。它异步记录传入消息和上下文信息，避免阻塞执行。
 func (aspect *NodeDebug) Before(nodeCtx types.NodeCtx, msg types.RuleMsg, relationType string) (types.RuleMsg, error) {
 	//异步记录In日志
-	fmt.Println("Before:", nodeCtx.Id(), nodeCtx.Type(), relationType, msg.GetInput())
+	fmt.Println("Before:", nodeCtx.Id(), nodeCtx.Type(), relationType, nodeCtx.Config().Redact(msg.GetInput()))
 	return msg, nil
 }
 
@@ -106,6 +130,6 @@ func (aspect *NodeDebug) Before(nodeCtx types.NodeCtx, msg types.RuleMsg, relati
 //
 // After 在节点处理之后执行。它记录传出消息和处理过程中发生的任何错误。
 func (aspect *NodeDebug) After(nodeCtx types.NodeCtx, msg types.RuleMsg, relationType string) (types.RuleMsg, error) {
-	fmt.Println("After:", nodeCtx.Id(), nodeCtx.Type(), relationType, msg.GetInput())
+	fmt.Println("After:", nodeCtx.Id(), nodeCtx.Type(), relationType, nodeCtx.Config().Redact(msg.GetInput()))
 	return msg, nil
 }
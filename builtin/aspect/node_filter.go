@@ -0,0 +1,71 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package aspect
+
+import (
+	"slices"
+
+	"github.com/bittoy/rule/types"
+)
+
+// NodeFilter restricts a node aspect's PointCut to an allow-list of node IDs
+// and/or node types. An empty filter matches every node.
+//
+// NodeFilter 将节点切面的 PointCut 限制在节点 ID 和/或节点类型白名单内。
+// 空过滤器匹配所有节点。
+//
+// Usage:
+// 使用方法：
+//
+//	// Only debug exprSwitch nodes
+//	// 只调试 exprSwitch 节点
+//	debug := NewNodeDebug(NodeFilter{NodeTypes: []types.NodeType{types.RuleSubTypeExprSwitch}})
+type NodeFilter struct {
+	// NodeIds is the allow-list of node IDs  节点 ID 白名单
+	NodeIds []string
+	// NodeTypes is the allow-list of node types  节点类型白名单
+	NodeTypes []types.NodeType
+}
+
+// IsEmpty reports whether no filter is configured.
+// IsEmpty 返回是否未配置任何过滤条件。
+func (f NodeFilter) IsEmpty() bool {
+	return len(f.NodeIds) == 0 && len(f.NodeTypes) == 0
+}
+
+// Match reports whether the node matches the filter. A node matches if its ID
+// or its type is in the allow-list, or if the filter is empty.
+//
+// Match 返回节点是否匹配过滤器。节点 ID 或类型在白名单内，或过滤器为空时匹配。
+func (f NodeFilter) Match(nodeCtx types.NodeCtx) bool {
+	if f.IsEmpty() {
+		return true
+	}
+	if nodeCtx == nil {
+		return false
+	}
+	return slices.Contains(f.NodeIds, nodeCtx.Id()) || slices.Contains(f.NodeTypes, nodeCtx.Type())
+}
+
+// Copy returns a copy of the filter so that aspect instances don't share slices.
+// Copy 返回过滤器副本，避免切面实例之间共享切片。
+func (f NodeFilter) Copy() NodeFilter {
+	return NodeFilter{
+		NodeIds:   slices.Clone(f.NodeIds),
+		NodeTypes: slices.Clone(f.NodeTypes),
+	}
+}
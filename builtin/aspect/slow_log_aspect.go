@@ -0,0 +1,122 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package aspect
+
+import (
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/bittoy/rule/types"
+)
+
+var (
+	// Compile-time check SlowLogAspect implements types.ChainBeforeAspect.
+	_ types.ChainBeforeAspect = (*SlowLogAspect)(nil)
+	// Compile-time check SlowLogAspect implements types.CompletedAspect.
+	_ types.CompletedAspect = (*SlowLogAspect)(nil)
+)
+
+// SlowLogAspect is a chain aspect that measures the total chain processing time and logs
+// the message input and the duration when it exceeds a threshold. Only a sampled fraction
+// of the slow messages is logged to limit the overhead under load.
+//
+// SlowLogAspect 是一个规则链切面，测量规则链的总处理时间，超过阈值时记录消息输入和耗时。
+// 只记录慢消息中被采样的部分，以限制高负载下的开销。
+//
+// The measurement ends in Completed rather than After, so that slow runs which
+// failed are logged as well, together with their error.
+// 测量在 Completed 而不是 After 中结束，因此失败的慢执行也会连同错误一起被记录。
+//
+// Usage:
+// 使用方法：
+//
+//	slowLog := NewSlowLogAspect(200*time.Millisecond, 0.1)
+//	engine, err := engine.NewChainEngine(def, engine.WithAspects(slowLog))
+type SlowLogAspect struct {
+	// Threshold is the duration above which a message is considered slow  超过该耗时的消息视为慢消息
+	Threshold time.Duration
+	// SampleRate is the fraction of slow messages logged, between 0 and 1  记录慢消息的比例，介于 0 和 1 之间
+	SampleRate float64
+
+	// starts holds the start time of the running messages by message id  按消息 id 保存运行中消息的开始时间
+	starts sync.Map
+}
+
+// NewSlowLogAspect creates a new slow log aspect.
+//
+// NewSlowLogAspect 创建新的慢日志切面。
+func NewSlowLogAspect(threshold time.Duration, sampleRate float64) *SlowLogAspect {
+	return &SlowLogAspect{
+		Threshold:  threshold,
+		SampleRate: sampleRate,
+	}
+}
+
+// Order returns the execution order of this aspect. Lower values execute earlier.
+// SlowLogAspect has order 1, so the time spent in the other chain aspects is measured too.
+//
+// Order 返回此切面的执行顺序。值越低，执行越早。
+// SlowLogAspect 的顺序为 1，因此其他规则链切面的耗时也会被计入。
+func (aspect *SlowLogAspect) Order() int {
+	return 1
+}
+
+// New creates a new instance of the slow log aspect with the same threshold and sample rate.
+//
+// New 创建具有相同阈值和采样率的慢日志切面新实例。
+func (aspect *SlowLogAspect) New() types.Aspect {
+	return NewSlowLogAspect(aspect.Threshold, aspect.SampleRate)
+}
+
+// Type returns the unique identifier for this aspect type.
+//
+// Type 返回此切面类型的唯一标识符。
+func (aspect *SlowLogAspect) Type() string {
+	return "slowLog"
+}
+
+// PointCut applies the aspect to every chain when messages can be sampled.
+//
+// PointCut 在可以采样消息时应用于所有规则链。
+func (aspect *SlowLogAspect) PointCut(chainCtx types.ChainCtx, msg types.RuleMsg) bool {
+	return aspect.SampleRate > 0
+}
+
+// Before records the start time of the message.
+//
+// Before 记录消息的开始时间。
+func (aspect *SlowLogAspect) Before(chainCtx types.ChainCtx, msg types.RuleMsg) (types.RuleMsg, error) {
+	aspect.starts.Store(msg.Id(), time.Now())
+	return msg, nil
+}
+
+// Completed logs the message when its processing time exceeds the threshold and it is sampled.
+//
+// Completed 在消息处理时间超过阈值且被采样时记录日志。
+func (aspect *SlowLogAspect) Completed(chainCtx types.ChainCtx, msg types.RuleMsg, err error) {
+	start, ok := aspect.starts.LoadAndDelete(msg.Id())
+	if !ok {
+		return
+	}
+	duration := time.Since(start.(time.Time))
+	if duration < aspect.Threshold || rand.Float64() >= aspect.SampleRate {
+		return
+	}
+	chainCtx.Config().Logger.Printf("slow chain: chainId=%s msgId=%s duration=%s input=%v err=%v",
+		chainCtx.Id(), msg.Id(), duration, chainCtx.Config().Redact(msg.GetInput()), err)
+}
@@ -0,0 +1,130 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package aspect
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bittoy/rule/types"
+)
+
+// ValidationCategory classifies a chain validation error so editors can group and highlight problems.
+// ValidationCategory 对规则链验证错误进行分类，便于编辑器分组和高亮问题。
+type ValidationCategory string
+
+const (
+	// ValidationCategoryStructure the chain misses required nodes  规则链缺少必需的节点
+	ValidationCategoryStructure ValidationCategory = "structure"
+	// ValidationCategoryConnection a connection is invalid or missing  连接无效或缺失
+	ValidationCategoryConnection ValidationCategory = "connection"
+	// ValidationCategoryCycle the connections form a cycle  连接形成环
+	ValidationCategoryCycle ValidationCategory = "cycle"
+	// ValidationCategoryUnreachable a node is unreachable from the start node  节点从开始节点不可达
+	ValidationCategoryUnreachable ValidationCategory = "unreachable"
+	// ValidationCategoryDeadEnd a node other than an end node has no outgoing connection  非结束节点没有传出连接
+	ValidationCategoryDeadEnd ValidationCategory = "deadEnd"
+)
+
+// ValidationError is a single chain validation problem.
+// ValidationError 是单个规则链验证问题。
+type ValidationError struct {
+	// NodeId is the id of the problem node, empty for chain level problems  问题节点的 id，规则链级别问题为空
+	NodeId string
+	// Category is the category of the problem  问题的类别
+	Category ValidationCategory
+	// Message describes the problem  问题描述
+	Message string
+}
+
+// Error returns the problem description.
+// Error 返回问题描述。
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// ValidationErrors is the list of problems collected by ValidateChainAll.
+// ValidationErrors 是 ValidateChainAll 收集的问题列表。
+type ValidationErrors []*ValidationError
+
+// Error joins the problem descriptions.
+// Error 拼接所有问题描述。
+func (errs ValidationErrors) Error() string {
+	messages := make([]string, 0, len(errs))
+	for _, err := range errs {
+		messages = append(messages, err.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Unwrap returns the problems, so errors.Is and errors.As inspect each of them.
+// Unwrap 返回所有问题，使 errors.Is 和 errors.As 可以逐个检查。
+func (errs ValidationErrors) Unwrap() []error {
+	list := make([]error, 0, len(errs))
+	for _, err := range errs {
+		list = append(list, err)
+	}
+	return list
+}
+
+// ValidateChainAll runs the built-in chain checks and collects every problem instead of
+// stopping at the first one, so an editor can show all issues at once.
+// Initialization keeps the fail-fast checks registered in ChainRules. The connections are checked against
+// the relations the components of registry declare, see types.RelationsGetter, usually Config.ComponentsRegistry.
+//
+// ValidateChainAll 执行内置的规则链检查并收集所有问题，而不是在第一个问题处停止，
+// 便于编辑器一次展示全部问题。初始化时仍使用 ChainRules 中注册的快速失败检查。
+// 连接按 registry 中组件声明的关系进行检查，参见 types.RelationsGetter，通常为 Config.ComponentsRegistry。
+//
+// Usage:
+// 使用方法：
+//
+//	if errs := aspect.ValidateChainAll(&chain, config.ComponentsRegistry); len(errs) > 0 {
+//		for _, err := range errs {
+//			fmt.Println(err.NodeId, err.Category, err.Message)
+//		}
+//	}
+func ValidateChainAll(chain *types.Chain, registry types.ComponentRegistry) ValidationErrors {
+	if chain == nil {
+		return nil
+	}
+	c := &validationCollector{}
+	collectChainCycles(chain, c)
+	collectChainNode(registry, chain, c)
+	collectChainReachability(registry, chain, c)
+	return c.errs
+}
+
+// validationCollector collects validation errors, in fail-fast mode it stops at the first one.
+type validationCollector struct {
+	failFast bool
+	errs     ValidationErrors
+}
+
+// add records a problem and reports whether the check should stop.
+func (c *validationCollector) add(nodeId string, category ValidationCategory, format string, args ...any) bool {
+	c.errs = append(c.errs, &ValidationError{NodeId: nodeId, Category: category, Message: fmt.Sprintf(format, args...)})
+	return c.failFast
+}
+
+// err returns the first collected problem, or nil.
+func (c *validationCollector) err() error {
+	if len(c.errs) == 0 {
+		return nil
+	}
+	return c.errs[0]
+}
@@ -0,0 +1,336 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package variable provides the VariableCenter, which resolves named variables
+// for a request from the input, registered fetchers or compute functions,
+// with per-request caching, TTL and dependency cycle detection.
+//
+// Package variable 提供 VariableCenter，用于从输入、注册的获取函数或计算函数中
+// 解析请求的命名变量，支持请求级缓存、TTL 和依赖环检测。
+package variable
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ---------------------------
+// Types / Meta
+// ---------------------------
+
+// VariableType is descriptive only (no strict runtime enforcement here)
+type VariableType string
+
+const (
+	TypeString VariableType = "string"
+	TypeInt    VariableType = "int"
+	TypeFloat  VariableType = "float"
+	TypeBool   VariableType = "bool"
+	TypeMap    VariableType = "map"
+	TypeAny    VariableType = "any"
+)
+
+// StopType for future extension (not used directly here)
+type StopType int
+
+// VariableMeta describes a variable
+type VariableMeta struct {
+	Key         string       // e.g. "user.age" or "device.riskScore"
+	Name        string       // human readable
+	Type        VariableType // type hint
+	Category    string       // domain: "user","device",...
+	Source      string       // textual source description
+	FetcherName string       // which fetcher to use if any
+	ComputeName string       // which compute function to use (optional)
+	Depends     []string     // dependent variable keys
+	Cached      bool         // whether we can cache result in request cache
+	TTLSeconds  int          // per-request TTL (0 = no expiry within request)
+	Version     string
+	Desc        string
+}
+
+// ---------------------------
+// Context (per request)
+// ---------------------------
+
+// VarContext holds the state of the variable resolution of a request. It is safe for concurrent use,
+// so the variables of a request can be resolved in parallel with Get.
+type VarContext struct {
+	Input map[string]any // raw input (from request)
+	// Per-request cache and meta
+	cacheMu sync.RWMutex
+	cache   map[string]cacheValue
+	// trace of accessed variables (for explainability), in completion order.
+	// Read it once the resolution is done, or use Traces while variables are still resolved concurrently.
+	Trace   []string
+	traceMu sync.Mutex
+}
+
+type cacheValue struct {
+	val       any
+	timestamp time.Time
+	ttl       int // seconds
+}
+
+// NewVarContext creates a new context for a request
+func NewVarContext(input map[string]any) *VarContext {
+	return &VarContext{
+		Input: input,
+		cache: make(map[string]cacheValue),
+		Trace: make([]string, 0, 32),
+	}
+}
+
+// get from cache (thread-safe)
+func (vc *VarContext) getCached(key string) (any, bool) {
+	vc.cacheMu.RLock()
+	defer vc.cacheMu.RUnlock()
+	cv, ok := vc.cache[key]
+	if !ok {
+		return nil, false
+	}
+	if cv.ttl > 0 {
+		if time.Since(cv.timestamp) > time.Duration(cv.ttl)*time.Second {
+			// expired
+			return nil, false
+		}
+	}
+	return cv.val, true
+}
+
+// set cache
+func (vc *VarContext) setCache(key string, val any, ttl int) {
+	vc.cacheMu.Lock()
+	defer vc.cacheMu.Unlock()
+	vc.cache[key] = cacheValue{val: val, timestamp: time.Now(), ttl: ttl}
+}
+
+// add trace (thread-safe)
+func (vc *VarContext) addTrace(key string) {
+	vc.traceMu.Lock()
+	defer vc.traceMu.Unlock()
+	vc.Trace = append(vc.Trace, key)
+}
+
+// Traces returns a copy of the trace, safe to call while variables are resolved concurrently
+func (vc *VarContext) Traces() []string {
+	vc.traceMu.Lock()
+	defer vc.traceMu.Unlock()
+	return append([]string(nil), vc.Trace...)
+}
+
+// resolvePath is the chain of variables being resolved on a path of dependencies, carried in the context
+// so concurrent Get calls on the same VarContext each detect the cycles of their own path
+type resolvePath struct {
+	key    string
+	parent *resolvePath
+}
+
+// resolvePathKey is the context key of the resolvePath
+type resolvePathKey struct{}
+
+// contains reports whether key is being resolved on the path
+func (p *resolvePath) contains(key string) bool {
+	for ; p != nil; p = p.parent {
+		if p.key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// ---------------------------
+// Fetcher / Compute function interfaces
+// ---------------------------
+
+type FetcherFunc func(ctx context.Context, vc *VarContext, meta VariableMeta) (any, error)
+type ComputeFunc func(ctx context.Context, vc *VarContext, meta VariableMeta, vcCenter *VariableCenter) (any, error)
+
+// ---------------------------
+// VariableCenter
+// ---------------------------
+
+type VariableCenter struct {
+	metasMu sync.RWMutex
+	metas   map[string]VariableMeta
+
+	fetchersMu sync.RWMutex
+	fetchers   map[string]FetcherFunc
+
+	computesMu sync.RWMutex
+	computes   map[string]ComputeFunc
+}
+
+func NewVariableCenter() *VariableCenter {
+	return &VariableCenter{
+		metas:    make(map[string]VariableMeta),
+		fetchers: make(map[string]FetcherFunc),
+		computes: make(map[string]ComputeFunc),
+	}
+}
+
+// RegisterMeta registers a variable meta (overwrite allowed)
+func (vc *VariableCenter) RegisterMeta(meta VariableMeta) {
+	vc.metasMu.Lock()
+	defer vc.metasMu.Unlock()
+	vc.metas[meta.Key] = meta
+}
+
+// GetMeta gets meta, ok
+func (vc *VariableCenter) GetMeta(key string) (VariableMeta, bool) {
+	vc.metasMu.RLock()
+	defer vc.metasMu.RUnlock()
+	m, ok := vc.metas[key]
+	return m, ok
+}
+
+// RegisterFetcher registers a named fetcher
+func (vc *VariableCenter) RegisterFetcher(name string, fn FetcherFunc) {
+	vc.fetchersMu.Lock()
+	defer vc.fetchersMu.Unlock()
+	vc.fetchers[name] = fn
+}
+
+// RegisterCompute registers a named compute function
+func (vc *VariableCenter) RegisterCompute(name string, fn ComputeFunc) {
+	vc.computesMu.Lock()
+	defer vc.computesMu.Unlock()
+	vc.computes[name] = fn
+}
+
+// ---------------------------
+// Core: Get(key)
+// ---------------------------
+
+var ErrVariableNotFound = errors.New("variable meta not found")
+var ErrCycleDetected = errors.New("cycle detected in variable dependencies")
+
+// Get resolves a variable value for a VarContext.
+// It handles cache, compute (depends), fetcher, TTL, and cycle detection.
+// Get may be called concurrently on the same VarContext. The dependencies being resolved are tracked in
+// ctx, so compute functions must resolve their dependencies with the ctx they receive.
+func (vc *VariableCenter) Get(ctx context.Context, vctx *VarContext, key string) (any, error) {
+	// 1) look meta
+	meta, ok := vc.GetMeta(key)
+	if !ok {
+		// fallback: if key present in input, return it (useful convenience)
+		if vctx != nil {
+			if val, has := vctx.Input[key]; has {
+				return val, nil
+			}
+		}
+		return nil, ErrVariableNotFound
+	}
+
+	// 2) check per-request cache
+	if meta.Cached && vctx != nil {
+		if val, ok := vctx.getCached(key); ok {
+			// append trace and return
+			if vctx != nil {
+				vctx.addTrace(key + " (cached)")
+			}
+			return val, nil
+		}
+	}
+
+	// 3) cycle detection
+	path, _ := ctx.Value(resolvePathKey{}).(*resolvePath)
+	if path.contains(key) {
+		return nil, ErrCycleDetected
+	}
+	ctx = context.WithValue(ctx, resolvePathKey{}, &resolvePath{key: key, parent: path})
+
+	// 4) If compute function exists, call it (after resolving dependencies if needed)
+	if meta.ComputeName != "" {
+		vc.computesMu.RLock()
+		comp, ok := vc.computes[meta.ComputeName]
+		vc.computesMu.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("compute function %s not registered", meta.ComputeName)
+		}
+		val, err := comp(ctx, vctx, meta, vc)
+		if err != nil {
+			return nil, err
+		}
+		// cache if allowed
+		if meta.Cached && vctx != nil {
+			vctx.setCache(key, val, meta.TTLSeconds)
+		}
+		if vctx != nil {
+			vctx.addTrace(key)
+		}
+		return val, nil
+	}
+
+	// 5) else call fetcher
+	if meta.FetcherName == "" {
+		return nil, fmt.Errorf("no fetcher or compute for variable %s", key)
+	}
+	vc.fetchersMu.RLock()
+	fetcher, ok := vc.fetchers[meta.FetcherName]
+	vc.fetchersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("fetcher %s not registered", meta.FetcherName)
+	}
+	val, err := fetcher(ctx, vctx, meta)
+	if err != nil {
+		return nil, err
+	}
+	if meta.Cached && vctx != nil {
+		vctx.setCache(key, val, meta.TTLSeconds)
+	}
+	if vctx != nil {
+		vctx.addTrace(key)
+	}
+	return val, nil
+}
+
+// ---------------------------
+// Utilities: resolve dependencies helper
+// ---------------------------
+
+// ResolveDependencies fetches all variables in a list and returns a map (non-failing: returns error on first fail)
+func (vc *VariableCenter) ResolveDependencies(ctx context.Context, vctx *VarContext, deps []string) (map[string]any, error) {
+	out := make(map[string]any, len(deps))
+	for _, k := range deps {
+		val, err := vc.Get(ctx, vctx, k)
+		if err != nil {
+			return nil, err
+		}
+		out[k] = val
+	}
+	return out, nil
+}
+
+// ---------------------------
+// Built-in fetcher (input map)
+// ---------------------------
+
+// InputFetcher resolves a variable directly from the request input by its key.
+func InputFetcher(ctx context.Context, vctx *VarContext, meta VariableMeta) (any, error) {
+	if vctx == nil {
+		return nil, fmt.Errorf("nil varcontext")
+	}
+	// direct key in input map usually stored under top-level key (e.g., "user.id")
+	if val, ok := vctx.Input[meta.Key]; ok {
+		return val, nil
+	}
+	// not found in input map
+	return nil, fmt.Errorf("input key %s not present", meta.Key)
+}
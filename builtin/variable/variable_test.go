@@ -0,0 +1,115 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package variable
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/rulego/rulego/test/assert"
+)
+
+func TestVariableCenter(t *testing.T) {
+	vc := NewVariableCenter()
+	vc.RegisterFetcher("input", InputFetcher)
+	var computed int
+	vc.RegisterCompute("double", func(ctx context.Context, vctx *VarContext, meta VariableMeta, center *VariableCenter) (any, error) {
+		computed++
+		deps, err := center.ResolveDependencies(ctx, vctx, meta.Depends)
+		if err != nil {
+			return nil, err
+		}
+		return deps["x"].(int) * 2, nil
+	})
+	vc.RegisterMeta(VariableMeta{Key: "x", FetcherName: "input"})
+	vc.RegisterMeta(VariableMeta{Key: "x2", ComputeName: "double", Depends: []string{"x"}, Cached: true})
+	vc.RegisterMeta(VariableMeta{Key: "a", ComputeName: "double", Depends: []string{"b"}})
+	vc.RegisterMeta(VariableMeta{Key: "b", ComputeName: "double", Depends: []string{"a"}})
+
+	t.Run("FetchAndCompute", func(t *testing.T) {
+		vctx := NewVarContext(map[string]any{"x": 3})
+		val, err := vc.Get(context.Background(), vctx, "x2")
+		assert.Nil(t, err)
+		assert.Equal(t, 6, val)
+		val, err = vc.Get(context.Background(), vctx, "x2")
+		assert.Nil(t, err)
+		assert.Equal(t, 6, val)
+		assert.Equal(t, 1, computed)
+	})
+
+	t.Run("InputFallback", func(t *testing.T) {
+		vctx := NewVarContext(map[string]any{"y": "v"})
+		val, err := vc.Get(context.Background(), vctx, "y")
+		assert.Nil(t, err)
+		assert.Equal(t, "v", val)
+		_, err = vc.Get(context.Background(), vctx, "z")
+		assert.Equal(t, ErrVariableNotFound, err)
+	})
+
+	t.Run("Cycle", func(t *testing.T) {
+		_, err := vc.Get(context.Background(), NewVarContext(nil), "a")
+		assert.Equal(t, ErrCycleDetected, err)
+	})
+}
+
+// TestVariableCenterParallel resolves overlapping dependency graphs concurrently on one VarContext, run it with -race.
+func TestVariableCenterParallel(t *testing.T) {
+	vc := NewVariableCenter()
+	vc.RegisterFetcher("input", InputFetcher)
+	vc.RegisterCompute("sum", func(ctx context.Context, vctx *VarContext, meta VariableMeta, center *VariableCenter) (any, error) {
+		deps, err := center.ResolveDependencies(ctx, vctx, meta.Depends)
+		if err != nil {
+			return nil, err
+		}
+		sum := 0
+		for _, v := range deps {
+			sum += v.(int)
+		}
+		return sum, nil
+	})
+	for _, key := range []string{"a", "b", "c"} {
+		vc.RegisterMeta(VariableMeta{Key: key, FetcherName: "input", Cached: true})
+	}
+	vc.RegisterMeta(VariableMeta{Key: "ab", ComputeName: "sum", Depends: []string{"a", "b"}, Cached: true})
+	vc.RegisterMeta(VariableMeta{Key: "bc", ComputeName: "sum", Depends: []string{"b", "c"}, Cached: true})
+	vc.RegisterMeta(VariableMeta{Key: "abc", ComputeName: "sum", Depends: []string{"ab", "bc", "a"}})
+
+	vctx := NewVarContext(map[string]any{"a": 1, "b": 2, "c": 3})
+	keys := []string{"abc", "ab", "bc", "abc", "a", "bc"}
+	want := map[string]int{"a": 1, "ab": 3, "bc": 5, "abc": 9}
+	var wg sync.WaitGroup
+	errs := make([]error, 50)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := keys[i%len(keys)]
+			val, err := vc.Get(context.Background(), vctx, key)
+			if err == nil && val != want[key] {
+				err = ErrVariableNotFound
+			}
+			errs[i] = err
+			_ = vctx.Traces()
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		assert.Nil(t, err)
+	}
+	assert.True(t, len(vctx.Trace) >= len(errs))
+}
@@ -69,6 +69,13 @@ func (n *nodeUtils) IsInitNetResource(_ types.Config, configuration types.Config
 	return ok
 }
 
+// IsLint 返回是否是规则链检查的初始化，此时组件只校验配置，不产生副作用
+// IsLint reports whether the node is initialized by a chain lint, validating its configuration without side effects.
+func (n *nodeUtils) IsLint(configuration types.Configuration) bool {
+	_, ok := configuration[types.NodeConfigurationKeyLint]
+	return ok
+}
+
 // TrimStrings 去除配置中所有字符串值的前后空格
 // 遍历 Configuration 中的所有值，如果是字符串类型则去除前后空格
 func (n *nodeUtils) TrimStrings(config types.Configuration) {
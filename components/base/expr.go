@@ -0,0 +1,328 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package base
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/bittoy/rule/types"
+	"github.com/bittoy/rule/utils/cast"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/ast"
+	"github.com/expr-lang/expr/vm"
+)
+
+// exprFunctions are the helpers available in every expr script.
+//
+//   - asString(v): converts v to its string form, e.g. asString(student) == "3"
+//   - asNumber(v): converts v to float64, e.g. asNumber(student) == 3
+//
+// exprFunctions 是所有 expr 脚本可用的辅助函数。
+var exprFunctions = []expr.Option{
+	expr.Function("asString", func(params ...any) (any, error) {
+		return cast.ToStringE(params[0])
+	}, new(func(any) string)),
+	expr.Function("asNumber", func(params ...any) (any, error) {
+		return cast.ToFloat64E(params[0])
+	}, new(func(any) float64)),
+}
+
+// maxRunChainDepth is the number of nested runChain calls allowed, so chains running each other do not recurse forever
+const maxRunChainDepth = 8
+
+// runChainDepthKey is the message attachment key of the runChain nesting depth of a message
+type runChainDepthKey struct{}
+
+// ExprOptions returns the compile options shared by expr based components:
+// undefined variables are allowed and the asString/asNumber helpers are registered.
+// When config.Clock is set, the now() builtin returns its time, see types.Config.Clock.
+// Additional options, like the expected output kind, are appended.
+//
+// ExprOptions 返回基于 expr 的组件共用的编译选项：允许未定义变量，并注册 asString/asNumber 辅助函数。
+// 设置 config.Clock 时，now() 内置函数返回该时钟的时间，
+// 参见 types.Config.Clock。额外的选项（如期望的输出类型）会追加在后面。
+func (n *nodeUtils) ExprOptions(config types.Config, opts ...expr.Option) []expr.Option {
+	options := []expr.Option{expr.AllowUndefinedVariables()}
+	options = append(options, exprFunctions...)
+	if clock := config.Clock; clock != nil {
+		options = append(options, expr.Function("now", func(params ...any) (any, error) {
+			return clock.Now(), nil
+		}, new(func() time.Time)))
+	}
+	return append(options, opts...)
+}
+
+// ExprEnv returns the evaluation environment for an expr program according to
+// config.ExprCoercion. The message input itself is never modified.
+//
+// ExprEnv 根据 config.ExprCoercion 返回 expr 程序的求值环境。不会修改消息输入本身。
+//
+// Coercion modes:
+// 转换模式：
+//   - ExprCoercionNone: the input is used as is  直接使用输入
+//   - ExprCoercionNumber: top-level numeric strings become numbers,
+//     so "3" and 3 both match student == 3  顶层数值字符串转为数字，"3" 和 3 都能匹配 student == 3
+//
+// The input fields are available at the top level, e.g. temperature > 50, and the whole input under
+// types.MsgKey, e.g. msg.temperature > 50. The message id, timestamp, type and data type are available under
+// types.IdKey, types.TsKey, types.MsgTypeKey and types.DataTypeKey, e.g. msgType == 'TELEMETRY'; an input
+// field of the same name takes precedence over them. The type builtin of expr is kept, e.g. type(amount) == 'int'.
+// 输入字段可以在顶层访问，例如 temperature > 50，整个输入可以通过 types.MsgKey 访问，例如 msg.temperature > 50。
+// 消息 id、时间戳、类型和数据类型可以通过 types.IdKey、types.TsKey、types.MsgTypeKey 和 types.DataTypeKey 访问，
+// 例如 msgType == 'TELEMETRY'；同名的输入字段优先于它们。expr 的 type 内置函数保持可用，例如 type(amount) == 'int'。
+//
+// The global properties are available under config.GetScriptGlobalKey(), e.g. global.env, and the
+// message metadata under config.GetScriptMetadataKey(). They take precedence over input fields of the same name.
+// 全局属性可以通过 config.GetScriptGlobalKey() 访问，例如 global.env，消息元数据可以通过
+// config.GetScriptMetadataKey() 访问。它们优先于同名的输入字段。
+//
+// The outputs of the executed nodes are available under types.NodesKey, e.g. nodes["s5"].score,
+// unless the input has a field of that name, see types.RuleMsg.GetNodeOutput.
+// 已执行节点的输出可以通过 types.NodesKey 访问，例如 nodes["s5"].score，除非输入中有同名字段，
+// 参见 types.RuleMsg.GetNodeOutput。
+//
+// The chain result accumulated by the nodes is available under types.ResultKey, e.g. result.score, and
+// updated with addScore(n), which returns the new score, addReason(reason) and addTag(tag), which return
+// true so they compose in filters, e.g. amount > 1000 && addScore(20) > 0, see types.RuleMsg.Result.
+// 各节点累计的规则链结果可以通过 types.ResultKey 访问，例如 result.score，并通过 addScore(n)（返回新的评分）、
+// addReason(reason) 和 addTag(tag)（返回 true，便于在过滤表达式中组合）更新，例如 amount > 1000 && addScore(20) > 0，
+// 参见 types.RuleMsg.Result。
+//
+// The message headers are available read-only under types.HeadersKey, e.g. headers.contentType, unless the
+// input has a field of that name, see types.RuleMsg.Headers.
+// 消息头以只读方式通过 types.HeadersKey 访问，例如 headers.contentType，除非输入中有同名字段，参见 types.RuleMsg.Headers。
+//
+// When config.EnginePool is set, runChain(chainId, input) runs the chain of the pool synchronously with
+// input and returns its output, e.g. runChain("scoring", {'amount': amount}).score > 80, see runChain.
+// 设置 config.EnginePool 时，runChain(chainId, input) 使用 input 同步执行池中的规则链并返回其输出，
+// 例如 runChain("scoring", {'amount': amount}).score > 80，参见 runChain。
+func (n *nodeUtils) ExprEnv(ctx context.Context, config types.Config, msg types.RuleMsg) map[string]any {
+	input := msg.GetInput()
+	vars := scriptVars(ctx, config, msg, input, nil)
+	env := make(map[string]any, len(input)+len(vars))
+	for k, v := range input {
+		env[k] = coerce(config, v)
+	}
+	for k, v := range vars {
+		env[k] = v
+	}
+	return env
+}
+
+// ExprProgramEnv returns the evaluation environment for program like ExprEnv, holding only the variables
+// program reads. When program reads no message variable and config.ExprCoercion is ExprCoercionNone, the
+// message input is returned as is, without copy. For a message with a protobuf payload only the payload
+// fields used by program are converted, see types.NewProtoRuleMsg, unless program reads the whole input
+// under types.MsgKey.
+//
+// ExprProgramEnv 与 ExprEnv 一样返回 program 的求值环境，但只包含 program 读取的变量。当 program 不读取任何消息变量
+// 且 config.ExprCoercion 为 ExprCoercionNone 时，直接返回消息输入，不做复制。对于携带 protobuf 负载的消息，
+// 只转换 program 使用到的负载字段，见 types.NewProtoRuleMsg，除非 program 通过 types.MsgKey 读取整个输入。
+func (n *nodeUtils) ExprProgramEnv(ctx context.Context, config types.Config, program *vm.Program, msg types.RuleMsg) map[string]any {
+	names := exprIdentifiers(program)
+	if slices.Contains(names, envIdentifier) {
+		return n.ExprEnv(ctx, config, msg)
+	}
+	var input map[string]any
+	if msg.Payload() == nil || slices.Contains(names, types.MsgKey) {
+		input = msg.GetInput()
+	} else {
+		input = make(map[string]any, len(names))
+		for _, name := range names {
+			if v, ok := msg.Field(name); ok {
+				input[name] = v
+			}
+		}
+		if metadata, ok := msg.Field(types.MetadataKey); ok {
+			input[types.MetadataKey] = metadata
+		}
+	}
+	vars := scriptVars(ctx, config, msg, input, names)
+	if len(vars) == 0 && config.ExprCoercion != types.ExprCoercionNumber {
+		return input
+	}
+	env := make(map[string]any, len(names))
+	for _, name := range names {
+		if v, ok := vars[name]; ok {
+			env[name] = v
+		} else if v, ok := input[name]; ok {
+			env[name] = coerce(config, v)
+		}
+	}
+	return env
+}
+
+// envIdentifier is the expr variable of the whole environment, e.g. $env["temperature"], a program reading it
+// gets the full environment.
+const envIdentifier = "$env"
+
+// exprIdentifiers returns the names of the variables read by program, a name may repeat.
+func exprIdentifiers(program *vm.Program) []string {
+	var visitor identifierVisitor
+	node := program.Node()
+	ast.Walk(&node, &visitor)
+	return visitor.names
+}
+
+// identifierVisitor collects the identifier names of an expression.
+type identifierVisitor struct {
+	names []string
+}
+
+func (v *identifierVisitor) Visit(node *ast.Node) {
+	if id, ok := (*node).(*ast.IdentifierNode); ok {
+		v.names = append(v.names, id.Value)
+	}
+}
+
+// coerce returns v converted according to config.ExprCoercion, see ExprEnv.
+func coerce(config types.Config, v any) any {
+	s, ok := v.(string)
+	if !ok || config.ExprCoercion != types.ExprCoercionNumber {
+		return v
+	}
+	if i, err := strconv.Atoi(s); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil && !math.IsNaN(f) && !math.IsInf(f, 0) {
+		return f
+	}
+	return v
+}
+
+// scriptVars returns the global properties and the message metadata under their configured script names,
+// the node outputs under types.NodesKey, the message variables and the functions, see ExprEnv. The metadata
+// is omitted when the input already holds it under the same name, the node outputs, the message variables
+// and the functions when the input has a field of their name. When names is not nil only the variables
+// of these names are built, so an expression pays only for the variables it reads.
+func scriptVars(ctx context.Context, config types.Config, msg types.RuleMsg, input map[string]any, names []string) map[string]any {
+	var vars map[string]any
+	wanted := func(name string) bool {
+		return names == nil || slices.Contains(names, name)
+	}
+	set := func(name string, value any) {
+		if vars == nil {
+			vars = make(map[string]any, 4)
+		}
+		vars[name] = value
+	}
+	// setVar sets a variable that an input field of the same name takes precedence over
+	setVar := func(name string, value func() any) {
+		if _, ok := input[name]; !ok && wanted(name) {
+			set(name, value())
+		}
+	}
+	setVar(types.MsgKey, func() any { return input })
+	setVar(types.IdKey, func() any { return msg.Id() })
+	setVar(types.TsKey, func() any { return msg.Ts() })
+	setVar(types.MsgTypeKey, func() any {
+		msgType, _ := msg.Field(types.MsgTypeKey)
+		return msgType
+	})
+	setVar(types.DataTypeKey, func() any {
+		dataType, _ := msg.Field(types.DataTypeKey)
+		return dataType
+	})
+	if nodes := msg.NodeOutputs(); len(nodes) > 0 {
+		setVar(types.NodesKey, func() any { return nodes })
+	}
+	setVar(types.ResultKey, func() any {
+		result := msg.Result()
+		return map[string]any{types.ScoreKey: result.Score, types.ReasonsKey: result.Reasons, types.TagsKey: result.Tags}
+	})
+	setVar("addScore", func() any {
+		return func(n any) (int, error) {
+			score, err := cast.ToIntE(n)
+			if err != nil {
+				return 0, err
+			}
+			return msg.AddScore(score), nil
+		}
+	})
+	setVar("addReason", func() any {
+		return func(reason string) bool {
+			msg.AddReason(reason)
+			return true
+		}
+	})
+	setVar("addTag", func() any {
+		return func(tag string) bool {
+			msg.AddResultTag(tag)
+			return true
+		}
+	})
+	setVar(types.HeadersKey, func() any {
+		headers := msg.Headers()
+		if headers == nil {
+			headers = map[string]string{}
+		}
+		return headers
+	})
+	if config.EnginePool != nil {
+		setVar(types.RunChainKey, func() any { return runChain(ctx, config.EnginePool, msg) })
+	}
+	if globalKey := config.GetScriptGlobalKey(); len(config.Properties) > 0 && wanted(globalKey) {
+		set(globalKey, config.Properties.Values())
+	}
+	metadataKey := config.GetScriptMetadataKey()
+	if metadata, ok := input[types.MetadataKey]; ok && metadataKey != types.MetadataKey && wanted(metadataKey) {
+		set(metadataKey, metadata)
+	}
+	return vars
+}
+
+// runChain returns the runChain function of the expressions of msg: it runs the chain chainId of pool with a
+// new message of input, sharing the timestamp and the deadline of msg, and returns the chain output. The chain
+// runs with ctx, the context of the node evaluating the expression, so it is cancelled along with the message.
+// The input is not modified. Calls nest at most maxRunChainDepth deep, so chains running each other fail
+// instead of recursing forever.
+func runChain(ctx context.Context, pool types.EnginePool, msg types.RuleMsg) func(chainId string, input map[string]any) (map[string]any, error) {
+	return func(chainId string, input map[string]any) (map[string]any, error) {
+		depth, _ := msg.Attachment(runChainDepthKey{}).(int)
+		if depth >= maxRunChainDepth {
+			return nil, fmt.Errorf("runChain %s: nested more than %d deep", chainId, maxRunChainDepth)
+		}
+		engine, ok := pool.Get(chainId)
+		if !ok {
+			return nil, fmt.Errorf("runChain %s: chain not found", chainId)
+		}
+		subMsg := types.NewRuleMsg("", msg.Ts(), maps.Clone(input))
+		subMsg.SetAttachment(runChainDepthKey{}, depth+1)
+		for key, value := range msg.Headers() {
+			subMsg.SetHeader(key, value)
+		}
+		// The output of the called chain is needed right away, it must not be handed off
+		// 需要立即获得被调用规则链的输出，不能移交消息
+		runCtx := types.ContextWithSync(ctx)
+		if deadline, ok := msg.Deadline(); ok {
+			var cancel context.CancelFunc
+			runCtx, cancel = context.WithDeadline(runCtx, deadline)
+			defer cancel()
+		}
+		if err := engine.OnMsg(runCtx, subMsg); err != nil {
+			return nil, fmt.Errorf("runChain %s: %w", chainId, err)
+		}
+		return subMsg.GetChainOutput(), nil
+	}
+}
@@ -0,0 +1,90 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package base
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/bittoy/rule/types"
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+	"github.com/rulego/rulego/test/assert"
+)
+
+func compile(t *testing.T, config types.Config, script string) *vm.Program {
+	program, err := expr.Compile(script, NodeUtils.ExprOptions(config)...)
+	assert.Nil(t, err)
+	return program
+}
+
+// TestExprProgramEnv checks that the environment holds only the variables the program reads
+// and that the input is used as is when the program reads no message variable.
+func TestExprProgramEnv(t *testing.T) {
+	config := types.NewConfig()
+	config.Properties = types.Properties{"env": "prod"}
+	msg := types.NewMsgBuilder().WithType("TELEMETRY").WithData(map[string]any{"temperature": 60, "student": "3"}).Build()
+
+	env := NodeUtils.ExprProgramEnv(context.Background(), config, compile(t, config, "temperature > 50"), msg)
+	assert.True(t, reflect.ValueOf(env).Pointer() == reflect.ValueOf(msg.GetInput()).Pointer())
+
+	env = NodeUtils.ExprProgramEnv(context.Background(), config, compile(t, config, "msgType == 'TELEMETRY' && global.env == 'prod' && id != ''"), msg)
+	assert.Equal(t, map[string]any{"msgType": "TELEMETRY", "global": map[string]any{"env": "prod"}, "id": msg.Id()}, env)
+
+	program := compile(t, config, "type(student) == 'int' && student == 3")
+	out, err := vm.Run(program, NodeUtils.ExprProgramEnv(context.Background(), config, program, msg))
+	assert.Nil(t, err)
+	assert.Equal(t, false, out)
+	config.ExprCoercion = types.ExprCoercionNumber
+	out, err = vm.Run(program, NodeUtils.ExprProgramEnv(context.Background(), config, program, msg))
+	assert.Nil(t, err)
+	assert.Equal(t, true, out)
+	assert.Equal(t, "3", msg.GetInput()["student"])
+}
+
+// TestExprCoercion checks the asString and asNumber helpers, and that ExprCoercionNumber converts the top-level
+// numeric strings of the environment only.
+func TestExprCoercion(t *testing.T) {
+	config := types.NewConfig()
+	for script, want := range map[string]any{
+		"asString(3)":             "3",
+		"asString('3')":           "3",
+		"asNumber('3')":           3.0,
+		"asNumber(2.5)":           2.5,
+		"asString(3) == '3'":      true,
+		"asNumber('1.5') + 1 > 2": true,
+	} {
+		out, err := vm.Run(compile(t, config, script), nil)
+		assert.Nil(t, err)
+		assert.Equal(t, want, out)
+	}
+	_, err := vm.Run(compile(t, config, "asNumber('abc')"), nil)
+	assert.NotNil(t, err)
+
+	msg := types.NewRuleMsg("", 0, map[string]any{"i": "3", "f": "1.5", "s": "abc", "nan": "NaN", "nested": map[string]any{"i": "3"}})
+	env := NodeUtils.ExprEnv(context.Background(), config, msg)
+	assert.Equal(t, "3", env["i"])
+	config.ExprCoercion = types.ExprCoercionNumber
+	env = NodeUtils.ExprEnv(context.Background(), config, msg)
+	assert.Equal(t, 3, env["i"])
+	assert.Equal(t, 1.5, env["f"])
+	assert.Equal(t, "abc", env["s"])
+	assert.Equal(t, "NaN", env["nan"])
+	assert.Equal(t, map[string]any{"i": "3"}, env["nested"])
+	assert.Equal(t, "3", msg.GetInput()["i"])
+}
@@ -0,0 +1,61 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package base
+
+import (
+	"github.com/bittoy/rule/types"
+	"github.com/bittoy/rule/utils/js"
+)
+
+// JsVMPool returns the JavaScript VM pool of the config, or js.DefaultVMPool when it is not set.
+// JsVMPool 返回配置的 JavaScript VM 池，未设置时返回 js.DefaultVMPool。
+func (n *nodeUtils) JsVMPool(config types.Config) types.JsVMPool {
+	if config.JsVMPool != nil {
+		return config.JsVMPool
+	}
+	return js.DefaultVMPool
+}
+
+// JsParams returns the parameter list of the functions generated by JavaScript nodes:
+// msg, then the message metadata and the global properties under their configured names,
+// then the message headers under types.HeadersKey.
+// JsParams 返回 JavaScript 节点生成的函数的参数列表：msg，以及按配置名称命名的消息元数据和全局属性，
+// 然后是 types.HeadersKey 下的消息头。
+func (n *nodeUtils) JsParams(config types.Config) string {
+	return "msg, " + config.GetScriptMetadataKey() + ", " + config.GetScriptGlobalKey() + ", " + types.HeadersKey
+}
+
+// JsArgs returns the arguments matching JsParams for a message. For a message with a protobuf
+// payload msg is passed as a types.FieldReader, so only the fields the script reads are converted.
+// JsArgs 返回与 JsParams 对应的消息参数。对于携带 protobuf 负载的消息，msg 以 types.FieldReader 传入，
+// 只转换脚本读取的字段。
+func (n *nodeUtils) JsArgs(config types.Config, msg types.RuleMsg) []any {
+	global := config.Properties.Values()
+	if global == nil {
+		global = map[string]any{}
+	}
+	headers := msg.Headers()
+	if headers == nil {
+		headers = map[string]string{}
+	}
+	if msg.Payload() != nil {
+		metadata, _ := msg.Field(types.MetadataKey)
+		return []any{&msg, metadata, global, headers}
+	}
+	input := msg.GetInput()
+	return []any{input, input[types.MetadataKey], global, headers}
+}
@@ -20,8 +20,11 @@ import (
 	"context"
 	"errors"
 	"reflect"
+	"strings"
 
+	"github.com/bittoy/rule/components/base"
 	"github.com/bittoy/rule/types"
+	"github.com/bittoy/rule/utils/cast"
 	"github.com/bittoy/rule/utils/maps"
 
 	"github.com/expr-lang/expr"
@@ -63,10 +66,18 @@ type EndNodeConfiguration struct {
 // - 规则链的明确结束点 - Explicit end point of rule chains
 // - 触发特定的结束处理逻辑 - Trigger specific end processing logic
 // - 替代默认的分支结束行为 - Replace default branch ending behavior
+//
+// 节点累计的规则链结果（参见 types.RuleMsg.Result）会添加到脚本未设置的 score、reasons 和 tags 输出字段中。
+// The chain result accumulated by the nodes, see types.RuleMsg.Result, is added to the score, reasons and tags
+// output fields the script does not set.
 type EndNode struct {
 	// Config 节点配置
 	Config EndNodeConfiguration
 
+	// config 规则引擎配置
+	// config is the rule engine configuration
+	config types.Config
+
 	// program 用于高效评估的编译表达式
 	// program is the compiled expression for efficient evaluation
 	program *vm.Program
@@ -78,6 +89,18 @@ func (x *EndNode) Type() types.NodeType {
 	return types.RuleSubTypeEnd
 }
 
+// Category 返回组件类别
+// Category returns the component category.
+func (x *EndNode) Category() string {
+	return types.CategoryFlow
+}
+
+// Relations 返回空列表，组件结束规则链
+// Relations returns no relation, the component ends the chain.
+func (x *EndNode) Relations() []string {
+	return []string{}
+}
+
 // New creates a new instance.
 func (x *EndNode) New() types.Node {
 	return &EndNode{Config: EndNodeConfiguration{
@@ -87,12 +110,13 @@ func (x *EndNode) New() types.Node {
 
 // Init initializes the component.
 func (x *EndNode) Init(ruleConfig types.Config, configuration types.Configuration) error {
+	x.config = ruleConfig
 	err := maps.Map2Struct(configuration, &x.Config)
 	if err != nil {
 		return err
 	}
 
-	program, err := expr.Compile(x.Config.Script, expr.AllowUndefinedVariables(), expr.AsKind(reflect.Map))
+	program, err := expr.Compile(x.Config.Script, base.NodeUtils.ExprOptions(ruleConfig, expr.AsKind(reflect.Map))...)
 	if err != nil {
 		return err
 	}
@@ -103,13 +127,15 @@ func (x *EndNode) Init(ruleConfig types.Config, configuration types.Configuratio
 
 // OnMsg processes the incoming message and triggers the end callback.
 func (x *EndNode) OnMsg(ctx context.Context, msg types.RuleMsg) (next string, err error) {
-	out, err := vm.Run(x.program, msg.GetInput())
+	out, err := vm.Run(x.program, base.NodeUtils.ExprProgramEnv(ctx, x.config, x.program, msg))
 	if err != nil {
 		return "", err
 	}
 	if result, ok := out.(map[string]any); ok {
 		msg.ClearInnerData()
+		addResult(result, msg.Result())
 		msg.SetChainOutput(result)
+		msg.AddTag(outputTags(result)...)
 	} else {
 		return "", errors.New("返回类型不匹配")
 	}
@@ -118,3 +144,44 @@ func (x *EndNode) OnMsg(ctx context.Context, msg types.RuleMsg) (next string, er
 
 func (x *EndNode) Destroy() {
 }
+
+// outputTags returns the tags of a chain output, read case-insensitively from its "tags" field
+func outputTags(output map[string]any) []string {
+	for k, v := range output {
+		if !strings.EqualFold(k, types.TagsKey) {
+			continue
+		}
+		switch values := v.(type) {
+		case []string:
+			return values
+		case []any:
+			tags := make([]string, 0, len(values))
+			for _, value := range values {
+				tags = append(tags, cast.ToString(value))
+			}
+			return tags
+		}
+	}
+	return nil
+}
+
+// addResult adds the non-empty fields of the accumulated result to the output keys not set, compared case-insensitively
+func addResult(output map[string]any, result types.ChainResult) {
+	set := func(key string, value any) {
+		for k := range output {
+			if strings.EqualFold(k, key) {
+				return
+			}
+		}
+		output[key] = value
+	}
+	if result.Score != 0 {
+		set(types.ScoreKey, result.Score)
+	}
+	if len(result.Reasons) > 0 {
+		set(types.ReasonsKey, result.Reasons)
+	}
+	if len(result.Tags) > 0 {
+		set(types.TagsKey, result.Tags)
+	}
+}
@@ -0,0 +1,67 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bittoy/rule/types"
+	"github.com/rulego/rulego/test/assert"
+)
+
+// TestEnd checks that the end node sets the chain output from its script and adds the output tags to the message.
+func TestEnd(t *testing.T) {
+	end := func(script string, msg types.RuleMsg) error {
+		node := (&EndNode{}).New().(*EndNode)
+		configuration := types.Configuration{}
+		if script != "" {
+			configuration["script"] = script
+		}
+		assert.Nil(t, node.Init(types.NewConfig(), configuration))
+		relation, err := node.OnMsg(context.Background(), msg)
+		assert.Equal(t, "", relation)
+		return err
+	}
+	msg := types.NewRuleMsg("", 0, map[string]any{"amount": 2})
+	msg.SetPrivateVar("level", "high")
+	assert.Nil(t, end("{'amount': amount * 2, 'level': priVars.level, 'Tags': ['big', 1]}", msg))
+	assert.Equal(t, map[string]any{"amount": 4, "level": "high", "Tags": []any{"big", 1}}, msg.GetChainOutput())
+	assert.Equal(t, []string{"big", "1"}, msg.Tags())
+	assert.Equal(t, 0, len(msg.GetPrivateVars()))
+
+	msg = types.NewRuleMsg("", 0, nil)
+	assert.Nil(t, end("", msg))
+	assert.Equal(t, map[string]any{}, msg.GetChainOutput())
+
+	assert.NotNil(t, end("{'amount': amount * 2}", types.NewRuleMsg("", 0, map[string]any{"amount": "n/a"})))
+	assert.NotNil(t, (&EndNode{}).Init(types.NewConfig(), types.Configuration{"script": "{'amount': amount *}"}))
+}
+
+// TestEndResult checks that the end node adds the accumulated chain result to the chain output, unless the
+// script sets it.
+func TestEndResult(t *testing.T) {
+	node := &EndNode{}
+	assert.Nil(t, node.Init(types.NewConfig(), types.Configuration{"script": "{'Score': 5}"}))
+	msg := types.NewRuleMsg("", 0, nil)
+	msg.AddScore(30)
+	msg.AddReason("velocity")
+	msg.AddResultTag("fraud")
+	_, err := node.OnMsg(context.Background(), msg)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]any{"Score": 5, types.ReasonsKey: []string{"velocity"}, types.TagsKey: []string{"fraud"}}, msg.GetChainOutput())
+}
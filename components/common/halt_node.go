@@ -0,0 +1,108 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"context"
+
+	"github.com/bittoy/rule/types"
+	"github.com/bittoy/rule/utils/maps"
+)
+
+// init registers the HaltNode component with the default registry.
+func init() {
+	Registry.Add(&HaltNode{})
+}
+
+// HaltNodeConfiguration HaltNode配置结构
+// HaltNodeConfiguration defines the configuration of the HaltNode component.
+type HaltNodeConfiguration struct {
+	// Action 终止时返回的动作，例如 "reject"
+	// Action is the action returned by the halted chain, e.g. "reject"
+	Action string
+	// Reason 终止原因
+	// Reason is the reason of the halt
+	Reason string
+	// Score 可选的评分
+	// Score is the optional score of the halted chain
+	Score int
+}
+
+// HaltNode 终止节点组件，到达时立即终止整个规则链，并将 {terminate: true, action, reason, score} 设置为规则链输出。
+// 与 end 节点只设置输出不同，halt 节点还会跳过拆分后的其余分支，规则链聚合也会在该规则链之后短路。
+// HaltNode stops the whole chain when reached and sets {terminate: true, action, reason, score} as the chain output,
+// which decodes into types.ChainResult. Unlike the end node, which only sets the output, halt also skips the
+// remaining branches of a split, and the chain aggregation short-circuits after the chain.
+//
+// 配置示例 - Configuration example:
+//
+//	{
+//		"id": "h1",
+//		"type": "halt",
+//		"configuration": {
+//			"action": "reject",
+//			"reason": "blacklisted"
+//		}
+//	}
+type HaltNode struct {
+	// Config 节点配置
+	Config HaltNodeConfiguration
+}
+
+// Type 返回组件类型
+// Type returns the component type identifier.
+func (x *HaltNode) Type() types.NodeType {
+	return types.RuleSubTypeHalt
+}
+
+// Category 返回组件类别
+// Category returns the component category.
+func (x *HaltNode) Category() string {
+	return types.CategoryFlow
+}
+
+// Relations 返回空列表，组件结束规则链
+// Relations returns no relation, the component ends the chain.
+func (x *HaltNode) Relations() []string {
+	return []string{}
+}
+
+// New creates a new instance.
+func (x *HaltNode) New() types.Node {
+	return &HaltNode{}
+}
+
+// Init initializes the component.
+func (x *HaltNode) Init(ruleConfig types.Config, configuration types.Configuration) error {
+	return maps.Map2Struct(configuration, &x.Config)
+}
+
+// OnMsg sets the terminating chain output and halts the message.
+func (x *HaltNode) OnMsg(ctx context.Context, msg types.RuleMsg) (next string, err error) {
+	msg.ClearInnerData()
+	msg.SetChainOutput(map[string]any{
+		"terminate": true,
+		"action":    x.Config.Action,
+		"reason":    x.Config.Reason,
+		"score":     x.Config.Score,
+	})
+	msg.Halt()
+	return "", nil
+}
+
+func (x *HaltNode) Destroy() {
+}
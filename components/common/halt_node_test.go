@@ -0,0 +1,39 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bittoy/rule/types"
+	"github.com/rulego/rulego/test/assert"
+)
+
+// TestHalt checks that the halt node halts the message with its decision as the chain output.
+func TestHalt(t *testing.T) {
+	node := &HaltNode{}
+	assert.Nil(t, node.Init(types.NewConfig(), types.Configuration{"action": "REJECT", "reason": "blacklisted", "score": 100}))
+	msg := types.NewRuleMsg("", 0, map[string]any{"amount": 2})
+	msg.SetPrivateVar("level", "high")
+	relation, err := node.OnMsg(context.Background(), msg)
+	assert.Nil(t, err)
+	assert.Equal(t, "", relation)
+	assert.True(t, msg.Halted())
+	assert.Equal(t, map[string]any{"terminate": true, "action": "REJECT", "reason": "blacklisted", "score": 100}, msg.GetChainOutput())
+	assert.Equal(t, 0, len(msg.GetPrivateVars()))
+}
@@ -18,30 +18,57 @@ package common
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 
+	"github.com/bittoy/rule/components/base"
 	"github.com/bittoy/rule/types"
+	"github.com/bittoy/rule/utils/maps"
 )
 
-// init registers the EndNode component with the default registry.
+// init registers the StartNode component with the default registry.
 func init() {
 	Registry.Add(&StartNode{})
 }
 
-// StartNode 开始节点组件，用于触发规则链的结束回调。如果规则链设置了结束节点组件，则会替代默认的分支结束行为，只有运行到结束节点组件时，才会触发结束回调
-// StartNode is an end node component that triggers the end callback of the rule chain. If the rule chain has an end node component set, it will replace the default branch ending behavior.
+// errPreconditionNotBool is returned when the precondition script does not return a boolean
+var errPreconditionNotBool = errors.New("start node precondition must return a boolean")
+
+// StartNodeConfiguration StartNode配置结构
+// StartNodeConfiguration defines the configuration of the StartNode component.
+type StartNodeConfiguration struct {
+	// JsScript 可选的前置条件脚本，参数与 jsFilter 相同，必须返回布尔值，例如 "return msg.temperature>10;"
+	// JsScript is the optional precondition script, it takes the parameters of jsFilter and must return
+	// a boolean, e.g. "return msg.temperature>10;"
+	JsScript string `json:"jsScript"`
+}
+
+// StartNode 开始节点组件，规则链的入口。未配置前置条件时直接路由到 default；
+// 配置了 JsScript 时，条件为 true 路由到 default，为 false 路由到 skip，没有 skip 连接时规则链直接结束，不执行下游节点。
+// StartNode is the entry of the rule chain. Without precondition it routes to "default". With JsScript set,
+// it routes to "default" when the precondition is true and to "skip" when it is false; without a skip
+// connection the chain then ends early, the downstream nodes do not run.
 //
-// 功能说明：
-// Function Description:
-// 1. 接收消息并触发DoOnEnd回调 - Receives messages and triggers DoOnEnd callback
-// 2. 使用上一个节点传入的关系类型 - Uses the relation type passed from the previous node
-// 3. 不会继续传递消息到下一个节点 - Does not continue passing messages to next nodes
+// 配置示例 - Configuration example:
 //
-// 使用场景：
-// Use Cases:
-// - 规则链的明确结束点 - Explicit end point of rule chains
-// - 触发特定的结束处理逻辑 - Trigger specific end processing logic
-// - 替代默认的分支结束行为 - Replace default branch ending behavior
+//	{
+//		"id": "s1",
+//		"type": "start",
+//		"configuration": {
+//			"jsScript": "return msg.temperature>10;"
+//		}
+//	}
 type StartNode struct {
+	// Config 节点配置
+	Config StartNodeConfiguration
+
+	// config 规则引擎配置
+	config types.Config
+	// vmPool 共享的 JavaScript VM 池
+	vmPool types.JsVMPool
+	// script 编译后执行的完整脚本，也是 VM 池中的键，未配置前置条件时为空
+	script string
 }
 
 // Type 返回组件类型
@@ -50,19 +77,58 @@ func (x *StartNode) Type() types.NodeType {
 	return types.RuleSubTypeStart
 }
 
+// Category 返回组件类别
+// Category returns the component category.
+func (x *StartNode) Category() string {
+	return types.CategoryFlow
+}
+
+// Relations 返回组件可能路由到的关系
+// Relations returns the relation types the component can route a message to.
+func (x *StartNode) Relations() []string {
+	return []string{types.DefaultRelationType, types.SkipRelationType}
+}
+
 // New creates a new instance.
 func (x *StartNode) New() types.Node {
 	return &StartNode{}
 }
 
-// Init initializes the component.
+// Init initializes the component, compiling the precondition script when set.
 func (x *StartNode) Init(ruleConfig types.Config, configuration types.Configuration) error {
-	// No configuration needed
+	err := maps.Map2Struct(configuration, &x.Config)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(x.Config.JsScript) == "" {
+		return nil
+	}
+	jsScript := fmt.Sprintf("function jsPrecondition(%s) { %s } jsPrecondition;", base.NodeUtils.JsParams(ruleConfig), x.Config.JsScript)
+	x.config = ruleConfig
+	x.vmPool = base.NodeUtils.JsVMPool(ruleConfig)
+	if err := x.vmPool.Compile("jsPrecondition.js", jsScript); err != nil {
+		return fmt.Errorf("new js vm err: script:%s", jsScript)
+	}
+	x.script = jsScript
 	return nil
 }
 
-// OnMsg processes the incoming message and triggers the end callback.
+// OnMsg routes the message to "default", or to "skip" when the precondition is false.
 func (x *StartNode) OnMsg(ctx context.Context, msg types.RuleMsg) (string, error) {
+	if x.script == "" {
+		return types.DefaultRelationType, nil
+	}
+	res, err := x.vmPool.Call(ctx, x.script, "jsPrecondition", base.NodeUtils.JsArgs(x.config, msg)...)
+	if err != nil {
+		return "", err
+	}
+	pass, ok := res.(bool)
+	if !ok {
+		return "", errPreconditionNotBool
+	}
+	if !pass {
+		return types.SkipRelationType, nil
+	}
 	return types.DefaultRelationType, nil
 }
 
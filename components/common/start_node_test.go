@@ -0,0 +1,50 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bittoy/rule/types"
+	"github.com/rulego/rulego/test/assert"
+)
+
+// TestStartPrecondition checks that the start node routes to skip when its precondition is false, and fails
+// when the precondition does not return a boolean.
+func TestStartPrecondition(t *testing.T) {
+	start := func(script string, temperature any) (string, error) {
+		node := &StartNode{}
+		assert.Nil(t, node.Init(types.NewConfig(), types.Configuration{"jsScript": script}))
+		return node.OnMsg(context.Background(), types.NewRuleMsg("", 0, map[string]any{"temperature": temperature}))
+	}
+	relation, err := start("", 5)
+	assert.Nil(t, err)
+	assert.Equal(t, types.DefaultRelationType, relation)
+	relation, err = start("return msg.temperature>10;", 20)
+	assert.Nil(t, err)
+	assert.Equal(t, types.DefaultRelationType, relation)
+	relation, err = start("return msg.temperature>10;", 5)
+	assert.Nil(t, err)
+	assert.Equal(t, types.SkipRelationType, relation)
+
+	_, err = start("return 'yes';", 5)
+	assert.Equal(t, errPreconditionNotBool, err)
+	_, err = start("throw new Error('boom');", 5)
+	assert.NotNil(t, err)
+	assert.NotNil(t, (&StartNode{}).Init(types.NewConfig(), types.Configuration{"jsScript": "return msg.temperature >;"}))
+}
@@ -0,0 +1,320 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transform
+
+//规则链节点配置示例：
+//{
+//        "id": "s12",
+//        "type": "batch",
+//        "name": "批量写入",
+//        "configuration": {
+//          "maxSize": 100,
+//          "maxWait": "1s",
+//          "outputKey": "items"
+//        }
+//      }
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bittoy/rule/types"
+	utilsmaps "github.com/bittoy/rule/utils/maps"
+)
+
+const (
+	// DefaultBatchMaxSize 默认的批次最大消息数
+	// DefaultBatchMaxSize is the default maximum number of messages in a batch
+	DefaultBatchMaxSize = 100
+	// DefaultBatchOutputKey 默认的批次私有变量键
+	// DefaultBatchOutputKey is the default private variable key of the batched items
+	DefaultBatchOutputKey = "items"
+)
+
+func init() {
+	Registry.Add(&BatchNode{})
+}
+
+// BatchNodeConfiguration BatchNode配置结构
+// BatchNodeConfiguration defines the configuration structure for the BatchNode component.
+type BatchNodeConfiguration struct {
+	// MaxSize 批次最大消息数，达到时立即发出批次，默认为 100
+	// MaxSize is the maximum number of messages in a batch, the batch is emitted as soon as it is full.
+	// Defaults to 100
+	MaxSize int `json:"maxSize"`
+	// MaxWait 批次最长等待时间，如 1s，从批次的第一条消息开始计时，到期时发出未满的批次
+	// MaxWait is the longest wait of a batch, e.g. 1s, counted from its first message,
+	// the batch is emitted when it expires even if it is not full
+	MaxWait string `json:"maxWait"`
+	// OutputKey 保存批次消息的私有变量键，默认为 items
+	// OutputKey is the private variable key holding the batched items, defaults to items
+	OutputKey string `json:"outputKey"`
+}
+
+// BatchNode 将多条消息合并为一个批次转发的组件
+// BatchNode accumulates messages until MaxSize messages are buffered or MaxWait has elapsed,
+// then forwards a single message holding the batch to "default", for bulk-write patterns like
+// batch inserts.
+//
+// 规则链每次只处理一条消息，因此批次由其第一条消息携带：第一条消息在节点中等待，批次发出时以
+// 批次内所有消息的输入（不含私有变量）组成的数组写入其私有变量 OutputKey 后继续执行下游节点。
+// 批次中的其他消息同样等待批次的结果：批次发出后结束规则链，不执行下游节点，也不产生规则链输出；
+// 批次被丢弃时返回 types.ErrBatchDropped，因此会按引擎配置重试或转入死信。
+// The chain runs one message at a time, so a batch is carried by its first message: the first
+// message waits in the node, and when the batch is emitted the array of the inputs of all the
+// batched messages, without their private variables, is written to its private variable OutputKey
+// and it continues to the downstream nodes. The other messages of the batch wait for the outcome of
+// the batch too: once it is emitted they end the chain without running the downstream nodes or
+// producing a chain output, when it is dropped they fail with types.ErrBatchDropped, so they are
+// retried or dead-lettered according to the engine configuration.
+//
+// 每个节点实例维护自己的缓冲区，因此批次按规则链和节点区分。试运行时消息不进入缓冲区，立即以只包含自身的批次继续执行。
+// Every node instance has its own buffer, so batches are kept per chain and node. In dry-run mode the
+// message does not enter the buffer and continues right away with a batch of itself.
+//
+// 引擎停止时未满的批次立即发出，之后到达的消息返回 types.ErrEngineShuttingDown。第一条消息的上下文取消时，
+// 节点返回上下文的错误，批次被丢弃；其他消息的上下文取消时，其输入在批次发出前被移出批次，节点返回上下文的错误。
+// When the engine stops the pending batch is emitted right away, messages arriving afterwards fail
+// with types.ErrEngineShuttingDown. When the context of the first message is cancelled the node
+// returns the context error and the batch is dropped. When the context of another message is
+// cancelled before the batch is emitted, its input is removed from the batch and the node returns
+// the context error.
+type BatchNode struct {
+	// Config 节点配置
+	// Config holds the batch node configuration
+	Config BatchNodeConfiguration
+
+	// maxWait 解析后的最长等待时间
+	// maxWait is the parsed longest wait
+	maxWait time.Duration
+	// clock 规则引擎配置的时钟
+	// clock is the clock of the rule engine configuration
+	clock types.Clock
+
+	// mu 保护 pending 和 destroyed
+	// mu guards pending and destroyed
+	mu sync.Mutex
+	// pending 正在收集的批次，没有时为 nil
+	// pending is the batch being filled, nil when there is none
+	pending *batch
+	// destroyed 节点已销毁
+	// destroyed reports whether the node has been destroyed
+	destroyed bool
+}
+
+// Type 返回组件类型
+// Type returns the component type identifier.
+func (x *BatchNode) Type() types.NodeType {
+	return types.RuleSubTypeBatch
+}
+
+// Category 返回组件类别
+// Category returns the component category.
+func (x *BatchNode) Category() string {
+	return types.CategoryFlow
+}
+
+// Relations 返回组件可能路由到的关系
+// Relations returns the relation types the component can route a message to.
+func (x *BatchNode) Relations() []string {
+	return []string{types.DefaultRelationType}
+}
+
+// New 创建新实例
+// New creates a new instance.
+func (x *BatchNode) New() types.Node {
+	return &BatchNode{Config: BatchNodeConfiguration{
+		MaxSize:   DefaultBatchMaxSize,
+		OutputKey: DefaultBatchOutputKey,
+	}}
+}
+
+// Init 初始化组件，解析最长等待时间
+// Init initializes the component, parsing the longest wait.
+func (x *BatchNode) Init(config types.Config, configuration types.Configuration) error {
+	x.clock = config.GetClock()
+	err := utilsmaps.Map2Struct(configuration, &x.Config)
+	if err != nil {
+		return err
+	}
+	if x.Config.MaxSize <= 0 {
+		x.Config.MaxSize = DefaultBatchMaxSize
+	}
+	if strings.TrimSpace(x.Config.OutputKey) == "" {
+		x.Config.OutputKey = DefaultBatchOutputKey
+	}
+	if x.maxWait, err = time.ParseDuration(x.Config.MaxWait); err != nil {
+		return fmt.Errorf("invalid maxWait:%w", err)
+	}
+	if x.maxWait <= 0 {
+		return errors.New("maxWait must be positive")
+	}
+	return nil
+}
+
+// OnMsg 处理消息，将其加入批次，批次的第一条消息等待批次发出后携带批次继续执行
+// OnMsg adds the message to the pending batch, the first message of the batch waits for it to be
+// emitted and continues with it.
+func (x *BatchNode) OnMsg(ctx context.Context, msg types.RuleMsg) (string, error) {
+	item := maps.Clone(msg.GetInput())
+	delete(item, types.PriVarsKey)
+	if types.IsDryRun(ctx) {
+		msg.SetPrivateVar(x.Config.OutputKey, []map[string]any{item})
+		return types.DefaultRelationType, nil
+	}
+	b, entry, first, err := x.add(item)
+	if err != nil {
+		return "", err
+	}
+	if !first {
+		return "", x.waitMember(ctx, b, entry)
+	}
+	expired := make(chan struct{})
+	timer := x.clock.AfterFunc(x.maxWait, func() {
+		close(expired)
+	})
+	defer timer.Stop()
+	select {
+	case <-b.full:
+	case <-expired:
+		x.detach(b)
+	case <-ctx.Done():
+		x.detach(b)
+		// The engine stopping cancels the waiting message, the pending batch is emitted rather than dropped
+		// 引擎停止时取消等待中的消息，未满的批次被发出而不是丢弃
+		if !errors.Is(context.Cause(ctx), types.ErrEngineShuttingDown) {
+			b.finish(fmt.Errorf("%w: %w", types.ErrBatchDropped, ctx.Err()))
+			return "", ctx.Err()
+		}
+	}
+	msg.SetPrivateVar(x.Config.OutputKey, b.items())
+	b.finish(nil)
+	return types.DefaultRelationType, nil
+}
+
+// waitMember waits for the outcome of the batch a message other than the first was added to,
+// it returns nil once the batch is emitted and the error of the batch when it is dropped
+func (x *BatchNode) waitMember(ctx context.Context, b *batch, entry *batchEntry) error {
+	select {
+	case <-b.done:
+		return b.err
+	case <-ctx.Done():
+		// The engine stopping emits the batch, the member waits for it
+		// 引擎停止时批次会被发出，成员消息等待其结果
+		if !errors.Is(context.Cause(ctx), types.ErrEngineShuttingDown) && x.remove(b, entry) {
+			return ctx.Err()
+		}
+		<-b.done
+		return b.err
+	}
+}
+
+// Destroy 清理资源，立即发出未满的批次
+// Destroy cleans up resources, emitting the pending batch right away.
+func (x *BatchNode) Destroy() {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.destroyed = true
+	if x.pending != nil {
+		close(x.pending.full)
+		x.pending = nil
+	}
+}
+
+// add appends the item to the pending batch, starting a new batch when there is none.
+// first reports whether the item starts the batch, so its message carries the batch
+func (x *BatchNode) add(item map[string]any) (b *batch, entry *batchEntry, first bool, err error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.destroyed {
+		return nil, nil, false, types.ErrEngineShuttingDown
+	}
+	if x.pending == nil {
+		x.pending = &batch{full: make(chan struct{}), done: make(chan struct{})}
+		first = true
+	}
+	b = x.pending
+	entry = &batchEntry{item: item}
+	b.entries = append(b.entries, entry)
+	if len(b.entries) >= x.Config.MaxSize {
+		close(b.full)
+		x.pending = nil
+	}
+	return b, entry, first, nil
+}
+
+// remove removes the entry from the batch when it is still pending, it reports whether it was removed
+func (x *BatchNode) remove(b *batch, entry *batchEntry) bool {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.pending != b {
+		return false
+	}
+	for i, e := range b.entries {
+		if e == entry {
+			b.entries = append(b.entries[:i], b.entries[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// detach stops adding items to the batch when it is still pending
+func (x *BatchNode) detach(b *batch) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.pending == b {
+		x.pending = nil
+	}
+}
+
+// batch is a batch of message inputs
+type batch struct {
+	// entries are the batched message inputs, the first belongs to the message carrying the batch.
+	// They are only changed while the batch is pending
+	entries []*batchEntry
+	// full is closed when the batch must be emitted before its wait expires: it is full or the node is destroyed
+	full chan struct{}
+	// done is closed once the batch is emitted or dropped, err is set before
+	done chan struct{}
+	// err is the error of a dropped batch, nil once it is emitted
+	err error
+}
+
+// batchEntry is the input of a batched message
+type batchEntry struct {
+	item map[string]any
+}
+
+// items returns the batched message inputs
+func (b *batch) items() []map[string]any {
+	items := make([]map[string]any, len(b.entries))
+	for i, entry := range b.entries {
+		items[i] = entry.item
+	}
+	return items
+}
+
+// finish reports the outcome of the batch to its members
+func (b *batch) finish(err error) {
+	b.err = err
+	close(b.done)
+}
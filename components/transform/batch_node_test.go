@@ -0,0 +1,120 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transform
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bittoy/rule/types"
+	"github.com/rulego/rulego/test/assert"
+)
+
+// TestBatch checks that the first message of a batch carries it downstream when it is full,
+// when its wait expires and when the engine stops, and that the other messages wait for the batch.
+func TestBatch(t *testing.T) {
+	newNode := func(maxWait string) *BatchNode {
+		node := &BatchNode{}
+		assert.Nil(t, node.Init(types.NewConfig(), types.Configuration{"maxSize": 3, "maxWait": maxWait}))
+		return node
+	}
+	items := func(msg types.RuleMsg) []map[string]any {
+		items, _ := msg.GetPrivateVars()[DefaultBatchOutputKey].([]map[string]any)
+		return items
+	}
+	node := newNode("1h")
+	first := types.NewRuleMsg("", 0, map[string]any{"n": 1})
+	done := make(chan error, 1)
+	go func() {
+		_, err := node.OnMsg(context.Background(), first)
+		done <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	member := types.NewRuleMsg("", 0, map[string]any{"n": 2})
+	memberDone := make(chan error, 1)
+	go func() {
+		_, err := node.OnMsg(context.Background(), member)
+		memberDone <- err
+	}()
+	select {
+	case <-memberDone:
+		t.Fatal("member returned before the batch was emitted")
+	case <-time.After(20 * time.Millisecond):
+	}
+	msg := types.NewRuleMsg("", 0, map[string]any{"n": 3})
+	relation, err := node.OnMsg(context.Background(), msg)
+	assert.Nil(t, err)
+	assert.Equal(t, "", relation)
+	assert.Equal(t, 0, len(items(msg)))
+	assert.Nil(t, <-memberDone)
+	assert.Equal(t, 0, len(items(member)))
+	assert.Nil(t, <-done)
+	assert.Equal(t, []map[string]any{{"n": 1}, {"n": 2}, {"n": 3}}, items(first))
+
+	// The members of a dropped batch fail
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		_, err := node.OnMsg(ctx, types.NewRuleMsg("", 0, map[string]any{"n": 1}))
+		done <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	time.AfterFunc(20*time.Millisecond, cancel)
+	_, err = node.OnMsg(context.Background(), types.NewRuleMsg("", 0, map[string]any{"n": 2}))
+	assert.True(t, errors.Is(err, types.ErrBatchDropped))
+	assert.True(t, errors.Is(<-done, context.Canceled))
+
+	// The engine stopping emits the pending batch
+	stopCtx, stop := context.WithCancelCause(context.Background())
+	time.AfterFunc(20*time.Millisecond, func() {
+		stop(types.ErrEngineShuttingDown)
+	})
+	first = types.NewRuleMsg("", 0, map[string]any{"n": 4})
+	relation, err = node.OnMsg(stopCtx, first)
+	assert.Nil(t, err)
+	assert.Equal(t, types.DefaultRelationType, relation)
+	assert.Equal(t, []map[string]any{{"n": 4}}, items(first))
+	node.Destroy()
+	_, err = node.OnMsg(context.Background(), types.NewRuleMsg("", 0, map[string]any{"n": 5}))
+	assert.True(t, errors.Is(err, types.ErrEngineShuttingDown))
+
+	node = newNode("30ms")
+	start := time.Now()
+	first = types.NewRuleMsg("", 0, map[string]any{"n": 5})
+	_, err = node.OnMsg(context.Background(), first)
+	assert.Nil(t, err)
+	assert.True(t, time.Since(start) >= 25*time.Millisecond)
+	assert.Equal(t, []map[string]any{{"n": 5}}, items(first))
+
+	// A dry run does not wait for a batch
+	msg = types.NewRuleMsg("", 0, map[string]any{"n": 6})
+	_, err = node.OnMsg(types.ContextWithDryRun(context.Background()), msg)
+	assert.Nil(t, err)
+	assert.Equal(t, []map[string]any{{"n": 6}}, items(msg))
+}
+
+// TestBatchInit checks the defaults and the validation of the batch configuration.
+func TestBatchInit(t *testing.T) {
+	node := (&BatchNode{}).New().(*BatchNode)
+	assert.Nil(t, node.Init(types.NewConfig(), types.Configuration{"maxSize": 0, "maxWait": "1s", "outputKey": " "}))
+	assert.Equal(t, DefaultBatchMaxSize, node.Config.MaxSize)
+	assert.Equal(t, DefaultBatchOutputKey, node.Config.OutputKey)
+	for _, maxWait := range []string{"", "soon", "0s", "-1s"} {
+		assert.NotNil(t, (&BatchNode{}).Init(types.NewConfig(), types.Configuration{"maxWait": maxWait}), maxWait)
+	}
+}
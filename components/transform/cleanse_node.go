@@ -0,0 +1,332 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transform
+
+//规则链节点配置示例：
+//{
+//        "id": "s13",
+//        "type": "cleanse",
+//        "name": "数据清洗",
+//        "configuration": {
+//          "fields": {
+//            "user.email": [{"op": "trim"}, {"op": "lowercase"}],
+//            "phone": [{"op": "regexReplace", "pattern": "[^0-9]", "replacement": ""}],
+//            "age": [{"op": "default", "value": "0"}, {"op": "cast", "type": "int"}]
+//          }
+//        }
+//      }
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/bittoy/rule/types"
+	"github.com/bittoy/rule/utils/cast"
+	utilsmaps "github.com/bittoy/rule/utils/maps"
+)
+
+func init() {
+	Registry.Add(&CleanseNode{})
+}
+
+// Cleanse operations.
+// 清洗操作。
+const (
+	// CleanseTrim 去除字符串首尾空白
+	// CleanseTrim removes the leading and trailing white space of a string
+	CleanseTrim = "trim"
+	// CleanseLowercase 将字符串转为小写
+	// CleanseLowercase converts a string to lower case
+	CleanseLowercase = "lowercase"
+	// CleanseUppercase 将字符串转为大写
+	// CleanseUppercase converts a string to upper case
+	CleanseUppercase = "uppercase"
+	// CleanseRegexReplace 将字符串中匹配 Pattern 的部分替换为 Replacement，Replacement 可以引用分组，如 $1
+	// CleanseRegexReplace replaces the matches of Pattern in a string with Replacement, which can refer to groups, e.g. $1
+	CleanseRegexReplace = "regexReplace"
+	// CleanseDefault 字段缺失、为 null 或空字符串时设置为 Value
+	// CleanseDefault sets the field to Value when it is missing, null or an empty string
+	CleanseDefault = "default"
+	// CleanseCast 将值转换为 Type 类型：string、number、int 或 bool
+	// CleanseCast converts the value to Type: string, number, int or bool
+	CleanseCast = "cast"
+)
+
+// FieldTypeInt is the integer type of the cast operation, the other types are those of the require node
+// FieldTypeInt 是 cast 操作的整数类型，其他类型与 require 节点相同
+const FieldTypeInt = "int"
+
+// CleanseOperation 字段的一个清洗操作
+// CleanseOperation is a cleansing operation of a field.
+type CleanseOperation struct {
+	// Op 操作名称，如 trim
+	// Op is the name of the operation, e.g. trim
+	Op string `json:"op"`
+	// Pattern regexReplace 的正则表达式
+	// Pattern is the regular expression of regexReplace
+	Pattern string `json:"pattern,omitempty"`
+	// Replacement regexReplace 的替换内容
+	// Replacement is the replacement of regexReplace
+	Replacement string `json:"replacement,omitempty"`
+	// Value default 的默认值
+	// Value is the default value of default
+	Value any `json:"value,omitempty"`
+	// Type cast 的目标类型
+	// Type is the target type of cast
+	Type string `json:"type,omitempty"`
+}
+
+// CleanseNodeConfiguration CleanseNode配置结构
+// CleanseNodeConfiguration defines the configuration structure for the CleanseNode component.
+type CleanseNodeConfiguration struct {
+	// Fields 键为字段路径，支持嵌套字段，如 user.email，值为按顺序执行的清洗操作
+	// Fields are keyed by the field path, nested fields are separated by dots, e.g. user.email,
+	// and valued by the operations applied in order
+	Fields map[string][]CleanseOperation `json:"fields"`
+}
+
+// CleanseNode 规范化字段值的数据清洗组件
+// CleanseNode normalizes the values of the input fields with simple operations, trimming, changing
+// the case, replacing with a regular expression, defaulting and casting, instead of verbose
+// expressions in exprAssign. The cleansed values replace the input fields and the message routes
+// to "default".
+//
+// 字符串操作跳过非字符串的值；缺失的字段只受 default 操作影响；转换失败时节点返回错误。
+// String operations skip the values that are not strings; a missing field is only affected by
+// a default operation; a failing cast fails the node.
+type CleanseNode struct {
+	// Config 节点配置
+	// Config holds the cleanse node configuration
+	Config CleanseNodeConfiguration
+
+	// fields 按路径排序的字段清洗步骤
+	// fields are the cleansing steps of the fields, sorted by path
+	fields []cleanseField
+}
+
+// cleanseField is the compiled operations of a field
+type cleanseField struct {
+	path  string
+	steps []cleanseStep
+}
+
+// cleanseStep is an operation with its regular expression compiled
+type cleanseStep struct {
+	CleanseOperation
+	re *regexp.Regexp
+}
+
+// Type 返回组件类型
+// Type returns the component type identifier.
+func (x *CleanseNode) Type() types.NodeType {
+	return types.RuleSubTypeCleanse
+}
+
+// Category 返回组件类别
+// Category returns the component category.
+func (x *CleanseNode) Category() string {
+	return types.CategoryTransform
+}
+
+// Relations 返回组件可能路由到的关系
+// Relations returns the relation types the component can route a message to.
+func (x *CleanseNode) Relations() []string {
+	return []string{types.DefaultRelationType}
+}
+
+// New 创建新实例
+// New creates a new instance.
+func (x *CleanseNode) New() types.Node {
+	return &CleanseNode{}
+}
+
+// Init 初始化组件，校验操作并编译正则表达式
+// Init initializes the component, checking the operations and compiling the regular expressions.
+func (x *CleanseNode) Init(config types.Config, configuration types.Configuration) error {
+	err := utilsmaps.Map2Struct(configuration, &x.Config)
+	if err != nil {
+		return err
+	}
+	if len(x.Config.Fields) == 0 {
+		return errors.New("fields must not be empty")
+	}
+	x.fields = make([]cleanseField, 0, len(x.Config.Fields))
+	for path, ops := range x.Config.Fields {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			return errors.New("field path must not be empty")
+		}
+		if head, _, _ := strings.Cut(path, "."); head == types.PriVarsKey {
+			return fmt.Errorf("%s is reserved for the private variables", types.PriVarsKey)
+		}
+		if len(ops) == 0 {
+			return fmt.Errorf("field %s operations must not be empty", path)
+		}
+		field := cleanseField{path: path, steps: make([]cleanseStep, len(ops))}
+		for i, op := range ops {
+			step := cleanseStep{CleanseOperation: op}
+			switch op.Op {
+			case CleanseTrim, CleanseLowercase, CleanseUppercase, CleanseDefault:
+			case CleanseRegexReplace:
+				if op.Pattern == "" {
+					return fmt.Errorf("field %s operation %d pattern must not be empty", path, i)
+				}
+				if step.re, err = regexp.Compile(op.Pattern); err != nil {
+					return fmt.Errorf("field %s operation %d pattern: %w", path, i, err)
+				}
+			case CleanseCast:
+				switch op.Type {
+				case FieldTypeString, FieldTypeNumber, FieldTypeInt, FieldTypeBool:
+				default:
+					return fmt.Errorf("field %s operation %d unknown cast type %s, must be string, number, int or bool", path, i, op.Type)
+				}
+			default:
+				return fmt.Errorf("field %s operation %d unknown op %s", path, i, op.Op)
+			}
+			field.steps[i] = step
+		}
+		x.fields = append(x.fields, field)
+	}
+	slices.SortFunc(x.fields, func(a, b cleanseField) int {
+		return strings.Compare(a.path, b.path)
+	})
+	return nil
+}
+
+// OnMsg 处理消息，按顺序对每个字段执行清洗操作
+// OnMsg applies the operations of each field in order.
+func (x *CleanseNode) OnMsg(ctx context.Context, msg types.RuleMsg) (string, error) {
+	for _, field := range x.fields {
+		value := fieldValue(msg, field.path)
+		original := value
+		var err error
+		for _, step := range field.steps {
+			if value, err = step.apply(value); err != nil {
+				return "", fmt.Errorf("field %s: %w", field.path, err)
+			}
+		}
+		if value == nil && original == nil {
+			continue
+		}
+		if err = setFieldValue(msg, field.path, value); err != nil {
+			return "", err
+		}
+	}
+	return types.DefaultRelationType, nil
+}
+
+// Destroy 清理资源
+// Destroy cleans up resources.
+func (x *CleanseNode) Destroy() {
+}
+
+// apply returns the value cleansed by the operation
+func (s cleanseStep) apply(value any) (any, error) {
+	if s.Op == CleanseDefault {
+		if value == nil || value == "" {
+			return s.Value, nil
+		}
+		return value, nil
+	}
+	if value == nil {
+		return nil, nil
+	}
+	if s.Op == CleanseCast {
+		return castValue(value, s.Type)
+	}
+	str, ok := value.(string)
+	if !ok {
+		return value, nil
+	}
+	switch s.Op {
+	case CleanseTrim:
+		return strings.TrimSpace(str), nil
+	case CleanseLowercase:
+		return strings.ToLower(str), nil
+	case CleanseUppercase:
+		return strings.ToUpper(str), nil
+	default:
+		return s.re.ReplaceAllString(str, s.Replacement), nil
+	}
+}
+
+// castValue converts the value to the cast type
+func castValue(value any, castType string) (any, error) {
+	switch castType {
+	case FieldTypeString:
+		return cast.ToStringE(value)
+	case FieldTypeNumber:
+		if str, ok := value.(string); ok {
+			value = strings.TrimSpace(str)
+		}
+		return cast.ToFloat64E(value)
+	case FieldTypeInt:
+		if str, ok := value.(string); ok {
+			value = strings.TrimSpace(str)
+		}
+		return cast.ToInt64E(value)
+	default:
+		return cast.ToBoolE(value)
+	}
+}
+
+// setFieldValue sets the input field at path, nested fields are separated by dots. The missing
+// intermediate objects are created, an intermediate value that is not an object is an error.
+// The objects along the path are copied before they are written, so maps shared with the caller
+// or with other messages are never modified.
+func setFieldValue(msg types.RuleMsg, path string, value any) error {
+	head, rest, nested := strings.Cut(path, ".")
+	if !nested {
+		msg.SetField(head, value)
+		return nil
+	}
+	headValue, _ := msg.Field(head)
+	root, err := childObject(headValue, head)
+	if err != nil {
+		return err
+	}
+	parent, parentPath := root, head
+	keys := strings.Split(rest, ".")
+	for _, key := range keys[:len(keys)-1] {
+		parentPath += "." + key
+		child, err := childObject(parent[key], parentPath)
+		if err != nil {
+			return err
+		}
+		parent[key] = child
+		parent = child
+	}
+	parent[keys[len(keys)-1]] = value
+	msg.SetField(head, root)
+	return nil
+}
+
+// childObject returns a copy of the object value, a new object when it is nil
+func childObject(value any, path string) (map[string]any, error) {
+	if value == nil {
+		return map[string]any{}, nil
+	}
+	object, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an object", path)
+	}
+	return maps.Clone(object), nil
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transform
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/bittoy/rule/types"
+	"github.com/rulego/rulego/test/assert"
+)
+
+// TestCleanse checks that the cleanse node applies the operations of each field in order, and fails on a bad cast.
+func TestCleanse(t *testing.T) {
+	node := &CleanseNode{}
+	assert.Nil(t, node.Init(types.NewConfig(), types.Configuration{"fields": map[string]any{
+		"user.email":      []map[string]any{{"op": "trim"}, {"op": "lowercase"}},
+		"code":            []map[string]any{{"op": "uppercase"}},
+		"phone":           []map[string]any{{"op": "regexReplace", "pattern": "[^0-9]", "replacement": ""}},
+		"age":             []map[string]any{{"op": "default", "value": "0"}, {"op": "cast", "type": "int"}},
+		"address.country": []map[string]any{{"op": "default", "value": "CN"}},
+		"missing":         []map[string]any{{"op": "trim"}},
+	}}))
+	cleanse := func(input map[string]any) (map[string]any, error) {
+		msg := types.NewRuleMsg("", 0, input)
+		relation, err := node.OnMsg(context.Background(), msg)
+		if err != nil {
+			return nil, err
+		}
+		assert.Equal(t, types.DefaultRelationType, relation)
+		fields := map[string]any{}
+		for _, name := range msg.FieldNames() {
+			fields[name], _ = msg.Field(name)
+		}
+		delete(fields, types.PriVarsKey)
+		return fields, nil
+	}
+
+	user := map[string]any{"email": "  Bob@Example.COM "}
+	fields, err := cleanse(map[string]any{"user": user, "code": "ab1", "phone": "+86 (138) 0000-1111", "age": ""})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]any{
+		"user":    map[string]any{"email": "bob@example.com"},
+		"code":    "AB1",
+		"phone":   "8613800001111",
+		"age":     int64(0),
+		"address": map[string]any{"country": "CN"},
+	}, fields)
+	// The nested objects of the caller are not modified
+	assert.Equal(t, map[string]any{"email": "  Bob@Example.COM "}, user)
+
+	fields, err = cleanse(map[string]any{"user": map[string]any{"email": "a@b.c"}, "code": 7, "phone": "1", "age": " 42 "})
+	assert.Nil(t, err)
+	assert.Equal(t, 7, fields["code"])
+	assert.Equal(t, int64(42), fields["age"])
+
+	_, err = cleanse(map[string]any{"user": map[string]any{}, "age": "old"})
+	assert.True(t, err != nil && strings.Contains(err.Error(), "field age"))
+	_, err = cleanse(map[string]any{"address": "Beijing"})
+	assert.True(t, err != nil && strings.Contains(err.Error(), "address is not an object"))
+}
+
+// TestCleanseInit checks the validation of the fields and their operations.
+func TestCleanseInit(t *testing.T) {
+	for _, fields := range []map[string]any{
+		nil,
+		{" ": []map[string]any{{"op": "trim"}}},
+		{"priVars.x": []map[string]any{{"op": "trim"}}},
+		{"code": []map[string]any{}},
+		{"code": []map[string]any{{"op": "reverse"}}},
+		{"code": []map[string]any{{"op": "regexReplace"}}},
+		{"code": []map[string]any{{"op": "regexReplace", "pattern": "[0-9"}}},
+		{"code": []map[string]any{{"op": "cast", "type": "date"}}},
+	} {
+		assert.NotNil(t, (&CleanseNode{}).Init(types.NewConfig(), types.Configuration{"fields": fields}), fields)
+	}
+}
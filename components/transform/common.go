@@ -2,6 +2,7 @@ package transform
 
 import (
 	"errors"
+	"strconv"
 	"strings"
 )
 
@@ -9,23 +10,54 @@ import (
 	"github.com/bittoy/rule/types"
 )
 
+// genExprScriptByCases generates an expr conditional chain from the cases, the relations are quoted
+// as string literals, see types.Case.Relation
 func genExprScriptByCases(cases []types.Case) (string, error) {
 	var script = strings.Builder{}
 
 	for _, v := range cases {
 		v.Case = strings.TrimSpace(v.Case)
-		v.Then = strings.TrimSpace(v.Then)
-		if len(v.Case) == 0 || len(v.Then) == 0 {
+		relation := v.Relation()
+		if len(v.Case) == 0 || len(relation) == 0 {
 			return "", errors.New("case must not be empty")
 		}
 		if v.Case == "other" {
-			script.WriteString(v.Then)
+			script.WriteString(strconv.Quote(relation))
 		} else {
 			script.WriteString(v.Case)
 			script.WriteString(" ? ")
-			script.WriteString(v.Then)
+			script.WriteString(strconv.Quote(relation))
 			script.WriteString(" : ")
 		}
 	}
 	return script.String(), nil
 }
+
+// genJsScriptByCases 根据 cases 生成 jsSwitch 的路由脚本，case 为 JavaScript 条件表达式，then 为关系名称，
+// 必须以 case 为 "other" 的默认分支结束
+func genJsScriptByCases(cases []types.Case) (string, error) {
+	var script = strings.Builder{}
+
+	for i, v := range cases {
+		v.Case = strings.TrimSpace(v.Case)
+		v.Then = v.Relation()
+		if len(v.Case) == 0 || len(v.Then) == 0 {
+			return "", errors.New("case must not be empty")
+		}
+		if v.Case == "other" {
+			if i != len(cases)-1 {
+				return "", errors.New("other case must be the last case")
+			}
+			script.WriteString("return ")
+			script.WriteString(strconv.Quote(v.Then))
+			script.WriteString(";")
+			return script.String(), nil
+		}
+		script.WriteString("if (")
+		script.WriteString(v.Case)
+		script.WriteString(") { return ")
+		script.WriteString(strconv.Quote(v.Then))
+		script.WriteString("; } ")
+	}
+	return "", errors.New("cases must end with an other case")
+}
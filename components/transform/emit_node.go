@@ -0,0 +1,168 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transform
+
+//规则链节点配置示例：
+//{
+//        "id": "s10",
+//        "type": "emit",
+//        "name": "下单事件",
+//        "configuration": {
+//          "name": "orderPlaced",
+//          "script": "{'orderId': orderId, 'amount': amount}"
+//        }
+//      }
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/bittoy/rule/components/base"
+	"github.com/bittoy/rule/types"
+	"github.com/bittoy/rule/utils/maps"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+)
+
+func init() {
+	Registry.Add(&EmitNode{})
+}
+
+// EmitNodeConfiguration EmitNode配置结构
+// EmitNodeConfiguration defines the configuration structure for the EmitNode component.
+type EmitNodeConfiguration struct {
+	// Name 事件名称
+	// Name is the event name
+	Name string `json:"name"`
+	// Script 计算事件负载的 expr 表达式，必须返回 map，默认为 {}
+	// Script is the expr computing the event payload, it must return a map. Defaults to {}
+	Script string `json:"script"`
+}
+
+// EmitNode 发出旁路事件的组件
+// EmitNode computes an event payload with expr and sends it to Config.Events, e.g. to fire business
+// events or to increment custom counters mid-chain, then forwards the message unchanged through "default".
+// Emitting never fails the chain: script and sink errors are logged and the message continues.
+// In dry-run mode the event is logged instead of sent, see types.IsDryRun.
+//
+// EmitNode 使用 expr 计算事件负载并发送到 Config.Events，例如在规则链中途触发业务事件或增加自定义计数器，
+// 然后通过 "default" 原样转发消息。发出事件不会使规则链失败：脚本和接收方的错误只会被记录，消息继续流转。
+// 试运行模式下只记录事件而不发送，参见 types.IsDryRun。
+type EmitNode struct {
+	// Config 节点配置
+	// Config holds the emit node configuration
+	Config EmitNodeConfiguration
+
+	// config 规则引擎配置
+	// config is the rule engine configuration
+	config types.Config
+
+	// program 用于高效评估的编译表达式
+	// program is the compiled expression for efficient evaluation
+	program *vm.Program
+}
+
+// Type 返回组件类型
+// Type returns the component type identifier.
+func (x *EmitNode) Type() types.NodeType {
+	return types.RuleSubTypeEmit
+}
+
+// Category 返回组件类别
+// Category returns the component category.
+func (x *EmitNode) Category() string {
+	return types.CategoryOther
+}
+
+// Relations 返回组件可能路由到的关系
+// Relations returns the relation types the component can route a message to.
+func (x *EmitNode) Relations() []string {
+	return []string{types.DefaultRelationType}
+}
+
+// New 创建新实例
+// New creates a new instance.
+func (x *EmitNode) New() types.Node {
+	return &EmitNode{Config: EmitNodeConfiguration{
+		Script: `{}`,
+	}}
+}
+
+// Init 初始化组件，编译负载表达式
+// Init initializes the component, compiling the payload expression.
+func (x *EmitNode) Init(config types.Config, configuration types.Configuration) error {
+	x.config = config
+	err := maps.Map2Struct(configuration, &x.Config)
+	if err != nil {
+		return err
+	}
+	x.Config.Name = strings.TrimSpace(x.Config.Name)
+	if x.Config.Name == "" {
+		return errors.New("name must not be empty")
+	}
+	program, err := expr.Compile(x.Config.Script, base.NodeUtils.ExprOptions(config, expr.AsKind(reflect.Map))...)
+	if err != nil {
+		return err
+	}
+	x.program = program
+	return nil
+}
+
+// OnMsg 发出事件并原样转发消息
+// OnMsg emits the event and forwards the message unchanged.
+func (x *EmitNode) OnMsg(ctx context.Context, msg types.RuleMsg) (string, error) {
+	if err := x.emit(ctx, msg); err != nil && x.config.Logger != nil {
+		x.config.Logger.Printf("emit %s msgId=%s error: %s", x.Config.Name, msg.Id(), err.Error())
+	}
+	return types.DefaultRelationType, nil
+}
+
+// emit computes the payload and sends the event to the sink, a panicking sink is reported as an error
+func (x *EmitNode) emit(ctx context.Context, msg types.RuleMsg) (err error) {
+	out, err := vm.Run(x.program, base.NodeUtils.ExprProgramEnv(ctx, x.config, x.program, msg))
+	if err != nil {
+		return err
+	}
+	payload, ok := out.(map[string]any)
+	if !ok {
+		return errors.New("返回类型不匹配")
+	}
+	event := types.Event{Name: x.Config.Name, MsgId: msg.Id(), Payload: payload}
+	if types.IsDryRun(ctx) {
+		if x.config.Logger != nil {
+			x.config.Logger.Printf("dry run: skip event %s msgId=%s payload=%v", event.Name, event.MsgId, x.config.Redact(payload))
+		}
+		return nil
+	}
+	if x.config.Events == nil {
+		return nil
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("events sink panic: %v", r)
+		}
+	}()
+	return x.config.Events(ctx, event)
+}
+
+// Destroy 清理资源
+// Destroy releases the resources.
+func (x *EmitNode) Destroy() {
+}
@@ -0,0 +1,66 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transform
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bittoy/rule/types"
+	"github.com/rulego/rulego/test/assert"
+)
+
+// TestEmit checks that the emit node sends its event to the sink, that sink failures and panics do not fail
+// the node and that a dry run sends nothing.
+func TestEmit(t *testing.T) {
+	var events []types.Event
+	sinkErr := errors.New("sink down")
+	config := types.NewConfig(types.WithEvents(func(ctx context.Context, event types.Event) error {
+		events = append(events, event)
+		return sinkErr
+	}))
+	node := &EmitNode{}
+	assert.Nil(t, node.Init(config, types.Configuration{"name": "orderPlaced", "script": "{'amount': amount * 2}"}))
+
+	msg := types.NewRuleMsg("m1", 0, map[string]any{"amount": 2})
+	relation, err := node.OnMsg(context.Background(), msg)
+	assert.Nil(t, err)
+	assert.Equal(t, types.DefaultRelationType, relation)
+	assert.Equal(t, 2, msg.GetInput()["amount"])
+	assert.Equal(t, []types.Event{{Name: "orderPlaced", MsgId: "m1", Payload: map[string]any{"amount": 4}}}, events)
+
+	relation, err = node.OnMsg(types.ContextWithDryRun(context.Background()), types.NewRuleMsg("", 0, map[string]any{"amount": 2}))
+	assert.Nil(t, err)
+	assert.Equal(t, types.DefaultRelationType, relation)
+	assert.Equal(t, 1, len(events))
+
+	config = types.NewConfig(types.WithEvents(func(ctx context.Context, event types.Event) error {
+		panic("sink panic")
+	}))
+	node = &EmitNode{}
+	assert.Nil(t, node.Init(config, types.Configuration{"name": "orderPlaced", "script": "{'amount': amount}"}))
+	err = node.emit(context.Background(), types.NewRuleMsg("", 0, map[string]any{"amount": 1}))
+	assert.True(t, err != nil && strings.Contains(err.Error(), "sink panic"))
+	relation, err = node.OnMsg(context.Background(), types.NewRuleMsg("", 0, map[string]any{"amount": 1}))
+	assert.Nil(t, err)
+	assert.Equal(t, types.DefaultRelationType, relation)
+
+	assert.NotNil(t, (&EmitNode{}).Init(config, types.Configuration{"script": "{}"}))
+	assert.NotNil(t, (&EmitNode{}).Init(config, types.Configuration{"name": "orderPlaced", "script": "amount *"}))
+}
@@ -29,8 +29,10 @@ package transform
 import (
 	"context"
 	"errors"
+	"fmt"
 	"reflect"
 
+	"github.com/bittoy/rule/components/base"
 	"github.com/bittoy/rule/types"
 	"github.com/bittoy/rule/utils/maps"
 
@@ -60,6 +62,17 @@ type ExprAssignNodeConfiguration struct {
 	//
 	// 示例: "return ['route1', 'route2'];"
 	Script string `json:"script"`
+	// ToInput 将结果写入顶层输入字段而不是私有变量，默认为 false
+	// ToInput writes the result into the top-level input fields instead of the private variables, defaults to false
+	//
+	// 默认情况下结果合并到私有变量，后续节点通过 priVars.score 读取，而顶层的 score 保持不变；
+	// 开启后结果写入顶层输入，读取 score 的 switch 等节点可以按新计算的字段路由，
+	// 同名的原始字段被覆盖。结果不能包含 priVars 键。
+	// By default the result is merged into the private variables, read by the following nodes as
+	// priVars.score while the top-level score is left unchanged. With ToInput the result is written into
+	// the top-level input, so the switch and other nodes reading score route on the computed fields,
+	// the original fields of the same name are overwritten. The result must not have a priVars key.
+	ToInput bool `json:"toInput"`
 }
 
 // ExprAssignNode 使用JavaScript确定消息路由路径的开关节点
@@ -67,6 +80,10 @@ type ExprAssignNode struct {
 	// Config 节点配置
 	Config ExprAssignNodeConfiguration
 
+	// config 规则引擎配置
+	// config is the rule engine configuration
+	config types.Config
+
 	// program 用于高效评估的编译表达式
 	// program is the compiled expression for efficient evaluation
 	program *vm.Program
@@ -78,6 +95,18 @@ func (x *ExprAssignNode) Type() types.NodeType {
 	return types.RuleSubTypeExprAssign
 }
 
+// Category 返回组件类别
+// Category returns the component category.
+func (x *ExprAssignNode) Category() string {
+	return types.CategoryTransform
+}
+
+// Relations 返回组件可能路由到的关系
+// Relations returns the relation types the component can route a message to.
+func (x *ExprAssignNode) Relations() []string {
+	return []string{types.DefaultRelationType}
+}
+
 // New 创建新实例
 func (x *ExprAssignNode) New() types.Node {
 	return &ExprAssignNode{Config: ExprAssignNodeConfiguration{
@@ -87,12 +116,13 @@ func (x *ExprAssignNode) New() types.Node {
 
 // Init 初始化节点
 func (x *ExprAssignNode) Init(config types.Config, configuration types.Configuration) error {
+	x.config = config
 	err := maps.Map2Struct(configuration, &x.Config)
 	if err != nil {
 		return err
 	}
 
-	program, err := expr.Compile(x.Config.Script, expr.AllowUndefinedVariables(), expr.AsKind(reflect.Map))
+	program, err := expr.Compile(x.Config.Script, base.NodeUtils.ExprOptions(config, expr.AsKind(reflect.Map))...)
 	if err != nil {
 		return err
 	}
@@ -103,12 +133,21 @@ func (x *ExprAssignNode) Init(config types.Config, configuration types.Configura
 
 // OnMsg 处理消息，执行JavaScript脚本确定路由路径
 func (x *ExprAssignNode) OnMsg(ctx context.Context, msg types.RuleMsg) (next string, err error) {
-	out, err := vm.Run(x.program, msg.GetInput())
+	out, err := vm.Run(x.program, base.NodeUtils.ExprProgramEnv(ctx, x.config, x.program, msg))
 	if err != nil {
 		return "", err
 	}
 	if result, ok := out.(map[string]any); ok {
-		msg.CopyInnerData(result)
+		if !x.Config.ToInput {
+			msg.CopyInnerData(result)
+			return types.DefaultRelationType, nil
+		}
+		if _, ok := result[types.PriVarsKey]; ok {
+			return "", fmt.Errorf("%s is reserved for the private variables", types.PriVarsKey)
+		}
+		for key, value := range result {
+			msg.SetField(key, value)
+		}
 		return types.DefaultRelationType, nil
 	}
 	return "", errors.New("返回类型不匹配")
@@ -0,0 +1,65 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transform
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bittoy/rule/types"
+	"github.com/rulego/rulego/test/assert"
+)
+
+// TestExprAssignToInput checks that an assignment into the input is seen by the expressions reading top-level
+// fields, without modifying the input of the caller, and that other assignments go to the private variables.
+func TestExprAssignToInput(t *testing.T) {
+	assign := func(toInput bool, script string) (types.RuleMsg, map[string]any, error) {
+		node := &ExprAssignNode{}
+		assert.Nil(t, node.Init(types.NewConfig(), types.Configuration{"script": script, "toInput": toInput}))
+		input := map[string]any{"amount": 8, "score": 1}
+		msg := types.NewRuleMsg("", 0, input)
+		relation, err := node.OnMsg(context.Background(), msg)
+		if err == nil {
+			assert.Equal(t, types.DefaultRelationType, relation)
+		}
+		return msg, input, err
+	}
+	level := &ExprSwitchNode{}
+	assert.Nil(t, level.Init(types.NewConfig(), types.Configuration{"script": "score > 10 ? 'high' : 'default'"}))
+
+	msg, input, err := assign(true, "{'score': amount * 2}")
+	assert.Nil(t, err)
+	score, _ := msg.Field("score")
+	assert.Equal(t, 16, score)
+	assert.Equal(t, 1, input["score"])
+	relation, err := level.OnMsg(context.Background(), msg)
+	assert.Nil(t, err)
+	assert.Equal(t, "high", relation)
+
+	msg, _, err = assign(false, "{'score': amount * 2}")
+	assert.Nil(t, err)
+	score, _ = msg.Field("score")
+	assert.Equal(t, 1, score)
+	assert.Equal(t, 16, msg.GetPrivateVars()["score"])
+	relation, err = level.OnMsg(context.Background(), msg)
+	assert.Nil(t, err)
+	assert.Equal(t, types.DefaultRelationType, relation)
+
+	_, _, err = assign(true, "{'priVars': {'score': 2}}")
+	assert.NotNil(t, err)
+	assert.NotNil(t, (&ExprAssignNode{}).Init(types.NewConfig(), types.Configuration{"script": "{'score': amount *}"}))
+}
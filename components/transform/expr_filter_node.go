@@ -7,16 +7,18 @@ package transform
 //        "name": "表达式过滤器",
 //        "debugMode": false,
 //        "configuration": {
-//          "expr": "msg.temperature > 50"
+//          "script": "msgType == 'TELEMETRY' && msg.temperature > 50"
 //        }
 //      }
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	"github.com/expr-lang/expr"
 	"github.com/expr-lang/expr/vm"
 
+	"github.com/bittoy/rule/components/base"
 	"github.com/bittoy/rule/types"
 	"github.com/bittoy/rule/utils/maps"
 )
@@ -32,14 +34,19 @@ func init() {
 type ExprFilterNodeConfiguration struct {
 	// Expr 用于过滤评估的表达式，必须返回布尔值
 	// Expr contains the expression to evaluate for filtering.
-	// The expression has access to the following variables:
+	// The expression has access to the following variables, see base.NodeUtils.ExprEnv:
+	//   - the input fields at the top level, e.g. temperature
+	//   - msg: Message input (object)
 	//   - id: Message ID (string)
-	//   - ts: Message timestamp (int64)
-	//   - data: Original message data (string)
-	//   - msg: Parsed message body (object for JSON, string otherwise)
-	//   - metadata: Message metadata (object with key-value pairs)
-	//   - type: Message type (string)
+	//   - ts: Message timestamp in milliseconds (int64)
+	//   - msgType: Message type (string)
 	//   - dataType: Message data type (string)
+	//   - metadata: Message metadata (object with key-value pairs)
+	//   - global: Global properties (object)
+	//   - nodes: Outputs of the executed nodes by node id (object)
+	//
+	// An input field named msg, id, ts, msgType, dataType or nodes takes precedence over the variable.
+	// 名为 msg、id、ts、msgType、dataType 或 nodes 的输入字段优先于同名变量。
 	//
 	// The expression must evaluate to a boolean value:
 	//   - true: Message passes the filter (routed to "True" relation)
@@ -49,11 +56,25 @@ type ExprFilterNodeConfiguration struct {
 	// 表达式示例：
 	//   - "msg.temperature > 50"
 	//   - "metadata.deviceType == 'sensor' && msg.value > 100"
-	//   - "type == 'TELEMETRY' && data contains 'alarm'"
-	//   - "ts > 1640995200 && msg.status == 'active'"
+	//   - "msgType == 'TELEMETRY' && msg.status contains 'alarm'"
+	//   - "ts > 1640995200000 && msg.status == 'active'"
 	Script string `json:"script"`
+	// OnEvalError 表达式求值出错时的处理方式：fail（默认，终止规则链）、false（视为 false）或 error（路由到 error 关系，错误信息写入私有变量 error）
+	// OnEvalError is how an expression failing to evaluate, e.g. on a message lacking a field, is handled:
+	//   - fail: the node fails, the default
+	//   - false: the message is routed to the "False" relation
+	//   - error: the message is routed to the "error" relation, with the error message in the private variable error
+	OnEvalError string `json:"onEvalError"`
 }
 
+// Evaluation error handling modes of the exprFilter node, see ExprFilterNodeConfiguration.OnEvalError.
+// exprFilter 节点表达式求值出错时的处理方式，参见 ExprFilterNodeConfiguration.OnEvalError。
+const (
+	EvalErrorFail  = "fail"
+	EvalErrorFalse = "false"
+	EvalErrorRoute = types.ErrorRelationType
+)
+
 // ExprFilterNode 使用expr-lang表达式进行布尔评估来过滤消息的过滤组件
 // ExprFilterNode filters messages using expr-lang expressions for boolean evaluation.
 //
@@ -75,6 +96,10 @@ type ExprFilterNode struct {
 	// Config holds the expression filter configuration
 	Config ExprFilterNodeConfiguration
 
+	// config 规则引擎配置
+	// config is the rule engine configuration
+	config types.Config
+
 	// program 用于高效评估的编译表达式
 	// program is the compiled expression for efficient evaluation
 	program *vm.Program
@@ -86,6 +111,18 @@ func (x *ExprFilterNode) Type() types.NodeType {
 	return types.RuleSubTypeExprFilter
 }
 
+// Category 返回组件类别
+// Category returns the component category.
+func (x *ExprFilterNode) Category() string {
+	return types.CategoryFilter
+}
+
+// Relations 返回组件可能路由到的关系，onEvalError 为 error 时还会路由到 error
+// Relations returns the relation types the component can route a message to, plus error when onEvalError is error.
+func (x *ExprFilterNode) Relations() []string {
+	return []string{types.TrueRelationType, types.FalseRelationType}
+}
+
 // New 创建新实例
 // New creates a new instance.
 func (x *ExprFilterNode) New() types.Node {
@@ -97,12 +134,20 @@ func (x *ExprFilterNode) New() types.Node {
 // Init 初始化组件，验证并编译表达式
 // Init initializes the component.
 func (x *ExprFilterNode) Init(ruleConfig types.Config, configuration types.Configuration) error {
+	x.config = ruleConfig
 	err := maps.Map2Struct(configuration, &x.Config)
 	if err != nil {
 		return err
 	}
+	switch x.Config.OnEvalError {
+	case "":
+		x.Config.OnEvalError = EvalErrorFail
+	case EvalErrorFail, EvalErrorFalse, EvalErrorRoute:
+	default:
+		return fmt.Errorf("unknown onEvalError %s, must be fail, false or error", x.Config.OnEvalError)
+	}
 
-	program, err := expr.Compile(x.Config.Script, expr.AllowUndefinedVariables(), expr.AsBool())
+	program, err := expr.Compile(x.Config.Script, base.NodeUtils.ExprOptions(ruleConfig, expr.AsBool())...)
 	if err != nil {
 		return err
 	}
@@ -115,8 +160,15 @@ func (x *ExprFilterNode) Init(ruleConfig types.Config, configuration types.Confi
 // OnMsg 处理消息，通过评估编译的表达式来过滤消息
 // OnMsg processes incoming messages by evaluating the compiled expression.
 func (x *ExprFilterNode) OnMsg(ctx context.Context, msg types.RuleMsg) (string, error) {
-	out, err := vm.Run(x.program, msg.GetInput())
+	out, err := vm.Run(x.program, base.NodeUtils.ExprProgramEnv(ctx, x.config, x.program, msg))
 	if err != nil {
+		switch x.Config.OnEvalError {
+		case EvalErrorFalse:
+			return types.FalseRelationType, nil
+		case EvalErrorRoute:
+			msg.SetPrivateVar(types.ErrorKey, err.Error())
+			return types.ErrorRelationType, nil
+		}
 		return "", err
 	}
 	if result, ok := out.(bool); ok {
@@ -0,0 +1,70 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transform
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bittoy/rule/types"
+	"github.com/rulego/rulego/test/assert"
+)
+
+// TestExprFilterGlobal checks that the filter expression reads the global properties, under a configurable name.
+func TestExprFilterGlobal(t *testing.T) {
+	filter := func(config types.Config, script string) string {
+		node := &ExprFilterNode{}
+		assert.Nil(t, node.Init(config, types.Configuration{"script": script}))
+		relation, err := node.OnMsg(context.Background(), types.NewRuleMsg("", 0, map[string]any{"temperature": 60}))
+		assert.Nil(t, err)
+		return relation
+	}
+	config := types.NewConfig(types.WithProperties(types.Properties{"env": "prod"}))
+	assert.Equal(t, types.TrueRelationType, filter(config, "global.env == 'prod' && temperature > 50"))
+	assert.Equal(t, types.FalseRelationType, filter(config, "global.env == 'dev'"))
+
+	config = types.NewConfig(types.WithProperties(types.Properties{"env": "prod"}), types.WithScriptEnvKeys("props", ""))
+	assert.Equal(t, types.TrueRelationType, filter(config, "props.env == 'prod'"))
+}
+
+// TestExprFilterEvalError checks the onEvalError handling of an expression failing to evaluate.
+func TestExprFilterEvalError(t *testing.T) {
+	filter := func(onEvalError string, amount any) (types.RuleMsg, string, error) {
+		node := &ExprFilterNode{}
+		assert.Nil(t, node.Init(types.NewConfig(), types.Configuration{"script": "amount > 10", "onEvalError": onEvalError}))
+		msg := types.NewRuleMsg("", 0, map[string]any{"amount": amount})
+		relation, err := node.OnMsg(context.Background(), msg)
+		return msg, relation, err
+	}
+	_, relation, err := filter(EvalErrorRoute, 20)
+	assert.Nil(t, err)
+	assert.Equal(t, types.TrueRelationType, relation)
+
+	msg, relation, err := filter(EvalErrorRoute, "n/a")
+	assert.Nil(t, err)
+	assert.Equal(t, types.ErrorRelationType, relation)
+	assert.NotEqual(t, "", msg.GetPrivateVars()[types.ErrorKey])
+
+	_, relation, err = filter(EvalErrorFalse, "n/a")
+	assert.Nil(t, err)
+	assert.Equal(t, types.FalseRelationType, relation)
+
+	_, _, err = filter("", "n/a")
+	assert.NotNil(t, err)
+
+	assert.NotNil(t, (&ExprFilterNode{}).Init(types.NewConfig(), types.Configuration{"script": "amount > 10", "onEvalError": "skip"}))
+}
@@ -22,6 +22,7 @@ import (
 	"reflect"
 	"strings"
 
+	"github.com/bittoy/rule/components/base"
 	"github.com/bittoy/rule/types"
 	"github.com/bittoy/rule/utils/maps"
 
@@ -50,6 +51,11 @@ type ExprSwitchNodeConfiguration struct {
 	// student=="3" ? "A" : ((score > 75 && level == "B")|| student == "C") ? "B" : (score > 60) ? "C" : "Default"
 	Script string `json:"script"`
 
+	// Cases 声明式路由分支，Script 为空时用于生成路由表达式
+	// case 为 expr 条件表达式，then 为关系名称（无需加引号），最后一个分支的 case 必须为 "other"
+	// Cases are the declarative branches generating the expression when Script is empty: case is an expr
+	// condition and then the relation name, no quotes needed, see types.Case.Relation
+	// 示例: [{"case": "score > 60", "then": "pass"}, {"case": "other", "then": "default"}]
 	Cases []types.Case `json:"cases"`
 }
 
@@ -85,6 +91,10 @@ type ExprSwitchNode struct {
 	// Config holds the switch node configuration
 	Config ExprSwitchNodeConfiguration
 
+	// config 规则引擎配置
+	// config is the rule engine configuration
+	config types.Config
+
 	// program 用于高效评估的编译表达式
 	// program is the compiled expression for efficient evaluation
 	program *vm.Program
@@ -96,6 +106,18 @@ func (x *ExprSwitchNode) Type() types.NodeType {
 	return types.RuleSubTypeExprSwitch
 }
 
+// Category 返回组件类别
+// Category returns the component category.
+func (x *ExprSwitchNode) Category() string {
+	return types.CategorySwitch
+}
+
+// Relations 返回组件可能路由到的关系，分支关系由配置决定
+// Relations returns the relation types the component can route a message to, the case relations depend on the configuration.
+func (x *ExprSwitchNode) Relations() []string {
+	return []string{types.DefaultRelationType, types.DynamicRelationType}
+}
+
 // New 创建新实例
 // New creates a new instance.
 func (x *ExprSwitchNode) New() types.Node {
@@ -107,6 +129,7 @@ func (x *ExprSwitchNode) New() types.Node {
 // Init 初始化组件，编译所有case表达式
 // Init initializes the component.
 func (x *ExprSwitchNode) Init(config types.Config, configuration types.Configuration) error {
+	x.config = config
 	err := maps.Map2Struct(configuration, &x.Config)
 	if err != nil {
 		return err
@@ -121,7 +144,7 @@ func (x *ExprSwitchNode) Init(config types.Config, configuration types.Configura
 		script = caseScript
 	}
 
-	program, err := expr.Compile(script, expr.AllowUndefinedVariables(), expr.AsKind(reflect.String))
+	program, err := expr.Compile(script, base.NodeUtils.ExprOptions(config, expr.AsKind(reflect.String))...)
 	if err != nil {
 		return err
 	}
@@ -134,7 +157,7 @@ func (x *ExprSwitchNode) Init(config types.Config, configuration types.Configura
 // OnMsg 处理消息，按顺序评估case表达式并路由到第一个匹配的case或默认关系
 // OnMsg processes incoming messages by evaluating case expressions sequentially.
 func (x *ExprSwitchNode) OnMsg(ctx context.Context, msg types.RuleMsg) (string, error) {
-	out, err := vm.Run(x.program, msg.GetInput())
+	out, err := vm.Run(x.program, base.NodeUtils.ExprProgramEnv(ctx, x.config, x.program, msg))
 	if err != nil {
 		return "", err
 	}
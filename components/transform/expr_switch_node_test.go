@@ -0,0 +1,58 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transform
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bittoy/rule/types"
+	"github.com/rulego/rulego/test/assert"
+)
+
+// TestExprSwitchGlobal checks that the switch cases read the global properties.
+func TestExprSwitchGlobal(t *testing.T) {
+	node := &ExprSwitchNode{}
+	config := types.NewConfig(types.WithProperties(types.Properties{"env": "prod"}))
+	assert.Nil(t, node.Init(config, types.Configuration{"cases": []types.Case{
+		{Case: "global.env == 'dev'", Then: "dev"},
+		{Case: "global.env == 'prod' && amount > 10", Then: "prod"},
+		{Case: "other", Then: types.DefaultRelationType},
+	}}))
+	relation, err := node.OnMsg(context.Background(), types.NewRuleMsg("", 0, map[string]any{"amount": 20}))
+	assert.Nil(t, err)
+	assert.Equal(t, "prod", relation)
+	relation, err = node.OnMsg(context.Background(), types.NewRuleMsg("", 0, map[string]any{"amount": 5}))
+	assert.Nil(t, err)
+	assert.Equal(t, types.DefaultRelationType, relation)
+}
+
+// TestExprSwitchCases checks that the then relations of the switch cases may be quoted or not.
+func TestExprSwitchCases(t *testing.T) {
+	node := &ExprSwitchNode{}
+	assert.Nil(t, node.Init(types.NewConfig(), types.Configuration{"cases": []types.Case{
+		{Case: "amount > 100", Then: `"1"`},
+		{Case: "amount > 10", Then: "2"},
+		{Case: "amount > 5", Then: "'3'"},
+		{Case: "other", Then: types.DefaultRelationType},
+	}}))
+	for amount, relation := range map[int]string{200: "1", 20: "2", 8: "3", 1: types.DefaultRelationType} {
+		got, err := node.OnMsg(context.Background(), types.NewRuleMsg("", 0, map[string]any{"amount": amount}))
+		assert.Nil(t, err)
+		assert.Equal(t, relation, got, amount)
+	}
+}
@@ -0,0 +1,186 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transform
+
+//规则链节点配置示例：
+//{
+//        "id": "s13",
+//        "type": "fingerprint",
+//        "name": "设备指纹",
+//        "configuration": {
+//          "fields": ["deviceId", "client.ip", "client.userAgent"],
+//          "algorithm": "sha256",
+//          "outputKey": "fingerprint"
+//        }
+//      }
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+
+	"github.com/bittoy/rule/types"
+	"github.com/bittoy/rule/utils/maps"
+)
+
+// Fingerprint hash algorithms.
+// 指纹哈希算法。
+const (
+	FingerprintSHA256 = "sha256"
+	FingerprintSHA512 = "sha512"
+	FingerprintSHA1   = "sha1"
+	FingerprintMD5    = "md5"
+)
+
+// DefaultFingerprintOutputKey 默认的指纹私有变量键
+// DefaultFingerprintOutputKey is the default private variable key of the fingerprint
+const DefaultFingerprintOutputKey = "fingerprint"
+
+// fingerprintHashes creates the hash of every algorithm
+var fingerprintHashes = map[string]func() hash.Hash{
+	FingerprintSHA256: sha256.New,
+	FingerprintSHA512: sha512.New,
+	FingerprintSHA1:   sha1.New,
+	FingerprintMD5:    md5.New,
+}
+
+func init() {
+	Registry.Add(&FingerprintNode{})
+}
+
+// FingerprintNodeConfiguration FingerprintNode配置结构
+// FingerprintNodeConfiguration defines the configuration structure for the FingerprintNode component.
+type FingerprintNodeConfiguration struct {
+	// Fields 参与计算的字段路径，嵌套字段用 . 分隔，如 client.ip
+	// Fields are the paths of the hashed fields, nested fields are separated by dots, e.g. client.ip
+	Fields []string `json:"fields"`
+	// Algorithm 哈希算法：sha256、sha512、sha1 或 md5，默认为 sha256
+	// Algorithm is the hash algorithm: sha256, sha512, sha1 or md5, defaults to sha256
+	Algorithm string `json:"algorithm"`
+	// OutputKey 保存指纹的私有变量键，默认为 fingerprint
+	// OutputKey is the private variable key holding the fingerprint, defaults to fingerprint
+	OutputKey string `json:"outputKey"`
+}
+
+// FingerprintNode 计算所选字段的稳定哈希的组件
+// FingerprintNode computes a stable hash over the selected fields and writes its hex encoding to the
+// private variable OutputKey, then forwards the message to "default". The fingerprint serves as a
+// dedupe or idempotency key, or as a device fingerprint.
+//
+// 指纹按字段路径排序后，以字段路径到字段值的 JSON 对象计算，对象的键同样排序，因此与 Fields 的顺序和输入中映射的
+// 遍历顺序无关。缺失的字段按 null 计算。使用 encoding/json 编码，不受 types.Config.JSONCodec 影响，
+// 因此相同的值在不同配置下得到相同的指纹；数值按其 JSON 形式计算，3 和 3.0 相同，但与 "3" 不同。
+// The fingerprint hashes the JSON object mapping the field paths to the field values, whose keys, like
+// those of the nested objects, are sorted, so it does not depend on the order of Fields or on the
+// iteration order of the maps in the input. A missing field is hashed as null. The object is encoded with
+// encoding/json regardless of types.Config.JSONCodec, so equal values get the same fingerprint under any
+// configuration; numbers are hashed in their JSON form, 3 and 3.0 are equal but differ from "3".
+type FingerprintNode struct {
+	// Config 节点配置
+	// Config holds the fingerprint node configuration
+	Config FingerprintNodeConfiguration
+
+	// newHash 创建配置的哈希
+	// newHash creates the configured hash
+	newHash func() hash.Hash
+}
+
+// Type 返回组件类型
+// Type returns the component type identifier.
+func (x *FingerprintNode) Type() types.NodeType {
+	return types.RuleSubTypeFingerprint
+}
+
+// Category 返回组件类别
+// Category returns the component category.
+func (x *FingerprintNode) Category() string {
+	return types.CategoryTransform
+}
+
+// Relations 返回组件可能路由到的关系
+// Relations returns the relation types the component can route a message to.
+func (x *FingerprintNode) Relations() []string {
+	return []string{types.DefaultRelationType}
+}
+
+// New 创建新实例
+// New creates a new instance.
+func (x *FingerprintNode) New() types.Node {
+	return &FingerprintNode{Config: FingerprintNodeConfiguration{
+		Algorithm: FingerprintSHA256,
+		OutputKey: DefaultFingerprintOutputKey,
+	}}
+}
+
+// Init 初始化组件，校验字段和哈希算法
+// Init initializes the component, checking the fields and the hash algorithm.
+func (x *FingerprintNode) Init(config types.Config, configuration types.Configuration) error {
+	err := maps.Map2Struct(configuration, &x.Config)
+	if err != nil {
+		return err
+	}
+	if len(x.Config.Fields) == 0 {
+		return errors.New("fields must not be empty")
+	}
+	for i, field := range x.Config.Fields {
+		x.Config.Fields[i] = strings.TrimSpace(field)
+		if x.Config.Fields[i] == "" {
+			return errors.New("field must not be empty")
+		}
+	}
+	if x.Config.Algorithm == "" {
+		x.Config.Algorithm = FingerprintSHA256
+	}
+	var ok bool
+	if x.newHash, ok = fingerprintHashes[x.Config.Algorithm]; !ok {
+		return fmt.Errorf("unknown algorithm %s, must be sha256, sha512, sha1 or md5", x.Config.Algorithm)
+	}
+	if strings.TrimSpace(x.Config.OutputKey) == "" {
+		x.Config.OutputKey = DefaultFingerprintOutputKey
+	}
+	return nil
+}
+
+// OnMsg 处理消息，计算所选字段的指纹
+// OnMsg computes the fingerprint of the selected fields.
+func (x *FingerprintNode) OnMsg(ctx context.Context, msg types.RuleMsg) (string, error) {
+	values := make(map[string]any, len(x.Config.Fields))
+	for _, field := range x.Config.Fields {
+		values[field] = fieldValue(msg, field)
+	}
+	// encoding/json sorts the map keys, so the encoding is canonical
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", fmt.Errorf("fingerprint fields cannot be encoded:%w", err)
+	}
+	h := x.newHash()
+	h.Write(data)
+	msg.SetPrivateVar(x.Config.OutputKey, hex.EncodeToString(h.Sum(nil)))
+	return types.DefaultRelationType, nil
+}
+
+// Destroy 清理资源
+// Destroy cleans up resources.
+func (x *FingerprintNode) Destroy() {
+}
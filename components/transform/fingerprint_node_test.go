@@ -0,0 +1,61 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transform
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bittoy/rule/types"
+	"github.com/rulego/rulego/test/assert"
+)
+
+// TestFingerprint checks that the fingerprint only depends on the selected fields.
+func TestFingerprint(t *testing.T) {
+	newNode := func(algorithm string) *FingerprintNode {
+		node := &FingerprintNode{}
+		assert.Nil(t, node.Init(types.NewConfig(), types.Configuration{"fields": []string{"deviceId", " client.ip "}, "algorithm": algorithm}))
+		return node
+	}
+	fingerprint := func(node *FingerprintNode, input map[string]any) string {
+		msg := types.NewRuleMsg("", 0, input)
+		relation, err := node.OnMsg(context.Background(), msg)
+		assert.Nil(t, err)
+		assert.Equal(t, types.DefaultRelationType, relation)
+		return msg.GetPrivateVars()[DefaultFingerprintOutputKey].(string)
+	}
+	node := newNode("")
+	first := fingerprint(node, map[string]any{"deviceId": "d1", "client": map[string]any{"ip": "10.0.0.1", "port": 1}})
+	assert.Equal(t, 64, len(first))
+	assert.Equal(t, first, fingerprint(node, map[string]any{"amount": 3, "client": map[string]any{"port": 2, "ip": "10.0.0.1"}, "deviceId": "d1"}))
+	assert.NotEqual(t, first, fingerprint(node, map[string]any{"deviceId": "d1", "client": map[string]any{"ip": "10.0.0.2"}}))
+	assert.NotEqual(t, first, fingerprint(node, map[string]any{"deviceId": "d1"}))
+
+	assert.Equal(t, 32, len(fingerprint(newNode(FingerprintMD5), map[string]any{"deviceId": "d1"})))
+	assert.Equal(t, 128, len(fingerprint(newNode(FingerprintSHA512), map[string]any{"deviceId": "d1"})))
+}
+
+// TestFingerprintInit checks the validation of the fingerprint configuration.
+func TestFingerprintInit(t *testing.T) {
+	for _, configuration := range []types.Configuration{
+		{},
+		{"fields": []string{"deviceId", " "}},
+		{"fields": []string{"deviceId"}, "algorithm": "crc"},
+	} {
+		assert.NotNil(t, (&FingerprintNode{}).Init(types.NewConfig(), configuration), configuration)
+	}
+}
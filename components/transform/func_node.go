@@ -0,0 +1,215 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transform
+
+//规则链节点配置示例：
+//{
+//        "id": "s4",
+//        "type": "func",
+//        "name": "计算折扣",
+//        "configuration": {
+//          "name": "discount",
+//          "outputKey": "discount"
+//        }
+//      }
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/bittoy/rule/types"
+	"github.com/bittoy/rule/utils/maps"
+)
+
+func init() {
+	Registry.Add(&FuncNode{})
+}
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	inputType   = reflect.TypeOf(map[string]any{})
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// FuncNodeConfiguration FuncNode配置结构
+// FuncNodeConfiguration defines the configuration structure for the FuncNode component.
+type FuncNodeConfiguration struct {
+	// Name 在 Config.Udf 中注册的 Go 函数名称
+	// Name is the name of the Go function registered in Config.Udf
+	Name string `json:"name"`
+	// OutputKey 保存函数结果的私有变量键，为空时函数必须返回 map[string]any 并合并到私有变量中
+	// OutputKey is the private variable key holding the function result. When empty the function
+	// must return a map[string]any, which is merged into the private variables
+	OutputKey string `json:"outputKey"`
+	// SideEffect 函数是否有副作用（如写数据库、调用外部服务），试运行时不调用该函数，只记录预期调用
+	// SideEffect marks a function with side effects (e.g. database writes, external calls). In dry-run
+	// mode the function is not called, the intended call is logged instead, see types.IsDryRun
+	SideEffect bool `json:"sideEffect"`
+}
+
+// FuncNode 直接调用注册的 Go 函数的组件
+// FuncNode invokes a Go function registered with Config.RegisterUdf, so native Go logic
+// can run in a chain without writing a full component.
+//
+// 函数按以下顺序查找：Go#name（types.Script{Type: "Go"} 注册），然后是 name。
+// The function is looked up as Go#name (registered as types.Script{Type: "Go"}), then as name.
+//
+// 支持的函数签名：
+// Supported signatures:
+//
+//	func(input map[string]any) R
+//	func(input map[string]any) (R, error)
+//	func(ctx context.Context, input map[string]any) R
+//	func(ctx context.Context, input map[string]any) (R, error)
+//
+// 与 exprAssign 相同，结果写入消息的私有变量。函数 panic 时节点以错误失败，不影响引擎。
+// As with exprAssign, the result is written to the private variables of the message. A panicking
+// function fails the node with an error instead of crashing the engine.
+type FuncNode struct {
+	// Config 节点配置
+	// Config holds the func node configuration
+	Config FuncNodeConfiguration
+
+	// config 规则引擎配置，用于记录试运行时跳过的调用
+	// config is the rule engine configuration, used to log the calls skipped in dry-run mode
+	config types.Config
+
+	// fn 注册的函数
+	// fn is the registered function
+	fn reflect.Value
+
+	// withContext 函数的第一个参数是否为 context.Context
+	// withContext reports whether the first argument of the function is a context.Context
+	withContext bool
+}
+
+// Type 返回组件类型
+// Type returns the component type identifier.
+func (x *FuncNode) Type() types.NodeType {
+	return types.RuleSubTypeFunc
+}
+
+// Category 返回组件类别
+// Category returns the component category.
+func (x *FuncNode) Category() string {
+	return types.CategoryTransform
+}
+
+// Relations 返回组件可能路由到的关系
+// Relations returns the relation types the component can route a message to.
+func (x *FuncNode) Relations() []string {
+	return []string{types.DefaultRelationType}
+}
+
+// New 创建新实例
+// New creates a new instance.
+func (x *FuncNode) New() types.Node {
+	return &FuncNode{}
+}
+
+// Init 初始化组件，查找并校验注册的函数
+// Init initializes the component, looking up and checking the registered function.
+func (x *FuncNode) Init(config types.Config, configuration types.Configuration) error {
+	err := maps.Map2Struct(configuration, &x.Config)
+	if err != nil {
+		return err
+	}
+	x.config = config
+	x.Config.Name = strings.TrimSpace(x.Config.Name)
+	if x.Config.Name == "" {
+		return errors.New("name must not be empty")
+	}
+	f, ok := config.Udf[types.Go+types.ScriptFuncSeparator+x.Config.Name]
+	if !ok {
+		f, ok = config.Udf[x.Config.Name]
+	}
+	if !ok {
+		return fmt.Errorf("udf %s not found", x.Config.Name)
+	}
+	if script, ok := f.(types.Script); ok {
+		f = script.Content
+	}
+	fn := reflect.ValueOf(f)
+	if fn.Kind() != reflect.Func {
+		return fmt.Errorf("udf %s is not a function", x.Config.Name)
+	}
+	fnType := fn.Type()
+	switch {
+	case fnType.NumIn() == 1 && inputType.AssignableTo(fnType.In(0)):
+	case fnType.NumIn() == 2 && fnType.In(0) == contextType && inputType.AssignableTo(fnType.In(1)):
+		x.withContext = true
+	default:
+		return fmt.Errorf("udf %s must accept (map[string]any) or (context.Context, map[string]any)", x.Config.Name)
+	}
+	switch {
+	case fnType.NumOut() == 1 && fnType.Out(0) != errorType:
+	case fnType.NumOut() == 2 && fnType.Out(1) == errorType:
+	default:
+		return fmt.Errorf("udf %s must return (R) or (R, error)", x.Config.Name)
+	}
+	x.fn = fn
+	return nil
+}
+
+// OnMsg 调用函数并将结果写入私有变量
+// OnMsg invokes the function with the message input and writes the result to the private variables.
+func (x *FuncNode) OnMsg(ctx context.Context, msg types.RuleMsg) (string, error) {
+	if x.Config.SideEffect && types.IsDryRun(ctx) {
+		if x.config.Logger != nil {
+			x.config.Logger.Printf("dry run: skip udf %s msgId=%s input=%v", x.Config.Name, msg.Id(), x.config.Redact(msg.GetInput()))
+		}
+		return types.DefaultRelationType, nil
+	}
+	args := []reflect.Value{reflect.ValueOf(msg.GetInput())}
+	if x.withContext {
+		args = append([]reflect.Value{reflect.ValueOf(&ctx).Elem()}, args...)
+	}
+	out, err := x.call(args)
+	if err != nil {
+		return "", err
+	}
+	if len(out) == 2 && !out[1].IsNil() {
+		return "", out[1].Interface().(error)
+	}
+	result := out[0].Interface()
+	if x.Config.OutputKey != "" {
+		msg.SetPrivateVar(x.Config.OutputKey, result)
+		return types.DefaultRelationType, nil
+	}
+	if values, ok := result.(map[string]any); ok {
+		msg.CopyInnerData(values)
+		return types.DefaultRelationType, nil
+	}
+	return "", fmt.Errorf("udf %s must return map[string]any when outputKey is empty", x.Config.Name)
+}
+
+// call calls the function, a panicking function is reported as an error
+func (x *FuncNode) call(args []reflect.Value) (out []reflect.Value, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("udf %s panic: %v", x.Config.Name, r)
+		}
+	}()
+	return x.fn.Call(args), nil
+}
+
+// Destroy 清理资源
+// Destroy cleans up resources.
+func (x *FuncNode) Destroy() {
+}
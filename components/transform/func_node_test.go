@@ -0,0 +1,81 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transform
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bittoy/rule/types"
+	"github.com/rulego/rulego/test/assert"
+)
+
+// TestFunc checks the supported signatures, the result handling and the dry run of the func node.
+func TestFunc(t *testing.T) {
+	calls := 0
+	config := types.NewConfig()
+	config.RegisterUdf("double", types.Script{Type: types.Go, Content: func(ctx context.Context, input map[string]any) (int, error) {
+		return input["x"].(int) * 2, nil
+	}})
+	config.RegisterUdf("fields", func(input map[string]any) map[string]any {
+		calls++
+		return map[string]any{"y": input["x"]}
+	})
+	config.RegisterUdf("fail", func(input map[string]any) (int, error) {
+		return 0, errors.New("fail")
+	})
+	config.RegisterUdf("bad", func(x int) int { return x })
+	call := func(configuration types.Configuration, ctx context.Context) (types.RuleMsg, error) {
+		node := &FuncNode{}
+		assert.Nil(t, node.Init(config, configuration))
+		msg := types.NewRuleMsg("", 0, map[string]any{"x": 21})
+		_, err := node.OnMsg(ctx, msg)
+		return msg, err
+	}
+
+	msg, err := call(types.Configuration{"name": "double", "outputKey": "d"}, context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 42, msg.GetPrivateVars()["d"])
+	msg, err = call(types.Configuration{"name": "fields"}, context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 21, msg.GetPrivateVars()["y"])
+	_, err = call(types.Configuration{"name": "fail"}, context.Background())
+	assert.Equal(t, "fail", err.Error())
+
+	msg, err = call(types.Configuration{"name": "fields", "sideEffect": true}, types.ContextWithDryRun(context.Background()))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, calls)
+	assert.Nil(t, msg.GetPrivateVars()["y"])
+
+	assert.NotNil(t, (&FuncNode{}).Init(config, types.Configuration{"name": "bad"}))
+	assert.NotNil(t, (&FuncNode{}).Init(config, types.Configuration{"name": "missing"}))
+}
+
+// TestFuncPanic checks that a panicking function fails the node with an error.
+func TestFuncPanic(t *testing.T) {
+	config := types.NewConfig()
+	config.RegisterUdf("boom", func(input map[string]any) int {
+		panic("boom")
+	})
+	node := &FuncNode{}
+	assert.Nil(t, node.Init(config, types.Configuration{"name": "boom", "outputKey": "d"}))
+	_, err := node.OnMsg(context.Background(), types.NewRuleMsg("", 0, nil))
+	assert.NotNil(t, err)
+	assert.True(t, strings.Contains(err.Error(), "udf boom panic: boom"))
+}
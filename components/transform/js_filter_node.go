@@ -12,14 +12,11 @@ package transform
 //      }
 import (
 	"context"
-	"errors"
 	"fmt"
-	"sync"
 
+	"github.com/bittoy/rule/components/base"
 	"github.com/bittoy/rule/types"
 	"github.com/bittoy/rule/utils/maps"
-
-	"github.com/dop251/goja"
 )
 
 const (
@@ -39,7 +36,7 @@ func init() {
 // JsFilterNodeConfiguration JsFilterNode配置结构
 type JsFilterNodeConfiguration struct {
 	// JsScript JavaScript脚本，用于评估过滤条件
-	// 函数参数：msg, metadata, msgType, dataType
+	// 函数参数：msg, metadata, global（metadata 和 global 的名称可通过 Config.ScriptMetadataKey 和 Config.ScriptGlobalKey 配置）
 	// 必须返回布尔值：true通过过滤，false不通过
 	//
 	// 内置变量：
@@ -57,7 +54,12 @@ type JsFilterNode struct {
 	// Config 节点配置
 	Config JsFilterNodeConfiguration
 
-	pool *sync.Pool
+	// config 规则引擎配置
+	config types.Config
+	// vmPool 共享的 JavaScript VM 池
+	vmPool types.JsVMPool
+	// script 编译后执行的完整脚本，也是 VM 池中的键
+	script string
 }
 
 // Type 返回组件类型
@@ -65,6 +67,18 @@ func (x *JsFilterNode) Type() types.NodeType {
 	return types.RuleSubTypeJsFilter
 }
 
+// Category 返回组件类别
+// Category returns the component category.
+func (x *JsFilterNode) Category() string {
+	return types.CategoryFilter
+}
+
+// Relations 返回组件可能路由到的关系
+// Relations returns the relation types the component can route a message to.
+func (x *JsFilterNode) Relations() []string {
+	return []string{types.TrueRelationType, types.FalseRelationType}
+}
+
 // New 创建新实例
 func (x *JsFilterNode) New() types.Node {
 	return &JsFilterNode{Config: JsFilterNodeConfiguration{
@@ -73,59 +87,30 @@ func (x *JsFilterNode) New() types.Node {
 }
 
 // Init 初始化节点
-func (x *JsFilterNode) Init(ruleConfig types.Config, configuration types.Configuration) error {
+func (x *JsFilterNode) Init(config types.Config, configuration types.Configuration) error {
 	err := maps.Map2Struct(configuration, &x.Config)
 	if err != nil {
 		return err
 	}
 
-	jsScript := fmt.Sprintf("function jsFilter(msg) { %s } jsFilter;", x.Config.Script)
-	program, err := goja.Compile("jsFilter.js", jsScript, true)
-	if err != nil {
+	jsScript := fmt.Sprintf("function jsFilter(%s) { %s } jsFilter;", base.NodeUtils.JsParams(config), x.Config.Script)
+	x.config = config
+	x.vmPool = base.NodeUtils.JsVMPool(config)
+	if err := x.vmPool.Compile("jsFilter.js", jsScript); err != nil {
 		return fmt.Errorf("new js vm err: script:%s", jsScript)
 	}
-
-	// vars, err := base.NodeUtils.GetVars(configuration)
-	// if err != nil {
-	// 	return err
-	// }
-
-	x.pool = &sync.Pool{
-		New: func() any {
-			vm := goja.New()
-			// 每个 vm 运行时执行 prog
-			_, err := vm.RunProgram(program)
-			if err != nil {
-				panic(fmt.Sprintf("failed to run program in new VM: %v", err))
-			}
-			return vm
-		},
-	}
+	x.script = jsScript
 	return nil
 }
 
 // OnMsg 处理消息，执行JavaScript过滤条件
 func (x *JsFilterNode) OnMsg(ctx context.Context, msg types.RuleMsg) (string, error) {
-	vm := x.pool.Get().(*goja.Runtime)
-	defer x.pool.Put(vm)
-
-	fnVal := vm.Get("jsSwitch")
-	if fnVal == nil {
-		return "", errors.New("function jsSwitch not found")
-	}
-
-	f, ok := goja.AssertFunction(fnVal)
-	if !ok {
-		return "", errors.New("jsSwitch is not a function")
-	}
-
-	// Execute function
-	res, err := f(goja.Undefined(), vm.ToValue(msg.GetInput()))
+	res, err := x.vmPool.Call(ctx, x.script, "jsFilter", base.NodeUtils.JsArgs(x.config, msg)...)
 	if err != nil {
 		return "", err
 	}
 
-	if result, ok := res.Export().(bool); ok {
+	if result, ok := res.(bool); ok {
 		if result {
 			return types.TrueRelationType, nil
 		} else {
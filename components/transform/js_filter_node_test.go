@@ -0,0 +1,46 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transform
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bittoy/rule/types"
+	"github.com/rulego/rulego/test/assert"
+)
+
+// TestJsFilter checks that the jsFilter node routes by the boolean the script returns.
+func TestJsFilter(t *testing.T) {
+	filter := func(script string, amount any) (string, error) {
+		node := &JsFilterNode{}
+		assert.Nil(t, node.Init(types.NewConfig(), types.Configuration{"script": script}))
+		return node.OnMsg(context.Background(), types.NewRuleMsg("", 0, map[string]any{"amount": amount}))
+	}
+	relation, err := filter("return msg.amount > 10;", 20)
+	assert.Nil(t, err)
+	assert.Equal(t, types.TrueRelationType, relation)
+	relation, err = filter("return msg.amount > 10;", 5)
+	assert.Nil(t, err)
+	assert.Equal(t, types.FalseRelationType, relation)
+
+	_, err = filter("return 'yes';", 5)
+	assert.Equal(t, JsSwitchReturnFormatErr, err)
+	_, err = filter("throw new Error('boom');", 5)
+	assert.NotNil(t, err)
+	assert.NotNil(t, (&JsFilterNode{}).Init(types.NewConfig(), types.Configuration{"script": "return msg.amount >;"}))
+}
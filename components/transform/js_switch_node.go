@@ -30,12 +30,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"sync"
+	"strings"
 
+	"github.com/bittoy/rule/components/base"
 	"github.com/bittoy/rule/types"
 	"github.com/bittoy/rule/utils/maps"
-
-	"github.com/dop251/goja"
 )
 
 // JsSwitchReturnFormatErr JavaScript脚本必须返回数组
@@ -49,7 +48,7 @@ func init() {
 // JsSwitchNodeConfiguration JsSwitchNode配置结构
 type JsSwitchNodeConfiguration struct {
 	// JsScript JavaScript脚本，用于确定消息路由路径
-	// 函数参数：msg, metadata, msgType, dataType
+	// 函数参数：msg, metadata, global（metadata 和 global 的名称可通过 Config.ScriptMetadataKey 和 Config.ScriptGlobalKey 配置）
 	// 必须返回字符串数组，表示路由关系类型
 	//
 	// 内置变量：
@@ -60,6 +59,12 @@ type JsSwitchNodeConfiguration struct {
 	//
 	// 示例: "return ['route1', 'route2'];"
 	Script string `json:"script"`
+
+	// Cases 声明式路由分支，Script 为空时用于生成路由脚本
+	// case 为 JavaScript 条件表达式，then 为关系名称，最后一个分支的 case 必须为 "other"
+	//
+	// 示例: [{"case": "msg.score > 60", "then": "pass"}, {"case": "other", "then": "default"}]
+	Cases []types.Case `json:"cases"`
 }
 
 // JsSwitchNode 使用JavaScript确定消息路由路径的开关节点
@@ -67,7 +72,12 @@ type JsSwitchNode struct {
 	// Config 节点配置
 	Config JsSwitchNodeConfiguration
 
-	pool *sync.Pool
+	// config 规则引擎配置
+	config types.Config
+	// vmPool 共享的 JavaScript VM 池
+	vmPool types.JsVMPool
+	// script 编译后执行的完整脚本，也是 VM 池中的键
+	script string
 }
 
 // Type 返回组件类型
@@ -75,10 +85,22 @@ func (x *JsSwitchNode) Type() types.NodeType {
 	return types.RuleSubTypeJsSwitch
 }
 
+// Category 返回组件类别
+// Category returns the component category.
+func (x *JsSwitchNode) Category() string {
+	return types.CategorySwitch
+}
+
+// Relations 返回组件可能路由到的关系，分支关系由配置决定
+// Relations returns the relation types the component can route a message to, the case relations depend on the configuration.
+func (x *JsSwitchNode) Relations() []string {
+	return []string{types.DefaultRelationType, types.DynamicRelationType}
+}
+
 // New 创建新实例
 func (x *JsSwitchNode) New() types.Node {
 	return &JsSwitchNode{Config: JsSwitchNodeConfiguration{
-		Script: `return default;`,
+		Script: "",
 	}}
 }
 
@@ -89,53 +111,33 @@ func (x *JsSwitchNode) Init(config types.Config, configuration types.Configurati
 		return err
 	}
 
-	jsScript := fmt.Sprintf("function jsSwitch(msg) { %s } jsSwitch;", x.Config.Script)
-	program, err := goja.Compile("jsSwitch.js", jsScript, true)
-	if err != nil {
-		return fmt.Errorf("new js vm err: script:%s", jsScript)
+	var script = strings.TrimSpace(x.Config.Script)
+	if len(script) == 0 {
+		caseScript, err := genJsScriptByCases(x.Config.Cases)
+		if err != nil {
+			return err
+		}
+		script = caseScript
 	}
 
-	// vars, err := base.NodeUtils.GetVars(configuration)
-	// if err != nil {
-	// 	return err
-	// }
-
-	x.pool = &sync.Pool{
-		New: func() any {
-			vm := goja.New()
-			// 每个 vm 运行时执行 prog
-			_, err := vm.RunProgram(program)
-			if err != nil {
-				panic(fmt.Sprintf("failed to run program in new VM: %v", err))
-			}
-			return vm
-		},
+	jsScript := fmt.Sprintf("function jsSwitch(%s) { %s } jsSwitch;", base.NodeUtils.JsParams(config), script)
+	x.config = config
+	x.vmPool = base.NodeUtils.JsVMPool(config)
+	if err := x.vmPool.Compile("jsSwitch.js", jsScript); err != nil {
+		return fmt.Errorf("new js vm err: script:%s", jsScript)
 	}
+	x.script = jsScript
 	return nil
 }
 
 // OnMsg 处理消息，执行JavaScript脚本确定路由路径
 func (x *JsSwitchNode) OnMsg(ctx context.Context, msg types.RuleMsg) (string, error) {
-	vm := x.pool.Get().(*goja.Runtime)
-	defer x.pool.Put(vm)
-
-	fnVal := vm.Get("jsSwitch")
-	if fnVal == nil {
-		return "", errors.New("function jsSwitch not found")
-	}
-
-	f, ok := goja.AssertFunction(fnVal)
-	if !ok {
-		return "", errors.New("jsSwitch is not a function")
-	}
-
-	// Execute function
-	res, err := f(goja.Undefined(), vm.ToValue(msg.GetInput()))
+	res, err := x.vmPool.Call(ctx, x.script, "jsSwitch", base.NodeUtils.JsArgs(x.config, msg)...)
 	if err != nil {
 		return "", err
 	}
 
-	if result, ok := res.Export().(string); ok {
+	if result, ok := res.(string); ok {
 		return result, nil
 	}
 	return "", JsSwitchReturnFormatErr
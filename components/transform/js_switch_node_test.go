@@ -0,0 +1,57 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transform
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bittoy/rule/types"
+	"github.com/rulego/rulego/test/assert"
+)
+
+// TestJsSwitchGlobal checks that the switch script reads the global properties, under a configurable name.
+func TestJsSwitchGlobal(t *testing.T) {
+	route := func(config types.Config, script string) string {
+		node := &JsSwitchNode{}
+		assert.Nil(t, node.Init(config, types.Configuration{"script": script}))
+		relation, err := node.OnMsg(context.Background(), types.NewRuleMsg("", 0, map[string]any{"amount": 20}))
+		assert.Nil(t, err)
+		return relation
+	}
+	config := types.NewConfig(types.WithProperties(types.Properties{"env": "prod"}))
+	assert.Equal(t, "prod", route(config, "return global.env == 'prod' && msg.amount > 10 ? 'prod' : 'other';"))
+
+	config = types.NewConfig(types.WithProperties(types.Properties{"env": "dev"}), types.WithScriptEnvKeys("props", ""))
+	assert.Equal(t, "dev", route(config, "return props.env;"))
+}
+
+// TestJsSwitchCases checks that the then relations of the switch cases may be quoted or not.
+func TestJsSwitchCases(t *testing.T) {
+	node := &JsSwitchNode{}
+	assert.Nil(t, node.Init(types.NewConfig(), types.Configuration{"cases": []types.Case{
+		{Case: "msg.amount > 100", Then: `"1"`},
+		{Case: "msg.amount > 10", Then: "2"},
+		{Case: "msg.amount > 5", Then: "'3'"},
+		{Case: "other", Then: types.DefaultRelationType},
+	}}))
+	for amount, relation := range map[int]string{200: "1", 20: "2", 8: "3", 1: types.DefaultRelationType} {
+		got, err := node.OnMsg(context.Background(), types.NewRuleMsg("", 0, map[string]any{"amount": amount}))
+		assert.Nil(t, err)
+		assert.Equal(t, relation, got, amount)
+	}
+}
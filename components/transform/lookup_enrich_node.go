@@ -0,0 +1,169 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transform
+
+//规则链节点配置示例：
+//{
+//        "id": "s15",
+//        "type": "lookupEnrich",
+//        "name": "商户信息",
+//        "configuration": {
+//          "key": "'merchant:' + merchantId",
+//          "outputKey": "merchant",
+//          "routeNotFound": true
+//        }
+//      }
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bittoy/rule/components/base"
+	"github.com/bittoy/rule/types"
+	"github.com/bittoy/rule/utils/cast"
+	"github.com/bittoy/rule/utils/maps"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+)
+
+func init() {
+	Registry.Add(&LookupEnrichNode{})
+}
+
+// LookupEnrichNodeConfiguration LookupEnrichNode配置结构
+// LookupEnrichNodeConfiguration defines the configuration structure for the LookupEnrichNode component.
+type LookupEnrichNodeConfiguration struct {
+	// Key 返回查找键的表达式，如 'merchant:' + merchantId
+	// Key is the expression evaluating to the looked up key, e.g. 'merchant:' + merchantId
+	Key string `json:"key"`
+	// OutputKey 保存记录的私有变量键，为空时记录的字段逐个合并到私有变量
+	// OutputKey is the private variable key holding the record, when empty the fields of the record are
+	// merged into the private variables
+	OutputKey string `json:"outputKey"`
+	// RouteNotFound 找不到记录时路由到 notFound，默认为 false，即不做修改路由到 default
+	// RouteNotFound routes to "notFound" when there is no record, defaults to false: the message is then
+	// forwarded to "default" unchanged
+	RouteNotFound bool `json:"routeNotFound"`
+}
+
+// LookupEnrichNode 从键值存储查找记录并合并到消息的组件
+// LookupEnrichNode fetches the record of the key from types.Config.KVStore and merges it into the private
+// variables, then forwards the message to "default". It is the generic enrichment primitive: the store
+// can be backed by Redis or by an in-memory map, see cache.MapKVStore.
+//
+// 查找键为空时视为找不到记录，存储返回错误时节点失败。
+// An empty key counts as no record, a store error fails the node.
+type LookupEnrichNode struct {
+	// Config 节点配置
+	// Config holds the lookup enrich node configuration
+	Config LookupEnrichNodeConfiguration
+
+	// config 规则引擎配置
+	// config is the rule engine configuration
+	config types.Config
+
+	// program 编译后的查找键表达式
+	// program is the compiled key expression
+	program *vm.Program
+}
+
+// Type 返回组件类型
+// Type returns the component type identifier.
+func (x *LookupEnrichNode) Type() types.NodeType {
+	return types.RuleSubTypeLookupEnrich
+}
+
+// Category 返回组件类别
+// Category returns the component category.
+func (x *LookupEnrichNode) Category() string {
+	return types.CategoryTransform
+}
+
+// Relations 返回组件可能路由到的关系
+// Relations returns the relation types the component can route a message to.
+func (x *LookupEnrichNode) Relations() []string {
+	return []string{types.DefaultRelationType, types.NotFoundRelationType}
+}
+
+// New 创建新实例
+// New creates a new instance.
+func (x *LookupEnrichNode) New() types.Node {
+	return &LookupEnrichNode{}
+}
+
+// Init 初始化组件，编译查找键表达式
+// Init initializes the component, compiling the key expression.
+func (x *LookupEnrichNode) Init(config types.Config, configuration types.Configuration) error {
+	x.config = config
+	err := maps.Map2Struct(configuration, &x.Config)
+	if err != nil {
+		return err
+	}
+	if config.KVStore == nil {
+		return errors.New("no key-value store configured, see types.Config.KVStore")
+	}
+	script := strings.TrimSpace(x.Config.Key)
+	if script == "" {
+		return errors.New("key must not be empty")
+	}
+	x.Config.OutputKey = strings.TrimSpace(x.Config.OutputKey)
+	program, err := expr.Compile(script, base.NodeUtils.ExprOptions(config)...)
+	if err != nil {
+		return err
+	}
+	x.program = program
+	return nil
+}
+
+// OnMsg 处理消息，查找记录并合并到私有变量
+// OnMsg looks up the record of the key and merges it into the private variables.
+func (x *LookupEnrichNode) OnMsg(ctx context.Context, msg types.RuleMsg) (string, error) {
+	out, err := vm.Run(x.program, base.NodeUtils.ExprProgramEnv(ctx, x.config, x.program, msg))
+	if err != nil {
+		return "", err
+	}
+	key, err := cast.ToStringE(out)
+	if err != nil {
+		return "", fmt.Errorf("key must be a string:%w", err)
+	}
+	var record map[string]any
+	found := false
+	if key != "" {
+		if record, found, err = x.config.KVStore.Get(ctx, key); err != nil {
+			return "", fmt.Errorf("lookup %s:%w", key, err)
+		}
+	}
+	if !found {
+		if x.Config.RouteNotFound {
+			return types.NotFoundRelationType, nil
+		}
+		return types.DefaultRelationType, nil
+	}
+	if x.Config.OutputKey != "" {
+		msg.SetPrivateVar(x.Config.OutputKey, record)
+	} else {
+		msg.CopyInnerData(record)
+	}
+	return types.DefaultRelationType, nil
+}
+
+// Destroy 清理资源
+// Destroy cleans up resources.
+func (x *LookupEnrichNode) Destroy() {
+}
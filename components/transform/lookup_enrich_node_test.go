@@ -0,0 +1,63 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transform
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bittoy/rule/types"
+	"github.com/bittoy/rule/utils/cache"
+	"github.com/rulego/rulego/test/assert"
+)
+
+// TestLookupEnrich checks that the lookupEnrich node merges the record of the store and routes the misses to notFound.
+func TestLookupEnrich(t *testing.T) {
+	store := cache.NewMapKVStore(map[string]map[string]any{"merchant:m1": {"name": "shop", "risk": "low"}})
+	config := types.NewConfig(types.WithKVStore(store))
+	lookup := func(configuration types.Configuration, merchantId string) (types.RuleMsg, string) {
+		node := &LookupEnrichNode{}
+		assert.Nil(t, node.Init(config, configuration))
+		msg := types.NewRuleMsg("", 0, map[string]any{"merchantId": merchantId})
+		relation, err := node.OnMsg(context.Background(), msg)
+		assert.Nil(t, err)
+		return msg, relation
+	}
+	key := "'merchant:' + merchantId"
+	msg, relation := lookup(types.Configuration{"key": key, "outputKey": " merchant "}, "m1")
+	assert.Equal(t, types.DefaultRelationType, relation)
+	assert.Equal(t, map[string]any{"name": "shop", "risk": "low"}, msg.GetPrivateVars()["merchant"])
+	assert.Nil(t, msg.GetPrivateVars()["name"])
+
+	msg, relation = lookup(types.Configuration{"key": key}, "m1")
+	assert.Equal(t, types.DefaultRelationType, relation)
+	assert.Equal(t, "shop", msg.GetPrivateVars()["name"])
+
+	_, relation = lookup(types.Configuration{"key": key, "outputKey": "merchant", "routeNotFound": true}, "m2")
+	assert.Equal(t, types.NotFoundRelationType, relation)
+	msg, relation = lookup(types.Configuration{"key": key, "outputKey": "merchant"}, "m2")
+	assert.Equal(t, types.DefaultRelationType, relation)
+	assert.Nil(t, msg.GetPrivateVars()["merchant"])
+}
+
+// TestLookupEnrichInit checks that the lookupEnrich node requires a key-value store and a valid key expression.
+func TestLookupEnrichInit(t *testing.T) {
+	config := types.NewConfig(types.WithKVStore(cache.NewMapKVStore(nil)))
+	assert.NotNil(t, (&LookupEnrichNode{}).Init(types.NewConfig(), types.Configuration{"key": "merchantId"}))
+	assert.NotNil(t, (&LookupEnrichNode{}).Init(config, types.Configuration{"key": " "}))
+	assert.NotNil(t, (&LookupEnrichNode{}).Init(config, types.Configuration{"key": "'merchant:' +"}))
+}
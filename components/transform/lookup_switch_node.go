@@ -0,0 +1,214 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transform
+
+//规则链节点配置示例：
+//{
+//        "id": "s7",
+//        "type": "lookupSwitch",
+//        "name": "国家路由",
+//        "configuration": {
+//          "key": "country",
+//          "file": "/etc/rule/country_routes.json",
+//          "refreshInterval": "1m"
+//        }
+//      }
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bittoy/rule/components/base"
+	"github.com/bittoy/rule/types"
+	"github.com/bittoy/rule/utils/cast"
+	"github.com/bittoy/rule/utils/json"
+	"github.com/bittoy/rule/utils/maps"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+)
+
+func init() {
+	Registry.Add(&LookupSwitchNode{})
+}
+
+// LookupSwitchNodeConfiguration LookupSwitchNode配置结构
+// LookupSwitchNodeConfiguration defines the configuration structure for the LookupSwitchNode component.
+//
+// Table、Property 和 File 必须且只能设置一个。
+// Exactly one of Table, Property and File must be set.
+type LookupSwitchNodeConfiguration struct {
+	// Key 返回查找值的表达式，如 country
+	// Key is the expression evaluating to the looked up value, e.g. country
+	Key string `json:"key"`
+	// Table 内联的查找表，值到关系的映射
+	// Table is an inline lookup table mapping values to relations
+	Table map[string]string `json:"table"`
+	// Property 保存查找表的全局属性键，每条消息都读取最新的属性值，值可以是 map[string]any 或 map[string]string
+	// Property is the global property key holding the lookup table, read for every message so updated
+	// properties apply immediately. The value can be a map[string]any or a map[string]string
+	Property string `json:"property"`
+	// File 保存查找表的 JSON 文件路径，文件修改后自动重新加载
+	// File is the path of a JSON file holding the lookup table, reloaded when the file changes
+	File string `json:"file"`
+	// RefreshInterval 检查文件是否修改的间隔，默认为 30s
+	// RefreshInterval is the interval between two checks of the file modification time, defaults to 30s
+	RefreshInterval string `json:"refreshInterval"`
+}
+
+// LookupSwitchNode 根据查找表进行路由的组件
+// LookupSwitchNode routes to the relation the lookup table maps the key value to, or to "default"
+// when the value is not in the table. Unlike the expression based switches the routing is data:
+// the table of a property or a file can change without changing the chain.
+//
+// 文件查找表由后台任务按 RefreshInterval 检查修改时间并原子替换，重新加载失败时记录日志并继续使用之前的表。
+// A file table is checked every RefreshInterval by a background refresher and swapped atomically,
+// a failed reload is logged and the previous table is kept.
+type LookupSwitchNode struct {
+	// Config 节点配置
+	// Config holds the lookup switch node configuration
+	Config LookupSwitchNodeConfiguration
+
+	// config 规则引擎配置
+	// config is the rule engine configuration
+	config types.Config
+
+	// program 编译后的查找值表达式
+	// program is the compiled key expression
+	program *vm.Program
+
+	// table 文件查找表，设置 File 时非空
+	// table is the file lookup table, not nil when File is set
+	table *reloadableFile[map[string]string]
+}
+
+// Type 返回组件类型
+// Type returns the component type identifier.
+func (x *LookupSwitchNode) Type() types.NodeType {
+	return types.RuleSubTypeLookupSwitch
+}
+
+// Category 返回组件类别
+// Category returns the component category.
+func (x *LookupSwitchNode) Category() string {
+	return types.CategorySwitch
+}
+
+// Relations 返回组件可能路由到的关系，分支关系由配置决定
+// Relations returns the relation types the component can route a message to, the case relations depend on the configuration.
+func (x *LookupSwitchNode) Relations() []string {
+	return []string{types.DefaultRelationType, types.DynamicRelationType}
+}
+
+// New 创建新实例
+// New creates a new instance.
+func (x *LookupSwitchNode) New() types.Node {
+	return &LookupSwitchNode{}
+}
+
+// Init 初始化组件，编译查找值表达式并加载查找表
+// Init initializes the component, compiling the key expression and loading the lookup table.
+func (x *LookupSwitchNode) Init(config types.Config, configuration types.Configuration) error {
+	x.config = config
+	err := maps.Map2Struct(configuration, &x.Config)
+	if err != nil {
+		return err
+	}
+	script := strings.TrimSpace(x.Config.Key)
+	if script == "" {
+		return errors.New("key must not be empty")
+	}
+	var sources int
+	for _, set := range []bool{x.Config.Table != nil, x.Config.Property != "", x.Config.File != ""} {
+		if set {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return errors.New("exactly one of table, property and file must be set")
+	}
+	if x.Config.File != "" && base.NodeUtils.IsLint(configuration) {
+		if _, err = parseRefreshInterval(x.Config.RefreshInterval); err != nil {
+			return err
+		}
+	} else if x.Config.File != "" {
+		if x.table, err = newReloadableFile(x.Config.File, x.Config.RefreshInterval, parseLookupTable, config.Logger, "lookupSwitch"); err != nil {
+			return err
+		}
+	}
+	program, err := expr.Compile(script, base.NodeUtils.ExprOptions(config)...)
+	if err != nil {
+		return err
+	}
+	x.program = program
+	return nil
+}
+
+// OnMsg 处理消息，查找值对应的关系
+// OnMsg looks up the relation of the key value.
+func (x *LookupSwitchNode) OnMsg(ctx context.Context, msg types.RuleMsg) (string, error) {
+	out, err := vm.Run(x.program, base.NodeUtils.ExprProgramEnv(ctx, x.config, x.program, msg))
+	if err != nil {
+		return "", err
+	}
+	key, err := cast.ToStringE(out)
+	if err != nil {
+		return "", fmt.Errorf("key must be a string:%w", err)
+	}
+	if relation, ok := x.lookup(key); ok && relation != "" {
+		return relation, nil
+	}
+	return types.DefaultRelationType, nil
+}
+
+// lookup returns the relation of key in the configured table
+func (x *LookupSwitchNode) lookup(key string) (string, bool) {
+	switch {
+	case x.Config.Table != nil:
+		relation, ok := x.Config.Table[key]
+		return relation, ok
+	case x.Config.Property != "":
+		switch table := x.config.Properties.GetValue(x.Config.Property).(type) {
+		case map[string]string:
+			relation, ok := table[key]
+			return relation, ok
+		case map[string]any:
+			relation, ok := table[key]
+			return cast.ToString(relation), ok
+		}
+		return "", false
+	default:
+		relation, ok := x.table.get()[key]
+		return relation, ok
+	}
+}
+
+// parseLookupTable parses a JSON lookup table file
+func parseLookupTable(data []byte) (map[string]string, error) {
+	var table map[string]string
+	err := json.Unmarshal(data, &table)
+	return table, err
+}
+
+// Destroy 清理资源
+// Destroy cleans up resources.
+func (x *LookupSwitchNode) Destroy() {
+	if x.table != nil {
+		x.table.close()
+	}
+}
@@ -0,0 +1,96 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transform
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bittoy/rule/types"
+	"github.com/rulego/rulego/test/assert"
+)
+
+// TestLookupSwitch checks the lookup of the relation in an inline table and in a table of the properties,
+// with the default relation for the misses.
+func TestLookupSwitch(t *testing.T) {
+	route := func(node *LookupSwitchNode, country any) string {
+		relation, err := node.OnMsg(context.Background(), types.NewRuleMsg("", 0, map[string]any{"country": country}))
+		assert.Nil(t, err)
+		return relation
+	}
+	node := &LookupSwitchNode{}
+	assert.Nil(t, node.Init(types.NewConfig(), types.Configuration{"key": "country", "table": map[string]string{"cn": "asia", "fr": ""}}))
+	assert.Equal(t, "asia", route(node, "cn"))
+	assert.Equal(t, types.DefaultRelationType, route(node, "fr"))
+	assert.Equal(t, types.DefaultRelationType, route(node, "us"))
+
+	properties := types.NewProperties()
+	properties.PutValue("regions", map[string]any{"cn": "asia", "us": "america"})
+	node = &LookupSwitchNode{}
+	assert.Nil(t, node.Init(types.NewConfig(types.WithProperties(properties)), types.Configuration{"key": "country", "property": "regions"}))
+	assert.Equal(t, "america", route(node, "us"))
+	assert.Equal(t, types.DefaultRelationType, route(node, "fr"))
+	properties.PutValue("regions", map[string]string{"fr": "europe"})
+	assert.Equal(t, "europe", route(node, "fr"))
+}
+
+// TestLookupSwitchFile checks the lookup in a table file reloaded in the background when it changes.
+func TestLookupSwitchFile(t *testing.T) {
+	file := t.TempDir() + "/regions.json"
+	assert.Nil(t, os.WriteFile(file, []byte(`{"cn":"a"}`), 0o644))
+	node := &LookupSwitchNode{}
+	assert.Nil(t, node.Init(types.NewConfig(), types.Configuration{"key": "country", "file": file, "refreshInterval": "1ms"}))
+	defer node.Destroy()
+	route := func(country string) string {
+		relation, err := node.OnMsg(context.Background(), types.NewRuleMsg("", 0, map[string]any{"country": country}))
+		assert.Nil(t, err)
+		return relation
+	}
+	assert.Equal(t, "a", route("cn"))
+	assert.Equal(t, types.DefaultRelationType, route("us"))
+
+	assert.Nil(t, os.WriteFile(file, []byte(`{"cn":"b","us":"a"}`), 0o644))
+	assert.Nil(t, os.Chtimes(file, time.Now(), time.Now().Add(time.Second)))
+	for deadline := time.Now().Add(time.Second); route("us") != "a" && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, "a", route("us"))
+	assert.Equal(t, "b", route("cn"))
+
+	// A broken file keeps the previous table
+	assert.Nil(t, os.WriteFile(file, []byte(`broken`), 0o644))
+	assert.Nil(t, os.Chtimes(file, time.Now(), time.Now().Add(2*time.Second)))
+	time.Sleep(5 * time.Millisecond)
+	assert.Equal(t, "b", route("cn"))
+
+	assert.NotNil(t, (&LookupSwitchNode{}).Init(types.NewConfig(), types.Configuration{"key": "country", "file": t.TempDir() + "/none.json"}))
+}
+
+// TestLookupSwitchInit checks the configuration checks of the lookupSwitch node.
+func TestLookupSwitchInit(t *testing.T) {
+	for _, configuration := range []types.Configuration{
+		{"table": map[string]string{"cn": "a"}},
+		{"key": "country"},
+		{"key": "country", "table": map[string]string{"cn": "a"}, "property": "regions"},
+		{"key": "country >", "table": map[string]string{"cn": "a"}},
+		{"key": "country", "file": "/nonexistent/regions.json", "refreshInterval": "soon"},
+	} {
+		assert.NotNil(t, (&LookupSwitchNode{}).Init(types.NewConfig(), configuration), configuration)
+	}
+}
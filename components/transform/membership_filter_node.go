@@ -0,0 +1,221 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transform
+
+//规则链节点配置示例：
+//{
+//        "id": "s11",
+//        "type": "membership",
+//        "name": "黑名单",
+//        "configuration": {
+//          "field": "user.id",
+//          "file": "/etc/rule/blacklist.txt",
+//          "refreshInterval": "1m"
+//        }
+//      }
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+
+	"github.com/bittoy/rule/components/base"
+	"github.com/bittoy/rule/types"
+	"github.com/bittoy/rule/utils/cast"
+	"github.com/bittoy/rule/utils/json"
+	"github.com/bittoy/rule/utils/maps"
+)
+
+func init() {
+	Registry.Add(&MembershipFilterNode{})
+}
+
+// MembershipFilterNodeConfiguration MembershipFilterNode配置结构
+// MembershipFilterNodeConfiguration defines the configuration structure for the MembershipFilterNode component.
+//
+// Values 和 File 必须且只能设置一个。
+// Exactly one of Values and File must be set.
+type MembershipFilterNodeConfiguration struct {
+	// Field 检查的字段路径，支持嵌套字段，如 user.id
+	// Field is the path of the checked field, nested fields are separated by dots, e.g. user.id
+	Field string `json:"field"`
+	// Values 内联的集合
+	// Values is an inline set
+	Values []string `json:"values"`
+	// File 保存集合的文件路径，.json 文件为字符串数组，其他文件每行一个值，忽略空行和 # 开头的行，文件修改后自动重新加载
+	// File is the path of a file holding the set: a JSON array of strings for a .json file, otherwise one
+	// value per line, blank lines and lines starting with # are ignored. It is reloaded when the file changes
+	File string `json:"file"`
+	// RefreshInterval 检查文件是否修改的间隔，默认为 30s
+	// RefreshInterval is the interval between two checks of the file modification time, defaults to 30s
+	RefreshInterval string `json:"refreshInterval"`
+	// Negate 为 true 时值不在集合中才路由到 true，用于白名单
+	// Negate routes to "True" when the value is not in the set instead, e.g. for allow lists
+	Negate bool `json:"negate"`
+}
+
+// MembershipFilterNode 检查字段值是否在集合中的过滤组件
+// MembershipFilterNode routes to "True" when the field value is in the set and to "False" otherwise,
+// or the other way round with Negate, for block and allow lists over large id sets where an expr
+// `in [...]` literal is impractical. The set is a map built once, so lookups are O(1). Values are
+// compared as strings, a missing field is not in the set.
+//
+// 文件集合由后台任务按 RefreshInterval 检查修改时间并原子替换，无需重新部署规则链即可更新，处理消息时不读取文件，
+// 重新加载失败时记录日志并继续使用之前的集合。
+// A file set is checked every RefreshInterval by a background refresher and swapped atomically, so it can be
+// updated without redeploying the chain and the messages never wait for the file,
+// a failed reload is logged and the previous set is kept.
+type MembershipFilterNode struct {
+	// Config 节点配置
+	// Config holds the membership filter node configuration
+	Config MembershipFilterNodeConfiguration
+
+	// config 规则引擎配置
+	// config is the rule engine configuration
+	config types.Config
+
+	// values 内联集合
+	// values is the inline set
+	values map[string]struct{}
+
+	// set 文件集合，设置 File 时非空
+	// set is the file set, not nil when File is set
+	set *reloadableFile[map[string]struct{}]
+}
+
+// Type 返回组件类型
+// Type returns the component type identifier.
+func (x *MembershipFilterNode) Type() types.NodeType {
+	return types.RuleSubTypeMembership
+}
+
+// Category 返回组件类别
+// Category returns the component category.
+func (x *MembershipFilterNode) Category() string {
+	return types.CategoryFilter
+}
+
+// Relations 返回组件可能路由到的关系
+// Relations returns the relation types the component can route a message to.
+func (x *MembershipFilterNode) Relations() []string {
+	return []string{types.TrueRelationType, types.FalseRelationType}
+}
+
+// New 创建新实例
+// New creates a new instance.
+func (x *MembershipFilterNode) New() types.Node {
+	return &MembershipFilterNode{}
+}
+
+// Init 初始化组件，构建集合
+// Init initializes the component, building the set.
+func (x *MembershipFilterNode) Init(config types.Config, configuration types.Configuration) error {
+	x.config = config
+	err := maps.Map2Struct(configuration, &x.Config)
+	if err != nil {
+		return err
+	}
+	x.Config.Field = strings.TrimSpace(x.Config.Field)
+	if x.Config.Field == "" {
+		return errors.New("field must not be empty")
+	}
+	if (x.Config.Values != nil) == (x.Config.File != "") {
+		return errors.New("exactly one of values and file must be set")
+	}
+	if x.Config.File != "" && base.NodeUtils.IsLint(configuration) {
+		_, err = parseRefreshInterval(x.Config.RefreshInterval)
+		return err
+	}
+	if x.Config.File != "" {
+		parse := parseSetLines
+		if strings.EqualFold(filepath.Ext(x.Config.File), ".json") {
+			parse = parseSetJSON
+		}
+		x.set, err = newReloadableFile(x.Config.File, x.Config.RefreshInterval, parse, config.Logger, "membership")
+		return err
+	}
+	x.values = newSet(x.Config.Values)
+	return nil
+}
+
+// OnMsg 处理消息，根据字段值是否在集合中路由
+// OnMsg routes the message by the membership of the field value.
+func (x *MembershipFilterNode) OnMsg(ctx context.Context, msg types.RuleMsg) (string, error) {
+	if x.contains(fieldValue(msg, x.Config.Field)) != x.Config.Negate {
+		return types.TrueRelationType, nil
+	}
+	return types.FalseRelationType, nil
+}
+
+// contains reports whether the value is in the set
+func (x *MembershipFilterNode) contains(value any) bool {
+	if value == nil {
+		return false
+	}
+	key, err := cast.ToStringE(value)
+	if err != nil {
+		return false
+	}
+	set := x.values
+	if x.set != nil {
+		set = x.set.get()
+	}
+	_, ok := set[key]
+	return ok
+}
+
+// Destroy 清理资源
+// Destroy cleans up resources.
+func (x *MembershipFilterNode) Destroy() {
+	if x.set != nil {
+		x.set.close()
+	}
+}
+
+// newSet builds a set of the values
+func newSet(values []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(values))
+	for _, value := range values {
+		set[value] = struct{}{}
+	}
+	return set
+}
+
+// parseSetJSON parses a set file holding a JSON array of strings
+func parseSetJSON(data []byte) (map[string]struct{}, error) {
+	var values []string
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+	return newSet(values), nil
+}
+
+// parseSetLines parses a set file holding one value per line, skipping blank lines and # comments
+func parseSetLines(data []byte) (map[string]struct{}, error) {
+	set := map[string]struct{}{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		set[line] = struct{}{}
+	}
+	return set, scanner.Err()
+}
@@ -0,0 +1,80 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transform
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bittoy/rule/types"
+	"github.com/rulego/rulego/test/assert"
+)
+
+// TestMembership checks the membership filter over a set file and the background reload of the file.
+func TestMembership(t *testing.T) {
+	file := t.TempDir() + "/blacklist.txt"
+	assert.Nil(t, os.WriteFile(file, []byte("# blocked users\nu1\n42\n"), 0o644))
+	node := &MembershipFilterNode{}
+	assert.Nil(t, node.Init(types.NewConfig(), types.Configuration{"field": "user.id", "file": file, "refreshInterval": "1ms"}))
+	defer node.Destroy()
+
+	blocked := func(id any) bool {
+		relation, err := node.OnMsg(context.Background(), types.NewRuleMsg("", 0, map[string]any{"user": map[string]any{"id": id}}))
+		assert.Nil(t, err)
+		return relation == types.TrueRelationType
+	}
+	assert.True(t, blocked("u1"))
+	assert.True(t, blocked(42))
+	assert.False(t, blocked("u2"))
+	assert.False(t, blocked(nil))
+
+	assert.Nil(t, os.WriteFile(file, []byte("u2\n"), 0o644))
+	assert.Nil(t, os.Chtimes(file, time.Now(), time.Now().Add(time.Second)))
+	for deadline := time.Now().Add(time.Second); !blocked("u2") && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	assert.True(t, blocked("u2"))
+	assert.False(t, blocked("u1"))
+
+	// A failed reload keeps the previous set
+	assert.Nil(t, os.Remove(file))
+	time.Sleep(5 * time.Millisecond)
+	assert.True(t, blocked("u2"))
+}
+
+// TestMembershipInit checks the configuration checks, and that a lint does not load the file.
+func TestMembershipInit(t *testing.T) {
+	node := &MembershipFilterNode{}
+	assert.NotNil(t, node.Init(types.NewConfig(), types.Configuration{"file": "/nonexistent/blacklist.txt"}))
+	assert.NotNil(t, node.Init(types.NewConfig(), types.Configuration{"field": "user", "values": []string{"u1"}, "file": "/nonexistent/blacklist.txt"}))
+	assert.NotNil(t, node.Init(types.NewConfig(), types.Configuration{"field": "user", "file": "/nonexistent/blacklist.txt"}))
+
+	node = &MembershipFilterNode{}
+	lint := types.Configuration{"field": "user", "file": "/nonexistent/blacklist.txt", types.NodeConfigurationKeyLint: true}
+	assert.Nil(t, node.Init(types.NewConfig(), lint))
+	assert.Nil(t, node.set)
+	lint["refreshInterval"] = "0s"
+	assert.NotNil(t, node.Init(types.NewConfig(), lint))
+
+	node = &MembershipFilterNode{}
+	assert.Nil(t, node.Init(types.NewConfig(), types.Configuration{"field": "user", "values": []string{"u1"}}))
+	relation, err := node.OnMsg(context.Background(), types.NewRuleMsg("", 0, map[string]any{"user": "u1"}))
+	assert.Nil(t, err)
+	assert.Equal(t, types.TrueRelationType, relation)
+}
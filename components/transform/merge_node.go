@@ -0,0 +1,278 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transform
+
+//规则链节点配置示例：
+//{
+//        "id": "s14",
+//        "type": "merge",
+//        "name": "合并画像",
+//        "configuration": {
+//          "source": "priVars.profile",
+//          "target": "user",
+//          "strategy": "deep",
+//          "onConflict": "error"
+//        }
+//      }
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/bittoy/rule/types"
+	"github.com/bittoy/rule/utils/maps"
+)
+
+// Merge strategies.
+// 合并策略。
+const (
+	// MergeShallow 源对象的顶层字段替换目标对象的同名字段
+	// MergeShallow replaces the top-level fields of the target with those of the source
+	MergeShallow = "shallow"
+	// MergeDeep 递归合并嵌套对象，其他值由源对象替换
+	// MergeDeep merges the nested objects recursively, other values are replaced by the source
+	MergeDeep = "deep"
+	// MergeAppend 与 deep 相同，但数组会被拼接
+	// MergeAppend is like deep, but arrays are concatenated
+	MergeAppend = "append"
+)
+
+// Merge conflict handling.
+// 合并冲突处理方式。
+const (
+	// MergeConflictOverwrite 源对象的值替换类型不同的目标值
+	// MergeConflictOverwrite replaces a target value of another type with the source value
+	MergeConflictOverwrite = "overwrite"
+	// MergeConflictError 类型不同时节点失败
+	// MergeConflictError fails the node on a type conflict
+	MergeConflictError = "error"
+)
+
+func init() {
+	Registry.Add(&MergeNode{})
+}
+
+// MergeNodeConfiguration MergeNode配置结构
+// MergeNodeConfiguration defines the configuration structure for the MergeNode component.
+type MergeNodeConfiguration struct {
+	// Source 源对象的字段路径，嵌套字段用 . 分隔，如 priVars.profile
+	// Source is the path of the source object, nested fields are separated by dots, e.g. priVars.profile
+	Source string `json:"source"`
+	// Target 目标对象的字段路径，为空时为整个输入（不含私有变量），字段不存在时为空对象
+	// Target is the path of the target object, the whole input without the private variables when empty,
+	// an empty object when the field is missing
+	Target string `json:"target"`
+	// Strategy 合并策略：shallow、deep 或 append，默认为 shallow
+	// Strategy is the merge strategy: shallow, deep or append, defaults to shallow
+	Strategy string `json:"strategy"`
+	// OnConflict 对象、数组与其他值之间类型冲突的处理方式：overwrite 或 error，默认为 overwrite
+	// OnConflict handles the type conflicts between objects, arrays and other values: overwrite or error,
+	// defaults to overwrite
+	OnConflict string `json:"onConflict"`
+	// OutputKey 可选，设置时合并结果保存到该私有变量，目标对象保持不变
+	// OutputKey is optional, when set the merged object is written to this private variable and the target is left unchanged
+	OutputKey string `json:"outputKey"`
+}
+
+// MergeNode 将源对象合并到目标对象的组件
+// MergeNode folds the source object into the target object, then forwards the message to "default". It folds
+// the partial objects produced by enrichment nodes into the main payload: a target field is replaced by the
+// merged object, with the nested objects along its path copied before they are written, and for the root
+// target the merged fields are set as top-level input fields. With OutputKey the merged object is written to
+// that private variable instead. The objects of the original input are never modified.
+// A type conflict is a field holding an object or an array in one object and another type in the other,
+// with OnConflict error it fails the node. A missing source or a source or target that is not an object
+// fails the node.
+// MergeNode 将源对象合并到目标对象，然后转发到 "default"：目标字段被替换为合并结果，其路径上的嵌套对象在写入前被复制；
+// 目标为根时，合并后的字段被设置为顶层输入字段。设置 OutputKey 时合并结果改为写入该私有变量。原始输入的对象不会被修改。
+// 类型冲突是指某个字段在一个对象中为对象或数组、在另一个对象中为其他类型，
+// OnConflict 为 error 时节点失败。源对象不存在，或源、目标不是对象时节点失败。
+type MergeNode struct {
+	// Config 节点配置
+	// Config holds the merge node configuration
+	Config MergeNodeConfiguration
+}
+
+// Type 返回组件类型
+// Type returns the component type identifier.
+func (x *MergeNode) Type() types.NodeType {
+	return types.RuleSubTypeMerge
+}
+
+// Category 返回组件类别
+// Category returns the component category.
+func (x *MergeNode) Category() string {
+	return types.CategoryTransform
+}
+
+// Relations 返回组件可能路由到的关系
+// Relations returns the relation types the component can route a message to.
+func (x *MergeNode) Relations() []string {
+	return []string{types.DefaultRelationType}
+}
+
+// New 创建新实例
+// New creates a new instance.
+func (x *MergeNode) New() types.Node {
+	return &MergeNode{Config: MergeNodeConfiguration{
+		Strategy:   MergeShallow,
+		OnConflict: MergeConflictOverwrite,
+	}}
+}
+
+// Init 初始化组件，校验字段和合并策略
+// Init initializes the component, checking the fields and the merge strategy.
+func (x *MergeNode) Init(config types.Config, configuration types.Configuration) error {
+	err := maps.Map2Struct(configuration, &x.Config)
+	if err != nil {
+		return err
+	}
+	x.Config.Source = strings.TrimSpace(x.Config.Source)
+	if x.Config.Source == "" {
+		return errors.New("source must not be empty")
+	}
+	x.Config.Target = strings.TrimSpace(x.Config.Target)
+	switch x.Config.Strategy {
+	case "":
+		x.Config.Strategy = MergeShallow
+	case MergeShallow, MergeDeep, MergeAppend:
+	default:
+		return fmt.Errorf("unknown strategy %s, must be shallow, deep or append", x.Config.Strategy)
+	}
+	switch x.Config.OnConflict {
+	case "":
+		x.Config.OnConflict = MergeConflictOverwrite
+	case MergeConflictOverwrite, MergeConflictError:
+	default:
+		return fmt.Errorf("unknown onConflict %s, must be overwrite or error", x.Config.OnConflict)
+	}
+	x.Config.OutputKey = strings.TrimSpace(x.Config.OutputKey)
+	return nil
+}
+
+// OnMsg 处理消息，合并源对象和目标对象
+// OnMsg merges the source object into the target object and writes the result back, see MergeNode.
+func (x *MergeNode) OnMsg(ctx context.Context, msg types.RuleMsg) (string, error) {
+	source, ok := fieldValue(msg, x.Config.Source).(map[string]any)
+	if !ok {
+		return "", fmt.Errorf("source %s is not an object", x.Config.Source)
+	}
+	var target map[string]any
+	if x.Config.Target == "" {
+		target = make(map[string]any, len(msg.GetInput()))
+		for k, v := range msg.GetInput() {
+			if k != types.PriVarsKey {
+				target[k] = v
+			}
+		}
+	} else if value := fieldValue(msg, x.Config.Target); value != nil {
+		if target, ok = value.(map[string]any); !ok {
+			return "", fmt.Errorf("target %s is not an object", x.Config.Target)
+		}
+	}
+	merged, err := x.merge(target, source, "")
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case x.Config.OutputKey != "":
+		msg.SetPrivateVar(x.Config.OutputKey, merged)
+	case x.Config.Target == "":
+		for k := range source {
+			if k != types.PriVarsKey {
+				msg.SetField(k, merged[k])
+			}
+		}
+	default:
+		if err = setFieldValue(msg, x.Config.Target, merged); err != nil {
+			return "", err
+		}
+	}
+	return types.DefaultRelationType, nil
+}
+
+// Destroy 清理资源
+// Destroy cleans up resources.
+func (x *MergeNode) Destroy() {
+}
+
+// merge returns a copy of target with the fields of source merged according to the strategy,
+// path is the path of the objects in the merged object, for the conflict errors
+func (x *MergeNode) merge(target, source map[string]any, path string) (map[string]any, error) {
+	merged := make(map[string]any, len(target)+len(source))
+	for k, v := range target {
+		merged[k] = v
+	}
+	for k, value := range source {
+		current, exists := merged[k]
+		if !exists {
+			merged[k] = value
+			continue
+		}
+		fieldPath := k
+		if path != "" {
+			fieldPath = path + "." + k
+		}
+		currentKind, valueKind := mergeKind(current), mergeKind(value)
+		if currentKind != valueKind && (currentKind != reflect.Invalid || valueKind != reflect.Invalid) {
+			if x.Config.OnConflict == MergeConflictError {
+				return nil, fmt.Errorf("merge conflict at %s: %T and %T", fieldPath, current, value)
+			}
+			merged[k] = value
+			continue
+		}
+		switch {
+		case x.Config.Strategy != MergeShallow && valueKind == reflect.Map:
+			nested, err := x.merge(current.(map[string]any), value.(map[string]any), fieldPath)
+			if err != nil {
+				return nil, err
+			}
+			merged[k] = nested
+		case x.Config.Strategy == MergeAppend && valueKind == reflect.Slice:
+			merged[k] = appendValues(current, value)
+		default:
+			merged[k] = value
+		}
+	}
+	return merged, nil
+}
+
+// mergeKind returns reflect.Map for an object, reflect.Slice for an array and reflect.Invalid for other values
+func mergeKind(value any) reflect.Kind {
+	if _, ok := value.(map[string]any); ok {
+		return reflect.Map
+	}
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Slice, reflect.Array:
+		return reflect.Slice
+	}
+	return reflect.Invalid
+}
+
+// appendValues concatenates two arrays into a new []any
+func appendValues(first, second any) []any {
+	a, b := reflect.ValueOf(first), reflect.ValueOf(second)
+	values := make([]any, 0, a.Len()+b.Len())
+	for _, array := range []reflect.Value{a, b} {
+		for i := 0; i < array.Len(); i++ {
+			values = append(values, array.Index(i).Interface())
+		}
+	}
+	return values
+}
@@ -0,0 +1,96 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transform
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bittoy/rule/types"
+	"github.com/rulego/rulego/test/assert"
+)
+
+// TestMerge checks the merge strategies, the conflict handling and where the merge node writes the merged object.
+func TestMerge(t *testing.T) {
+	merge := func(configuration types.Configuration, input map[string]any) (types.RuleMsg, error) {
+		node := &MergeNode{}
+		if err := node.Init(types.NewConfig(), configuration); err != nil {
+			return types.RuleMsg{}, err
+		}
+		msg := types.NewRuleMsg("", 0, input)
+		relation, err := node.OnMsg(context.Background(), msg)
+		if err == nil {
+			assert.Equal(t, types.DefaultRelationType, relation)
+		}
+		return msg, err
+	}
+	input := func() map[string]any {
+		return map[string]any{
+			"user":  map[string]any{"name": "a", "tags": []any{"x"}, "address": map[string]any{"city": "c1", "zip": "1"}},
+			"patch": map[string]any{"age": 3, "tags": []any{"y"}, "address": map[string]any{"city": "c2"}},
+		}
+	}
+
+	msg, err := merge(types.Configuration{"source": "patch", "target": "user"}, input())
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]any{"name": "a", "age": 3, "tags": []any{"y"}, "address": map[string]any{"city": "c2"}}, msg.GetInput()["user"])
+
+	msg, err = merge(types.Configuration{"source": "patch", "target": "user", "strategy": MergeDeep}, input())
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]any{"name": "a", "age": 3, "tags": []any{"y"}, "address": map[string]any{"city": "c2", "zip": "1"}}, msg.GetInput()["user"])
+
+	in := input()
+	user := in["user"]
+	msg, err = merge(types.Configuration{"source": "patch", "target": "user", "strategy": MergeAppend}, in)
+	assert.Nil(t, err)
+	assert.Equal(t, []any{"x", "y"}, msg.GetInput()["user"].(map[string]any)["tags"])
+	assert.Equal(t, []any{"x"}, user.(map[string]any)["tags"])
+	assert.Equal(t, "c1", user.(map[string]any)["address"].(map[string]any)["city"])
+
+	// A nested target is set through the objects along its path, copied before they are written
+	nested := map[string]any{"profile": map[string]any{"user": map[string]any{"name": "a"}}, "patch": map[string]any{"age": 3}}
+	profile := nested["profile"]
+	msg, err = merge(types.Configuration{"source": "patch", "target": "profile.user"}, nested)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]any{"user": map[string]any{"name": "a", "age": 3}}, msg.GetInput()["profile"])
+	assert.Equal(t, map[string]any{"user": map[string]any{"name": "a"}}, profile)
+
+	// The root target gets the merged fields at the top level
+	msg, err = merge(types.Configuration{"source": "patch"}, input())
+	assert.Nil(t, err)
+	assert.Equal(t, 3, msg.GetInput()["age"])
+	assert.NotNil(t, msg.GetInput()["user"])
+
+	// With outputKey the target is left unchanged
+	msg, err = merge(types.Configuration{"source": "patch", "target": "user", "outputKey": "merged"}, input())
+	assert.Nil(t, err)
+	assert.Equal(t, 3, msg.GetPrivateVars()["merged"].(map[string]any)["age"])
+	assert.Nil(t, msg.GetInput()["user"].(map[string]any)["age"])
+
+	conflicting := input()
+	conflicting["patch"].(map[string]any)["name"] = map[string]any{"first": "a"}
+	msg, err = merge(types.Configuration{"source": "patch", "target": "user", "strategy": MergeDeep, "onConflict": MergeConflictOverwrite}, conflicting)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]any{"first": "a"}, msg.GetInput()["user"].(map[string]any)["name"])
+	_, err = merge(types.Configuration{"source": "patch", "target": "user", "strategy": MergeDeep, "onConflict": MergeConflictError}, conflicting)
+	assert.NotNil(t, err)
+
+	_, err = merge(types.Configuration{"source": "missing", "target": "user"}, map[string]any{"user": map[string]any{}})
+	assert.NotNil(t, err)
+	_, err = merge(types.Configuration{"source": "patch", "strategy": "union"}, input())
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,161 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transform
+
+//规则链节点配置示例：
+//{
+//        "id": "s5",
+//        "type": "rangeSwitch",
+//        "name": "分数分段",
+//        "configuration": {
+//          "value": "score * 0.6 + level * 10",
+//          "bands": [
+//            {"max": 60, "relation": "low"},
+//            {"max": 90, "relation": "medium"}
+//          ]
+//        }
+//      }
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bittoy/rule/components/base"
+	"github.com/bittoy/rule/types"
+	"github.com/bittoy/rule/utils/cast"
+	"github.com/bittoy/rule/utils/maps"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+)
+
+func init() {
+	Registry.Add(&RangeSwitchNode{})
+}
+
+// RangeSwitchNodeConfiguration RangeSwitchNode配置结构
+// RangeSwitchNodeConfiguration defines the configuration structure for the RangeSwitchNode component.
+type RangeSwitchNodeConfiguration struct {
+	// Value 返回数值的表达式
+	// Value is the expression evaluating to the numeric value
+	Value string `json:"value"`
+	// Bands 按 Max 严格升序排列的区间
+	// Bands are the bands sorted by strictly ascending Max
+	Bands []types.RangeBand `json:"bands"`
+}
+
+// RangeSwitchNode 根据数值所在区间进行路由的组件
+// RangeSwitchNode routes to the relation of the first band whose Max is above the value,
+// or to "default" when the value is not below any band.
+//
+// 相比在 exprSwitch 中编写嵌套三元表达式，分段配置更清晰：
+// It replaces nested ternaries in exprSwitch for score banding:
+//
+//	score < 60 ? "low" : score < 90 ? "medium" : "default"
+type RangeSwitchNode struct {
+	// Config 区间路由节点配置
+	// Config holds the range switch node configuration
+	Config RangeSwitchNodeConfiguration
+
+	// config 规则引擎配置
+	// config is the rule engine configuration
+	config types.Config
+
+	// program 编译后的数值表达式
+	// program is the compiled value expression
+	program *vm.Program
+}
+
+// Type 返回组件类型
+// Type returns the component type identifier.
+func (x *RangeSwitchNode) Type() types.NodeType {
+	return types.RuleSubTypeRangeSwitch
+}
+
+// Category 返回组件类别
+// Category returns the component category.
+func (x *RangeSwitchNode) Category() string {
+	return types.CategorySwitch
+}
+
+// Relations 返回组件可能路由到的关系，分支关系由配置决定
+// Relations returns the relation types the component can route a message to, the case relations depend on the configuration.
+func (x *RangeSwitchNode) Relations() []string {
+	return []string{types.DefaultRelationType, types.DynamicRelationType}
+}
+
+// New 创建新实例
+// New creates a new instance.
+func (x *RangeSwitchNode) New() types.Node {
+	return &RangeSwitchNode{}
+}
+
+// Init 初始化组件，编译数值表达式并校验区间
+// Init initializes the component, compiling the value expression and checking the bands.
+func (x *RangeSwitchNode) Init(config types.Config, configuration types.Configuration) error {
+	x.config = config
+	err := maps.Map2Struct(configuration, &x.Config)
+	if err != nil {
+		return err
+	}
+	script := strings.TrimSpace(x.Config.Value)
+	if len(script) == 0 {
+		return errors.New("value must not be empty")
+	}
+	if len(x.Config.Bands) == 0 {
+		return errors.New("bands must not be empty")
+	}
+	for i, band := range x.Config.Bands {
+		if strings.TrimSpace(band.Relation) == "" {
+			return fmt.Errorf("band %d relation must not be empty", i)
+		}
+		if i > 0 && band.Max <= x.Config.Bands[i-1].Max {
+			return fmt.Errorf("bands must be sorted by ascending max, band %d max:%v", i, band.Max)
+		}
+	}
+	program, err := expr.Compile(script, base.NodeUtils.ExprOptions(config)...)
+	if err != nil {
+		return err
+	}
+	x.program = program
+	return nil
+}
+
+// OnMsg 处理消息，计算数值并路由到所在区间的关系
+// OnMsg evaluates the value and routes to the relation of its band.
+func (x *RangeSwitchNode) OnMsg(ctx context.Context, msg types.RuleMsg) (string, error) {
+	out, err := vm.Run(x.program, base.NodeUtils.ExprProgramEnv(ctx, x.config, x.program, msg))
+	if err != nil {
+		return "", err
+	}
+	value, err := cast.ToFloat64E(out)
+	if err != nil {
+		return "", err
+	}
+	for _, band := range x.Config.Bands {
+		if value < band.Max {
+			return strings.TrimSpace(band.Relation), nil
+		}
+	}
+	return types.DefaultRelationType, nil
+}
+
+// Destroy 清理资源
+// Destroy cleans up resources.
+func (x *RangeSwitchNode) Destroy() {
+}
@@ -0,0 +1,55 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transform
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bittoy/rule/types"
+	"github.com/rulego/rulego/test/assert"
+)
+
+// TestRangeSwitch checks that the rangeSwitch node routes to the first band whose max is above the value,
+// and to the default relation above the last band.
+func TestRangeSwitch(t *testing.T) {
+	node := &RangeSwitchNode{}
+	assert.Nil(t, node.Init(types.NewConfig(), types.Configuration{"value": "amount * 10", "bands": []types.RangeBand{
+		{Max: 100, Relation: "low"},
+		{Max: 500, Relation: " medium "},
+	}}))
+	for amount, relation := range map[any]string{-3: "low", 9.9: "low", 10: "medium", 49: "medium", 50: types.DefaultRelationType} {
+		got, err := node.OnMsg(context.Background(), types.NewRuleMsg("", 0, map[string]any{"amount": amount}))
+		assert.Nil(t, err, amount)
+		assert.Equal(t, relation, got, amount)
+	}
+	_, err := node.OnMsg(context.Background(), types.NewRuleMsg("", 0, map[string]any{"amount": "n/a"}))
+	assert.NotNil(t, err)
+}
+
+// TestRangeSwitchInit checks the validation of the value and the bands.
+func TestRangeSwitchInit(t *testing.T) {
+	for _, configuration := range []types.Configuration{
+		{"value": " ", "bands": []types.RangeBand{{Max: 1, Relation: "a"}}},
+		{"value": "amount"},
+		{"value": "amount", "bands": []types.RangeBand{{Max: 1, Relation: " "}}},
+		{"value": "amount", "bands": []types.RangeBand{{Max: 2, Relation: "a"}, {Max: 2, Relation: "b"}}},
+		{"value": "amount *", "bands": []types.RangeBand{{Max: 1, Relation: "a"}}},
+	} {
+		assert.NotNil(t, (&RangeSwitchNode{}).Init(types.NewConfig(), configuration), configuration)
+	}
+}
@@ -0,0 +1,134 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transform
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bittoy/rule/types"
+)
+
+// defaultRefreshInterval is the default interval between two checks of a reloadable file
+const defaultRefreshInterval = 30 * time.Second
+
+// reloadableFile is a file parsed into a T. A background refresher checks the modification time every
+// interval and parses the file again when it changes, swapping the value atomically, so reading the
+// value never touches the file.
+type reloadableFile[T any] struct {
+	// path is the file path
+	path string
+	// parse parses the file content
+	parse func(data []byte) (T, error)
+	// value is the last parsed value
+	value atomic.Pointer[T]
+	// modTime is the modification time of the parsed file, only used by the loader
+	modTime time.Time
+	// stop stops the refresher
+	stop chan struct{}
+	// stopOnce closes stop once
+	stopOnce sync.Once
+}
+
+// newReloadableFile loads the file and starts its refresher, refreshInterval is a duration string, empty for
+// defaultRefreshInterval. A failed reload is logged as kind and the previous value is kept.
+func newReloadableFile[T any](path, refreshInterval string, parse func(data []byte) (T, error), logger types.Logger, kind string) (*reloadableFile[T], error) {
+	interval, err := parseRefreshInterval(refreshInterval)
+	if err != nil {
+		return nil, err
+	}
+	f := &reloadableFile[T]{path: path, parse: parse, stop: make(chan struct{})}
+	if err := f.load(); err != nil {
+		return nil, err
+	}
+	go f.refresh(interval, logger, kind)
+	return f, nil
+}
+
+// parseRefreshInterval parses a refresh interval duration string, empty for defaultRefreshInterval
+func parseRefreshInterval(refreshInterval string) (time.Duration, error) {
+	if refreshInterval == "" {
+		return defaultRefreshInterval, nil
+	}
+	interval, err := time.ParseDuration(refreshInterval)
+	if err != nil {
+		return 0, fmt.Errorf("invalid refreshInterval:%w", err)
+	}
+	if interval <= 0 {
+		return 0, fmt.Errorf("invalid refreshInterval:%s must be positive", refreshInterval)
+	}
+	return interval, nil
+}
+
+// get returns the current value
+func (f *reloadableFile[T]) get() T {
+	return *f.value.Load()
+}
+
+// close stops the refresher
+func (f *reloadableFile[T]) close() {
+	f.stopOnce.Do(func() {
+		close(f.stop)
+	})
+}
+
+// refresh reloads the file every interval until closed
+func (f *reloadableFile[T]) refresh(interval time.Duration, logger types.Logger, kind string) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-f.stop:
+			return
+		case <-ticker.C:
+			// A tick ready together with the stop must not reload a file the owner is done with
+			select {
+			case <-f.stop:
+				return
+			default:
+			}
+			if err := f.load(); err != nil && logger != nil {
+				logger.Printf("%s reload %s error: %s", kind, f.path, err.Error())
+			}
+		}
+	}
+}
+
+// load parses the file when its modification time has changed, it runs in newReloadableFile, then only in the refresher
+func (f *reloadableFile[T]) load() error {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return err
+	}
+	if f.value.Load() != nil && info.ModTime().Equal(f.modTime) {
+		return nil
+	}
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return err
+	}
+	value, err := f.parse(data)
+	if err != nil {
+		return fmt.Errorf("invalid file %s:%w", f.path, err)
+	}
+	f.value.Store(&value)
+	f.modTime = info.ModTime()
+	return nil
+}
@@ -0,0 +1,218 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transform
+
+//规则链节点配置示例：
+//{
+//        "id": "s9",
+//        "type": "require",
+//        "name": "必填字段",
+//        "configuration": {
+//          "fields": ["orderId", "amount", "user.id"],
+//          "types": {"amount": "number"},
+//          "outputKey": "missingFields",
+//          "mismatchedKey": "mismatchedFields"
+//        }
+//      }
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/bittoy/rule/types"
+	"github.com/bittoy/rule/utils/maps"
+)
+
+func init() {
+	Registry.Add(&RequireFieldsNode{})
+}
+
+// defaultMissingFieldsKey is the private variable key of the missing fields when no output key is configured
+const defaultMissingFieldsKey = "missingFields"
+
+// defaultMismatchedFieldsKey is the private variable key of the mismatched fields when no mismatched key is configured
+const defaultMismatchedFieldsKey = "mismatchedFields"
+
+// Field types checked by the require node.
+// require 节点检查的字段类型。
+const (
+	FieldTypeString = "string"
+	FieldTypeNumber = "number"
+	FieldTypeBool   = "bool"
+	FieldTypeObject = "object"
+	FieldTypeArray  = "array"
+)
+
+// RequireFieldsNodeConfiguration RequireFieldsNode配置结构
+// RequireFieldsNodeConfiguration defines the configuration structure for the RequireFieldsNode component.
+type RequireFieldsNodeConfiguration struct {
+	// Fields 必填字段路径，支持嵌套字段，如 user.id
+	// Fields are the paths of the required fields, nested fields are separated by dots, e.g. user.id
+	Fields []string `json:"fields"`
+	// Types 可选的字段类型检查，键为 Fields 中的字段路径，值为 string、number、bool、object 或 array
+	// Types are the optional type checks, keyed by a path of Fields, valued string, number, bool, object or array
+	Types map[string]string `json:"types"`
+	// OutputKey 保存缺失字段列表的私有变量键，默认为 missingFields
+	// OutputKey is the private variable key holding the list of missing fields, defaults to missingFields
+	OutputKey string `json:"outputKey"`
+	// MismatchedKey 保存类型不符字段列表的私有变量键，默认为 mismatchedFields
+	// MismatchedKey is the private variable key holding the list of fields whose type does not match, defaults to mismatchedFields
+	MismatchedKey string `json:"mismatchedKey"`
+}
+
+// RequireFieldsNode 检查必填字段的组件
+// RequireFieldsNode checks that the required fields are present, a lighter guard than the schema node
+// for the common "these fields must be present" case. Messages with all fields route to "default",
+// the others to "missing" with the paths of the missing fields written to the private variable OutputKey
+// and the paths of the fields whose type does not match to the private variable MismatchedKey,
+// both in the order of Fields and only written when not empty.
+//
+// 值为 null 的字段视为缺失；类型不符的字段单独列出，不会列为缺失。
+// A field with a null value counts as missing; a field whose type does not match is listed apart, not as missing.
+type RequireFieldsNode struct {
+	// Config 节点配置
+	// Config holds the require fields node configuration
+	Config RequireFieldsNodeConfiguration
+}
+
+// Type 返回组件类型
+// Type returns the component type identifier.
+func (x *RequireFieldsNode) Type() types.NodeType {
+	return types.RuleSubTypeRequire
+}
+
+// Category 返回组件类别
+// Category returns the component category.
+func (x *RequireFieldsNode) Category() string {
+	return types.CategorySwitch
+}
+
+// Relations 返回组件可能路由到的关系
+// Relations returns the relation types the component can route a message to.
+func (x *RequireFieldsNode) Relations() []string {
+	return []string{types.DefaultRelationType, types.MissingRelationType}
+}
+
+// New 创建新实例
+// New creates a new instance.
+func (x *RequireFieldsNode) New() types.Node {
+	return &RequireFieldsNode{}
+}
+
+// Init 初始化组件，校验字段和类型配置
+// Init initializes the component, checking the fields and the type checks.
+func (x *RequireFieldsNode) Init(config types.Config, configuration types.Configuration) error {
+	err := maps.Map2Struct(configuration, &x.Config)
+	if err != nil {
+		return err
+	}
+	if x.Config.OutputKey == "" {
+		x.Config.OutputKey = defaultMissingFieldsKey
+	}
+	if x.Config.MismatchedKey == "" {
+		x.Config.MismatchedKey = defaultMismatchedFieldsKey
+	}
+	if x.Config.MismatchedKey == x.Config.OutputKey {
+		return errors.New("mismatchedKey must differ from outputKey")
+	}
+	if len(x.Config.Fields) == 0 {
+		return errors.New("fields must not be empty")
+	}
+	for i, field := range x.Config.Fields {
+		x.Config.Fields[i] = strings.TrimSpace(field)
+		if x.Config.Fields[i] == "" {
+			return errors.New("field must not be empty")
+		}
+	}
+	for field, fieldType := range x.Config.Types {
+		if !slices.Contains(x.Config.Fields, field) {
+			return fmt.Errorf("type of field %s which is not in fields", field)
+		}
+		switch fieldType {
+		case FieldTypeString, FieldTypeNumber, FieldTypeBool, FieldTypeObject, FieldTypeArray:
+		default:
+			return fmt.Errorf("unknown type %s of field %s", fieldType, field)
+		}
+	}
+	return nil
+}
+
+// OnMsg 处理消息，检查必填字段
+// OnMsg checks the required fields of the message.
+func (x *RequireFieldsNode) OnMsg(ctx context.Context, msg types.RuleMsg) (string, error) {
+	var missing, mismatched []string
+	for _, field := range x.Config.Fields {
+		value := fieldValue(msg, field)
+		if value == nil {
+			missing = append(missing, field)
+		} else if fieldType, ok := x.Config.Types[field]; ok && !hasFieldType(value, fieldType) {
+			mismatched = append(mismatched, field)
+		}
+	}
+	if len(missing) == 0 && len(mismatched) == 0 {
+		return types.DefaultRelationType, nil
+	}
+	if len(missing) > 0 {
+		msg.SetPrivateVar(x.Config.OutputKey, missing)
+	}
+	if len(mismatched) > 0 {
+		msg.SetPrivateVar(x.Config.MismatchedKey, mismatched)
+	}
+	return types.MissingRelationType, nil
+}
+
+// Destroy 清理资源
+// Destroy cleans up resources.
+func (x *RequireFieldsNode) Destroy() {
+}
+
+// fieldValue returns the value at the field path, or nil when it is absent.
+// The top-level field is read with msg.Field, so protobuf payloads are not fully converted.
+func fieldValue(msg types.RuleMsg, path string) any {
+	head, rest, nested := strings.Cut(path, ".")
+	value, ok := msg.Field(head)
+	if !ok || !nested {
+		return value
+	}
+	return maps.Get(value, rest)
+}
+
+// hasFieldType reports whether the value has the field type
+func hasFieldType(value any, fieldType string) bool {
+	if _, ok := value.(json.Number); ok {
+		return fieldType == FieldTypeNumber
+	}
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.String:
+		return fieldType == FieldTypeString
+	case reflect.Bool:
+		return fieldType == FieldTypeBool
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return fieldType == FieldTypeNumber
+	case reflect.Map, reflect.Struct:
+		return fieldType == FieldTypeObject
+	case reflect.Slice, reflect.Array:
+		return fieldType == FieldTypeArray
+	}
+	return false
+}
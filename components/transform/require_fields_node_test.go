@@ -0,0 +1,75 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transform
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bittoy/rule/types"
+	"github.com/rulego/rulego/test/assert"
+)
+
+// TestRequireFields checks that the require node lists the missing fields and the mismatched fields apart.
+func TestRequireFields(t *testing.T) {
+	node := &RequireFieldsNode{}
+	assert.Nil(t, node.Init(types.NewConfig(), types.Configuration{
+		"fields": []string{"orderId", "amount", "user.id", "tags"},
+		"types":  map[string]string{"amount": FieldTypeNumber, "tags": FieldTypeArray},
+	}))
+
+	msg := types.NewRuleMsg("", 0, map[string]any{"orderId": "o1", "amount": 10, "user": map[string]any{"id": "u1"}, "tags": []any{"a"}})
+	relation, err := node.OnMsg(context.Background(), msg)
+	assert.Nil(t, err)
+	assert.Equal(t, types.DefaultRelationType, relation)
+	assert.Nil(t, msg.GetPrivateVars()[defaultMissingFieldsKey])
+	assert.Nil(t, msg.GetPrivateVars()[defaultMismatchedFieldsKey])
+
+	msg = types.NewRuleMsg("", 0, map[string]any{"orderId": nil, "amount": "10", "user": map[string]any{}, "tags": "a"})
+	relation, err = node.OnMsg(context.Background(), msg)
+	assert.Nil(t, err)
+	assert.Equal(t, types.MissingRelationType, relation)
+	assert.Equal(t, []string{"orderId", "user.id"}, msg.GetPrivateVars()[defaultMissingFieldsKey])
+	assert.Equal(t, []string{"amount", "tags"}, msg.GetPrivateVars()[defaultMismatchedFieldsKey])
+
+	// Only mismatched fields
+	msg = types.NewRuleMsg("", 0, map[string]any{"orderId": "o1", "amount": true, "user": map[string]any{"id": 1}, "tags": []any{}})
+	relation, _ = node.OnMsg(context.Background(), msg)
+	assert.Equal(t, types.MissingRelationType, relation)
+	assert.Nil(t, msg.GetPrivateVars()[defaultMissingFieldsKey])
+	assert.Equal(t, []string{"amount"}, msg.GetPrivateVars()[defaultMismatchedFieldsKey])
+}
+
+// TestRequireFieldsInit checks the configuration checks of the require node.
+func TestRequireFieldsInit(t *testing.T) {
+	for _, configuration := range []types.Configuration{
+		{},
+		{"fields": []string{" "}},
+		{"fields": []string{"a"}, "types": map[string]string{"b": FieldTypeString}},
+		{"fields": []string{"a"}, "types": map[string]string{"a": "date"}},
+		{"fields": []string{"a"}, "outputKey": "bad", "mismatchedKey": "bad"},
+	} {
+		assert.NotNil(t, (&RequireFieldsNode{}).Init(types.NewConfig(), configuration), configuration)
+	}
+	node := &RequireFieldsNode{}
+	assert.Nil(t, node.Init(types.NewConfig(), types.Configuration{"fields": []string{" a "}, "types": map[string]string{"a": FieldTypeString},
+		"outputKey": "absent", "mismatchedKey": "wrongType"}))
+	msg := types.NewRuleMsg("", 0, map[string]any{"a": 1})
+	relation, _ := node.OnMsg(context.Background(), msg)
+	assert.Equal(t, types.MissingRelationType, relation)
+	assert.Equal(t, []string{"a"}, msg.GetPrivateVars()["wrongType"])
+}
@@ -0,0 +1,184 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transform
+
+//规则链节点配置示例：
+//{
+//        "id": "s8",
+//        "type": "schema",
+//        "name": "校验订单",
+//        "configuration": {
+//          "schema": {
+//            "type": "object",
+//            "required": ["orderId", "amount"],
+//            "properties": {
+//              "orderId": {"type": "string"},
+//              "amount": {"type": "number", "minimum": 0}
+//            }
+//          }
+//        }
+//      }
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bittoy/rule/types"
+	"github.com/bittoy/rule/utils/maps"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+func init() {
+	Registry.Add(&SchemaValidateNode{})
+}
+
+// defaultSchemaErrorsKey is the private variable key of the validation errors when no output key is configured
+const defaultSchemaErrorsKey = "schemaErrors"
+
+// schemaResourceURL is the URL the schema is compiled under, it only appears in the schema errors
+const schemaResourceURL = "mem:///schema.json"
+
+// SchemaValidateNodeConfiguration SchemaValidateNode配置结构
+// SchemaValidateNodeConfiguration defines the configuration structure for the SchemaValidateNode component.
+type SchemaValidateNodeConfiguration struct {
+	// Schema JSON Schema，可以是对象或 JSON 字符串
+	// Schema is the JSON Schema, either as an object or as a JSON string
+	Schema any `json:"schema"`
+	// OutputKey 保存校验错误的私有变量键，默认为 schemaErrors
+	// OutputKey is the private variable key holding the validation errors, defaults to schemaErrors
+	OutputKey string `json:"outputKey"`
+}
+
+// SchemaValidateNode 使用 JSON Schema 校验消息输入的组件
+// SchemaValidateNode validates the message input against a JSON Schema, so chains reject malformed
+// inputs early with detailed diagnostics instead of failing deep in an expression.
+// Valid messages route to "default", invalid ones to "invalid" with the errors written to the
+// private variables as a list of {"path": instance location, "message": error}.
+//
+// 私有变量不参与校验。支持 draft 4 到 2020-12，未声明 $schema 时按 2020-12 处理。
+// The private variables are not validated. Drafts 4 to 2020-12 are supported, 2020-12 is used
+// when the schema declares no $schema.
+type SchemaValidateNode struct {
+	// Config 节点配置
+	// Config holds the schema validate node configuration
+	Config SchemaValidateNodeConfiguration
+
+	// schema 编译后的 JSON Schema
+	// schema is the compiled JSON Schema
+	schema *jsonschema.Schema
+}
+
+// Type 返回组件类型
+// Type returns the component type identifier.
+func (x *SchemaValidateNode) Type() types.NodeType {
+	return types.RuleSubTypeSchema
+}
+
+// Category 返回组件类别
+// Category returns the component category.
+func (x *SchemaValidateNode) Category() string {
+	return types.CategorySwitch
+}
+
+// Relations 返回组件可能路由到的关系
+// Relations returns the relation types the component can route a message to.
+func (x *SchemaValidateNode) Relations() []string {
+	return []string{types.DefaultRelationType, types.InvalidRelationType}
+}
+
+// New 创建新实例
+// New creates a new instance.
+func (x *SchemaValidateNode) New() types.Node {
+	return &SchemaValidateNode{}
+}
+
+// Init 初始化组件，编译 JSON Schema
+// Init initializes the component, compiling the JSON Schema.
+func (x *SchemaValidateNode) Init(config types.Config, configuration types.Configuration) error {
+	err := maps.Map2Struct(configuration, &x.Config)
+	if err != nil {
+		return err
+	}
+	if x.Config.OutputKey == "" {
+		x.Config.OutputKey = defaultSchemaErrorsKey
+	}
+	doc := x.Config.Schema
+	if text, ok := doc.(string); ok {
+		if doc, err = jsonschema.UnmarshalJSON(strings.NewReader(text)); err != nil {
+			return fmt.Errorf("invalid schema:%w", err)
+		}
+	}
+	if doc == nil {
+		return errors.New("schema must not be empty")
+	}
+	compiler := jsonschema.NewCompiler()
+	if err = compiler.AddResource(schemaResourceURL, doc); err != nil {
+		return fmt.Errorf("invalid schema:%w", err)
+	}
+	if x.schema, err = compiler.Compile(schemaResourceURL); err != nil {
+		return fmt.Errorf("invalid schema:%w", err)
+	}
+	return nil
+}
+
+// OnMsg 处理消息，校验消息输入
+// OnMsg validates the message input.
+func (x *SchemaValidateNode) OnMsg(ctx context.Context, msg types.RuleMsg) (string, error) {
+	input := msg.GetInput()
+	instance := make(map[string]any, len(input))
+	for k, v := range input {
+		if k != types.PriVarsKey {
+			instance[k] = v
+		}
+	}
+	// Go values such as []string, map[string]string or int are not JSON values to the validator,
+	// normalize the instance with a JSON round trip
+	// []string、map[string]string 或 int 等 Go 值对校验器而言不是 JSON 值，通过 JSON 序列化往返规范化实例
+	data, err := json.Marshal(instance)
+	if err != nil {
+		return "", fmt.Errorf("invalid input:%w", err)
+	}
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("invalid input:%w", err)
+	}
+	err = x.schema.Validate(doc)
+	if err == nil {
+		return types.DefaultRelationType, nil
+	}
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return "", err
+	}
+	var details []map[string]any
+	for _, unit := range validationErr.BasicOutput().Errors {
+		if unit.Error != nil {
+			details = append(details, map[string]any{"path": unit.InstanceLocation, "message": unit.Error.String()})
+		}
+	}
+	msg.SetPrivateVar(x.Config.OutputKey, details)
+	return types.InvalidRelationType, nil
+}
+
+// Destroy 清理资源
+// Destroy cleans up resources.
+func (x *SchemaValidateNode) Destroy() {
+}
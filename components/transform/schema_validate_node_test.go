@@ -0,0 +1,52 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transform
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bittoy/rule/types"
+	"github.com/rulego/rulego/test/assert"
+)
+
+// TestSchemaValidateGoValues checks that inputs holding Go typed values, not only decoded JSON values, are validated.
+func TestSchemaValidateGoValues(t *testing.T) {
+	node := &SchemaValidateNode{}
+	err := node.Init(types.NewConfig(), types.Configuration{"schema": map[string]any{
+		"type":     "object",
+		"required": []any{"tags", "labels", "count"},
+		"properties": map[string]any{
+			"tags":   map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+			"labels": map[string]any{"type": "object", "additionalProperties": map[string]any{"type": "string"}},
+			"count":  map[string]any{"type": "integer", "minimum": 1},
+		},
+	}})
+	assert.Nil(t, err)
+
+	msg := types.NewRuleMsg("", 0, map[string]any{"tags": []string{"a", "b"}, "labels": map[string]string{"env": "prod"}, "count": 3})
+	relation, err := node.OnMsg(context.Background(), msg)
+	assert.Nil(t, err)
+	assert.Equal(t, types.DefaultRelationType, relation)
+
+	msg = types.NewRuleMsg("", 0, map[string]any{"tags": []int{1}, "labels": map[string]string{}, "count": int64(0)})
+	relation, err = node.OnMsg(context.Background(), msg)
+	assert.Nil(t, err)
+	assert.Equal(t, types.InvalidRelationType, relation)
+	details, _ := msg.GetPrivateVars()[defaultSchemaErrorsKey].([]map[string]any)
+	assert.Equal(t, 2, len(details))
+}
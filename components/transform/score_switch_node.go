@@ -0,0 +1,173 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transform
+
+//规则链节点配置示例：
+//{
+//        "id": "s2",
+//        "type": "scoreSwitch",
+//        "name": "打分路由",
+//        "configuration": {
+//          "cases": [
+//            {"case": "score * 0.6 + level * 10", "then": "A"},
+//            {"case": "amount > 1000 ? 80 : 0", "then": "B"}
+//          ]
+//        }
+//      }
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/bittoy/rule/components/base"
+	"github.com/bittoy/rule/types"
+	"github.com/bittoy/rule/utils/cast"
+	"github.com/bittoy/rule/utils/maps"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+)
+
+func init() {
+	Registry.Add(&ScoreSwitchNode{})
+}
+
+// ScoreSwitchNodeConfiguration ScoreSwitchNode配置结构
+// ScoreSwitchNodeConfiguration defines the configuration structure for the ScoreSwitchNode component.
+type ScoreSwitchNodeConfiguration struct {
+	// Cases 打分分支列表
+	// case: 返回数值分数的表达式
+	// then: 该分支对应的关系类型（字面量，不是表达式）
+	//
+	// Cases is the list of scored branches.
+	// case: expression evaluating to a numeric score
+	// then: relation type of the branch (literal, not an expression)
+	Cases []types.Case `json:"cases"`
+}
+
+// scoreCase 编译后的打分分支
+// scoreCase is a compiled scored branch
+type scoreCase struct {
+	relation string
+	program  *vm.Program
+}
+
+// ScoreSwitchNode 基于打分选择最佳分支的路由组件
+// ScoreSwitchNode routes to the relation of the highest-scoring case.
+//
+// 核心算法：
+// Core Algorithm:
+// 1. 初始化时编译所有case表达式 - Compile all case expressions during initialization
+// 2. 对每条消息评估所有case，记录最高分 - Evaluate every case per message and track the max score
+// 3. 路由到最高分case的关系，分数相同时取先配置的case - Route to the best case, ties go to the earlier case
+// 4. 所有分数都不大于0时路由到"default"关系 - Route to "default" if no case scores above zero
+//
+// 与 exprSwitch 的区别：exprSwitch 选择第一个为真的case，ScoreSwitchNode 选择最佳匹配。
+// Unlike exprSwitch which picks the first true case, ScoreSwitchNode picks the best match.
+type ScoreSwitchNode struct {
+	// Config 打分路由节点配置
+	// Config holds the score switch node configuration
+	Config ScoreSwitchNodeConfiguration
+
+	// config 规则引擎配置
+	// config is the rule engine configuration
+	config types.Config
+
+	// cases 编译后的打分分支
+	// cases are the compiled scored branches
+	cases []scoreCase
+}
+
+// Type 返回组件类型
+// Type returns the component type identifier.
+func (x *ScoreSwitchNode) Type() types.NodeType {
+	return types.RuleSubTypeScoreSwitch
+}
+
+// Category 返回组件类别
+// Category returns the component category.
+func (x *ScoreSwitchNode) Category() string {
+	return types.CategorySwitch
+}
+
+// Relations 返回组件可能路由到的关系，分支关系由配置决定
+// Relations returns the relation types the component can route a message to, the case relations depend on the configuration.
+func (x *ScoreSwitchNode) Relations() []string {
+	return []string{types.DefaultRelationType, types.DynamicRelationType}
+}
+
+// New 创建新实例
+// New creates a new instance.
+func (x *ScoreSwitchNode) New() types.Node {
+	return &ScoreSwitchNode{}
+}
+
+// Init 初始化组件，编译所有case表达式
+// Init initializes the component.
+func (x *ScoreSwitchNode) Init(config types.Config, configuration types.Configuration) error {
+	x.config = config
+	err := maps.Map2Struct(configuration, &x.Config)
+	if err != nil {
+		return err
+	}
+	if len(x.Config.Cases) == 0 {
+		return errors.New("cases must not be empty")
+	}
+
+	x.cases = make([]scoreCase, 0, len(x.Config.Cases))
+	for _, v := range x.Config.Cases {
+		script := strings.TrimSpace(v.Case)
+		relation := strings.TrimSpace(v.Then)
+		if len(script) == 0 || len(relation) == 0 {
+			return errors.New("case must not be empty")
+		}
+		program, err := expr.Compile(script, base.NodeUtils.ExprOptions(config)...)
+		if err != nil {
+			return err
+		}
+		x.cases = append(x.cases, scoreCase{relation: relation, program: program})
+	}
+	return nil
+}
+
+// OnMsg 处理消息，评估所有case并路由到最高分的case
+// OnMsg evaluates every case and routes to the highest-scoring one.
+func (x *ScoreSwitchNode) OnMsg(ctx context.Context, msg types.RuleMsg) (string, error) {
+	var relation = types.DefaultRelationType
+	var maxScore float64
+	env := base.NodeUtils.ExprEnv(ctx, x.config, msg)
+	for _, item := range x.cases {
+		out, err := vm.Run(item.program, env)
+		if err != nil {
+			return "", err
+		}
+		score, err := cast.ToFloat64E(out)
+		if err != nil {
+			return "", err
+		}
+		if score > maxScore {
+			maxScore = score
+			relation = item.relation
+		}
+	}
+	return relation, nil
+}
+
+// Destroy 清理资源
+// Destroy cleans up resources.
+func (x *ScoreSwitchNode) Destroy() {
+}
@@ -0,0 +1,54 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transform
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bittoy/rule/types"
+	"github.com/rulego/rulego/test/assert"
+)
+
+// TestScoreSwitch checks that the scoreSwitch node routes to the case with the highest positive score,
+// the first one on a tie, and to the default relation when no case scores.
+func TestScoreSwitch(t *testing.T) {
+	node := &ScoreSwitchNode{}
+	assert.Nil(t, node.Init(types.NewConfig(), types.Configuration{"cases": []types.Case{
+		{Case: "fraud", Then: "fraud"},
+		{Case: "spam", Then: " spam "},
+	}}))
+	for _, tc := range []struct {
+		fraud, spam any
+		relation    string
+	}{
+		{0.9, 0.2, "fraud"},
+		{0.1, "0.5", "spam"},
+		{0.5, 0.5, "fraud"},
+		{0, -1, types.DefaultRelationType},
+	} {
+		relation, err := node.OnMsg(context.Background(), types.NewRuleMsg("", 0, map[string]any{"fraud": tc.fraud, "spam": tc.spam}))
+		assert.Nil(t, err)
+		assert.Equal(t, tc.relation, relation, tc)
+	}
+	_, err := node.OnMsg(context.Background(), types.NewRuleMsg("", 0, map[string]any{"fraud": "high", "spam": 0}))
+	assert.NotNil(t, err)
+
+	assert.NotNil(t, (&ScoreSwitchNode{}).Init(types.NewConfig(), types.Configuration{}))
+	assert.NotNil(t, (&ScoreSwitchNode{}).Init(types.NewConfig(), types.Configuration{"cases": []types.Case{{Case: "fraud", Then: " "}}}))
+	assert.NotNil(t, (&ScoreSwitchNode{}).Init(types.NewConfig(), types.Configuration{"cases": []types.Case{{Case: "fraud *", Then: "fraud"}}}))
+}
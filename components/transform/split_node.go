@@ -0,0 +1,182 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transform
+
+//规则链节点配置示例：
+//{
+//        "id": "s3",
+//        "type": "split",
+//        "name": "拆分订单项",
+//        "configuration": {
+//          "field": "items",
+//          "itemKey": "item",
+//          "maxFanOut": 100
+//        }
+//      }
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"strings"
+
+	"github.com/bittoy/rule/types"
+	utilsmaps "github.com/bittoy/rule/utils/maps"
+)
+
+const (
+	// DefaultSplitItemKey 默认的元素键
+	// DefaultSplitItemKey is the default input key of the element in each emitted message
+	DefaultSplitItemKey = "item"
+	// DefaultSplitMaxFanOut 默认的最大拆分数量
+	// DefaultSplitMaxFanOut is the default maximum number of emitted messages
+	DefaultSplitMaxFanOut = 100
+	// SplitIndexKey 元素下标的键
+	// SplitIndexKey is the input key of the element index in each emitted message
+	SplitIndexKey = "index"
+)
+
+func init() {
+	Registry.Add(&SplitNode{})
+}
+
+// SplitNodeConfiguration SplitNode配置结构
+// SplitNodeConfiguration defines the configuration structure for the SplitNode component.
+type SplitNodeConfiguration struct {
+	// Field 要拆分的数组字段
+	// Field is the input field holding the array to split
+	Field string `json:"field"`
+	// ItemKey 每条消息中元素的键，默认为 "item"
+	// ItemKey is the input key of the element in each emitted message, defaults to "item"
+	ItemKey string `json:"itemKey"`
+	// MaxFanOut 最大拆分数量，超过时返回 types.ErrMaxFanOutExceeded，默认为 100
+	// MaxFanOut is the maximum number of emitted messages, types.ErrMaxFanOutExceeded is
+	// returned when the array is longer. Defaults to 100
+	MaxFanOut int `json:"maxFanOut"`
+}
+
+// SplitNode 将数组字段拆分为多条消息的组件
+// SplitNode emits one message per element of an array field downstream.
+//
+// 每条消息携带原消息输入的浅拷贝（共享上下文）、元素本身（ItemKey）和元素下标（index），
+// 以及原消息私有变量的拷贝。
+// Each message carries a shallow copy of the original input (the shared context), the element
+// under ItemKey, its index under "index", and a copy of the original private variables.
+//
+// 规则链按顺序为每个元素执行一次下游节点，各分支的输出以 types.SplitResultsKey 汇总到原消息的规则链输出。
+// The chain runs the downstream nodes once per element in order, the branch outputs are
+// collected into the chain output of the original message under types.SplitResultsKey.
+// 参见 types.MultiOutputNode。
+// See types.MultiOutputNode.
+type SplitNode struct {
+	// Config 拆分节点配置
+	// Config holds the split node configuration
+	Config SplitNodeConfiguration
+}
+
+var _ types.MultiOutputNode = (*SplitNode)(nil)
+
+// Type 返回组件类型
+// Type returns the component type identifier.
+func (x *SplitNode) Type() types.NodeType {
+	return types.RuleSubTypeSplit
+}
+
+// Category 返回组件类别
+// Category returns the component category.
+func (x *SplitNode) Category() string {
+	return types.CategoryFlow
+}
+
+// Relations 返回组件可能路由到的关系
+// Relations returns the relation types the component can route a message to.
+func (x *SplitNode) Relations() []string {
+	return []string{types.DefaultRelationType}
+}
+
+// New 创建新实例
+// New creates a new instance.
+func (x *SplitNode) New() types.Node {
+	return &SplitNode{Config: SplitNodeConfiguration{
+		ItemKey:   DefaultSplitItemKey,
+		MaxFanOut: DefaultSplitMaxFanOut,
+	}}
+}
+
+// Init 初始化组件
+// Init initializes the component.
+func (x *SplitNode) Init(config types.Config, configuration types.Configuration) error {
+	err := utilsmaps.Map2Struct(configuration, &x.Config)
+	if err != nil {
+		return err
+	}
+	x.Config.Field = strings.TrimSpace(x.Config.Field)
+	if x.Config.Field == "" {
+		return errors.New("field must not be empty")
+	}
+	if strings.TrimSpace(x.Config.ItemKey) == "" {
+		x.Config.ItemKey = DefaultSplitItemKey
+	}
+	if x.Config.MaxFanOut <= 0 {
+		x.Config.MaxFanOut = DefaultSplitMaxFanOut
+	}
+	return nil
+}
+
+// OnMsg 拆分节点只能通过 OnMsgs 执行
+// OnMsg is not supported, the chain runs split nodes through OnMsgs.
+func (x *SplitNode) OnMsg(ctx context.Context, msg types.RuleMsg) (string, error) {
+	return "", errors.New("split node must be run through OnMsgs")
+}
+
+// OnMsgs 将数组字段的每个元素拆分为一条消息
+// OnMsgs emits one message per element of the array field.
+func (x *SplitNode) OnMsgs(ctx context.Context, msg types.RuleMsg) (string, []types.RuleMsg, error) {
+	input := msg.GetInput()
+	value, ok := input[x.Config.Field]
+	if !ok || value == nil {
+		return "", nil, fmt.Errorf("field %s not found", x.Config.Field)
+	}
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return "", nil, fmt.Errorf("field %s is not an array", x.Config.Field)
+	}
+	if rv.Len() > x.Config.MaxFanOut {
+		return "", nil, fmt.Errorf("%w: field:%s len:%d max:%d", types.ErrMaxFanOutExceeded, x.Config.Field, rv.Len(), x.Config.MaxFanOut)
+	}
+
+	msgs := make([]types.RuleMsg, 0, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		itemInput := maps.Clone(input)
+		delete(itemInput, types.PriVarsKey)
+		itemInput[x.Config.ItemKey] = rv.Index(i).Interface()
+		itemInput[SplitIndexKey] = i
+		itemMsg := msg.Derive(itemInput)
+		itemMsg.CopyInnerData(msg.GetPrivateVars())
+		for nodeId, output := range msg.NodeOutputs() {
+			itemMsg.SetNodeOutput(nodeId, output)
+		}
+		msgs = append(msgs, itemMsg)
+	}
+	return types.DefaultRelationType, msgs, nil
+}
+
+// Destroy 清理资源
+// Destroy cleans up resources.
+func (x *SplitNode) Destroy() {
+}
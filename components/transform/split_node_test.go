@@ -0,0 +1,75 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transform
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bittoy/rule/types"
+	"github.com/rulego/rulego/test/assert"
+)
+
+// splitAttachmentKey is a message attachment key for the split tests
+type splitAttachmentKey struct{}
+
+// TestSplit checks that the split node emits one message per element, derived from the split message.
+func TestSplit(t *testing.T) {
+	node := &SplitNode{}
+	assert.Nil(t, node.Init(types.NewConfig(), types.Configuration{"field": "items", "maxFanOut": 2}))
+
+	msg := types.NewRuleMsg("parent", 1700000000000, map[string]any{"items": []any{"a", "b"}, "user": "u1"})
+	msg.SetHeader("traceId", "t1")
+	deadline := time.Now().Add(time.Minute).Truncate(time.Millisecond)
+	msg.SetDeadline(deadline)
+	msg.AddTag("vip")
+	msg.SetAttachment(splitAttachmentKey{}, "state")
+	msg.SetPrivateVar("step", 1)
+
+	relation, msgs, err := node.OnMsgs(context.Background(), msg)
+	assert.Nil(t, err)
+	assert.Equal(t, types.DefaultRelationType, relation)
+	assert.Equal(t, 2, len(msgs))
+	for i, item := range msgs {
+		assert.True(t, item.Id() != msg.Id())
+		assert.Equal(t, msg.Ts(), item.Ts())
+		assert.Equal(t, []any{"a", "b"}[i], item.GetInput()[DefaultSplitItemKey])
+		assert.Equal(t, i, item.GetInput()[SplitIndexKey])
+		assert.Equal(t, "u1", item.GetInput()["user"])
+		assert.Equal(t, 1, item.GetPrivateVars()["step"])
+		traceId, _ := item.Header("traceId")
+		assert.Equal(t, "t1", traceId)
+		itemDeadline, ok := item.Deadline()
+		assert.True(t, ok)
+		assert.Equal(t, deadline, itemDeadline)
+		assert.Equal(t, []string{"vip"}, item.Tags())
+		assert.Equal(t, "state", item.Attachment(splitAttachmentKey{}))
+	}
+	// The derived messages do not share the mutable state of the split message
+	msgs[0].SetHeader("traceId", "t2")
+	msgs[0].AddTag("child")
+	traceId, _ := msg.Header("traceId")
+	assert.Equal(t, "t1", traceId)
+	assert.Equal(t, []string{"vip"}, msg.Tags())
+
+	_, _, err = node.OnMsgs(context.Background(), types.NewRuleMsg("", 0, map[string]any{"items": []any{1, 2, 3}}))
+	assert.True(t, errors.Is(err, types.ErrMaxFanOutExceeded))
+	_, _, err = node.OnMsgs(context.Background(), types.NewRuleMsg("", 0, map[string]any{"items": "a"}))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,326 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transform
+
+//规则链节点配置示例：
+//{
+//        "id": "s11",
+//        "type": "timeRouter",
+//        "name": "时段路由",
+//        "configuration": {
+//          "location": "Asia/Shanghai",
+//          "windows": [
+//            {"cron": "* 2-4 * * 0", "relation": "maintenance"},
+//            {"days": ["mon", "tue", "wed", "thu", "fri"], "start": "09:00", "end": "18:00", "relation": "business"}
+//          ]
+//        }
+//      }
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bittoy/rule/types"
+	"github.com/bittoy/rule/utils/maps"
+)
+
+func init() {
+	Registry.Add(&TimeRouterNode{})
+}
+
+// TimeRouterNodeConfiguration TimeRouterNode配置结构
+// TimeRouterNodeConfiguration defines the configuration structure for the TimeRouterNode component.
+type TimeRouterNodeConfiguration struct {
+	// Location 匹配时间窗口使用的 IANA 时区，如 Asia/Shanghai，为空时使用本地时区
+	// Location is the IANA time zone the windows are matched in, e.g. Asia/Shanghai, the local time zone when empty
+	Location string `json:"location"`
+	// Windows 按顺序匹配的时间窗口
+	// Windows are the time windows, matched in order
+	Windows []types.TimeWindow `json:"windows"`
+}
+
+// TimeRouterNode 根据当前时间所在的时间窗口进行路由的组件
+// TimeRouterNode routes to the relation of the first window matching the current time, or to "default"
+// when none matches, so rules can behave differently during business hours, peak hours or maintenance
+// windows. The time is read from types.Config.Clock, so with a types.ReplayClock the messages are routed
+// by the replayed time. Windows are matched to the minute.
+// 时间取自 types.Config.Clock，因此使用 types.ReplayClock 时按回放的时间路由。窗口按分钟匹配。
+type TimeRouterNode struct {
+	// Config 时段路由节点配置
+	// Config holds the time router node configuration
+	Config TimeRouterNodeConfiguration
+
+	// clock 规则引擎配置的时钟
+	// clock is the clock of the rule engine configuration
+	clock types.Clock
+	// location 匹配时间窗口使用的时区
+	// location is the time zone the windows are matched in
+	location *time.Location
+	// windows 解析后的时间窗口
+	// windows are the parsed time windows
+	windows []timeWindow
+}
+
+// Type 返回组件类型
+// Type returns the component type identifier.
+func (x *TimeRouterNode) Type() types.NodeType {
+	return types.RuleSubTypeTimeRouter
+}
+
+// Category 返回组件类别
+// Category returns the component category.
+func (x *TimeRouterNode) Category() string {
+	return types.CategorySwitch
+}
+
+// Relations 返回组件可能路由到的关系，窗口关系由配置决定
+// Relations returns the relation types the component can route a message to, the window relations depend on the configuration.
+func (x *TimeRouterNode) Relations() []string {
+	return []string{types.DefaultRelationType, types.DynamicRelationType}
+}
+
+// New 创建新实例
+// New creates a new instance.
+func (x *TimeRouterNode) New() types.Node {
+	return &TimeRouterNode{}
+}
+
+// Init 初始化组件，解析时区和时间窗口
+// Init initializes the component, parsing the time zone and the windows.
+func (x *TimeRouterNode) Init(config types.Config, configuration types.Configuration) error {
+	err := maps.Map2Struct(configuration, &x.Config)
+	if err != nil {
+		return err
+	}
+	x.clock = config.GetClock()
+	x.location = time.Local
+	if x.Config.Location != "" {
+		if x.location, err = time.LoadLocation(x.Config.Location); err != nil {
+			return fmt.Errorf("invalid location:%w", err)
+		}
+	}
+	if len(x.Config.Windows) == 0 {
+		return errors.New("windows must not be empty")
+	}
+	x.windows = make([]timeWindow, len(x.Config.Windows))
+	for i, window := range x.Config.Windows {
+		if x.windows[i], err = parseTimeWindow(window); err != nil {
+			return fmt.Errorf("window %d %w", i, err)
+		}
+	}
+	return nil
+}
+
+// OnMsg 处理消息，路由到当前时间匹配的第一个窗口的关系
+// OnMsg routes to the relation of the first window matching the current time.
+func (x *TimeRouterNode) OnMsg(ctx context.Context, msg types.RuleMsg) (string, error) {
+	now := x.clock.Now().In(x.location)
+	for _, window := range x.windows {
+		if window.matches(now) {
+			return window.relation, nil
+		}
+	}
+	return types.DefaultRelationType, nil
+}
+
+// Destroy 清理资源
+// Destroy cleans up resources.
+func (x *TimeRouterNode) Destroy() {
+}
+
+// timeWindow is a parsed types.TimeWindow
+type timeWindow struct {
+	relation string
+	// cron is the parsed cron expression, nil for a day window
+	cron *cronSchedule
+	// days is the bit set of the days of the week, time.Sunday is bit 0
+	days uint8
+	// start and end are the minutes of the day the window starts at, included, and ends at, excluded
+	start, end int
+}
+
+// weekdays maps the day names accepted in types.TimeWindow.Days to their day of the week
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// parseTimeWindow parses a window of the configuration
+func parseTimeWindow(window types.TimeWindow) (timeWindow, error) {
+	parsed := timeWindow{relation: strings.TrimSpace(window.Relation), end: 24 * 60}
+	if parsed.relation == "" {
+		return parsed, errors.New("relation must not be empty")
+	}
+	if strings.TrimSpace(window.Cron) != "" {
+		cron, err := parseCron(window.Cron)
+		if err != nil {
+			return parsed, fmt.Errorf("invalid cron:%w", err)
+		}
+		parsed.cron = cron
+		return parsed, nil
+	}
+	for _, day := range window.Days {
+		weekday, ok := weekdays[strings.ToLower(strings.TrimSpace(day))]
+		if !ok {
+			return parsed, fmt.Errorf("invalid day:%s", day)
+		}
+		parsed.days |= 1 << weekday
+	}
+	if parsed.days == 0 {
+		parsed.days = 1<<7 - 1
+	}
+	var err error
+	if window.Start != "" {
+		if parsed.start, err = parseTimeOfDay(window.Start); err != nil {
+			return parsed, fmt.Errorf("invalid start:%w", err)
+		}
+	}
+	if window.End != "" {
+		if parsed.end, err = parseTimeOfDay(window.End); err != nil {
+			return parsed, fmt.Errorf("invalid end:%w", err)
+		}
+	}
+	if parsed.start == parsed.end {
+		return parsed, errors.New("start and end must differ")
+	}
+	return parsed, nil
+}
+
+// parseTimeOfDay parses a HH:MM time of day into minutes of the day
+func parseTimeOfDay(value string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// matches reports whether t falls in the window. The part after midnight of a window spanning
+// midnight belongs to the day it started on.
+func (w timeWindow) matches(t time.Time) bool {
+	if w.cron != nil {
+		return w.cron.matches(t)
+	}
+	minute := t.Hour()*60 + t.Minute()
+	if w.start < w.end {
+		return w.hasDay(t.Weekday()) && minute >= w.start && minute < w.end
+	}
+	if minute >= w.start {
+		return w.hasDay(t.Weekday())
+	}
+	return minute < w.end && w.hasDay((t.Weekday()+6)%7)
+}
+
+// hasDay reports whether the window is on the day of the week
+func (w timeWindow) hasDay(day time.Weekday) bool {
+	return w.days&(1<<day) != 0
+}
+
+// cronSchedule is a parsed 5 field cron expression, each field is the bit set of its matching values
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// domAny and dowAny report whether the day fields are *, when both are restricted a day matching either matches
+	domAny, dowAny bool
+}
+
+// parseCron parses a cron expression of 5 fields: minute hour day-of-month month day-of-week.
+// A field is *, or a comma separated list of values and ranges a-b, each optionally followed by a step /n.
+// The day of the week is 0 to 7, both 0 and 7 being Sunday.
+func parseCron(spec string) (*cronSchedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields, got %d", len(fields))
+	}
+	var cron cronSchedule
+	var err error
+	if cron.minute, _, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, err
+	}
+	if cron.hour, _, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, err
+	}
+	if cron.dom, cron.domAny, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, err
+	}
+	if cron.month, _, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, err
+	}
+	if cron.dow, cron.dowAny, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, err
+	}
+	if cron.dow&(1<<7) != 0 {
+		cron.dow |= 1
+	}
+	return &cron, nil
+}
+
+// parseCronField parses a cron field into the bit set of its values between first and last,
+// all reports whether the field is *
+func parseCronField(field string, first, last int) (set uint64, all bool, err error) {
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			if step, err = strconv.Atoi(stepPart); err != nil || step <= 0 {
+				return 0, false, fmt.Errorf("invalid step in %s", field)
+			}
+		}
+		low, high := first, last
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			lowPart, highPart, _ := strings.Cut(rangePart, "-")
+			low, err = strconv.Atoi(lowPart)
+			if err == nil {
+				high, err = strconv.Atoi(highPart)
+			}
+		default:
+			low, err = strconv.Atoi(rangePart)
+			high = low
+			if hasStep {
+				high = last
+			}
+		}
+		if err != nil || low < first || high > last || low > high {
+			return 0, false, fmt.Errorf("invalid value %s, expected %d-%d", part, first, last)
+		}
+		for value := low; value <= high; value += step {
+			set |= 1 << value
+		}
+	}
+	return set, field == "*", nil
+}
+
+// matches reports whether the minute of t matches the expression
+func (c *cronSchedule) matches(t time.Time) bool {
+	if c.minute&(1<<t.Minute()) == 0 || c.hour&(1<<t.Hour()) == 0 || c.month&(1<<int(t.Month())) == 0 {
+		return false
+	}
+	dom := c.dom&(1<<t.Day()) != 0
+	dow := c.dow&(1<<int(t.Weekday())) != 0
+	switch {
+	case c.domAny:
+		return dow
+	case c.dowAny:
+		return dom
+	default:
+		return dom || dow
+	}
+}
@@ -0,0 +1,71 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transform
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bittoy/rule/types"
+	"github.com/rulego/rulego/test/assert"
+)
+
+// TestTimeRouter checks that the timeRouter node routes by the windows matching the clock time.
+func TestTimeRouter(t *testing.T) {
+	clock := types.NewReplayClock(time.Time{})
+	node := &TimeRouterNode{}
+	assert.Nil(t, node.Init(types.NewConfig(types.WithClock(clock)), types.Configuration{"location": "UTC", "windows": []types.TimeWindow{
+		{Cron: "* 2-4 * * 0", Relation: "maintenance"},
+		{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "09:00", End: "18:00", Relation: "business"},
+		{Days: []string{"fri"}, Start: "22:00", End: "06:00", Relation: "night"},
+	}}))
+	for _, tc := range []struct {
+		at       time.Time
+		relation string
+	}{
+		{time.Date(2026, 10, 12, 10, 0, 0, 0, time.UTC), "business"},
+		{time.Date(2026, 10, 12, 18, 0, 0, 0, time.UTC), types.DefaultRelationType},
+		{time.Date(2026, 10, 16, 23, 0, 0, 0, time.UTC), "night"},
+		{time.Date(2026, 10, 17, 2, 0, 0, 0, time.UTC), "night"},
+		{time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC), types.DefaultRelationType},
+		{time.Date(2026, 10, 18, 3, 59, 0, 0, time.UTC), "maintenance"},
+		{time.Date(2026, 10, 18, 5, 0, 0, 0, time.UTC), types.DefaultRelationType},
+	} {
+		clock.Advance(tc.at)
+		relation, err := node.OnMsg(context.Background(), types.NewRuleMsg("", 0, map[string]any{}))
+		assert.Nil(t, err)
+		assert.Equal(t, tc.relation, relation, tc.at)
+	}
+}
+
+// TestTimeRouterInit checks the validation of the time windows.
+func TestTimeRouterInit(t *testing.T) {
+	for _, windows := range [][]types.TimeWindow{
+		nil,
+		{{Cron: "* * *", Relation: "x"}},
+		{{Cron: "61 * * * *", Relation: "x"}},
+		{{Start: "25:00", Relation: "x"}},
+		{{Days: []string{"someday"}, Relation: "x"}},
+		{{Start: "09:00", End: "09:00", Relation: "x"}},
+		{{Start: "09:00"}},
+	} {
+		assert.NotNil(t, (&TimeRouterNode{}).Init(types.NewConfig(), types.Configuration{"windows": windows}), windows)
+	}
+	assert.NotNil(t, (&TimeRouterNode{}).Init(types.NewConfig(), types.Configuration{"location": "Mars/Olympus",
+		"windows": []types.TimeWindow{{Start: "09:00", Relation: "x"}}}))
+}
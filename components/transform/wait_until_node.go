@@ -0,0 +1,315 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transform
+
+//规则链节点配置示例：
+//{
+//        "id": "s10",
+//        "type": "waitUntil",
+//        "name": "等待到目标时间",
+//        "configuration": {
+//          "field": "processAt",
+//          "maxDelay": "30s"
+//        }
+//      }
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bittoy/rule/types"
+	"github.com/bittoy/rule/utils/cast"
+	"github.com/bittoy/rule/utils/maps"
+)
+
+func init() {
+	Registry.Add(&WaitUntilNode{})
+}
+
+// WaitUntilNodeConfiguration WaitUntilNode配置结构
+// WaitUntilNodeConfiguration defines the configuration structure for the WaitUntilNode component.
+type WaitUntilNodeConfiguration struct {
+	// Field 目标时间字段路径，值为毫秒时间戳或 RFC 3339 时间字符串
+	// Field is the path of the target time field, valued a Unix timestamp in milliseconds or an RFC 3339 time string
+	Field string `json:"field"`
+	// MaxDelay 最长等待时间，如 10m，目标时间更晚的消息在等待 MaxDelay 后转发
+	// MaxDelay is the longest wait, e.g. 10m, messages with a later target time are forwarded after MaxDelay
+	MaxDelay string `json:"maxDelay"`
+}
+
+// WaitUntilNode 持有消息直到消息字段中的目标时间后再转发消息的组件
+// WaitUntilNode holds the message until the target time read from a message field, at most MaxDelay later,
+// then forwards it to "default". The message is handed off, see types.AsyncNode: the engine OnMsg returns
+// right away and the chain resumes from the timer at the target time, so the waiting messages hold no goroutine.
+// A target time in the past forwards the message immediately, a missing or malformed field fails the node.
+// A message whose target time is after its deadline is routed to "deadlineExceeded" right away, see
+// types.RuleMsg.Deadline. The time is read from types.Config.Clock, so with a types.ReplayClock the
+// messages wait in replayed time.
+// WaitUntilNode 持有消息直到消息字段中的目标时间（最多 MaxDelay）后将消息转发到 "default"。消息会被移交，参见
+// types.AsyncNode：引擎 OnMsg 立即返回，规则链在目标时间由定时器恢复执行，等待中的消息不占用 goroutine。
+// 目标时间已过时立即转发消息，字段缺失或格式错误时节点失败。
+// 目标时间晚于消息截止时间时，立即路由到 "deadlineExceeded"，参见 types.RuleMsg.Deadline。
+// 时间取自 types.Config.Clock，因此使用 types.ReplayClock 时按回放的时间等待。
+//
+// 等待中的消息保存在按目标时间排序的堆中，由单个定时器唤醒，不为每条消息创建定时器。
+// The waiting messages are kept in a heap ordered by target time and woken by a single timer, no timer
+// is created per message.
+//
+// 等待中的消息只保存在内存中：进程退出时丢失。上下文取消时消息以取消原因失败，引擎停止时会取消正在等待的消息，
+// 它们以 types.ErrEngineShuttingDown 失败并转入 types.Config.DeadLetter。重载后等待中的消息继续通过原规则链执行。
+// The waiting messages are only kept in memory, they are lost when the process exits. A message fails with the
+// cancellation cause when its context is cancelled; stopping the engine cancels the waiting messages, which fail
+// with types.ErrEngineShuttingDown and are passed to types.Config.DeadLetter. After a reload the waiting messages
+// resume through the chain they entered.
+type WaitUntilNode struct {
+	// Config 节点配置
+	// Config holds the waitUntil node configuration
+	Config WaitUntilNodeConfiguration
+
+	// maxDelay 解析后的最长等待时间
+	// maxDelay is the parsed longest wait
+	maxDelay time.Duration
+	// clock 规则引擎配置的时钟
+	// clock is the clock of the rule engine configuration
+	clock types.Clock
+
+	// mu 保护 pending、timer 和 destroyed
+	// mu guards pending, timer and destroyed
+	mu sync.Mutex
+	// pending 按目标时间排序的等待消息
+	// pending are the held messages ordered by target time
+	pending waitHeap
+	// timer 在最早的目标时间触发
+	// timer fires at the earliest target time
+	timer types.ClockTimer
+	// destroyed 节点已销毁
+	// destroyed reports whether the node has been destroyed
+	destroyed bool
+}
+
+var _ types.AsyncNode = (*WaitUntilNode)(nil)
+
+// Type 返回组件类型
+// Type returns the component type identifier.
+func (x *WaitUntilNode) Type() types.NodeType {
+	return types.RuleSubTypeWaitUntil
+}
+
+// Category 返回组件类别
+// Category returns the component category.
+func (x *WaitUntilNode) Category() string {
+	return types.CategoryFlow
+}
+
+// Relations 返回组件可能路由到的关系
+// Relations returns the relation types the component can route a message to.
+func (x *WaitUntilNode) Relations() []string {
+	return []string{types.DefaultRelationType, types.DeadlineExceededRelationType}
+}
+
+// New 创建新实例
+// New creates a new instance.
+func (x *WaitUntilNode) New() types.Node {
+	return &WaitUntilNode{}
+}
+
+// Init 初始化组件，解析最长等待时间
+// Init initializes the component, parsing the longest wait.
+func (x *WaitUntilNode) Init(config types.Config, configuration types.Configuration) error {
+	x.clock = config.GetClock()
+	err := maps.Map2Struct(configuration, &x.Config)
+	if err != nil {
+		return err
+	}
+	x.Config.Field = strings.TrimSpace(x.Config.Field)
+	if x.Config.Field == "" {
+		return errors.New("field must not be empty")
+	}
+	if x.maxDelay, err = time.ParseDuration(x.Config.MaxDelay); err != nil {
+		return fmt.Errorf("invalid maxDelay:%w", err)
+	}
+	if x.maxDelay <= 0 {
+		return errors.New("maxDelay must be positive")
+	}
+	return nil
+}
+
+// OnMsg 处理消息，阻塞到目标时间后转发
+// OnMsg blocks until the target time of the message, then forwards it, see OnMsgAsync.
+func (x *WaitUntilNode) OnMsg(ctx context.Context, msg types.RuleMsg) (string, error) {
+	resumed := make(chan struct{})
+	var resumedRelation string
+	var resumedErr error
+	relationType, handedOff, err := x.OnMsgAsync(ctx, msg, func(relationType string, err error) {
+		resumedRelation, resumedErr = relationType, err
+		close(resumed)
+	})
+	if !handedOff {
+		return relationType, err
+	}
+	<-resumed
+	return resumedRelation, resumedErr
+}
+
+// OnMsgAsync 处理消息，移交消息直到目标时间，然后调用 resume 转发
+// OnMsgAsync hands the message off until its target time, then forwards it with resume.
+func (x *WaitUntilNode) OnMsgAsync(ctx context.Context, msg types.RuleMsg, resume func(relationType string, err error)) (string, bool, error) {
+	value := fieldValue(msg, x.Config.Field)
+	if value == nil {
+		return "", false, fmt.Errorf("field %s not found", x.Config.Field)
+	}
+	at, err := cast.ToTimeE(value)
+	if err != nil {
+		return "", false, fmt.Errorf("invalid %s:%w", x.Config.Field, err)
+	}
+	now := x.clock.Now()
+	if latest := now.Add(x.maxDelay); at.After(latest) {
+		at = latest
+	}
+	if !at.After(now) {
+		return types.DefaultRelationType, false, nil
+	}
+	if deadline, ok := msg.Deadline(); ok && at.After(deadline) {
+		return types.DeadlineExceededRelationType, false, nil
+	}
+	if err = x.schedule(ctx, at, resume); err != nil {
+		return "", false, err
+	}
+	return "", true, nil
+}
+
+// Destroy 清理资源，丢弃等待中的消息
+// Destroy cleans up resources, dropping the held messages, which fail with types.ErrEngineShuttingDown.
+func (x *WaitUntilNode) Destroy() {
+	x.mu.Lock()
+	x.destroyed = true
+	if x.timer != nil {
+		x.timer.Stop()
+	}
+	pending := x.pending
+	for _, entry := range pending {
+		entry.index = -1
+	}
+	x.pending = nil
+	x.mu.Unlock()
+	for _, entry := range pending {
+		entry.stop()
+		entry.resume("", types.ErrEngineShuttingDown)
+	}
+}
+
+// schedule adds a held message resumed at at, or with the cancellation cause once ctx is cancelled
+func (x *WaitUntilNode) schedule(ctx context.Context, at time.Time, resume func(string, error)) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.destroyed {
+		return types.ErrEngineShuttingDown
+	}
+	entry := &waitEntry{at: at, resume: resume}
+	heap.Push(&x.pending, entry)
+	if entry.index == 0 {
+		x.resetTimer()
+	}
+	entry.stop = context.AfterFunc(ctx, func() {
+		x.cancel(entry, context.Cause(ctx))
+	})
+	return nil
+}
+
+// cancel removes a held message whose context is cancelled and resumes it with cause
+func (x *WaitUntilNode) cancel(entry *waitEntry, cause error) {
+	x.mu.Lock()
+	held := entry.index >= 0
+	if held {
+		heap.Remove(&x.pending, entry.index)
+	}
+	x.mu.Unlock()
+	if held {
+		entry.resume("", cause)
+	}
+}
+
+// release resumes the held messages whose target time has passed, each in its own goroutine as the clock runs
+// the timer functions without waiting, and rearms the timer
+func (x *WaitUntilNode) release() {
+	x.mu.Lock()
+	now := x.clock.Now()
+	var due []*waitEntry
+	for len(x.pending) > 0 && !x.pending[0].at.After(now) {
+		due = append(due, heap.Pop(&x.pending).(*waitEntry))
+	}
+	if len(x.pending) > 0 && !x.destroyed {
+		x.resetTimer()
+	}
+	x.mu.Unlock()
+	for _, entry := range due {
+		entry.stop()
+		go entry.resume(types.DefaultRelationType, nil)
+	}
+}
+
+// resetTimer arms the timer at the earliest target time, x.mu must be held
+func (x *WaitUntilNode) resetTimer() {
+	if x.timer != nil {
+		x.timer.Stop()
+	}
+	x.timer = x.clock.AfterFunc(x.pending[0].at.Sub(x.clock.Now()), x.release)
+}
+
+// waitEntry is a held message
+type waitEntry struct {
+	// at is the time the message is released
+	at time.Time
+	// resume forwards the message once it is released, or fails it when it is dropped
+	resume func(relationType string, err error)
+	// stop stops watching the context of the message
+	stop func() bool
+	// index is the position in the heap, -1 once removed
+	index int
+}
+
+// waitHeap is a min-heap of held messages ordered by release time
+type waitHeap []*waitEntry
+
+func (h waitHeap) Len() int { return len(h) }
+
+func (h waitHeap) Less(i, j int) bool { return h[i].at.Before(h[j].at) }
+
+func (h waitHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *waitHeap) Push(x any) {
+	entry := x.(*waitEntry)
+	entry.index = len(*h)
+	*h = append(*h, entry)
+}
+
+func (h *waitHeap) Pop() any {
+	old := *h
+	entry := old[len(old)-1]
+	old[len(old)-1] = nil
+	entry.index = -1
+	*h = old[:len(old)-1]
+	return entry
+}
@@ -0,0 +1,176 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transform
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bittoy/rule/types"
+	"github.com/rulego/rulego/test/assert"
+)
+
+// TestWaitUntil checks that the waitUntil node holds a message until its target time, capped by maxDelay.
+func TestWaitUntil(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := types.NewReplayClock(start)
+	node := &WaitUntilNode{}
+	assert.Nil(t, node.Init(types.NewConfig(types.WithClock(clock)), types.Configuration{"field": " at ", "maxDelay": "1h"}))
+	defer node.Destroy()
+	wait := func(ctx context.Context, msg types.RuleMsg) chan error {
+		done := make(chan error, 1)
+		go func() {
+			relation, err := node.OnMsg(ctx, msg)
+			if err == nil && relation != types.DefaultRelationType {
+				err = errors.New("unexpected relation " + relation)
+			}
+			done <- err
+		}()
+		time.Sleep(20 * time.Millisecond)
+		return done
+	}
+	held := func(done chan error) bool {
+		select {
+		case <-done:
+			return false
+		case <-time.After(20 * time.Millisecond):
+			return true
+		}
+	}
+
+	relation, err := node.OnMsg(context.Background(), types.NewRuleMsg("", 0, map[string]any{"at": start.Add(-time.Minute).UnixMilli()}))
+	assert.Nil(t, err)
+	assert.Equal(t, types.DefaultRelationType, relation)
+
+	later := wait(context.Background(), types.NewRuleMsg("", 0, map[string]any{"at": start.Add(2 * time.Minute).UnixMilli()}))
+	sooner := wait(context.Background(), types.NewRuleMsg("", 0, map[string]any{"at": start.Add(time.Minute).UnixMilli()}))
+	clock.Advance(start.Add(30 * time.Second))
+	assert.True(t, held(sooner))
+	clock.Advance(start.Add(time.Minute))
+	assert.Nil(t, <-sooner)
+	assert.True(t, held(later))
+	clock.Advance(start.Add(2 * time.Minute))
+	assert.Nil(t, <-later)
+
+	// The target time is capped by maxDelay
+	now := clock.Now()
+	capped := wait(context.Background(), types.NewRuleMsg("", 0, map[string]any{"at": now.Add(24 * time.Hour).UnixMilli()}))
+	clock.Advance(now.Add(time.Hour))
+	assert.Nil(t, <-capped)
+
+	// A cancelled message stops waiting
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelled := wait(ctx, types.NewRuleMsg("", 0, map[string]any{"at": clock.Now().Add(time.Minute).UnixMilli()}))
+	cancel()
+	assert.True(t, errors.Is(<-cancelled, context.Canceled))
+	assert.Equal(t, 0, len(node.pending))
+
+	_, err = node.OnMsg(context.Background(), types.NewRuleMsg("", 0, map[string]any{}))
+	assert.NotNil(t, err)
+	_, err = node.OnMsg(context.Background(), types.NewRuleMsg("", 0, map[string]any{"at": "soon"}))
+	assert.NotNil(t, err)
+}
+
+// TestWaitUntilDeadline checks that the waitUntil node routes the messages it cannot release before their
+// deadline, set on the message or read from the deadline header, to the deadlineExceeded relation.
+func TestWaitUntilDeadline(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	node := &WaitUntilNode{}
+	assert.Nil(t, node.Init(types.NewConfig(types.WithClock(types.NewReplayClock(start))), types.Configuration{"field": "at", "maxDelay": "1h"}))
+	defer node.Destroy()
+
+	msg := types.NewRuleMsg("", 0, map[string]any{"at": start.Add(time.Minute).UnixMilli()})
+	msg.SetHeader(types.DeadlineHeader, start.Add(30*time.Second).Format(time.RFC3339Nano))
+	relation, err := node.OnMsg(context.Background(), msg)
+	assert.Nil(t, err)
+	assert.Equal(t, types.DeadlineExceededRelationType, relation)
+
+	msg = types.NewRuleMsg("", 0, map[string]any{"at": start.Add(time.Minute).UnixMilli()})
+	msg.SetDeadline(start.Add(30 * time.Second))
+	relation, err = node.OnMsg(context.Background(), msg)
+	assert.Nil(t, err)
+	assert.Equal(t, types.DeadlineExceededRelationType, relation)
+
+	// A message already due is released even past its deadline
+	msg = types.NewRuleMsg("", 0, map[string]any{"at": start.UnixMilli()})
+	msg.SetDeadline(start.Add(-time.Second))
+	relation, err = node.OnMsg(context.Background(), msg)
+	assert.Nil(t, err)
+	assert.Equal(t, types.DefaultRelationType, relation)
+}
+
+// TestWaitUntilAsync checks that OnMsgAsync hands off the messages with a future target time and resumes them
+// at that time, and completes the others right away.
+func TestWaitUntilAsync(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := types.NewReplayClock(start)
+	node := &WaitUntilNode{}
+	assert.Nil(t, node.Init(types.NewConfig(types.WithClock(clock)), types.Configuration{"field": "at", "maxDelay": "1h"}))
+	defer node.Destroy()
+	resumed := make(chan string, 1)
+	resume := func(relationType string, err error) {
+		assert.Nil(t, err)
+		resumed <- relationType
+	}
+
+	relation, handedOff, err := node.OnMsgAsync(context.Background(), types.NewRuleMsg("", 0, map[string]any{"at": start.UnixMilli()}), resume)
+	assert.Nil(t, err)
+	assert.False(t, handedOff)
+	assert.Equal(t, types.DefaultRelationType, relation)
+
+	_, handedOff, err = node.OnMsgAsync(context.Background(), types.NewRuleMsg("", 0, map[string]any{"at": start.Add(time.Minute).UnixMilli()}), resume)
+	assert.Nil(t, err)
+	assert.True(t, handedOff)
+	clock.Advance(start.Add(30 * time.Second))
+	select {
+	case <-resumed:
+		t.Fatal("message resumed before its target time")
+	case <-time.After(20 * time.Millisecond):
+	}
+	clock.Advance(start.Add(time.Minute))
+	assert.Equal(t, types.DefaultRelationType, <-resumed)
+}
+
+// TestWaitUntilDestroy checks that destroying the node fails the waiting messages and the later ones.
+func TestWaitUntilDestroy(t *testing.T) {
+	node := &WaitUntilNode{}
+	assert.Nil(t, node.Init(types.NewConfig(), types.Configuration{"field": "at", "maxDelay": "1h"}))
+	done := make(chan error, 1)
+	go func() {
+		_, err := node.OnMsg(context.Background(), types.NewRuleMsg("", 0, map[string]any{"at": time.Now().Add(time.Hour).UnixMilli()}))
+		done <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	node.Destroy()
+	assert.True(t, errors.Is(<-done, types.ErrEngineShuttingDown))
+	_, err := node.OnMsg(context.Background(), types.NewRuleMsg("", 0, map[string]any{"at": time.Now().Add(time.Hour).UnixMilli()}))
+	assert.True(t, errors.Is(err, types.ErrEngineShuttingDown))
+}
+
+// TestWaitUntilInit checks the validation of the waitUntil configuration.
+func TestWaitUntilInit(t *testing.T) {
+	for _, configuration := range []types.Configuration{
+		{"field": " ", "maxDelay": "1h"},
+		{"field": "at"},
+		{"field": "at", "maxDelay": "later"},
+		{"field": "at", "maxDelay": "0s"},
+	} {
+		assert.NotNil(t, (&WaitUntilNode{}).Init(types.NewConfig(), configuration), configuration)
+	}
+}
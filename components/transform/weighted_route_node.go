@@ -0,0 +1,178 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transform
+
+//规则链节点配置示例：
+//{
+//        "id": "s12",
+//        "type": "weightedRoute",
+//        "name": "灰度分流",
+//        "configuration": {
+//          "routes": [
+//            {"relation": "control", "weight": 90},
+//            {"relation": "treatment", "weight": 10}
+//          ],
+//          "seed": 42,
+//          "byMsgId": true
+//        }
+//      }
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand/v2"
+	"strings"
+	"sync"
+
+	"github.com/bittoy/rule/types"
+	"github.com/bittoy/rule/utils/maps"
+)
+
+func init() {
+	Registry.Add(&WeightedRouteNode{})
+}
+
+// weightTolerance is the tolerance of the sum of the weights to 100, for weights like 33.3
+const weightTolerance = 0.01
+
+// WeightedRouteNodeConfiguration WeightedRouteNode配置结构
+// WeightedRouteNodeConfiguration defines the configuration structure for the WeightedRouteNode component.
+type WeightedRouteNodeConfiguration struct {
+	// Routes 路由分支，权重为百分比，之和必须为 100
+	// Routes are the routes, their weights are percentages that must sum to 100
+	Routes []types.WeightedRoute `json:"routes"`
+	// Seed 随机数种子，相同种子的节点对相同的消息序列做出相同的选择，为 0 时使用随机种子
+	// Seed seeds the random generator, so nodes with the same seed route the same sequence of messages
+	// the same way. A random seed is used when it is 0
+	Seed int64 `json:"seed"`
+	// ByMsgId 为 true 时由消息 id 和 Seed 决定分支，同一消息总是路由到同一分支
+	// ByMsgId derives the route from the message id and Seed instead, so a message always takes the same route
+	ByMsgId bool `json:"byMsgId"`
+}
+
+// WeightedRouteNode 按权重随机分流的组件
+// WeightedRouteNode routes each message to one of the relations of Routes with the probability of its weight,
+// e.g. 90% to "control" and 10% to "treatment", for A/B tests and canary releases of rule logic.
+//
+// 使用 Seed 时分流结果可复现；使用 ByMsgId 时同一消息重试或重放时保持在同一分支。
+// Routing is reproducible with Seed; with ByMsgId a message keeps its route when it is retried or replayed.
+type WeightedRouteNode struct {
+	// Config 节点配置
+	// Config holds the weighted route node configuration
+	Config WeightedRouteNodeConfiguration
+
+	// bounds 各分支累计权重的上界
+	// bounds are the upper bounds of the cumulated weights of the routes
+	bounds []float64
+
+	// mu 保护 rng
+	// mu guards rng
+	mu sync.Mutex
+	// rng 随机数生成器
+	// rng is the random generator
+	rng *rand.Rand
+}
+
+// Type 返回组件类型
+// Type returns the component type identifier.
+func (x *WeightedRouteNode) Type() types.NodeType {
+	return types.RuleSubTypeWeightedRoute
+}
+
+// Category 返回组件类别
+// Category returns the component category.
+func (x *WeightedRouteNode) Category() string {
+	return types.CategorySwitch
+}
+
+// Relations 返回组件可能路由到的关系，分支关系由配置决定
+// Relations returns the relation types the component can route a message to, the route relations depend on the configuration.
+func (x *WeightedRouteNode) Relations() []string {
+	return []string{types.DynamicRelationType}
+}
+
+// New 创建新实例
+// New creates a new instance.
+func (x *WeightedRouteNode) New() types.Node {
+	return &WeightedRouteNode{}
+}
+
+// Init 初始化组件，校验权重并创建随机数生成器
+// Init initializes the component, checking the weights and creating the random generator.
+func (x *WeightedRouteNode) Init(config types.Config, configuration types.Configuration) error {
+	err := maps.Map2Struct(configuration, &x.Config)
+	if err != nil {
+		return err
+	}
+	if len(x.Config.Routes) == 0 {
+		return errors.New("routes must not be empty")
+	}
+	x.bounds = make([]float64, len(x.Config.Routes))
+	var sum float64
+	for i, route := range x.Config.Routes {
+		relation := strings.TrimSpace(route.Relation)
+		if relation == "" {
+			return fmt.Errorf("route %d relation must not be empty", i)
+		}
+		if route.Weight <= 0 || math.IsNaN(route.Weight) || math.IsInf(route.Weight, 0) {
+			return fmt.Errorf("route %s weight must be positive, weight:%v", relation, route.Weight)
+		}
+		x.Config.Routes[i].Relation = relation
+		sum += route.Weight
+		x.bounds[i] = sum
+	}
+	if math.Abs(sum-100) > weightTolerance {
+		return fmt.Errorf("route weights must sum to 100, sum:%v", sum)
+	}
+	seed := uint64(x.Config.Seed)
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+	x.rng = rand.New(rand.NewPCG(seed, seed))
+	return nil
+}
+
+// OnMsg 处理消息，按权重选择分支关系
+// OnMsg routes the message to a relation chosen by weight.
+func (x *WeightedRouteNode) OnMsg(ctx context.Context, msg types.RuleMsg) (string, error) {
+	point := x.point(msg) * x.bounds[len(x.bounds)-1]
+	for i, bound := range x.bounds {
+		if point < bound {
+			return x.Config.Routes[i].Relation, nil
+		}
+	}
+	return x.Config.Routes[len(x.Config.Routes)-1].Relation, nil
+}
+
+// point returns a number in [0, 1) drawn from the random generator, or from the message id with ByMsgId
+func (x *WeightedRouteNode) point(msg types.RuleMsg) float64 {
+	if x.Config.ByMsgId {
+		h := fnv.New64a()
+		_, _ = fmt.Fprintf(h, "%d:%s", x.Config.Seed, msg.Id())
+		return float64(h.Sum64()>>11) / (1 << 53)
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return x.rng.Float64()
+}
+
+// Destroy 清理资源
+// Destroy cleans up resources.
+func (x *WeightedRouteNode) Destroy() {
+}
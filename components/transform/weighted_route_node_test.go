@@ -0,0 +1,81 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transform
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/bittoy/rule/types"
+	"github.com/rulego/rulego/test/assert"
+)
+
+// TestWeightedRoute checks that the weightedRoute node splits the messages by weight, reproducibly with a seed
+// and stably per message id with byMsgId.
+func TestWeightedRoute(t *testing.T) {
+	routes := []types.WeightedRoute{{Relation: "control", Weight: 90}, {Relation: " treatment ", Weight: 10}}
+	route := func(byMsgId bool, ids []string) []string {
+		node := &WeightedRouteNode{}
+		assert.Nil(t, node.Init(types.NewConfig(), types.Configuration{"routes": slices.Clone(routes), "seed": 42, "byMsgId": byMsgId}))
+		var result []string
+		for _, id := range ids {
+			relation, err := node.OnMsg(context.Background(), types.NewRuleMsg(id, 0, map[string]any{}))
+			assert.Nil(t, err)
+			result = append(result, relation)
+		}
+		return result
+	}
+	ids := make([]string, 1000)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("msg-%d", i)
+	}
+	first := route(false, ids)
+	assert.Equal(t, first, route(false, ids))
+	treatment := 0
+	for _, relation := range first {
+		if relation == "treatment" {
+			treatment++
+		}
+	}
+	assert.True(t, treatment > 50 && treatment < 150, treatment)
+
+	forward := route(true, ids)
+	reversed := slices.Clone(ids)
+	slices.Reverse(reversed)
+	backward := route(true, reversed)
+	slices.Reverse(backward)
+	assert.Equal(t, forward, backward)
+}
+
+// TestWeightedRouteInit checks the validation of the routes.
+func TestWeightedRouteInit(t *testing.T) {
+	for _, tc := range []struct {
+		routes []types.WeightedRoute
+		err    string
+	}{
+		{nil, "must not be empty"},
+		{[]types.WeightedRoute{{Relation: " ", Weight: 100}}, "relation must not be empty"},
+		{[]types.WeightedRoute{{Relation: "a", Weight: 100}, {Relation: "b", Weight: 0}}, "weight must be positive"},
+		{[]types.WeightedRoute{{Relation: "a", Weight: 90}, {Relation: "b", Weight: 20}}, "sum to 100"},
+	} {
+		err := (&WeightedRouteNode{}).Init(types.NewConfig(), types.Configuration{"routes": tc.routes})
+		assert.True(t, err != nil && strings.Contains(err.Error(), tc.err), tc.err)
+	}
+}
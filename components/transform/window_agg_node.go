@@ -0,0 +1,374 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transform
+
+//规则链节点配置示例：
+//{
+//        "id": "s6",
+//        "type": "windowAgg",
+//        "name": "10分钟内失败次数",
+//        "configuration": {
+//          "namespace": "loginFailures",
+//          "key": "userId",
+//          "window": "10m",
+//          "operation": "count",
+//          "threshold": 5,
+//          "outputKey": "failures"
+//        }
+//      }
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bittoy/rule/components/base"
+	"github.com/bittoy/rule/types"
+	"github.com/bittoy/rule/utils/cast"
+	"github.com/bittoy/rule/utils/maps"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+)
+
+func init() {
+	Registry.Add(&WindowAggNode{})
+}
+
+// Window aggregate operations.
+// 窗口聚合操作。
+const (
+	WindowAggCount = "count"
+	WindowAggSum   = "sum"
+	WindowAggAvg   = "avg"
+)
+
+// defaultWindowAggNamespace is the cache key prefix of the windows when no namespace is configured
+const defaultWindowAggNamespace = "windowAgg"
+
+// WindowAggNodeConfiguration WindowAggNode配置结构
+// WindowAggNodeConfiguration defines the configuration structure for the WindowAggNode component.
+type WindowAggNodeConfiguration struct {
+	// Namespace 窗口在缓存中的键前缀，默认为 windowAgg。命名空间和键相同的节点共享同一个窗口
+	// Namespace is the cache key prefix of the windows, defaults to windowAgg.
+	// Nodes with the same namespace and key share the same window
+	Namespace string `json:"namespace"`
+	// Key 返回窗口键的表达式，如 userId，每个键维护独立的窗口
+	// Key is the expression evaluating to the window key, e.g. userId, every key has its own window
+	Key string `json:"key"`
+	// Value 返回数值的表达式，sum 和 avg 必填，count 不使用
+	// Value is the expression evaluating to the numeric value, required by sum and avg, unused by count
+	Value string `json:"value"`
+	// Window 滑动窗口时长，如 5m
+	// Window is the sliding window duration, e.g. 5m
+	Window string `json:"window"`
+	// Operation 聚合操作：count、sum 或 avg，默认为 count
+	// Operation is the aggregate operation: count, sum or avg, defaults to count
+	Operation string `json:"operation"`
+	// Threshold 聚合值大于该值时路由到 threshold 关系，否则路由到 default
+	// Threshold routes to the threshold relation when the aggregate is above it, otherwise to default
+	Threshold float64 `json:"threshold"`
+	// OutputKey 保存聚合值的私有变量键，默认为 windowAgg
+	// OutputKey is the private variable key holding the aggregate, defaults to windowAgg
+	OutputKey string `json:"outputKey"`
+}
+
+// WindowEvent 窗口中记录的事件
+// WindowEvent is an event recorded in a window.
+type WindowEvent struct {
+	// Ts 消息时间戳（毫秒）
+	// Ts is the message timestamp in milliseconds
+	Ts int64 `json:"ts"`
+	// Value 事件的值，count 时为 1
+	// Value is the event value, 1 for count
+	Value float64 `json:"value"`
+}
+
+// WindowAggState 窗口聚合节点保存在缓存中的键的窗口，可以序列化为 JSON，因此可以保存在进程外的缓存中
+// WindowAggState is the window of a key stored by the windowAgg node in Config.Cache. It serializes to JSON,
+// so caches keeping their values out of process can store it.
+type WindowAggState struct {
+	// Events 窗口中按时间戳排序的事件
+	// Events are the events in the window ordered by timestamp
+	Events []WindowEvent `json:"events"`
+	// Sum 事件值的总和
+	// Sum is the sum of the event values
+	Sum float64 `json:"sum"`
+}
+
+// add drops the events not after since and records event, in timestamp order. It takes O(log n) plus the
+// number of dropped events, the events being mostly recorded in timestamp order.
+func (s *WindowAggState) add(event WindowEvent, since int64) {
+	s.evict(since)
+	i := sort.Search(len(s.Events), func(i int) bool { return s.Events[i].Ts > event.Ts })
+	if i == len(s.Events) {
+		s.Events = append(s.Events, event)
+	} else {
+		s.Events = slices.Insert(s.Events, i, event)
+	}
+	s.Sum += event.Value
+}
+
+// evict drops the events not after since
+func (s *WindowAggState) evict(since int64) {
+	i := sort.Search(len(s.Events), func(i int) bool { return s.Events[i].Ts > since })
+	if i == len(s.Events) {
+		// Restart the sum, so the rounding errors do not build up
+		// 重新开始求和，避免舍入误差累积
+		s.Events, s.Sum = s.Events[:0], 0
+		return
+	}
+	for _, item := range s.Events[:i] {
+		s.Sum -= item.Value
+	}
+	s.Events = s.Events[i:]
+}
+
+// peek returns the count and the sum of the window with event recorded, without modifying the window
+func (s *WindowAggState) peek(event WindowEvent, since int64) (int, float64) {
+	i := sort.Search(len(s.Events), func(i int) bool { return s.Events[i].Ts > since })
+	sum := s.Sum + event.Value
+	for _, item := range s.Events[:i] {
+		sum -= item.Value
+	}
+	return len(s.Events) - i + 1, sum
+}
+
+// toWindowAggState returns the window stored in the cache: the state stored by the node, or its JSON
+// form for the caches keeping their values serialized. A nil value is an empty window.
+func toWindowAggState(value any) (*WindowAggState, error) {
+	switch v := value.(type) {
+	case nil:
+		return &WindowAggState{}, nil
+	case *WindowAggState:
+		return v, nil
+	case []byte:
+		state := &WindowAggState{}
+		return state, json.Unmarshal(v, state)
+	case string:
+		state := &WindowAggState{}
+		return state, json.Unmarshal([]byte(v), state)
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		state := &WindowAggState{}
+		return state, json.Unmarshal(data, state)
+	}
+}
+
+// WindowAggNode 在滑动时间窗口内按键统计事件的组件
+// WindowAggNode aggregates the events of each key over a sliding time window kept in Config.Cache,
+// writes the aggregate to the private variables and routes to "threshold" when it is above
+// the threshold, so rules like "more than 5 failures in 10 minutes" run inside the chain.
+//
+// 窗口按消息时间戳滑动，当前消息计入窗口。试运行时当前消息参与计算但不写入缓存。
+// The window slides with the message timestamps and includes the current message.
+// In dry-run mode the current message is aggregated but not stored.
+//
+// 窗口通过缓存的 types.CacheUpdater 原子更新，命名空间和键相同的节点和进程之间也是如此；缓存未实现它时，
+// 并发更新同一个键可能丢失事件。窗口以 WindowAggState 保存。
+// The window is updated atomically through the types.CacheUpdater of the cache, also across the nodes and
+// the processes sharing the namespace and the key; with a cache not implementing it, concurrent updates of
+// the same key may lose events. The window is stored as a WindowAggState.
+type WindowAggNode struct {
+	// Config 节点配置
+	// Config holds the window aggregate node configuration
+	Config WindowAggNodeConfiguration
+
+	// config 规则引擎配置
+	// config is the rule engine configuration
+	config types.Config
+
+	// window 窗口时长
+	// window is the window duration
+	window time.Duration
+
+	// keyProgram 编译后的键表达式
+	// keyProgram is the compiled key expression
+	keyProgram *vm.Program
+
+	// valueProgram 编译后的数值表达式，count 时为 nil
+	// valueProgram is the compiled value expression, nil for count
+	valueProgram *vm.Program
+}
+
+// Type 返回组件类型
+// Type returns the component type identifier.
+func (x *WindowAggNode) Type() types.NodeType {
+	return types.RuleSubTypeWindowAgg
+}
+
+// Category 返回组件类别
+// Category returns the component category.
+func (x *WindowAggNode) Category() string {
+	return types.CategorySwitch
+}
+
+// Relations 返回组件可能路由到的关系
+// Relations returns the relation types the component can route a message to.
+func (x *WindowAggNode) Relations() []string {
+	return []string{types.DefaultRelationType, types.ThresholdRelationType}
+}
+
+// New 创建新实例
+// New creates a new instance.
+func (x *WindowAggNode) New() types.Node {
+	return &WindowAggNode{}
+}
+
+// Init 初始化组件，编译表达式并校验窗口配置
+// Init initializes the component, compiling the expressions and checking the window configuration.
+func (x *WindowAggNode) Init(config types.Config, configuration types.Configuration) error {
+	x.config = config
+	err := maps.Map2Struct(configuration, &x.Config)
+	if err != nil {
+		return err
+	}
+	if config.Cache == nil {
+		return types.ErrCacheNotInitialized
+	}
+	if x.Config.Namespace == "" {
+		x.Config.Namespace = defaultWindowAggNamespace
+	}
+	if x.Config.OutputKey == "" {
+		x.Config.OutputKey = defaultWindowAggNamespace
+	}
+	if x.Config.Operation == "" {
+		x.Config.Operation = WindowAggCount
+	}
+	x.window, err = time.ParseDuration(x.Config.Window)
+	if err != nil {
+		return fmt.Errorf("invalid window:%w", err)
+	}
+	if x.window <= 0 {
+		return errors.New("window must be positive")
+	}
+	keyScript := strings.TrimSpace(x.Config.Key)
+	if keyScript == "" {
+		return errors.New("key must not be empty")
+	}
+	if x.keyProgram, err = expr.Compile(keyScript, base.NodeUtils.ExprOptions(config)...); err != nil {
+		return err
+	}
+	switch x.Config.Operation {
+	case WindowAggCount:
+	case WindowAggSum, WindowAggAvg:
+		valueScript := strings.TrimSpace(x.Config.Value)
+		if valueScript == "" {
+			return fmt.Errorf("value must not be empty for operation %s", x.Config.Operation)
+		}
+		if x.valueProgram, err = expr.Compile(valueScript, base.NodeUtils.ExprOptions(config)...); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown operation: %s", x.Config.Operation)
+	}
+	return nil
+}
+
+// OnMsg 处理消息，将其计入窗口并写入聚合值
+// OnMsg adds the message to its window and writes the aggregate.
+func (x *WindowAggNode) OnMsg(ctx context.Context, msg types.RuleMsg) (string, error) {
+	out, err := vm.Run(x.keyProgram, base.NodeUtils.ExprProgramEnv(ctx, x.config, x.keyProgram, msg))
+	if err != nil {
+		return "", err
+	}
+	key, err := cast.ToStringE(out)
+	if err != nil {
+		return "", fmt.Errorf("key must be a string:%w", err)
+	}
+	event := WindowEvent{Ts: msg.Ts(), Value: 1}
+	if x.valueProgram != nil {
+		out, err = vm.Run(x.valueProgram, base.NodeUtils.ExprProgramEnv(ctx, x.config, x.valueProgram, msg))
+		if err != nil {
+			return "", err
+		}
+		if event.Value, err = cast.ToFloat64E(out); err != nil {
+			return "", fmt.Errorf("value must be a number:%w", err)
+		}
+	}
+
+	aggregate, err := x.add(key, event, types.IsDryRun(ctx))
+	if err != nil {
+		return "", err
+	}
+	msg.SetPrivateVar(x.Config.OutputKey, aggregate)
+	if aggregate > x.Config.Threshold {
+		return types.ThresholdRelationType, nil
+	}
+	return types.DefaultRelationType, nil
+}
+
+// add records the event in the window of key, drops the events outside the window and returns the aggregate.
+// In dry-run mode the window is not stored.
+func (x *WindowAggNode) add(key string, event WindowEvent, dryRun bool) (float64, error) {
+	cacheKey := x.Config.Namespace + ":" + key
+	since := event.Ts - x.window.Milliseconds()
+	var aggregate float64
+	var err error
+	update := func(value any) (any, bool) {
+		var state *WindowAggState
+		if state, err = toWindowAggState(value); err != nil {
+			return nil, false
+		}
+		if dryRun {
+			aggregate = x.aggregate(state.peek(event, since))
+			return nil, false
+		}
+		state.add(event, since)
+		aggregate = x.aggregate(len(state.Events), state.Sum)
+		return state, true
+	}
+	if updater, ok := x.config.Cache.(types.CacheUpdater); ok {
+		if updateErr := updater.Update(cacheKey, x.Config.Window, update); updateErr != nil {
+			return 0, updateErr
+		}
+		return aggregate, err
+	}
+	// The stored state is cloned, so the value of the cache is not modified in place
+	// 复制保存的状态，因此不会原地修改缓存中的值
+	stored := x.config.Cache.Get(cacheKey)
+	if state, ok := stored.(*WindowAggState); ok {
+		stored = &WindowAggState{Events: slices.Clone(state.Events), Sum: state.Sum}
+	}
+	if value, store := update(stored); store {
+		return aggregate, x.config.Cache.Set(cacheKey, value, x.Config.Window)
+	}
+	return aggregate, err
+}
+
+// aggregate computes the configured operation from the count and the sum of the window
+func (x *WindowAggNode) aggregate(count int, sum float64) float64 {
+	switch x.Config.Operation {
+	case WindowAggCount:
+		return float64(count)
+	case WindowAggAvg:
+		return sum / float64(count)
+	}
+	return sum
+}
+
+// Destroy 清理资源
+// Destroy cleans up resources.
+func (x *WindowAggNode) Destroy() {
+}
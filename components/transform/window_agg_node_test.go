@@ -0,0 +1,121 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transform
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bittoy/rule/types"
+	"github.com/bittoy/rule/utils/cache"
+	"github.com/rulego/rulego/test/assert"
+)
+
+// plainCache is a cache without types.CacheUpdater keeping its values as JSON, like an external cache.
+type plainCache struct {
+	types.Cache
+}
+
+func (c plainCache) Set(key string, value any, ttl string) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return c.Cache.Set(key, data, ttl)
+}
+
+func newWindowAggNode(t *testing.T, config types.Config, configuration types.Configuration) *WindowAggNode {
+	node := &WindowAggNode{}
+	assert.Nil(t, node.Init(config, configuration))
+	return node
+}
+
+// TestWindowAgg checks the aggregate operations, the threshold and the window sliding with the message timestamps.
+func TestWindowAgg(t *testing.T) {
+	for _, c := range []types.Cache{cache.NewMemoryCache(0), plainCache{cache.NewMemoryCache(0)}} {
+		config := types.NewConfig(types.WithCache(c))
+		count := newWindowAggNode(t, config, types.Configuration{"namespace": "n", "key": "user", "window": "1m", "threshold": 2, "outputKey": "n"})
+		avg := newWindowAggNode(t, config, types.Configuration{"namespace": "a", "key": "user", "value": "amount", "window": "1m", "operation": WindowAggAvg})
+		run := func(node *WindowAggNode, ts int64, amount int) (string, any) {
+			msg := types.NewRuleMsg("", ts, map[string]any{"user": "u1", "amount": amount})
+			relation, err := node.OnMsg(context.Background(), msg)
+			assert.Nil(t, err)
+			return relation, msg.GetPrivateVars()[node.Config.OutputKey]
+		}
+		start := time.Now().UnixMilli()
+		relation, n := run(count, start, 0)
+		assert.Equal(t, types.DefaultRelationType, relation)
+		assert.Equal(t, 1.0, n)
+		run(count, start+1000, 0)
+		relation, n = run(count, start+2000, 0)
+		assert.Equal(t, types.ThresholdRelationType, relation)
+		assert.Equal(t, 3.0, n)
+		// A late message is kept in timestamp order
+		_, n = run(count, start+500, 0)
+		assert.Equal(t, 4.0, n)
+		// The first events leave the window
+		_, n = run(count, start+61000, 0)
+		assert.Equal(t, 2.0, n)
+
+		_, value := run(avg, start, 10)
+		assert.Equal(t, 10.0, value)
+		_, value = run(avg, start+1000, 20)
+		assert.Equal(t, 15.0, value)
+		_, value = run(avg, start+60500, 60)
+		assert.Equal(t, 40.0, value)
+	}
+
+	assert.NotNil(t, (&WindowAggNode{}).Init(types.NewConfig(types.WithCache(cache.NewMemoryCache(0))), types.Configuration{"key": "user", "window": "1m", "operation": WindowAggSum}))
+}
+
+// TestWindowAggDryRun checks that a dry run aggregates the current message without storing it.
+func TestWindowAggDryRun(t *testing.T) {
+	config := types.NewConfig(types.WithCache(cache.NewMemoryCache(0)))
+	node := newWindowAggNode(t, config, types.Configuration{"key": "user", "value": "amount", "window": "1m", "operation": WindowAggSum})
+	ts := time.Now().UnixMilli()
+	msg := types.NewRuleMsg("", ts, map[string]any{"user": "u1", "amount": 5})
+	_, err := node.OnMsg(context.Background(), msg)
+	assert.Nil(t, err)
+	msg = types.NewRuleMsg("", ts, map[string]any{"user": "u1", "amount": 7})
+	_, err = node.OnMsg(types.ContextWithDryRun(context.Background()), msg)
+	assert.Nil(t, err)
+	assert.Equal(t, 12.0, msg.GetPrivateVars()[defaultWindowAggNamespace])
+	state := config.Cache.Get(defaultWindowAggNamespace + ":u1").(*WindowAggState)
+	assert.Equal(t, []WindowEvent{{Ts: ts, Value: 5}}, state.Events)
+}
+
+// TestWindowAggConcurrent checks that concurrent updates of one key through nodes sharing the namespace lose no event.
+func TestWindowAggConcurrent(t *testing.T) {
+	config := types.NewConfig(types.WithCache(cache.NewMemoryCache(0)))
+	configuration := types.Configuration{"namespace": "shared", "key": "user", "window": "1h"}
+	nodes := []*WindowAggNode{newWindowAggNode(t, config, configuration), newWindowAggNode(t, config, configuration)}
+	ts := time.Now().UnixMilli()
+	var wg sync.WaitGroup
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+		go func(node *WindowAggNode) {
+			defer wg.Done()
+			_, err := node.OnMsg(context.Background(), types.NewRuleMsg("", ts, map[string]any{"user": "u1"}))
+			assert.Nil(t, err)
+		}(nodes[i%2])
+	}
+	wg.Wait()
+	assert.Equal(t, 200, len(config.Cache.Get("shared:u1").(*WindowAggState).Events))
+}
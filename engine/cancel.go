@@ -0,0 +1,113 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/bittoy/rule/types"
+)
+
+var (
+	// Compile-time check ChainEngine implements types.MsgCanceller.
+	_ types.MsgCanceller = (*ChainEngine)(nil)
+	// Compile-time check ChainAggregationEngine implements types.MsgCanceller.
+	_ types.MsgCanceller = (*ChainAggregationEngine)(nil)
+)
+
+// msgCancels holds the cancel functions of the in-flight messages of an engine by message id, see types.MsgCanceller.
+// The zero value is ready to use.
+// msgCancels 按消息 id 保存引擎正在处理的消息的取消函数，参见 types.MsgCanceller。零值即可使用。
+type msgCancels struct {
+	mu sync.Mutex
+	// cancels are the cancel functions by message id, messages submitted concurrently may share an id
+	cancels map[string][]*context.CancelCauseFunc
+	// closed is the cause the messages registered are cancelled with, set by close until open
+	closed error
+}
+
+// register returns a cancelable context of ctx for the message and the function releasing it once the message completes
+func (c *msgCancels) register(ctx context.Context, msgId string) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	entry := &cancel
+	c.mu.Lock()
+	if c.cancels == nil {
+		c.cancels = map[string][]*context.CancelCauseFunc{}
+	}
+	c.cancels[msgId] = append(c.cancels[msgId], entry)
+	if c.closed != nil {
+		cancel(c.closed)
+	}
+	c.mu.Unlock()
+	return ctx, func() {
+		c.mu.Lock()
+		entries := c.cancels[msgId]
+		for i, e := range entries {
+			if e == entry {
+				entries = append(entries[:i], entries[i+1:]...)
+				break
+			}
+		}
+		if len(entries) == 0 {
+			delete(c.cancels, msgId)
+		} else {
+			c.cancels[msgId] = entries
+		}
+		c.mu.Unlock()
+		cancel(nil)
+	}
+}
+
+// cancel cancels the in-flight messages with the id, it reports whether one was running
+func (c *msgCancels) cancel(msgId string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, cancel := range c.cancels[msgId] {
+		(*cancel)(types.ErrMsgCancelled)
+	}
+	return len(c.cancels[msgId]) > 0
+}
+
+// close cancels all the in-flight messages with cause, and the messages registered afterwards until open is called
+func (c *msgCancels) close(cause error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = cause
+	for _, entries := range c.cancels {
+		for _, cancel := range entries {
+			(*cancel)(cause)
+		}
+	}
+}
+
+// open lets the messages registered afterwards run again after close
+func (c *msgCancels) open() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = nil
+}
+
+// cancelledErr wraps err with types.ErrMsgCancelled when the message was cancelled
+func cancelledErr(ctx context.Context, err error) error {
+	if err != nil && !errors.Is(err, types.ErrMsgCancelled) && errors.Is(context.Cause(ctx), types.ErrMsgCancelled) {
+		return fmt.Errorf("%w: %w", types.ErrMsgCancelled, err)
+	}
+	return err
+}
@@ -17,13 +17,27 @@
 package engine
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
 
+	"github.com/bittoy/rule/components/base"
 	"github.com/bittoy/rule/types"
+	utilsmaps "github.com/bittoy/rule/utils/maps"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
 )
 
+// errHandedOff is returned instead of the error of the chain when a node handed the message off, see ChainCtx.run
+var errHandedOff = errors.New("message handed off")
+
 type ChainCtx struct {
 	// SelfDefinition contains the complete rule chain definition including
 	// metadata, nodes, connections, and configuration
@@ -45,9 +59,14 @@ type ChainCtx struct {
 	// nodeRoutes 将每个节点映射到其传出关系，定义消息通过规则链的流动
 	nodeRoutes map[string][]types.RuleNodeRelation
 
-	// rootRuleContext is the root context for message processing within this rule chain,
-	// providing the entry point for message flow and execution coordination
-	// rootRuleContext 是此规则链内消息处理的根上下文，为消息流和执行协调提供入口点
+	// conditions maps connection guard expressions to their compiled programs,
+	// compiled once when the chain is initialized
+	// conditions 将连接守卫表达式映射到编译后的程序，在规则链初始化时编译一次
+	conditions map[string]*vm.Program
+
+	// rootNodeId is the id of the entry node of this rule chain, the start node unless
+	// overridden by RuleMetadata.RootNodeId or SetRootNode
+	// rootNodeId 是此规则链入口节点的 id，默认为开始节点，可由 RuleMetadata.RootNodeId 或 SetRootNode 覆盖
 	rootNodeId string
 
 	// aspects contains the list of AOP aspects applied to this rule chain,
@@ -59,14 +78,24 @@ type ChainCtx struct {
 	afterAspects  []types.NodeAfterAspect
 
 	configuration types.Configuration
+
+	// refs counts the messages running through the chain, the engine destroys a replaced chain once they complete
+	// refs 统计正在通过规则链的消息，引擎在这些消息完成后销毁被替换的规则链
+	refs ctxRefs
 }
 
-func InitChainCtx(config types.Config, aspects types.AspectList, chainDef *types.Chain) (*ChainCtx, error) {
+func InitChainCtx(config types.Config, aspects types.AspectList, chainDef *types.Chain) (_ *ChainCtx, err error) {
+	if config, err = withTypeAliases(config); err != nil {
+		return nil, err
+	}
 	// Initialize a new RuleChainCtx with the provided configuration and aspects
 	// Retrieve aspects for the engine
 	onChainBeforeInitAspects := aspects.GetOnChainBeforeInitAspects()
 	for _, aspect := range onChainBeforeInitAspects {
-		if err := aspect.OnChainBeforeInit(config, chainDef); err != nil {
+		start := aspectStart(config)
+		err := aspect.OnChainBeforeInit(config, chainDef)
+		observeAspect(aspect, aspectPointBeforeInit, start)
+		if err != nil {
 			return nil, err
 		}
 	}
@@ -79,9 +108,30 @@ func InitChainCtx(config types.Config, aspects types.AspectList, chainDef *types
 	}
 
 	chainCtx.beforeAspects, chainCtx.afterAspects = aspects.GetNodeAspects()
+	// Destroy the nodes already initialized when a later step fails, so a failed load leaves nothing behind
+	// 后续步骤失败时销毁已初始化的节点，使加载失败不留下任何资源
+	defer func() {
+		if err != nil {
+			chainCtx.Destroy()
+		}
+	}()
+
+	if err := chainDef.Metadata.ValidateRelationAliases(); err != nil {
+		return nil, fmt.Errorf("chain %s: %w", chainDef.Id, err)
+	}
+	if err := chainDef.ValidatePrivateParameters(); err != nil {
+		return nil, fmt.Errorf("chain %s: %w", chainDef.Id, err)
+	}
 
 	// Load all node information
 	for _, item := range chainDef.Metadata.Nodes {
+		// Chain level configuration provides defaults for every node, node configuration wins on conflict
+		// 规则链级别配置为每个节点提供默认值，冲突时以节点配置为准
+		if len(chainDef.Configuration) > 0 {
+			nodeDef := *item
+			nodeDef.Configuration = item.Configuration.MergeDefaults(chainDef.Configuration)
+			item = &nodeDef
+		}
 		ruleNodeCtx, err := InitRuleNodeCtx(config, chainCtx, aspects, item)
 		if err != nil {
 			return nil, err
@@ -94,13 +144,24 @@ func InitChainCtx(config types.Config, aspects types.AspectList, chainDef *types
 	}
 
 	// Load node relationship information
-	for _, item := range chainDef.Metadata.Connections {
+	for _, item := range chainDef.Metadata.EnabledConnections() {
 		inNodeId := item.FromId
 		outNodeId := item.ToId
+		// The chain ends at an end node, so an outgoing connection would be silently ignored, checked here
+		// and not only by the validator aspect
+		// 规则链在结束节点结束，其传出连接会被静默忽略，因此在此检查，而不仅由校验切面检查
+		if from, ok := chainCtx.nodes[inNodeId]; ok && from.Type() == types.RuleSubTypeEnd {
+			return nil, fmt.Errorf("chain %s: %w: %s->%s type:%s", chainDef.Id, types.ErrEndNodeConnection, inNodeId, outNodeId, item.Type)
+		}
 		ruleNodeRelation := types.RuleNodeRelation{
 			InId:         inNodeId,
 			OutId:        outNodeId,
-			RelationType: item.Type,
+			RelationType: chainDef.Metadata.Relation(item.Type),
+			Condition:    strings.TrimSpace(item.Condition),
+			Priority:     item.Priority,
+		}
+		if err := chainCtx.compileCondition(ruleNodeRelation.Condition); err != nil {
+			return nil, fmt.Errorf("connection %s->%s condition error:%w", inNodeId, outNodeId, err)
 		}
 		nodeRelations, ok := chainCtx.nodeRoutes[inNodeId]
 
@@ -111,10 +172,56 @@ func InitChainCtx(config types.Config, aspects types.AspectList, chainDef *types
 		}
 		chainCtx.nodeRoutes[inNodeId] = nodeRelations
 	}
+	// A weightedRoute node drops the messages routed to a relation without a connection, checked here
+	// and not only by the validator aspect
+	// weightedRoute 节点路由到没有连接的关系时消息会被丢弃，因此在此检查，而不仅由校验切面检查
+	for _, item := range chainDef.Metadata.Nodes {
+		if node, ok := chainCtx.nodes[item.Id]; !ok || node.Type() != types.RuleSubTypeWeightedRoute {
+			continue
+		}
+		var routes struct {
+			Routes []types.WeightedRoute
+		}
+		_ = utilsmaps.Map2Struct(item.Configuration.MergeDefaults(chainDef.Configuration), &routes)
+		for _, route := range routes.Routes {
+			relationType := chainDef.Metadata.Relation(strings.TrimSpace(route.Relation))
+			if !slices.ContainsFunc(chainCtx.nodeRoutes[item.Id], func(relation types.RuleNodeRelation) bool {
+				return relation.RelationType == relationType
+			}) {
+				return nil, fmt.Errorf("chain %s: %w: node %s relation %s", chainDef.Id, types.ErrRouteConnection, item.Id, relationType)
+			}
+		}
+	}
+	// getNextNode follows the first matching relation, so order them by priority, keeping the declaration order of ties
+	for _, nodeRelations := range chainCtx.nodeRoutes {
+		slices.SortStableFunc(nodeRelations, func(a, b types.RuleNodeRelation) int {
+			return cmp.Compare(b.Priority, a.Priority)
+		})
+	}
+
+	if chainDef.Metadata.RootNodeId != "" {
+		if err := chainCtx.SetRootNode(chainDef.Metadata.RootNodeId); err != nil {
+			return nil, err
+		}
+	}
 
 	return chainCtx, nil
 }
 
+// newDisabledChainCtx creates an inert rule chain context for a disabled chain definition.
+// No node is initialized, OnMsg returns types.ErrEngineDisabled.
+// newDisabledChainCtx 为已禁用的规则链定义创建不可执行的上下文。
+// 不会初始化任何节点，OnMsg 返回 types.ErrEngineDisabled。
+func newDisabledChainCtx(config types.Config, aspects types.AspectList, chainDef *types.Chain) *ChainCtx {
+	return &ChainCtx{
+		config:         config,
+		selfDefinition: chainDef,
+		nodes:          make(map[string]types.NodeCtx),
+		nodeRoutes:     make(map[string][]types.RuleNodeRelation),
+		aspects:        aspects,
+	}
+}
+
 // Config returns the configuration of the rule chain context
 func (rc *ChainCtx) Config() types.Config {
 	return rc.config
@@ -135,6 +242,11 @@ func (rc *ChainCtx) TerminalOnErr() bool {
 	return rc.selfDefinition.TerminalOnErr
 }
 
+// Disabled returns whether the rule chain is disabled
+func (rc *ChainCtx) Disabled() bool {
+	return rc.selfDefinition.Disabled
+}
+
 // GetNodeById retrieves a node context by its ID
 func (rc *ChainCtx) GetNodeById(id string) (types.NodeCtx, bool) {
 	ruleNodeCtx, ok := rc.nodes[id]
@@ -147,21 +259,57 @@ func (rc *ChainCtx) GetNodeRoutes(id string) ([]types.RuleNodeRelation, bool) {
 	return relations, ok
 }
 
-func (rc *ChainCtx) getNextNode(id string, relationType string) (types.NodeCtx, bool) {
+// compileCondition compiles a connection guard expression once, empty conditions are ignored
+func (rc *ChainCtx) compileCondition(condition string) error {
+	if condition == "" {
+		return nil
+	}
+	if rc.conditions == nil {
+		rc.conditions = make(map[string]*vm.Program)
+	}
+	if _, ok := rc.conditions[condition]; ok {
+		return nil
+	}
+	program, err := expr.Compile(condition, base.NodeUtils.ExprOptions(rc.config, expr.AsBool())...)
+	if err != nil {
+		return err
+	}
+	rc.conditions[condition] = program
+	return nil
+}
+
+// getNextNode returns the target of the first connection matching the relation type
+// whose guard condition is empty or evaluates to true, relation aliases are resolved first
+func (rc *ChainCtx) getNextNode(ctx context.Context, id string, relationType string, msg types.RuleMsg) (types.NodeCtx, bool, error) {
+	relationType = rc.selfDefinition.Metadata.Relation(relationType)
 	relations, ok := rc.GetNodeRoutes(id)
 	if ok {
+		var env map[string]any
 		for _, item := range relations {
-			if item.RelationType == relationType {
-				if nodeCtx, nodeCtxOk := rc.GetNodeById(item.OutId); nodeCtxOk {
-					return nodeCtx, true
+			if item.RelationType != relationType {
+				continue
+			}
+			if item.Condition != "" {
+				if env == nil {
+					env = base.NodeUtils.ExprEnv(ctx, rc.config, msg)
+				}
+				out, err := vm.Run(rc.conditions[item.Condition], env)
+				if err != nil {
+					return nil, true, fmt.Errorf("connection %s->%s condition error:%w", item.InId, item.OutId, err)
+				}
+				if pass, _ := out.(bool); !pass {
+					continue
 				}
 			}
+			if nodeCtx, nodeCtxOk := rc.GetNodeById(item.OutId); nodeCtxOk {
+				return nodeCtx, true, nil
+			}
 		}
 		// 父节点存在但是子分支不存在
-		return nil, true
+		return nil, true, nil
 	}
 	// 父节点不存在子分支也不存在
-	return nil, false
+	return nil, false, nil
 }
 
 // Type returns the component type
@@ -179,9 +327,39 @@ func (rc *ChainCtx) Init(_ types.Config, configuration types.Configuration) erro
 	return fmt.Errorf("RuleChainCtx cant not init")
 }
 
-// OnMsg processes incoming messages
+// OnMsg processes incoming messages, it returns once the chain has completed
 func (rc *ChainCtx) OnMsg(ctx context.Context, msg types.RuleMsg) (string, error) {
-	return "", rc.execute(ctx, msg)
+	return "", rc.run(ctx, msg, nil)
+}
+
+// run runs the message through the chain. With a nil done it returns once the chain has completed, otherwise
+// when a node hands the message off, see types.AsyncNode, it returns errHandedOff and passes the error of
+// the chain to done once it has completed.
+func (rc *ChainCtx) run(ctx context.Context, msg types.RuleMsg, done func(error)) error {
+	if rc.Disabled() {
+		return types.ErrEngineDisabled
+	}
+	msg.ResetResult()
+	rc.initPrivateVars(msg)
+	var err error
+	withChainLabel(ctx, rc.Id(), func(ctx context.Context) {
+		err = rc.execute(ctx, msg, done)
+	})
+	return err
+}
+
+// initPrivateVars sets the private variables declared by the chain that the message does not have yet
+// initPrivateVars 设置规则链声明的、消息尚未拥有的私有变量
+func (rc *ChainCtx) initPrivateVars(msg types.RuleMsg) {
+	if len(rc.selfDefinition.PrivateParameter) == 0 {
+		return
+	}
+	priVars := msg.GetPrivateVars()
+	for _, param := range rc.selfDefinition.PrivateParameter {
+		if _, ok := priVars[param.Key]; !ok {
+			priVars[param.Key] = param.DefaultValue()
+		}
+	}
 }
 
 // Destroy cleans up resources and executes destroy aspects
@@ -199,49 +377,266 @@ func (rc *ChainCtx) DSL() []byte {
 	return v
 }
 
-func (rc *ChainCtx) execute(ctx context.Context, msg types.RuleMsg) error {
-	currentNode, found := rc.GetNodeById(rc.rootNodeId)
+// RootNodeId returns the id of the entry node of the chain.
+// RootNodeId 返回规则链入口节点的 id。
+func (rc *ChainCtx) RootNodeId() string {
+	return rc.rootNodeId
+}
+
+// SetRootNode makes the node id the entry node of the chain, so messages enter the chain
+// at that node instead of the start node. It returns types.ErrRootNodeNotFound if the
+// node does not exist. It must not be called while the chain is processing messages.
+//
+// SetRootNode 将节点 id 设置为规则链的入口节点，使消息从该节点而不是开始节点进入规则链。
+// 节点不存在时返回 types.ErrRootNodeNotFound。不能在规则链处理消息时调用。
+func (rc *ChainCtx) SetRootNode(id string) error {
+	if _, ok := rc.nodes[id]; !ok {
+		return fmt.Errorf("chain %s: %w: %s", rc.Id(), types.ErrRootNodeNotFound, id)
+	}
+	rc.rootNodeId = id
+	return nil
+}
+
+func (rc *ChainCtx) execute(ctx context.Context, msg types.RuleMsg, done func(error)) error {
+	rootNode, found := rc.GetNodeById(rc.rootNodeId)
 	if !found {
-		return errors.New("not found rootNode")
+		return fmt.Errorf("chain %s: %w: %q", rc.Id(), types.ErrRootNodeNotFound, rc.rootNodeId)
 	}
+	return rc.executeFrom(ctx, rootNode, msg, new(atomic.Int32), done)
+}
+
+// executeFrom runs the chain from currentNode. steps counts the nodes visited by the message, it is shared by
+// the branches of a split so they count against one limit.
+// With a non nil done, an async node may hand the message off, see run.
+func (rc *ChainCtx) executeFrom(ctx context.Context, currentNode types.NodeCtx, msg types.RuleMsg, steps *atomic.Int32, done func(error)) error {
+	maxSteps := rc.config.GetMaxSteps()
+	tracing := types.IsTracing(ctx)
 	for currentNode != nil {
+		if int(steps.Add(1)) > maxSteps {
+			return fmt.Errorf("%w: chain:%s node:%s steps:%d", types.ErrMaxChainDepthExceeded, rc.Id(), currentNode.Id(), maxSteps)
+		}
+		if errors.Is(context.Cause(ctx), types.ErrMsgCancelled) {
+			return fmt.Errorf("%w: chain:%s node:%s", types.ErrMsgCancelled, rc.Id(), currentNode.Id())
+		}
 		fmt.Printf("执行节点: %s (%s)\n", currentNode.Id(), currentNode.Type())
 
 		_, err := rc.onBefore(currentNode, msg, "")
 		if err != nil {
 			return err
 		}
-		relationType, err := currentNode.OnMsg(ctx, msg)
-		if err != nil {
-			return err
+		var relationType string
+		var outMsgs []types.RuleMsg
+		var trace *types.NodeTrace
+		var traceStart time.Time
+		if tracing {
+			trace, traceStart = rc.startTrace(currentNode, msg), time.Now()
 		}
-		_, err = rc.onAfter(currentNode, msg, relationType)
-		if err != nil {
+		msg.SetCurrentNode(currentNode.Id())
+		multiOutputNode, isMultiOutput := asMultiOutputNode(currentNode)
+		if asyncNode, isAsync := asAsyncNode(currentNode); isAsync {
+			var handedOff bool
+			node := currentNode
+			relationType, handedOff, err = rc.onMsgAsync(ctx, asyncNode, msg, done != nil, func(relationType string, err error) {
+				// The message resumes in the goroutine of the node, the chain continues from there
+				// 消息在节点的 goroutine 中恢复，规则链从此处继续执行
+				nextNode, err := rc.afterNode(ctx, node, msg, trace, traceStart, relationType, nil, false, steps, err)
+				if err == nil && nextNode != nil {
+					if err = rc.executeFrom(ctx, nextNode, msg, steps, done); err == errHandedOff {
+						return
+					}
+				}
+				done(err)
+			})
+			if handedOff {
+				return errHandedOff
+			}
+		} else if isMultiOutput {
+			relationType, outMsgs, err = multiOutputNode.OnMsgs(ctx, msg)
+		} else {
+			relationType, err = currentNode.OnMsg(ctx, msg)
+		}
+		if currentNode, err = rc.afterNode(ctx, currentNode, msg, trace, traceStart, relationType, outMsgs, isMultiOutput, steps, err); err != nil {
 			return err
 		}
+	}
+	return nil
+}
+
+// afterNode completes the execution of currentNode with its result and returns the node to run next,
+// nil when the chain ends. The remainder of the chain after a multi output node runs there, see fanOut.
+func (rc *ChainCtx) afterNode(ctx context.Context, currentNode types.NodeCtx, msg types.RuleMsg, trace *types.NodeTrace, traceStart time.Time,
+	relationType string, outMsgs []types.RuleMsg, isMultiOutput bool, steps *atomic.Int32, err error) (types.NodeCtx, error) {
+	msg.SetCurrentNode("")
+	if trace != nil {
+		trace.Elapsed = time.Since(traceStart)
+		rc.endTrace(trace, msg, relationType, err)
+	}
+	if err != nil {
+		nodeCtx, ok, failureErr := rc.failureNode(ctx, currentNode, msg, err)
+		if !ok {
+			return nil, err
+		}
+		return nodeCtx, failureErr
+	}
+	if _, err = rc.onAfter(currentNode, msg, relationType); err != nil {
+		return nil, err
+	}
+
+	if len(relationType) == 0 {
+		return nil, nil
+	}
+	if relationType == types.SkipRelationType && !rc.hasRelation(currentNode.Id(), relationType) {
+		// A skipped message without skip connection ends the chain without running the downstream nodes
+		// 没有 skip 连接时，被跳过的消息直接结束规则链，不执行下游节点
+		return nil, nil
+	}
+	if isMultiOutput {
+		return nil, rc.fanOut(ctx, currentNode, relationType, msg, outMsgs, steps)
+	}
+	return rc.nextNode(ctx, currentNode, relationType, msg)
+}
+
+// onMsgAsync runs an async node. When the chain cannot resume later, resumable is false, it waits for the node
+// to resume instead of handing the message off.
+func (rc *ChainCtx) onMsgAsync(ctx context.Context, node types.AsyncNode, msg types.RuleMsg, resumable bool, resume func(string, error)) (string, bool, error) {
+	if resumable {
+		return node.OnMsgAsync(ctx, msg, resume)
+	}
+	resumed := make(chan struct{})
+	var resumedRelation string
+	var resumedErr error
+	relationType, handedOff, err := node.OnMsgAsync(ctx, msg, func(relationType string, err error) {
+		resumedRelation, resumedErr = relationType, err
+		close(resumed)
+	})
+	if !handedOff {
+		return relationType, false, err
+	}
+	<-resumed
+	return resumedRelation, false, resumedErr
+}
 
-		if len(relationType) == 0 {
-			break
+// fanOut runs the remainder of the chain once per message emitted by a multi output node, in order,
+// and collects the branch chain outputs into the chain output of msg under types.SplitResultsKey.
+// A halted branch skips the remaining branches, its chain output and tags become those of msg
+func (rc *ChainCtx) fanOut(ctx context.Context, currentNode types.NodeCtx, relationType string, msg types.RuleMsg, outMsgs []types.RuleMsg, steps *atomic.Int32) error {
+	results := make([]map[string]any, 0, len(outMsgs))
+	for _, outMsg := range outMsgs {
+		nodeCtx, err := rc.nextNode(ctx, currentNode, relationType, outMsg)
+		if err != nil {
+			return err
 		}
-		nodeCtx, found := rc.getNextNode(currentNode.Id(), relationType)
-		if !found {
-			return fmt.Errorf("node for id:%s not found", currentNode.Id())
+		err = rc.executeFrom(ctx, nodeCtx, outMsg, steps, nil)
+		msg.AddTrace(outMsg.Traces()...)
+		if err != nil {
+			return err
 		}
-		if nodeCtx == nil {
-			return fmt.Errorf("node for id:%s branch: %s node not found", currentNode.Id(), relationType)
+		if outMsg.Halted() {
+			msg.SetChainOutput(outMsg.GetChainOutput())
+			msg.AddTag(outMsg.Tags()...)
+			msg.Halt()
+			return nil
 		}
-		currentNode = nodeCtx
+		results = append(results, outMsg.GetChainOutput())
 	}
+	msg.SetChainOutput(map[string]any{types.SplitResultsKey: results})
 	return nil
 }
 
+// startTrace starts the trace of the node execution with a snapshot of the message input
+func (rc *ChainCtx) startTrace(nodeCtx types.NodeCtx, msg types.RuleMsg) *types.NodeTrace {
+	return &types.NodeTrace{
+		ChainId:  rc.Id(),
+		NodeId:   nodeCtx.Id(),
+		NodeType: nodeCtx.Type(),
+		Input:    rc.config.Redact(msg.Snapshot()),
+	}
+}
+
+// endTrace completes the trace with the node result and appends it to the message
+func (rc *ChainCtx) endTrace(trace *types.NodeTrace, msg types.RuleMsg, relationType string, err error) {
+	priVars := rc.config.Redact(map[string]any{types.PriVarsKey: maps.Clone(msg.GetPrivateVars())})
+	trace.PriVars, _ = priVars[types.PriVarsKey].(map[string]any)
+	trace.ChainOutput = maps.Clone(rc.config.Redact(msg.GetChainOutput()))
+	trace.Relation = relationType
+	if err != nil {
+		trace.Err = err.Error()
+	}
+	msg.AddTrace(*trace)
+}
+
+// nextNode returns the node following currentNode for the relation type
+func (rc *ChainCtx) nextNode(ctx context.Context, currentNode types.NodeCtx, relationType string, msg types.RuleMsg) (types.NodeCtx, error) {
+	nodeCtx, found, err := rc.getNextNode(ctx, currentNode.Id(), relationType, msg)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("node for id:%s not found", currentNode.Id())
+	}
+	if nodeCtx == nil {
+		return nil, fmt.Errorf("node for id:%s branch: %s node not found", currentNode.Id(), relationType)
+	}
+	return nodeCtx, nil
+}
+
+// hasRelation reports whether the node has a connection of the relation type
+func (rc *ChainCtx) hasRelation(id string, relationType string) bool {
+	relationType = rc.selfDefinition.Metadata.Relation(relationType)
+	relations, _ := rc.GetNodeRoutes(id)
+	for _, item := range relations {
+		if item.RelationType == relationType {
+			return true
+		}
+	}
+	return false
+}
+
+// failureNode returns the failure connection target of a failing node when the chain continues on errors,
+// the error message is recorded in the private variables under types.ErrorKey.
+// ok is false when the error must abort the chain: continueOnErr is off, the node sets
+// terminalOnErr, or the node has no failure connection.
+func (rc *ChainCtx) failureNode(ctx context.Context, currentNode types.NodeCtx, msg types.RuleMsg, err error) (nodeCtx types.NodeCtx, ok bool, failureErr error) {
+	if !rc.selfDefinition.ContinueOnErr || currentNode.TerminalOnErr() {
+		return nil, false, nil
+	}
+	msg.SetPrivateVar(types.ErrorKey, err.Error())
+	nodeCtx, _, failureErr = rc.getNextNode(ctx, currentNode.Id(), types.FailureRelationType, msg)
+	if failureErr != nil {
+		return nil, true, failureErr
+	}
+	return nodeCtx, nodeCtx != nil, nil
+}
+
+// asAsyncNode returns the node implementation if it may hand the message off
+func asAsyncNode(nodeCtx types.NodeCtx) (types.AsyncNode, bool) {
+	if ruleNodeCtx, ok := nodeCtx.(*RuleNodeCtx); ok {
+		asyncNode, ok := ruleNodeCtx.Node.(types.AsyncNode)
+		return asyncNode, ok
+	}
+	asyncNode, ok := nodeCtx.(types.AsyncNode)
+	return asyncNode, ok
+}
+
+// asMultiOutputNode returns the node implementation if it emits multiple messages
+func asMultiOutputNode(nodeCtx types.NodeCtx) (types.MultiOutputNode, bool) {
+	if ruleNodeCtx, ok := nodeCtx.(*RuleNodeCtx); ok {
+		multiOutputNode, ok := ruleNodeCtx.Node.(types.MultiOutputNode)
+		return multiOutputNode, ok
+	}
+	multiOutputNode, ok := nodeCtx.(types.MultiOutputNode)
+	return multiOutputNode, ok
+}
+
 // 执行After aop
 func (rc *ChainCtx) onBefore(nodeCtx types.NodeCtx, msg types.RuleMsg, relationType string) (types.RuleMsg, error) {
 	// after aop
 	var err error
 	for _, aop := range rc.beforeAspects {
 		if aop.PointCut(nodeCtx, msg, relationType) {
+			start := aspectStart(rc.config)
 			msg, err = aop.Before(nodeCtx, msg, relationType)
+			observeAspect(aop, aspectPointBefore, start)
 			if err != nil {
 				return msg, err
 			}
@@ -256,7 +651,9 @@ func (rc *ChainCtx) onAfter(nodeCtx types.NodeCtx, msg types.RuleMsg, relationTy
 	var err error
 	for _, aop := range rc.afterAspects {
 		if aop.PointCut(nodeCtx, msg, relationType) {
+			start := aspectStart(rc.config)
 			msg, err = aop.After(nodeCtx, msg, relationType)
+			observeAspect(aop, aspectPointAfter, start)
 			if err != nil {
 				return msg, err
 			}
@@ -3,10 +3,17 @@ package engine
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"sort"
+	"strings"
 
+	"github.com/bittoy/rule/components/base"
 	"github.com/bittoy/rule/types"
+	"github.com/bittoy/rule/utils/cast"
 	"github.com/bittoy/rule/utils/maps"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
 )
 
 type ChainAggregationCtx struct {
@@ -35,22 +42,42 @@ type ChainAggregationCtx struct {
 	// aspects 包含应用于此规则链的 AOP 切面列表，提供如日志、验证和指标等横切关注点
 	aspects types.AspectList
 
-	beforeAspects []types.ChainBeforeAspect
-	afterAspects  []types.ChainAfterAspect
+	beforeAspects    []types.ChainBeforeAspect
+	afterAspects     []types.ChainAfterAspect
+	completedAspects []types.CompletedAspect
 
 	chainAggregationNode types.Node
 
 	Aggregation types.ChainCtx
 
 	chainAggregationConfiguration types.ChainAggregationConfiguration
+
+	// outputKeys maps each chain id to the key of its output in the aggregation output
+	// outputKeys 将每个规则链 id 映射到其输出在聚合输出中的键
+	outputKeys map[string]string
+
+	// scoreProgram and actionProgram are the compiled Aggregation.ScoreExpr and Aggregation.ActionExpr, nil when not set
+	// scoreProgram 和 actionProgram 是编译后的 Aggregation.ScoreExpr 和 Aggregation.ActionExpr，未设置时为 nil
+	scoreProgram  *vm.Program
+	actionProgram *vm.Program
+
+	// refs counts the messages running through the aggregation, the engine destroys a replaced aggregation once they complete
+	// refs 统计正在通过聚合的消息，引擎在这些消息完成后销毁被替换的聚合
+	refs ctxRefs
 }
 
-func InitChainAggregationCtx(config types.Config, aspects types.AspectList, chainAggregationDef *types.ChainAggregation) (*ChainAggregationCtx, error) {
+func InitChainAggregationCtx(config types.Config, aspects types.AspectList, chainAggregationDef *types.ChainAggregation) (_ *ChainAggregationCtx, err error) {
+	if config, err = withTypeAliases(config); err != nil {
+		return nil, err
+	}
 	// Initialize a new RuleChainCtx with the provided configuration and aspects
 	// Retrieve aspects for the engine
 	onChainAggregationBeforeInitAspects := aspects.GetOnChainAggregationBeforeInitAspects()
 	for _, aspect := range onChainAggregationBeforeInitAspects {
-		if err := aspect.OnChainAggregationBeforeInit(config, chainAggregationDef); err != nil {
+		start := aspectStart(config)
+		err := aspect.OnChainAggregationBeforeInit(config, chainAggregationDef)
+		observeAspect(aspect, aspectPointBeforeInit, start)
+		if err != nil {
 			return nil, err
 		}
 	}
@@ -61,6 +88,13 @@ func InitChainAggregationCtx(config types.Config, aspects types.AspectList, chai
 		chainRoutes:    map[string]types.ChainCtx{},
 		aspects:        aspects,
 	}
+	// Destroy the child chains already initialized when a later step fails
+	// 后续步骤失败时销毁已初始化的子规则链
+	defer func() {
+		if err != nil {
+			chainAggregationCtx.Destroy()
+		}
+	}()
 
 	sort.Slice(chainAggregationDef.Metadata.Chains, func(i, j int) bool {
 		return chainAggregationDef.Metadata.Chains[i].Priority > chainAggregationDef.Metadata.Chains[j].Priority
@@ -76,15 +110,60 @@ func InitChainAggregationCtx(config types.Config, aspects types.AspectList, chai
 	}
 
 	chainAggregationCtx.beforeAspects, chainAggregationCtx.afterAspects = aspects.GetChainAspects()
+	chainAggregationCtx.completedAspects = aspects.GetCompletedAspects()
 
-	err := maps.Map2Struct(chainAggregationDef.Configuration, &chainAggregationCtx.chainAggregationConfiguration)
+	err = maps.Map2Struct(chainAggregationDef.Configuration, &chainAggregationCtx.chainAggregationConfiguration)
+	if err != nil {
+		return nil, err
+	}
+	chainAggregationCtx.outputKeys, err = chainOutputKeys(chainAggregationCtx.chainAggregationConfiguration.OutputKey, chainAggregationDef.Metadata.Chains)
 	if err != nil {
 		return nil, err
 	}
+	aggregation := chainAggregationCtx.chainAggregationConfiguration.Aggregation
+	if script := strings.TrimSpace(aggregation.ScoreExpr); script != "" {
+		if chainAggregationCtx.scoreProgram, err = expr.Compile(script, base.NodeUtils.ExprOptions(config)...); err != nil {
+			return nil, fmt.Errorf("aggregation %s scoreExpr error:%w", chainAggregationDef.Id, err)
+		}
+	}
+	if script := strings.TrimSpace(aggregation.ActionExpr); script != "" {
+		if chainAggregationCtx.actionProgram, err = expr.Compile(script, base.NodeUtils.ExprOptions(config, expr.AsKind(reflect.String))...); err != nil {
+			return nil, fmt.Errorf("aggregation %s actionExpr error:%w", chainAggregationDef.Id, err)
+		}
+	}
 
 	return chainAggregationCtx, nil
 }
 
+// chainOutputKeys returns the key of each chain output in the aggregation output, by chain id
+func chainOutputKeys(outputKey string, chains []*types.Chain) (map[string]string, error) {
+	keys := make(map[string]string, len(chains))
+	switch outputKey {
+	case "", types.AggregationOutputKeyId:
+		for _, chain := range chains {
+			keys[chain.Id] = chain.Id
+		}
+	case types.AggregationOutputKeyName:
+		names := make(map[string]int, len(chains))
+		for _, chain := range chains {
+			names[chain.Name]++
+		}
+		for _, chain := range chains {
+			switch {
+			case chain.Name == "":
+				keys[chain.Id] = chain.Id
+			case names[chain.Name] > 1:
+				keys[chain.Id] = chain.Name + "#" + chain.Id
+			default:
+				keys[chain.Id] = chain.Name
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown aggregation outputKey: %s", outputKey)
+	}
+	return keys, nil
+}
+
 // Config returns the configuration of the rule chain context
 func (rc *ChainAggregationCtx) Config() types.Config {
 	return rc.config
@@ -128,32 +207,36 @@ func (rc *ChainAggregationCtx) Init(_ types.Config, configuration types.Configur
 
 // OnMsg processes incoming messages
 func (rc *ChainAggregationCtx) OnMsg(ctx context.Context, msg types.RuleMsg) (string, error) {
+	_, err := rc.aggregate(ctx, msg)
+	return "", err
+}
+
+// aggregate runs the child chains in priority order and returns the aggregated result,
+// which is also written to the aggregation output of the message.
+// aggregate 按优先级执行子规则链并返回聚合结果，结果同时写入消息的聚合输出。
+func (rc *ChainAggregationCtx) aggregate(ctx context.Context, msg types.RuleMsg) (types.ChainAggregationResult, error) {
 	var output = map[string]map[string]any{}
-	var chainResult types.ChainResult
 	var chainAggregationResult types.ChainAggregationResult
 	var aggregationOutput map[string]any
+	onChainResult := rc.config.ChainResult
 	for _, chain := range rc.chains {
-		msg, err := rc.onBefore(chain, msg)
+		msg, chainErr, err := rc.runChain(ctx, chain, msg)
 		if err != nil {
-			return "", err
-		}
-		if _, err = chain.OnMsg(ctx, msg); err != nil {
-			if chain.TerminalOnErr() {
-				return "", err
-			} else {
-				fmt.Printf("chain:%s, err%v\n", chain.Id(), err)
+			if onChainResult != nil && chainErr != nil {
+				onChainResult(ctx, chain.Id(), types.ChainResult{}, chainErr)
 			}
-		}
-		msg, err = rc.onAfter(chain, msg)
-		if err != nil {
-			return "", err
+			return types.ChainAggregationResult{}, err
 		}
 
-		output[chain.Id()] = msg.GetChainOutput()
+		output[rc.outputKeys[chain.Id()]] = msg.GetChainOutput()
 
+		var chainResult types.ChainResult
 		err = maps.Map2Struct(msg.GetChainOutput(), &chainResult)
 		if err != nil {
-			return "", err
+			return types.ChainAggregationResult{}, err
+		}
+		if onChainResult != nil {
+			onChainResult(ctx, chain.Id(), chainResult, chainErr)
 		}
 
 		if chainResult.Terminate {
@@ -169,14 +252,85 @@ func (rc *ChainAggregationCtx) OnMsg(ctx context.Context, msg types.RuleMsg) (st
 	}
 
 	if !chainAggregationResult.Terminate {
-
+		if err := rc.evaluate(ctx, msg, output, &chainAggregationResult); err != nil {
+			return types.ChainAggregationResult{}, err
+		}
 	}
 
-	maps.Struct2Map(chainAggregationResult, aggregationOutput)
+	maps.Struct2Map(chainAggregationResult, &aggregationOutput)
 	msg.SetChainOutput(nil)
 	msg.SetChainAggregationOutput(output)
 	msg.SetAggregationOutput(aggregationOutput)
-	return "", nil
+	return chainAggregationResult, nil
+}
+
+// runChain runs a child chain between its chain aspects and always runs the completed aspects afterwards,
+// so that resources taken in Before (e.g. concurrency permits) are released on every path.
+// chainErr is the error of the child chain; err is set when the aggregation must stop.
+// runChain 在规则链切面之间执行子规则链，并且总是在之后执行完成切面，
+// 确保 Before 中获取的资源（如并发许可）在任何路径上都会被释放。
+// chainErr 为子规则链的错误；err 在聚合必须终止时设置。
+func (rc *ChainAggregationCtx) runChain(ctx context.Context, chain types.ChainCtx, msg types.RuleMsg) (out types.RuleMsg, chainErr error, err error) {
+	defer func() {
+		completedErr := err
+		if completedErr == nil {
+			completedErr = chainErr
+		}
+		rc.onCompleted(chain, msg, completedErr)
+	}()
+	if msg, err = rc.onBefore(chain, msg); err != nil {
+		return msg, nil, err
+	}
+	if _, chainErr = chain.OnMsg(ctx, msg); chainErr != nil {
+		if chain.TerminalOnErr() {
+			return msg, chainErr, chainErr
+		}
+		fmt.Printf("chain:%s, err%v\n", chain.Id(), chainErr)
+	}
+	msg, err = rc.onAfter(chain, msg)
+	return msg, chainErr, err
+}
+
+// evaluate computes the final score with ScoreExpr, maps it to a band and computes the final action with ActionExpr
+func (rc *ChainAggregationCtx) evaluate(ctx context.Context, msg types.RuleMsg, output map[string]map[string]any, result *types.ChainAggregationResult) error {
+	var env map[string]any
+	if rc.scoreProgram != nil || rc.actionProgram != nil {
+		chains := make(map[string]any, len(output))
+		for key, chainOutput := range output {
+			chains[key] = chainOutput
+		}
+		env = base.NodeUtils.ExprEnv(ctx, rc.config, msg)
+		env[types.AggregationChainsKey] = chains
+		env[types.AggregationReasonsKey] = result.Reasons
+		env[types.AggregationTagsKey] = result.Tags
+	}
+	if rc.scoreProgram != nil {
+		env[types.AggregationScoreKey] = result.Score
+		out, err := vm.Run(rc.scoreProgram, env)
+		if err != nil {
+			return fmt.Errorf("aggregation %s scoreExpr error:%w", rc.Id(), err)
+		}
+		if result.Score, err = cast.ToIntE(out); err != nil {
+			return fmt.Errorf("aggregation %s scoreExpr error:%w", rc.Id(), err)
+		}
+	}
+	if band, ok := rc.chainAggregationConfiguration.Aggregation.Band(result.Score); ok {
+		result.Action = band.Action
+		result.Label = band.Label
+	}
+	if rc.actionProgram != nil {
+		env[types.AggregationScoreKey] = result.Score
+		env[types.AggregationActionKey] = result.Action
+		env[types.AggregationLabelKey] = result.Label
+		out, err := vm.Run(rc.actionProgram, env)
+		if err != nil {
+			return fmt.Errorf("aggregation %s actionExpr error:%w", rc.Id(), err)
+		}
+		if action, _ := out.(string); action != "" {
+			result.Action = action
+		}
+	}
+	return nil
 }
 
 // Destroy cleans up resources and executes destroy aspects
@@ -198,7 +352,12 @@ func (e *ChainAggregationCtx) onBefore(chainCtx types.ChainCtx, msg types.RuleMs
 	var err error
 	for _, aop := range e.beforeAspects {
 		if aop.PointCut(chainCtx, msg) {
+			start := aspectStart(e.config)
 			msg, err = aop.Before(chainCtx, msg)
+			observeAspect(aop, aspectPointBefore, start)
+			if err != nil {
+				return msg, err
+			}
 		}
 	}
 	return msg, err
@@ -210,8 +369,25 @@ func (e *ChainAggregationCtx) onAfter(chainCtx types.ChainCtx, msg types.RuleMsg
 	var err error
 	for _, aop := range e.afterAspects {
 		if aop.PointCut(chainCtx, msg) {
+			start := aspectStart(e.config)
 			msg, err = aop.After(chainCtx, msg)
+			observeAspect(aop, aspectPointAfter, start)
+			if err != nil {
+				return msg, err
+			}
 		}
 	}
 	return msg, err
 }
+
+// onCompleted executes the list of completed aspects when a child chain completes, whether it succeeded or failed.
+// onCompleted 在子规则链执行完成时（无论成功或失败）执行完成切面列表。
+func (e *ChainAggregationCtx) onCompleted(chainCtx types.ChainCtx, msg types.RuleMsg, err error) {
+	for _, aop := range e.completedAspects {
+		if aop.PointCut(chainCtx, msg) {
+			start := aspectStart(e.config)
+			aop.Completed(chainCtx, msg, err)
+			observeAspect(aop, aspectPointCompleted, start)
+		}
+	}
+}
@@ -3,7 +3,9 @@ package engine
 import (
 	"context"
 	"errors"
+	"io"
 	"strconv"
+	"sync"
 	"sync/atomic"
 	"time"
 	"unsafe"
@@ -33,14 +35,30 @@ type ChainAggregationEngine struct {
 	// 使用原子操作防止并发访问时的数据竞态
 	initialized int32
 
+	// reloadMu serializes the reloads and Stop, so each reload diffs against the chain it replaces
+	// reloadMu 串行化重载和 Stop，使每次重载都与其替换的规则链计算差异
+	reloadMu sync.Mutex
+
 	// Aspects is a list of AOP (Aspect-Oriented Programming) aspects
 	// that provide cross-cutting concerns like logging, validation, and metrics
 	// Aspects 是面向切面编程（AOP）切面列表，提供如日志、验证和指标等横切关注点
 	aspects types.AspectList
 
+	// builtinAspects holds the instances added by initBuiltinsAspects
+	// builtinAspects 保存 initBuiltinsAspects 添加的切面实例
+	builtinAspects types.AspectList
+
 	beforeAspects []types.ChainAggregationBeforeAspect
 	afterAspects  []types.ChainAggregationAfterAspect
 
+	// tenant is the tenant the engine runs chains for, set by the caller with WithTenant
+	// tenant 是引擎所属的租户，由调用方通过 WithTenant 设置
+	tenant string
+
+	// cancels holds the cancel functions of the in-flight messages, see Cancel
+	// cancels 保存正在处理的消息的取消函数，参见 Cancel
+	cancels msgCancels
+
 	// Callbacks provides hooks for rule engine lifecycle events,
 	// enabling custom handling of creation, updates, and deletion.
 	// Callbacks 为规则引擎生命周期事件提供钩子，
@@ -48,7 +66,7 @@ type ChainAggregationEngine struct {
 	callbacks types.Callbacks
 }
 
-func NewChainAggregationEngine(def []byte, opts ...types.EngineOption) (types.Engine, error) {
+func NewChainAggregationEngine(def []byte, opts ...types.EngineOption) (types.AggregationEngine, error) {
 	if len(def) == 0 {
 		return nil, errors.New("def can not nil")
 	}
@@ -63,29 +81,71 @@ func NewChainAggregationEngine(def []byte, opts ...types.EngineOption) (types.En
 		types.WithOnUpdated(chainAggregationEngine.onUpdate),
 	)
 
-	err := chainAggregationEngine.reloadSelf(def, opts...)
+	// Apply the options to the engine and set up the built-in aspects before the first load,
+	// so that validation aspects such as OnChainBeforeInit run for the initial definition too.
+	// 在首次加载前应用选项并初始化内置切面，使验证等切面对初始定义同样生效。
+	for _, opt := range opts {
+		_ = opt(chainAggregationEngine)
+	}
+	chainAggregationEngine.initBuiltinsAspects()
+
+	err := chainAggregationEngine.reloadSelf(def)
 	return chainAggregationEngine, err
 }
 
 // Id 返回规则引擎实例的唯一标识符。
 func (e *ChainAggregationEngine) Id() string {
-	return e.chainAggregationCtx.Id()
+	if aggregationCtx := e.aggregationCtx(); aggregationCtx != nil {
+		return aggregationCtx.Id()
+	}
+	return ""
 }
 
 // Name 返回规则引擎实例的唯一标识符。
 func (e *ChainAggregationEngine) Name() string {
-	return e.chainAggregationCtx.Name()
+	if aggregationCtx := e.aggregationCtx(); aggregationCtx != nil {
+		return aggregationCtx.Name()
+	}
+	return ""
 }
 
 // TerminalOnErr 是否出错推出
 func (rc *ChainAggregationEngine) TerminalOnErr() bool {
-	return rc.chainAggregationCtx.TerminalOnErr()
+	if aggregationCtx := rc.aggregationCtx(); aggregationCtx != nil {
+		return aggregationCtx.TerminalOnErr()
+	}
+	return false
+}
+
+// aggregationCtx returns the current aggregation context, nil when the engine has none. The context is only safe
+// to read, use acquireAggregationCtx to run messages through it.
+// aggregationCtx 返回当前的聚合上下文，引擎没有聚合时返回 nil。该上下文仅可安全读取，通过它处理消息时使用 acquireAggregationCtx。
+func (e *ChainAggregationEngine) aggregationCtx() *ChainAggregationCtx {
+	return (*ChainAggregationCtx)(atomic.LoadPointer((*unsafe.Pointer)(unsafe.Pointer(&e.chainAggregationCtx))))
+}
+
+// acquireAggregationCtx returns the current aggregation context counted as in use, so it is not destroyed until
+// the caller releases it with refs.release. It returns nil when the engine has no aggregation.
+// acquireAggregationCtx 返回当前的聚合上下文并计为使用中，在调用方通过 refs.release 释放前不会被销毁。引擎没有聚合时返回 nil。
+func (e *ChainAggregationEngine) acquireAggregationCtx() *ChainAggregationCtx {
+	for {
+		aggregationCtx := e.aggregationCtx()
+		if aggregationCtx == nil || aggregationCtx.refs.acquire() {
+			return aggregationCtx
+		}
+	}
+}
+
+// swapAggregationCtx replaces the aggregation context with ctx and returns the previous one, reloadMu must be held
+func (e *ChainAggregationEngine) swapAggregationCtx(ctx *ChainAggregationCtx) *ChainAggregationCtx {
+	return (*ChainAggregationCtx)(atomic.SwapPointer((*unsafe.Pointer)(unsafe.Pointer(&e.chainAggregationCtx)), unsafe.Pointer(ctx)))
 }
 
 // SetConfig 更新规则引擎的配置。
 // 为了获得最佳效果，应在初始化前调用。
+// 为 nil 的 Logger、Parser、ComponentsRegistry 或 JsVMPool 会替换为默认值。
 func (e *ChainAggregationEngine) SetConfig(config types.Config) {
-	e.config = config
+	e.config = withConfigDefaults(config)
 }
 
 // SetAspects 更新规则引擎使用的切面列表。
@@ -100,6 +160,13 @@ func (e *ChainAggregationEngine) GetAspects() types.AspectList {
 	return e.aspects
 }
 
+// DescribeAspects returns the aspects in execution order, with the aspect interfaces each
+// implements and whether it was added from BuiltinsAspects or from user config.
+// DescribeAspects 按执行顺序返回切面，包括各切面实现的切面接口，以及它来自 BuiltinsAspects 还是用户配置。
+func (e *ChainAggregationEngine) DescribeAspects() []types.AspectInfo {
+	return e.aspects.Describe(e.builtinAspects)
+}
+
 // initBuiltinsAspects initializes the built-in aspects if no custom aspects are provided.
 // It ensures that essential aspects like validation and debugging are always available.
 // initBuiltinsAspects 如果没有提供自定义切面，则初始化内置切面。
@@ -107,28 +174,48 @@ func (e *ChainAggregationEngine) GetAspects() types.AspectList {
 func (e *ChainAggregationEngine) initBuiltinsAspects() {
 	//初始化内置切面
 	for _, builtinsAspect := range BuiltinsAspects {
-		e.aspects = append(e.aspects, builtinsAspect.New())
+		// 已配置同类型切面时不再添加内置切面
+		// A built-in is skipped when an aspect of the same type is configured
+		if e.aspects.HasType(builtinsAspect) {
+			continue
+		}
+		instance := builtinsAspect.New()
+		e.aspects = append(e.aspects, instance)
+		e.builtinAspects = append(e.builtinAspects, instance)
 	}
 	e.beforeAspects, e.afterAspects = e.aspects.GetChainAggregationAspects()
 }
 
 // initChain initializes the rule chain with the provided definition.
 // It sets up all nodes, relationships, and executes creation aspects.
+// The replaced aggregation is destroyed once the messages running through it complete.
+// It returns the diff between the replaced chain and the new one, computed right before the swap. reloadMu must be held.
 // initChain 使用提供的定义初始化规则链。
-// 它设置所有节点、关系并执行创建切面。
-func (e *ChainAggregationEngine) init(def types.ChainAggregation) error {
+// 它设置所有节点、关系并执行创建切面。被替换的聚合在其正在处理的消息完成后销毁。
+// 返回在替换前计算的被替换规则链与新规则链之间的差异。必须持有 reloadMu。
+func (e *ChainAggregationEngine) init(def types.ChainAggregation) (types.ChainDiff, error) {
 	if def.Disabled {
-		return types.ErrEngineDisabled
+		return types.ChainDiff{}, types.ErrEngineDisabled
 	}
-	ctx, err := InitChainAggregationCtx(e.config, e.aspects, &def)
+	config, err := e.config.ForTenant(e.tenant)
 	if err != nil {
-		return err
+		return types.ChainDiff{}, err
+	}
+	ctx, err := InitChainAggregationCtx(config, e.aspects, &def)
+	if err != nil {
+		return types.ChainDiff{}, err
 	}
 
-	unsafepL := (*unsafe.Pointer)(unsafe.Pointer(&e.chainAggregationCtx))
-	atomic.StorePointer(unsafepL, unsafe.Pointer(ctx))
+	var oldDef *types.ChainAggregation
+	if current := e.aggregationCtx(); current != nil {
+		oldDef = current.selfDefinition
+	}
+	diff := types.DiffChainAggregations(oldDef, ctx.selfDefinition)
+	if old := e.swapAggregationCtx(ctx); old != nil {
+		old.refs.retire(old.Destroy)
+	}
 
-	return nil
+	return diff, nil
 }
 
 // ReloadSelf reloads the rule chain with new definition and options.
@@ -164,6 +251,8 @@ func (e *ChainAggregationEngine) ReloadSelf(dsl []byte) error {
 }
 
 func (e *ChainAggregationEngine) reloadSelf(dsl []byte, opts ...types.EngineOption) error {
+	e.reloadMu.Lock()
+	defer e.reloadMu.Unlock()
 	// Apply the options to the RuleEngine.
 	// 将选项应用于 RuleEngine。
 	for _, opt := range opts {
@@ -176,18 +265,18 @@ func (e *ChainAggregationEngine) reloadSelf(dsl []byte, opts ...types.EngineOpti
 		return err
 	}
 
-	err = e.init(chainAggregationDef)
+	diff, err := e.init(chainAggregationDef)
 	if err != nil {
 		return err
 	}
+	e.cancels.open()
 
 	if e.isInitialized() {
 		//执行创建切面逻辑
 		if e.callbacks.OnUpdated != nil {
-			e.callbacks.OnUpdated(e.Id(), e.DSL())
+			e.callbacks.OnUpdated(e.Id(), e.DSL(), diff)
 		}
 	} else {
-		e.initBuiltinsAspects()
 		e.setInitialized()
 		//执行创建切面逻辑
 		if e.callbacks.OnNew != nil {
@@ -201,7 +290,10 @@ func (e *ChainAggregationEngine) reloadSelf(dsl []byte, opts ...types.EngineOpti
 // DSL returns the current rule chain configuration in its original format.
 // DSL 返回原始格式的当前规则链配置。
 func (e *ChainAggregationEngine) DSL() []byte {
-	return e.chainAggregationCtx.DSL()
+	if aggregationCtx := e.aggregationCtx(); aggregationCtx != nil {
+		return aggregationCtx.DSL()
+	}
+	return nil
 }
 
 // Initialized returns whether the rule engine has been properly initialized.
@@ -252,11 +344,14 @@ func (e *ChainAggregationEngine) forceStop() {
 		e.callbacks.OnDeleted(e.Id())
 	}
 
-	// Destroy rule chain context and all nodes
-	// 销毁规则链上下文和所有节点
-	if e.chainAggregationCtx != nil {
-		e.chainAggregationCtx.Destroy()
-		e.chainAggregationCtx = nil
+	// Cancel the messages being processed, so nodes waiting on them return, then destroy the
+	// rule chain context and all nodes once they have drained
+	// 取消正在处理的消息，使等待中的节点返回，在消息处理完成后再销毁规则链上下文和所有节点
+	e.cancels.close(types.ErrEngineShuttingDown)
+	e.reloadMu.Lock()
+	defer e.reloadMu.Unlock()
+	if old := e.swapAggregationCtx(nil); old != nil {
+		<-old.refs.retire(old.Destroy)
 	}
 
 	e.unSetInitialized()
@@ -267,8 +362,85 @@ func (e *ChainAggregationEngine) forceStop() {
 //
 // OnMsg 使用规则引擎异步处理消息。
 // 它接受可选的 RuleContextOption 参数来自定义执行上下文。
+//
+// A failed message is retried and dead-lettered according to Config.MaxRetries and Config.DeadLetter.
+// 失败的消息按照 Config.MaxRetries 和 Config.DeadLetter 重试并转入死信处理。
+//
+// A message larger than Config.MaxPayloadSize is rejected with types.ErrPayloadTooLarge, it is neither retried
+// nor dead-lettered.
+// 超过 Config.MaxPayloadSize 的消息以 types.ErrPayloadTooLarge 拒绝，不重试也不转入死信。
 func (e *ChainAggregationEngine) OnMsg(ctx context.Context, msg types.RuleMsg) error {
-	return e.onMsg(ctx, msg)
+	_, err := e.OnMsgAndWait(ctx, msg)
+	return err
+}
+
+// OnMsgAndWait processes a message like OnMsg and returns the aggregated decision of the child chains,
+// so callers do not need to decode it from the aggregation output of the message.
+//
+// OnMsgAndWait 与 OnMsg 一样处理消息，并返回子规则链的聚合决策，
+// 调用方无需再从消息的聚合输出中解码。
+//
+// Usage:
+// 使用方法：
+//
+//	result, err := engine.OnMsgAndWait(ctx, msg)
+//	if err == nil && result.Terminate {
+//		fmt.Println(result.Action, result.Score, result.Reasons)
+//	}
+func (e *ChainAggregationEngine) OnMsgAndWait(ctx context.Context, msg types.RuleMsg) (types.ChainAggregationResult, error) {
+	if err := checkPayloadSize(e.config, msg); err != nil {
+		return types.ChainAggregationResult{}, err
+	}
+	e.config.StampMsg(msg)
+	ctx, release := e.cancels.register(ctx, msg.Id())
+	defer release()
+	ctx = runContext(ctx, e.config, msg)
+	var result types.ChainAggregationResult
+	err := runWithRetry(ctx, e.config, msg, func(func(error)) (err error) {
+		result, err = e.onMsg(ctx, msg)
+		return err
+	}, nil)
+	return result, err
+}
+
+// Cancel cancels the in-flight messages with the id, see types.MsgCanceller.
+// Cancel 取消该 id 对应的正在处理的消息，参见 types.MsgCanceller。
+func (e *ChainAggregationEngine) Cancel(msgId string) bool {
+	return e.cancels.cancel(msgId)
+}
+
+// EnableProfiling starts a CPU profile written to w and labels the samples taken while a chain runs
+// with the chain id under ProfileLabelChain, to attribute CPU to specific chains in a multi-chain process.
+// The CPU profile is process wide: while it runs the messages of every engine are labeled, and
+// starting a second profile fails until DisableProfiling stops the first one.
+// Only CPU samples carry labels, use the heap profile of net/http/pprof for allocations.
+//
+// EnableProfiling 启动写入 w 的 CPU 性能分析，并以 ProfileLabelChain 标签标记规则链执行期间的采样的规则链 id，
+// 以便在包含多个规则链的进程中将 CPU 归属到具体的规则链。CPU 性能分析是进程级的：运行期间所有引擎的消息都会被标记，
+// 在 DisableProfiling 停止之前无法启动第二个性能分析。只有 CPU 采样带有标签，内存分配请使用 net/http/pprof 的 heap 分析。
+//
+// Usage:
+// 使用方法：
+//
+//	f, _ := os.Create("cpu.pprof")
+//	err := ruleEngine.EnableProfiling(f)
+//	// ... process messages ...
+//	ruleEngine.DisableProfiling()
+//	f.Close()
+//
+// Then show the hot chains, or the hot functions of one chain:
+// 然后查看最耗 CPU 的规则链，或某个规则链中最耗 CPU 的函数：
+//
+//	go tool pprof -tags cpu.pprof
+//	go tool pprof -tagfocus=chain=chain01 -top cpu.pprof
+func (e *ChainAggregationEngine) EnableProfiling(w io.Writer) error {
+	return startProfiling(w)
+}
+
+// DisableProfiling stops the CPU profile started by EnableProfiling and flushes it to its writer.
+// DisableProfiling 停止 EnableProfiling 启动的 CPU 性能分析，并将其刷新到写入器。
+func (e *ChainAggregationEngine) DisableProfiling() {
+	stopProfiling()
 }
 
 // GetMetrics returns engine metrics if the metrics aspect is enabled.
@@ -282,7 +454,15 @@ func (e *ChainAggregationEngine) GetMetrics() *metrics.EngineMetrics {
 	return nil
 }
 
-func (e *ChainAggregationEngine) onMsg(ctx context.Context, msg types.RuleMsg) error {
+func (e *ChainAggregationEngine) onMsg(ctx context.Context, msg types.RuleMsg) (types.ChainAggregationResult, error) {
+	var result types.ChainAggregationResult
+	// Hold the aggregation for the whole message, so a reload does not destroy it meanwhile
+	// 在整个消息处理期间持有聚合，确保重载不会在此期间将其销毁
+	aggregationCtx := e.acquireAggregationCtx()
+	if aggregationCtx == nil {
+		return result, types.ErrEngineNotInitialized
+	}
+	defer aggregationCtx.refs.release()
 	var err error
 	start := time.Now()
 	defer func() {
@@ -293,38 +473,45 @@ func (e *ChainAggregationEngine) onMsg(ctx context.Context, msg types.RuleMsg) e
 		duration := time.Since(start).Seconds()
 		// 统计
 		enginRequestsTotal.WithLabelValues(
-			e.Name(),
+			aggregationCtx.Name(),
 			strconv.Itoa(status),
 		).Inc()
 
 		enginRequestDuration.WithLabelValues(
-			e.Name(),
+			aggregationCtx.Name(),
 		).Observe(duration)
+		observeTags(e.config, aggregationCtx.Name(), strconv.Itoa(status), msg)
 	}()
 	// Execute start aspects
 	// 执行开始切面
-	msg, err = e.onBefore(msg)
+	msg, err = e.onBefore(aggregationCtx, msg)
 	if err != nil {
-		return err
+		return result, err
 	}
 
-	// Process message with or without waiting
-	// 处理消息，可选择是否等待
-	if _, err := e.chainAggregationCtx.OnMsg(ctx, msg); err != nil {
-		return err
+	// Process message and aggregate the child chain results
+	// 处理消息并聚合子规则链结果
+	result, err = aggregationCtx.aggregate(ctx, msg)
+	if err != nil {
+		return result, err
 	}
 
 	// Execute start aspects
 	// 执行开始切面
-	_, err = e.onAfter(msg)
-	return err
+	_, err = e.onAfter(aggregationCtx, msg)
+	return result, err
 }
 
-func (e *ChainAggregationEngine) onBefore(msg types.RuleMsg) (types.RuleMsg, error) {
+func (e *ChainAggregationEngine) onBefore(aggregationCtx *ChainAggregationCtx, msg types.RuleMsg) (types.RuleMsg, error) {
 	var err error
 	for _, aop := range e.beforeAspects {
-		if aop.PointCut(e.chainAggregationCtx, msg) {
-			msg, err = aop.Before(e.chainAggregationCtx, msg)
+		if aop.PointCut(aggregationCtx, msg) {
+			start := aspectStart(e.config)
+			msg, err = aop.Before(aggregationCtx, msg)
+			observeAspect(aop, aspectPointBefore, start)
+			if err != nil {
+				return msg, err
+			}
 		}
 	}
 	return msg, err
@@ -332,12 +519,16 @@ func (e *ChainAggregationEngine) onBefore(msg types.RuleMsg) (types.RuleMsg, err
 
 // onEnd executes the list of end aspects when a branch of the rule chain ends.
 // onEnd 在规则链分支结束时执行结束切面列表。
-func (e *ChainAggregationEngine) onAfter(msg types.RuleMsg) (types.RuleMsg, error) {
+func (e *ChainAggregationEngine) onAfter(aggregationCtx *ChainAggregationCtx, msg types.RuleMsg) (types.RuleMsg, error) {
 	var err error
 	for _, aop := range e.afterAspects {
-		if aop.PointCut(e.chainAggregationCtx, msg) {
-			msg, err = aop.After(e.chainAggregationCtx, msg)
-
+		if aop.PointCut(aggregationCtx, msg) {
+			start := aspectStart(e.config)
+			msg, err = aop.After(aggregationCtx, msg)
+			observeAspect(aop, aspectPointAfter, start)
+			if err != nil {
+				return msg, err
+			}
 		}
 	}
 	return msg, err
@@ -347,8 +538,8 @@ func (e *ChainAggregationEngine) onNew(chainId string, dsl []byte) {
 	e.config.Logger.Printf("ChainAggregationEngine OnNew: chainId=%s", chainId)
 }
 
-func (e *ChainAggregationEngine) onUpdate(chainId string, dsl []byte) {
-	e.config.Logger.Printf("ChainAggregationEngine onUpdate: chainId=%s", chainId)
+func (e *ChainAggregationEngine) onUpdate(chainId string, dsl []byte, diff types.ChainDiff) {
+	e.config.Logger.Printf("ChainAggregationEngine onUpdate: chainId=%s added=%v removed=%v modified=%v", chainId, diff.AddedNodes, diff.RemovedNodes, diff.ModifiedNodes)
 }
 
 func (e *ChainAggregationEngine) onDelete(id string) {
@@ -55,3 +55,8 @@ func (ctx *DefaultChainContext) Self() types.NodeCtx {
 func (ctx *DefaultChainContext) From() types.NodeCtx {
 	return nil
 }
+
+// IsDryRun reports whether the chain is configured to run in dry-run mode.
+func (ctx *DefaultChainContext) IsDryRun() bool {
+	return ctx.self.Config().DryRun
+}
@@ -19,7 +19,9 @@ package engine
 import (
 	"context"
 	"errors"
+	"io"
 	"strconv"
+	"sync"
 	"sync/atomic"
 	"time"
 	"unsafe"
@@ -47,15 +49,33 @@ type ChainEngine struct {
 	// 使用原子操作防止并发访问时的数据竞态
 	initialized int32
 
+	// reloadMu serializes the reloads, Reset and Stop, so each reload diffs against the chain it replaces
+	// reloadMu 串行化重载、Reset 和 Stop，使每次重载都与其替换的规则链计算差异
+	reloadMu sync.Mutex
+
 	// Aspects is a list of AOP (Aspect-Oriented Programming) aspects
 	// that provide cross-cutting concerns like logging, validation, and metrics
 	// Aspects 是面向切面编程（AOP）切面列表，提供如日志、验证和指标等横切关注点
 	aspects types.AspectList
 
+	// builtinAspects holds the instances added by initBuiltinsAspects
+	// builtinAspects 保存 initBuiltinsAspects 添加的切面实例
+	builtinAspects types.AspectList
+
 	beforeAspects []types.ChainBeforeAspect
 
 	afterAspects []types.ChainAfterAspect
 
+	completedAspects []types.CompletedAspect
+
+	// tenant is the tenant the engine runs chains for, set by the caller with WithTenant
+	// tenant 是引擎所属的租户，由调用方通过 WithTenant 设置
+	tenant string
+
+	// cancels holds the cancel functions of the in-flight messages, see Cancel
+	// cancels 保存正在处理的消息的取消函数，参见 Cancel
+	cancels msgCancels
+
 	// Callbacks provides hooks for rule engine lifecycle events,
 	// enabling custom handling of creation, updates, and deletion.
 	// Callbacks 为规则引擎生命周期事件提供钩子，
@@ -78,32 +98,79 @@ func NewChainEngine(def []byte, opts ...types.EngineOption) (types.Engine, error
 		types.WithOnUpdated(ruleEngine.onUpdate),
 	)
 
-	err := ruleEngine.reloadSelf(def, opts...)
+	// Apply the options to the engine and set up the built-in aspects before the first load,
+	// so that validation aspects such as OnChainBeforeInit run for the initial definition too.
+	// 在首次加载前应用选项并初始化内置切面，使验证等切面对初始定义同样生效。
+	for _, opt := range opts {
+		_ = opt(ruleEngine)
+	}
+	ruleEngine.initBuiltinsAspects()
+
+	err := ruleEngine.reloadSelf(def)
 	return ruleEngine, err
 }
 
 // Id returns the unique identifier of the rule engine instance.
 // Id 返回规则引擎实例的唯一标识符。
 func (e *ChainEngine) Id() string {
-	return e.ruleChainCtx.Id()
+	if chainCtx := e.chainCtx(); chainCtx != nil {
+		return chainCtx.Id()
+	}
+	return ""
 }
 
-// GetNodeById retrieves a node context by its ID
-func (rc *ChainEngine) Name() string {
-	return rc.ruleChainCtx.Name()
+// Name returns the name of the rule chain, "" when the engine has no chain.
+// Name 返回规则链的名称，引擎没有规则链时返回 ""。
+func (e *ChainEngine) Name() string {
+	if chainCtx := e.chainCtx(); chainCtx != nil {
+		return chainCtx.Name()
+	}
+	return ""
 }
 
-// GetNodeById retrieves a node context by its ID
-func (rc *ChainEngine) TerminalOnErr() bool {
-	return rc.ruleChainCtx.TerminalOnErr()
+// TerminalOnErr reports whether the rule chain stops on error, false when the engine has no chain.
+// TerminalOnErr 返回规则链出错时是否终止，引擎没有规则链时返回 false。
+func (e *ChainEngine) TerminalOnErr() bool {
+	if chainCtx := e.chainCtx(); chainCtx != nil {
+		return chainCtx.TerminalOnErr()
+	}
+	return false
+}
+
+// chainCtx returns the current rule chain context, nil when the engine has none. The context is only safe
+// to read, use acquireChainCtx to run messages through it.
+// chainCtx 返回当前的规则链上下文，引擎没有规则链时返回 nil。该上下文仅可安全读取，通过它处理消息时使用 acquireChainCtx。
+func (e *ChainEngine) chainCtx() *ChainCtx {
+	return (*ChainCtx)(atomic.LoadPointer((*unsafe.Pointer)(unsafe.Pointer(&e.ruleChainCtx))))
+}
+
+// acquireChainCtx returns the current rule chain context counted as in use, so it is not destroyed until
+// the caller releases it with refs.release. It returns nil when the engine has no chain.
+// acquireChainCtx 返回当前的规则链上下文并计为使用中，在调用方通过 refs.release 释放前不会被销毁。引擎没有规则链时返回 nil。
+func (e *ChainEngine) acquireChainCtx() *ChainCtx {
+	for {
+		chainCtx := e.chainCtx()
+		if chainCtx == nil || chainCtx.refs.acquire() {
+			return chainCtx
+		}
+		// The context was retired after it was swapped out, the next load returns its replacement
+		// 该上下文在被替换后已退役，再次读取将返回替换它的上下文
+	}
+}
+
+// swapChainCtx replaces the rule chain context with ctx and returns the previous one, reloadMu must be held
+func (e *ChainEngine) swapChainCtx(ctx *ChainCtx) *ChainCtx {
+	return (*ChainCtx)(atomic.SwapPointer((*unsafe.Pointer)(unsafe.Pointer(&e.ruleChainCtx)), unsafe.Pointer(ctx)))
 }
 
 // SetConfig updates the configuration of the rule engine.
 // This should be called before initialization for best results.
+// A nil Logger, Parser, ComponentsRegistry or JsVMPool is replaced with the default.
 // SetConfig 更新规则引擎的配置。
 // 为了获得最佳效果，应在初始化前调用。
+// 为 nil 的 Logger、Parser、ComponentsRegistry 或 JsVMPool 会替换为默认值。
 func (e *ChainEngine) SetConfig(config types.Config) {
-	e.config = config
+	e.config = withConfigDefaults(config)
 }
 
 // SetAspects updates the list of aspects used by the rule engine.
@@ -120,6 +187,47 @@ func (e *ChainEngine) GetAspects() types.AspectList {
 	return e.aspects
 }
 
+// DescribeAspects returns the aspects in execution order, with the aspect interfaces each
+// implements and whether it was added from BuiltinsAspects or from user config.
+// DescribeAspects 按执行顺序返回切面，包括各切面实现的切面接口，以及它来自 BuiltinsAspects 还是用户配置。
+func (e *ChainEngine) DescribeAspects() []types.AspectInfo {
+	return e.aspects.Describe(e.builtinAspects)
+}
+
+// EnableProfiling starts a CPU profile written to w and labels the samples taken while a chain runs
+// with the chain id under ProfileLabelChain, to attribute CPU to specific chains in a multi-chain process.
+// The CPU profile is process wide: while it runs the messages of every engine are labeled, and
+// starting a second profile fails until DisableProfiling stops the first one.
+// Only CPU samples carry labels, use the heap profile of net/http/pprof for allocations.
+//
+// EnableProfiling 启动写入 w 的 CPU 性能分析，并以 ProfileLabelChain 标签标记规则链执行期间的采样的规则链 id，
+// 以便在包含多个规则链的进程中将 CPU 归属到具体的规则链。CPU 性能分析是进程级的：运行期间所有引擎的消息都会被标记，
+// 在 DisableProfiling 停止之前无法启动第二个性能分析。只有 CPU 采样带有标签，内存分配请使用 net/http/pprof 的 heap 分析。
+//
+// Usage:
+// 使用方法：
+//
+//	f, _ := os.Create("cpu.pprof")
+//	err := ruleEngine.EnableProfiling(f)
+//	// ... process messages ...
+//	ruleEngine.DisableProfiling()
+//	f.Close()
+//
+// Then show the hot chains, or the hot functions of one chain:
+// 然后查看最耗 CPU 的规则链，或某个规则链中最耗 CPU 的函数：
+//
+//	go tool pprof -tags cpu.pprof
+//	go tool pprof -tagfocus=chain=chain01 -top cpu.pprof
+func (e *ChainEngine) EnableProfiling(w io.Writer) error {
+	return startProfiling(w)
+}
+
+// DisableProfiling stops the CPU profile started by EnableProfiling and flushes it to its writer.
+// DisableProfiling 停止 EnableProfiling 启动的 CPU 性能分析，并将其刷新到写入器。
+func (e *ChainEngine) DisableProfiling() {
+	stopProfiling()
+}
+
 // initBuiltinsAspects initializes the built-in aspects if no custom aspects are provided.
 // It ensures that essential aspects like validation and debugging are always available.
 // initBuiltinsAspects 如果没有提供自定义切面，则初始化内置切面。
@@ -127,28 +235,59 @@ func (e *ChainEngine) GetAspects() types.AspectList {
 func (e *ChainEngine) initBuiltinsAspects() {
 	//初始化内置切面
 	for _, builtinsAspect := range BuiltinsAspects {
-		e.aspects = append(e.aspects, builtinsAspect.New())
+		// 已配置同类型切面时不再添加内置切面
+		// A built-in is skipped when an aspect of the same type is configured
+		if e.aspects.HasType(builtinsAspect) {
+			continue
+		}
+		instance := builtinsAspect.New()
+		e.aspects = append(e.aspects, instance)
+		e.builtinAspects = append(e.builtinAspects, instance)
 	}
 	e.beforeAspects, e.afterAspects = e.aspects.GetChainAspects()
+	e.completedAspects = e.aspects.GetCompletedAspects()
 }
 
 // initChain initializes the rule chain with the provided definition.
 // It sets up all nodes, relationships, and executes creation aspects.
+// The new chain is built completely before it replaces the current one, which is destroyed once the messages
+// running through it complete, without holding up the new messages; when the definition fails to load the
+// current chain keeps serving untouched. It returns the diff between the
+// replaced chain and the new one, computed right before the swap. reloadMu must be held.
 // initChain 使用提供的定义初始化规则链。
 // 它设置所有节点、关系并执行创建切面。
-func (e *ChainEngine) init(def types.Chain) error {
-	if def.Disabled {
-		return types.ErrEngineDisabled
-	}
-	ctx, err := InitChainCtx(e.config, e.aspects, &def)
+// 新规则链完全构建后才替换当前规则链，当前规则链在其正在处理的消息完成后销毁，不会阻塞新消息；定义加载失败时当前规则链保持不变并继续服务。
+// 返回在替换前计算的被替换规则链与新规则链之间的差异。必须持有 reloadMu。
+func (e *ChainEngine) init(def types.Chain) (types.ChainDiff, error) {
+	config, err := e.config.ForTenant(e.tenant)
 	if err != nil {
-		return err
+		return types.ChainDiff{}, err
+	}
+	var ctx *ChainCtx
+	if def.Disabled {
+		// A disabled chain loads as an inert engine, OnMsg returns ErrEngineDisabled until it is re-enabled by ReloadSelf
+		// 已禁用的规则链加载为不可执行的引擎，在通过 ReloadSelf 重新启用前 OnMsg 返回 ErrEngineDisabled
+		ctx = newDisabledChainCtx(config, e.aspects, &def)
+	} else {
+		ctx, err = InitChainCtx(config, e.aspects, &def)
+		if err != nil {
+			return types.ChainDiff{}, err
+		}
 	}
 
-	unsafepL := (*unsafe.Pointer)(unsafe.Pointer(&e.ruleChainCtx))
-	atomic.StorePointer(unsafepL, unsafe.Pointer(ctx))
+	var oldDef *types.Chain
+	if current := e.chainCtx(); current != nil {
+		oldDef = current.selfDefinition
+	}
+	diff := types.DiffChains(oldDef, ctx.selfDefinition)
+	// New messages run through the new chain right after the swap, the replaced one is destroyed
+	// once the messages running through it complete
+	// 替换后新消息立即通过新规则链处理，被替换的规则链在其正在处理的消息完成后销毁
+	if old := e.swapChainCtx(ctx); old != nil {
+		old.refs.retire(old.Destroy)
+	}
 
-	return nil
+	return diff, nil
 }
 
 // ReloadSelf reloads the rule chain with new definition and options.
@@ -167,9 +306,13 @@ func (e *ChainEngine) init(def types.Chain) error {
 // - Update atomic aspect pointers  更新原子切面指针
 // - Resume normal operation  恢复正常运行
 //
+// The reload is transactional: the new chain is fully initialized and validated before it is swapped in,
+// on any error the current chain keeps serving and no callback runs.
+//
 // ReloadSelf 使用新定义和选项重新加载规则链。
 // 此方法支持在不停止引擎的情况下热重载规则配置。
 // 它实现了两阶段优雅重载过程：
+// 重载是事务性的：新规则链完全初始化并验证后才会替换，出现任何错误时当前规则链继续服务，且不执行任何回调。
 //
 // Parameters:
 // 参数：
@@ -184,6 +327,8 @@ func (e *ChainEngine) ReloadSelf(dsl []byte) error {
 }
 
 func (e *ChainEngine) reloadSelf(dsl []byte, opts ...types.EngineOption) error {
+	e.reloadMu.Lock()
+	defer e.reloadMu.Unlock()
 	// Apply the options to the RuleEngine.
 	// 将选项应用于 RuleEngine。
 	for _, opt := range opts {
@@ -196,18 +341,18 @@ func (e *ChainEngine) reloadSelf(dsl []byte, opts ...types.EngineOption) error {
 		return err
 	}
 
-	err = e.init(chainDef)
+	diff, err := e.init(chainDef)
 	if err != nil {
 		return err
 	}
+	e.cancels.open()
 
 	if e.isInitialized() {
 		//执行创建切面逻辑
 		if e.callbacks.OnUpdated != nil {
-			e.callbacks.OnUpdated(e.Id(), e.DSL())
+			e.callbacks.OnUpdated(e.Id(), e.DSL(), diff)
 		}
 	} else {
-		e.initBuiltinsAspects()
 		e.setInitialized()
 		//执行创建切面逻辑
 		if e.callbacks.OnNew != nil {
@@ -221,7 +366,10 @@ func (e *ChainEngine) reloadSelf(dsl []byte, opts ...types.EngineOption) error {
 // DSL returns the current rule chain configuration in its original format.
 // DSL 返回原始格式的当前规则链配置。
 func (e *ChainEngine) DSL() []byte {
-	return e.ruleChainCtx.DSL()
+	if chainCtx := e.chainCtx(); chainCtx != nil {
+		return chainCtx.DSL()
+	}
+	return nil
 }
 
 // Initialized returns whether the rule engine has been properly initialized.
@@ -251,6 +399,23 @@ func (e *ChainEngine) Stop() {
 	//e.callbacks.OnDeleted()
 }
 
+// Reset destroys the current rule chain and returns the engine to a blank, uninitialized state,
+// keeping its config, aspects and accumulated metrics. Reset waits for the messages being
+// processed to complete, messages received afterwards fail with types.ErrEngineNotInitialized
+// until a chain is loaded again with ReloadSelf.
+//
+// Reset 销毁当前规则链，将引擎恢复为空白的未初始化状态，同时保留配置、切面和已累计的指标。
+// Reset 会等待正在处理的消息完成，之后收到的消息返回 types.ErrEngineNotInitialized，
+// 直到通过 ReloadSelf 重新加载规则链。
+func (e *ChainEngine) Reset() {
+	e.reloadMu.Lock()
+	defer e.reloadMu.Unlock()
+	if old := e.swapChainCtx(nil); old != nil {
+		<-old.refs.retire(old.Destroy)
+	}
+	e.unSetInitialized()
+}
+
 // forceStop performs immediate cleanup of all rule engine resources.
 // This method is called during shutdown to ensure complete resource cleanup,
 // regardless of whether graceful shutdown completed successfully.
@@ -272,11 +437,14 @@ func (e *ChainEngine) forceStop() {
 		e.callbacks.OnDeleted(e.Id())
 	}
 
-	// Destroy rule chain context and all nodes
-	// 销毁规则链上下文和所有节点
-	if e.ruleChainCtx != nil {
-		e.ruleChainCtx.Destroy()
-		e.ruleChainCtx = nil
+	// Cancel the messages being processed, so nodes waiting on them, like the waitUntil node, return,
+	// then destroy the chain once they have drained
+	// 取消正在处理的消息，使 waitUntil 等等待中的节点返回，在消息处理完成后再销毁规则链
+	e.cancels.close(types.ErrEngineShuttingDown)
+	e.reloadMu.Lock()
+	defer e.reloadMu.Unlock()
+	if old := e.swapChainCtx(nil); old != nil {
+		<-old.refs.retire(old.Destroy)
 	}
 
 	e.unSetInitialized()
@@ -287,14 +455,200 @@ func (e *ChainEngine) forceStop() {
 //
 // OnMsg 使用规则引擎异步处理消息。
 // 它接受可选的 RuleContextOption 参数来自定义执行上下文。
+//
+// A disabled chain returns types.ErrEngineDisabled without running any aspect.
+// 已禁用的规则链直接返回 types.ErrEngineDisabled，不执行任何切面。
+//
+// A failed message is retried and dead-lettered according to Config.MaxRetries and Config.DeadLetter.
+// 失败的消息按照 Config.MaxRetries 和 Config.DeadLetter 重试并转入死信处理。
+//
+// A message larger than Config.MaxPayloadSize is rejected with types.ErrPayloadTooLarge, it is neither retried
+// nor dead-lettered.
+// 超过 Config.MaxPayloadSize 的消息以 types.ErrPayloadTooLarge 拒绝，不重试也不转入死信。
+//
+// A message created without an id or a timestamp gets them from Config.IdGenerator and Config.Clock, see Config.StampMsg.
+// 未指定 id 或时间戳创建的消息从 Config.IdGenerator 和 Config.Clock 获得它们，参见 Config.StampMsg。
+//
+// When a node hands the message off, such as the waitUntil node, OnMsg returns nil right away and the chain
+// resumes later without holding the calling goroutine, see types.AsyncNode. The outcome of the message is then
+// reported to the completed aspects and to Config.DeadLetter. The message keeps running with ctx, cancelling it
+// cancels the message. A context marked with types.ContextWithSync makes OnMsg wait for the chain to complete.
+// 当节点移交消息时（如 waitUntil 节点），OnMsg 立即返回 nil，规则链稍后恢复执行，不占用调用方 goroutine，
+// 参见 types.AsyncNode。消息的结果随后报告给完成切面和 Config.DeadLetter。消息继续使用 ctx 执行，取消 ctx 会取消该消息。
+// 以 types.ContextWithSync 标记的上下文使 OnMsg 等待规则链执行完成。
 func (e *ChainEngine) OnMsg(ctx context.Context, msg types.RuleMsg) error {
-	return e.onMsg(ctx, msg)
+	if err := checkPayloadSize(e.config, msg); err != nil {
+		return err
+	}
+	e.config.StampMsg(msg)
+	ctx, release := e.cancels.register(ctx, msg.Id())
+	ctx = runContext(ctx, e.config, msg)
+	var done func(error)
+	if !types.IsSync(ctx) {
+		done = func(error) {
+			release()
+		}
+	}
+	err := runWithRetry(ctx, e.config, msg, func(done func(error)) error {
+		return e.process(ctx, msg, done)
+	}, done)
+	if err == errHandedOff {
+		return nil
+	}
+	release()
+	return err
 }
 
-func (e *ChainEngine) onMsg(ctx context.Context, msg types.RuleMsg) error {
-	var err error
+// Cancel cancels the in-flight messages with the id, see types.MsgCanceller.
+// Cancel 取消该 id 对应的正在处理的消息，参见 types.MsgCanceller。
+func (e *ChainEngine) Cancel(msgId string) bool {
+	return e.cancels.cancel(msgId)
+}
+
+// process runs the message through the chain once, holding a reference so the chain is not destroyed meanwhile,
+// until done is called when the message is handed off, see ChainCtx.run.
+// process 执行一次规则链处理消息，期间持有其引用，确保规则链不会被销毁；消息被移交时持有到调用 done 为止，参见 ChainCtx.run。
+func (e *ChainEngine) process(ctx context.Context, msg types.RuleMsg, done func(error)) error {
+	chainCtx := e.acquireChainCtx()
+	if chainCtx == nil {
+		return types.ErrEngineNotInitialized
+	}
+	if chainCtx.Disabled() {
+		chainCtx.refs.release()
+		return types.ErrEngineDisabled
+	}
+	var resume func(error)
+	if done != nil {
+		resume = func(err error) {
+			chainCtx.refs.release()
+			done(err)
+		}
+	}
+	err := e.onMsg(ctx, chainCtx, msg, resume)
+	if err != errHandedOff {
+		chainCtx.refs.release()
+	}
+	return err
+}
+
+// runContext returns the context a message runs with: marked as a dry run when Config.DryRun is set,
+// as traced when Config.Trace is set, and carrying the message id as request id when the caller
+// did not set one, see types.ContextWithRequestId. The context deadline becomes the message deadline,
+// see types.RuleMsg.Deadline, and the context headers are added to the message headers, see types.ContextWithHeaders.
+// A types.ReplayClock is advanced to the message timestamp.
+// runContext 返回消息执行使用的上下文：设置 Config.DryRun 时标记为试运行，设置 Config.Trace 时标记为跟踪，
+// 调用方未设置请求 ID 时以消息 ID 作为请求 ID。上下文的截止时间成为消息的截止时间，参见 types.RuleMsg.Deadline，
+// 上下文的消息头被添加到消息头中，参见 types.ContextWithHeaders。types.ReplayClock 会被推进到消息的时间戳。
+func runContext(ctx context.Context, config types.Config, msg types.RuleMsg) context.Context {
+	if clock, ok := config.Clock.(*types.ReplayClock); ok {
+		clock.Advance(time.UnixMilli(msg.Ts()))
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		msg.SetDeadline(deadline)
+	}
+	for key, value := range types.HeadersFromContext(ctx) {
+		if _, ok := msg.Header(key); !ok {
+			msg.SetHeader(key, value)
+		}
+	}
+	if config.DryRun {
+		ctx = types.ContextWithDryRun(ctx)
+	}
+	if config.Trace {
+		ctx = types.ContextWithTrace(ctx)
+	}
+	if msg.Id() != "" && types.RequestIdFromContext(ctx) == "" {
+		ctx = types.ContextWithRequestId(ctx, msg.Id())
+	}
+	return ctx
+}
+
+// runWithRetry runs the message with run, running it again from its initial state up to Config.MaxRetries
+// times while it fails, and passes the final error to Config.DeadLetter. When run hands the message off, see
+// ChainCtx.run, runWithRetry returns errHandedOff and continues from the done function passed to run, the final
+// error is then passed to done. Messages failed by the engine
+// shutting down are not retried. Messages cancelled through MsgCanceller.Cancel fail with
+// types.ErrMsgCancelled, they are neither retried nor passed to Config.DeadLetter. Errors of an engine
+// that is not initialized or disabled are returned as is, the message never ran.
+// runWithRetry 使用 run 执行消息，失败时从初始状态最多重新执行 Config.MaxRetries 次，并将最终的错误传给
+// Config.DeadLetter。因引擎停止而失败的消息不会重试。通过 MsgCanceller.Cancel 取消的消息以 types.ErrMsgCancelled
+// 失败，既不重试也不传给 Config.DeadLetter。引擎未初始化或已禁用的错误直接返回，消息并未执行。run 移交消息时
+// （参见 ChainCtx.run），runWithRetry 返回 errHandedOff 并从传给 run 的 done 函数继续，最终的错误随后传给 done。
+func runWithRetry(ctx context.Context, config types.Config, msg types.RuleMsg, run func(done func(error)) error, done func(error)) error {
+	var restore func()
+	if config.MaxRetries > 0 {
+		restore = msg.Checkpoint()
+	}
+	var attempt func(retries int) error
+	// complete handles the error of an attempt, running the message again while it is retryable
+	complete := func(retries int, err error) error {
+		err = cancelledErr(ctx, err)
+		if err != nil && retries < config.MaxRetries && retryable(err) && waitRetry(ctx, config.RetryInterval) {
+			restore()
+			return attempt(retries + 1)
+		}
+		if err != nil && config.DeadLetter != nil && !engineUnavailable(err) && !errors.Is(err, types.ErrMsgCancelled) {
+			config.DeadLetter(ctx, msg, err)
+		}
+		return err
+	}
+	attempt = func(retries int) error {
+		var resume func(error)
+		if done != nil {
+			resume = func(err error) {
+				if err = complete(retries, err); err != errHandedOff {
+					done(err)
+				}
+			}
+		}
+		err := run(resume)
+		if err == errHandedOff {
+			return err
+		}
+		return complete(retries, err)
+	}
+	return attempt(0)
+}
+
+// retryable reports whether the message may succeed when run again, it fails for good once the engine stops
+// or the message is cancelled
+func retryable(err error) bool {
+	return !engineUnavailable(err) && !errors.Is(err, types.ErrEngineShuttingDown) && !errors.Is(err, types.ErrMsgCancelled)
+}
+
+// engineUnavailable reports whether err means the message did not run because the engine has no active chain
+func engineUnavailable(err error) bool {
+	return errors.Is(err, types.ErrEngineNotInitialized) || errors.Is(err, types.ErrEngineDisabled)
+}
+
+// waitRetry waits interval before a retry, it returns false when ctx is done first
+func waitRetry(ctx context.Context, interval time.Duration) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if interval <= 0 {
+		return true
+	}
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (e *ChainEngine) onMsg(ctx context.Context, chainCtx *ChainCtx, msg types.RuleMsg, done func(error)) error {
 	start := time.Now()
-	defer func() {
+	// complete runs the after aspects of a successful message, then the completed aspects and the metrics
+	complete := func(err error) error {
+		if err == nil {
+			// Execute start aspects
+			// 执行开始切面
+			_, err = e.onAfter(chainCtx, msg)
+		}
+		e.onCompleted(chainCtx, msg, err)
 		var status int
 		if err != nil {
 			status = 100
@@ -302,39 +656,48 @@ func (e *ChainEngine) onMsg(ctx context.Context, msg types.RuleMsg) error {
 		duration := time.Since(start).Seconds()
 		// 统计
 		enginRequestsTotal.WithLabelValues(
-			e.Name(),
+			chainCtx.Name(),
 			strconv.Itoa(status),
 		).Inc()
 
 		enginRequestDuration.WithLabelValues(
-			e.Name(),
+			chainCtx.Name(),
 		).Observe(duration)
-	}()
+		observeTags(e.config, chainCtx.Name(), strconv.Itoa(status), msg)
+		return err
+	}
 
 	// Execute start aspects
 	// 执行开始切面
-	msg, err = e.onBefore(msg)
+	msg, err := e.onBefore(chainCtx, msg)
 	if err != nil {
-		return err
+		return complete(err)
 	}
 
-	// Process message with or without waiting
-	// 处理消息，可选择是否等待
-	if _, err := e.ruleChainCtx.OnMsg(ctx, msg); err != nil {
+	// Process message, the chain completes from done when the message is handed off
+	// 处理消息，消息被移交时规则链在 done 中完成
+	var resume func(error)
+	if done != nil {
+		resume = func(err error) {
+			done(complete(err))
+		}
+	}
+	if err = chainCtx.run(ctx, msg, resume); err == errHandedOff {
 		return err
 	}
-
-	// Execute start aspects
-	// 执行开始切面
-	_, err = e.onAfter(msg)
-	return err
+	return complete(err)
 }
 
-func (e *ChainEngine) onBefore(msg types.RuleMsg) (types.RuleMsg, error) {
+func (e *ChainEngine) onBefore(chainCtx *ChainCtx, msg types.RuleMsg) (types.RuleMsg, error) {
 	var err error
 	for _, aop := range e.beforeAspects {
-		if aop.PointCut(e.ruleChainCtx, msg) {
-			msg, err = aop.Before(e.ruleChainCtx, msg)
+		if aop.PointCut(chainCtx, msg) {
+			start := aspectStart(e.config)
+			msg, err = aop.Before(chainCtx, msg)
+			observeAspect(aop, aspectPointBefore, start)
+			if err != nil {
+				return msg, err
+			}
 		}
 	}
 	return msg, err
@@ -342,22 +705,40 @@ func (e *ChainEngine) onBefore(msg types.RuleMsg) (types.RuleMsg, error) {
 
 // onEnd executes the list of end aspects when a branch of the rule chain ends.
 // onEnd 在规则链分支结束时执行结束切面列表。
-func (e *ChainEngine) onAfter(msg types.RuleMsg) (types.RuleMsg, error) {
+func (e *ChainEngine) onAfter(chainCtx *ChainCtx, msg types.RuleMsg) (types.RuleMsg, error) {
 	var err error
 	for _, aop := range e.afterAspects {
-		if aop.PointCut(e.ruleChainCtx, msg) {
-			msg, err = aop.After(e.ruleChainCtx, msg)
+		if aop.PointCut(chainCtx, msg) {
+			start := aspectStart(e.config)
+			msg, err = aop.After(chainCtx, msg)
+			observeAspect(aop, aspectPointAfter, start)
+			if err != nil {
+				return msg, err
+			}
 		}
 	}
 	return msg, err
 }
 
+// onCompleted executes the list of completed aspects when the chain execution completes,
+// whether it succeeded or failed.
+// onCompleted 在规则链执行完成时（无论成功或失败）执行完成切面列表。
+func (e *ChainEngine) onCompleted(chainCtx *ChainCtx, msg types.RuleMsg, err error) {
+	for _, aop := range e.completedAspects {
+		if aop.PointCut(chainCtx, msg) {
+			start := aspectStart(e.config)
+			aop.Completed(chainCtx, msg, err)
+			observeAspect(aop, aspectPointCompleted, start)
+		}
+	}
+}
+
 func (e *ChainEngine) onNew(chainId string, dsl []byte) {
 	e.config.Logger.Printf("ChainEngine OnNew: chainId=%s", chainId)
 }
 
-func (e *ChainEngine) onUpdate(chainId string, dsl []byte) {
-	e.config.Logger.Printf("ChainEngine onUpdate: chainId=%s", chainId)
+func (e *ChainEngine) onUpdate(chainId string, dsl []byte, diff types.ChainDiff) {
+	e.config.Logger.Printf("ChainEngine onUpdate: chainId=%s added=%v removed=%v modified=%v", chainId, diff.AddedNodes, diff.RemovedNodes, diff.ModifiedNodes)
 }
 
 func (e *ChainEngine) onDelete(id string) {
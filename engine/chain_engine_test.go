@@ -0,0 +1,2224 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bittoy/rule/builtin/aspect"
+	"github.com/bittoy/rule/builtin/variable"
+	"github.com/bittoy/rule/test/testutil"
+	"github.com/bittoy/rule/types"
+	"github.com/bittoy/rule/utils/maps"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/rulego/rulego/test/assert"
+)
+
+const zeroConfigChain = `{"id":"zeroConfig","name":"zeroConfig","metadata":{"nodes":[
+{"id":"s","type":"start"},
+{"id":"e","type":"end","configuration":{"script":"{'ok': true}"}}
+],"connections":[
+{"fromId":"s","toId":"e","type":"default"}
+]}}`
+
+const zeroConfigAggregation = `{"id":"zeroConfigAggregation","name":"zeroConfigAggregation","metadata":{"chains":[` + zeroConfigChain + `]}}`
+
+// TestZeroValueConfig checks that engines built with a Config literal do not panic on the nil Logger.
+func TestZeroValueConfig(t *testing.T) {
+	chainEngine, err := NewChainEngine([]byte(zeroConfigChain), WithConfig(types.Config{}))
+	assert.Nil(t, err)
+	msg := types.NewRuleMsg("", 0, nil)
+	assert.Nil(t, chainEngine.OnMsg(context.Background(), msg))
+	assert.Equal(t, true, msg.GetChainOutput()["ok"])
+	assert.Nil(t, chainEngine.ReloadSelf([]byte(zeroConfigChain)))
+	chainEngine.Stop()
+
+	aggregationEngine, err := NewChainAggregationEngine([]byte(zeroConfigAggregation), WithConfig(types.Config{}))
+	assert.Nil(t, err)
+	assert.Nil(t, aggregationEngine.OnMsg(context.Background(), types.NewRuleMsg("", 0, nil)))
+	aggregationEngine.Stop()
+}
+
+var errAspectRejected = errors.New("rejected by aspect")
+
+// chainBeforeAspect is a chain before aspect returning err, or passing the message through when err is nil.
+// With failures set, only the first failures calls return err.
+type chainBeforeAspect struct {
+	order    int
+	err      error
+	failures int
+	calls    *int
+}
+
+func (a *chainBeforeAspect) Order() int {
+	return a.order
+}
+
+func (a *chainBeforeAspect) New() types.Aspect {
+	return a
+}
+
+func (a *chainBeforeAspect) PointCut(chainCtx types.ChainCtx, msg types.RuleMsg) bool {
+	return true
+}
+
+func (a *chainBeforeAspect) Before(chainCtx types.ChainCtx, msg types.RuleMsg) (types.RuleMsg, error) {
+	*a.calls++
+	if a.failures > 0 && *a.calls > a.failures {
+		return msg, nil
+	}
+	return msg, a.err
+}
+
+// TestAggregationBeforeAspectError checks that a failing chain before aspect aborts the aggregation
+// even when a later aspect succeeds.
+func TestAggregationBeforeAspectError(t *testing.T) {
+	var calls int
+	aggregationEngine, err := NewChainAggregationEngine([]byte(zeroConfigAggregation), WithAspects(
+		&chainBeforeAspect{order: 1, err: errAspectRejected, calls: &calls},
+		&chainBeforeAspect{order: 2, calls: &calls},
+	))
+	assert.Nil(t, err)
+	defer aggregationEngine.Stop()
+
+	msg := types.NewRuleMsg("", 0, nil)
+	_, err = aggregationEngine.OnMsgAndWait(context.Background(), msg)
+	assert.True(t, errors.Is(err, errAspectRejected))
+	assert.Equal(t, 1, calls)
+	assert.Nil(t, msg.GetAggregationOutput())
+}
+
+// TestRetryDeadLetter checks that a failed message is retried and passed to the dead-letter handler
+// once the retries are exhausted.
+func TestRetryDeadLetter(t *testing.T) {
+	var deadLetters []error
+	config := NewConfig(types.WithRetry(2, time.Millisecond), types.WithDeadLetter(func(ctx context.Context, msg types.RuleMsg, err error) {
+		deadLetters = append(deadLetters, err)
+	}))
+
+	var calls int
+	chainEngine, err := NewChainEngine([]byte(traceChain), WithConfig(config), WithAspects(
+		&chainBeforeAspect{err: errAspectRejected, failures: 2, calls: &calls},
+	))
+	assert.Nil(t, err)
+	defer chainEngine.Stop()
+	msg := types.NewRuleMsg("", 0, map[string]any{"amount": 2})
+	assert.Nil(t, chainEngine.OnMsg(context.Background(), msg))
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, 4, msg.GetChainOutput()["result"])
+	assert.Equal(t, 0, len(deadLetters))
+
+	calls = 0
+	chainEngine, err = NewChainEngine([]byte(traceChain), WithConfig(config), WithAspects(
+		&chainBeforeAspect{err: errAspectRejected, calls: &calls},
+	))
+	assert.Nil(t, err)
+	defer chainEngine.Stop()
+	err = chainEngine.OnMsg(context.Background(), types.NewRuleMsg("", 0, map[string]any{"amount": 2}))
+	assert.True(t, errors.Is(err, errAspectRejected))
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, 1, len(deadLetters))
+	assert.True(t, errors.Is(deadLetters[0], errAspectRejected))
+}
+
+const traceChain = `{"id":"trace","name":"trace","metadata":{"nodes":[
+{"id":"s","type":"start"},
+{"id":"a","type":"exprAssign","configuration":{"script":"{'doubled': amount * 2}"}},
+{"id":"e","type":"end","configuration":{"script":"{'result': priVars.doubled}"}}
+],"connections":[
+{"fromId":"s","toId":"a","type":"default"},
+{"fromId":"a","toId":"e","type":"default"}
+]}}`
+
+// TestTrace checks that trace mode records the snapshots of every node on the message.
+func TestTrace(t *testing.T) {
+	chainEngine, err := NewChainEngine([]byte(traceChain), WithConfig(NewConfig(types.WithTrace(true))))
+	assert.Nil(t, err)
+	defer chainEngine.Stop()
+
+	msg := types.NewRuleMsg("", 0, map[string]any{"amount": 2})
+	assert.Nil(t, chainEngine.OnMsg(context.Background(), msg))
+	traces := msg.Traces()
+	assert.Equal(t, 3, len(traces))
+	assert.Equal(t, "a", traces[1].NodeId)
+	assert.Equal(t, 0, len(traces[1].Input[types.PriVarsKey].(map[string]any)))
+	assert.Equal(t, 4, traces[1].PriVars["doubled"])
+	assert.Equal(t, types.DefaultRelationType, traces[1].Relation)
+	assert.Equal(t, 4, traces[2].ChainOutput["result"])
+
+	untraced, err := NewChainEngine([]byte(traceChain))
+	assert.Nil(t, err)
+	defer untraced.Stop()
+	msg = types.NewRuleMsg("", 0, map[string]any{"amount": 2})
+	assert.Nil(t, untraced.OnMsg(context.Background(), msg))
+	assert.Equal(t, 0, len(msg.Traces()))
+}
+
+const scoredAggregation = `{"id":"scored","name":"scored","metadata":{"chains":[
+{"id":"first","name":"first","metadata":{"nodes":[
+{"id":"s","type":"start"},
+{"id":"e","type":"end","configuration":{"script":"{'score': 10}"}}
+],"connections":[{"fromId":"s","toId":"e","type":"default"}]}},
+{"id":"second","name":"second","metadata":{"nodes":[
+{"id":"s","type":"start"},
+{"id":"e","type":"end","configuration":{"script":"{'score': 5}"}}
+],"connections":[{"fromId":"s","toId":"e","type":"default"}]}}
+]}}`
+
+// TestChainResultHandler checks that Config.ChainResult observes every child chain result in order,
+// with the error of a chain that failed.
+func TestChainResultHandler(t *testing.T) {
+	var chainIds []string
+	var scores []int
+	var errs []error
+	config := NewConfig(types.WithChainResult(func(ctx context.Context, chainId string, result types.ChainResult, err error) {
+		chainIds = append(chainIds, chainId)
+		scores = append(scores, result.Score)
+		errs = append(errs, err)
+	}))
+	aggregationEngine, err := NewChainAggregationEngine([]byte(scoredAggregation), WithConfig(config))
+	assert.Nil(t, err)
+	defer aggregationEngine.Stop()
+
+	result, err := aggregationEngine.OnMsgAndWait(context.Background(), types.NewRuleMsg("", 0, nil))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"first", "second"}, chainIds)
+	assert.Equal(t, []int{10, 5}, scores)
+	assert.Equal(t, []error{nil, nil}, errs)
+	assert.Equal(t, 15, result.Score)
+
+	chainIds, scores, errs = nil, nil, nil
+	failing := strings.Replace(scoredAggregation, `"script":"{'score': 10}"`, `"script":"{'score': int('x')}"`, 1)
+	aggregationEngine, err = NewChainAggregationEngine([]byte(failing), WithConfig(config))
+	assert.Nil(t, err)
+	defer aggregationEngine.Stop()
+
+	result, err = aggregationEngine.OnMsgAndWait(context.Background(), types.NewRuleMsg("", 0, nil))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"first", "second"}, chainIds)
+	assert.NotNil(t, errs[0])
+	assert.Nil(t, errs[1])
+	assert.Equal(t, 5, result.Score)
+}
+
+const waitUntilChain = `{"id":"waitUntil","name":"waitUntil","metadata":{"nodes":[
+{"id":"s","type":"start"},
+{"id":"d","type":"waitUntil","configuration":{"field":"at","maxDelay":"1h"}},
+{"id":"e","type":"end","configuration":{"script":"{'done': true}"}}
+],"connections":[
+{"fromId":"s","toId":"d","type":"default"},
+{"fromId":"d","toId":"e","type":"default"}
+]}}`
+
+// completedAspect is a completed aspect sending the messages completing the chain and their error to completed.
+type completedAspect struct {
+	completed chan completedMsg
+}
+
+type completedMsg struct {
+	msg types.RuleMsg
+	err error
+}
+
+func (a *completedAspect) Order() int {
+	return 0
+}
+
+func (a *completedAspect) New() types.Aspect {
+	return a
+}
+
+func (a *completedAspect) PointCut(chainCtx types.ChainCtx, msg types.RuleMsg) bool {
+	return true
+}
+
+func (a *completedAspect) Completed(chainCtx types.ChainCtx, msg types.RuleMsg, err error) {
+	a.completed <- completedMsg{msg: msg, err: err}
+}
+
+// TestWaitUntil checks that the messages waiting in a node are handed off without holding a goroutine, that
+// they resume at their target time or when cancelled, and that they are dead-lettered when the engine stops.
+func TestWaitUntil(t *testing.T) {
+	deadLetters := make(chan error, 100)
+	config := NewConfig(types.WithDeadLetter(func(ctx context.Context, msg types.RuleMsg, err error) {
+		deadLetters <- err
+	}))
+	completed := &completedAspect{completed: make(chan completedMsg, 200)}
+	ruleEngine, err := NewChainEngine([]byte(waitUntilChain), WithConfig(config), WithAspects(completed))
+	assert.Nil(t, err)
+	chainEngine := ruleEngine.(*ChainEngine)
+
+	before := runtime.NumGoroutine()
+	for i := 0; i < 100; i++ {
+		msg := types.NewRuleMsg("", 0, map[string]any{"at": time.Now().Add(time.Hour).UnixMilli()})
+		assert.Nil(t, chainEngine.OnMsg(context.Background(), msg))
+	}
+	assert.True(t, runtime.NumGoroutine() < before+10)
+
+	assert.Nil(t, chainEngine.OnMsg(context.Background(), types.NewRuleMsg("cancelled", 0, map[string]any{"at": time.Now().Add(time.Hour).UnixMilli()})))
+	assert.True(t, chainEngine.Cancel("cancelled"))
+	result := <-completed.completed
+	assert.Equal(t, "cancelled", result.msg.Id())
+	assert.True(t, errors.Is(result.err, types.ErrMsgCancelled))
+
+	assert.Nil(t, chainEngine.OnMsg(context.Background(), types.NewRuleMsg("soon", 0, map[string]any{"at": time.Now().Add(20 * time.Millisecond).UnixMilli()})))
+	result = <-completed.completed
+	assert.Equal(t, "soon", result.msg.Id())
+	assert.Nil(t, result.err)
+	assert.Equal(t, true, result.msg.GetChainOutput()["done"])
+
+	chainEngine.Stop()
+	for i := 0; i < 100; i++ {
+		assert.True(t, errors.Is(<-deadLetters, types.ErrEngineShuttingDown))
+	}
+}
+
+const switchRelationsChain = `{"id":"switch","name":"switch","metadata":{"nodes":[
+{"id":"s","type":"start"},
+{"id":"w","type":"exprSwitch","configuration":{"script":"amount > 100 ? 'big' : 'default'"}},
+{"id":"e","type":"end"}
+],"connections":[
+{"fromId":"s","toId":"w","type":"default"},
+{"fromId":"w","toId":"e","type":"big"},
+{"fromId":"w","toId":"e","type":"default"}
+]}}`
+
+// TestSwitchRelations checks that the relations a switch returns are cross-checked with its connections.
+func TestSwitchRelations(t *testing.T) {
+	chainEngine, err := NewChainEngine([]byte(switchRelationsChain))
+	assert.Nil(t, err)
+	chainEngine.Stop()
+
+	_, err = NewChainEngine([]byte(strings.Replace(switchRelationsChain, `'big'`, `'huge'`, 1)))
+	assert.NotNil(t, err)
+	assert.True(t, strings.Contains(err.Error(), "可能返回关系 huge"))
+
+	_, err = NewChainEngine([]byte(strings.Replace(switchRelationsChain, `'big'`, `'default'`, 1)))
+	assert.NotNil(t, err)
+	assert.True(t, strings.Contains(err.Error(), "big 连接不会被使用"))
+}
+
+// TestAspectBundle checks that bundles merge with the built-in aspects, deduplicated by type,
+// and that every engine gets its own aspect instances.
+func TestAspectBundle(t *testing.T) {
+	bundle := NewAspectBundle("production", &aspect.MetricsAspect{}, &aspect.ChainValidator{}, &aspect.SlowLogAspect{})
+	first, err := NewChainEngine([]byte(traceChain), WithAspectBundle(bundle, NewAspectBundle("audit", &aspect.SlowLogAspect{})))
+	assert.Nil(t, err)
+	defer first.Stop()
+	second, err := NewChainEngine([]byte(traceChain), WithAspectBundle(bundle))
+	assert.Nil(t, err)
+	defer second.Stop()
+
+	counts := map[string]int{}
+	for _, item := range first.GetAspects() {
+		counts[types.AspectType(item)]++
+	}
+	assert.Equal(t, map[string]int{"metrics": 1, "chainValidator": 1, "slowLog": 1, "chainAggregationValidator": 1}, counts)
+	assert.True(t, first.GetAspects()[0] != second.GetAspects()[0])
+}
+
+const messageVarsChain = `{"id":"vars","name":"vars","metadata":{"nodes":[
+{"id":"s","type":"start"},
+{"id":"f","type":"exprFilter","configuration":{"script":"msgType == 'TELEMETRY' && type(temperature) == 'int' && msg.temperature > 50 && metadata.deviceType == 'sensor' && id != ''"}},
+{"id":"t","type":"end","configuration":{"script":"{'alarm': true, 'ts': ts, 'dataType': dataType}"}},
+{"id":"n","type":"end","configuration":{"script":"{'alarm': false}"}}
+],"connections":[
+{"fromId":"s","toId":"f","type":"default"},
+{"fromId":"f","toId":"t","type":"true"},
+{"fromId":"f","toId":"n","type":"false"}
+]}}`
+
+// TestExprMessageVars checks that expr scripts see the message type, data type, metadata, id and timestamp
+// next to the input fields, and keep the type builtin.
+func TestExprMessageVars(t *testing.T) {
+	chainEngine, err := NewChainEngine([]byte(messageVarsChain))
+	assert.Nil(t, err)
+	defer chainEngine.Stop()
+
+	builder := types.NewMsgBuilder().
+		WithTs(1700000000000).
+		WithType("TELEMETRY").
+		WithMetadata(types.Properties{"deviceType": "sensor"}).
+		WithData(map[string]any{"temperature": 60, types.DataTypeKey: "JSON"})
+	msg := builder.Build()
+	assert.Nil(t, chainEngine.OnMsg(context.Background(), msg))
+	assert.Equal(t, map[string]any{"alarm": true, "ts": int64(1700000000000), "dataType": "JSON"}, msg.GetChainOutput())
+
+	msg = builder.WithType("ATTRIBUTES").Build()
+	assert.Nil(t, chainEngine.OnMsg(context.Background(), msg))
+	assert.Equal(t, false, msg.GetChainOutput()["alarm"])
+
+	// An input field of the same name takes precedence
+	msg = builder.WithType("TELEMETRY").WithData(map[string]any{"temperature": 60, types.IdKey: ""}).Build()
+	assert.Nil(t, chainEngine.OnMsg(context.Background(), msg))
+	assert.Equal(t, false, msg.GetChainOutput()["alarm"])
+}
+
+// TestProfiling checks that a CPU profile is written while profiling and that a second profile cannot start.
+func TestProfiling(t *testing.T) {
+	chainEngine, err := NewChainEngine([]byte(traceChain))
+	assert.Nil(t, err)
+	defer chainEngine.Stop()
+	profiled := chainEngine.(*ChainEngine)
+
+	var profile bytes.Buffer
+	assert.Nil(t, profiled.EnableProfiling(&profile))
+	assert.NotNil(t, profiled.EnableProfiling(io.Discard))
+	for i := 0; i < 100; i++ {
+		assert.Nil(t, chainEngine.OnMsg(context.Background(), types.NewRuleMsg("", 0, map[string]any{"amount": i})))
+	}
+	profiled.DisableProfiling()
+	assert.True(t, profile.Len() > 0)
+	assert.Nil(t, profiled.EnableProfiling(io.Discard))
+	profiled.DisableProfiling()
+}
+
+// TestLoadAll checks that chains load in parallel into the pool and that a failing chain does not stop the others.
+func TestLoadAll(t *testing.T) {
+	jsChain := `{"id":"js%d","name":"js","metadata":{"nodes":[
+{"id":"s","type":"start"},
+{"id":"f","type":"jsFilter","configuration":{"script":"return msg.amount > %d;"}},
+{"id":"e","type":"end"}
+],"connections":[
+{"fromId":"s","toId":"f","type":"default"},
+{"fromId":"f","toId":"e","type":"true"},
+{"fromId":"f","toId":"e","type":"false"}
+]}}`
+	defs := map[string][]byte{"bad": []byte(`{"id":"bad"}`)}
+	for i := 0; i < 16; i++ {
+		defs[fmt.Sprintf("trace%d", i)] = []byte(strings.Replace(traceChain, `"id":"trace"`, fmt.Sprintf(`"id":"trace%d"`, i), 1))
+		defs[fmt.Sprintf("js%d", i)] = []byte(fmt.Sprintf(jsChain, i, i))
+	}
+	pool := NewPool()
+	result := pool.LoadAll(defs, 4, WithConfig(NewConfig()))
+	assert.Equal(t, 32, len(result.Engines))
+	_, ok := pool.Get("trace3")
+	assert.True(t, ok)
+	assert.Equal(t, 1, len(result.Errors))
+	assert.NotNil(t, result.Errors["bad"])
+	assert.True(t, result.Elapsed > 0)
+	for _, ruleEngine := range result.Engines {
+		assert.Nil(t, ruleEngine.OnMsg(context.Background(), types.NewRuleMsg("", 0, map[string]any{"amount": 8})))
+		ruleEngine.Stop()
+	}
+}
+
+const haltChain = `{"id":"halt","name":"halt","metadata":{"nodes":[
+{"id":"s","type":"start"},
+{"id":"sp","type":"split","configuration":{"field":"items"}},
+{"id":"f","type":"exprFilter","configuration":{"script":"item > 3"}},
+{"id":"h","type":"halt","configuration":{"action":"reject","reason":"too big","score":100}},
+{"id":"e","type":"end","configuration":{"script":"{'v': item}"}}
+],"connections":[
+{"fromId":"s","toId":"sp","type":"default"},
+{"fromId":"sp","toId":"f","type":"default"},
+{"fromId":"f","toId":"h","type":"true"},
+{"fromId":"f","toId":"e","type":"false"}
+]}}`
+
+// TestHalt checks that a halt node skips the remaining split branches and short-circuits the chain aggregation.
+func TestHalt(t *testing.T) {
+	chainEngine, err := NewChainEngine([]byte(haltChain))
+	assert.Nil(t, err)
+	defer chainEngine.Stop()
+
+	msg := types.NewRuleMsg("", 0, map[string]any{"items": []any{1, 5, 2}})
+	assert.Nil(t, chainEngine.OnMsg(types.ContextWithTrace(context.Background()), msg))
+	assert.True(t, msg.Halted())
+	assert.Equal(t, map[string]any{"terminate": true, "action": "reject", "reason": "too big", "score": 100}, msg.GetChainOutput())
+	// start and split, then filter and end or halt for the first two items only
+	assert.Equal(t, 6, len(msg.Traces()))
+
+	msg = types.NewRuleMsg("", 0, map[string]any{"items": []any{1, 2}})
+	assert.Nil(t, chainEngine.OnMsg(context.Background(), msg))
+	assert.False(t, msg.Halted())
+	assert.Equal(t, 2, len(msg.GetChainOutput()[types.SplitResultsKey].([]map[string]any)))
+
+	aggregation := `{"id":"halted","name":"halted","metadata":{"chains":[` + haltChain + `,` +
+		strings.Replace(haltChain, `"id":"halt","name":"halt"`, `"id":"next","name":"next"`, 1) + `]}}`
+	var chainIds []string
+	aggregationEngine, err := NewChainAggregationEngine([]byte(aggregation), WithConfig(NewConfig(
+		types.WithChainResult(func(ctx context.Context, chainId string, result types.ChainResult, err error) {
+			chainIds = append(chainIds, chainId)
+		}))))
+	assert.Nil(t, err)
+	defer aggregationEngine.Stop()
+	result, err := aggregationEngine.OnMsgAndWait(context.Background(), types.NewRuleMsg("", 0, map[string]any{"items": []any{5}}))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"halt"}, chainIds)
+	assert.True(t, result.Terminate)
+	assert.Equal(t, "reject", result.Action)
+}
+
+const priorityChain = `{"id":"priority","name":"priority","metadata":{"nodes":[
+{"id":"s","type":"start"},
+{"id":"w","type":"exprSwitch","configuration":{"script":"amount > 10 ? 'big' : 'default'"}},
+{"id":"low","type":"end","configuration":{"script":"{'to': 'low'}"}},
+{"id":"high","type":"end","configuration":{"script":"{'to': 'high'}"}},
+{"id":"top","type":"end","configuration":{"script":"{'to': 'top'}"}}
+],"connections":[
+{"fromId":"s","toId":"w","type":"default"},
+{"fromId":"w","toId":"low","type":"big"},
+{"fromId":"w","toId":"high","type":"big","priority":1},
+{"fromId":"w","toId":"top","type":"big","condition":"amount > 100","priority":2},
+{"fromId":"w","toId":"low","type":"default"}
+]}}`
+
+// TestConnectionPriority checks that the matching connection with the highest priority is followed
+// and that unguarded connections of the same relation type need distinct priorities.
+func TestConnectionPriority(t *testing.T) {
+	chainEngine, err := NewChainEngine([]byte(priorityChain))
+	assert.Nil(t, err)
+	defer chainEngine.Stop()
+	for amount, to := range map[int]string{200: "top", 20: "high", 5: "low"} {
+		msg := types.NewRuleMsg("", 0, map[string]any{"amount": amount})
+		assert.Nil(t, chainEngine.OnMsg(context.Background(), msg))
+		assert.Equal(t, to, msg.GetChainOutput()["to"])
+	}
+
+	ambiguous := strings.Replace(priorityChain, `"type":"big","priority":1`, `"type":"big"`, 1)
+	_, err = NewChainEngine([]byte(ambiguous))
+	assert.NotNil(t, err)
+	assert.True(t, strings.Contains(err.Error(), "优先级相同的无条件 big 连接"))
+
+	ambiguous = strings.Replace(ambiguous, `"connections":[`, `"allowAmbiguousConnections":true,"connections":[`, 1)
+	chainEngine, err = NewChainEngine([]byte(ambiguous))
+	assert.Nil(t, err)
+	defer chainEngine.Stop()
+	msg := types.NewRuleMsg("", 0, map[string]any{"amount": 20})
+	assert.Nil(t, chainEngine.OnMsg(context.Background(), msg))
+	assert.Equal(t, "low", msg.GetChainOutput()["to"])
+}
+
+// typedNode is a node component with a configurable type, for the registry tests.
+type typedNode struct {
+	nodeType types.NodeType
+}
+
+func (x *typedNode) Type() types.NodeType {
+	return x.nodeType
+}
+
+func (x *typedNode) Category() string {
+	return types.CategoryOther
+}
+
+func (x *typedNode) New() types.Node {
+	return &typedNode{nodeType: x.nodeType}
+}
+
+func (x *typedNode) Init(ruleConfig types.Config, configuration types.Configuration) error {
+	return nil
+}
+
+func (x *typedNode) OnMsg(ctx context.Context, msg types.RuleMsg) (string, error) {
+	return types.DefaultRelationType, nil
+}
+
+func (x *typedNode) Destroy() {
+}
+
+// TestRegisterComponentType checks that components with an invalid type are rejected at registration.
+func TestRegisterComponentType(t *testing.T) {
+	registry := new(RuleComponentRegistry)
+	for _, nodeType := range []types.NodeType{"", "my node", "my\tnode", ":node", "ns:", "a:b:c"} {
+		assert.NotNil(t, registry.Register(&typedNode{nodeType: nodeType}), nodeType)
+		assert.NotNil(t, registry.RegisterOrReplace(&typedNode{nodeType: nodeType}), nodeType)
+	}
+	assert.NotNil(t, registry.Register(nil))
+	assert.Nil(t, registry.Register(&typedNode{nodeType: "myNode"}))
+	assert.Nil(t, registry.Register(&typedNode{nodeType: "ns:myNode"}))
+	assert.Equal(t, 2, len(registry.GetComponents()))
+}
+
+const evalErrorChain = `{"id":"evalError","name":"evalError","metadata":{"nodes":[
+{"id":"s","type":"start"},
+{"id":"f","type":"exprFilter","configuration":{"script":"amount > 10","onEvalError":"error"}},
+{"id":"t","type":"end","configuration":{"script":"{'to': 'true'}"}},
+{"id":"x","type":"end","configuration":{"script":"{'to': 'false'}"}},
+{"id":"r","type":"end","configuration":{"script":"{'to': 'error', 'error': priVars.error}"}}
+],"connections":[
+{"fromId":"s","toId":"f","type":"default"},
+{"fromId":"f","toId":"t","type":"true"},
+{"fromId":"f","toId":"x","type":"false"},
+{"fromId":"f","toId":"r","type":"error"}
+]}}`
+
+// TestFilterEvalError checks that an exprFilter node routing its evaluation errors needs an error connection.
+func TestFilterEvalError(t *testing.T) {
+	chainEngine, err := NewChainEngine([]byte(evalErrorChain))
+	assert.Nil(t, err)
+	defer chainEngine.Stop()
+	msg := types.NewRuleMsg("", 0, map[string]any{"amount": "n/a"})
+	assert.Nil(t, chainEngine.OnMsg(context.Background(), msg))
+	assert.Equal(t, "error", msg.GetChainOutput()["to"])
+	assert.NotEqual(t, "", msg.GetChainOutput()["error"])
+
+	noErrorRelation := strings.NewReplacer(`,
+{"fromId":"f","toId":"r","type":"error"}`, ``, `,
+{"id":"r","type":"end","configuration":{"script":"{'to': 'error', 'error': priVars.error}"}}`, ``).Replace(evalErrorChain)
+	_, err = NewChainEngine([]byte(noErrorRelation))
+	assert.NotNil(t, err)
+	chainEngine, err = NewChainEngine([]byte(strings.Replace(noErrorRelation, `"onEvalError":"error"`, `"onEvalError":"false"`, 1)))
+	assert.Nil(t, err)
+	chainEngine.Stop()
+}
+
+// TestLintChain checks that the lint reports the script, condition and structure problems with their node ids.
+func TestLintChain(t *testing.T) {
+	assert.Equal(t, 0, len(LintChain([]byte(traceChain))))
+
+	dsl := strings.NewReplacer(`amount * 2`, `amount *`, `"type":"default"}
+]`, `"type":"default","condition":"amount >"},
+{"fromId":"s","toId":"e","type":"default"}
+]`).Replace(traceChain)
+	dsl = strings.Replace(dsl, `{"id":"e","type":"end"`, `{"id":"x","type":"end"},
+{"id":"e","type":"end"`, 1)
+	counts := map[string]int{}
+	for _, issue := range LintChain([]byte(dsl)) {
+		counts[issue.NodeId+"/"+string(issue.Category)]++
+	}
+	// the script of a and the condition of its connection
+	assert.Equal(t, 2, counts["a/"+string(LintCategoryCompile)])
+	assert.Equal(t, 1, counts["x/"+string(aspect.ValidationCategoryUnreachable)])
+
+	issues := LintChain([]byte("{"))
+	assert.Equal(t, 1, len(issues))
+	assert.Equal(t, aspect.ValidationCategoryStructure, issues[0].Category)
+
+	// the custom component is only known with the registry option, the set file is not loaded
+	registry := Registry.Clone()
+	assert.Nil(t, registry.Register(&relationsNode{typedNode: typedNode{nodeType: "linted"}, relations: []string{types.DefaultRelationType}}))
+	assert.True(t, len(LintChain([]byte(lintFileChain))) > 0)
+	assert.Equal(t, 0, len(LintChain([]byte(lintFileChain), types.WithComponentsRegistry(registry))))
+	issues = LintChain([]byte(strings.Replace(lintFileChain, `"1m"`, `"soon"`, 1)), types.WithComponentsRegistry(registry))
+	assert.Equal(t, 1, len(issues))
+	assert.Equal(t, "m", issues[0].NodeId)
+}
+
+const lintFileChain = `{"id":"lint","name":"lint","metadata":{"nodes":[
+{"id":"s","type":"start"},
+{"id":"m","type":"membership","configuration":{"field":"user","file":"/nonexistent/blacklist.txt","refreshInterval":"1m"}},
+{"id":"c","type":"linted"},
+{"id":"e","type":"end"}
+],"connections":[
+{"fromId":"s","toId":"m","type":"default"},
+{"fromId":"m","toId":"c","type":"true"},
+{"fromId":"m","toId":"e","type":"false"},
+{"fromId":"c","toId":"e","type":"default"}
+]}}`
+
+// TestDeadline checks that the context deadline becomes the message deadline, which the waitUntil node
+// routes to the deadlineExceeded relation when it cannot release the message before it.
+func TestDeadline(t *testing.T) {
+	dsl := strings.NewReplacer(`{"id":"e","type":"end"`, `{"id":"late","type":"end","configuration":{"script":"{'late': true}"}},
+{"id":"e","type":"end"`, `{"fromId":"d","toId":"e","type":"default"}`, `{"fromId":"d","toId":"e","type":"default"},
+{"fromId":"d","toId":"late","type":"deadlineExceeded"}`).Replace(waitUntilChain)
+	chainEngine, err := NewChainEngine([]byte(dsl))
+	assert.Nil(t, err)
+	defer chainEngine.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	msg := types.NewRuleMsg("", 0, map[string]any{"at": time.Now().Add(time.Second).UnixMilli()})
+	assert.Nil(t, chainEngine.OnMsg(ctx, msg))
+	assert.Equal(t, true, msg.GetChainOutput()["late"])
+	_, ok := msg.Deadline()
+	assert.True(t, ok)
+
+	msg = types.NewRuleMsg("", 0, map[string]any{"at": time.Now().Add(10 * time.Millisecond).UnixMilli()})
+	assert.Nil(t, chainEngine.OnMsg(types.ContextWithSync(context.Background()), msg))
+	assert.Equal(t, true, msg.GetChainOutput()["done"])
+	_, ok = msg.Deadline()
+	assert.False(t, ok)
+}
+
+// TestAggregationExpr checks that the aggregation expressions see the outputs of every chain.
+func TestAggregationExpr(t *testing.T) {
+	dsl := strings.Replace(scoredAggregation, `"name":"scored",`, `"name":"scored","configuration":{"aggregation":{
+"thresholds":[{"minScore":0,"action":"ACCEPT"},{"minScore":20,"action":"REJECT","label":"high"}],
+"scoreExpr":"chains.first.score > chains.second.score ? score * 2 : score",
+"actionExpr":"label == 'high' && amount < 100 ? 'REVIEW' : ''"}},`, 1)
+	aggregationEngine, err := NewChainAggregationEngine([]byte(dsl))
+	assert.Nil(t, err)
+	defer aggregationEngine.Stop()
+
+	result, err := aggregationEngine.OnMsgAndWait(context.Background(), types.NewRuleMsg("", 0, map[string]any{"amount": 50}))
+	assert.Nil(t, err)
+	assert.Equal(t, 30, result.Score)
+	assert.Equal(t, "REVIEW", result.Action)
+
+	result, err = aggregationEngine.OnMsgAndWait(context.Background(), types.NewRuleMsg("", 0, map[string]any{"amount": 500}))
+	assert.Nil(t, err)
+	assert.Equal(t, "REJECT", result.Action)
+
+	_, err = NewChainAggregationEngine([]byte(strings.Replace(dsl, `score * 2`, `score *`, 1)))
+	assert.NotNil(t, err)
+}
+
+// TestDebugSampling checks that the chain and node debug aspects sample a message once and agree on it.
+func TestDebugSampling(t *testing.T) {
+	chainDebug := &aspect.ChainDebug{SampleRate: 0.5}
+	nodeDebug := &aspect.NodeDebug{SampleRate: 0.5}
+	sampled := 0
+	for i := 0; i < 1000; i++ {
+		msg := types.NewRuleMsg("", 0, nil)
+		logged := chainDebug.PointCut(nil, msg)
+		for j := 0; j < 3; j++ {
+			assert.Equal(t, logged, nodeDebug.PointCut(nil, msg, types.DefaultRelationType))
+		}
+		if logged {
+			sampled++
+		}
+	}
+	assert.True(t, sampled > 350 && sampled < 650)
+	assert.True(t, (&aspect.NodeDebug{}).PointCut(nil, types.NewRuleMsg("", 0, nil), types.DefaultRelationType))
+}
+
+// relationsNode is a node component declaring its relations, for the validation tests.
+type relationsNode struct {
+	typedNode
+	relations []string
+}
+
+func (x *relationsNode) Relations() []string {
+	return x.relations
+}
+
+const declaredRelationsChain = `{"id":"declared","name":"declared","metadata":{"nodes":[
+{"id":"s","type":"start"},
+{"id":"n","type":"declared"},
+{"id":"e","type":"end"}
+],"connections":[
+{"fromId":"s","toId":"n","type":"default"},
+{"fromId":"n","toId":"e","type":"default"},
+{"fromId":"n","toId":"e","type":"RELATION"}
+]}}`
+
+// TestDeclaredRelations checks that the connections are validated against the relations the components declare.
+func TestDeclaredRelations(t *testing.T) {
+	for nodeType, node := range Registry.GetComponents() {
+		_, ok := node.(types.RelationsGetter)
+		assert.True(t, ok, nodeType)
+	}
+
+	registry := Registry.Clone()
+	assert.Nil(t, registry.Register(&relationsNode{typedNode: typedNode{nodeType: "declared"}, relations: []string{types.DefaultRelationType, "ok"}}))
+	config := NewConfig(types.WithComponentsRegistry(registry))
+	for relation, valid := range map[string]bool{"ok": true, "nope": false} {
+		chain, err := config.Parser.DecodeChain([]byte(strings.Replace(declaredRelationsChain, "RELATION", relation, 1)))
+		assert.Nil(t, err)
+		errs := aspect.ValidateChainAll(&chain, registry)
+		assert.Equal(t, valid, len(errs) == 0, relation)
+		if !valid {
+			assert.True(t, strings.Contains(errs[0].Message, relation))
+		}
+	}
+}
+
+// TestReplayClock checks that a replay clock drives the expr now() function and the waitUntil node from the message timestamps.
+func TestReplayClock(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := types.NewReplayClock(time.Time{})
+	config := NewConfig(types.WithClock(clock))
+	nowChain := strings.Replace(waitUntilChain, "{'done': true}", "{'now': now().UnixMilli()}", 1)
+	chainEngine, err := NewChainEngine([]byte(nowChain), WithConfig(config))
+	assert.Nil(t, err)
+	defer chainEngine.Stop()
+
+	msg := types.NewRuleMsg("", start.UnixMilli(), map[string]any{"at": start.Add(-time.Minute).UnixMilli()})
+	assert.Nil(t, chainEngine.OnMsg(context.Background(), msg))
+	assert.Equal(t, start.UnixMilli(), msg.GetChainOutput()["now"])
+
+	// The held message is released when the clock reaches its target time, not after an hour of wall time
+	msg = types.NewRuleMsg("", start.Add(time.Minute).UnixMilli(), map[string]any{"at": start.Add(time.Hour).UnixMilli()})
+	done := make(chan error, 1)
+	go func() {
+		done <- chainEngine.OnMsg(types.ContextWithSync(context.Background()), msg)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	clock.Advance(start.Add(30 * time.Minute))
+	select {
+	case <-done:
+		t.Fatal("message released before its target time")
+	case <-time.After(20 * time.Millisecond):
+	}
+	clock.Advance(start.Add(2 * time.Hour))
+	assert.Nil(t, <-done)
+	assert.Equal(t, start.Add(2*time.Hour).UnixMilli(), msg.GetChainOutput()["now"])
+}
+
+// TestAssertAspect checks that the assert aspect reports the unmet node, relation and chain output expectations.
+func TestAssertAspect(t *testing.T) {
+	asserts := aspect.NewAssertAspect(
+		aspect.Expectation{NodeId: "a", Key: "doubled", Value: 4.0},
+		aspect.Expectation{NodeId: "a", Relation: types.DefaultRelationType},
+		aspect.Expectation{Key: "result", Value: 4},
+	)
+	chainEngine, err := NewChainEngine([]byte(traceChain), WithAspects(asserts, asserts.NodeAspect()))
+	assert.Nil(t, err)
+	defer chainEngine.Stop()
+
+	assert.Nil(t, chainEngine.OnMsg(context.Background(), types.NewRuleMsg("", 0, map[string]any{"amount": 2})))
+	assert.Nil(t, asserts.Err())
+	assert.Nil(t, chainEngine.OnMsg(context.Background(), types.NewRuleMsg("", 0, map[string]any{"amount": 3})))
+	failures := asserts.Failures()
+	assert.Equal(t, 2, len(failures))
+	assert.Equal(t, 6, failures[0].Actual)
+	assert.Equal(t, "result", failures[1].Expectation.Key)
+	assert.NotNil(t, asserts.Err())
+	asserts.Reset()
+	assert.Nil(t, asserts.Err())
+
+	missing := aspect.NewAssertAspect(aspect.Expectation{NodeId: "x", Key: "doubled", Value: 4})
+	missing.FailFast = true
+	missingEngine, err := NewChainEngine([]byte(traceChain), WithAspects(missing, missing.NodeAspect()))
+	assert.Nil(t, err)
+	defer missingEngine.Stop()
+	assert.Nil(t, missingEngine.OnMsg(context.Background(), types.NewRuleMsg("", 0, map[string]any{"amount": 2})))
+	assert.Equal(t, 1, len(missing.Failures()))
+	assert.True(t, strings.Contains(missing.Failures()[0].Reason, "did not run"))
+
+	failFast := aspect.NewAssertAspect(aspect.Expectation{NodeId: "a", Key: "doubled", Value: 5})
+	failFast.FailFast = true
+	failFastEngine, err := NewChainEngine([]byte(traceChain), WithAspects(failFast, failFast.NodeAspect()))
+	assert.Nil(t, err)
+	defer failFastEngine.Stop()
+	assert.NotNil(t, failFastEngine.OnMsg(context.Background(), types.NewRuleMsg("", 0, map[string]any{"amount": 2})))
+}
+
+// errorRecorder is a testing.TB recording the reported errors and running the cleanups on demand.
+type errorRecorder struct {
+	testing.TB
+	errors   []string
+	cleanups []func()
+}
+
+func (r *errorRecorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *errorRecorder) Cleanup(f func()) {
+	r.cleanups = append(r.cleanups, f)
+}
+
+// TestAssertAspects checks that testutil.AssertAspects reports the unmet expectations to the test when it ends.
+func TestAssertAspects(t *testing.T) {
+	recorder := &errorRecorder{TB: t}
+	chainEngine, err := NewChainEngine([]byte(traceChain), WithAspects(testutil.AssertAspects(recorder,
+		aspect.Expectation{NodeId: "a", Key: "doubled", Value: 4},
+		aspect.Expectation{Key: "result", Value: 4},
+	)...))
+	assert.Nil(t, err)
+	defer chainEngine.Stop()
+
+	assert.Nil(t, chainEngine.OnMsg(context.Background(), types.NewRuleMsg("", 0, map[string]any{"amount": 2})))
+	assert.Nil(t, chainEngine.OnMsg(context.Background(), types.NewRuleMsg("m3", 0, map[string]any{"amount": 3})))
+	assert.Equal(t, 0, len(recorder.errors))
+	for _, cleanup := range recorder.cleanups {
+		cleanup()
+	}
+	assert.Equal(t, 2, len(recorder.errors))
+	assert.True(t, strings.HasPrefix(recorder.errors[0], "msg m3: node a doubled is 6"))
+}
+
+// TestReloadKeepsChainOnError checks that a definition failing to load leaves the current chain serving.
+func TestReloadKeepsChainOnError(t *testing.T) {
+	chainEngine, err := NewChainEngine([]byte(traceChain))
+	assert.Nil(t, err)
+	defer chainEngine.Stop()
+	dsl := chainEngine.DSL()
+
+	badNode := strings.Replace(traceChain, `{"id":"e","type":"end"`, `{"id":"f","type":"fingerprint","configuration":{"fields":["amount"],"algorithm":"crc"}},
+{"id":"e","type":"end"`, 1)
+	badNode = strings.Replace(badNode, `{"fromId":"a","toId":"e","type":"default"}`, `{"fromId":"a","toId":"f","type":"default"},
+{"fromId":"f","toId":"e","type":"default"}`, 1)
+	assert.NotNil(t, chainEngine.ReloadSelf([]byte(badNode)))
+	assert.Equal(t, string(dsl), string(chainEngine.DSL()))
+
+	msg := types.NewRuleMsg("", 0, map[string]any{"amount": 2})
+	assert.Nil(t, chainEngine.OnMsg(context.Background(), msg))
+	assert.Equal(t, 4, msg.GetChainOutput()["result"])
+
+	assert.Nil(t, chainEngine.ReloadSelf([]byte(strings.Replace(traceChain, "amount * 2", "amount * 3", 1))))
+	msg = types.NewRuleMsg("", 0, map[string]any{"amount": 2})
+	assert.Nil(t, chainEngine.OnMsg(context.Background(), msg))
+	assert.Equal(t, 6, msg.GetChainOutput()["result"])
+}
+
+const runChainScoring = `{"id":"scoring","name":"scoring","metadata":{"nodes":[
+{"id":"s","type":"start"},
+{"id":"e","type":"end","configuration":{"script":"{'score': amount * 10}"}}
+],"connections":[
+{"fromId":"s","toId":"e","type":"default"}
+]}}`
+
+const runChainCaller = `{"id":"CHAIN","name":"caller","metadata":{"nodes":[
+{"id":"s","type":"start"},
+{"id":"a","type":"exprAssign","configuration":{"script":"{'score': runChain('CALLED', {'amount': amount}).score}"}},
+{"id":"e","type":"end","configuration":{"script":"{'score': priVars.score}"}}
+],"connections":[
+{"fromId":"s","toId":"a","type":"default"},
+{"fromId":"a","toId":"e","type":"default"}
+]}}`
+
+// TestRunChain checks that expressions run the chains of the engine pool and that recursion is bounded.
+func TestRunChain(t *testing.T) {
+	pool := NewPool()
+	config := NewConfig(types.WithEnginePool(pool))
+	newEngine := func(dsl string) types.Engine {
+		chainEngine, err := NewChainEngine([]byte(dsl), WithConfig(config))
+		assert.Nil(t, err)
+		pool.Add(chainEngine)
+		return chainEngine
+	}
+	scoring := newEngine(runChainScoring)
+	defer scoring.Stop()
+	caller := newEngine(strings.NewReplacer("CHAIN", "caller", "CALLED", "scoring").Replace(runChainCaller))
+	defer caller.Stop()
+
+	msg := types.NewRuleMsg("", 0, map[string]any{"amount": 3})
+	assert.Nil(t, caller.OnMsg(context.Background(), msg))
+	assert.Equal(t, 30, msg.GetChainOutput()["score"])
+
+	missing := newEngine(strings.NewReplacer("CHAIN", "missing", "CALLED", "nope").Replace(runChainCaller))
+	defer missing.Stop()
+	assert.NotNil(t, missing.OnMsg(context.Background(), types.NewRuleMsg("", 0, map[string]any{"amount": 3})))
+
+	loop := newEngine(strings.NewReplacer("CHAIN", "loop", "CALLED", "loop").Replace(runChainCaller))
+	defer loop.Stop()
+	err := loop.OnMsg(context.Background(), types.NewRuleMsg("", 0, map[string]any{"amount": 3}))
+	assert.NotNil(t, err)
+	assert.True(t, strings.Contains(err.Error(), "nested more than"))
+
+	// The called chain runs with the context of the caller, so it is cancelled along with it
+	waiting := newEngine(strings.Replace(waitUntilChain, `"id":"waitUntil"`, `"id":"waiting"`, 1))
+	defer waiting.Stop()
+	waiter := newEngine(strings.NewReplacer("CHAIN", "waiter", "CALLED", "waiting", "{'amount': amount}", "{'at': at}").Replace(runChainCaller))
+	defer waiter.Stop()
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	err = waiter.OnMsg(ctx, types.NewRuleMsg("", 0, map[string]any{"at": time.Now().Add(time.Hour).UnixMilli()}))
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
+const runChainNested = `{"id":"nested","name":"nested","metadata":{"nodes":[
+{"id":"s","type":"start"},
+{"id":"b","type":"blocking"},
+{"id":"a","type":"exprAssign","configuration":{"script":"{'score': inner ? 1 : runChain('nested', {'inner': true}).score + 1}"}},
+{"id":"e","type":"end","configuration":{"script":"{'score': priVars.score}"}}
+],"connections":[
+{"fromId":"s","toId":"b","type":"default"},
+{"fromId":"b","toId":"a","type":"default"},
+{"fromId":"a","toId":"e","type":"default"}
+]}}`
+
+// TestRunChainDuringReload checks that a chain calling itself with runChain completes when the engine
+// is reloaded while the message is running.
+func TestRunChainDuringReload(t *testing.T) {
+	node := &blockingNode{typedNode: typedNode{nodeType: "blocking"}, started: make(chan struct{}), release: make(chan error)}
+	registry := Registry.Clone()
+	assert.Nil(t, registry.Register(node))
+	pool := NewPool()
+	chainEngine, err := NewChainEngine([]byte(runChainNested), WithConfig(NewConfig(types.WithEnginePool(pool),
+		types.WithComponentsRegistry(registry))))
+	assert.Nil(t, err)
+	defer chainEngine.Stop()
+	pool.Add(chainEngine)
+
+	msg := types.NewRuleMsg("", 0, map[string]any{"inner": false})
+	done := make(chan error)
+	go func() {
+		done <- chainEngine.OnMsg(context.Background(), msg)
+	}()
+	<-node.started
+	reloaded := make(chan error)
+	go func() {
+		reloaded <- chainEngine.ReloadSelf([]byte(runChainNested))
+	}()
+	select {
+	case err := <-reloaded:
+		assert.Nil(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("reload blocked by a running message")
+	}
+	node.release <- nil
+	<-node.started
+	node.release <- nil
+	select {
+	case err := <-done:
+		assert.Nil(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("nested runChain blocked by the reload")
+	}
+	assert.Equal(t, 2, msg.GetChainOutput()["score"])
+}
+
+const tenantChain = `{"id":"tenant","name":"tenant","metadata":{"nodes":[
+{"id":"s","type":"start"},
+{"id":"n","type":"tenantOnly"},
+{"id":"e","type":"end"}
+],"connections":[
+{"fromId":"s","toId":"n","type":"default"},
+{"fromId":"n","toId":"e","type":"default"}
+]}}`
+
+// TestTenantRegistry checks that the engines of a tenant create their nodes from the registry of the tenant,
+// and that an engine whose tenant has no registry fails to load.
+func TestTenantRegistry(t *testing.T) {
+	acme := Registry.Clone()
+	assert.Nil(t, acme.Register(&relationsNode{typedNode: typedNode{nodeType: "tenantOnly"}, relations: []string{types.DefaultRelationType}}))
+	config := NewConfig(types.WithRegistryProvider(func(tenant string) types.ComponentRegistry {
+		if tenant == "acme" {
+			return acme
+		}
+		return nil
+	}))
+
+	chainEngine, err := NewChainEngine([]byte(tenantChain), WithConfig(config), WithTenant("acme"))
+	assert.Nil(t, err)
+	defer chainEngine.Stop()
+	assert.Nil(t, chainEngine.OnMsg(context.Background(), types.NewRuleMsg("", 0, map[string]any{})))
+
+	_, err = NewChainEngine([]byte(tenantChain), WithConfig(config), WithTenant("other"))
+	assert.True(t, errors.Is(err, types.ErrTenantRegistryNotFound))
+	_, err = NewChainEngine([]byte(tenantChain), WithConfig(config))
+	assert.NotNil(t, err)
+	_, err = NewChainEngine([]byte(tenantChain), WithTenant("acme"))
+	assert.True(t, errors.Is(err, types.ErrTenantRegistryNotFound))
+}
+
+const resultChain = `{"id":"result","name":"result","metadata":{"nodes":[
+{"id":"s","type":"start"},
+{"id":"a","type":"exprFilter","configuration":{"script":"amount > 1000 && addScore(20) > 0 && addReason('large amount')"}},
+{"id":"b","type":"exprAssign","configuration":{"script":"{'total': addScore(country != 'CN' ? 15 : 0), 'tagged': addTag('foreign')}"}},
+{"id":"e","type":"end","configuration":{"script":"{'action': result.score >= 30 ? 'review' : 'pass'}"}}
+],"connections":[
+{"fromId":"s","toId":"a","type":"default"},
+{"fromId":"a","toId":"b","type":"true"},
+{"fromId":"a","toId":"b","type":"false"},
+{"fromId":"b","toId":"e","type":"default"}
+]}}`
+
+// TestResultAccumulation checks that the nodes of a chain accumulate a result read by the end node.
+func TestResultAccumulation(t *testing.T) {
+	chainEngine, err := NewChainEngine([]byte(resultChain))
+	assert.Nil(t, err)
+	defer chainEngine.Stop()
+
+	msg := types.NewRuleMsg("", 0, map[string]any{"amount": 5000, "country": "US"})
+	assert.Nil(t, chainEngine.OnMsg(context.Background(), msg))
+	output := msg.GetChainOutput()
+	assert.Equal(t, "review", output["action"])
+	assert.Equal(t, 35, output[types.ScoreKey])
+	assert.Equal(t, []string{"large amount"}, output[types.ReasonsKey])
+	assert.Equal(t, []string{"foreign"}, output[types.TagsKey])
+	assert.Equal(t, 35, msg.Result().Score)
+
+	msg = types.NewRuleMsg("", 0, map[string]any{"amount": 10, "country": "CN"})
+	assert.Nil(t, chainEngine.OnMsg(context.Background(), msg))
+	output = msg.GetChainOutput()
+	assert.Equal(t, "pass", output["action"])
+	assert.Nil(t, output[types.ScoreKey])
+	assert.Equal(t, []string{"foreign"}, output[types.TagsKey])
+}
+
+// TestTypeAliases checks that node types are resolved through the type aliases and that aliases are validated.
+func TestTypeAliases(t *testing.T) {
+	aliased := strings.Replace(traceChain, `"type":"exprAssign"`, `"type":"assign"`, 1)
+	_, err := NewChainEngine([]byte(aliased))
+	assert.NotNil(t, err)
+
+	config := NewConfig(types.WithTypeAliases(map[types.NodeType]types.NodeType{"assign": types.RuleSubTypeExprAssign, "end": "nope"}))
+	_, err = NewChainEngine([]byte(aliased), WithConfig(config))
+	assert.NotNil(t, err)
+	assert.True(t, strings.Contains(err.Error(), "nope"))
+
+	config.TypeAliases = map[types.NodeType]types.NodeType{"assign": types.RuleSubTypeExprAssign, "end": types.RuleSubTypeHalt}
+	chainEngine, err := NewChainEngine([]byte(aliased), WithConfig(config))
+	assert.Nil(t, err)
+	defer chainEngine.Stop()
+	msg := types.NewRuleMsg("", 0, map[string]any{"amount": 2})
+	assert.Nil(t, chainEngine.OnMsg(context.Background(), msg))
+	assert.Equal(t, 4, msg.GetChainOutput()["result"])
+}
+
+// TestCancelMsg checks that an in-flight message can be cancelled by id, and that a cancelled message
+// is neither retried nor passed to the dead-letter handler.
+func TestCancelMsg(t *testing.T) {
+	var deadLetters atomic.Int32
+	config := NewConfig(types.WithRetry(2, time.Millisecond), types.WithDeadLetter(func(ctx context.Context, msg types.RuleMsg, err error) {
+		deadLetters.Add(1)
+	}))
+	ruleEngine, err := NewChainEngine([]byte(waitUntilChain), WithConfig(config))
+	assert.Nil(t, err)
+	defer ruleEngine.Stop()
+	chainEngine := ruleEngine.(*ChainEngine)
+	assert.False(t, chainEngine.Cancel("unknown"))
+
+	msg := types.NewRuleMsg("stuck", 0, map[string]any{"at": time.Now().Add(time.Hour).UnixMilli()})
+	done := make(chan error, 1)
+	go func() {
+		done <- chainEngine.OnMsg(types.ContextWithSync(context.Background()), msg)
+	}()
+	for !chainEngine.Cancel("stuck") {
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case err = <-done:
+		assert.True(t, errors.Is(err, types.ErrMsgCancelled))
+	case <-time.After(time.Second):
+		t.Fatal("cancelled message still running")
+	}
+	assert.False(t, chainEngine.Cancel("stuck"))
+	assert.Nil(t, msg.GetChainOutput()["done"])
+	assert.Equal(t, int32(0), deadLetters.Load())
+}
+
+// TestMaxPayloadSize checks that the messages larger than Config.MaxPayloadSize are rejected before entering the chain.
+func TestMaxPayloadSize(t *testing.T) {
+	chainEngine, err := NewChainEngine([]byte(traceChain), WithConfig(NewConfig(types.WithMaxPayloadSize(64))))
+	assert.Nil(t, err)
+	defer chainEngine.Stop()
+
+	msg := types.NewRuleMsg("", 0, map[string]any{"amount": 2, "note": "small"})
+	assert.Nil(t, chainEngine.OnMsg(context.Background(), msg))
+	assert.Equal(t, 4, msg.GetChainOutput()["result"])
+
+	msg = types.NewRuleMsg("", 0, map[string]any{"amount": 2, "note": strings.Repeat("x", 64)})
+	err = chainEngine.OnMsg(context.Background(), msg)
+	assert.True(t, errors.Is(err, types.ErrPayloadTooLarge))
+	assert.Nil(t, msg.GetChainOutput()["result"])
+
+	msg = types.NewRuleMsg("", 0, map[string]any{"amount": 2, "items": []int{1, 2, 3, 4, 5, 6, 7, 8}})
+	assert.True(t, errors.Is(chainEngine.OnMsg(context.Background(), msg), types.ErrPayloadTooLarge))
+}
+
+// TestOnUpdatedDiff checks that OnUpdated receives the nodes and connections changed by a reload.
+func TestOnUpdatedDiff(t *testing.T) {
+	var diffs []types.ChainDiff
+	chainEngine, err := NewChainEngine([]byte(traceChain), WithOnUpdated(func(chainId string, dsl []byte, diff types.ChainDiff) {
+		diffs = append(diffs, diff)
+	}))
+	assert.Nil(t, err)
+	defer chainEngine.Stop()
+	assert.Equal(t, 0, len(diffs))
+
+	assert.Nil(t, chainEngine.ReloadSelf([]byte(traceChain)))
+	assert.Equal(t, 1, len(diffs))
+	assert.True(t, diffs[0].Empty())
+
+	updated := strings.Replace(traceChain, "amount * 2", "amount * 3", 1)
+	updated = strings.Replace(updated, `{"id":"e","type":"end"`, `{"id":"x","type":"exprAssign","configuration":{"script":"{'tripled': priVars.doubled}"}},
+{"id":"e","type":"end"`, 1)
+	updated = strings.Replace(updated, `{"fromId":"a","toId":"e","type":"default"}`, `{"fromId":"a","toId":"x","type":"default"},
+{"fromId":"x","toId":"e","type":"default"}`, 1)
+	assert.Nil(t, chainEngine.ReloadSelf([]byte(updated)))
+	assert.Equal(t, 2, len(diffs))
+	diff := diffs[1]
+	assert.False(t, diff.ChainModified)
+	assert.Equal(t, []string{"x"}, diff.AddedNodes)
+	assert.Equal(t, 0, len(diff.RemovedNodes))
+	assert.Equal(t, []string{"a"}, diff.ModifiedNodes)
+	assert.Equal(t, []types.NodeConnection{{FromId: "a", ToId: "x", Type: "default"}, {FromId: "x", ToId: "e", Type: "default"}}, diff.AddedConnections)
+	assert.Equal(t, []types.NodeConnection{{FromId: "a", ToId: "e", Type: "default"}}, diff.RemovedConnections)
+}
+
+// TestConcurrentReloadDiff checks that concurrent reloads are serialized, so each diff is computed against
+// the chain it replaces.
+func TestConcurrentReloadDiff(t *testing.T) {
+	withX := strings.Replace(traceChain, `{"id":"e","type":"end"`, `{"id":"x","type":"exprAssign","configuration":{"script":"{'tripled': priVars.doubled}"}},
+{"id":"e","type":"end"`, 1)
+	withX = strings.Replace(withX, `{"fromId":"a","toId":"e","type":"default"}`, `{"fromId":"a","toId":"x","type":"default"},
+{"fromId":"x","toId":"e","type":"default"}`, 1)
+	// The callbacks run under the reload lock, so hasX needs no synchronization
+	var hasX, inconsistent bool
+	chainEngine, err := NewChainEngine([]byte(traceChain), WithOnUpdated(func(chainId string, dsl []byte, diff types.ChainDiff) {
+		switch {
+		case diff.Empty():
+		case len(diff.AddedNodes) == 1 && !hasX:
+			hasX = true
+		case len(diff.RemovedNodes) == 1 && hasX:
+			hasX = false
+		default:
+			inconsistent = true
+		}
+		if hasX != strings.Contains(string(dsl), `"x"`) {
+			inconsistent = true
+		}
+	}))
+	assert.Nil(t, err)
+	defer chainEngine.Stop()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				dsl := traceChain
+				if (i+j)%2 == 0 {
+					dsl = withX
+				}
+				assert.Nil(t, chainEngine.ReloadSelf([]byte(dsl)))
+			}
+		}(i)
+	}
+	wg.Wait()
+	assert.False(t, inconsistent)
+}
+
+// TestEndNodeConnection checks that an end node with an outgoing connection fails to load without the validator aspect.
+func TestEndNodeConnection(t *testing.T) {
+	dsl := strings.Replace(traceChain, `{"fromId":"a","toId":"e","type":"default"}`, `{"fromId":"a","toId":"e","type":"default"},
+{"fromId":"e","toId":"x","type":"default"}`, 1)
+	dsl = strings.Replace(dsl, `{"id":"e","type":"end"`, `{"id":"x","type":"exprAssign","configuration":{"script":"{}"}},
+{"id":"e","type":"end"`, 1)
+	config := NewConfig()
+	def, err := config.Parser.DecodeChain([]byte(dsl))
+	assert.Nil(t, err)
+	_, err = InitChainCtx(config, nil, &def)
+	assert.True(t, errors.Is(err, types.ErrEndNodeConnection))
+	assert.True(t, strings.Contains(err.Error(), "e->x"))
+}
+
+const headersChain = `{"id":"headers","name":"headers","metadata":{"nodes":[
+{"id":"s","type":"start"},
+{"id":"f","type":"jsFilter","configuration":{"script":"return headers.source === 'api';"}},
+{"id":"w","type":"exprSwitch","configuration":{"script":"headers.contentType == 'json' ? 'json' : 'default'"}},
+{"id":"j","type":"end","configuration":{"script":"{'route': 'json', 'source': headers.source}"}},
+{"id":"e","type":"end","configuration":{"script":"{'route': 'other'}"}}
+],"connections":[
+{"fromId":"s","toId":"f","type":"default"},
+{"fromId":"f","toId":"w","type":"true"},
+{"fromId":"f","toId":"e","type":"false"},
+{"fromId":"w","toId":"j","type":"json"},
+{"fromId":"w","toId":"e","type":"default"}
+]}}`
+
+// TestMsgHeaders checks that the message and context headers are available to the scripts apart from the input.
+func TestMsgHeaders(t *testing.T) {
+	chainEngine, err := NewChainEngine([]byte(headersChain))
+	assert.Nil(t, err)
+	defer chainEngine.Stop()
+
+	msg := types.NewRuleMsg("", 0, map[string]any{"amount": 1})
+	msg.SetHeader("source", "api")
+	ctx := types.ContextWithHeaders(context.Background(), map[string]string{"source": "mqtt", "contentType": "json"})
+	assert.Nil(t, chainEngine.OnMsg(ctx, msg))
+	assert.Equal(t, map[string]any{"route": "json", "source": "api"}, msg.GetChainOutput())
+	assert.Equal(t, map[string]string{"source": "api", "contentType": "json"}, msg.Headers())
+	assert.Nil(t, msg.GetInput()["headers"])
+
+	msg = types.NewRuleMsg("", 0, map[string]any{"amount": 1})
+	assert.Nil(t, chainEngine.OnMsg(context.Background(), msg))
+	assert.Equal(t, "other", msg.GetChainOutput()["route"])
+	assert.Nil(t, msg.Headers())
+}
+
+// TestFixedEnv checks that the chain runs under testutil.FixedEnv produce identical outputs, also in parallel.
+func TestFixedEnv(t *testing.T) {
+	dsl := `{"id":"fixed","name":"fixed","metadata":{"nodes":[
+{"id":"s","type":"start"},
+{"id":"e","type":"end","configuration":{"script":"{'id': id, 'ts': ts, 'now': now()}"}}
+],"connections":[
+{"fromId":"s","toId":"e","type":"default"}
+]}}`
+	run := func(t *testing.T) []byte {
+		t.Parallel()
+		config := NewConfig(testutil.FixedEnv()...)
+		chainEngine, err := NewChainEngine([]byte(dsl), WithConfig(config))
+		assert.Nil(t, err)
+		defer chainEngine.Stop()
+		msg := types.NewRuleMsg("", 0, map[string]any{"amount": 1})
+		assert.Nil(t, chainEngine.OnMsg(context.Background(), msg))
+		output := msg.GetChainOutput()
+		assert.Equal(t, "msg-1", output["id"])
+		assert.Equal(t, testutil.FixedTime.UnixMilli(), output["ts"])
+		data, err := json.Marshal(output)
+		assert.Nil(t, err)
+		return data
+	}
+	outputs := make([][]byte, 2)
+	t.Run("group", func(t *testing.T) {
+		for i := range outputs {
+			t.Run(strconv.Itoa(i), func(t *testing.T) { outputs[i] = run(t) })
+		}
+	})
+	assert.Equal(t, string(outputs[0]), string(outputs[1]))
+}
+
+// TestNodeConfigDecodeError checks that a configuration value of an incompatible type fails the chain init
+// with the node and the field in the error.
+func TestNodeConfigDecodeError(t *testing.T) {
+	dsl := strings.Replace(traceChain, `amount * 2}"}`, `amount * 2}","toInput":["yes"]}`, 1)
+	_, err := NewChainEngine([]byte(dsl))
+	var decodeErr *maps.DecodeError
+	assert.True(t, errors.As(err, &decodeErr))
+	assert.Equal(t, []string{"toInput"}, decodeErr.Fields)
+	assert.True(t, strings.Contains(err.Error(), "id:a"))
+	assert.True(t, strings.Contains(err.Error(), "'toInput' expected type 'bool'"))
+}
+
+// blockingNode is a node component that waits for release, for the concurrency tests. It fails with the
+// error received from release, if any. destroyed, when set, is closed when the node is destroyed.
+type blockingNode struct {
+	typedNode
+	started   chan struct{}
+	release   chan error
+	destroyed chan struct{}
+}
+
+func (x *blockingNode) New() types.Node {
+	return x
+}
+
+func (x *blockingNode) Relations() []string {
+	return []string{types.DefaultRelationType}
+}
+
+func (x *blockingNode) OnMsg(ctx context.Context, msg types.RuleMsg) (string, error) {
+	x.started <- struct{}{}
+	return types.DefaultRelationType, <-x.release
+}
+
+func (x *blockingNode) Destroy() {
+	if x.destroyed != nil {
+		close(x.destroyed)
+	}
+}
+
+// TestConcurrencyLimitAspect checks that a chain rejects the messages above its concurrency limit and
+// that the permit is released when a node fails.
+func TestConcurrencyLimitAspect(t *testing.T) {
+	node := &blockingNode{typedNode: typedNode{nodeType: "blocking"}, started: make(chan struct{}), release: make(chan error)}
+	registry := Registry.Clone()
+	assert.Nil(t, registry.Register(node))
+	dsl := strings.Replace(traceChain, `{"id":"a","type":"exprAssign","configuration":{"script":"{'doubled': amount * 2}"}}`, `{"id":"a","type":"blocking"}`, 1)
+	chainEngine, err := NewChainEngine([]byte(dsl), WithConfig(NewConfig(types.WithComponentsRegistry(registry))),
+		WithAspects(aspect.NewConcurrencyLimitAspect(1)))
+	assert.Nil(t, err)
+	defer chainEngine.Stop()
+
+	done := make(chan error)
+	go func() {
+		done <- chainEngine.OnMsg(context.Background(), types.NewRuleMsg("", 0, map[string]any{}))
+	}()
+	<-node.started
+	err = chainEngine.OnMsg(context.Background(), types.NewRuleMsg("", 0, map[string]any{}))
+	assert.True(t, errors.Is(err, types.ErrChainAtCapacity))
+	node.release <- errors.New("downstream failed")
+	assert.NotNil(t, <-done)
+
+	go func() {
+		<-node.started
+		node.release <- nil
+	}()
+	assert.Nil(t, chainEngine.OnMsg(context.Background(), types.NewRuleMsg("", 0, map[string]any{})))
+}
+
+// TestReloadDoesNotWaitForMessages checks that a reload swaps the chain without waiting for the messages running
+// through the current chain, which is destroyed once they have completed.
+func TestReloadDoesNotWaitForMessages(t *testing.T) {
+	node := &blockingNode{typedNode: typedNode{nodeType: "blocking"}, started: make(chan struct{}), release: make(chan error),
+		destroyed: make(chan struct{})}
+	registry := Registry.Clone()
+	assert.Nil(t, registry.Register(node))
+	dsl := strings.Replace(traceChain, `{"id":"a","type":"exprAssign","configuration":{"script":"{'doubled': amount * 2}"}}`, `{"id":"a","type":"blocking"}`, 1)
+	chainEngine, err := NewChainEngine([]byte(dsl), WithConfig(NewConfig(types.WithComponentsRegistry(registry))))
+	assert.Nil(t, err)
+	defer chainEngine.Stop()
+
+	done := make(chan error)
+	go func() {
+		done <- chainEngine.OnMsg(context.Background(), types.NewRuleMsg("", 0, map[string]any{}))
+	}()
+	<-node.started
+	assert.Nil(t, chainEngine.ReloadSelf([]byte(traceChain)))
+	msg := types.NewRuleMsg("", 0, map[string]any{"amount": 2})
+	assert.Nil(t, chainEngine.OnMsg(context.Background(), msg))
+	assert.Equal(t, 4, msg.GetChainOutput()["result"])
+	select {
+	case <-node.destroyed:
+		t.Fatal("the replaced chain was destroyed while a message was running")
+	case <-time.After(20 * time.Millisecond):
+	}
+	node.release <- nil
+	assert.Nil(t, <-done)
+	<-node.destroyed
+}
+
+// TestReset checks that Reset waits for the running messages, leaves a blank engine whose accessors do not
+// panic and keeps the aspects, and that a chain can be loaded again afterwards.
+func TestReset(t *testing.T) {
+	node := &blockingNode{typedNode: typedNode{nodeType: "blocking"}, started: make(chan struct{}), release: make(chan error)}
+	registry := Registry.Clone()
+	assert.Nil(t, registry.Register(node))
+	dsl := strings.Replace(traceChain, `{"id":"a","type":"exprAssign","configuration":{"script":"{'doubled': amount * 2}"}}`, `{"id":"a","type":"blocking"}`, 1)
+	ruleEngine, err := NewChainEngine([]byte(dsl), WithConfig(NewConfig(types.WithComponentsRegistry(registry))),
+		WithAspects(aspect.NewConcurrencyLimitAspect(1)))
+	assert.Nil(t, err)
+	defer ruleEngine.Stop()
+	chainEngine := ruleEngine.(*ChainEngine)
+	aspects := len(chainEngine.GetAspects())
+
+	done := make(chan error)
+	go func() {
+		done <- chainEngine.OnMsg(context.Background(), types.NewRuleMsg("", 0, map[string]any{}))
+	}()
+	<-node.started
+	reset := make(chan struct{})
+	go func() {
+		chainEngine.Reset()
+		close(reset)
+	}()
+	select {
+	case <-reset:
+		t.Fatal("reset completed while a message was running")
+	case <-time.After(20 * time.Millisecond):
+	}
+	node.release <- nil
+	assert.Nil(t, <-done)
+	<-reset
+
+	assert.Equal(t, "", chainEngine.Id())
+	assert.Equal(t, "", chainEngine.Name())
+	assert.False(t, chainEngine.TerminalOnErr())
+	assert.Nil(t, chainEngine.DSL())
+	assert.Equal(t, aspects, len(chainEngine.GetAspects()))
+	err = chainEngine.OnMsg(context.Background(), types.NewRuleMsg("", 0, map[string]any{}))
+	assert.True(t, errors.Is(err, types.ErrEngineNotInitialized))
+
+	assert.Nil(t, chainEngine.ReloadSelf([]byte(traceChain)))
+	assert.Equal(t, "trace", chainEngine.Id())
+	msg := types.NewRuleMsg("", 0, map[string]any{"amount": 2})
+	assert.Nil(t, chainEngine.OnMsg(context.Background(), msg))
+	assert.Equal(t, 4, msg.GetChainOutput()["result"])
+}
+
+// TestConcurrencyLimitAspectAggregation checks that the permits taken for the child chains of an aggregation
+// are released, so sequential messages are not rejected.
+func TestConcurrencyLimitAspectAggregation(t *testing.T) {
+	aggregationEngine, err := NewChainAggregationEngine([]byte(zeroConfigAggregation), WithAspects(aspect.NewConcurrencyLimitAspect(1)))
+	assert.Nil(t, err)
+	defer aggregationEngine.Stop()
+	for i := 0; i < 3; i++ {
+		_, err = aggregationEngine.OnMsgAndWait(context.Background(), types.NewRuleMsg("", 0, nil))
+		assert.Nil(t, err)
+	}
+}
+
+const weightedRouteChain = `{"id":"weighted","name":"weighted","metadata":{"nodes":[
+{"id":"s","type":"start"},
+{"id":"w","type":"weightedRoute","configuration":{"routes":[{"relation":"control","weight":90},{"relation":"treatment","weight":10}],"seed":42}},
+{"id":"c","type":"end","configuration":{"script":"{'route': 'control'}"}},
+{"id":"t","type":"end","configuration":{"script":"{'route': 'treatment'}"}}
+],"connections":[
+{"fromId":"s","toId":"w","type":"default"},
+{"fromId":"w","toId":"c","type":"control"},
+{"fromId":"w","toId":"t","type":"treatment"}
+]}}`
+
+// TestWeightedRouteConnection checks that a weightedRoute node with a route relation without connection fails to
+// load without the validator aspect.
+func TestWeightedRouteConnection(t *testing.T) {
+	config := NewConfig()
+	def, err := config.Parser.DecodeChain([]byte(weightedRouteChain))
+	assert.Nil(t, err)
+	chainCtx, err := InitChainCtx(config, nil, &def)
+	assert.Nil(t, err)
+	chainCtx.Destroy()
+
+	def, err = config.Parser.DecodeChain([]byte(strings.Replace(weightedRouteChain, `"type":"treatment"`, `"type":"default"`, 1)))
+	assert.Nil(t, err)
+	_, err = InitChainCtx(config, nil, &def)
+	assert.True(t, errors.Is(err, types.ErrRouteConnection))
+	assert.True(t, strings.Contains(err.Error(), "node w relation treatment"))
+
+	_, err = NewChainEngine([]byte(strings.Replace(weightedRouteChain, `,
+{"fromId":"w","toId":"t","type":"treatment"}`, ``, 1)))
+	assert.True(t, err != nil && strings.Contains(err.Error(), "treatment"))
+}
+
+const privateParameterChain = `{"id":"privateParameter","name":"privateParameter",
+"privateParameter":[{"key":"pjws","type":"STRING","name":"裁判文书","desc":""},{"key":"limit","type":"NUMBER","name":"限额","default":3}],
+"metadata":{"nodes":[
+{"id":"s","type":"start"},
+{"id":"e","type":"end","configuration":{"script":"{'pjws': priVars.pjws + '!', 'limit': priVars.limit}"}}
+],"connections":[
+{"fromId":"s","toId":"e","type":"default"}
+]}}`
+
+// TestPrivateParameter checks that the private parameters declared by a chain are parsed and initialize
+// the private variables the message does not have yet.
+func TestPrivateParameter(t *testing.T) {
+	chainEngine, err := NewChainEngine([]byte(privateParameterChain))
+	assert.Nil(t, err)
+	defer chainEngine.Stop()
+	def, err := NewConfig().Parser.DecodeChain(chainEngine.DSL())
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(def.PrivateParameter))
+	assert.Equal(t, types.PrivateParameter{Key: "pjws", Type: types.ParamTypeString, Name: "裁判文书"}, def.PrivateParameter[0])
+
+	msg := types.NewRuleMsg("", 0, map[string]any{})
+	assert.Nil(t, chainEngine.OnMsg(context.Background(), msg))
+	assert.Equal(t, map[string]any{"pjws": "!", "limit": float64(3)}, msg.GetChainOutput())
+
+	msg = types.NewRuleMsg("", 0, map[string]any{})
+	msg.SetPrivateVar("limit", 10)
+	assert.Nil(t, chainEngine.OnMsg(context.Background(), msg))
+	assert.Equal(t, 10, msg.GetChainOutput()["limit"])
+
+	duplicate := strings.Replace(privateParameterChain, `"key":"limit"`, `"key":"pjws"`, 1)
+	_, err = NewChainEngine([]byte(duplicate))
+	assert.True(t, err != nil && strings.Contains(err.Error(), "declared twice"))
+}
+
+// TestDescribe checks the engine snapshot: aspects, nodes with redacted configuration and a configuration summary,
+// taken while messages are processed.
+func TestDescribe(t *testing.T) {
+	dsl := strings.Replace(traceChain, `amount * 2}"}`, `amount * 2}","token":"s3cret"}`, 1)
+	chainEngine, err := NewChainEngine([]byte(dsl), WithConfig(NewConfig(types.WithMaxPayloadSize(1024))),
+		WithAspects(aspect.NewSlowLogAspect(time.Second, 0.1)))
+	assert.Nil(t, err)
+	defer chainEngine.Stop()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			_ = chainEngine.OnMsg(context.Background(), types.NewRuleMsg("", 0, map[string]any{"amount": i}))
+		}
+	}()
+	data, err := chainEngine.(*ChainEngine).Describe()
+	<-done
+	assert.Nil(t, err)
+	assert.False(t, bytes.Contains(data, []byte("s3cret")))
+
+	var description EngineDescription
+	assert.Nil(t, json.Unmarshal(data, &description))
+	assert.Equal(t, "trace", description.Id)
+	assert.Equal(t, "*aspect.SlowLogAspect", description.Aspects[0].Type)
+	assert.False(t, description.Aspects[0].Builtin)
+	assert.Equal(t, 3, len(description.Nodes))
+	assert.Equal(t, types.RuleSubTypeExprAssign, description.Nodes[1].Type)
+	assert.Equal(t, types.CategoryTransform, description.Nodes[1].Category)
+	assert.Equal(t, types.RedactedValue, description.Nodes[1].Configuration["token"])
+	assert.Equal(t, 2, len(description.Connections))
+	assert.Equal(t, 1024, description.Config.MaxPayloadSize)
+}
+
+const preconditionChain = `{"id":"precondition","name":"precondition","metadata":{"nodes":[
+{"id":"s","type":"start","configuration":{"jsScript":"return msg.temperature>10;"}},
+{"id":"a","type":"exprAssign","configuration":{"script":"{'alert': true}"}},
+{"id":"e","type":"end","configuration":{"script":"{'alert': priVars.alert}"}}
+],"connections":[
+{"fromId":"s","toId":"a","type":"default"},
+{"fromId":"a","toId":"e","type":"default"}
+]}}`
+
+// TestStartPrecondition checks that a skipping start node ends the chain without running the downstream
+// nodes, or follows the skip connection when there is one.
+func TestStartPrecondition(t *testing.T) {
+	chainEngine, err := NewChainEngine([]byte(preconditionChain), WithConfig(NewConfig(types.WithTrace(true))))
+	assert.Nil(t, err)
+	defer chainEngine.Stop()
+
+	msg := types.NewRuleMsg("", 0, map[string]any{"temperature": 20})
+	assert.Nil(t, chainEngine.OnMsg(context.Background(), msg))
+	assert.Equal(t, map[string]any{"alert": true}, msg.GetChainOutput())
+
+	msg = types.NewRuleMsg("", 0, map[string]any{"temperature": 5})
+	assert.Nil(t, chainEngine.OnMsg(context.Background(), msg))
+	assert.Nil(t, msg.GetChainOutput())
+	assert.Equal(t, 1, len(msg.Traces()))
+	assert.Equal(t, types.SkipRelationType, msg.Traces()[0].Relation)
+
+	skipChain := strings.NewReplacer(`{"id":"e","type":"end"`, `{"id":"k","type":"end","configuration":{"script":"{'skipped': true}"}},
+{"id":"e","type":"end"`, `{"fromId":"a","toId":"e","type":"default"}`, `{"fromId":"a","toId":"e","type":"default"},
+{"fromId":"s","toId":"k","type":"skip"}`).Replace(preconditionChain)
+	chainEngine, err = NewChainEngine([]byte(skipChain))
+	assert.Nil(t, err)
+	defer chainEngine.Stop()
+	msg = types.NewRuleMsg("", 0, map[string]any{"temperature": 5})
+	assert.Nil(t, chainEngine.OnMsg(context.Background(), msg))
+	assert.Equal(t, map[string]any{"skipped": true}, msg.GetChainOutput())
+}
+
+const conditionChain = `{"id":"condition","name":"condition","metadata":{"nodes":[
+{"id":"s","type":"start"},
+{"id":"big","type":"end","configuration":{"script":"{'to': 'big'}"}},
+{"id":"small","type":"end","configuration":{"script":"{'to': 'small'}"}}
+],"connections":[
+{"fromId":"s","toId":"big","type":"default","condition":"amount > 1000"},
+{"fromId":"s","toId":"small","type":"default"}
+]}}`
+
+// TestConnectionCondition checks that a connection is only followed when its condition holds,
+// and that a condition that does not compile fails the load.
+func TestConnectionCondition(t *testing.T) {
+	chainEngine, err := NewChainEngine([]byte(conditionChain))
+	assert.Nil(t, err)
+	defer chainEngine.Stop()
+	for amount, to := range map[int]string{2000: "big", 5: "small"} {
+		msg := types.NewRuleMsg("", 0, map[string]any{"amount": amount})
+		assert.Nil(t, chainEngine.OnMsg(context.Background(), msg))
+		assert.Equal(t, to, msg.GetChainOutput()["to"])
+	}
+
+	_, err = NewChainEngine([]byte(strings.Replace(conditionChain, "amount > 1000", "amount >", 1)))
+	assert.True(t, err != nil && strings.Contains(err.Error(), "s->big"))
+}
+
+const splitChain = `{"id":"split","name":"split","metadata":{"nodes":[
+{"id":"s","type":"start"},
+{"id":"sp","type":"split","configuration":{"field":"items","maxFanOut":3}},
+{"id":"e","type":"end","configuration":{"script":"{'v': item * 2, 'u': user, 'i': index}"}}
+],"connections":[
+{"fromId":"s","toId":"sp","type":"default"},
+{"fromId":"sp","toId":"e","type":"default"}
+]}}`
+
+// TestSplitTraversal checks that every message of a split runs the rest of the chain and that the branch
+// outputs are collected in the chain output of the split message.
+func TestSplitTraversal(t *testing.T) {
+	chainEngine, err := NewChainEngine([]byte(splitChain))
+	assert.Nil(t, err)
+	defer chainEngine.Stop()
+
+	msg := types.NewRuleMsg("", 0, map[string]any{"items": []any{1, 2, 3}, "user": "u1"})
+	assert.Nil(t, chainEngine.OnMsg(context.Background(), msg))
+	assert.Equal(t, []map[string]any{{"v": 2, "u": "u1", "i": 0}, {"v": 4, "u": "u1", "i": 1}, {"v": 6, "u": "u1", "i": 2}},
+		msg.GetChainOutput()[types.SplitResultsKey])
+
+	err = chainEngine.OnMsg(context.Background(), types.NewRuleMsg("", 0, map[string]any{"items": []any{1, 2, 3, 4}}))
+	assert.True(t, errors.Is(err, types.ErrMaxFanOutExceeded))
+}
+
+const loopChain = `{"id":"loop","name":"loop","metadata":{"nodes":[
+{"id":"s","type":"start"},
+{"id":"sp","type":"split","configuration":{"field":"items","maxFanOut":3}},
+{"id":"a","type":"exprAssign","configuration":{"script":"{'n': (priVars.n ?? 0) + 1}"}},
+{"id":"w","type":"exprSwitch","configuration":{"script":"priVars.n < 5 ? 'again' : 'default'"}},
+{"id":"e","type":"end","configuration":{"script":"{'n': priVars.n}"}}
+],"connections":[
+{"fromId":"s","toId":"sp","type":"default"},
+{"fromId":"sp","toId":"a","type":"default"},
+{"fromId":"a","toId":"w","type":"default"},
+{"fromId":"w","toId":"a","type":"again"},
+{"fromId":"w","toId":"e","type":"default"}
+]}}`
+
+// loopValidator replaces the built-in chain validator, which rejects cycles, so a looping chain can load.
+type loopValidator struct{}
+
+func (a *loopValidator) Order() int {
+	return 10
+}
+
+func (a *loopValidator) New() types.Aspect {
+	return a
+}
+
+func (a *loopValidator) Type() string {
+	return "chainValidator"
+}
+
+// TestMaxStepsSplit checks that the branches of a split count their steps against one limit, so a chain
+// looping in every branch stops once the branches together exceed Config.MaxSteps.
+func TestMaxStepsSplit(t *testing.T) {
+	// Each branch visits 11 nodes after the start and split nodes
+	chainEngine, err := NewChainEngine([]byte(loopChain), WithConfig(NewConfig(types.WithMaxSteps(20))),
+		WithAspects(&loopValidator{}))
+	assert.Nil(t, err)
+	defer chainEngine.Stop()
+
+	msg := types.NewRuleMsg("", 0, map[string]any{"items": []any{1}})
+	assert.Nil(t, chainEngine.OnMsg(context.Background(), msg))
+	assert.Equal(t, []map[string]any{{"n": 5}}, msg.GetChainOutput()[types.SplitResultsKey])
+
+	err = chainEngine.OnMsg(context.Background(), types.NewRuleMsg("", 0, map[string]any{"items": []any{1, 2}}))
+	assert.True(t, errors.Is(err, types.ErrMaxChainDepthExceeded))
+}
+
+const continueOnErrChain = `{"id":"continueOnErr","name":"continueOnErr","continueOnErr":true,"metadata":{"nodes":[
+{"id":"s","type":"start"},
+{"id":"f","type":"func","configuration":{"name":"boom","outputKey":"d"}},
+{"id":"e","type":"end","configuration":{"script":"{'ok': true}"}},
+{"id":"e2","type":"end","configuration":{"script":"{'err': priVars.error}"}}
+],"connections":[
+{"fromId":"s","toId":"f","type":"default"},
+{"fromId":"f","toId":"e","type":"default"},
+{"fromId":"f","toId":"e2","type":"failure"}
+]}}`
+
+// TestContinueOnErr checks that a failing node follows its failure connection when the chain continues on errors,
+// and that terminalOnErr on the node aborts the chain anyway.
+func TestContinueOnErr(t *testing.T) {
+	config := NewConfig()
+	config.RegisterUdf("boom", func(in map[string]any) (int, error) {
+		return 0, errors.New("boom")
+	})
+	chainEngine, err := NewChainEngine([]byte(continueOnErrChain), WithConfig(config))
+	assert.Nil(t, err)
+	defer chainEngine.Stop()
+	msg := types.NewRuleMsg("", 0, map[string]any{"x": 21})
+	assert.Nil(t, chainEngine.OnMsg(context.Background(), msg))
+	assert.Equal(t, map[string]any{"err": "boom"}, msg.GetChainOutput())
+
+	terminal := strings.Replace(continueOnErrChain, `{"id":"f","type":"func",`, `{"id":"f","type":"func","terminalOnErr":true,`, 1)
+	chainEngine, err = NewChainEngine([]byte(terminal), WithConfig(config))
+	assert.Nil(t, err)
+	defer chainEngine.Stop()
+	assert.NotNil(t, chainEngine.OnMsg(context.Background(), types.NewRuleMsg("", 0, map[string]any{"x": 21})))
+
+	_, err = NewChainEngine([]byte(strings.Replace(continueOnErrChain, `"continueOnErr":true,`, "", 1)), WithConfig(config),
+		WithAspects(&aspect.ChainValidator{}))
+	assert.True(t, err != nil && strings.Contains(err.Error(), "continueOnErr"))
+}
+
+const rootNodeChain = `{"id":"rootNode","name":"rootNode","metadata":{"rootNodeId":"a","nodes":[
+{"id":"s","type":"start"},
+{"id":"a","type":"exprAssign","configuration":{"script":"{'x': 1}"}},
+{"id":"e","type":"end","configuration":{"script":"{'x': priVars.x}"}}
+],"connections":[
+{"fromId":"s","toId":"e","type":"default"},
+{"fromId":"a","toId":"e","type":"default"}
+]}}`
+
+// TestRootNode checks that messages enter the chain at the configured root node, and that an unknown root node
+// fails the load.
+func TestRootNode(t *testing.T) {
+	chainEngine, err := NewChainEngine([]byte(rootNodeChain))
+	assert.Nil(t, err)
+	defer chainEngine.Stop()
+	msg := types.NewRuleMsg("", 0, map[string]any{})
+	assert.Nil(t, chainEngine.OnMsg(context.Background(), msg))
+	assert.Equal(t, map[string]any{"x": 1}, msg.GetChainOutput())
+
+	config := NewConfig()
+	def, err := config.Parser.DecodeChain([]byte(strings.Replace(rootNodeChain, `"rootNodeId":"a",`, "", 1)))
+	assert.Nil(t, err)
+	chainCtx, err := InitChainCtx(config, nil, &def)
+	assert.Nil(t, err)
+	defer chainCtx.Destroy()
+	assert.Equal(t, "s", chainCtx.RootNodeId())
+	assert.True(t, errors.Is(chainCtx.SetRootNode("zz"), types.ErrRootNodeNotFound))
+	assert.Nil(t, chainCtx.SetRootNode("a"))
+	assert.Equal(t, "a", chainCtx.RootNodeId())
+
+	_, err = NewChainEngine([]byte(strings.Replace(rootNodeChain, `"rootNodeId":"a"`, `"rootNodeId":"zz"`, 1)))
+	assert.True(t, err != nil && strings.Contains(err.Error(), "zz"))
+}
+
+const nodeOutputsChain = `{"id":"nodeOutputs","name":"nodeOutputs","metadata":{"nodes":[
+{"id":"s","type":"start"},
+{"id":"a","type":"exprAssign","configuration":{"script":"{'score': 1}"}},
+{"id":"b","type":"exprAssign","configuration":{"script":"{'score': nodes['a'].score + 10}"}},
+{"id":"e","type":"end","configuration":{"script":"{'a': nodes['a'].score, 'b': nodes.b.score, 'p': priVars.score}"}}
+],"connections":[
+{"fromId":"s","toId":"a","type":"default"},
+{"fromId":"a","toId":"b","type":"default"},
+{"fromId":"b","toId":"e","type":"default"}
+]}}`
+
+// TestNodeOutputs checks that the output of every node is recorded on the message and readable through nodes.
+func TestNodeOutputs(t *testing.T) {
+	chainEngine, err := NewChainEngine([]byte(nodeOutputsChain))
+	assert.Nil(t, err)
+	defer chainEngine.Stop()
+	msg := types.NewRuleMsg("", 0, map[string]any{})
+	assert.Nil(t, chainEngine.OnMsg(context.Background(), msg))
+	assert.Equal(t, map[string]any{"a": 1, "b": 11, "p": 11}, msg.GetChainOutput())
+	assert.Equal(t, map[string]any{"score": 1}, msg.GetNodeOutput("a"))
+	assert.Equal(t, map[string]any{"score": 11}, msg.GetNodeOutput("b"))
+	assert.Nil(t, msg.GetNodeOutput("zz"))
+}
+
+const relationAliasChain = `{"id":"relationAlias","name":"relationAlias","metadata":{"relationAliases":{"ok":"success","yes":"true"},"nodes":[
+{"id":"s","type":"start"},
+{"id":"w","type":"lookupSwitch","configuration":{"key":"k","table":{"a":"ok","b":"success"}}},
+{"id":"f","type":"exprFilter","configuration":{"script":"k == 'a'"}},
+{"id":"ea","type":"end","configuration":{"script":"{'r': 'S'}"}},
+{"id":"eb","type":"end","configuration":{"script":"{'r': 'D'}"}},
+{"id":"ef","type":"end","configuration":{"script":"{'r': 'F'}"}}
+],"connections":[
+{"fromId":"s","toId":"w","type":"default"},
+{"fromId":"w","toId":"f","type":"success"},
+{"fromId":"w","toId":"eb","type":"default"},
+{"fromId":"f","toId":"ea","type":"yes"},
+{"fromId":"f","toId":"ef","type":"false"}
+]}}`
+
+// TestRelationAliases checks that the relations returned by the nodes and the connection types are
+// normalized by the relation aliases, and that an alias of a built-in relation fails the load.
+func TestRelationAliases(t *testing.T) {
+	chainEngine, err := NewChainEngine([]byte(relationAliasChain))
+	assert.Nil(t, err)
+	defer chainEngine.Stop()
+	for k, r := range map[string]string{"a": "S", "b": "F", "c": "D"} {
+		msg := types.NewRuleMsg("", 0, map[string]any{"k": k})
+		assert.Nil(t, chainEngine.OnMsg(context.Background(), msg))
+		assert.Equal(t, r, msg.GetChainOutput()["r"], k)
+	}
+
+	_, err = NewChainEngine([]byte(strings.Replace(relationAliasChain, `"ok":"success"`, `"default":"success"`, 1)))
+	assert.True(t, err != nil && strings.Contains(err.Error(), "collides with a built-in relation"))
+}
+
+const membershipChain = `{"id":"membership","name":"membership","metadata":{"nodes":[
+{"id":"s","type":"start"},
+{"id":"m","type":"membership","configuration":{"field":"user","file":"FILE","refreshInterval":"1m"}},
+{"id":"e","type":"end"}
+],"connections":[
+{"fromId":"s","toId":"m","type":"default"},
+{"fromId":"m","toId":"e","type":"true"},
+{"fromId":"m","toId":"e","type":"false"}
+]}}`
+
+// waitGoroutines waits for the number of goroutines to drop to want, the stopped goroutines exit asynchronously.
+func waitGoroutines(t *testing.T, want int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > want && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > want {
+		t.Fatalf("%d goroutines, want at most %d", n, want)
+	}
+}
+
+// TestAggregationReloadDestroysChains checks that reloading an aggregation destroys the replaced child chains, and
+// that an aggregation failing to load destroys the child chains initialized before the failure.
+func TestAggregationReloadDestroysChains(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "blacklist.txt")
+	assert.Nil(t, os.WriteFile(file, []byte("alice\n"), 0o644))
+	chain := strings.Replace(membershipChain, "FILE", filepath.ToSlash(file), 1)
+	dsl := `{"id":"membership","name":"membership","metadata":{"chains":[` + chain + `]}}`
+	before := runtime.NumGoroutine()
+
+	aggregationEngine, err := NewChainAggregationEngine([]byte(dsl))
+	assert.Nil(t, err)
+	loaded := runtime.NumGoroutine()
+	for i := 0; i < 50; i++ {
+		assert.Nil(t, aggregationEngine.ReloadSelf([]byte(dsl)))
+	}
+	waitGoroutines(t, loaded)
+	assert.Nil(t, aggregationEngine.OnMsg(context.Background(), types.NewRuleMsg("", 0, map[string]any{"user": "alice"})))
+	aggregationEngine.Stop()
+	waitGoroutines(t, before)
+
+	broken := strings.NewReplacer(`"id":"membership","name":"membership","metadata":{"nodes"`, `"id":"broken","name":"broken","priority":-1,"metadata":{"nodes"`,
+		`"type":"end"`, `"type":"unknown"`).Replace(chain)
+	_, err = NewChainAggregationEngine([]byte(`{"id":"membership","name":"membership","metadata":{"chains":[` + chain + `,` + broken + `]}}`))
+	assert.NotNil(t, err)
+	waitGoroutines(t, before)
+}
+
+const fileNodesChain = `{"id":"fileNodes","name":"fileNodes","metadata":{"nodes":[
+{"id":"s","type":"start"},
+{"id":"m","type":"membership","configuration":{"field":"user","file":"SET","refreshInterval":"1m"}},
+{"id":"l","type":"lookupSwitch","configuration":{"key":"country","file":"TABLE","refreshInterval":"1m"}},
+{"id":"e","type":"end"}
+],"connections":[
+{"fromId":"s","toId":"m","type":"default"},
+{"fromId":"m","toId":"l","type":"true"},
+{"fromId":"m","toId":"e","type":"false"},
+{"fromId":"l","toId":"e","type":"cn"},
+{"fromId":"l","toId":"e","type":"default"}
+]}}`
+
+// TestReloadFileNodes checks that reloading chains with file backed lookupSwitch and membership nodes stops the
+// file refreshers of the replaced chains, so the number of goroutines stays stable.
+func TestReloadFileNodes(t *testing.T) {
+	dir := t.TempDir()
+	set, table := filepath.Join(dir, "blacklist.txt"), filepath.Join(dir, "countries.json")
+	assert.Nil(t, os.WriteFile(set, []byte("alice\n"), 0o644))
+	assert.Nil(t, os.WriteFile(table, []byte(`{"CN":"cn"}`), 0o644))
+	chain := strings.NewReplacer("SET", filepath.ToSlash(set), "TABLE", filepath.ToSlash(table)).Replace(fileNodesChain)
+	aggregation := `{"id":"fileNodes","name":"fileNodes","metadata":{"chains":[` + chain + `]}}`
+	before := runtime.NumGoroutine()
+
+	chainEngine, err := NewChainEngine([]byte(chain))
+	assert.Nil(t, err)
+	aggregationEngine, err := NewChainAggregationEngine([]byte(aggregation))
+	assert.Nil(t, err)
+	loaded := runtime.NumGoroutine()
+	for i := 0; i < 50; i++ {
+		assert.Nil(t, chainEngine.ReloadSelf([]byte(chain)))
+		assert.Nil(t, aggregationEngine.ReloadSelf([]byte(aggregation)))
+	}
+	waitGoroutines(t, loaded)
+
+	msg := types.NewRuleMsg("", 0, map[string]any{"user": "alice", "country": "CN"})
+	assert.Nil(t, chainEngine.OnMsg(context.Background(), msg))
+	assert.Nil(t, aggregationEngine.OnMsg(context.Background(), msg))
+	chainEngine.Stop()
+	aggregationEngine.Stop()
+	waitGoroutines(t, before)
+}
+
+// TestDisabledChain checks that a disabled chain loads without initializing its nodes, rejects messages with
+// ErrEngineDisabled, and runs them again once re-enabled by ReloadSelf.
+func TestDisabledChain(t *testing.T) {
+	disabled := strings.Replace(zeroConfigChain, `"name":"zeroConfig",`, `"name":"zeroConfig","disabled":true,`, 1)
+	disabled = strings.Replace(disabled, `"type":"end"`, `"type":"unknownType"`, 1)
+	chainEngine, err := NewChainEngine([]byte(disabled))
+	assert.Nil(t, err)
+	defer chainEngine.Stop()
+
+	err = chainEngine.OnMsg(context.Background(), types.NewRuleMsg("", 0, nil))
+	assert.True(t, errors.Is(err, types.ErrEngineDisabled))
+
+	assert.Nil(t, chainEngine.ReloadSelf([]byte(zeroConfigChain)))
+	msg := types.NewRuleMsg("", 0, nil)
+	assert.Nil(t, chainEngine.OnMsg(context.Background(), msg))
+	assert.Equal(t, true, msg.GetChainOutput()["ok"])
+}
+
+const filterChain = `{"id":"filter","name":"filter","metadata":{"nodes":[
+{"id":"s","type":"start"},
+{"id":"a","type":"exprAssign","configuration":{"script":"{'doubled': amount * 2}"}},
+{"id":"b","type":"exprAssign","configuration":{"script":"{'tripled': amount * 3}"}},
+{"id":"e","type":"end","configuration":{"script":"{'result': priVars.doubled + priVars.tripled}"}}
+],"connections":[
+{"fromId":"s","toId":"a","type":"default"},
+{"fromId":"a","toId":"b","type":"default"},
+{"fromId":"b","toId":"e","type":"default"}
+]}}`
+
+// TestNodeFilter checks that a node aspect with a filter only advises the nodes matched by id or type.
+func TestNodeFilter(t *testing.T) {
+	for _, tc := range []struct {
+		filter aspect.NodeFilter
+		want   []string
+	}{
+		{aspect.NodeFilter{NodeIds: []string{"b"}}, []string{"b"}},
+		{aspect.NodeFilter{NodeTypes: []types.NodeType{types.RuleSubTypeExprAssign}}, []string{"a", "b"}},
+		{aspect.NodeFilter{NodeIds: []string{"x"}}, nil},
+	} {
+		var advised []string
+		audit := aspect.NewAuditAspect(func(record aspect.AuditRecord) {
+			advised = append(advised, record.NodeId)
+		}, 1)
+		audit.Filter = tc.filter
+		chainEngine, err := NewChainEngine([]byte(filterChain), WithAspects(audit))
+		assert.Nil(t, err)
+		assert.Nil(t, chainEngine.OnMsg(context.Background(), types.NewRuleMsg("", 0, map[string]any{"amount": 2})))
+		assert.Equal(t, tc.want, advised)
+		chainEngine.Stop()
+	}
+}
+
+const enrichChain = `{"id":"enrich","name":"enrich","metadata":{"nodes":[
+{"id":"s","type":"start"},
+{"id":"e","type":"end","configuration":{"script":"{'level': level, 'userId': userId}"}}
+],"connections":[
+{"fromId":"s","toId":"e","type":"default"}
+]}}`
+
+// TestEnrichAspect checks that the enrich aspect merges the resolved variables into the message input, and that
+// a variable failing to resolve fails the message or is skipped depending on FailOnError.
+func TestEnrichAspect(t *testing.T) {
+	center := variable.NewVariableCenter()
+	center.RegisterFetcher("user", func(ctx context.Context, vctx *variable.VarContext, meta variable.VariableMeta) (any, error) {
+		return "gold", nil
+	})
+	center.RegisterFetcher("broken", func(ctx context.Context, vctx *variable.VarContext, meta variable.VariableMeta) (any, error) {
+		return nil, errors.New("fetch failed")
+	})
+	center.RegisterMeta(variable.VariableMeta{Key: "level", FetcherName: "user"})
+	center.RegisterMeta(variable.VariableMeta{Key: "country", FetcherName: "broken"})
+
+	chainEngine, err := NewChainEngine([]byte(enrichChain), WithAspects(aspect.NewEnrichAspect(center, []string{"level"}, true)))
+	assert.Nil(t, err)
+	msg := types.NewRuleMsg("", 0, map[string]any{"userId": "u1"})
+	assert.Nil(t, chainEngine.OnMsg(context.Background(), msg))
+	assert.Equal(t, map[string]any{"level": "gold", "userId": "u1"}, msg.GetChainOutput())
+	chainEngine.Stop()
+
+	chainEngine, err = NewChainEngine([]byte(enrichChain), WithAspects(aspect.NewEnrichAspect(center, []string{"country", "level"}, true)))
+	assert.Nil(t, err)
+	err = chainEngine.OnMsg(context.Background(), types.NewRuleMsg("", 0, map[string]any{"userId": "u1"}))
+	assert.NotNil(t, err)
+	assert.True(t, strings.Contains(err.Error(), "enrich variable:country"))
+	chainEngine.Stop()
+
+	chainEngine, err = NewChainEngine([]byte(enrichChain), WithAspects(aspect.NewEnrichAspect(center, []string{"country", "level"}, false)))
+	assert.Nil(t, err)
+	msg = types.NewRuleMsg("", 0, map[string]any{"userId": "u1"})
+	assert.Nil(t, chainEngine.OnMsg(context.Background(), msg))
+	assert.Equal(t, map[string]any{"level": "gold", "userId": "u1"}, msg.GetChainOutput())
+	_, ok := msg.GetInput()["country"]
+	assert.False(t, ok)
+	chainEngine.Stop()
+}
+
+const completedChain = `{"id":"completed","name":"completed","metadata":{"nodes":[
+{"id":"s","type":"start"},
+{"id":"f","type":"func","configuration":{"name":"boom","outputKey":"d"}},
+{"id":"e","type":"end","configuration":{"script":"{'ok': true}"}}
+],"connections":[
+{"fromId":"s","toId":"f","type":"default"},
+{"fromId":"f","toId":"e","type":"default"}
+]}}`
+
+// TestCompletedAspect checks that the completed aspects receive the message with a nil error when the chain
+// succeeds, and the error of the node when a node fails mid-chain.
+func TestCompletedAspect(t *testing.T) {
+	config := NewConfig()
+	config.RegisterUdf("boom", func(in map[string]any) (int, error) {
+		if in["x"] == 0 {
+			return 0, errors.New("boom")
+		}
+		return 1, nil
+	})
+	completed := &completedAspect{completed: make(chan completedMsg, 1)}
+	chainEngine, err := NewChainEngine([]byte(completedChain), WithConfig(config), WithAspects(completed))
+	assert.Nil(t, err)
+	defer chainEngine.Stop()
+
+	msg := types.NewRuleMsg("", 0, map[string]any{"x": 21})
+	assert.Nil(t, chainEngine.OnMsg(context.Background(), msg))
+	result := <-completed.completed
+	assert.Nil(t, result.err)
+	assert.Equal(t, msg.Id(), result.msg.Id())
+
+	msg = types.NewRuleMsg("", 0, map[string]any{"x": 0})
+	err = chainEngine.OnMsg(context.Background(), msg)
+	assert.NotNil(t, err)
+	result = <-completed.completed
+	assert.NotNil(t, result.err)
+	assert.True(t, strings.Contains(result.err.Error(), "boom"))
+	assert.Equal(t, msg.Id(), result.msg.Id())
+}
+
+const chainConfigurationChain = `{"id":"chainConf","name":"chainConf","configuration":{"script":"{'from': priVars.from ?? 'chain'}"},"metadata":{"nodes":[
+{"id":"s","type":"start"},
+{"id":"a","type":"exprAssign","configuration":{"script":"{'from': 'node'}"}},
+{"id":"e","type":"end"}
+],"connections":[
+{"fromId":"s","toId":"a","type":"default"},
+{"fromId":"a","toId":"e","type":"default"}
+]}}`
+
+// TestChainConfigurationDefaults checks that the chain configuration fills the keys missing from the node
+// configurations, and that the node configuration wins on conflict.
+func TestChainConfigurationDefaults(t *testing.T) {
+	chainEngine, err := NewChainEngine([]byte(chainConfigurationChain))
+	assert.Nil(t, err)
+	defer chainEngine.Stop()
+	msg := types.NewRuleMsg("", 0, nil)
+	assert.Nil(t, chainEngine.OnMsg(context.Background(), msg))
+	// The end node runs the chain script and reads what the node script of a assigned
+	assert.Equal(t, map[string]any{"from": "node"}, msg.GetChainOutput())
+
+	defaults := types.Configuration{"timeout": 30, "retry": 3}
+	node := types.Configuration{"timeout": 10}
+	assert.Equal(t, types.Configuration{"timeout": 10, "retry": 3}, node.MergeDefaults(defaults))
+	assert.Equal(t, types.Configuration{"timeout": 10}, node)
+	assert.Equal(t, types.Configuration{"timeout": 30, "retry": 3}, defaults)
+}
+
+// meteredAspect is a chain before aspect with its own type, so its duration metrics can be told apart.
+type meteredAspect struct{}
+
+func (a *meteredAspect) Order() int {
+	return 0
+}
+
+func (a *meteredAspect) New() types.Aspect {
+	return a
+}
+
+func (a *meteredAspect) Type() string {
+	return "metered"
+}
+
+func (a *meteredAspect) PointCut(chainCtx types.ChainCtx, msg types.RuleMsg) bool {
+	return true
+}
+
+func (a *meteredAspect) Before(chainCtx types.ChainCtx, msg types.RuleMsg) (types.RuleMsg, error) {
+	return msg, nil
+}
+
+// TestAspectMetrics checks that the duration of the aspect invocations is recorded, unless
+// Config.DisableAspectMetrics is set.
+func TestAspectMetrics(t *testing.T) {
+	samples := func() uint64 {
+		var metric dto.Metric
+		assert.Nil(t, aspectDuration.WithLabelValues("metered", aspectPointBefore).(prometheus.Histogram).Write(&metric))
+		return metric.GetHistogram().GetSampleCount()
+	}
+	before := samples()
+	chainEngine, err := NewChainEngine([]byte(zeroConfigChain), WithConfig(NewConfig(types.WithDisableAspectMetrics(true))),
+		WithAspects(&meteredAspect{}))
+	assert.Nil(t, err)
+	assert.Nil(t, chainEngine.OnMsg(context.Background(), types.NewRuleMsg("", 0, nil)))
+	chainEngine.Stop()
+	assert.Equal(t, before, samples())
+
+	chainEngine, err = NewChainEngine([]byte(zeroConfigChain), WithAspects(&meteredAspect{}))
+	assert.Nil(t, err)
+	assert.Nil(t, chainEngine.OnMsg(context.Background(), types.NewRuleMsg("", 0, nil)))
+	assert.Nil(t, chainEngine.OnMsg(context.Background(), types.NewRuleMsg("", 0, nil)))
+	chainEngine.Stop()
+	assert.Equal(t, before+2, samples())
+}
+
+// TestChainReachability checks that a chain with a node unreachable from the start node, or with a non-end node
+// without outgoing connection, fails to load with the ids of the offending nodes.
+func TestChainReachability(t *testing.T) {
+	unreachable := strings.Replace(traceChain, `{"id":"e","type":"end"`, `{"id":"x","type":"end"},
+{"id":"e","type":"end"`, 1)
+	_, err := NewChainEngine([]byte(unreachable))
+	assert.NotNil(t, err)
+	assert.True(t, strings.Contains(err.Error(), "不可达的节点: [x]"), err)
+
+	// A component declaring no relations is only caught by the dead-end check
+	registry := Registry.Clone()
+	assert.Nil(t, registry.Register(&typedNode{nodeType: "custom"}))
+	deadEnd := strings.Replace(traceChain, `{"id":"e","type":"end"`, `{"id":"b","type":"custom"},
+{"id":"e","type":"end"`, 1)
+	deadEnd = strings.Replace(deadEnd, `{"fromId":"s","toId":"a","type":"default"}`, `{"fromId":"s","toId":"a","type":"default"},
+{"fromId":"s","toId":"b","type":"default","priority":1}`, 1)
+	_, err = NewChainEngine([]byte(deadEnd), WithConfig(NewConfig(types.WithComponentsRegistry(registry))))
+	assert.NotNil(t, err)
+	assert.True(t, strings.Contains(err.Error(), "没有传出连接的非结束节点: [b]"), err)
+}
+
+const disabledConnectionChain = `{"id":"disabledConnection","name":"disabledConnection","metadata":{"nodes":[
+{"id":"s","type":"start"},
+{"id":"w","type":"exprSwitch","configuration":{"script":"amount > 10 ? 'big' : 'default'"}},
+{"id":"low","type":"end","configuration":{"script":"{'to': 'low'}"}},
+{"id":"high","type":"end","configuration":{"script":"{'to': 'high'}"}}
+],"connections":[
+{"fromId":"s","toId":"w","type":"default"},
+{"fromId":"w","toId":"low","type":"big"},
+{"fromId":"w","toId":"high","type":"big","priority":1,"disabled":true},
+{"fromId":"w","toId":"high","type":"default"}
+]}}`
+
+// TestDisabledConnection checks that a disabled connection is skipped at runtime, as if it was deleted.
+func TestDisabledConnection(t *testing.T) {
+	for dsl, to := range map[string]string{
+		disabledConnectionChain: "low",
+		strings.Replace(disabledConnectionChain, `,"disabled":true`, "", 1): "high",
+	} {
+		chainEngine, err := NewChainEngine([]byte(dsl))
+		assert.Nil(t, err)
+		msg := types.NewRuleMsg("", 0, map[string]any{"amount": 20})
+		assert.Nil(t, chainEngine.OnMsg(context.Background(), msg))
+		assert.Equal(t, to, msg.GetChainOutput()["to"])
+		chainEngine.Stop()
+	}
+}
+
+// TestScoreBands checks that the final score maps to the band it falls in, and that overlapping or unsorted
+// bands fail to load.
+func TestScoreBands(t *testing.T) {
+	bands := `{"minScore":0,"action":"ACCEPT","label":"low"},{"minScore":10,"action":"REVIEW","label":"mid"},{"minScore":20,"action":"REJECT","label":"high"}`
+	withBands := func(bands string) string {
+		return strings.Replace(scoredAggregation, `"name":"scored",`, `"name":"scored","configuration":{"aggregation":{"thresholds":[`+bands+`]}},`, 1)
+	}
+	aggregationEngine, err := NewChainAggregationEngine([]byte(withBands(bands)))
+	assert.Nil(t, err)
+	defer aggregationEngine.Stop()
+	result, err := aggregationEngine.OnMsgAndWait(context.Background(), types.NewRuleMsg("", 0, nil))
+	assert.Nil(t, err)
+	assert.Equal(t, 15, result.Score)
+	assert.Equal(t, "REVIEW", result.Action)
+	assert.Equal(t, "mid", result.Label)
+
+	aggregation := types.Aggregation{Thresholds: []types.ThresholdBand{{MinScore: 0, Action: "ACCEPT"}, {MinScore: 10, Action: "REVIEW"}, {MinScore: 20, Action: "REJECT"}}}
+	for score, action := range map[int]string{0: "ACCEPT", 9: "ACCEPT", 10: "REVIEW", 19: "REVIEW", 20: "REJECT", 1000: "REJECT"} {
+		band, ok := aggregation.Band(score)
+		assert.True(t, ok)
+		assert.Equal(t, action, band.Action, score)
+	}
+	_, ok := aggregation.Band(-1)
+	assert.False(t, ok)
+
+	for _, invalid := range []string{
+		`{"minScore":0,"action":"ACCEPT"},{"minScore":10,"action":"REVIEW"},{"minScore":10,"action":"REJECT"}`,
+		`{"minScore":20,"action":"REJECT"},{"minScore":0,"action":"ACCEPT"}`,
+	} {
+		_, err = NewChainAggregationEngine([]byte(withBands(invalid)))
+		assert.NotNil(t, err)
+		assert.True(t, strings.Contains(err.Error(), "不能重叠"), err)
+	}
+}
+
+// TestDescribeAspects checks that the aspects are described in execution order with their interfaces, and that
+// a configured aspect replacing a built-in of the same type is not marked built-in.
+func TestDescribeAspects(t *testing.T) {
+	ruleEngine, err := NewChainEngine([]byte(zeroConfigChain), WithAspects(&meteredAspect{}, &loopValidator{}))
+	assert.Nil(t, err)
+	defer ruleEngine.Stop()
+	assert.Equal(t, []types.AspectInfo{
+		{Type: "*engine.meteredAspect", Order: 0, Interfaces: []string{"ChainBeforeAspect"}},
+		{Type: "*engine.loopValidator", Order: 10},
+		{Type: "*aspect.ChainAggregationValidator", Order: 10, Interfaces: []string{"OnChainAggregationBeforeInitAspect"}, Builtin: true},
+		{Type: "*aspect.MetricsAspect", Order: 20, Builtin: true},
+	}, ruleEngine.(*ChainEngine).DescribeAspects())
+}
+
+// TestAuditAspect checks the audit records of the nodes changing the message, and that a failing node
+// produces no record.
+func TestAuditAspect(t *testing.T) {
+	config := NewConfig()
+	config.RegisterUdf("boom", func(in map[string]any) (int, error) {
+		if in["x"] == 0 {
+			return 0, errors.New("boom")
+		}
+		return 1, nil
+	})
+	var records []aspect.AuditRecord
+	audit := aspect.NewAuditAspect(func(record aspect.AuditRecord) {
+		records = append(records, record)
+	}, 1)
+	chainEngine, err := NewChainEngine([]byte(completedChain), WithConfig(config), WithAspects(audit))
+	assert.Nil(t, err)
+	defer chainEngine.Stop()
+
+	msg := types.NewRuleMsg("", 0, map[string]any{"x": 21})
+	assert.Nil(t, chainEngine.OnMsg(context.Background(), msg))
+	assert.Equal(t, []aspect.AuditRecord{
+		{MsgId: msg.Id(), NodeId: "f", NodeType: "func", Relation: types.DefaultRelationType, Added: []string{"priVars.d"}},
+		{MsgId: msg.Id(), NodeId: "e", NodeType: "end", Removed: []string{"priVars.d"}},
+	}, records)
+
+	records = nil
+	assert.NotNil(t, chainEngine.OnMsg(context.Background(), types.NewRuleMsg("", 0, map[string]any{"x": 0})))
+	assert.Equal(t, 0, len(records))
+}
@@ -4,6 +4,8 @@ import (
 	"github.com/bittoy/rule/builtin/aspect"
 	"github.com/bittoy/rule/builtin/funcs"
 	"github.com/bittoy/rule/types"
+	"github.com/bittoy/rule/utils/cache"
+	"github.com/bittoy/rule/utils/js"
 )
 
 // 这些切面在初始化期间通过 initBuiltinsAspects() 方法自动添加到规则引擎中。
@@ -30,15 +32,17 @@ var BuiltinsAspects = []types.Aspect{&aspect.ChainAggregationValidator{}, &aspec
 //   - JSON parser for rule chain definitions  规则链定义的 JSON 解析器
 //   - Default component registry with built-in components  包含内置组件的默认组件注册表
 //   - User-defined functions registry  用户定义函数注册表
+//   - Shared JavaScript VM pool  共享的 JavaScript VM 池
 //   - Default cache implementation  默认缓存实现
 func NewConfig(opts ...types.Option) types.Config {
 	c := types.NewConfig(opts...)
-	if c.Parser == nil {
-		c.Parser = &JsonParser{}
+	if c.JsVMPool == nil {
+		c.JsVMPool = js.NewVMPool(js.DefaultMaxIdleVMs)
 	}
-	if c.ComponentsRegistry == nil {
-		c.ComponentsRegistry = Registry
+	if c.Cache == nil {
+		c.Cache = cache.DefaultCache
 	}
+	c = withConfigDefaults(c)
 	// register all udfs
 	// 注册所有用户定义函数
 	for name, f := range funcs.ScriptFunc.GetAll() {
@@ -47,6 +51,23 @@ func NewConfig(opts ...types.Option) types.Config {
 	return c
 }
 
+// withConfigDefaults fills the fields the engine dereferences without checking, so a Config
+// literal such as types.Config{} passed to SetConfig does not panic.
+// withConfigDefaults 填充引擎直接使用的字段，使传给 SetConfig 的 types.Config{} 等配置字面量不会引发 panic。
+func withConfigDefaults(c types.Config) types.Config {
+	c.Logger = types.NewLogger(c.Logger)
+	if c.Parser == nil {
+		c.Parser = &JsonParser{Codec: c.JSONCodec}
+	}
+	if c.ComponentsRegistry == nil {
+		c.ComponentsRegistry = Registry
+	}
+	if c.JsVMPool == nil {
+		c.JsVMPool = js.DefaultVMPool
+	}
+	return c
+}
+
 // WithConfig is an option that sets the Config of the RuleEngine.
 // WithConfig 是设置 RuleEngine 配置的选项。
 func WithConfig(config types.Config) types.EngineOption {
@@ -68,3 +89,87 @@ func WithAspects(aspects ...types.Aspect) types.EngineOption {
 		return nil                // Return no error.
 	}
 }
+
+// AspectBundle is a named set of aspects applied to an engine in one call with WithAspectBundle,
+// e.g. a production bundle with metrics and validation or a debug bundle with the debug aspects.
+// A bundle can be shared by many engines, each engine gets its own instances, see types.Aspect.New.
+//
+// AspectBundle 是通过 WithAspectBundle 一次应用到引擎的具名切面集合，例如包含指标和校验切面的生产环境集合，
+// 或包含调试切面的调试集合。一个集合可以被多个引擎共享，每个引擎获得各自的实例，参见 types.Aspect.New。
+//
+// Usage:
+// 使用方法：
+//
+//	var debugBundle = NewAspectBundle("debug", &aspect.NodeDebug{}, &aspect.ChainDebug{})
+//	engine, err := NewChainEngine(def, WithAspectBundle(debugBundle))
+type AspectBundle struct {
+	// Name identifies the bundle  集合名称
+	Name string
+	// Aspects are the aspects of the bundle  集合中的切面
+	Aspects []types.Aspect
+}
+
+// NewAspectBundle creates a named aspect bundle.
+// NewAspectBundle 创建具名切面集合。
+func NewAspectBundle(name string, aspects ...types.Aspect) AspectBundle {
+	return AspectBundle{Name: name, Aspects: aspects}
+}
+
+// WithAspectBundle creates a RuleEngineOption adding the aspects of the bundles to the aspects set by the
+// options before it. Aspects are deduplicated by type, see types.AspectType: an aspect whose type is
+// already set is skipped, and a built-in aspect is only added when no aspect of its type is set.
+// Apply it after WithAspects, which replaces the aspects.
+//
+// WithAspectBundle 创建一个 RuleEngineOption，将集合中的切面添加到之前选项设置的切面中。切面按类型去重，
+// 参见 types.AspectType：类型已设置的切面会被跳过，内置切面也只在没有同类型切面时添加。
+// 应在替换切面的 WithAspects 之后使用。
+func WithAspectBundle(bundles ...AspectBundle) types.EngineOption {
+	return func(re types.Engine) error {
+		aspects := re.GetAspects()
+		for _, bundle := range bundles {
+			for _, item := range bundle.Aspects {
+				aspects = aspects.Merge(item.New())
+			}
+		}
+		re.SetAspects(aspects...)
+		return nil
+	}
+}
+
+// WithTenant creates a RuleEngineOption setting the tenant the engine runs chains for. The chains are initialized
+// with the component registry Config.RegistryProvider resolves for the tenant, see types.Config.ForTenant, and the
+// engine fails to load when none is resolved. The tenant is set by the caller, not read from the definition, so a
+// definition cannot select the registry of another tenant.
+//
+// WithTenant 创建一个 RuleEngineOption，设置引擎所属的租户。规则链使用 Config.RegistryProvider 为该租户解析的组件注册表
+// 初始化，参见 types.Config.ForTenant，无法解析时引擎加载失败。租户由调用方设置，而不是从定义中读取，
+// 因此定义无法选择其他租户的注册表。
+func WithTenant(tenant string) types.EngineOption {
+	return func(re types.Engine) error {
+		switch e := re.(type) {
+		case *ChainEngine:
+			e.tenant = tenant
+		case *ChainAggregationEngine:
+			e.tenant = tenant
+		}
+		return nil
+	}
+}
+
+// WithOnUpdated creates a RuleEngineOption setting the callback run after each successful ReloadSelf, in place of
+// the default logging. It receives the difference between the old and the new definition, see types.ChainDiff,
+// so subscribers can e.g. invalidate the caches of the changed nodes only.
+//
+// WithOnUpdated 创建一个 RuleEngineOption，设置每次 ReloadSelf 成功后执行的回调，替代默认的日志输出。
+// 回调接收新旧定义之间的差异，参见 types.ChainDiff，使订阅方可以例如只使变化节点的缓存失效。
+func WithOnUpdated(onUpdated types.OnUpdated) types.EngineOption {
+	return func(re types.Engine) error {
+		switch e := re.(type) {
+		case *ChainEngine:
+			e.callbacks.OnUpdated = onUpdated
+		case *ChainAggregationEngine:
+			e.callbacks.OnUpdated = onUpdated
+		}
+		return nil
+	}
+}
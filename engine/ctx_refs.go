@@ -0,0 +1,83 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engine
+
+import "sync"
+
+// ctxRefs counts the messages running through a rule chain context, so a reload can swap the context without
+// waiting for them and destroy the replaced one once the last of them completes. The zero value is ready to use.
+// ctxRefs 统计正在通过规则链上下文的消息，使重载无需等待它们即可替换上下文，并在最后一条消息完成后销毁被替换的上下文。
+// 零值即可使用。
+type ctxRefs struct {
+	mu   sync.Mutex
+	refs int
+	// retired is set by retire, the context takes no new messages afterwards
+	retired bool
+	// destroy is the function retire passed, run once the context has drained
+	destroy func()
+	// drained is closed once the retired context has drained and been destroyed
+	drained chan struct{}
+}
+
+// acquire counts a message running through the context, it fails once the context is retired
+func (r *ctxRefs) acquire() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.retired {
+		return false
+	}
+	r.refs++
+	return true
+}
+
+// release completes a message counted by acquire, the last one of a retired context destroys it
+func (r *ctxRefs) release() {
+	r.mu.Lock()
+	r.refs--
+	drained := r.retired && r.refs == 0
+	r.mu.Unlock()
+	if drained {
+		r.finish()
+	}
+}
+
+// retire stops the context from taking new messages and destroys it with destroy once the messages running
+// through it complete, right away when there are none. The returned channel is closed once it is destroyed.
+func (r *ctxRefs) retire(destroy func()) <-chan struct{} {
+	r.mu.Lock()
+	if r.retired {
+		r.mu.Unlock()
+		return r.drained
+	}
+	r.retired = true
+	r.destroy = destroy
+	r.drained = make(chan struct{})
+	drained := r.refs == 0
+	r.mu.Unlock()
+	if drained {
+		r.finish()
+	}
+	return r.drained
+}
+
+// finish destroys the drained context and closes drained
+func (r *ctxRefs) finish() {
+	if r.destroy != nil {
+		r.destroy()
+	}
+	close(r.drained)
+}
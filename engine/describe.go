@@ -0,0 +1,165 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/bittoy/rule/types"
+)
+
+// EngineDescription is the snapshot of what an engine runs, see ChainEngine.Describe.
+// EngineDescription 是引擎运行内容的快照，参见 ChainEngine.Describe。
+type EngineDescription struct {
+	// Id is the id of the rule chain  规则链 id
+	Id string `json:"id"`
+	// Name is the name of the rule chain  规则链名称
+	Name string `json:"name"`
+	// Version is the version of the rule chain definition  规则链定义的版本
+	Version string `json:"version,omitempty"`
+	// Disabled reports whether the rule chain is disabled  规则链是否被禁用
+	Disabled bool `json:"disabled"`
+	// RootNodeId is the id of the entry node  入口节点 id
+	RootNodeId string `json:"rootNodeId,omitempty"`
+	// Aspects are the active aspects in execution order  按执行顺序排列的生效切面
+	Aspects []types.AspectInfo `json:"aspects"`
+	// Nodes are the nodes in definition order  按定义顺序排列的节点
+	Nodes []NodeDescription `json:"nodes"`
+	// Connections are the enabled connections  生效的连接
+	Connections []types.NodeConnection `json:"connections"`
+	// Config summarizes the engine configuration  引擎配置摘要
+	Config ConfigSummary `json:"config"`
+}
+
+// NodeDescription describes a node of the chain, its configuration is redacted with Config.Redact.
+// NodeDescription 描述规则链的节点，其配置经过 Config.Redact 脱敏。
+type NodeDescription struct {
+	Id       string         `json:"id"`
+	Type     types.NodeType `json:"type"`
+	Name     string         `json:"name,omitempty"`
+	Category string         `json:"category,omitempty"`
+	// Component is the Go type of the component, it tells apart the versions of a replaced component
+	// Component 是组件的 Go 类型，用于区分被替换组件的不同实现
+	Component string `json:"component,omitempty"`
+	// Relations are the relations declared by the component, see types.RelationsGetter
+	// Relations 是组件声明的关系，参见 types.RelationsGetter
+	Relations     []string            `json:"relations,omitempty"`
+	Configuration types.Configuration `json:"configuration,omitempty"`
+}
+
+// ConfigSummary is the part of the engine configuration safe to expose: limits and switches, the names of
+// the functions and hooks set, never the property values which may hold secrets.
+// ConfigSummary 是可以安全公开的引擎配置部分：限制和开关、已设置的函数和钩子的名称，不包括可能包含密钥的属性值。
+type ConfigSummary struct {
+	MaxSteps       int                `json:"maxSteps"`
+	MaxRetries     int                `json:"maxRetries"`
+	RetryInterval  string             `json:"retryInterval"`
+	MaxPayloadSize int                `json:"maxPayloadSize"`
+	DryRun         bool               `json:"dryRun"`
+	Trace          bool               `json:"trace"`
+	ExprCoercion   types.ExprCoercion `json:"exprCoercion,omitempty"`
+	MetricsTags    []string           `json:"metricsTags,omitempty"`
+	RedactKeys     []string           `json:"redactKeys"`
+	// Udfs are the names of the user defined functions  用户自定义函数的名称
+	Udfs []string `json:"udfs,omitempty"`
+	// Hooks are the names of the optional handlers and stores set, e.g. deadLetter  已设置的可选处理器和存储的名称，如 deadLetter
+	Hooks []string `json:"hooks,omitempty"`
+}
+
+// Describe returns a JSON snapshot of what the engine runs: its aspects, nodes, connections and a
+// configuration summary without secrets, for introspection of production engines. The node
+// configurations are redacted with Config.Redact. It is safe to call while messages are processed.
+//
+// Describe 返回引擎运行内容的 JSON 快照：切面、节点、连接以及不含密钥的配置摘要，用于生产环境引擎的自省。
+// 节点配置经过 Config.Redact 脱敏。可以在处理消息的同时调用。
+func (e *ChainEngine) Describe() ([]byte, error) {
+	chainCtx := e.acquireChainCtx()
+	if chainCtx == nil {
+		return nil, types.ErrEngineNotInitialized
+	}
+	defer chainCtx.refs.release()
+	def := chainCtx.selfDefinition
+	description := EngineDescription{
+		Id:          def.Id,
+		Name:        def.Name,
+		Version:     def.Version,
+		Disabled:    def.Disabled,
+		RootNodeId:  chainCtx.rootNodeId,
+		Aspects:     e.DescribeAspects(),
+		Nodes:       make([]NodeDescription, 0, len(def.Metadata.Nodes)),
+		Connections: def.Metadata.EnabledConnections(),
+		Config:      summarizeConfig(chainCtx.config),
+	}
+	for _, item := range def.Metadata.Nodes {
+		node := NodeDescription{
+			Id:            item.Id,
+			Type:          item.Type,
+			Name:          item.Name,
+			Configuration: chainCtx.config.Redact(item.Configuration),
+		}
+		if nodeCtx, ok := chainCtx.nodes[item.Id].(*RuleNodeCtx); ok {
+			// The effective configuration includes the chain level defaults
+			// 生效的配置包含规则链级别的默认值
+			node.Configuration = chainCtx.config.Redact(nodeCtx.selfDefinition.Configuration)
+			node.Component = fmt.Sprintf("%T", nodeCtx.Node)
+			if getter, ok := nodeCtx.Node.(types.CategoryGetter); ok {
+				node.Category = getter.Category()
+			}
+			if getter, ok := nodeCtx.Node.(types.RelationsGetter); ok {
+				node.Relations = getter.Relations()
+			}
+		}
+		description.Nodes = append(description.Nodes, node)
+	}
+	return json.Marshal(description)
+}
+
+// summarizeConfig returns the summary of the configuration
+func summarizeConfig(config types.Config) ConfigSummary {
+	summary := ConfigSummary{
+		MaxSteps:       config.MaxSteps,
+		MaxRetries:     config.MaxRetries,
+		RetryInterval:  config.RetryInterval.String(),
+		MaxPayloadSize: config.MaxPayloadSize,
+		DryRun:         config.DryRun,
+		Trace:          config.Trace,
+		ExprCoercion:   config.ExprCoercion,
+		MetricsTags:    config.MetricsTags,
+		RedactKeys:     config.GetRedactKeys(),
+	}
+	for name := range config.Udf {
+		summary.Udfs = append(summary.Udfs, name)
+	}
+	slices.Sort(summary.Udfs)
+	for name, set := range map[string]bool{
+		"deadLetter":  config.DeadLetter != nil,
+		"events":      config.Events != nil,
+		"chainResult": config.ChainResult != nil,
+		"cache":       config.Cache != nil,
+		"kvStore":     config.KVStore != nil,
+		"enginePool":  config.EnginePool != nil,
+		"clock":       config.Clock != nil,
+	} {
+		if set {
+			summary.Hooks = append(summary.Hooks, name)
+		}
+	}
+	slices.Sort(summary.Hooks)
+	return summary
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engine
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bittoy/rule/builtin/aspect"
+	"github.com/bittoy/rule/components/base"
+	"github.com/bittoy/rule/types"
+
+	"github.com/expr-lang/expr"
+)
+
+// LintCategoryCompile a node fails to initialize, e.g. its script does not compile, or a connection condition does not compile
+// LintCategoryCompile 节点初始化失败（例如脚本无法编译），或连接条件无法编译
+const LintCategoryCompile aspect.ValidationCategory = "compile"
+
+// LintIssue is a problem found by LintChain.
+// LintIssue 是 LintChain 发现的问题。
+type LintIssue struct {
+	// NodeId is the id of the problem node, empty for chain level problems  问题节点的 id，规则链级别问题为空
+	NodeId string
+	// Category is the category of the problem, see aspect.ValidationCategory  问题的类别，参见 aspect.ValidationCategory
+	Category aspect.ValidationCategory
+	// Message describes the problem  问题描述
+	Message string
+}
+
+// LintChain checks a chain definition without creating an engine, e.g. in CI before deploying chains.
+// It reports every problem of aspect.ValidateChainAll, then initializes each node on its own, compiling its
+// expr or JavaScript scripts, and compiles the connection conditions. The options are those the chain is
+// deployed with, e.g. the custom components, the properties or the script env keys the scripts depend on.
+// The nodes are initialized with types.NodeConfigurationKeyLint set, so they validate their configuration
+// without side effects such as loading files or starting background tasks, and are destroyed right away.
+// No message is processed. Returns nil when the chain has no problem.
+//
+// LintChain 在不创建引擎的情况下检查规则链定义，例如在 CI 中部署规则链之前。它报告 aspect.ValidateChainAll 的所有问题，
+// 然后逐个初始化节点以编译其 expr 或 JavaScript 脚本，并编译连接条件。opts 为部署规则链时使用的选项，
+// 例如脚本依赖的自定义组件、全局属性或脚本环境变量名。节点初始化时设置 types.NodeConfigurationKeyLint，
+// 只校验配置而不产生副作用（例如加载文件或启动后台任务），并随即被销毁。不会处理任何消息。
+// 没有问题时返回 nil。
+//
+// Usage:
+// 使用方法：
+//
+//	for _, issue := range engine.LintChain(dsl, types.WithComponentsRegistry(registry)) {
+//		fmt.Println(issue.NodeId, issue.Category, issue.Message)
+//	}
+func LintChain(dsl []byte, opts ...types.Option) []LintIssue {
+	config := NewConfig(opts...)
+	chain, err := config.Parser.DecodeChain(dsl)
+	if err != nil {
+		return []LintIssue{{Category: aspect.ValidationCategoryStructure, Message: err.Error()}}
+	}
+	var issues []LintIssue
+	for _, validationErr := range aspect.ValidateChainAll(&chain, config.ComponentsRegistry) {
+		issues = append(issues, LintIssue{NodeId: validationErr.NodeId, Category: validationErr.Category, Message: validationErr.Message})
+	}
+	for _, item := range chain.Metadata.Nodes {
+		configuration := types.Configuration{types.NodeConfigurationKeyLint: true}
+		for k, v := range item.Configuration {
+			configuration[k] = v
+		}
+		if len(chain.Configuration) > 0 {
+			configuration = configuration.MergeDefaults(chain.Configuration)
+		}
+		if err := lintNode(config, item.Type, configuration); err != nil {
+			issues = append(issues, LintIssue{NodeId: item.Id, Category: LintCategoryCompile,
+				Message: fmt.Sprintf("节点 %s(%s) 初始化失败: %s", item.Id, item.Type, err.Error())})
+		}
+	}
+	for _, item := range chain.Metadata.EnabledConnections() {
+		condition := strings.TrimSpace(item.Condition)
+		if condition == "" {
+			continue
+		}
+		if _, err := expr.Compile(condition, base.NodeUtils.ExprOptions(config, expr.AsBool())...); err != nil {
+			issues = append(issues, LintIssue{NodeId: item.FromId, Category: LintCategoryCompile,
+				Message: fmt.Sprintf("连接 %s->%s 的条件无法编译: %s", item.FromId, item.ToId, err.Error())})
+		}
+	}
+	return issues
+}
+
+// lintNode creates and initializes a node of the type in lint mode, then destroys it
+func lintNode(config types.Config, nodeType types.NodeType, configuration types.Configuration) error {
+	node, err := config.ComponentsRegistry.NewNode(nodeType)
+	if err != nil {
+		return err
+	}
+	if err = node.Init(config, configuration); err != nil {
+		return err
+	}
+	node.Destroy()
+	return nil
+}
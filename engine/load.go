@@ -0,0 +1,95 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engine
+
+import (
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/bittoy/rule/types"
+)
+
+// LoadResult is the result of Pool.LoadAll.
+// LoadResult 是 Pool.LoadAll 的结果。
+type LoadResult struct {
+	// Engines are the engines of the chains loaded successfully, by the key of their definition
+	// Engines 加载成功的规则链的引擎，以定义的键为键
+	Engines map[string]types.Engine
+	// Errors are the errors of the chains that failed to load, by the key of their definition
+	// Errors 加载失败的规则链的错误，以定义的键为键
+	Errors map[string]error
+	// Elapsed is the total load time
+	// Elapsed 总加载耗时
+	Elapsed time.Duration
+}
+
+// LoadAll creates the chain engines of defs in parallel with at most concurrency workers and adds them to
+// the pool, so the expr and JavaScript programs of many chains compile concurrently at startup instead of
+// one chain after the other. A concurrency that is not positive uses runtime.GOMAXPROCS(0) workers. Every
+// engine is created with opts, a chain failing to load does not stop the others.
+//
+// LoadAll 使用最多 concurrency 个工作协程并行创建 defs 中规则链的引擎并添加到池中，使启动时多个规则链的 expr 和
+// JavaScript 程序并发编译，而不是逐个规则链编译。concurrency 非正数时使用 runtime.GOMAXPROCS(0) 个工作协程。
+// 每个引擎都使用 opts 创建，某个规则链加载失败不会影响其他规则链。
+//
+// Usage:
+// 使用方法：
+//
+//	pool := engine.NewPool()
+//	result := pool.LoadAll(defs, 8, engine.WithConfig(config))
+//	for id, err := range result.Errors {
+//		log.Printf("chain %s: %v", id, err)
+//	}
+//	log.Printf("loaded %d chains in %s", len(result.Engines), result.Elapsed)
+func (p *Pool) LoadAll(defs map[string][]byte, concurrency int, opts ...types.EngineOption) LoadResult {
+	start := time.Now()
+	if concurrency <= 0 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
+	result := LoadResult{
+		Engines: make(map[string]types.Engine, len(defs)),
+		Errors:  map[string]error{},
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	keys := make(chan string)
+	for i := 0; i < min(concurrency, len(defs)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for key := range keys {
+				ruleEngine, err := NewChainEngine(defs[key], opts...)
+				mu.Lock()
+				if err != nil {
+					result.Errors[key] = err
+				} else {
+					result.Engines[key] = ruleEngine
+					p.Add(ruleEngine)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for key := range defs {
+		keys <- key
+	}
+	close(keys)
+	wg.Wait()
+	result.Elapsed = time.Since(start)
+	return result
+}
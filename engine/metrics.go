@@ -1,9 +1,23 @@
 package engine
 
 import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/bittoy/rule/types"
+
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+// 切面切入点标签
+const (
+	aspectPointBefore     = "before"
+	aspectPointAfter      = "after"
+	aspectPointCompleted  = "completed"
+	aspectPointBeforeInit = "beforeInit"
+)
+
 var (
 	// 请求总数
 	enginRequestsTotal = prometheus.NewCounterVec(
@@ -27,9 +41,76 @@ var (
 		},
 		[]string{"name"},
 	)
+
+	// 按消息标签统计的请求数，仅在配置 MetricsTags 时记录
+	enginTaggedRequestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "rule",
+			Subsystem: "engine",
+			Name:      "tagged_requests_total",
+			Help:      "Total requests by message tag",
+		},
+		[]string{"name", "status", "tag"},
+	)
+
+	// 切面耗时
+	aspectDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "rule",
+			Subsystem: "aspect",
+			Name:      "duration_seconds",
+			Help:      "Aspect invocation latency",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"aspect", "point"},
+	)
 )
 
 func init() {
 	// 注册指标
-	prometheus.MustRegister(enginRequestsTotal, enginRequestDuration)
+	prometheus.MustRegister(enginRequestsTotal, enginRequestDuration, enginTaggedRequestsTotal, aspectDuration)
+}
+
+// aspectStart returns the start time of an aspect invocation,
+// or the zero time if aspect metrics are disabled
+func aspectStart(config types.Config) time.Time {
+	if config.DisableAspectMetrics {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// observeAspect records the duration of an aspect invocation started at start
+func observeAspect(aspect types.Aspect, point string, start time.Time) {
+	if start.IsZero() {
+		return
+	}
+	aspectDuration.WithLabelValues(aspectType(aspect), point).Observe(time.Since(start).Seconds())
+}
+
+// aspectType returns the aspect type used as metrics label,
+// falling back to the Go type name if the aspect has no Type method
+func aspectType(aspect types.Aspect) string {
+	if typed, ok := aspect.(interface{ Type() string }); ok {
+		return typed.Type()
+	}
+	return fmt.Sprintf("%T", aspect)
+}
+
+// observeTags counts a request once per message tag when config.MetricsTags is set.
+// Tags not listed in config.MetricsTags share the types.MetricsTagOther label, which bounds the cardinality
+func observeTags(config types.Config, name, status string, msg types.RuleMsg) {
+	if len(config.MetricsTags) == 0 {
+		return
+	}
+	var other bool
+	for _, tag := range msg.Tags() {
+		if !slices.Contains(config.MetricsTags, tag) {
+			if other {
+				continue
+			}
+			tag, other = types.MetricsTagOther, true
+		}
+		enginTaggedRequestsTotal.WithLabelValues(name, status, tag).Inc()
+	}
 }
@@ -17,58 +17,202 @@
 package engine
 
 import (
+	"bytes"
+	"fmt"
+
 	"github.com/bittoy/rule/types"
 	"github.com/bittoy/rule/utils/json"
 )
 
 // JsonParser Json
 type JsonParser struct {
+	// Codec 编解码 DSL 使用的 JSON 实现，为 nil 时使用 encoding/json，参见 types.JSONCodec
+	// Codec is the JSON implementation decoding and encoding the DSL, encoding/json when nil, see types.JSONCodec
+	Codec types.JSONCodec
+}
+
+// importMarker 存在节点模板引用时 DSL 中必然出现的内容，不包含时跳过模板展开
+var importMarker = []byte(`"` + types.ImportKey + `"`)
+
+func (p *JsonParser) codec() types.JSONCodec {
+	if p.Codec != nil {
+		return p.Codec
+	}
+	return json.DefaultCodec
 }
 
 // DecodeRuleChain 通过json解析规则链结构体
+// 聚合顶层的 templates 对所有子规则链可见，子规则链的同名模板优先
 func (p *JsonParser) DecodeChainAggregation(chainAggregationDef []byte) (types.ChainAggregation, error) {
 	var def types.ChainAggregation
-	err := json.Unmarshal(chainAggregationDef, &def)
+	codec := p.codec()
+	if !bytes.Contains(chainAggregationDef, importMarker) {
+		err := codec.Unmarshal(chainAggregationDef, &def)
+		return def, err
+	}
+	var doc map[string]any
+	if err := codec.Unmarshal(chainAggregationDef, &doc); err != nil {
+		return def, err
+	}
+	templates, _ := doc[types.TemplatesKey].(map[string]any)
+	delete(doc, types.TemplatesKey)
+	metadata, _ := doc["metadata"].(map[string]any)
+	chains, _ := metadata["chains"].([]any)
+	for _, item := range chains {
+		chain, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		if err := expandChainTemplates(chain, templates); err != nil {
+			return def, fmt.Errorf("chain %v: %w", chain["id"], err)
+		}
+	}
+	v, err := codec.Marshal(doc)
+	if err != nil {
+		return def, err
+	}
+	err = codec.Unmarshal(v, &def)
 	return def, err
 }
 
 func (p *JsonParser) EncodeChainAggregation(def interface{}) ([]byte, error) {
-	if v, err := json.Marshal(def); err != nil {
-		return nil, err
-	} else {
-		//格式化Json
-		return json.Format(v)
-	}
+	return p.encode(def)
 }
 
-// DecodeRuleChain 通过json解析规则链结构体
+// DecodeRuleChain 通过json解析规则链结构体，并展开节点模板引用
 func (p *JsonParser) DecodeChain(chainDef []byte) (types.Chain, error) {
 	var def types.Chain
-	err := json.Unmarshal(chainDef, &def)
+	codec := p.codec()
+	v := chainDef
+	if bytes.Contains(chainDef, importMarker) {
+		var err error
+		if v, err = resolveChainTemplates(codec, chainDef); err != nil {
+			return def, err
+		}
+	}
+	err := codec.Unmarshal(v, &def)
 	return def, err
 }
 
 // DecodeRuleNode 通过json解析节点结构体
 func (p *JsonParser) DecodeRule(ruleDef []byte) (types.BaseInfo, error) {
 	var def types.BaseInfo
-	err := json.Unmarshal(ruleDef, &def)
+	err := p.codec().Unmarshal(ruleDef, &def)
 	return def, err
 }
 
 func (p *JsonParser) EncodeChain(def interface{}) ([]byte, error) {
-	if v, err := json.Marshal(def); err != nil {
+	return p.encode(def)
+}
+
+func (p *JsonParser) EncodeRule(def interface{}) ([]byte, error) {
+	return p.encode(def)
+}
+
+// encode 序列化并格式化Json
+func (p *JsonParser) encode(def interface{}) ([]byte, error) {
+	codec := p.codec()
+	if v, err := codec.Marshal(def); err != nil {
 		return nil, err
 	} else {
 		//格式化Json
-		return json.Format(v)
+		return codec.Format(v)
 	}
 }
 
-func (p *JsonParser) EncodeRule(def interface{}) ([]byte, error) {
-	if v, err := json.Marshal(def); err != nil {
+// resolveChainTemplates 展开规则链 DSL 中的节点模板引用
+// resolveChainTemplates expands the node template imports of a chain DSL.
+//
+// 节点通过 "$import" 引用顶层 "templates" 中的命名模板，节点自身的字段覆盖模板字段，
+// configuration 按键合并。模板也可以引用其他模板，循环引用返回 types.ErrTemplateCycle。
+// A node references a named template of the top-level "templates" section with "$import".
+// The node fields override the template fields, configuration is merged key by key.
+// Templates may import other templates, an import cycle returns types.ErrTemplateCycle.
+//
+//	{
+//	  "templates": {"start": {"type": "start", "name": "开始"}},
+//	  "metadata": {"nodes": [{"id": "s1", "$import": "start"}]}
+//	}
+func resolveChainTemplates(codec types.JSONCodec, chainDef []byte) ([]byte, error) {
+	var doc map[string]any
+	if err := codec.Unmarshal(chainDef, &doc); err != nil {
 		return nil, err
-	} else {
-		//格式化Json
-		return json.Format(v)
 	}
+	if err := expandChainTemplates(doc, nil); err != nil {
+		return nil, err
+	}
+	return codec.Marshal(doc)
+}
+
+// expandChainTemplates 展开规则链文档中的节点模板引用，parent 为上级（规则链聚合）的模板
+func expandChainTemplates(doc map[string]any, parent map[string]any) error {
+	templates := mergeTemplates(parent, doc[types.TemplatesKey])
+	delete(doc, types.TemplatesKey)
+	metadata, _ := doc["metadata"].(map[string]any)
+	nodes, _ := metadata["nodes"].([]any)
+	for i, item := range nodes {
+		node, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		resolved, err := resolveNodeTemplate(node, templates, map[string]bool{})
+		if err != nil {
+			return fmt.Errorf("node %v: %w", node["id"], err)
+		}
+		nodes[i] = resolved
+	}
+	return nil
+}
+
+// mergeTemplates 合并上级模板和当前模板，同名时当前模板优先
+func mergeTemplates(parent map[string]any, value any) map[string]any {
+	own, _ := value.(map[string]any)
+	if len(parent) == 0 {
+		return own
+	}
+	templates := make(map[string]any, len(parent)+len(own))
+	for name, template := range parent {
+		templates[name] = template
+	}
+	for name, template := range own {
+		templates[name] = template
+	}
+	return templates
+}
+
+// resolveNodeTemplate 将节点与其引用的模板合并，visiting 用于检测循环引用
+func resolveNodeTemplate(node map[string]any, templates map[string]any, visiting map[string]bool) (map[string]any, error) {
+	value, ok := node[types.ImportKey]
+	if !ok {
+		return node, nil
+	}
+	name, _ := value.(string)
+	if visiting[name] {
+		return nil, fmt.Errorf("%w: %s", types.ErrTemplateCycle, name)
+	}
+	template, ok := templates[name].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", types.ErrTemplateNotFound, name)
+	}
+	visiting[name] = true
+	template, err := resolveNodeTemplate(template, templates, visiting)
+	if err != nil {
+		return nil, err
+	}
+	delete(visiting, name)
+
+	merged := make(map[string]any, len(template)+len(node))
+	for key, v := range template {
+		merged[key] = v
+	}
+	for key, v := range node {
+		merged[key] = v
+	}
+	delete(merged, types.ImportKey)
+	templateConfiguration, _ := template["configuration"].(map[string]any)
+	nodeConfiguration, _ := node["configuration"].(map[string]any)
+	if templateConfiguration != nil && nodeConfiguration != nil {
+		merged["configuration"] = map[string]any(types.Configuration(nodeConfiguration).MergeDefaults(templateConfiguration))
+	}
+	return merged, nil
 }
@@ -0,0 +1,151 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/bittoy/rule/types"
+	"github.com/bittoy/rule/utils/json"
+	"github.com/rulego/rulego/test/assert"
+)
+
+// largeAggregationDsl returns an aggregation of chains, each a start node, a line of exprAssign nodes and an end node.
+func largeAggregationDsl(chains, nodes int) []byte {
+	var sb strings.Builder
+	sb.WriteString(`{"id":"large","name":"large","metadata":{"chains":[`)
+	for c := 0; c < chains; c++ {
+		if c > 0 {
+			sb.WriteString(",")
+		}
+		fmt.Fprintf(&sb, `{"id":"c%d","name":"c%d","priority":%d,"metadata":{"nodes":[{"id":"s","type":"start"}`, c, c, c)
+		for n := 0; n < nodes; n++ {
+			fmt.Fprintf(&sb, `,{"id":"n%d","type":"exprAssign","configuration":{"script":"{'v%d': score + %d}"}}`, n, n, n)
+		}
+		sb.WriteString(`,{"id":"e","type":"end","configuration":{"script":"{'Score': score}"}}],"connections":[`)
+		prev := "s"
+		for n := 0; n < nodes; n++ {
+			fmt.Fprintf(&sb, `{"fromId":"%s","toId":"n%d","type":"default"},`, prev, n)
+			prev = fmt.Sprintf("n%d", n)
+		}
+		fmt.Fprintf(&sb, `{"fromId":"%s","toId":"e","type":"default"}]}}`, prev)
+	}
+	sb.WriteString(`]}}`)
+	return []byte(sb.String())
+}
+
+// countingCodec counts the calls to the wrapped codec.
+type countingCodec struct {
+	json.Codec
+	unmarshal int
+}
+
+func (c *countingCodec) Unmarshal(b []byte, v interface{}) error {
+	c.unmarshal++
+	return c.Codec.Unmarshal(b, v)
+}
+
+func TestJSONCodec(t *testing.T) {
+	codec := &countingCodec{}
+	config := NewConfig(types.WithJSONCodec(codec), types.WithLogger(log.New(io.Discard, "", 0)))
+	e, err := NewChainAggregationEngine(largeAggregationDsl(2, 3), WithConfig(config))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, codec.unmarshal)
+	def, err := config.Parser.DecodeChainAggregation(e.DSL())
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(def.Metadata.Chains))
+	assert.Equal(t, 5, len(def.Metadata.Chains[0].Metadata.Nodes))
+}
+
+// TestNodeTemplates checks that a node importing a template gets the template fields with its own fields and
+// configuration keys overriding them, also through a nested import, and that an import cycle or a missing
+// template fails to decode.
+func TestNodeTemplates(t *testing.T) {
+	parser := &JsonParser{}
+	def, err := parser.DecodeChain([]byte(`{"id":"tpl","templates":{
+"assign":{"type":"exprAssign","name":"assign","configuration":{"script":"{'x': 1}","outputKey":"o"}},
+"named":{"$import":"assign","name":"named"}
+},"metadata":{"nodes":[
+{"id":"a","$import":"assign","configuration":{"script":"{'x': 2}"}},
+{"id":"b","$import":"named"}
+]}}`))
+	assert.Nil(t, err)
+	a, b := def.Metadata.Nodes[0], def.Metadata.Nodes[1]
+	assert.Equal(t, "a", a.Id)
+	assert.Equal(t, types.RuleSubTypeExprAssign, a.Type)
+	assert.Equal(t, "assign", a.Name)
+	assert.Equal(t, types.Configuration{"script": "{'x': 2}", "outputKey": "o"}, a.Configuration)
+	assert.Equal(t, types.RuleSubTypeExprAssign, b.Type)
+	assert.Equal(t, "named", b.Name)
+	assert.Equal(t, types.Configuration{"script": "{'x': 1}", "outputKey": "o"}, b.Configuration)
+
+	_, err = parser.DecodeChain([]byte(`{"id":"tpl","templates":{
+"a":{"$import":"b"},"b":{"$import":"a"}
+},"metadata":{"nodes":[{"id":"n","$import":"a"}]}}`))
+	assert.True(t, errors.Is(err, types.ErrTemplateCycle))
+
+	_, err = parser.DecodeChain([]byte(`{"id":"tpl","metadata":{"nodes":[{"id":"n","$import":"missing"}]}}`))
+	assert.True(t, errors.Is(err, types.ErrTemplateNotFound))
+}
+
+func BenchmarkDecodeChainAggregation(b *testing.B) {
+	dsl := largeAggregationDsl(20, 50)
+	parser := &JsonParser{}
+	b.ReportAllocs()
+	b.SetBytes(int64(len(dsl)))
+	for i := 0; i < b.N; i++ {
+		if _, err := parser.DecodeChainAggregation(dsl); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReloadChainAggregation(b *testing.B) {
+	dsl := largeAggregationDsl(20, 50)
+	config := NewConfig(types.WithLogger(log.New(io.Discard, "", 0)))
+	e, err := NewChainAggregationEngine(dsl, WithConfig(config))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := e.ReloadSelf(dsl); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkChainAggregationDSL(b *testing.B) {
+	config := NewConfig(types.WithLogger(log.New(io.Discard, "", 0)))
+	e, err := NewChainAggregationEngine(largeAggregationDsl(20, 50), WithConfig(config))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if len(e.DSL()) == 0 {
+			b.Fatal("empty dsl")
+		}
+	}
+}
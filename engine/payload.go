@@ -0,0 +1,111 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/bittoy/rule/types"
+	"google.golang.org/protobuf/proto"
+)
+
+// checkPayloadSize returns types.ErrPayloadTooLarge when the estimated size of the message input exceeds
+// Config.MaxPayloadSize, see types.Config.MaxPayloadSize.
+// checkPayloadSize 在消息输入的估算大小超过 Config.MaxPayloadSize 时返回 types.ErrPayloadTooLarge。
+func checkPayloadSize(config types.Config, msg types.RuleMsg) error {
+	if config.MaxPayloadSize <= 0 {
+		return nil
+	}
+	var size int
+	if payload := msg.Payload(); payload != nil {
+		size = proto.Size(payload)
+	} else {
+		size = payloadSize(msg.GetInput(), config.MaxPayloadSize)
+	}
+	if size > config.MaxPayloadSize {
+		return fmt.Errorf("%w: msg:%s size:%d max:%d", types.ErrPayloadTooLarge, msg.Id(), size, config.MaxPayloadSize)
+	}
+	return nil
+}
+
+// payloadSize estimates the JSON size of value in bytes, numbers count 8 bytes whatever their value.
+// The walk stops once the size exceeds limit, the result is then only known to be above limit.
+// payloadSize 估算 value 的 JSON 大小（字节），数值无论大小按 8 字节计算。大小超过 limit 后停止遍历，此时结果只保证大于 limit。
+func payloadSize(value any, limit int) int {
+	switch v := value.(type) {
+	case nil:
+		return 4
+	case bool:
+		return 5
+	case string:
+		return len(v) + 2
+	case []byte:
+		return (len(v)+2)/3*4 + 2
+	case map[string]any:
+		size := 2
+		for k, item := range v {
+			if size > limit {
+				break
+			}
+			size += len(k) + 4 + payloadSize(item, limit-size)
+		}
+		return size
+	case []any:
+		size := 2
+		for _, item := range v {
+			if size > limit {
+				break
+			}
+			size += 1 + payloadSize(item, limit-size)
+		}
+		return size
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return 8
+	case reflect.String:
+		return rv.Len() + 2
+	case reflect.Slice, reflect.Array:
+		size := 2
+		for i := 0; i < rv.Len() && size <= limit; i++ {
+			size += 1 + payloadSize(rv.Index(i).Interface(), limit-size)
+		}
+		return size
+	case reflect.Map:
+		size := 2
+		for iter := rv.MapRange(); iter.Next() && size <= limit; {
+			size += 2 + payloadSize(iter.Key().Interface(), limit-size) + payloadSize(iter.Value().Interface(), limit-size)
+		}
+		return size
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return 4
+		}
+		return payloadSize(rv.Elem().Interface(), limit)
+	}
+	// structs and other values are encoded to know their size
+	data, err := json.Marshal(value)
+	if err != nil {
+		return 0
+	}
+	return len(data)
+}
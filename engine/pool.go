@@ -0,0 +1,76 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engine
+
+import (
+	"sync"
+
+	"github.com/bittoy/rule/types"
+)
+
+// Compile-time check Pool implements types.EnginePool.
+var _ types.EnginePool = (*Pool)(nil)
+
+// Pool holds engines by chain id, so chains can run each other, e.g. with the expr runChain function,
+// see types.Config.EnginePool. It is safe for concurrent use.
+//
+// Pool 按规则链 id 保存引擎，使规则链可以相互调用，例如通过 expr 的 runChain 函数，参见 types.Config.EnginePool。
+// 它是并发安全的。
+//
+// Usage:
+// 使用方法：
+//
+//	pool := engine.NewPool()
+//	config := engine.NewConfig(types.WithEnginePool(pool))
+//	scoring, err := engine.NewChainEngine(scoringDef, engine.WithConfig(config))
+//	...
+//	pool.Add(scoring)
+type Pool struct {
+	mu      sync.RWMutex
+	engines map[string]types.Engine
+}
+
+// NewPool creates an empty engine pool.
+// NewPool 创建空的引擎池。
+func NewPool() *Pool {
+	return &Pool{engines: map[string]types.Engine{}}
+}
+
+// Add adds the engine under its chain id, replacing the engine of the same id.
+// Add 以规则链 id 添加引擎，替换相同 id 的引擎。
+func (p *Pool) Add(engine types.Engine) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.engines[engine.Id()] = engine
+}
+
+// Get returns the engine of the chain id, false when there is none.
+// Get 返回规则链 id 对应的引擎，不存在时返回 false。
+func (p *Pool) Get(id string) (types.Engine, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	engine, ok := p.engines[id]
+	return engine, ok
+}
+
+// Delete removes the engine of the chain id from the pool, the engine is not stopped.
+// Delete 从池中移除规则链 id 对应的引擎，不会停止该引擎。
+func (p *Pool) Delete(id string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.engines, id)
+}
@@ -0,0 +1,66 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engine
+
+import (
+	"context"
+	"io"
+	"runtime/pprof"
+	"sync"
+	"sync/atomic"
+)
+
+// ProfileLabelChain is the pprof label holding the id of the chain a CPU sample was taken in.
+// ProfileLabelChain 是保存 CPU 采样所在规则链 id 的 pprof 标签。
+const ProfileLabelChain = "chain"
+
+var (
+	// profiling reports whether a CPU profile started by EnableProfiling is running
+	profiling atomic.Bool
+	// profilingMu serializes starting and stopping the CPU profile
+	profilingMu sync.Mutex
+)
+
+// startProfiling starts the process wide CPU profile written to w and labels the chain executions
+func startProfiling(w io.Writer) error {
+	profilingMu.Lock()
+	defer profilingMu.Unlock()
+	if err := pprof.StartCPUProfile(w); err != nil {
+		return err
+	}
+	profiling.Store(true)
+	return nil
+}
+
+// stopProfiling stops the CPU profile started by startProfiling, flushing it to its writer
+func stopProfiling() {
+	profilingMu.Lock()
+	defer profilingMu.Unlock()
+	if profiling.CompareAndSwap(true, false) {
+		pprof.StopCPUProfile()
+	}
+}
+
+// withChainLabel runs fn with the chain id as pprof label while a CPU profile is running,
+// so the samples taken in fn are attributed to the chain
+func withChainLabel(ctx context.Context, chainId string, fn func(ctx context.Context)) {
+	if !profiling.Load() {
+		fn(ctx)
+		return
+	}
+	pprof.Do(ctx, pprof.Labels(ProfileLabelChain, chainId), fn)
+}
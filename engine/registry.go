@@ -17,8 +17,12 @@
 package engine
 
 import (
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"sync"
+	"unicode"
 
 	"github.com/bittoy/rule/components/common"
 	"github.com/bittoy/rule/components/transform"
@@ -33,15 +37,19 @@ var Registry = new(RuleComponentRegistry)
 
 // init registers default components to the default component registry.
 func init() {
+	// Register all components to the default component registry.
+	for _, node := range builtinComponents() {
+		_ = Registry.Register(node)
+	}
+}
+
+// builtinComponents returns the components registered by the built-in component packages.
+func builtinComponents() []types.Node {
 	var components []types.Node
 	// Append components from various packages to the components slice.
 	components = append(components, common.Registry.Components()...)
 	components = append(components, transform.Registry.Components()...)
-
-	// Register all components to the default component registry.
-	for _, node := range components {
-		_ = Registry.Register(node)
-	}
+	return components
 }
 
 // RuleComponentRegistry is a registry for rule engine components.
@@ -53,7 +61,14 @@ type RuleComponentRegistry struct {
 }
 
 // Register adds a rule engine node component to the registry.
+// The component type must be valid, see validateComponentType.
 func (r *RuleComponentRegistry) Register(node types.Node) error {
+	if node == nil {
+		return errors.New("the component is nil")
+	}
+	if err := validateComponentType(node.Type()); err != nil {
+		return err
+	}
 	r.Lock()
 	defer r.Unlock()
 	if r.components == nil {
@@ -67,6 +82,48 @@ func (r *RuleComponentRegistry) Register(node types.Node) error {
 	return nil
 }
 
+// RegisterOrReplace adds a rule engine node component to the registry, replacing any
+// component already registered with the same type. The replacement is logged.
+// Unlike Unregister followed by Register, there is no window in which the type is missing,
+// so it can be used to update a component implementation at runtime, e.g. on plugin hot-reload.
+// Chains already initialized keep their node instances, only new instances use the replacement.
+func (r *RuleComponentRegistry) RegisterOrReplace(node types.Node) error {
+	if node == nil {
+		return errors.New("the component is nil")
+	}
+	if err := validateComponentType(node.Type()); err != nil {
+		return err
+	}
+	r.Lock()
+	defer r.Unlock()
+	if r.components == nil {
+		r.components = make(map[types.NodeType]types.Node)
+	}
+	if _, ok := r.components[node.Type()]; ok {
+		types.DefaultLogger().Printf("replace the component. componentType=%s", node.Type())
+	}
+	r.components[node.Type()] = node
+	return nil
+}
+
+// validateComponentType checks that a component type is not empty and has no whitespace, and that a
+// namespaced type has the form namespace:type, see types.NamespaceSeparator
+func validateComponentType(componentType types.NodeType) error {
+	if componentType == "" {
+		return errors.New("the component type is empty")
+	}
+	if strings.ContainsFunc(string(componentType), unicode.IsSpace) {
+		return fmt.Errorf("the component type contains whitespace. componentType=%q", componentType)
+	}
+	if strings.Contains(string(componentType), types.NamespaceSeparator) {
+		parts := strings.Split(string(componentType), types.NamespaceSeparator)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return fmt.Errorf("the component type must have the form namespace%stype. componentType=%s", types.NamespaceSeparator, componentType)
+		}
+	}
+	return nil
+}
+
 // Unregister removes a component from the registry by its type or plugin name.
 func (r *RuleComponentRegistry) Unregister(componentType types.NodeType) error {
 	r.Lock()
@@ -109,3 +166,124 @@ func (r *RuleComponentRegistry) GetComponents() map[types.NodeType]types.Node {
 	}
 	return components
 }
+
+// GetComponentsSorted returns the registered components ordered by type, so listings such as
+// UI palettes and golden files are stable across runs.
+func (r *RuleComponentRegistry) GetComponentsSorted() []types.Node {
+	r.RLock()
+	defer r.RUnlock()
+	components := make([]types.Node, 0, len(r.components))
+	for _, v := range r.components {
+		components = append(components, v)
+	}
+	slices.SortFunc(components, func(a, b types.Node) int {
+		return strings.Compare(string(a.Type()), string(b.Type()))
+	})
+	return components
+}
+
+// GetComponent returns the registered component prototype of the given type.
+func (r *RuleComponentRegistry) GetComponent(componentType types.NodeType) (types.Node, bool) {
+	r.RLock()
+	defer r.RUnlock()
+	node, ok := r.components[componentType]
+	return node, ok
+}
+
+// Snapshot returns a copy of the registered components, which can later be passed to Restore.
+// It is typically used by tests to save the registry state before registering custom components.
+//
+//	snapshot := engine.Registry.Snapshot()
+//	defer engine.Registry.Restore(snapshot)
+//	_ = engine.Registry.Register(&MyNode{})
+func (r *RuleComponentRegistry) Snapshot() map[types.NodeType]types.Node {
+	return r.GetComponents()
+}
+
+// Restore replaces the registered components with the given snapshot.
+// Components registered after the snapshot was taken are removed.
+func (r *RuleComponentRegistry) Restore(snapshot map[types.NodeType]types.Node) {
+	components := make(map[types.NodeType]types.Node, len(snapshot))
+	for k, v := range snapshot {
+		components[k] = v
+	}
+	r.Lock()
+	defer r.Unlock()
+	r.components = components
+}
+
+// Clone returns an independent registry pre-populated with the built-in components.
+// Components registered into the clone do not affect the receiver or the default Registry.
+func (r *RuleComponentRegistry) Clone() *RuleComponentRegistry {
+	clone := new(RuleComponentRegistry)
+	for _, node := range builtinComponents() {
+		_ = clone.Register(node)
+	}
+	return clone
+}
+
+// GetCategories groups the registered component types by category.
+// Components that don't implement types.CategoryGetter are grouped under types.CategoryOther.
+// Component types in each category are sorted.
+func (r *RuleComponentRegistry) GetCategories() map[string][]types.NodeType {
+	r.RLock()
+	defer r.RUnlock()
+	var categories = map[string][]types.NodeType{}
+	for k, v := range r.components {
+		category := types.CategoryOther
+		if getter, ok := v.(types.CategoryGetter); ok && getter.Category() != "" {
+			category = getter.Category()
+		}
+		categories[category] = append(categories[category], k)
+	}
+	for _, componentTypes := range categories {
+		slices.Sort(componentTypes)
+	}
+	return categories
+}
+
+// aliasRegistry is a component registry resolving the node types of types.Config.TypeAliases
+// that are not registered themselves
+type aliasRegistry struct {
+	types.ComponentRegistry
+	aliases map[types.NodeType]types.NodeType
+}
+
+// withTypeAliases returns config with a registry resolving its type aliases, an error when an alias
+// does not resolve to a registered component type
+func withTypeAliases(config types.Config) (types.Config, error) {
+	if len(config.TypeAliases) == 0 {
+		return config, nil
+	}
+	if _, ok := config.ComponentsRegistry.(*aliasRegistry); ok {
+		return config, nil
+	}
+	for alias, componentType := range config.TypeAliases {
+		if _, ok := config.ComponentsRegistry.GetComponent(componentType); !ok {
+			return config, fmt.Errorf("type alias %s: component not found. componentType=%s", alias, componentType)
+		}
+	}
+	config.ComponentsRegistry = &aliasRegistry{ComponentRegistry: config.ComponentsRegistry, aliases: config.TypeAliases}
+	return config, nil
+}
+
+// resolve returns the component type of the node type, the alias target when only the alias is known
+func (r *aliasRegistry) resolve(componentType types.NodeType) types.NodeType {
+	if _, ok := r.ComponentRegistry.GetComponent(componentType); ok {
+		return componentType
+	}
+	if target, ok := r.aliases[componentType]; ok {
+		return target
+	}
+	return componentType
+}
+
+// NewNode creates a new instance of the component of the node type or of its alias.
+func (r *aliasRegistry) NewNode(componentType types.NodeType) (types.Node, error) {
+	return r.ComponentRegistry.NewNode(r.resolve(componentType))
+}
+
+// GetComponent returns the component of the node type or of its alias.
+func (r *aliasRegistry) GetComponent(componentType types.NodeType) (types.Node, bool) {
+	return r.ComponentRegistry.GetComponent(r.resolve(componentType))
+}
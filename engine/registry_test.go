@@ -0,0 +1,120 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engine
+
+import (
+	"testing"
+
+	"github.com/bittoy/rule/types"
+	"github.com/rulego/rulego/test/assert"
+)
+
+// TestRegistrySnapshot checks that changing a snapshot, a restored registry or a clone does not affect the
+// registry they were taken from.
+func TestRegistrySnapshot(t *testing.T) {
+	registry := Registry.Clone()
+	builtins := len(registry.GetComponents())
+	assert.True(t, builtins > 0)
+
+	snapshot := registry.Snapshot()
+	assert.Nil(t, registry.Register(&typedNode{nodeType: "custom"}))
+	_, ok := snapshot["custom"]
+	assert.False(t, ok)
+	delete(snapshot, types.RuleSubTypeExprAssign)
+	_, ok = registry.GetComponent(types.RuleSubTypeExprAssign)
+	assert.True(t, ok)
+	snapshot[types.RuleSubTypeExprAssign] = Registry.GetComponents()[types.RuleSubTypeExprAssign]
+
+	registry.Restore(snapshot)
+	_, ok = registry.GetComponent("custom")
+	assert.False(t, ok)
+	assert.Equal(t, builtins, len(registry.GetComponents()))
+	delete(snapshot, types.RuleSubTypeExprAssign)
+	_, ok = registry.GetComponent(types.RuleSubTypeExprAssign)
+	assert.True(t, ok)
+	assert.Nil(t, registry.Register(&typedNode{nodeType: "restored"}))
+	_, ok = snapshot["restored"]
+	assert.False(t, ok)
+
+	clone := registry.Clone()
+	assert.Nil(t, clone.Register(&typedNode{nodeType: "cloned"}))
+	assert.Nil(t, clone.Unregister(types.RuleSubTypeExprAssign))
+	_, ok = registry.GetComponent("cloned")
+	assert.False(t, ok)
+	_, ok = registry.GetComponent(types.RuleSubTypeExprAssign)
+	assert.True(t, ok)
+	_, ok = Registry.GetComponent("cloned")
+	assert.False(t, ok)
+	// A clone holds the built-ins only, not the components registered into the receiver
+	_, ok = clone.GetComponent("restored")
+	assert.False(t, ok)
+}
+
+// TestGetCategories checks that the components are grouped by category, with the types of each category sorted.
+func TestGetCategories(t *testing.T) {
+	registry := new(RuleComponentRegistry)
+	for _, nodeType := range []types.NodeType{types.RuleSubTypeExprSwitch, types.RuleSubTypeExprFilter, "jsFilter",
+		types.RuleSubTypeExprAssign, "end", "start"} {
+		node, ok := Registry.GetComponent(nodeType)
+		assert.True(t, ok, nodeType)
+		assert.Nil(t, registry.Register(node))
+	}
+	assert.Nil(t, registry.Register(&typedNode{nodeType: "custom"}))
+	assert.Equal(t, map[string][]types.NodeType{
+		types.CategoryFilter:    {"exprFilter", "jsFilter"},
+		types.CategorySwitch:    {"exprSwitch"},
+		types.CategoryTransform: {"exprAssign"},
+		types.CategoryFlow:      {"end", "start"},
+		types.CategoryOther:     {"custom"},
+	}, registry.GetCategories())
+}
+
+// TestGetComponent checks that GetComponent returns the registered prototype of a type, and false for an
+// unknown type.
+func TestGetComponent(t *testing.T) {
+	registry := new(RuleComponentRegistry)
+	node := &typedNode{nodeType: "custom"}
+	assert.Nil(t, registry.Register(node))
+	found, ok := registry.GetComponent("custom")
+	assert.True(t, ok)
+	assert.True(t, found == types.Node(node))
+
+	found, ok = registry.GetComponent("unknown")
+	assert.False(t, ok)
+	assert.Nil(t, found)
+
+	_, ok = Registry.GetComponent(types.RuleSubTypeExprAssign)
+	assert.True(t, ok)
+}
+
+// TestGetComponentsSorted checks that the components are listed by type whatever the registration order.
+func TestGetComponentsSorted(t *testing.T) {
+	want := []types.NodeType{"a", "b", "ns:c", "z"}
+	for _, order := range [][]types.NodeType{{"z", "ns:c", "a", "b"}, {"b", "a", "z", "ns:c"}} {
+		registry := new(RuleComponentRegistry)
+		for _, nodeType := range order {
+			assert.Nil(t, registry.Register(&typedNode{nodeType: nodeType}))
+		}
+		for i := 0; i < 3; i++ {
+			var listed []types.NodeType
+			for _, node := range registry.GetComponentsSorted() {
+				listed = append(listed, node.Type())
+			}
+			assert.Equal(t, want, listed)
+		}
+	}
+}
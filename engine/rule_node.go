@@ -18,9 +18,11 @@ package engine
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/bittoy/rule/types"
+	"github.com/bittoy/rule/utils/maps"
 )
 
 const (
@@ -136,7 +138,10 @@ func initRuleNodeCtx(config types.Config, chainCtx *ChainCtx, aspects types.Aspe
 	//beforeAspect, afterAspect := aspects.GetNodeAspects()
 	onNodeBeforeInitAspects := aspects.GetOnNodeBeforeInitAspects()
 	for _, aspect := range onNodeBeforeInitAspects {
-		if err := aspect.OnNodeBeforeInit(config, selfDefinition); err != nil {
+		start := aspectStart(config)
+		err := aspect.OnNodeBeforeInit(config, selfDefinition)
+		observeAspect(aspect, aspectPointBeforeInit, start)
+		if err != nil {
 			return nil, fmt.Errorf("nodeType:%s for id:%s OnNodeBeforeInit error:%s", selfDefinition.Type, selfDefinition.Id, err.Error())
 		}
 	}
@@ -148,6 +153,10 @@ func initRuleNodeCtx(config types.Config, chainCtx *ChainCtx, aspects types.Aspe
 
 	// Initialize the node with the processed configuration.
 	if err = node.Init(config, selfDefinition.Configuration); err != nil {
+		var decodeErr *maps.DecodeError
+		if errors.As(err, &decodeErr) {
+			return nil, fmt.Errorf("nodeType:%s for id:%s invalid configuration: %w", selfDefinition.Type, selfDefinition.Id, err)
+		}
 		return nil, fmt.Errorf("nodeType:%s for id:%s init error:%s", selfDefinition.Type, selfDefinition.Id, err.Error())
 	}
 
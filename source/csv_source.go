@@ -0,0 +1,276 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package source provides adapters that read records from external sources,
+// map them to RuleMsg and feed them through an Engine.
+//
+// Package source 提供从外部数据源读取记录、转换为 RuleMsg 并输入规则引擎的适配器。
+package source
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/bittoy/rule/types"
+)
+
+// ErrorStrategy defines how a source reacts to a failed record.
+// ErrorStrategy 定义数据源如何处理失败的记录。
+type ErrorStrategy string
+
+const (
+	// ErrorSkip skips the failed record and continues  跳过失败记录并继续
+	ErrorSkip ErrorStrategy = "skip"
+	// ErrorStop stops reading at the first failed record  遇到第一条失败记录时停止
+	ErrorStop ErrorStrategy = "stop"
+)
+
+// DefaultBatchSize is the default number of records processed concurrently.
+// DefaultBatchSize 是默认并发处理的记录数。
+const DefaultBatchSize = 1
+
+// Stats summarizes a source run.
+// Stats 汇总一次数据源运行的结果。
+type Stats struct {
+	// Total is the number of records read  读取的记录数
+	Total int
+	// Success is the number of records processed successfully  处理成功的记录数
+	Success int
+	// Failed is the number of records that failed to parse or process  解析或处理失败的记录数
+	Failed int
+}
+
+// CSVOption is a function type that modifies the CSVSource.
+// CSVOption 是修改 CSVSource 的函数类型。
+type CSVOption func(*CSVSource)
+
+// WithBatchSize sets the number of records sent to the engine concurrently.
+// WithBatchSize 设置并发发送给引擎的记录数。
+func WithBatchSize(batchSize int) CSVOption {
+	return func(s *CSVSource) {
+		s.batchSize = batchSize
+	}
+}
+
+// WithErrorStrategy sets how failed records are handled.
+// WithErrorStrategy 设置失败记录的处理方式。
+func WithErrorStrategy(strategy ErrorStrategy) CSVOption {
+	return func(s *CSVSource) {
+		s.errorStrategy = strategy
+	}
+}
+
+// WithComma sets the field delimiter, defaulting to ','.
+// WithComma 设置字段分隔符，默认为 ','。
+func WithComma(comma rune) CSVOption {
+	return func(s *CSVSource) {
+		s.comma = comma
+	}
+}
+
+// WithTypeInference enables or disables type inference of field values.
+// When disabled, every value is passed as a string.
+// WithTypeInference 启用或禁用字段值类型推断。禁用时所有值都作为字符串传递。
+func WithTypeInference(enabled bool) CSVOption {
+	return func(s *CSVSource) {
+		s.inferTypes = enabled
+	}
+}
+
+// CSVSource reads a CSV file, maps each row to a RuleMsg and streams it through an Engine.
+// The first row is the header, header names become the input keys.
+//
+// CSVSource 读取 CSV 文件，将每一行转换为 RuleMsg 并输入规则引擎。
+// 第一行是表头，表头名称作为输入的键。
+//
+// Type inference:
+// 类型推断：
+//   - Integers become int, decimals become float64  整数转为 int，小数转为 float64
+//   - "true"/"false" (case insensitive) become bool  "true"/"false"（不区分大小写）转为 bool
+//   - Everything else stays a string  其他值保持字符串
+//
+// Usage:
+// 使用方法：
+//
+//	src := source.NewCSVSource("orders.csv", source.WithBatchSize(16), source.WithErrorStrategy(source.ErrorSkip))
+//	stats, err := src.Run(ctx, ruleEngine)
+type CSVSource struct {
+	path          string
+	batchSize     int
+	errorStrategy ErrorStrategy
+	comma         rune
+	inferTypes    bool
+}
+
+// NewCSVSource creates a CSV source for the given file path.
+// NewCSVSource 为给定文件路径创建 CSV 数据源。
+func NewCSVSource(path string, opts ...CSVOption) *CSVSource {
+	s := &CSVSource{
+		path:          path,
+		batchSize:     DefaultBatchSize,
+		errorStrategy: ErrorStop,
+		comma:         ',',
+		inferTypes:    true,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.batchSize <= 0 {
+		s.batchSize = DefaultBatchSize
+	}
+	return s
+}
+
+// Run opens the file and streams every row through the engine.
+// Run 打开文件并将每一行输入规则引擎。
+func (s *CSVSource) Run(ctx context.Context, engine types.Engine) (Stats, error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return Stats{}, err
+	}
+	defer f.Close()
+	return s.RunReader(ctx, f, engine)
+}
+
+// RunReader streams every CSV row read from r through the engine.
+// RunReader 将从 r 读取的每一行 CSV 输入规则引擎。
+func (s *CSVSource) RunReader(ctx context.Context, r io.Reader, engine types.Engine) (Stats, error) {
+	var stats Stats
+	reader := csv.NewReader(r)
+	reader.Comma = s.comma
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return stats, nil
+		}
+		return stats, fmt.Errorf("read csv header error:%w", err)
+	}
+	for i := range header {
+		header[i] = strings.TrimSpace(header[i])
+	}
+
+	batch := make([]types.RuleMsg, 0, s.batchSize)
+	for {
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		stats.Total++
+		if err != nil {
+			stats.Failed++
+			if s.errorStrategy == ErrorStop {
+				return stats, fmt.Errorf("read csv line:%d error:%w", stats.Total+1, err)
+			}
+			continue
+		}
+		batch = append(batch, types.NewRuleMsg("", 0, s.toInput(header, record)))
+		if len(batch) >= s.batchSize {
+			if err := s.flush(ctx, engine, batch, &stats); err != nil {
+				return stats, err
+			}
+			batch = batch[:0]
+		}
+	}
+	if len(batch) > 0 {
+		if err := s.flush(ctx, engine, batch, &stats); err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
+
+// flush sends a batch of messages to the engine concurrently and waits for all of them.
+func (s *CSVSource) flush(ctx context.Context, engine types.Engine, batch []types.RuleMsg, stats *Stats) error {
+	errs := make([]error, len(batch))
+	if len(batch) == 1 {
+		errs[0] = engine.OnMsg(ctx, batch[0])
+	} else {
+		var wg sync.WaitGroup
+		for i := range batch {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				errs[i] = engine.OnMsg(ctx, batch[i])
+			}(i)
+		}
+		wg.Wait()
+	}
+
+	var firstErr error
+	for _, err := range errs {
+		if err != nil {
+			stats.Failed++
+			if firstErr == nil {
+				firstErr = err
+			}
+		} else {
+			stats.Success++
+		}
+	}
+	if firstErr != nil && s.errorStrategy == ErrorStop {
+		return firstErr
+	}
+	return nil
+}
+
+// toInput maps a record to the message input using the header as keys.
+func (s *CSVSource) toInput(header []string, record []string) map[string]any {
+	input := make(map[string]any, len(header))
+	for i, key := range header {
+		if i >= len(record) {
+			break
+		}
+		if s.inferTypes {
+			input[key] = inferType(record[i])
+		} else {
+			input[key] = record[i]
+		}
+	}
+	return input
+}
+
+// inferType converts a CSV field to int, float64 or bool when possible.
+func inferType(value string) any {
+	v := strings.TrimSpace(value)
+	if v == "" {
+		return value
+	}
+	if i, err := strconv.Atoi(v); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(v, 64); err == nil {
+		return f
+	}
+	if strings.EqualFold(v, "true") {
+		return true
+	}
+	if strings.EqualFold(v, "false") {
+		return false
+	}
+	return value
+}
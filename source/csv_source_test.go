@@ -0,0 +1,91 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package source
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bittoy/rule/types"
+	"github.com/rulego/rulego/test/assert"
+)
+
+type mockEngine struct {
+	sync.Mutex
+	inputs []map[string]any
+}
+
+func (e *mockEngine) Id() string                         { return "mock" }
+func (e *mockEngine) SetConfig(config types.Config)      {}
+func (e *mockEngine) SetAspects(aspects ...types.Aspect) {}
+func (e *mockEngine) GetAspects() types.AspectList       { return nil }
+func (e *mockEngine) ReloadSelf(def []byte) error        { return nil }
+func (e *mockEngine) DSL() []byte                        { return nil }
+func (e *mockEngine) Stop()                              {}
+func (e *mockEngine) OnMsg(ctx context.Context, msg types.RuleMsg) error {
+	e.Lock()
+	defer e.Unlock()
+	e.inputs = append(e.inputs, msg.GetInput())
+	if msg.GetInput()["name"] == "bad" {
+		return errors.New("bad row")
+	}
+	return nil
+}
+
+const csvData = `name, age, score, vip
+alice, 30, 88.5, true
+bad, 1, 2, false
+bob, 25, 70, FALSE
+`
+
+func TestCSVSource(t *testing.T) {
+	t.Run("TypeInference", func(t *testing.T) {
+		engine := &mockEngine{}
+		stats, err := NewCSVSource("", WithErrorStrategy(ErrorSkip)).RunReader(context.Background(), strings.NewReader(csvData), engine)
+		assert.Nil(t, err)
+		assert.Equal(t, Stats{Total: 3, Success: 2, Failed: 1}, stats)
+		assert.Equal(t, "alice", engine.inputs[0]["name"])
+		assert.Equal(t, 30, engine.inputs[0]["age"])
+		assert.Equal(t, 88.5, engine.inputs[0]["score"])
+		assert.Equal(t, true, engine.inputs[0]["vip"])
+		assert.Equal(t, false, engine.inputs[2]["vip"])
+	})
+
+	t.Run("Stop", func(t *testing.T) {
+		engine := &mockEngine{}
+		stats, err := NewCSVSource("").RunReader(context.Background(), strings.NewReader(csvData), engine)
+		assert.NotNil(t, err)
+		assert.Equal(t, 2, stats.Total)
+		assert.Equal(t, 2, len(engine.inputs))
+	})
+
+	t.Run("Batch", func(t *testing.T) {
+		engine := &mockEngine{}
+		stats, err := NewCSVSource("", WithBatchSize(2), WithErrorStrategy(ErrorSkip), WithTypeInference(false)).
+			RunReader(context.Background(), strings.NewReader(csvData), engine)
+		assert.Nil(t, err)
+		assert.Equal(t, 3, len(engine.inputs))
+		assert.Equal(t, Stats{Total: 3, Success: 2, Failed: 1}, stats)
+		for _, input := range engine.inputs {
+			_, ok := input["age"].(string)
+			assert.True(t, ok)
+		}
+	})
+}
@@ -0,0 +1,78 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package testutil provides helpers for testing rule chains.
+//
+// Package testutil 提供测试规则链的辅助函数。
+package testutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bittoy/rule/builtin/aspect"
+	"github.com/bittoy/rule/types"
+)
+
+// FixedTime is the time of the clock set by FixedEnv.
+// FixedTime 是 FixedEnv 设置的时钟的时间。
+var FixedTime = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// FixedEnv makes the chain runs of a test deterministic, for golden-file tests of the chain outputs. It returns
+// the engine config options under which the messages created without an id get the ids msg-1, msg-2 and so on,
+// and the messages created without a timestamp, the time-dependent components and the expr now() function see
+// FixedTime, see types.Config.IdGenerator and types.Config.Clock. Every call returns a new id sequence and clock,
+// nothing is process wide, so tests using it may run in parallel.
+//
+// FixedEnv 使测试中的规则链执行结果确定，用于规则链输出的黄金文件测试。它返回引擎配置选项，在这些选项下未指定 id 创建的消息
+// 依次获得 msg-1、msg-2 等 id，未指定时间戳创建的消息、依赖时间的组件和 expr 的 now() 函数都看到 FixedTime，
+// 参见 types.Config.IdGenerator 和 types.Config.Clock。每次调用返回新的 id 序列和时钟，没有进程级的状态，因此使用它的测试可以并行运行。
+//
+// Usage:
+// 使用方法：
+//
+//	config := engine.NewConfig(testutil.FixedEnv()...)
+//	chainEngine, err := engine.NewChainEngine(def, engine.WithConfig(config))
+func FixedEnv() []types.Option {
+	return []types.Option{
+		types.WithClock(types.NewReplayClock(FixedTime)),
+		types.WithIdGenerator(types.SequentialIds("msg-")),
+	}
+}
+
+// AssertAspects returns the chain and node aspects of an aspect.AssertAspect checking the expectations, for
+// declarative chain tests. Every unmet expectation is reported to t with Errorf when the test ends, so the
+// test needs no assertion of its own on the collected failures.
+//
+// AssertAspects 返回检查给定期望的 aspect.AssertAspect 的规则链切面和节点切面，用于声明式规则链测试。
+// 测试结束时，每个未满足的期望都通过 Errorf 报告给 t，测试无需自行断言收集的失败。
+//
+// Usage:
+// 使用方法：
+//
+//	chainEngine, err := engine.NewChainEngine(def, engine.WithAspects(testutil.AssertAspects(t,
+//		aspect.Expectation{NodeId: "s5", Key: "score", Value: 80},
+//		aspect.Expectation{Key: "action", Value: "reject"},
+//	)...))
+func AssertAspects(t testing.TB, expectations ...aspect.Expectation) []types.Aspect {
+	asserts := aspect.NewAssertAspect(expectations...)
+	t.Cleanup(func() {
+		for _, failure := range asserts.Failures() {
+			t.Errorf("%v", failure)
+		}
+	})
+	return []types.Aspect{asserts, asserts.NodeAspect()}
+}
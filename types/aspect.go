@@ -17,6 +17,8 @@
 package types
 
 import (
+	"fmt"
+	"reflect"
 	"sort"
 )
 
@@ -265,6 +267,21 @@ type ChainAfterAspect interface {
 	After(chainCtx ChainCtx, msg RuleMsg) (RuleMsg, error)
 }
 
+// CompletedAspect defines the interface for aspects executed when a chain execution completes,
+// regardless of success or failure. It receives the terminal error of the execution, or nil.
+// These aspects are called in ChainEngine.onMsg in a defer, after the ChainAfterAspect list.
+//
+// CompletedAspect 定义在规则链执行完成时执行的切面接口，无论成功或失败都会调用。
+// 它接收执行的最终错误，成功时为 nil。
+// 这些切面在 ChainEngine.onMsg 的 defer 中、ChainAfterAspect 之后调用。
+//
+// Typical uses are closing tracing spans and recording final-outcome metrics.
+// 典型用途是关闭链路追踪 span 和记录最终结果指标。
+type CompletedAspect interface {
+	ChainAspect
+	Completed(chainCtx ChainCtx, msg RuleMsg, err error)
+}
+
 type ChainAggregationAspect interface {
 	Aspect
 	PointCut(chainAggregationCtx ChainAggregationCtx, msg RuleMsg) bool
@@ -353,6 +370,125 @@ type OnChainBeforeInitAspect interface {
 
 type AspectList []Aspect
 
+// AspectInfo describes an aspect registered on an engine, see AspectList.Describe.
+// AspectInfo 描述引擎上注册的切面，见 AspectList.Describe。
+type AspectInfo struct {
+	// Type is the Go type name of the aspect, e.g. *aspect.ChainValidator  切面的 Go 类型名称
+	Type string `json:"type"`
+	// Order is the execution priority of the aspect  切面的执行优先级
+	Order int `json:"order"`
+	// Interfaces lists the aspect interfaces implemented, e.g. ChainBeforeAspect  实现的切面接口
+	Interfaces []string `json:"interfaces"`
+	// Builtin reports whether the aspect was added from the engine built-ins rather than user config
+	// Builtin 切面是否来自引擎内置切面，而不是用户配置
+	Builtin bool `json:"builtin"`
+}
+
+// Describe returns the aspects in execution order with the aspect interfaces each implements,
+// an aspect instance contained in builtins is marked Builtin. The list itself is not reordered.
+// Describe 按执行顺序返回切面及其实现的切面接口，builtins 中包含的切面实例标记为 Builtin。不会修改列表本身的顺序。
+func (list AspectList) Describe(builtins AspectList) []AspectInfo {
+	sorted := make(AspectList, len(list))
+	copy(sorted, list)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Order() < sorted[j].Order()
+	})
+	infos := make([]AspectInfo, 0, len(sorted))
+	for _, item := range sorted {
+		infos = append(infos, AspectInfo{
+			Type:       fmt.Sprintf("%T", item),
+			Order:      item.Order(),
+			Interfaces: aspectInterfaces(item),
+			Builtin:    builtins.contains(item),
+		})
+	}
+	return infos
+}
+
+// contains reports whether the list holds the aspect instance a
+func (list AspectList) contains(a Aspect) bool {
+	if !reflect.TypeOf(a).Comparable() {
+		return false
+	}
+	for _, item := range list {
+		if reflect.TypeOf(item) == reflect.TypeOf(a) && item == a {
+			return true
+		}
+	}
+	return false
+}
+
+// HasType reports whether the list holds an aspect of the same type as a, see AspectType.
+// HasType 返回列表中是否有与 a 类型相同的切面，参见 AspectType。
+func (list AspectList) HasType(a Aspect) bool {
+	aspectType := AspectType(a)
+	for _, item := range list {
+		if AspectType(item) == aspectType {
+			return true
+		}
+	}
+	return false
+}
+
+// Merge returns the list with the aspects appended, skipping an aspect whose type is already
+// in the list, so the first aspect of each type wins. The list itself is not modified.
+// Merge 返回追加了 aspects 的列表，跳过类型已在列表中的切面，即每种类型以第一个切面为准。不会修改列表本身。
+func (list AspectList) Merge(aspects ...Aspect) AspectList {
+	merged := append(AspectList(nil), list...)
+	for _, item := range aspects {
+		if !merged.HasType(item) {
+			merged = append(merged, item)
+		}
+	}
+	return merged
+}
+
+// AspectType returns the type identifier of the aspect, the result of its Type method when it
+// has one, e.g. chainValidator, its Go type name otherwise.
+// AspectType 返回切面的类型标识，切面有 Type 方法时为其返回值，例如 chainValidator，否则为其 Go 类型名称。
+func AspectType(a Aspect) string {
+	if typed, ok := a.(interface{ Type() string }); ok {
+		return typed.Type()
+	}
+	return fmt.Sprintf("%T", a)
+}
+
+// aspectInterfaces returns the names of the aspect interfaces implemented by a
+func aspectInterfaces(a Aspect) []string {
+	var names []string
+	if _, ok := a.(OnChainBeforeInitAspect); ok {
+		names = append(names, "OnChainBeforeInitAspect")
+	}
+	if _, ok := a.(OnNodeBeforeInitAspect); ok {
+		names = append(names, "OnNodeBeforeInitAspect")
+	}
+	if _, ok := a.(OnChainAggregationBeforeInitAspect); ok {
+		names = append(names, "OnChainAggregationBeforeInitAspect")
+	}
+	if _, ok := a.(ChainAggregationBeforeAspect); ok {
+		names = append(names, "ChainAggregationBeforeAspect")
+	}
+	if _, ok := a.(ChainAggregationAfterAspect); ok {
+		names = append(names, "ChainAggregationAfterAspect")
+	}
+	if _, ok := a.(ChainBeforeAspect); ok {
+		names = append(names, "ChainBeforeAspect")
+	}
+	if _, ok := a.(ChainAfterAspect); ok {
+		names = append(names, "ChainAfterAspect")
+	}
+	if _, ok := a.(CompletedAspect); ok {
+		names = append(names, "CompletedAspect")
+	}
+	if _, ok := a.(NodeBeforeAspect); ok {
+		names = append(names, "NodeBeforeAspect")
+	}
+	if _, ok := a.(NodeAfterAspect); ok {
+		names = append(names, "NodeAfterAspect")
+	}
+	return names
+}
+
 // GetChainAspects 获取规则链执行类型增强点切面列表
 func (list AspectList) GetChainAspects() ([]ChainBeforeAspect, []ChainAfterAspect) {
 	//从小到大排序
@@ -374,7 +510,23 @@ func (list AspectList) GetChainAspects() ([]ChainBeforeAspect, []ChainAfterAspec
 	return beforeAspects, afterAspects
 }
 
-// GetChainAspects 获取规则链执行类型增强点切面列表
+// GetCompletedAspects returns the CompletedAspect list sorted by Order.
+// GetCompletedAspects 返回按 Order 排序的 CompletedAspect 列表。
+func (list AspectList) GetCompletedAspects() []CompletedAspect {
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Order() < list[j].Order()
+	})
+
+	var completedAspects []CompletedAspect
+	for _, item := range list {
+		if a, ok := item.(CompletedAspect); ok {
+			completedAspects = append(completedAspects, a)
+		}
+	}
+
+	return completedAspects
+}
+
 func (list AspectList) GetChainAggregationAspects() ([]ChainAggregationBeforeAspect, []ChainAggregationAfterAspect) {
 	//从小到大排序
 	sort.Slice(list, func(i, j int) bool {
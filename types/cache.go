@@ -0,0 +1,71 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import "context"
+
+// Cache is a key-value store with optional expiration shared by the components, see Config.Cache.
+// Implementations must be safe for concurrent use.
+//
+// Cache 是组件共享的支持过期的键值存储，参见 Config.Cache。实现必须是并发安全的。
+type Cache interface {
+	// Set stores the value, ttl is a duration string such as "10m", empty or zero never expires.
+	// Set 保存值，ttl 为持续时间字符串，如 "10m"，为空或零时永不过期。
+	Set(key string, value interface{}, ttl string) error
+	// Get returns the value, nil if it does not exist or has expired.
+	// Get 返回值，不存在或已过期时返回 nil。
+	Get(key string) interface{}
+	// Has reports whether the key exists and has not expired.
+	// Has 判断键是否存在且未过期。
+	Has(key string) bool
+	// Delete removes the key.
+	// Delete 删除键。
+	Delete(key string) error
+	// DeleteByPrefix removes the keys with the prefix.
+	// DeleteByPrefix 删除具有该前缀的键。
+	DeleteByPrefix(prefix string) error
+	// GetByPrefix returns the values of the keys with the prefix.
+	// GetByPrefix 返回具有该前缀的键的值。
+	GetByPrefix(prefix string) map[string]interface{}
+}
+
+// CacheUpdater is implemented by the caches updating a value atomically, see Config.Cache. Stateful components
+// like the windowAgg node use it for their read-modify-write; a cache without it is read and written separately,
+// so concurrent updates of the same key may be lost.
+//
+// CacheUpdater 由支持原子更新值的缓存实现，参见 Config.Cache。windowAgg 等有状态组件使用它完成读-改-写；
+// 未实现它的缓存分别读取和写入，因此对同一个键的并发更新可能丢失。
+type CacheUpdater interface {
+	// Update calls update with the value of the key, nil if it does not exist or has expired, and stores the
+	// returned value with ttl when store is true. No other Update or Set of the key runs in between, so update
+	// may modify the value in place; it must be fast and must not call the cache.
+	// Update 以键的值调用 update，键不存在或已过期时为 nil，store 为 true 时以 ttl 保存返回的值。期间不会有该键的其他
+	// Update 或 Set，因此 update 可以原地修改值；update 必须快速完成，且不能调用缓存。
+	Update(key string, ttl string, update func(value any) (newValue any, store bool)) error
+}
+
+// KVStore is a store of records looked up by key, see Config.KVStore and the lookupEnrich node.
+// It can be backed by Redis or by an in-memory map, see cache.MapKVStore.
+// Implementations must be safe for concurrent use.
+//
+// KVStore 是按键查找记录的存储，参见 Config.KVStore 和 lookupEnrich 节点。可以由 Redis 或内存映射实现，
+// 参见 cache.MapKVStore。实现必须是并发安全的。
+type KVStore interface {
+	// Get returns the record of the key, false when there is none. The caller must not modify the record.
+	// Get 返回键对应的记录，不存在时返回 false。调用方不能修改返回的记录。
+	Get(ctx context.Context, key string) (map[string]any, bool, error)
+}
@@ -0,0 +1,171 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// Clock tells the time to the time-dependent components and to the expr now() function, see Config.Clock.
+// Implementations must be safe for concurrent use.
+//
+// Clock 为依赖时间的组件和 expr 的 now() 函数提供时间，参见 Config.Clock。实现必须是并发安全的。
+type Clock interface {
+	// Now returns the current time.
+	// Now 返回当前时间。
+	Now() time.Time
+	// AfterFunc calls f in its own goroutine once the clock has advanced by d, the returned timer cancels the call.
+	// AfterFunc 在时钟前进 d 后在单独的 goroutine 中调用 f，返回的定时器可以取消调用。
+	AfterFunc(d time.Duration, f func()) ClockTimer
+}
+
+// ClockTimer is a call scheduled by Clock.AfterFunc.
+// ClockTimer 是通过 Clock.AfterFunc 安排的调用。
+type ClockTimer interface {
+	// Stop cancels the call, it returns false if the call has already run or been cancelled.
+	// Stop 取消调用，调用已执行或已取消时返回 false。
+	Stop() bool
+}
+
+// RealClock is the wall clock, used when Config.Clock is not set.
+// RealClock 是系统时钟，在未设置 Config.Clock 时使用。
+var RealClock Clock = realClock{}
+
+// realClock is the wall clock
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) AfterFunc(d time.Duration, f func()) ClockTimer {
+	return time.AfterFunc(d, f)
+}
+
+// ReplayClock is a clock driven by the messages, for backtesting chains against historical data.
+// The engines advance it to the timestamp of every message they receive, so the time-dependent
+// components see the time of the replayed data instead of the wall clock. It never moves backwards,
+// a message older than the clock leaves it unchanged. The replayed messages must carry their
+// timestamp, see NewRuleMsg.
+//
+// ReplayClock 是由消息驱动的时钟，用于基于历史数据回测规则链。引擎将其推进到收到的每条消息的时间戳，
+// 使依赖时间的组件看到回放数据的时间而不是系统时间。时钟不会后退，早于时钟的消息不会改变时钟。
+// 回放的消息必须携带其时间戳，参见 NewRuleMsg。
+//
+// 定时器在时钟推进到其触发时间时执行，因此等待中的节点（如 waitUntil）要等到后续消息到达，或调用 Advance 后才会继续：
+// 回放时需要并发提交消息，或在回放结束时调用 Advance 推进到结束时间。
+// The timers run when the clock reaches their time, so a node waiting on one, like the waitUntil node,
+// resumes when a later message arrives or Advance is called: a replay must submit the messages
+// concurrently, or call Advance to the end time once the messages are submitted.
+//
+// Usage:
+// 使用方法：
+//
+//	clock := types.NewReplayClock(time.Time{})
+//	config := engine.NewConfig(types.WithClock(clock))
+type ReplayClock struct {
+	// mu guards now and timers
+	mu sync.Mutex
+	// now is the current time
+	now time.Time
+	// timers are the pending calls
+	timers []*replayTimer
+}
+
+// NewReplayClock creates a replay clock starting at start.
+// NewReplayClock 创建从 start 开始的回放时钟。
+func NewReplayClock(start time.Time) *ReplayClock {
+	return &ReplayClock{now: start}
+}
+
+// Now returns the latest time the clock was advanced to.
+// Now 返回时钟最近推进到的时间。
+func (c *ReplayClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// AfterFunc calls f once the clock has advanced by d.
+// AfterFunc 在时钟前进 d 后调用 f。
+func (c *ReplayClock) AfterFunc(d time.Duration, f func()) ClockTimer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	timer := &replayTimer{clock: c, at: c.now.Add(d), f: f}
+	if d <= 0 {
+		go f()
+		return timer
+	}
+	c.timers = append(c.timers, timer)
+	return timer
+}
+
+// Advance moves the clock forward to t and runs the timers due by then, in time order, synchronously in the
+// calling goroutine: every timer sees the clock at its own time, and the timers it schedules run too when
+// they are due by t. A time before the current time is ignored. The timer functions must not block, as
+// with the timers of the engine components.
+// Advance 将时钟推进到 t，并在调用方的协程中按时间顺序同步执行届时到期的定时器：每个定时器执行时时钟处于其触发时间，
+// 它新设置的定时器若在 t 之前到期也会执行。早于当前时间的 t 被忽略。与引擎组件的定时器一样，定时器函数不能阻塞。
+func (c *ReplayClock) Advance(t time.Time) {
+	c.mu.Lock()
+	if !t.After(c.now) {
+		c.mu.Unlock()
+		return
+	}
+	for {
+		next := -1
+		for i, timer := range c.timers {
+			if !timer.at.After(t) && (next < 0 || timer.at.Before(c.timers[next].at)) {
+				next = i
+			}
+		}
+		if next < 0 {
+			c.now = t
+			c.mu.Unlock()
+			return
+		}
+		timer := c.timers[next]
+		c.timers = slices.Delete(c.timers, next, next+1)
+		if timer.at.After(c.now) {
+			c.now = timer.at
+		}
+		c.mu.Unlock()
+		timer.f()
+		c.mu.Lock()
+	}
+}
+
+// replayTimer is a call scheduled on a ReplayClock
+type replayTimer struct {
+	clock *ReplayClock
+	// at is the time the call runs
+	at time.Time
+	f  func()
+}
+
+func (t *replayTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	i := slices.Index(t.clock.timers, t)
+	if i < 0 {
+		return false
+	}
+	t.clock.timers = slices.Delete(t.clock.timers, i, i+1)
+	return true
+}
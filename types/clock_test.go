@@ -0,0 +1,57 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rulego/rulego/test/assert"
+)
+
+// TestReplayClockAdvance checks that Advance runs the due timers synchronously, in time order and at their own time.
+func TestReplayClockAdvance(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewReplayClock(start)
+	var fired []string
+	var seen []time.Time
+	record := func(name string) func() {
+		return func() {
+			fired = append(fired, name)
+			seen = append(seen, clock.Now())
+		}
+	}
+	clock.AfterFunc(3*time.Minute, record("c"))
+	clock.AfterFunc(time.Minute, func() {
+		record("a")()
+		// A timer scheduled by a timer runs in the same Advance when it is due
+		clock.AfterFunc(30*time.Second, record("a2"))
+	})
+	clock.AfterFunc(2*time.Minute, record("b"))
+	stopped := clock.AfterFunc(2*time.Minute, record("stopped"))
+	assert.True(t, stopped.Stop())
+	clock.AfterFunc(time.Hour, record("later"))
+
+	clock.Advance(start.Add(3 * time.Minute))
+	assert.Equal(t, []string{"a", "a2", "b", "c"}, fired)
+	assert.Equal(t, []time.Time{start.Add(time.Minute), start.Add(90 * time.Second), start.Add(2 * time.Minute), start.Add(3 * time.Minute)}, seen)
+	assert.Equal(t, start.Add(3*time.Minute), clock.Now())
+
+	clock.Advance(start)
+	assert.Equal(t, start.Add(3*time.Minute), clock.Now())
+	assert.Equal(t, 4, len(fired))
+}
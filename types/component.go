@@ -36,6 +36,30 @@ const (
 	ComponentKindEndpoint string = "ec"
 )
 
+// Component category constants used by CategoryGetter to organize components in visual tools.
+// 组件类别常量，供 CategoryGetter 在可视化工具中组织组件使用。
+const (
+	// CategoryFilter represents components that route a message to true or false
+	// CategoryFilter 表示将消息路由到 true 或 false 的组件
+	CategoryFilter = "filter"
+
+	// CategorySwitch represents components that route a message to one of several relations
+	// CategorySwitch 表示将消息路由到多个关系之一的组件
+	CategorySwitch = "switch"
+
+	// CategoryTransform represents components that modify the message
+	// CategoryTransform 表示修改消息的组件
+	CategoryTransform = "transform"
+
+	// CategoryFlow represents components that control the chain flow, like start and end
+	// CategoryFlow 表示控制规则链流程的组件，如开始和结束
+	CategoryFlow = "flow"
+
+	// CategoryOther is used for components that don't implement CategoryGetter
+	// CategoryOther 用于未实现 CategoryGetter 的组件
+	CategoryOther = "other"
+)
+
 // CategoryGetter is an optional interface that components can implement to provide
 // category information for organizing components in visual tools.
 //
@@ -57,6 +81,21 @@ type DescGetter interface {
 	Desc() string
 }
 
+// RelationsGetter is an optional interface that components can implement to declare the relation types
+// they can route a message to, so validation and visual tools know the connections a node takes.
+// An empty list declares a component ending the chain. A component whose relations depend on its
+// configuration, like a switch, includes DynamicRelationType. The failure relation, available to every
+// node when the chain continues on errors, is not declared.
+//
+// RelationsGetter 是组件可以实现的可选接口，用于声明组件可能将消息路由到的关系类型，使校验和可视化工具知道节点可以有哪些连接。
+// 空列表表示组件结束规则链。关系由配置决定的组件（如开关）包含 DynamicRelationType。
+// 规则链出错继续时所有节点都可以使用的 failure 关系不需要声明。
+type RelationsGetter interface {
+	// Relations returns the relation types the component can route a message to
+	// Relations 返回组件可能将消息路由到的关系类型
+	Relations() []string
+}
+
 // SafeComponentSlice provides a thread-safe slice for storing Node components.
 // It uses mutex synchronization to ensure safe concurrent access.
 //
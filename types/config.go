@@ -16,6 +16,71 @@
 
 package types
 
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// DeadLetterHandler receives a message that failed the chain after all retries, see Config.DeadLetter.
+// DeadLetterHandler 接收经过所有重试后仍执行规则链失败的消息，参见 Config.DeadLetter。
+type DeadLetterHandler func(ctx context.Context, msg RuleMsg, err error)
+
+// Event is a side-band event emitted by the emit node, see Config.Events.
+// Event 是 emit 节点发出的旁路事件，参见 Config.Events。
+type Event struct {
+	// Name is the event name configured on the node
+	// Name 是节点配置的事件名称
+	Name string
+	// MsgId is the id of the message the event was emitted for
+	// MsgId 是发出事件的消息 id
+	MsgId string
+	// Payload is the event payload computed by the node
+	// Payload 是节点计算的事件负载
+	Payload map[string]any
+}
+
+// EventHandler receives the events emitted by the emit nodes, see Config.Events.
+// EventHandler 接收 emit 节点发出的事件，参见 Config.Events。
+type EventHandler func(ctx context.Context, event Event) error
+
+// ChainResultHandler receives the result of a child chain of an aggregation as soon as the chain completes,
+// see Config.ChainResult. chainId is the id of the child chain and err its error, result is empty when the
+// chain failed without output.
+// ChainResultHandler 在聚合的子规则链完成后立即接收其结果，参见 Config.ChainResult。chainId 为子规则链 id，
+// err 为其错误，规则链失败且没有输出时 result 为空。
+type ChainResultHandler func(ctx context.Context, chainId string, result ChainResult, err error)
+
+// DefaultMaxSteps is the default maximum number of nodes visited by a single chain execution.
+// DefaultMaxSteps 是单次规则链执行默认最多访问的节点数。
+const DefaultMaxSteps = 1000
+
+// MetricsTagOther is the tag label of the message tags not listed in Config.MetricsTags.
+// MetricsTagOther 是未在 Config.MetricsTags 中列出的消息标签的 tag 标签值。
+const MetricsTagOther = "other"
+
+const (
+	// DefaultScriptGlobalKey is the default script variable name of the global properties.
+	// DefaultScriptGlobalKey 是全局属性默认的脚本变量名。
+	DefaultScriptGlobalKey = "global"
+	// DefaultScriptMetadataKey is the default script variable name of the message metadata.
+	// DefaultScriptMetadataKey 是消息元数据默认的脚本变量名。
+	DefaultScriptMetadataKey = "metadata"
+)
+
+// ExprCoercion defines how the message input is normalized before expr programs run.
+// ExprCoercion 定义 expr 程序运行前如何规范化消息输入。
+type ExprCoercion string
+
+const (
+	// ExprCoercionNone uses the input as is, "3" and 3 are different values.
+	// ExprCoercionNone 直接使用输入，"3" 和 3 是不同的值。
+	ExprCoercionNone ExprCoercion = ""
+	// ExprCoercionNumber converts top-level numeric strings to numbers.
+	// ExprCoercionNumber 将顶层数值字符串转换为数字。
+	ExprCoercionNumber ExprCoercion = "number"
+)
+
 // Config defines the configuration for the rule engine.
 // Config 定义规则引擎的配置。
 //
@@ -58,6 +123,20 @@ type Config struct {
 	// Defaults to `rulego.Registry` with all standard components. See ComponentRegistry interface for detailed functionality.
 	//
 	ComponentsRegistry ComponentRegistry
+	// RegistryProvider resolves the component registry of the engines of a tenant, see engine.WithTenant and
+	// ForTenant, so tenants can use different implementations for the same component type. It is called
+	// when a chain is initialized. Defaults to nil, only engines without a tenant can then be initialized.
+	// RegistryProvider 解析租户引擎使用的组件注册表，参见 engine.WithTenant 和 ForTenant，使不同租户可以对相同的组件类型
+	// 使用不同的实现。在规则链初始化时调用。默认为 nil，此时只有未设置租户的引擎可以初始化。
+	RegistryProvider RegistryProvider
+	// TypeAliases maps node types to the registered component types they stand for, e.g. restApiCall to
+	// httpClient, so chains authored for upstream RuleGo or using renamed components load unchanged. An alias
+	// is only used when no component is registered under the node type itself. Every alias must resolve to a
+	// registered component type, which is checked when a chain is initialized. Defaults to nil.
+	// TypeAliases 将节点类型映射为其代表的已注册组件类型，例如将 restApiCall 映射为 httpClient，使为上游 RuleGo
+	// 编写的或使用了已重命名组件的规则链无需修改即可加载。仅当节点类型本身没有注册组件时才使用别名。
+	// 每个别名都必须解析为已注册的组件类型，在规则链初始化时检查。默认为 nil。
+	TypeAliases map[NodeType]NodeType
 	// Parser is the rule chain parser interface, defaulting to `rulego.JsonParser`.
 	// Parser 是规则链解析器接口，默认为 `rulego.JsonParser`。
 	//
@@ -123,6 +202,157 @@ type Config struct {
 	//       return encryptedData
 	//   })
 	Udf map[string]interface{}
+	// MaxSteps is the maximum number of nodes a single chain execution may visit, the nodes visited by
+	// the branches of a split count together, defaulting to DefaultMaxSteps. Values <= 0 fall back to the default.
+	// MaxSteps 是单次规则链执行最多可访问的节点数，拆分的各分支访问的节点合并计数，默认为 DefaultMaxSteps。
+	// 小于等于 0 时使用默认值。
+	//
+	// This is a safety net against mis-wired chains that would otherwise loop forever,
+	// independent of the static cycle check performed at initialization.
+	// 这是防止错误连线导致无限循环的安全网，独立于初始化时的静态环检测。
+	MaxSteps int
+	// ExprCoercion controls how the input is normalized before expr programs run,
+	// defaulting to ExprCoercionNone.
+	// ExprCoercion 控制 expr 程序运行前如何规范化输入，默认为 ExprCoercionNone。
+	//
+	// A value read from JSON or CSV may be the string "3" in one message and the number 3
+	// in another, so student == "3" silently fails for the latter. Either enable
+	// ExprCoercionNumber and compare against numbers (student == 3), or keep the default
+	// and use the asString/asNumber helpers available in every expr script:
+	// 同一个值在不同消息中可能是字符串 "3" 或数字 3，导致 student == "3" 对后者静默失败。
+	// 可以启用 ExprCoercionNumber 并与数字比较（student == 3），或保持默认并使用所有 expr 脚本
+	// 中可用的 asString/asNumber 辅助函数：
+	//
+	//	asString(student) == "3"
+	//	asNumber(score) > 60
+	ExprCoercion ExprCoercion
+	// DisableAspectMetrics disables the per-aspect duration histogram recorded around every
+	// aspect invocation, removing its overhead. Defaults to false (enabled).
+	// DisableAspectMetrics 禁用在每次切面调用时记录的切面耗时直方图，以去除其开销。默认为 false（启用）。
+	DisableAspectMetrics bool
+	// ScriptGlobalKey is the variable name under which JavaScript and expr scripts see Properties,
+	// e.g. global.env. Defaults to DefaultScriptGlobalKey.
+	// ScriptGlobalKey 是 JavaScript 和 expr 脚本中访问 Properties 的变量名，例如 global.env。默认为 DefaultScriptGlobalKey。
+	ScriptGlobalKey string
+	// ScriptMetadataKey is the variable name under which JavaScript and expr scripts see the message
+	// metadata (the input under MetadataKey). Defaults to DefaultScriptMetadataKey.
+	// ScriptMetadataKey 是 JavaScript 和 expr 脚本中访问消息元数据（输入中 MetadataKey 的值）的变量名。
+	// 默认为 DefaultScriptMetadataKey。
+	ScriptMetadataKey string
+	// DryRun runs every message in dry-run mode: the evaluation and routing run fully, but side-effecting
+	// components log the intended action instead of performing it, see IsDryRun.
+	// A single message can be dry-run with ContextWithDryRun. Defaults to false.
+	// DryRun 以试运行模式执行所有消息：求值和路由完整执行，但有副作用的组件只记录预期动作而不执行，参见 IsDryRun。
+	// 也可以通过 ContextWithDryRun 试运行单条消息。默认为 false。
+	DryRun bool
+	// Trace collects a NodeTrace with the input and output snapshots of every executed node on the
+	// message, see RuleMsg.Traces. Snapshots copy the input for every node, so only enable it for debugging.
+	// A single message can be traced with ContextWithTrace. Defaults to false.
+	// Trace 在消息上收集每个已执行节点的 NodeTrace，包含输入和输出快照，参见 RuleMsg.Traces。
+	// 每个节点都会复制输入生成快照，因此只应在调试时启用。也可以通过 ContextWithTrace 跟踪单条消息。默认为 false。
+	Trace bool
+	// MetricsTags enables the per-tag request counter rule_engine_tagged_requests_total and lists the
+	// message tags used as its tag label, any other tag is counted as MetricsTagOther. Defaults to empty (disabled).
+	// Every label value creates a time series per engine and status, so keep the list short and
+	// never put unbounded values such as user ids in tags counted here.
+	// MetricsTags 启用按标签统计的请求计数器 rule_engine_tagged_requests_total，并列出作为其 tag 标签的消息标签，
+	// 其他标签计为 MetricsTagOther。默认为空（禁用）。
+	// 每个标签值都会为每个引擎和状态创建一个时间序列，因此请保持列表简短，不要在此统计用户 id 等无界的值。
+	MetricsTags []string
+	// MaxRetries is the number of times the engine runs a message again after it failed the chain,
+	// from the state the message had when it was submitted, see RuleMsg.Checkpoint. Messages are not
+	// retried once their context is done. Defaults to 0 (no retry).
+	// Every attempt is counted in the engine metrics, so only retry chains whose failures are transient.
+	// MaxRetries 是消息执行规则链失败后引擎重新执行的次数，从消息提交时的状态开始执行，参见 RuleMsg.Checkpoint。
+	// 消息的上下文结束后不再重试。默认为 0（不重试）。每次尝试都计入引擎指标，因此只应对失败是暂时性的规则链重试。
+	MaxRetries int
+	// RetryInterval is the time waited before every retry, see MaxRetries. Defaults to 0 (retry immediately).
+	// RetryInterval 是每次重试前等待的时间，参见 MaxRetries。默认为 0（立即重试）。
+	RetryInterval time.Duration
+	// DeadLetter is called with the message and the error when a message failed the chain after all
+	// retries, so the message can be persisted for later reprocessing. It is called synchronously before
+	// OnMsg returns. Defaults to nil.
+	// DeadLetter 在消息经过所有重试后仍执行规则链失败时，以消息和错误调用，以便持久化消息供之后重新处理。
+	// 在 OnMsg 返回前同步调用。默认为 nil。
+	DeadLetter DeadLetterHandler
+	// Events is the sink of the events emitted by the emit nodes, e.g. to fire business events or to
+	// increment custom counters. It is called synchronously, its errors are logged and do not fail the
+	// chain. Defaults to nil, the events are dropped.
+	// Events 是 emit 节点发出的事件的接收方，例如触发业务事件或增加自定义计数器。同步调用，
+	// 其错误会被记录但不会使规则链失败。默认为 nil，事件被丢弃。
+	Events EventHandler
+	// ChainResult is called with the result of every child chain of an aggregation as soon as the chain
+	// completes, including the chains that failed, e.g. to show progressive scoring while a heavy policy
+	// group evaluates. It is called synchronously, in chain order, before the next chain runs. Chains skipped
+	// after a terminating chain are not reported. Defaults to nil.
+	// ChainResult 在聚合的每个子规则链完成后立即以其结果调用，包括失败的规则链，例如在大型策略组求值时展示逐步累积的评分。
+	// 同步调用，按规则链顺序，在下一个规则链运行之前调用。终止规则链之后被跳过的规则链不会被报告。默认为 nil。
+	ChainResult ChainResultHandler
+	// JsVMPool is the JavaScript VM pool shared by the JavaScript nodes of the engines using this config,
+	// so nodes with identical scripts reuse warm VMs across instances and reloads.
+	// engine.NewConfig creates a bounded pool, see js.NewVMPool.
+	// JsVMPool 是使用此配置的引擎中 JavaScript 节点共享的 VM 池，相同脚本的节点可以跨实例和重新加载复用已预热的 VM。
+	// engine.NewConfig 会创建一个有界的池，参见 js.NewVMPool。
+	JsVMPool JsVMPool
+	// Cache is the cache shared by the stateful components of the engines using this config,
+	// such as the windowAgg node. engine.NewConfig uses cache.DefaultCache.
+	// Cache 是使用此配置的引擎中有状态组件（如 windowAgg 节点）共享的缓存。engine.NewConfig 使用 cache.DefaultCache。
+	Cache Cache
+	// KVStore is the store the lookupEnrich node fetches its records from. Defaults to nil, the lookupEnrich
+	// node then fails to initialize.
+	// KVStore 是 lookupEnrich 节点获取记录的存储。默认为 nil，此时 lookupEnrich 节点初始化失败。
+	KVStore KVStore
+	// JSONCodec is the JSON implementation of the default JSON parser, defaulting to encoding/json.
+	// It is only used when engine.NewConfig creates the parser, a custom Parser chooses its own.
+	// JSONCodec 是默认 JSON 解析器使用的 JSON 实现，默认为 encoding/json。
+	// 仅在 engine.NewConfig 创建解析器时使用，自定义的 Parser 自行选择实现。
+	JSONCodec JSONCodec
+	// Clock tells the time to the time-dependent components, like the waitUntil and batch nodes, and to the
+	// expr now() function. Defaults to RealClock, set a ReplayClock to backtest chains against historical data.
+	// When set, it also stamps the messages created without a timestamp, see StampMsg.
+	// Clock 为依赖时间的组件（如 waitUntil 和 batch 节点）和 expr 的 now() 函数提供时间。
+	// 默认为 RealClock，设置 ReplayClock 可以基于历史数据回测规则链。设置时也为未指定时间戳创建的消息打时间戳，参见 StampMsg。
+	Clock Clock
+	// IdGenerator generates the ids of the messages created without one, see StampMsg.
+	// Defaults to nil, such messages keep the random UUID given at creation.
+	// IdGenerator 生成未指定 id 创建的消息的 id，参见 StampMsg。默认为 nil，此类消息保留创建时生成的随机 UUID。
+	IdGenerator IdGenerator
+	// RedactKeys lists the message fields masked in debug and log output, see Redact.
+	// Defaults to DefaultRedactKeys when nil, an empty list disables the redaction.
+	// RedactKeys 列出在调试和日志输出中被掩码的消息字段，参见 Redact。
+	// 为 nil 时默认为 DefaultRedactKeys，空列表表示禁用脱敏。
+	RedactKeys []string
+	// EnginePool resolves the chains run by the expr runChain(chainId, input) function, see engine.Pool.
+	// Defaults to nil, runChain is then not available.
+	// EnginePool 用于解析 expr 的 runChain(chainId, input) 函数执行的规则链，参见 engine.Pool。
+	// 默认为 nil，此时 runChain 不可用。
+	EnginePool EnginePool
+	// MaxPayloadSize is the maximum estimated serialized size of a message input in bytes, the engines reject
+	// larger messages with ErrPayloadTooLarge before they enter the chain. The size of a map input is its
+	// approximate JSON size, that of a protobuf payload its wire size. Defaults to 0, no limit.
+	// MaxPayloadSize 是消息输入估算的最大序列化大小（字节），引擎在更大的消息进入规则链前以 ErrPayloadTooLarge 拒绝。
+	// 映射输入的大小为其近似的 JSON 大小，protobuf 负载为其编码大小。默认为 0，不限制。
+	MaxPayloadSize int
+}
+
+// RegistryProvider returns the component registry of a tenant, nil when the tenant is unknown.
+// RegistryProvider 返回租户的组件注册表，租户未知时返回 nil。
+type RegistryProvider func(tenant string) ComponentRegistry
+
+// JsVMPool is a pool of JavaScript VMs keyed by script, shared across nodes.
+// JsVMPool 是按脚本区分、在节点间共享的 JavaScript VM 池。
+type JsVMPool interface {
+	// Compile compiles the script so syntax errors are reported at initialization.
+	// Compile 编译脚本，以便在初始化时报告语法错误。
+	Compile(name, source string) error
+	// Call runs the function fnName defined by the script on a pooled VM and returns the exported result.
+	// A call must not leave global state behind for the next call on the same VM, and must stop the script
+	// and return the context error when ctx is done.
+	// An argument implementing FieldReader is passed as a read-only object whose fields are read on access.
+	// Call 在池中的 VM 上执行脚本定义的 fnName 函数并返回导出的结果。调用不能为同一 VM 上的下一次调用遗留全局状态，
+	// ctx 结束时必须停止脚本并返回上下文的错误。
+	// 实现 FieldReader 的参数以只读对象传入，其字段在访问时读取。
+	Call(ctx context.Context, source, fnName string, args ...any) (any, error)
 }
 
 // RegisterUdf registers a custom function. Function names can be repeated for different script types.
@@ -205,6 +435,7 @@ func NewConfig(opts ...Option) Config {
 	c := &Config{
 		Logger:     DefaultLogger(),
 		Properties: NewProperties(),
+		MaxSteps:   DefaultMaxSteps,
 	}
 
 	for _, opt := range opts {
@@ -212,3 +443,77 @@ func NewConfig(opts ...Option) Config {
 	}
 	return *c
 }
+
+// ForTenant returns the config the chains of tenant are initialized with: its ComponentsRegistry is the
+// registry RegistryProvider resolves for tenant. The nodes of a chain, and the validation of its node types,
+// only use that registry, so a tenant cannot instantiate a component registered for another tenant only.
+// An empty tenant keeps ComponentsRegistry. It fails with ErrTenantRegistryNotFound when no registry is
+// resolved for a tenant, rather than falling back to ComponentsRegistry.
+// ForTenant 返回租户规则链初始化使用的配置：其 ComponentsRegistry 为 RegistryProvider 为该租户解析的注册表。
+// 规则链的节点及其节点类型的验证只使用该注册表，因此租户无法实例化仅为其他租户注册的组件。
+// 租户为空时保留 ComponentsRegistry。无法为租户解析注册表时返回 ErrTenantRegistryNotFound，而不是回退到 ComponentsRegistry。
+func (c Config) ForTenant(tenant string) (Config, error) {
+	if tenant == "" {
+		return c, nil
+	}
+	var registry ComponentRegistry
+	if c.RegistryProvider != nil {
+		registry = c.RegistryProvider(tenant)
+	}
+	if registry == nil {
+		return c, fmt.Errorf("%w: %s", ErrTenantRegistryNotFound, tenant)
+	}
+	c.ComponentsRegistry = registry
+	return c, nil
+}
+
+// GetScriptGlobalKey returns ScriptGlobalKey, or DefaultScriptGlobalKey if it is not set.
+// GetScriptGlobalKey 返回 ScriptGlobalKey，未设置时返回 DefaultScriptGlobalKey。
+func (c Config) GetScriptGlobalKey() string {
+	if c.ScriptGlobalKey == "" {
+		return DefaultScriptGlobalKey
+	}
+	return c.ScriptGlobalKey
+}
+
+// GetScriptMetadataKey returns ScriptMetadataKey, or DefaultScriptMetadataKey if it is not set.
+// GetScriptMetadataKey 返回 ScriptMetadataKey，未设置时返回 DefaultScriptMetadataKey。
+func (c Config) GetScriptMetadataKey() string {
+	if c.ScriptMetadataKey == "" {
+		return DefaultScriptMetadataKey
+	}
+	return c.ScriptMetadataKey
+}
+
+// GetMaxSteps returns MaxSteps, or DefaultMaxSteps if it is not set.
+// GetMaxSteps 返回 MaxSteps，未设置时返回 DefaultMaxSteps。
+func (c Config) GetMaxSteps() int {
+	if c.MaxSteps <= 0 {
+		return DefaultMaxSteps
+	}
+	return c.MaxSteps
+}
+
+// StampMsg replaces the id and the timestamp generated when msg was created without them with IdGenerator
+// and Clock, when they are set. The engines call it when they receive a message; only the first engine
+// receiving msg stamps it, so a message keeps its id and timestamp across nested chains.
+// StampMsg 在设置了 IdGenerator 和 Clock 时，用它们替换消息创建时因未指定而生成的 id 和时间戳。引擎在接收消息时调用它；
+// 只有第一个接收消息的引擎为其打戳，使消息在嵌套的规则链中保持相同的 id 和时间戳。
+func (c Config) StampMsg(msg RuleMsg) {
+	if msg.data.idGenerated && c.IdGenerator != nil {
+		msg.data.id = c.IdGenerator()
+	}
+	if msg.data.tsGenerated && c.Clock != nil {
+		msg.data.ts = c.Clock.Now().UnixMilli()
+	}
+	msg.data.idGenerated, msg.data.tsGenerated = false, false
+}
+
+// GetClock returns Clock, or RealClock if it is not set.
+// GetClock 返回 Clock，未设置时返回 RealClock。
+func (c Config) GetClock() Clock {
+	if c.Clock == nil {
+		return RealClock
+	}
+	return c.Clock
+}
@@ -49,11 +49,14 @@ const (
 	Js        = "Js"     // Represents JavaScript scripting language. 表示 JavaScript 脚本语言
 	Lua       = "Lua"    // Represents Lua scripting language. 表示 Lua 脚本语言
 	Python    = "Python" // Represents Python scripting language. 表示 Python 脚本语言
+	Go        = "Go"     // Represents native Go functions. 表示原生 Go 函数
 )
 
 const (
 	//NodeConfigurationKeyIsInitNetResource 组件配置key是否是初始化网络资源，用于节点组件初始化参数校验区分
 	NodeConfigurationKeyIsInitNetResource = "$initNetResource"
+	// NodeConfigurationKeyLint 组件配置key是否是规则链检查，设置时组件只校验配置，不加载文件或启动后台任务，参见 engine.LintChain
+	NodeConfigurationKeyLint = "$lint"
 	// NodeConfigurationKeyChainCtx 获取规则链上下文Key, value类型: ChainCtx
 	NodeConfigurationKeyChainCtx = "$chainCtx"
 	//NodeConfigurationKeySelfDefinition 获取节点定义，value类型: RuleNode
@@ -81,6 +84,58 @@ var (
 	ErrEngineDisabled = errors.New("the rule chain has been disabled")
 	// ErrEngineDslEmpty is returned when the rule chain dsl is empty.
 	ErrEngineDslEmpty = errors.New("dsl can not empty")
+	// ErrMaxChainDepthExceeded is returned when a chain execution visits more nodes than Config.MaxSteps.
+	ErrMaxChainDepthExceeded = errors.New("max chain depth exceeded")
+	// ErrMaxFanOutExceeded is returned when a node emits more messages than its fan-out limit.
+	ErrMaxFanOutExceeded = errors.New("max fan-out exceeded")
+	// ErrTemplateNotFound is returned when a node imports a template that is not defined.
+	ErrTemplateNotFound = errors.New("node template not found")
+	// ErrTemplateCycle is returned when node templates import each other in a cycle.
+	ErrTemplateCycle = errors.New("node template import cycle")
+	// ErrRootNodeNotFound is returned when the root node of a chain does not exist.
+	ErrRootNodeNotFound = errors.New("root node not found")
+	// ErrEndNodeConnection is returned when an end node has an outgoing connection, which would never be followed.
+	ErrEndNodeConnection = errors.New("end node has an outgoing connection")
+	// ErrRouteConnection is returned when a route relation of a weightedRoute node has no connection, so the messages
+	// routed to it would be dropped.
+	ErrRouteConnection = errors.New("route relation has no connection")
+	// ErrMsgCancelled is the cause of the context of a message cancelled by MsgCanceller.Cancel.
+	ErrMsgCancelled = errors.New("message cancelled")
+	// ErrPayloadTooLarge is returned when the estimated size of a message input exceeds Config.MaxPayloadSize.
+	ErrPayloadTooLarge = errors.New("payload too large")
+	// ErrChainAtCapacity is returned when a chain already processes as many messages as its concurrency limit allows
+	ErrChainAtCapacity = errors.New("chain at capacity")
+	// ErrBatchDropped is returned to the members of a batch dropped before it was emitted, see the batch node.
+	ErrBatchDropped = errors.New("batch dropped")
+	// ErrTenantRegistryNotFound is returned when no component registry is resolved for the tenant of an engine, see Config.ForTenant.
+	ErrTenantRegistryNotFound = errors.New("tenant registry not found")
+)
+
+const (
+	// TemplatesKey DSL 顶层节点模板定义的键
+	// TemplatesKey is the DSL key of the top-level node template definitions.
+	TemplatesKey = "templates"
+	// ImportKey 节点引用模板的键
+	// ImportKey is the node key referencing a template.
+	ImportKey = "$import"
+)
+
+const (
+	// SplitResultsKey 拆分后各分支的规则链输出在原消息规则链输出中的键
+	// SplitResultsKey is the key of the branch chain outputs in the chain output of the split message.
+	SplitResultsKey = "results"
+	// ErrorKey 节点沿 failure 连接继续时，错误信息在私有变量中的键
+	// ErrorKey is the private variable key of the error message when a failing node continues through its failure connection.
+	ErrorKey = "error"
+	// TagsKey 规则链输出中标签的键（不区分大小写），结束节点将其添加到消息标签
+	// TagsKey is the chain output key of the tags (case-insensitive), the end node adds them to the message tags.
+	TagsKey = "tags"
+	// ScoreKey 规则链输出中累计评分的键，参见 RuleMsg.AddScore
+	// ScoreKey is the chain output key of the accumulated score, see RuleMsg.AddScore.
+	ScoreKey = "score"
+	// ReasonsKey 规则链输出中累计原因的键，参见 RuleMsg.AddReason
+	// ReasonsKey is the chain output key of the accumulated reasons, see RuleMsg.AddReason.
+	ReasonsKey = "reasons"
 )
 
 const (
@@ -89,4 +144,41 @@ const (
 	DefaultRelationType = "default"
 	TrueRelationType    = "true"
 	FalseRelationType   = "false"
+	// FailureRelationType 节点出错时的关系名称，仅在规则链开启 continueOnErr 时使用
+	// FailureRelationType is the relation followed by a failing node when the chain enables continueOnErr.
+	FailureRelationType = "failure"
+	// ThresholdRelationType windowAgg 节点的聚合值超过阈值时的关系名称
+	// ThresholdRelationType is the relation of a windowAgg node when the aggregate exceeds its threshold.
+	ThresholdRelationType = "threshold"
+	// InvalidRelationType schema 节点的消息输入未通过校验时的关系名称
+	// InvalidRelationType is the relation of a schema node when the message input fails the validation.
+	InvalidRelationType = "invalid"
+	// MissingRelationType require 节点的消息缺少必填字段或字段类型不符时的关系名称
+	// MissingRelationType is the relation of a require node when the message misses required fields or their types do not match.
+	MissingRelationType = "missing"
+	// ErrorRelationType exprFilter 节点在 onEvalError 为 error 时，表达式求值出错的关系名称
+	// ErrorRelationType is the relation of an exprFilter node whose expression fails to evaluate, when its onEvalError is error.
+	ErrorRelationType = "error"
+	// DeadlineExceededRelationType 耗时节点无法在消息截止时间前完成处理时的关系名称，参见 RuleMsg.Deadline
+	// DeadlineExceededRelationType is the relation of a time-consuming node that cannot process the message before its deadline, see RuleMsg.Deadline.
+	DeadlineExceededRelationType = "deadlineExceeded"
+	// NotFoundRelationType lookupEnrich 节点在存储中找不到记录时的关系名称
+	// NotFoundRelationType is the relation of a lookupEnrich node when the store has no record for the key.
+	NotFoundRelationType = "notFound"
+	// SkipRelationType start 节点的前置条件不满足时的关系名称，节点没有 skip 连接时规则链直接结束
+	// SkipRelationType is the relation of a start node whose precondition is false, the chain ends when the node has no skip connection.
+	SkipRelationType = "skip"
+	// DynamicRelationType 组件通过 RelationsGetter 声明的、由节点配置决定的关系，如开关的分支关系
+	// DynamicRelationType stands for the relations declared through RelationsGetter that depend on the node configuration, like the case relations of a switch.
+	DynamicRelationType = "*"
 )
+
+// IsBuiltinRelationType reports whether the relation type has a meaning to the engine or to the built-in components.
+// IsBuiltinRelationType 返回关系类型是否对引擎或内置组件有特殊含义。
+func IsBuiltinRelationType(relationType string) bool {
+	switch relationType {
+	case DefaultRelationType, TrueRelationType, FalseRelationType, FailureRelationType, ThresholdRelationType, InvalidRelationType, MissingRelationType, ErrorRelationType, DeadlineExceededRelationType, NotFoundRelationType, SkipRelationType:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,164 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"reflect"
+	"sort"
+)
+
+// ChainDiff is the difference between two definitions of a chain, passed to Callbacks.OnUpdated after a reload
+// so subscribers can react to the changed nodes only, e.g. invalidate the caches of the modified nodes.
+// For a chain aggregation the nodes are the child chains, identified by chain id.
+// ChainDiff 是规则链两个定义之间的差异，重载后传给 Callbacks.OnUpdated，使订阅方只针对变化的节点处理，
+// 例如只使被修改节点的缓存失效。对于规则链聚合，节点为子规则链，以规则链 id 标识。
+type ChainDiff struct {
+	// ChainModified reports whether the chain attributes other than its nodes and connections changed,
+	// e.g. its configuration or Disabled
+	// ChainModified 表示节点和连接之外的规则链属性是否变化，例如配置或 Disabled
+	ChainModified bool
+	// AddedNodes are the ids of the nodes only in the new definition, sorted
+	// AddedNodes 是只存在于新定义中的节点 id，已排序
+	AddedNodes []string
+	// RemovedNodes are the ids of the nodes only in the old definition, sorted
+	// RemovedNodes 是只存在于旧定义中的节点 id，已排序
+	RemovedNodes []string
+	// ModifiedNodes are the ids of the nodes in both definitions whose definition changed, sorted
+	// ModifiedNodes 是两个定义中都存在且定义发生变化的节点 id，已排序
+	ModifiedNodes []string
+	// AddedConnections are the connections only in the new definition, a connection is identified by its
+	// source node, target node and type
+	// AddedConnections 是只存在于新定义中的连接，连接以源节点、目标节点和类型标识
+	AddedConnections []NodeConnection
+	// RemovedConnections are the connections only in the old definition
+	// RemovedConnections 是只存在于旧定义中的连接
+	RemovedConnections []NodeConnection
+	// ModifiedConnections are the new definitions of the connections in both definitions whose other
+	// attributes changed, e.g. their priority
+	// ModifiedConnections 是两个定义中都存在且其他属性（如优先级）发生变化的连接的新定义
+	ModifiedConnections []NodeConnection
+}
+
+// Empty reports whether the definitions are equal.
+// Empty 返回两个定义是否相同。
+func (d ChainDiff) Empty() bool {
+	return !d.ChainModified && len(d.AddedNodes) == 0 && len(d.RemovedNodes) == 0 && len(d.ModifiedNodes) == 0 &&
+		len(d.AddedConnections) == 0 && len(d.RemovedConnections) == 0 && len(d.ModifiedConnections) == 0
+}
+
+// DiffChains returns the difference from the old to the new definition of a chain, a nil old definition
+// has no nodes or connections.
+// DiffChains 返回规则链从旧定义到新定义的差异，旧定义为 nil 时视为没有节点和连接。
+func DiffChains(old, new *Chain) ChainDiff {
+	var diff ChainDiff
+	oldNodes := map[string]any{}
+	if old != nil {
+		diff.ChainModified = !reflect.DeepEqual(old.BaseInfo, new.BaseInfo) ||
+			!reflect.DeepEqual(chainAttributes(old.Metadata), chainAttributes(new.Metadata))
+		for _, node := range old.Metadata.Nodes {
+			oldNodes[node.Id] = node
+		}
+		diff.RemovedConnections, diff.AddedConnections, diff.ModifiedConnections = diffConnections(old.Metadata.Connections, new.Metadata.Connections)
+	} else {
+		diff.AddedConnections = new.Metadata.Connections
+	}
+	newNodes := make(map[string]any, len(new.Metadata.Nodes))
+	for _, node := range new.Metadata.Nodes {
+		newNodes[node.Id] = node
+	}
+	diff.AddedNodes, diff.RemovedNodes, diff.ModifiedNodes = diffNodes(oldNodes, newNodes)
+	return diff
+}
+
+// DiffChainAggregations returns the difference from the old to the new definition of a chain aggregation,
+// whose nodes are the child chains, a nil old definition has no chains or connections.
+// DiffChainAggregations 返回规则链聚合从旧定义到新定义的差异，其节点为子规则链，旧定义为 nil 时视为没有子规则链和连接。
+func DiffChainAggregations(old, new *ChainAggregation) ChainDiff {
+	var diff ChainDiff
+	oldChains := map[string]any{}
+	if old != nil {
+		diff.ChainModified = !reflect.DeepEqual(old.BaseInfo, new.BaseInfo)
+		for _, chain := range old.Metadata.Chains {
+			oldChains[chain.Id] = chain
+		}
+		diff.RemovedConnections, diff.AddedConnections, diff.ModifiedConnections = diffConnections(old.Metadata.Connections, new.Metadata.Connections)
+	} else {
+		diff.AddedConnections = new.Metadata.Connections
+	}
+	newChains := make(map[string]any, len(new.Metadata.Chains))
+	for _, chain := range new.Metadata.Chains {
+		newChains[chain.Id] = chain
+	}
+	diff.AddedNodes, diff.RemovedNodes, diff.ModifiedNodes = diffNodes(oldChains, newChains)
+	return diff
+}
+
+// chainAttributes returns the metadata of a chain without its nodes and connections
+func chainAttributes(metadata RuleMetadata) RuleMetadata {
+	metadata.Nodes = nil
+	metadata.Connections = nil
+	return metadata
+}
+
+// diffNodes compares the node definitions by id
+func diffNodes(old, new map[string]any) (added, removed, modified []string) {
+	for id, node := range new {
+		if oldNode, ok := old[id]; !ok {
+			added = append(added, id)
+		} else if !reflect.DeepEqual(oldNode, node) {
+			modified = append(modified, id)
+		}
+	}
+	for id := range old {
+		if _, ok := new[id]; !ok {
+			removed = append(removed, id)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(modified)
+	return added, removed, modified
+}
+
+// connectionKey identifies a connection
+type connectionKey struct {
+	fromId, toId, relation string
+}
+
+// diffConnections compares the connections by source node, target node and type, keeping the order of the definitions
+func diffConnections(old, new []NodeConnection) (removed, added, modified []NodeConnection) {
+	oldByKey := make(map[connectionKey]NodeConnection, len(old))
+	for _, conn := range old {
+		oldByKey[connectionKey{conn.FromId, conn.ToId, conn.Type}] = conn
+	}
+	newKeys := make(map[connectionKey]bool, len(new))
+	for _, conn := range new {
+		key := connectionKey{conn.FromId, conn.ToId, conn.Type}
+		newKeys[key] = true
+		if oldConn, ok := oldByKey[key]; !ok {
+			added = append(added, conn)
+		} else if !reflect.DeepEqual(oldConn, conn) {
+			modified = append(modified, conn)
+		}
+	}
+	for _, conn := range old {
+		if !newKeys[connectionKey{conn.FromId, conn.ToId, conn.Type}] {
+			removed = append(removed, conn)
+		}
+	}
+	return removed, added, modified
+}
@@ -16,6 +16,13 @@
 
 package types
 
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
 type NodeType string
 
 const (
@@ -25,13 +32,32 @@ const (
 	AggTable        NodeType = "policyTable"  // 表驱动
 
 	// rule
-	RuleSubTypeStart      NodeType = "start"
-	RuleSubTypeEnd        NodeType = "end"
-	RuleSubTypeJsSwitch   NodeType = "jsSwitch"
-	RuleSubTypeExprSwitch NodeType = "exprSwitch"
-	RuleSubTypeJsFilter   NodeType = "jsFilter"
-	RuleSubTypeExprFilter NodeType = "exprFilter"
-	RuleSubTypeExprAssign NodeType = "exprAssign"
+	RuleSubTypeStart         NodeType = "start"
+	RuleSubTypeEnd           NodeType = "end"
+	RuleSubTypeJsSwitch      NodeType = "jsSwitch"
+	RuleSubTypeExprSwitch    NodeType = "exprSwitch"
+	RuleSubTypeJsFilter      NodeType = "jsFilter"
+	RuleSubTypeExprFilter    NodeType = "exprFilter"
+	RuleSubTypeExprAssign    NodeType = "exprAssign"
+	RuleSubTypeScoreSwitch   NodeType = "scoreSwitch"
+	RuleSubTypeSplit         NodeType = "split"
+	RuleSubTypeFunc          NodeType = "func"
+	RuleSubTypeRangeSwitch   NodeType = "rangeSwitch"
+	RuleSubTypeWindowAgg     NodeType = "windowAgg"
+	RuleSubTypeLookupSwitch  NodeType = "lookupSwitch"
+	RuleSubTypeSchema        NodeType = "schema"
+	RuleSubTypeRequire       NodeType = "require"
+	RuleSubTypeWaitUntil     NodeType = "waitUntil"
+	RuleSubTypeHalt          NodeType = "halt"
+	RuleSubTypeEmit          NodeType = "emit"
+	RuleSubTypeMembership    NodeType = "membership"
+	RuleSubTypeBatch         NodeType = "batch"
+	RuleSubTypeFingerprint   NodeType = "fingerprint"
+	RuleSubTypeTimeRouter    NodeType = "timeRouter"
+	RuleSubTypeMerge         NodeType = "merge"
+	RuleSubTypeLookupEnrich  NodeType = "lookupEnrich"
+	RuleSubTypeWeightedRoute NodeType = "weightedRoute"
+	RuleSubTypeCleanse       NodeType = "cleanse"
 )
 
 type ChainAggregation struct {
@@ -91,7 +117,91 @@ type BaseInfo struct {
 	// 出错终止
 	TerminalOnErr bool `json:"terminalOnErr"`
 
+	// ContinueOnErr 规则链内节点出错时不终止执行，而是沿 failure 连接继续（仅适用于规则链）
+	// 设置了 TerminalOnErr 的节点出错时仍终止执行；没有 failure 连接时返回错误
+	// ContinueOnErr routes a failing node to its failure connection instead of aborting the chain (chains only).
+	// A node setting TerminalOnErr still aborts; without a failure connection the error is returned.
+	ContinueOnErr bool `json:"continueOnErr,omitempty"`
+
 	Configuration Configuration `json:"configuration,omitempty"`
+
+	// PrivateParameter declares the private variables of the rule chain. Every message entering the chain
+	// gets the declared variables it does not have yet in its private variables, so the scripts can read
+	// priVars.<key> without checking it exists.
+	// PrivateParameter 声明规则链的私有变量。进入规则链的每条消息会获得其尚未拥有的已声明变量，
+	// 因此脚本可以直接读取 priVars.<key> 而无需检查是否存在。
+	PrivateParameter []PrivateParameter `json:"privateParameter,omitempty"`
+}
+
+// Private parameter types of PrivateParameter.Type, they are case-insensitive
+// PrivateParameter.Type 的私有参数类型，不区分大小写
+const (
+	ParamTypeString  = "STRING"
+	ParamTypeNumber  = "NUMBER"
+	ParamTypeBoolean = "BOOLEAN"
+	ParamTypeObject  = "OBJECT"
+	ParamTypeArray   = "ARRAY"
+)
+
+// PrivateParameter is a private variable declared by a rule chain.
+// PrivateParameter 是规则链声明的私有变量。
+type PrivateParameter struct {
+	// Key is the name of the variable in priVars  变量在 priVars 中的名称
+	Key string `json:"key"`
+	// Type is the type of the variable, e.g. STRING  变量类型，例如 STRING
+	Type string `json:"type"`
+	// Name is the display name of the variable  变量的显示名称
+	Name string `json:"name"`
+	// Desc describes the variable  变量描述
+	Desc string `json:"desc"`
+	// Default is the initial value of the variable, the zero value of Type when it is not set
+	// Default 是变量的初始值，未设置时为 Type 的零值
+	Default any `json:"default,omitempty"`
+}
+
+// DefaultValue returns the initial value of the variable, a copy for an object or an array default,
+// so a message changing it in place does not affect the others.
+// DefaultValue 返回变量的初始值，对象或数组的默认值返回副本，使消息原地修改时不影响其他消息。
+func (p PrivateParameter) DefaultValue() any {
+	switch v := p.Default.(type) {
+	case nil:
+	case map[string]any:
+		return maps.Clone(v)
+	case []any:
+		return slices.Clone(v)
+	default:
+		return v
+	}
+	switch strings.ToUpper(p.Type) {
+	case ParamTypeString:
+		return ""
+	case ParamTypeNumber:
+		return float64(0)
+	case ParamTypeBoolean:
+		return false
+	case ParamTypeObject:
+		return map[string]any{}
+	case ParamTypeArray:
+		return []any{}
+	default:
+		return nil
+	}
+}
+
+// ValidatePrivateParameters checks that the private parameters have a key, which is declared only once.
+// ValidatePrivateParameters 检查私有参数都有 key，并且每个 key 只声明一次。
+func (b BaseInfo) ValidatePrivateParameters() error {
+	keys := make(map[string]struct{}, len(b.PrivateParameter))
+	for i, param := range b.PrivateParameter {
+		if strings.TrimSpace(param.Key) == "" {
+			return fmt.Errorf("private parameter %d key must not be empty", i)
+		}
+		if _, ok := keys[param.Key]; ok {
+			return fmt.Errorf("private parameter %q is declared twice", param.Key)
+		}
+		keys[param.Key] = struct{}{}
+	}
+	return nil
 }
 
 // RuleMetadata defines the metadata of a rule chain, including information about nodes and connections.
@@ -135,6 +245,23 @@ type RuleMetadata struct {
 	// move from one node to another based on processing results and relationship types.
 	// 连接通过指定消息如何基于处理结果和关系类型从一个节点移动到另一个节点来建立消息流拓扑。
 	Connections []NodeConnection `json:"connections"`
+
+	// RootNodeId 规则链的入口节点 id，为空时使用 start 类型的节点。用于从任意节点进入规则链，如部分规则链测试
+	// RootNodeId is the id of the entry node of the chain, the start node is used when empty.
+	// It allows entering the chain at an arbitrary node, e.g. for partial chain testing.
+	RootNodeId string `json:"rootNodeId,omitempty"`
+
+	// RelationAliases 关系别名到规范关系名称的映射，如 {"ok": "success"}，使输出 ok 的节点匹配 success 类型的连接。
+	// 节点输出的关系和连接的类型都会被规范化，内置关系名称不能作为别名
+	// RelationAliases maps relation aliases to their canonical names, e.g. {"ok": "success"} lets a node
+	// emitting ok match a connection typed success. Both the relations emitted by the nodes and the
+	// connection types are normalized. Built-in relation names can't be aliases, see IsBuiltinRelationType.
+	RelationAliases map[string]string `json:"relationAliases,omitempty"`
+
+	// AllowAmbiguousConnections 允许节点有多个关系类型和优先级都相同的无条件连接，此时按声明顺序沿第一个连接流转
+	// AllowAmbiguousConnections allows a node to have several unguarded connections with the same relation type
+	// and priority, the first declared one is followed. See NodeConnection.Priority.
+	AllowAmbiguousConnections bool `json:"allowAmbiguousConnections,omitempty"`
 }
 
 // NodeAdditionalInfo is used for visualization position information (reserved field).
@@ -221,6 +348,72 @@ type NodeConnection struct {
 	// 标签提供连接的人类可读描述，
 	// 对于可视化编辑器和文档目的很有用。
 	Label string `json:"label,omitempty"`
+
+	// Condition is an optional expr guard that must evaluate to true against the message input
+	// for the connection to be followed, independent of the relation type returned by the source node.
+	// Condition 是可选的 expr 守卫表达式，基于消息输入求值为 true 时才会沿该连接流转，与源节点返回的关系类型无关。
+	//
+	// When several connections match the relation type, they are tried by descending Priority, then in
+	// declaration order, and the first one whose condition is empty or true is followed.
+	// 当多个连接匹配关系类型时，按 Priority 从高到低、再按声明顺序尝试，沿第一个条件为空或为 true 的连接流转。
+	//
+	// Example: "amount > 1000"
+	// 示例："amount > 1000"
+	Condition string `json:"condition,omitempty"`
+
+	// Priority orders the connections of a node matching the same relation type, higher first.
+	// Priority 对节点中匹配相同关系类型的连接排序，值越大越优先。
+	//
+	// Unguarded connections of a node with the same relation type must have distinct priorities,
+	// unless the chain sets RuleMetadata.AllowAmbiguousConnections.
+	// 节点中关系类型相同的无条件连接必须具有不同的优先级，除非规则链设置了 RuleMetadata.AllowAmbiguousConnections。
+	Priority int `json:"priority,omitempty"`
+
+	// Disabled turns the connection off without deleting it, a disabled connection is treated as absent.
+	// Disabled 在不删除连接的情况下关闭连接，已禁用的连接视为不存在。
+	//
+	// This is useful to experiment with routing in place while debugging.
+	// 这便于在调试时原地尝试不同的路由。
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+// EnabledConnections returns the connections that are not disabled.
+// EnabledConnections 返回未禁用的连接。
+func (m RuleMetadata) EnabledConnections() []NodeConnection {
+	connections := make([]NodeConnection, 0, len(m.Connections))
+	for _, item := range m.Connections {
+		if !item.Disabled {
+			connections = append(connections, item)
+		}
+	}
+	return connections
+}
+
+// Relation returns the canonical name of the relation type according to RelationAliases.
+// Relation 根据 RelationAliases 返回关系类型的规范名称。
+func (m RuleMetadata) Relation(relationType string) string {
+	if canonical, ok := m.RelationAliases[relationType]; ok {
+		return canonical
+	}
+	return relationType
+}
+
+// ValidateRelationAliases checks that no alias is empty or a built-in relation name,
+// and that no alias points to an empty name or to another alias.
+// ValidateRelationAliases 检查别名不为空且不是内置关系名称，并且别名不指向空名称或另一个别名。
+func (m RuleMetadata) ValidateRelationAliases() error {
+	for alias, canonical := range m.RelationAliases {
+		if alias == "" || canonical == "" {
+			return fmt.Errorf("relation alias %q->%q must not be empty", alias, canonical)
+		}
+		if IsBuiltinRelationType(alias) {
+			return fmt.Errorf("relation alias %q collides with a built-in relation", alias)
+		}
+		if _, ok := m.RelationAliases[canonical]; ok && canonical != alias {
+			return fmt.Errorf("relation alias %q points to alias %q", alias, canonical)
+		}
+	}
+	return nil
 }
 
 // RuleChainConnection defines the connection between a node and a sub-rule chain.
@@ -39,6 +39,10 @@ type Engine interface {
 	// 切面提供如指标、调试和验证等横切功能。
 	SetAspects(aspects ...Aspect)
 
+	// GetAspects returns the aspects of the RuleEngine, including the built-in aspects once initialized.
+	// GetAspects 返回 RuleEngine 的切面，初始化后包括内置切面。
+	GetAspects() AspectList
+
 	// ReloadSelf reloads the RuleEngine itself with the given definition and options.
 	// This completely replaces the current rule chain with a new configuration.
 	// ReloadSelf 使用给定定义和选项重新加载 RuleEngine 本身。
@@ -67,3 +71,33 @@ type Engine interface {
 	// 这是向规则引擎输入数据的主要方法。
 	OnMsg(ctx context.Context, msg RuleMsg) error
 }
+
+// MsgCanceller is implemented by the engines able to cancel an in-flight message, e.g. a stuck request
+// killed by an operator. The built-in engines implement it.
+// MsgCanceller 由能够取消正在处理的消息的引擎实现，例如由运维人员终止卡住的请求。内置引擎都实现了该接口。
+type MsgCanceller interface {
+	// Cancel cancels the context of the in-flight messages with the id, with ErrMsgCancelled as cause,
+	// and reports whether such a message was running. The chain stops before its next node, and nodes
+	// waiting on the context return early. OnMsg then returns an error wrapping ErrMsgCancelled.
+	// Cancel 以 ErrMsgCancelled 为原因取消该 id 对应的正在处理的消息的上下文，并返回是否存在这样的消息。
+	// 规则链在下一个节点之前停止，等待上下文的节点会提前返回。之后 OnMsg 返回包装了 ErrMsgCancelled 的错误。
+	Cancel(msgId string) bool
+}
+
+// EnginePool looks up the engines by chain id, see Config.EnginePool. Implementations must be safe for concurrent use.
+// EnginePool 按规则链 id 查找引擎，参见 Config.EnginePool。实现必须是并发安全的。
+type EnginePool interface {
+	// Get returns the engine of the chain id, false when there is none.
+	// Get 返回规则链 id 对应的引擎，不存在时返回 false。
+	Get(id string) (Engine, bool)
+}
+
+// AggregationEngine is an Engine running a chain aggregation, it also returns the aggregated decision.
+// AggregationEngine 是执行规则链聚合的 Engine，同时可以返回聚合决策。
+type AggregationEngine interface {
+	Engine
+
+	// OnMsgAndWait processes a message like OnMsg and returns the result aggregated from the child chains.
+	// OnMsgAndWait 与 OnMsg 一样处理消息，并返回由子规则链聚合得到的结果。
+	OnMsgAndWait(ctx context.Context, msg RuleMsg) (ChainAggregationResult, error)
+}
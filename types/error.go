@@ -11,7 +11,7 @@ type EngineError struct {
 }
 
 func (e *EngineError) Error() string {
-	return fmt.Sprintf("EngineError: %s, input:%+v, nodeDSL: %s", e.err.Error(), e.msg.GetInput(), e.nodeCtx.DSL())
+	return fmt.Sprintf("EngineError: %s, input:%+v, nodeDSL: %s", e.err.Error(), e.nodeCtx.Config().Redact(e.msg.GetInput()), e.nodeCtx.DSL())
 }
 
 func NewEngineError(nodeCtx NodeCtx, msg RuleMsg, err error) *EngineError {
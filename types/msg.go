@@ -17,10 +17,14 @@
 package types
 
 import (
+	"maps"
+	"slices"
 	"time"
 
-	"github.com/bittoy/rule/utils/maps"
-	"github.com/gofrs/uuid/v5"
+	"github.com/bittoy/rule/utils/cast"
+	utilsmaps "github.com/bittoy/rule/utils/maps"
+	"github.com/bittoy/rule/utils/pb"
+	"google.golang.org/protobuf/proto"
 )
 
 // Constants for keys used in message handling and metadata operations.
@@ -35,8 +39,18 @@ const (
 	MetadataKey = "metadata" // Key for the message metadata  消息元数据的键
 	MsgTypeKey  = "msgType"  // Key for the message type  消息类型的键
 	DataTypeKey = "dataType" // Key for the data type of the message  消息数据类型的键
+	PriVarsKey  = "priVars"  // Key for the private variables in the message input  消息输入中私有变量的键
+	NodesKey    = "nodes"    // Key for the node outputs in the expr environment  expr 环境中节点输出的键
+	RunChainKey = "runChain" // Key for the function running a chain in the expr environment, see Config.EnginePool  expr 环境中执行规则链的函数的键，参见 Config.EnginePool
+	ResultKey   = "result"   // Key for the accumulated chain result in the expr environment, see RuleMsg.Result  expr 环境中累计的规则链结果的键，参见 RuleMsg.Result
+	HeadersKey  = "headers"  // Key for the message headers in the expr and JavaScript environments, see RuleMsg.Headers  expr 和 JavaScript 环境中消息头的键，参见 RuleMsg.Headers
 )
 
+// DeadlineHeader is the message header holding the message deadline, see RuleMsg.Deadline. It is a header rather
+// than an input field, so the business data never sets the deadline by accident.
+// DeadlineHeader 是保存消息截止时间的消息头，参见 RuleMsg.Deadline。它是消息头而不是输入字段，业务数据不会意外设置截止时间。
+const DeadlineHeader = "X-Rule-Deadline"
+
 // Properties is a simple map type for storing key-value pairs as metadata.
 // It provides basic operations for metadata management without Copy-on-Write optimization.
 // This type is suitable for scenarios where performance is not critical or when
@@ -127,18 +141,6 @@ func (md Properties) Values() map[string]any {
 }
 
 type RuleMsg struct {
-	// Ts is the message timestamp in milliseconds since Unix epoch.
-	// This field is automatically set when creating a new message if not provided.
-	// Ts 是自 Unix 纪元以来的消息时间戳（毫秒）。
-	// 如果未提供，创建新消息时会自动设置此字段。
-	ts int64
-
-	// Id is the unique identifier for the message as it flows through the rule engine.
-	// Each message gets a UUID when created, ensuring uniqueness across the system.
-	// Id 是消息在规则引擎中流转时的唯一标识符。
-	// 每个消息在创建时都会获得一个 UUID，确保在系统中的唯一性。
-	id string
-
 	// Data contains the actual message payload using Copy-on-Write optimization.
 	// The format of this data should match the DataType field.
 	// Data 包含使用写时复制优化的实际消息负载。
@@ -149,52 +151,499 @@ type RuleMsg struct {
 // SharedData represents a thread-safe copy-on-write data structure for message payload.
 // This improved version addresses potential race conditions in the original implementation.
 type RuleData struct {
-	input                  map[string]any
-	chainOutput            map[string]any
-	chainAggregationOutput map[string]map[string]any
-	aggregationOutput      map[string]any
+	// ts is the message timestamp in milliseconds since Unix epoch, see Ts
+	// ts 是自 Unix 纪元以来的消息时间戳（毫秒），参见 Ts
+	ts int64
+	// id is the unique identifier of the message, see Id
+	// id 是消息的唯一标识符，参见 Id
+	id string
+	// tsGenerated and idGenerated report whether the timestamp and the id were generated at creation, so the
+	// first engine receiving the message may replace them, see Config.StampMsg
+	// tsGenerated 和 idGenerated 表示时间戳和 id 是否在创建时生成，使第一个接收消息的引擎可以替换它们，参见 Config.StampMsg
+	tsGenerated, idGenerated bool
+	input                    map[string]any
+	chainOutput              map[string]any
+	chainAggregationOutput   map[string]map[string]any
+	aggregationOutput        map[string]any
+	tags                     []string
+	// payload is the optional protobuf payload, its fields are copied to input by GetInput
+	// payload 是可选的 protobuf 负载，其字段由 GetInput 复制到 input
+	payload proto.Message
+	// converted reports whether every payload field has been copied to input
+	// converted 表示 payload 的所有字段是否都已复制到 input
+	converted bool
+	// traces are the node traces collected in trace mode, see NodeTrace
+	// traces 是跟踪模式下收集的节点跟踪记录，参见 NodeTrace
+	traces []NodeTrace
+	// attachments are the values attached by aspects and components, see SetAttachment
+	// attachments 是切面和组件附加的值，参见 SetAttachment
+	attachments map[any]any
+	// currentNode is the id of the running node, whose output the private variable writes are recorded to
+	// currentNode 是正在运行的节点 id，私有变量的写入记录为该节点的输出
+	currentNode string
+	// nodeOutputs are the outputs of the executed nodes by node id, see GetNodeOutput
+	// nodeOutputs 是按节点 id 保存的已执行节点的输出，参见 GetNodeOutput
+	nodeOutputs map[string]map[string]any
+	// halted reports whether a halt node stopped the chain, see Halt
+	// halted 表示是否有 halt 节点终止了规则链，参见 Halt
+	halted bool
+	// deadline is the deadline set by SetDeadline, zero when none
+	// deadline 是 SetDeadline 设置的截止时间，未设置时为零值
+	deadline time.Time
+	// result is the chain result accumulated by the nodes of the running chain, see Result
+	// result 是正在运行的规则链各节点累计的规则链结果，参见 Result
+	result ChainResult
+	// headers are the transport-level attributes of the message, see Headers
+	// headers 是消息的传输层属性，参见 Headers
+	headers map[string]string
 }
 
-// NewMsgWithJsonDataFromBytes creates a new message instance with JSON data from []byte.
+// NewRuleMsg creates a new message instance. The data map is copied, so the caller's map is not modified.
+// An empty id is generated as a random UUID and a ts <= 0 is the current time. The first engine receiving
+// the message replaces them with its Config.IdGenerator and Config.Clock when they are set, see Config.StampMsg.
+// NewRuleMsg 创建新的消息实例。数据映射会被复制，因此不会修改调用方的映射。
+// id 为空时生成随机 UUID，ts <= 0 时为当前时间。第一个接收消息的引擎设置了 Config.IdGenerator 和 Config.Clock 时，
+// 会用它们替换生成的值，参见 Config.StampMsg。
 func NewRuleMsg(id string, ts int64, data map[string]any) RuleMsg {
 	return newRuleMsg(id, ts, data)
 }
 
 // newMsg is a helper function to create a new RuleMsg from []byte data.
 func newRuleMsg(id string, ts int64, input map[string]any) RuleMsg {
-	if ts <= 0 {
-		ts = time.Now().UnixMilli()
+	data := &RuleData{ts: ts, id: id, tsGenerated: ts <= 0, idGenerated: id == ""}
+	if data.tsGenerated {
+		data.ts = RealClock.Now().UnixMilli()
 	}
-	if id == "" {
-		uuId, _ := uuid.NewV4()
-		id = uuId.String()
+	if data.idGenerated {
+		data.id = uuidV4()
 	}
-	input["priVars"] = map[string]any{}
-	// Create the message
-	return RuleMsg{
-		ts:   ts,
-		id:   id,
-		data: &RuleData{input: input},
+	// Copy the input so the caller's map is not modified
+	// 复制输入，避免修改调用方的映射
+	data.input = copyInput(input)
+	data.input[PriVarsKey] = map[string]any{}
+	return RuleMsg{data: data}
+}
+
+// Derive creates a message of input derived from the message, for the components producing several messages
+// from one, like the split node. It gets a new id and keeps the timestamp, headers, deadline, tags and
+// attachments of the message; the attachments are copied, their values shared. The input map is copied,
+// as in NewRuleMsg.
+//
+// Derive 创建由该消息派生、以 input 为输入的消息，用于由一条消息生成多条消息的组件，如 split 节点。派生消息获得新的 id，
+// 并保留该消息的时间戳、消息头、截止时间、标签和附件；附件映射被复制，其值共享。与 NewRuleMsg 相同，输入映射会被复制。
+func (sd *RuleMsg) Derive(input map[string]any) RuleMsg {
+	child := newRuleMsg("", sd.data.ts, input)
+	child.data.headers = maps.Clone(sd.data.headers)
+	child.data.deadline = sd.data.deadline
+	child.data.tags = slices.Clone(sd.data.tags)
+	child.data.attachments = maps.Clone(sd.data.attachments)
+	return child
+}
+
+// FieldReader gives read access to fields that are converted on access, see RuleMsg.Field.
+// FieldReader 提供对访问时才转换的字段的读取，见 RuleMsg.Field。
+type FieldReader interface {
+	Field(name string) (any, bool)
+	FieldNames() []string
+}
+
+var _ FieldReader = (*RuleMsg)(nil)
+
+// NewProtoRuleMsg creates a message carrying a protobuf payload instead of an input map.
+// Script nodes read single fields through Field, only converting the fields they use;
+// GetInput converts the remaining fields once, so map based nodes work unchanged.
+// Fields are keyed by their proto name, see package utils/pb for the conversion rules.
+//
+// NewProtoRuleMsg 创建携带 protobuf 负载而不是输入映射的消息。
+// 脚本节点通过 Field 读取单个字段，只转换使用到的字段；GetInput 一次性转换其余字段，
+// 因此基于映射的节点无需修改。字段以 proto 名称为键，转换规则见 utils/pb 包。
+func NewProtoRuleMsg(id string, ts int64, payload proto.Message) RuleMsg {
+	msg := newRuleMsg(id, ts, nil)
+	msg.data.payload = payload
+	return msg
+}
+
+// copyInput returns a shallow copy of the input map.
+func copyInput(input map[string]any) map[string]any {
+	values := make(map[string]any, len(input)+1)
+	for k, v := range input {
+		values[k] = v
 	}
+	return values
 }
 
-// IsEmpty checks if the data is empty.
+// Id returns the unique identifier of the message.
+// Id 返回消息的唯一标识符。
+func (sd *RuleMsg) Id() string {
+	return sd.data.id
+}
+
+// Ts returns the message timestamp in milliseconds.
+// Ts 返回消息时间戳（毫秒）。
+func (sd *RuleMsg) Ts() int64 {
+	return sd.data.ts
+}
+
+// GetInput returns the message input. For a protobuf message the payload fields not set by SetField are
+// converted into the input first, once.
+// GetInput 返回消息输入。对于 protobuf 消息，会先将未被 SetField 设置的负载字段一次性转换到输入中。
 func (sd *RuleMsg) GetInput() map[string]any {
+	if sd.data.payload != nil && !sd.data.converted {
+		for _, name := range pb.FieldNames(sd.data.payload) {
+			if _, ok := sd.data.input[name]; ok {
+				continue
+			}
+			if v, ok := pb.Field(sd.data.payload, name); ok {
+				sd.data.input[name] = v
+			}
+		}
+		sd.data.converted = true
+	}
 	return sd.data.input
 }
 
-// IsEmpty checks if the data is empty.
+// Headers returns a copy of the message headers, nil when there are none. Headers are the transport-level
+// attributes of the message, like the source endpoint, the content type or the auth claims, kept apart
+// from the input the rules operate on. Scripts read them, but cannot change them, under HeadersKey.
+// The engines add the headers of the context, see ContextWithHeaders.
+//
+// Headers 返回消息头的副本，没有时返回 nil。消息头是消息的传输层属性，如来源端点、内容类型或认证声明，
+// 与规则处理的输入分开保存。脚本通过 HeadersKey 读取但不能修改。引擎会添加上下文中的消息头，参见 ContextWithHeaders。
+func (sd *RuleMsg) Headers() map[string]string {
+	if len(sd.data.headers) == 0 {
+		return nil
+	}
+	headers := make(map[string]string, len(sd.data.headers))
+	for k, v := range sd.data.headers {
+		headers[k] = v
+	}
+	return headers
+}
+
+// Header returns the value of a message header, see Headers.
+// Header 返回消息头的值，参见 Headers。
+func (sd *RuleMsg) Header(key string) (string, bool) {
+	value, ok := sd.data.headers[key]
+	return value, ok
+}
+
+// SetHeader sets a message header, see Headers.
+// SetHeader 设置消息头，参见 Headers。
+func (sd *RuleMsg) SetHeader(key, value string) {
+	if sd.data.headers == nil {
+		sd.data.headers = make(map[string]string)
+	}
+	sd.data.headers[key] = value
+}
+
+// Payload returns the protobuf payload of the message, or nil.
+// Payload 返回消息的 protobuf 负载，没有时返回 nil。
+func (sd *RuleMsg) Payload() proto.Message {
+	return sd.data.payload
+}
+
+// Field returns an input field. For a protobuf message only that payload field is converted, and the input
+// is left untouched, so reading a field never modifies the message. The scripts reading the same
+// field several times should read it once, or call GetInput to convert every field once.
+//
+// Field 返回输入字段。对于 protobuf 消息只转换该负载字段且不修改输入，因此读取字段不会修改消息。
+// 多次读取同一字段的脚本应只读取一次，或调用 GetInput 一次性转换所有字段。
+func (sd *RuleMsg) Field(name string) (any, bool) {
+	if v, ok := sd.data.input[name]; ok || sd.data.payload == nil || sd.data.converted {
+		return v, ok
+	}
+	return pb.Field(sd.data.payload, name)
+}
+
+// FieldNames returns the names of the input fields, including the payload fields not converted yet.
+// FieldNames 返回输入字段的名称，包括尚未转换的负载字段。
+func (sd *RuleMsg) FieldNames() []string {
+	names := make([]string, 0, len(sd.data.input))
+	for name := range sd.data.input {
+		names = append(names, name)
+	}
+	if sd.data.payload != nil && !sd.data.converted {
+		for _, name := range pb.FieldNames(sd.data.payload) {
+			if _, ok := sd.data.input[name]; !ok {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// GetPrivateVars returns the private variables of the message.
+// If the entry is missing or has a wrong type, it is reset to an empty map.
+//
+// GetPrivateVars 返回消息的私有变量。
+// 如果条目不存在或类型错误，会被重置为空映射。
+func (sd *RuleMsg) GetPrivateVars() map[string]any {
+	if priVars, ok := sd.data.input[PriVarsKey].(map[string]any); ok && priVars != nil {
+		return priVars
+	}
+	priVars := map[string]any{}
+	sd.data.input[PriVarsKey] = priVars
+	return priVars
+}
+
+// SetPrivateVar sets a private variable of the message.
+// SetPrivateVar 设置消息的私有变量。
+func (sd *RuleMsg) SetPrivateVar(key string, value any) {
+	sd.GetPrivateVars()[key] = value
+	sd.recordOutput(key, value)
+}
+
+// SetField sets a top-level input field, visible to the scripts of the following nodes like the fields of
+// the original input. For a protobuf message it takes precedence over the payload field of the same name.
+// The field is recorded in the output of the current node like a private variable, see SetCurrentNode.
+// PriVarsKey is reserved for the private variables and must not be set.
+//
+// SetField 设置顶层输入字段，后续节点的脚本与原始输入的字段一样可见。对于 protobuf 消息，该字段优先于同名的负载字段。
+// 与私有变量一样，该字段被记录为当前节点的输出，参见 SetCurrentNode。PriVarsKey 保留给私有变量，不能设置。
+func (sd *RuleMsg) SetField(key string, value any) {
+	sd.data.input[key] = value
+	sd.recordOutput(key, value)
+}
+
+// CopyInnerData merges the given variables into the private variables of the message.
+// CopyInnerData 将给定变量合并到消息的私有变量中。
 func (sd *RuleMsg) CopyInnerData(priVars map[string]any) {
-	maps.Copy(sd.data.input["priVars"].(map[string]any), priVars)
+	maps.Copy(sd.GetPrivateVars(), priVars)
+	for key, value := range priVars {
+		sd.recordOutput(key, value)
+	}
 }
 
+// ClearInnerData resets the private variables of the message.
+// ClearInnerData 重置消息的私有变量。
 func (sd *RuleMsg) ClearInnerData() {
-	sd.data.input["priVars"] = map[string]any{}
+	sd.data.input[PriVarsKey] = map[string]any{}
 }
 
-// IsEmpty checks if the data is empty.
+// SetAttachment attaches a value to the message under key, a nil value removes it. Attachments carry the state
+// of aspects and components across the nodes of one message and are released with it. Unlike private
+// variables they are not part of the input and not visible to scripts. Use an unexported key type to
+// avoid collisions, as with context.WithValue.
+//
+// SetAttachment 以 key 为键向消息附加值，值为 nil 时删除。附件在一条消息的各节点之间携带切面和组件的状态，
+// 并随消息一起释放。与私有变量不同，附件不属于输入，脚本不可见。与 context.WithValue 相同，应使用未导出的键类型避免冲突。
+func (sd *RuleMsg) SetAttachment(key, value any) {
+	if value == nil {
+		delete(sd.data.attachments, key)
+		return
+	}
+	if sd.data.attachments == nil {
+		sd.data.attachments = map[any]any{}
+	}
+	sd.data.attachments[key] = value
+}
+
+// Attachment returns the value attached to the message under key, or nil.
+// Attachment 返回以 key 为键附加到消息的值，没有时返回 nil。
+func (sd *RuleMsg) Attachment(key any) any {
+	return sd.data.attachments[key]
+}
+
+// Checkpoint saves the state of the message and returns a function restoring it, so a failed execution
+// can be retried from the same state, see Config.MaxRetries. The input, private variables, tags, outputs,
+// traces and attachments are restored; nested values are shared, so changes made inside them are not undone.
+//
+// Checkpoint 保存消息的状态并返回恢复该状态的函数，使失败的执行可以从相同的状态重试，参见 Config.MaxRetries。
+// 输入、私有变量、标签、输出、跟踪记录和附件都会被恢复；嵌套的值是共享的，因此在其内部所做的修改不会被撤销。
+func (sd *RuleMsg) Checkpoint() (restore func()) {
+	saved := *sd.data
+	saved.input = sd.Snapshot()
+	saved.tags = append([]string(nil), sd.data.tags...)
+	saved.result = sd.Result()
+	saved.attachments = make(map[any]any, len(sd.data.attachments))
+	for k, v := range sd.data.attachments {
+		saved.attachments[k] = v
+	}
+	saved.nodeOutputs = make(map[string]map[string]any, len(sd.data.nodeOutputs))
+	for k, v := range sd.data.nodeOutputs {
+		saved.nodeOutputs[k] = v
+	}
+	return func() {
+		*sd.data = saved
+		sd.data.input = copyInput(saved.input)
+		sd.data.input[PriVarsKey] = copyInput(saved.input[PriVarsKey].(map[string]any))
+		sd.data.tags = append([]string(nil), saved.tags...)
+		sd.data.result.Reasons = append([]string(nil), saved.result.Reasons...)
+		sd.data.result.Tags = append([]string(nil), saved.result.Tags...)
+		sd.data.traces = saved.traces[:len(saved.traces):len(saved.traces)]
+		sd.data.attachments = make(map[any]any, len(saved.attachments))
+		for k, v := range saved.attachments {
+			sd.data.attachments[k] = v
+		}
+		sd.data.nodeOutputs = make(map[string]map[string]any, len(saved.nodeOutputs))
+		for k, v := range saved.nodeOutputs {
+			sd.data.nodeOutputs[k] = v
+		}
+	}
+}
+
+// SetCurrentNode marks the node about to run, the engine calls it around every node execution.
+// The private variables set while the node runs are recorded as its output, replacing its output
+// of a previous run; an empty id stops the recording.
+//
+// SetCurrentNode 标记即将运行的节点，引擎在每个节点执行前后调用。节点运行期间设置的私有变量被记录为其输出，
+// 并替换其上一次运行的输出；id 为空时停止记录。
+func (sd *RuleMsg) SetCurrentNode(nodeId string) {
+	sd.data.currentNode = nodeId
+	if nodeId != "" {
+		delete(sd.data.nodeOutputs, nodeId)
+	}
+}
+
+// SetNodeOutput sets the output of the node, replacing the recorded one.
+// SetNodeOutput 设置节点的输出，替换已记录的输出。
+func (sd *RuleMsg) SetNodeOutput(nodeId string, output map[string]any) {
+	if sd.data.nodeOutputs == nil {
+		sd.data.nodeOutputs = map[string]map[string]any{}
+	}
+	sd.data.nodeOutputs[nodeId] = output
+}
+
+// GetNodeOutput returns the output of an executed node: the private variables it set, or the chain
+// output for an end node. Expr scripts read it as nodes["nodeId"].key, see NodesKey.
+//
+// GetNodeOutput 返回已执行节点的输出：节点设置的私有变量，结束节点则为规则链输出。
+// expr 脚本通过 nodes["nodeId"].key 读取，参见 NodesKey。
+func (sd *RuleMsg) GetNodeOutput(nodeId string) map[string]any {
+	return sd.data.nodeOutputs[nodeId]
+}
+
+// NodeOutputs returns the outputs of the executed nodes by node id.
+// NodeOutputs 返回按节点 id 索引的已执行节点的输出。
+func (sd *RuleMsg) NodeOutputs() map[string]map[string]any {
+	return sd.data.nodeOutputs
+}
+
+// recordOutput records a private variable write as output of the running node
+func (sd *RuleMsg) recordOutput(key string, value any) {
+	if sd.data.currentNode == "" {
+		return
+	}
+	output := sd.data.nodeOutputs[sd.data.currentNode]
+	if output == nil {
+		output = map[string]any{}
+		sd.SetNodeOutput(sd.data.currentNode, output)
+	}
+	output[key] = value
+}
+
+// Tags returns the tags of the message, in the order they were added.
+// Tags 返回消息的标签，按添加顺序排列。
+func (sd *RuleMsg) Tags() []string {
+	return append([]string(nil), sd.data.tags...)
+}
+
+// AddTag adds tags to the message, empty and duplicate tags are ignored.
+// AddTag 为消息添加标签，忽略空标签和重复标签。
+func (sd *RuleMsg) AddTag(tags ...string) {
+	for _, tag := range tags {
+		if tag != "" && !slices.Contains(sd.data.tags, tag) {
+			sd.data.tags = append(sd.data.tags, tag)
+		}
+	}
+}
+
+// AddScore adds n to the score accumulated by the nodes of the chain and returns the new score, so several
+// checks of one chain can add up their points, see Result.
+// AddScore 将 n 累加到规则链各节点累计的评分并返回新的评分，使同一规则链的多个检查可以累加分数，参见 Result。
+func (sd *RuleMsg) AddScore(n int) int {
+	sd.data.result.Score += n
+	return sd.data.result.Score
+}
+
+// AddReason adds reasons to the reasons accumulated by the nodes of the chain, empty reasons are ignored, see Result.
+// AddReason 将原因添加到规则链各节点累计的原因中，忽略空原因，参见 Result。
+func (sd *RuleMsg) AddReason(reasons ...string) {
+	for _, reason := range reasons {
+		if reason != "" {
+			sd.data.result.Reasons = append(sd.data.result.Reasons, reason)
+		}
+	}
+}
+
+// AddResultTag adds tags to the tags accumulated by the nodes of the chain and to the message tags,
+// empty and duplicate tags are ignored, see Result.
+// AddResultTag 将标签添加到规则链各节点累计的标签和消息标签中，忽略空标签和重复标签，参见 Result。
+func (sd *RuleMsg) AddResultTag(tags ...string) {
+	for _, tag := range tags {
+		if tag != "" && !slices.Contains(sd.data.result.Tags, tag) {
+			sd.data.result.Tags = append(sd.data.result.Tags, tag)
+		}
+	}
+	sd.AddTag(tags...)
+}
+
+// Result returns the chain result accumulated by the nodes of the running chain with AddScore, AddReason
+// and AddResultTag. It is reset when a chain starts, so the chains of an aggregation accumulate their own
+// results. The end node adds it to the chain output, see ScoreKey.
+// Result 返回正在运行的规则链各节点通过 AddScore、AddReason 和 AddResultTag 累计的规则链结果。
+// 规则链开始时会重置，因此聚合中的各规则链分别累计自己的结果。结束节点将其添加到规则链输出，参见 ScoreKey。
+func (sd *RuleMsg) Result() ChainResult {
+	result := sd.data.result
+	result.Reasons = append([]string(nil), result.Reasons...)
+	result.Tags = append([]string(nil), result.Tags...)
+	return result
+}
+
+// ResetResult clears the accumulated chain result, see Result.
+// ResetResult 清除累计的规则链结果，参见 Result。
+func (sd *RuleMsg) ResetResult() {
+	sd.data.result = ChainResult{}
+}
+
+// SetChainOutput sets the chain output, which is also recorded as the output of the running node.
+// SetChainOutput 设置规则链输出，同时记录为正在运行的节点的输出。
 func (sd *RuleMsg) SetChainOutput(output map[string]any) {
 	sd.data.chainOutput = output
+	if sd.data.currentNode != "" && output != nil {
+		sd.SetNodeOutput(sd.data.currentNode, copyInput(output))
+	}
+}
+
+// SetDeadline sets the time by which the message should be processed, see Deadline. A later deadline than
+// the current one is ignored, so the deadline can only be tightened. The engines set it from the context
+// deadline in OnMsg.
+// SetDeadline 设置消息应完成处理的时间，参见 Deadline。晚于当前截止时间的设置会被忽略，截止时间只能收紧。
+// 引擎在 OnMsg 中根据上下文的截止时间设置它。
+func (sd *RuleMsg) SetDeadline(deadline time.Time) {
+	if sd.data.deadline.IsZero() || deadline.Before(sd.data.deadline) {
+		sd.data.deadline = deadline
+	}
+}
+
+// Deadline returns the time by which the message should be processed, the earlier of the deadline set by
+// SetDeadline and the header DeadlineHeader, a Unix timestamp in milliseconds or an RFC 3339 time string.
+// ok is false when the message has no deadline. Time-consuming nodes, such as the waitUntil node, route a
+// message they could not process in time to the "deadlineExceeded" relation.
+// Deadline 返回消息应完成处理的时间，即 SetDeadline 设置的截止时间与消息头 DeadlineHeader（毫秒时间戳或 RFC 3339
+// 时间字符串）中较早的一个。消息没有截止时间时 ok 为 false。耗时的节点（如 waitUntil 节点）将无法按时处理的消息
+// 路由到 "deadlineExceeded" 关系。
+func (sd *RuleMsg) Deadline() (deadline time.Time, ok bool) {
+	deadline = sd.data.deadline
+	if value, found := sd.Header(DeadlineHeader); found && value != "" {
+		if headerDeadline, err := cast.ToTimeE(value); err == nil && (deadline.IsZero() || headerDeadline.Before(deadline)) {
+			deadline = headerDeadline
+		}
+	}
+	return deadline, !deadline.IsZero()
+}
+
+// Halt marks the message as halted: the engine stops the chain, including the remaining branches
+// of a split, and keeps the chain output of the halting branch.
+// Halt 将消息标记为已终止：引擎终止规则链，包括拆分后的其余分支，并保留终止分支的规则链输出。
+func (sd *RuleMsg) Halt() {
+	sd.data.halted = true
+}
+
+// Halted reports whether the chain was halted, see Halt.
+// Halted 返回规则链是否已被终止，参见 Halt。
+func (sd *RuleMsg) Halted() bool {
+	return sd.data.halted
 }
 
 // IsEmpty checks if the data is empty.
@@ -221,3 +670,25 @@ func (sd *RuleMsg) SetAggregationOutput(aggregationOutput map[string]any) {
 func (sd *RuleMsg) GetAggregationOutput() map[string]any {
 	return sd.data.aggregationOutput
 }
+
+// ChainResults decodes the per chain outputs of a chain aggregation, keyed like GetChainAggregationOutput.
+// ChainResults 解码规则链聚合中各规则链的输出，键与 GetChainAggregationOutput 相同。
+func (sd *RuleMsg) ChainResults() (map[string]ChainResult, error) {
+	results := make(map[string]ChainResult, len(sd.data.chainAggregationOutput))
+	for key, output := range sd.data.chainAggregationOutput {
+		var result ChainResult
+		if err := utilsmaps.Map2Struct(output, &result); err != nil {
+			return nil, err
+		}
+		results[key] = result
+	}
+	return results, nil
+}
+
+// AggregationResult decodes the final result of a chain aggregation.
+// AggregationResult 解码规则链聚合的最终结果。
+func (sd *RuleMsg) AggregationResult() (ChainAggregationResult, error) {
+	var result ChainAggregationResult
+	err := utilsmaps.Map2Struct(sd.data.aggregationOutput, &result)
+	return result, err
+}
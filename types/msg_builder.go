@@ -0,0 +1,107 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import "google.golang.org/protobuf/proto"
+
+// MsgBuilder builds a RuleMsg with a fluent API.
+// The message type and metadata are stored in the input under MsgTypeKey and MetadataKey.
+//
+// MsgBuilder 通过链式 API 构建 RuleMsg。
+// 消息类型和元数据以 MsgTypeKey 和 MetadataKey 保存在输入中。
+//
+// Usage:
+// 使用方法：
+//
+//	msg := types.NewMsgBuilder().
+//		WithType("TELEMETRY_MSG").
+//		WithData(map[string]any{"temperature": 41}).
+//		Build()
+type MsgBuilder struct {
+	id       string
+	ts       int64
+	msgType  string
+	metadata Properties
+	data     map[string]any
+	payload  proto.Message
+}
+
+// NewMsgBuilder creates a new message builder.
+// NewMsgBuilder 创建新的消息构建器。
+func NewMsgBuilder() *MsgBuilder {
+	return &MsgBuilder{}
+}
+
+// WithId sets the message id, a UUID is generated when it is empty.
+// WithId 设置消息 id，为空时生成 UUID。
+func (b *MsgBuilder) WithId(id string) *MsgBuilder {
+	b.id = id
+	return b
+}
+
+// WithTs sets the message timestamp in milliseconds, the current time is used when it is not positive.
+// WithTs 设置消息时间戳（毫秒），非正数时使用当前时间。
+func (b *MsgBuilder) WithTs(ts int64) *MsgBuilder {
+	b.ts = ts
+	return b
+}
+
+// WithType sets the message type, stored in the input under MsgTypeKey.
+// WithType 设置消息类型，以 MsgTypeKey 保存在输入中。
+func (b *MsgBuilder) WithType(msgType string) *MsgBuilder {
+	b.msgType = msgType
+	return b
+}
+
+// WithMetadata sets the message metadata, stored in the input under MetadataKey.
+// WithMetadata 设置消息元数据，以 MetadataKey 保存在输入中。
+func (b *MsgBuilder) WithMetadata(metadata Properties) *MsgBuilder {
+	b.metadata = metadata
+	return b
+}
+
+// WithData sets the message input. The map is copied when the message is built.
+// WithData 设置消息输入。构建消息时会复制该映射。
+func (b *MsgBuilder) WithData(data map[string]any) *MsgBuilder {
+	b.data = data
+	return b
+}
+
+// WithPayload sets a protobuf payload read lazily by script nodes, see NewProtoRuleMsg.
+// Fields set with WithData take precedence over payload fields of the same name.
+// WithPayload 设置由脚本节点按需读取的 protobuf 负载，见 NewProtoRuleMsg。WithData 设置的字段优先于同名的负载字段。
+func (b *MsgBuilder) WithPayload(payload proto.Message) *MsgBuilder {
+	b.payload = payload
+	return b
+}
+
+// Build creates the message. The builder can be reused, every call returns an independent message.
+// Build 创建消息。构建器可以复用，每次调用都返回独立的消息。
+func (b *MsgBuilder) Build() RuleMsg {
+	input := copyInput(b.data)
+	if b.msgType != "" {
+		input[MsgTypeKey] = b.msgType
+	}
+	if b.metadata != nil {
+		// Stored as a plain map, so scripts and encoders see an ordinary object
+		// 以普通映射保存，使脚本和编码器看到普通对象
+		input[MetadataKey] = b.metadata.Copy().Values()
+	}
+	msg := newRuleMsg(b.id, b.ts, input)
+	msg.data.payload = b.payload
+	return msg
+}
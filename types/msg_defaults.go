@@ -0,0 +1,44 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"strconv"
+	"sync/atomic"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// IdGenerator returns the id of a message created without one, see Config.IdGenerator.
+// Implementations must be safe for concurrent use.
+// IdGenerator 返回未指定 id 创建的消息的 id，参见 Config.IdGenerator。实现必须是并发安全的。
+type IdGenerator func() string
+
+// SequentialIds returns an IdGenerator of the ids prefix1, prefix2 and so on.
+// SequentialIds 返回依次生成 prefix1、prefix2 等 id 的 IdGenerator。
+func SequentialIds(prefix string) IdGenerator {
+	var n atomic.Int64
+	return func() string {
+		return prefix + strconv.FormatInt(n.Add(1), 10)
+	}
+}
+
+// uuidV4 returns a random UUID
+func uuidV4() string {
+	id, _ := uuid.NewV4()
+	return id.String()
+}
@@ -0,0 +1,76 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/rulego/rulego/test/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// TestDeadline checks that the deadline is the earlier of SetDeadline and the deadline header, and that an
+// input field never sets it.
+func TestDeadline(t *testing.T) {
+	now := time.Now().Truncate(time.Millisecond)
+	msg := NewRuleMsg("", 0, map[string]any{"deadline": now.UnixMilli()})
+	_, ok := msg.Deadline()
+	assert.False(t, ok)
+
+	msg.SetHeader(DeadlineHeader, strconv.FormatInt(now.Add(time.Minute).UnixMilli(), 10))
+	deadline, ok := msg.Deadline()
+	assert.True(t, ok)
+	assert.True(t, deadline.Equal(now.Add(time.Minute)))
+
+	msg.SetDeadline(now.Add(time.Second))
+	deadline, _ = msg.Deadline()
+	assert.True(t, deadline.Equal(now.Add(time.Second)))
+
+	msg.SetHeader(DeadlineHeader, now.Add(time.Millisecond).Format(time.RFC3339Nano))
+	deadline, _ = msg.Deadline()
+	assert.True(t, deadline.Equal(now.Add(time.Millisecond)))
+
+	msg.SetHeader(DeadlineHeader, "soon")
+	deadline, _ = msg.Deadline()
+	assert.True(t, deadline.Equal(now.Add(time.Second)))
+}
+
+// TestProtoField checks that reading a payload field leaves the input untouched, and that GetInput converts
+// the payload fields once without overriding the fields set by SetField.
+func TestProtoField(t *testing.T) {
+	msg := NewProtoRuleMsg("", 0, &descriptorpb.FieldDescriptorProto{Name: proto.String("id"), Number: proto.Int32(1)})
+	size := len(msg.data.input)
+	name, ok := msg.Field("name")
+	assert.True(t, ok)
+	assert.Equal(t, "id", name)
+	assert.Equal(t, size, len(msg.data.input))
+	_, ok = msg.Field("missing")
+	assert.False(t, ok)
+
+	msg.SetField("name", "renamed")
+	name, _ = msg.Field("name")
+	assert.Equal(t, "renamed", name)
+
+	input := msg.GetInput()
+	assert.Equal(t, "renamed", input["name"])
+	assert.Equal(t, int32(1), input["number"])
+	number, _ := msg.Field("number")
+	assert.Equal(t, int32(1), number)
+}
@@ -16,6 +16,8 @@
 
 package types
 
+import "time"
+
 // Option is a function type that modifies the Config.
 // Option 是修改 Config 的函数类型。
 //
@@ -152,6 +154,206 @@ func WithProperties(properties Properties) Option {
 	}
 }
 
+// WithMaxSteps sets the maximum number of nodes a single chain execution may visit.
+// WithMaxSteps 设置单次规则链执行最多可访问的节点数。
+func WithMaxSteps(maxSteps int) Option {
+	return func(c *Config) error {
+		c.MaxSteps = maxSteps
+		return nil
+	}
+}
+
+// WithExprCoercion sets how the input is normalized before expr programs run.
+// WithExprCoercion 设置 expr 程序运行前如何规范化输入。
+func WithExprCoercion(coercion ExprCoercion) Option {
+	return func(c *Config) error {
+		c.ExprCoercion = coercion
+		return nil
+	}
+}
+
+// WithDisableAspectMetrics disables or enables the per-aspect duration metrics.
+// WithDisableAspectMetrics 禁用或启用切面耗时指标。
+func WithDisableAspectMetrics(disable bool) Option {
+	return func(c *Config) error {
+		c.DisableAspectMetrics = disable
+		return nil
+	}
+}
+
+// WithScriptEnvKeys sets the script variable names of the global properties and the message metadata.
+// WithScriptEnvKeys 设置全局属性和消息元数据的脚本变量名。
+func WithScriptEnvKeys(globalKey, metadataKey string) Option {
+	return func(c *Config) error {
+		c.ScriptGlobalKey = globalKey
+		c.ScriptMetadataKey = metadataKey
+		return nil
+	}
+}
+
+// WithDryRun enables or disables the dry-run mode, see Config.DryRun.
+// WithDryRun 启用或禁用试运行模式，参见 Config.DryRun。
+func WithDryRun(dryRun bool) Option {
+	return func(c *Config) error {
+		c.DryRun = dryRun
+		return nil
+	}
+}
+
+// WithTrace enables or disables the collection of node traces, see Config.Trace.
+// WithTrace 启用或禁用节点跟踪记录的收集，参见 Config.Trace。
+func WithTrace(trace bool) Option {
+	return func(c *Config) error {
+		c.Trace = trace
+		return nil
+	}
+}
+
+// WithRedactKeys sets the message fields masked in debug and log output, see Config.RedactKeys.
+// WithRedactKeys 设置在调试和日志输出中被掩码的消息字段，参见 Config.RedactKeys。
+func WithRedactKeys(keys ...string) Option {
+	return func(c *Config) error {
+		c.RedactKeys = append([]string{}, keys...)
+		return nil
+	}
+}
+
+// WithMetricsTags enables the per-tag request counter for the given message tags, see Config.MetricsTags.
+// WithMetricsTags 为给定的消息标签启用按标签统计的请求计数器，参见 Config.MetricsTags。
+func WithMetricsTags(tags ...string) Option {
+	return func(c *Config) error {
+		c.MetricsTags = tags
+		return nil
+	}
+}
+
+// WithRetry sets the number of retries of a failed message and the time waited before each, see Config.MaxRetries.
+// WithRetry 设置失败消息的重试次数和每次重试前等待的时间，参见 Config.MaxRetries。
+func WithRetry(maxRetries int, interval time.Duration) Option {
+	return func(c *Config) error {
+		c.MaxRetries = maxRetries
+		c.RetryInterval = interval
+		return nil
+	}
+}
+
+// WithDeadLetter sets the handler of the messages that failed the chain after all retries, see Config.DeadLetter.
+// WithDeadLetter 设置经过所有重试后仍执行规则链失败的消息的处理函数，参见 Config.DeadLetter。
+func WithDeadLetter(handler DeadLetterHandler) Option {
+	return func(c *Config) error {
+		c.DeadLetter = handler
+		return nil
+	}
+}
+
+// WithChainResult sets the handler of the child chain results of the aggregations, see Config.ChainResult.
+// WithChainResult 设置聚合子规则链结果的处理函数，参见 Config.ChainResult。
+func WithChainResult(handler ChainResultHandler) Option {
+	return func(c *Config) error {
+		c.ChainResult = handler
+		return nil
+	}
+}
+
+// WithEvents sets the sink of the events emitted by the emit nodes, see Config.Events.
+// WithEvents 设置 emit 节点发出的事件的接收方，参见 Config.Events。
+func WithEvents(handler EventHandler) Option {
+	return func(c *Config) error {
+		c.Events = handler
+		return nil
+	}
+}
+
+// WithJsVMPool sets the JavaScript VM pool shared by the JavaScript nodes.
+// WithJsVMPool 设置 JavaScript 节点共享的 VM 池。
+func WithJsVMPool(pool JsVMPool) Option {
+	return func(c *Config) error {
+		c.JsVMPool = pool
+		return nil
+	}
+}
+
+// WithCache sets the cache shared by the stateful components.
+// WithCache 设置有状态组件共享的缓存。
+func WithCache(cache Cache) Option {
+	return func(c *Config) error {
+		c.Cache = cache
+		return nil
+	}
+}
+
+// WithKVStore sets the store the lookupEnrich node fetches its records from, see Config.KVStore.
+// WithKVStore 设置 lookupEnrich 节点获取记录的存储，参见 Config.KVStore。
+func WithKVStore(store KVStore) Option {
+	return func(c *Config) error {
+		c.KVStore = store
+		return nil
+	}
+}
+
+// WithJSONCodec sets the JSON implementation of the default JSON parser, see Config.JSONCodec.
+// WithJSONCodec 设置默认 JSON 解析器使用的 JSON 实现，参见 Config.JSONCodec。
+func WithJSONCodec(codec JSONCodec) Option {
+	return func(c *Config) error {
+		c.JSONCodec = codec
+		return nil
+	}
+}
+
+// WithClock sets the clock of the time-dependent components, see Config.Clock.
+// WithClock 设置依赖时间的组件使用的时钟，参见 Config.Clock。
+func WithClock(clock Clock) Option {
+	return func(c *Config) error {
+		c.Clock = clock
+		return nil
+	}
+}
+
+// WithIdGenerator sets the generator of the ids of the messages created without one, see Config.IdGenerator.
+// WithIdGenerator 设置未指定 id 创建的消息的 id 生成函数，参见 Config.IdGenerator。
+func WithIdGenerator(ids IdGenerator) Option {
+	return func(c *Config) error {
+		c.IdGenerator = ids
+		return nil
+	}
+}
+
+// WithRegistryProvider sets the resolver of the component registries of the tenants, see Config.RegistryProvider.
+// WithRegistryProvider 设置租户组件注册表的解析函数，参见 Config.RegistryProvider。
+func WithRegistryProvider(provider RegistryProvider) Option {
+	return func(c *Config) error {
+		c.RegistryProvider = provider
+		return nil
+	}
+}
+
+// WithTypeAliases sets the node type aliases, see Config.TypeAliases.
+// WithTypeAliases 设置节点类型别名，参见 Config.TypeAliases。
+func WithTypeAliases(aliases map[NodeType]NodeType) Option {
+	return func(c *Config) error {
+		c.TypeAliases = aliases
+		return nil
+	}
+}
+
+// WithEnginePool sets the engines the expr runChain function runs, see Config.EnginePool.
+// WithEnginePool 设置 expr 的 runChain 函数执行的引擎，参见 Config.EnginePool。
+func WithEnginePool(pool EnginePool) Option {
+	return func(c *Config) error {
+		c.EnginePool = pool
+		return nil
+	}
+}
+
+// WithMaxPayloadSize sets the maximum estimated size of a message input in bytes, see Config.MaxPayloadSize.
+// WithMaxPayloadSize 设置消息输入估算的最大大小（字节），参见 Config.MaxPayloadSize。
+func WithMaxPayloadSize(size int) Option {
+	return func(c *Config) error {
+		c.MaxPayloadSize = size
+		return nil
+	}
+}
+
 type CallbackOption func(*Callbacks) error
 
 func NewCallbacks(opts ...CallbackOption) Callbacks {
@@ -0,0 +1,99 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import "strings"
+
+// RedactedValue replaces the values of the redacted fields.
+// RedactedValue 替换被脱敏字段的值。
+const RedactedValue = "******"
+
+// DefaultRedactKeys are the fields redacted when Config.RedactKeys is nil.
+// DefaultRedactKeys 是 Config.RedactKeys 为 nil 时脱敏的字段。
+var DefaultRedactKeys = []string{"password", "token", "ssn"}
+
+// GetRedactKeys returns RedactKeys, or DefaultRedactKeys if it is nil.
+// GetRedactKeys 返回 RedactKeys，为 nil 时返回 DefaultRedactKeys。
+func (c Config) GetRedactKeys() []string {
+	if c.RedactKeys == nil {
+		return DefaultRedactKeys
+	}
+	return c.RedactKeys
+}
+
+// Redact returns a copy of data for logging with the values of the fields matched by
+// GetRedactKeys replaced by RedactedValue, data itself is not modified.
+// A key without dot matches the field at any depth, e.g. token matches user.token; a dotted key
+// matches the field path from the top, e.g. priVars.card. Keys are matched case-insensitively.
+//
+// Redact 返回用于日志输出的 data 副本，GetRedactKeys 匹配的字段值被替换为 RedactedValue，data 本身不被修改。
+// 不含点的键匹配任意层级的字段，例如 token 匹配 user.token；含点的键匹配从顶层开始的字段路径，例如 priVars.card。
+// 键匹配不区分大小写。
+func (c Config) Redact(data map[string]any) map[string]any {
+	keys := c.GetRedactKeys()
+	if len(keys) == 0 || data == nil {
+		return data
+	}
+	return redactMap(data, "", keys)
+}
+
+// redactMap copies m, masking the fields matched by keys, prefix is the path of m
+func redactMap(m map[string]any, prefix string, keys []string) map[string]any {
+	redacted := make(map[string]any, len(m))
+	for k, v := range m {
+		path := k
+		if prefix != "" {
+			path = prefix + "." + k
+		}
+		if redactMatch(k, path, keys) {
+			redacted[k] = RedactedValue
+		} else {
+			redacted[k] = redactValue(v, path, keys)
+		}
+	}
+	return redacted
+}
+
+// redactValue copies the maps and lists of v, masking the fields matched by keys
+func redactValue(v any, path string, keys []string) any {
+	switch value := v.(type) {
+	case map[string]any:
+		return redactMap(value, path, keys)
+	case []any:
+		items := make([]any, len(value))
+		for i, item := range value {
+			items[i] = redactValue(item, path, keys)
+		}
+		return items
+	default:
+		return v
+	}
+}
+
+// redactMatch reports whether the field named key at path is matched by keys
+func redactMatch(key, path string, keys []string) bool {
+	for _, k := range keys {
+		if strings.Contains(k, ".") {
+			if strings.EqualFold(k, path) {
+				return true
+			}
+		} else if strings.EqualFold(k, key) {
+			return true
+		}
+	}
+	return false
+}
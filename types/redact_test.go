@@ -0,0 +1,55 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"testing"
+
+	"github.com/rulego/rulego/test/assert"
+)
+
+// TestRedact checks the default keys at any depth, the dotted keys from the top, the case-insensitive match,
+// that the data is not modified and that an empty list disables the redaction.
+func TestRedact(t *testing.T) {
+	data := map[string]any{
+		"Password": "x",
+		"user":     map[string]any{"token": "t", "name": "n"},
+		"list":     []any{map[string]any{"ssn": 1}},
+		"priVars":  map[string]any{"card": 1, "ok": 2},
+	}
+	config := Config{}
+	assert.Equal(t, map[string]any{
+		"Password": RedactedValue,
+		"user":     map[string]any{"token": RedactedValue, "name": "n"},
+		"list":     []any{map[string]any{"ssn": RedactedValue}},
+		"priVars":  map[string]any{"card": 1, "ok": 2},
+	}, config.Redact(data))
+	assert.Equal(t, "x", data["Password"])
+	assert.Equal(t, "t", data["user"].(map[string]any)["token"])
+
+	config = NewConfig(WithRedactKeys("priVars.card"))
+	redacted := config.Redact(data)
+	assert.Equal(t, "x", redacted["Password"])
+	assert.Equal(t, map[string]any{"card": RedactedValue, "ok": 2}, redacted["priVars"])
+	// A dotted key only matches from the top
+	assert.Equal(t, map[string]any{"priVars": map[string]any{"card": 1}},
+		NewConfig(WithRedactKeys("card.priVars")).Redact(map[string]any{"priVars": map[string]any{"card": 1}}))
+
+	config = NewConfig(WithRedactKeys())
+	assert.Equal(t, "x", config.Redact(data)["Password"])
+	assert.Nil(t, config.Redact(nil))
+}
@@ -16,4 +16,136 @@ type RuleContext interface {
 	Self() NodeCtx
 	// From retrieves the node instance from which the message entered the current node.
 	From() NodeCtx
+	// IsDryRun reports whether the chain runs in dry-run mode, see Config.DryRun.
+	// IsDryRun 返回规则链是否以试运行模式执行，参见 Config.DryRun。
+	IsDryRun() bool
+}
+
+// dryRunKey is the context key marking a dry-run execution.
+type dryRunKey struct{}
+
+// ContextWithDryRun returns a context marking the execution as a dry run, so a single message can be
+// evaluated without side effects while the engine keeps running normally for other messages.
+// The engine also marks the context of every message when Config.DryRun is set.
+//
+// ContextWithDryRun 返回将执行标记为试运行的上下文，使单条消息可以在无副作用的情况下求值，
+// 而引擎对其他消息保持正常执行。设置 Config.DryRun 时，引擎也会标记每条消息的上下文。
+//
+//	err := ruleEngine.OnMsg(types.ContextWithDryRun(ctx), msg)
+func ContextWithDryRun(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dryRunKey{}, true)
+}
+
+// IsDryRun reports whether the context marks a dry-run execution. Side-effecting components
+// check it in OnMsg and log the intended action instead of performing it.
+// IsDryRun 返回上下文是否标记为试运行。有副作用的组件在 OnMsg 中检查它，记录预期动作而不是执行它。
+func IsDryRun(ctx context.Context) bool {
+	dryRun, _ := ctx.Value(dryRunKey{}).(bool)
+	return dryRun
+}
+
+// syncKey is the context key marking a synchronous execution.
+type syncKey struct{}
+
+// ContextWithSync returns a context making the engine OnMsg return only once the chain has completed, also
+// when a node hands the message off, see AsyncNode, so the caller can read the chain output of the message.
+//
+// ContextWithSync 返回使引擎 OnMsg 在规则链执行完成后才返回的上下文，节点移交消息时也是如此，参见 AsyncNode，
+// 使调用方可以读取消息的规则链输出。
+//
+//	err := ruleEngine.OnMsg(types.ContextWithSync(ctx), msg)
+func ContextWithSync(ctx context.Context) context.Context {
+	return context.WithValue(ctx, syncKey{}, true)
+}
+
+// IsSync reports whether the context marks a synchronous execution, see ContextWithSync.
+// IsSync 返回上下文是否标记为同步执行，参见 ContextWithSync。
+func IsSync(ctx context.Context) bool {
+	sync, _ := ctx.Value(syncKey{}).(bool)
+	return sync
+}
+
+// requestKey is the type of the request-scoped context keys, unexported so that only the helpers
+// below can set or read them and they cannot collide with keys of other packages.
+//
+// requestKey 是请求级上下文键的类型。未导出，只能通过下面的函数设置和读取，不会与其他包的键冲突。
+type requestKey int
+
+// Request-scoped context keys.
+// 请求级上下文键。
+const (
+	// requestIdKey holds the request id, see ContextWithRequestId.
+	requestIdKey requestKey = iota
+	// tenantIdKey holds the tenant id, see ContextWithTenantId.
+	tenantIdKey
+	// userIdKey holds the user id, see ContextWithUserId.
+	userIdKey
+)
+
+// ContextWithRequestId returns a context carrying the request id. When the context passed to
+// ChainEngine.OnMsg or ChainAggregationEngine.OnMsg has no request id, the engine sets it to the message id,
+// so components can always correlate their logs and calls with the message.
+//
+// ContextWithRequestId 返回携带请求 ID 的上下文。传给 ChainEngine.OnMsg 或 ChainAggregationEngine.OnMsg
+// 的上下文没有请求 ID 时，引擎将其设置为消息 ID，使组件总能将日志和调用与消息关联。
+//
+//	err := ruleEngine.OnMsg(types.ContextWithRequestId(ctx, r.Header.Get("X-Request-Id")), msg)
+func ContextWithRequestId(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIdKey, id)
+}
+
+// RequestIdFromContext returns the request id of the context, or "" when it has none.
+// RequestIdFromContext 返回上下文的请求 ID，没有时返回 ""。
+func RequestIdFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIdKey).(string)
+	return id
+}
+
+// ContextWithTenantId returns a context carrying the tenant id of the request.
+// ContextWithTenantId 返回携带请求租户 ID 的上下文。
+func ContextWithTenantId(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, tenantIdKey, id)
+}
+
+// TenantIdFromContext returns the tenant id of the context, or "" when it has none.
+// TenantIdFromContext 返回上下文的租户 ID，没有时返回 ""。
+func TenantIdFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(tenantIdKey).(string)
+	return id
+}
+
+// ContextWithUserId returns a context carrying the user id of the request.
+// ContextWithUserId 返回携带请求用户 ID 的上下文。
+func ContextWithUserId(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, userIdKey, id)
+}
+
+// UserIdFromContext returns the user id of the context, or "" when it has none.
+// UserIdFromContext 返回上下文的用户 ID，没有时返回 ""。
+func UserIdFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(userIdKey).(string)
+	return id
+}
+
+// headersKey is the context key of the message headers.
+type headersKey struct{}
+
+// ContextWithHeaders returns a context carrying message headers, e.g. the attributes of the transport a
+// message came from. ChainEngine.OnMsg and ChainAggregationEngine.OnMsg add them to the message headers,
+// the headers already set on the message win, see RuleMsg.Headers.
+//
+// ContextWithHeaders 返回携带消息头的上下文，例如消息来源传输的属性。ChainEngine.OnMsg 和
+// ChainAggregationEngine.OnMsg 将其添加到消息头中，消息上已设置的消息头优先，参见 RuleMsg.Headers。
+//
+//	ctx = types.ContextWithHeaders(ctx, map[string]string{"contentType": r.Header.Get("Content-Type")})
+//	err := ruleEngine.OnMsg(ctx, msg)
+func ContextWithHeaders(ctx context.Context, headers map[string]string) context.Context {
+	return context.WithValue(ctx, headersKey{}, headers)
+}
+
+// HeadersFromContext returns the message headers of the context, or nil when it has none.
+// HeadersFromContext 返回上下文的消息头，没有时返回 nil。
+func HeadersFromContext(ctx context.Context) map[string]string {
+	headers, _ := ctx.Value(headersKey{}).(map[string]string)
+	return headers
 }
@@ -0,0 +1,44 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rulego/rulego/test/assert"
+)
+
+// TestRequestContext checks that the request, tenant and user ids round-trip through the context helpers
+// without overwriting each other, and read as "" from a context without them.
+func TestRequestContext(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, "", RequestIdFromContext(ctx))
+	assert.Equal(t, "", TenantIdFromContext(ctx))
+	assert.Equal(t, "", UserIdFromContext(ctx))
+
+	ctx = ContextWithRequestId(ctx, "r1")
+	ctx = ContextWithTenantId(ctx, "t1")
+	ctx = ContextWithUserId(ctx, "u1")
+	assert.Equal(t, "r1", RequestIdFromContext(ctx))
+	assert.Equal(t, "t1", TenantIdFromContext(ctx))
+	assert.Equal(t, "u1", UserIdFromContext(ctx))
+
+	ctx = ContextWithTenantId(ctx, "t2")
+	assert.Equal(t, "t2", TenantIdFromContext(ctx))
+	assert.Equal(t, "r1", RequestIdFromContext(ctx))
+}
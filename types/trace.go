@@ -0,0 +1,110 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"context"
+	"time"
+)
+
+// NodeTrace is the record of one node execution collected in trace mode, see Config.Trace.
+// Unlike the node debug aspect, which prints the messages, the records are kept on the message
+// so a test or an admin tool can inspect why the message took a branch.
+//
+// NodeTrace 是跟踪模式下收集的单个节点执行记录，参见 Config.Trace。
+// 与打印消息的节点调试切面不同，记录保存在消息上，测试或管理工具可以据此查看消息为什么进入某个分支。
+//
+// The snapshots are redacted with Config.Redact.
+// 快照经过 Config.Redact 脱敏。
+type NodeTrace struct {
+	// ChainId is the id of the chain the node belongs to
+	// ChainId 节点所属规则链的 id
+	ChainId string `json:"chainId"`
+	// NodeId is the id of the node
+	// NodeId 节点 id
+	NodeId string `json:"nodeId"`
+	// NodeType is the type of the node
+	// NodeType 节点类型
+	NodeType NodeType `json:"nodeType"`
+	// Input is a snapshot of the message input, including the private variables, before the node ran
+	// Input 节点执行前消息输入（包括私有变量）的快照
+	Input map[string]any `json:"input"`
+	// PriVars is a snapshot of the private variables after the node ran
+	// PriVars 节点执行后私有变量的快照
+	PriVars map[string]any `json:"priVars"`
+	// ChainOutput is the chain output after the node ran, nil until an end node sets it
+	// ChainOutput 节点执行后的规则链输出，结束节点设置之前为 nil
+	ChainOutput map[string]any `json:"chainOutput,omitempty"`
+	// Relation is the relation the node routed to, empty when the node ends the chain or fails
+	// Relation 节点路由到的关系，节点结束规则链或出错时为空
+	Relation string `json:"relation,omitempty"`
+	// Err is the error message of a failing node
+	// Err 节点出错时的错误信息
+	Err string `json:"err,omitempty"`
+	// Elapsed is the execution time of the node, aspects excluded
+	// Elapsed 节点的执行耗时，不包括切面
+	Elapsed time.Duration `json:"elapsed"`
+}
+
+// traceKey is the context key marking a traced execution.
+type traceKey struct{}
+
+// ContextWithTrace returns a context marking the execution as traced, so the node traces of a single
+// message are collected while the engine runs other messages without the overhead.
+// The engine also marks the context of every message when Config.Trace is set.
+//
+// ContextWithTrace 返回将执行标记为跟踪的上下文，使单条消息收集节点跟踪记录，而引擎处理其他消息时没有额外开销。
+// 设置 Config.Trace 时，引擎也会标记每条消息的上下文。
+//
+//	err := ruleEngine.OnMsg(types.ContextWithTrace(ctx), msg)
+//	for _, trace := range msg.Traces() {
+//		fmt.Println(trace.NodeId, trace.Relation, trace.PriVars)
+//	}
+func ContextWithTrace(ctx context.Context) context.Context {
+	return context.WithValue(ctx, traceKey{}, true)
+}
+
+// IsTracing reports whether the context marks a traced execution.
+// IsTracing 返回上下文是否标记为跟踪执行。
+func IsTracing(ctx context.Context) bool {
+	tracing, _ := ctx.Value(traceKey{}).(bool)
+	return tracing
+}
+
+// Traces returns the node traces collected for the message, in execution order.
+// The traces of the messages emitted by a split node follow the split node, branch by branch.
+//
+// Traces 返回为消息收集的节点跟踪记录，按执行顺序排列。拆分节点产生的消息的记录按分支依次排在拆分节点之后。
+func (sd *RuleMsg) Traces() []NodeTrace {
+	return append([]NodeTrace(nil), sd.data.traces...)
+}
+
+// AddTrace appends node traces to the message.
+// AddTrace 向消息追加节点跟踪记录。
+func (sd *RuleMsg) AddTrace(traces ...NodeTrace) {
+	sd.data.traces = append(sd.data.traces, traces...)
+}
+
+// Snapshot returns a copy of the message input whose private variables are copied too,
+// so later changes by the nodes do not show in it. Nested values are shared.
+//
+// Snapshot 返回消息输入的副本，私有变量也会被复制，因此之后节点的修改不会反映在其中。嵌套的值是共享的。
+func (sd *RuleMsg) Snapshot() map[string]any {
+	snapshot := copyInput(sd.GetInput())
+	snapshot[PriVarsKey] = copyInput(sd.GetPrivateVars())
+	return snapshot
+}
@@ -131,6 +131,8 @@ package types
 
 import (
 	"context"
+	"strconv"
+	"strings"
 )
 
 // Configuration is a type for component configurations, represented as a map with string keys and interface{} values.
@@ -176,6 +178,31 @@ func (c Configuration) Copy() Configuration {
 	return copy
 }
 
+// MergeDefaults returns a shallow copy of the Configuration with the keys missing
+// from it filled from defaults. Values in c win on conflict, neither map is modified.
+// MergeDefaults 返回 Configuration 的浅拷贝，并用 defaults 填充其中缺失的键。
+// 冲突时以 c 中的值为准，两个映射都不会被修改。
+//
+// Example:
+// 示例：
+//
+//	chainConf := Configuration{"timeout": 30, "retry": 3}
+//	nodeConf := Configuration{"timeout": 10}
+//	nodeConf.MergeDefaults(chainConf) // {"timeout": 10, "retry": 3}
+func (c Configuration) MergeDefaults(defaults Configuration) Configuration {
+	if len(defaults) == 0 {
+		return c
+	}
+	merged := make(Configuration, len(c)+len(defaults))
+	for key, value := range defaults {
+		merged[key] = value
+	}
+	for key, value := range c {
+		merged[key] = value
+	}
+	return merged
+}
+
 // ComponentRegistry is an interface for registering node components.
 // ComponentRegistry 是注册节点组件的接口。
 //
@@ -215,6 +242,7 @@ func (c Configuration) Copy() Configuration {
 //
 // 组件发现 - Component Discovery:
 //   - GetComponents(): 获取所有可用组件列表 - Get list of all available components
+//   - GetComponentsSorted(): 获取按类型排序的组件列表 - Get the components ordered by type
 //   - GetComponentForms(): 获取组件配置表单，支持UI工具 - Get component configuration forms for UI tools
 //   - NewNode(): 通过类型名称实例化组件 - Instantiate components by type name
 //   - 自动组件分类和元数据提取 - Automatic component categorization and metadata extraction
@@ -272,6 +300,22 @@ type ComponentRegistry interface {
 	// 注意：返回的实例是仅用于元数据的原型。
 	// 使用 NewNode() 为规则链创建工作实例。
 	GetComponents() map[NodeType]Node
+	// GetComponentsSorted retrieves the registered components ordered by type.
+	// GetComponentsSorted 检索按类型排序的已注册组件。
+	//
+	// Unlike ranging over GetComponents, the order is deterministic, so UI palettes and
+	// golden tests listing the components are stable.
+	// 与遍历 GetComponents 不同，顺序是确定的，因此列出组件的 UI 面板和黄金测试是稳定的。
+	GetComponentsSorted() []Node
+	// GetComponent retrieves the prototype of a single registered component, without copying the whole registry.
+	// GetComponent 检索单个已注册组件的原型，无需复制整个注册表。
+	//
+	// Returns false when the component type is not registered.
+	// 组件类型未注册时返回 false。
+	//
+	// Note: Like GetComponents, the returned instance is a prototype for metadata only.
+	// 注意：与 GetComponents 相同，返回的实例是仅用于元数据的原型。
+	GetComponent(componentType NodeType) (Node, bool)
 }
 
 // Node is the core interface for rule engine node components.
@@ -592,6 +636,43 @@ type Node interface {
 	Destroy()
 }
 
+// MultiOutputNode is an optional interface for nodes that emit several messages downstream,
+// such as the split node.
+// MultiOutputNode 是向下游发送多条消息的节点可实现的可选接口，如拆分节点。
+//
+// The chain calls OnMsgs instead of OnMsg and runs the remainder of the chain once per
+// returned message, in order. Each branch writes its chain output to its own message,
+// the outputs are then collected into the chain output of the original message
+// under SplitResultsKey.
+// 规则链调用 OnMsgs 而不是 OnMsg，并按顺序为每条返回的消息执行一次规则链的剩余部分。
+// 每个分支的规则链输出写入各自的消息，最后以 SplitResultsKey 汇总到原消息的规则链输出中。
+type MultiOutputNode interface {
+	Node
+	// OnMsgs processes the message and returns the relation type and the messages to send to it.
+	// OnMsgs 处理消息，返回关系类型以及要发送的消息列表。
+	OnMsgs(ctx context.Context, msg RuleMsg) (string, []RuleMsg, error)
+}
+
+// AsyncNode is an optional interface for nodes that hand the message off instead of holding the calling
+// goroutine while they wait, such as the waitUntil node.
+// AsyncNode 是在等待期间移交消息、不占用调用方 goroutine 的节点可实现的可选接口，如 waitUntil 节点。
+//
+// The chain calls OnMsgAsync instead of OnMsg. A node completing right away returns its relation type or
+// error with handedOff false, like OnMsg. Otherwise it returns handedOff true and later calls resume exactly
+// once, from any goroutine but not while holding its own locks, with the relation type or the error; the chain
+// continues from there. The engine OnMsg returns once the message is handed off unless the context is marked
+// with ContextWithSync. Split branches, aggregation child chains and chains run by runChain wait for resume.
+// 规则链调用 OnMsgAsync 而不是 OnMsg。立即完成的节点与 OnMsg 一样返回关系类型或错误，handedOff 为 false。
+// 否则返回 handedOff 为 true，之后在任意 goroutine 中（但不能在持有自身锁时）调用 resume 恰好一次，传入关系类型或错误，
+// 规则链从此处继续执行。消息移交后引擎 OnMsg 即返回，除非上下文以 ContextWithSync 标记。
+// 拆分分支、聚合子规则链以及 runChain 执行的规则链会等待 resume。
+type AsyncNode interface {
+	Node
+	// OnMsgAsync processes the message, handing it off when it cannot complete right away.
+	// OnMsgAsync 处理消息，无法立即完成时移交消息。
+	OnMsgAsync(ctx context.Context, msg RuleMsg, resume func(relationType string, err error)) (relationType string, handedOff bool, err error)
+}
+
 // NodeCtx is the context for instantiating rule nodes.
 // NodeCtx 是实例化规则节点的上下文。
 //
@@ -700,6 +781,26 @@ type Parser interface {
 	EncodeRule(def interface{}) ([]byte, error)
 }
 
+// JSONCodec is the JSON implementation used by the JSON parser to decode and encode the DSL,
+// see Config.JSONCodec. It lets a faster library than encoding/json be plugged in, since
+// JSON decoding and encoding dominate the reload and DSL() cost of large chains.
+// Implementations must produce the same documents as encoding/json for the DSL types.
+//
+// JSONCodec 是 JSON 解析器解码和编码 DSL 所使用的 JSON 实现，参见 Config.JSONCodec。
+// 由于 JSON 解码和编码是大型规则链重新加载和 DSL() 的主要开销，可以接入比 encoding/json 更快的库。
+// 实现对 DSL 类型必须生成与 encoding/json 相同的文档。
+type JSONCodec interface {
+	// Marshal encodes v without HTML escaping.
+	// Marshal 编码 v，不转义 HTML。
+	Marshal(v interface{}) ([]byte, error)
+	// Unmarshal decodes data into v.
+	// Unmarshal 将 data 解码到 v。
+	Unmarshal(data []byte, v interface{}) error
+	// Format indents the JSON document for display.
+	// Format 缩进 JSON 文档以便展示。
+	Format(data []byte) ([]byte, error)
+}
+
 // RuleNodeRelation defines the relationship between nodes.
 // RuleNodeRelation 定义节点间的关系。
 //
@@ -741,6 +842,12 @@ type RuleNodeRelation struct {
 	// 此字段决定消息从 InId 流向 OutId 的条件。
 	// 自定义关系类型启用领域特定的路由逻辑。
 	RelationType string
+	// Condition is the optional expr guard of the connection, see NodeConnection.Condition.
+	// Condition 是连接的可选 expr 守卫表达式，参见 NodeConnection.Condition。
+	Condition string
+	// Priority orders the relations of a node with the same type, see NodeConnection.Priority.
+	// Priority 对节点中相同类型的关系排序，参见 NodeConnection.Priority。
+	Priority int
 }
 
 // ScriptFuncSeparator is the delimiter for script function names.
@@ -804,7 +911,7 @@ type Script struct {
 }
 
 type OnNew func(chainId string, dsl []byte)
-type OnUpdated func(chainId string, dsl []byte)
+type OnUpdated func(chainId string, dsl []byte, diff ChainDiff)
 type OnDeleted func(id string)
 
 // Callbacks is a set of callback functions for pool events.
@@ -857,6 +964,8 @@ type Callbacks struct {
 	//     nodeId：更新组件的标识符
 	//   - dsl: Updated DSL definition of the component
 	//     dsl：组件的更新 DSL 定义
+	//   - diff: Nodes and connections changed by the update, see DiffChains
+	//     diff：本次更新变化的节点和连接，参见 DiffChains
 	OnUpdated OnUpdated
 
 	// OnDeleted is called when a component or rule chain is deleted.
@@ -871,15 +980,148 @@ type Callbacks struct {
 
 type Case struct {
 	Case string `json:"case"`
+	// Then 条件成立时返回的关系，按字面字符串处理，带引号的写法（如 "\"1\"" 或 "'1'"）也被接受
+	// Then is the relation returned when the case matches, taken as a literal string. A quoted string
+	// literal, e.g. "\"1\"" or "'1'", is accepted too and means the same relation.
 	Then string `json:"then"`
 }
 
+// Relation returns the relation of Then, trimmed and without the quotes of a quoted string literal.
+// Relation 返回 Then 对应的关系，去除首尾空白以及字符串字面量的引号。
+func (c Case) Relation() string {
+	then := strings.TrimSpace(c.Then)
+	if len(then) < 2 || then[0] != then[len(then)-1] {
+		return then
+	}
+	switch then[0] {
+	case '"':
+		if relation, err := strconv.Unquote(then); err == nil {
+			return relation
+		}
+	case '\'', '`':
+		return then[1 : len(then)-1]
+	}
+	return then
+}
+
 type ChainAggregationConfiguration struct {
 	Aggregation Aggregation
+	// OutputKey 子规则链输出在聚合输出中的键：AggregationOutputKeyId（默认）或 AggregationOutputKeyName
+	// OutputKey selects the key of each child chain output in the aggregation output:
+	// AggregationOutputKeyId (default) or AggregationOutputKeyName
+	OutputKey string
 }
 
+const (
+	// AggregationOutputKeyId 按规则链 id 作为聚合输出的键
+	// AggregationOutputKeyId keys the chain outputs by chain id.
+	AggregationOutputKeyId = "id"
+	// AggregationOutputKeyName 按规则链名称作为聚合输出的键，名称为空或重复时使用 "名称#id"（名称为空时使用 id）
+	// AggregationOutputKeyName keys the chain outputs by chain name. Chains sharing a name are keyed
+	// by "name#id", chains without a name by id.
+	AggregationOutputKeyName = "name"
+)
+
 type Aggregation struct {
 	Cases []Case
+	// Thresholds 按 MinScore 升序排列的分数区间，最终分数通过区间映射为动作
+	// Thresholds are the score bands sorted by ascending MinScore, the final score maps to an action through them
+	Thresholds []ThresholdBand
+	// ScoreExpr 可选的 expr 表达式，在所有子规则链执行后计算最终分数，替代各规则链分数之和，在映射区间之前求值
+	// ScoreExpr is an optional expr computing the final score once every chain ran, in place of the sum of
+	// the chain scores. It is evaluated before the score maps to a band. See AggregationChainsKey for the variables.
+	//
+	// Example: "chains.blacklist.score > 0 ? 100 : score"
+	ScoreExpr string `json:"scoreExpr"`
+	// ActionExpr 可选的 expr 表达式，在映射区间之后计算最终动作，返回空字符串时保留区间的动作
+	// ActionExpr is an optional expr computing the final action after the score mapped to a band,
+	// an empty result keeps the action of the band. See AggregationChainsKey for the variables.
+	//
+	// Example: "chains.kyc.verified != true && action == 'ACCEPT' ? 'REVIEW' : ''"
+	ActionExpr string `json:"actionExpr"`
+}
+
+// Variables of the ScoreExpr and ActionExpr aggregation expressions, in addition to those of the
+// node expressions, see base.NodeUtils.ExprEnv. They take precedence over input fields of the same name.
+// 聚合表达式 ScoreExpr 和 ActionExpr 的变量，此外还可以使用节点表达式的变量（参见 base.NodeUtils.ExprEnv）。
+// 它们优先于同名的输入字段。
+const (
+	// AggregationChainsKey the outputs of the chains that ran, keyed like RuleMsg.GetChainAggregationOutput,
+	// e.g. chains.blacklist.score  已执行的规则链的输出，键与 RuleMsg.GetChainAggregationOutput 相同，如 chains.blacklist.score
+	AggregationChainsKey = "chains"
+	// AggregationScoreKey the sum of the chain scores, or the result of ScoreExpr in ActionExpr  各规则链分数之和，在 ActionExpr 中为 ScoreExpr 的结果
+	AggregationScoreKey = "score"
+	// AggregationReasonsKey the reasons of the chains ([]string)  各规则链的原因（[]string）
+	AggregationReasonsKey = "reasons"
+	// AggregationTagsKey the tags of the chains ([]string)  各规则链的标签（[]string）
+	AggregationTagsKey = "tags"
+	// AggregationActionKey the action of the band, only in ActionExpr  区间的动作，仅在 ActionExpr 中可用
+	AggregationActionKey = "action"
+	// AggregationLabelKey the label of the band, only in ActionExpr  区间的标签，仅在 ActionExpr 中可用
+	AggregationLabelKey = "label"
+)
+
+// ThresholdBand 分数区间，覆盖从 MinScore 到下一个区间 MinScore 之前的分数
+// ThresholdBand covers the scores from MinScore up to the MinScore of the next band.
+//
+// Example:
+// 示例：
+//
+//	[{"minScore": 0, "action": "ACCEPT"}, {"minScore": 60, "action": "REVIEW"}, {"minScore": 90, "action": "REJECT"}]
+type ThresholdBand struct {
+	MinScore int    `json:"minScore"`
+	Action   string `json:"action"`
+	Label    string `json:"label"`
+}
+
+// Band 返回分数所在的区间，分数低于所有区间时返回 false
+// Band returns the band the score falls in, false if the score is below every band.
+func (a Aggregation) Band(score int) (ThresholdBand, bool) {
+	for i := len(a.Thresholds) - 1; i >= 0; i-- {
+		if score >= a.Thresholds[i].MinScore {
+			return a.Thresholds[i], true
+		}
+	}
+	return ThresholdBand{}, false
+}
+
+// RangeBand rangeSwitch 节点的数值区间，值小于 Max 且不小于上一个区间的 Max 时路由到 Relation
+// RangeBand is a numeric band of a rangeSwitch node, a value below Max and not below the Max
+// of the previous band routes to Relation.
+type RangeBand struct {
+	Max      float64 `json:"max"`
+	Relation string  `json:"relation"`
+}
+
+// WeightedRoute weightedRoute 节点的路由分支，消息以 Weight（百分比）的概率路由到 Relation
+// WeightedRoute is a route of a weightedRoute node, a message routes to Relation with the probability
+// Weight, in percent.
+type WeightedRoute struct {
+	Relation string  `json:"relation"`
+	Weight   float64 `json:"weight"`
+}
+
+// TimeWindow timeRouter 节点的时间窗口，当前时间匹配 Cron 表达式，或位于 Days 中某天的 Start 至 End 之间时路由到 Relation
+// TimeWindow is a time window of a timeRouter node. The current time matches the window when it matches
+// Cron, a 5 field cron expression, or else when it falls on one of Days between Start and End.
+type TimeWindow struct {
+	// Cron 5 段 cron 表达式：分 时 日 月 周，如 "* 2-4 * * 0" 表示周日 2 点至 5 点
+	// Cron is a cron expression of 5 fields: minute hour day-of-month month day-of-week,
+	// e.g. "* 2-4 * * 0" for 2 to 5 am on Sundays
+	Cron string `json:"cron,omitempty"`
+	// Days 窗口所在的星期，如 ["mon", "fri"]，为空时每天
+	// Days are the days of the week of the window, e.g. ["mon", "fri"], every day when empty
+	Days []string `json:"days,omitempty"`
+	// Start 窗口开始时间 HH:MM（含），为空时从 00:00 开始
+	// Start is the time of day the window starts at, HH:MM included, 00:00 when empty
+	Start string `json:"start,omitempty"`
+	// End 窗口结束时间 HH:MM（不含），早于 Start 时窗口跨越午夜，为空时到 24:00 结束
+	// End is the time of day the window ends at, HH:MM excluded, the window spans midnight when it is before
+	// Start, 24:00 when empty
+	End string `json:"end,omitempty"`
+	// Relation 匹配时路由到的关系
+	// Relation is the relation routed to when the window matches
+	Relation string `json:"relation"`
 }
 
 type ChainResult struct {
@@ -888,6 +1130,7 @@ type ChainResult struct {
 	Terminate bool
 	Action    string
 	Reason    string
+	Reasons   []string
 	Tags      []string
 }
 
@@ -895,6 +1138,7 @@ type ChainAggregationResult struct {
 	Score     int
 	Terminate bool
 	Action    string
+	Label     string
 	Reasons   []string
 	Tags      []string
 }
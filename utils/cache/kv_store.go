@@ -0,0 +1,68 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cache
+
+import (
+	"context"
+	"sync"
+
+	"github.com/bittoy/rule/types"
+)
+
+// Compile-time check MapKVStore implements types.KVStore.
+var _ types.KVStore = (*MapKVStore)(nil)
+
+// MapKVStore is an in-memory types.KVStore, for tests and small reference data sets.
+// The zero value is ready to use.
+type MapKVStore struct {
+	mu      sync.RWMutex
+	records map[string]map[string]any
+}
+
+// NewMapKVStore creates a MapKVStore holding the records.
+func NewMapKVStore(records map[string]map[string]any) *MapKVStore {
+	s := &MapKVStore{}
+	for key, record := range records {
+		s.Set(key, record)
+	}
+	return s
+}
+
+// Get returns the record of the key.
+func (s *MapKVStore) Get(ctx context.Context, key string) (map[string]any, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	record, ok := s.records[key]
+	return record, ok, nil
+}
+
+// Set stores the record of the key, replacing any previous one.
+func (s *MapKVStore) Set(key string, record map[string]any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.records == nil {
+		s.records = make(map[string]map[string]any)
+	}
+	s.records[key] = record
+}
+
+// Delete removes the record of the key.
+func (s *MapKVStore) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+}
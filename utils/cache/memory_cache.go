@@ -20,7 +20,7 @@ import (
 	"sync"
 	"time"
 
-	"github.com/rulego/rulego/api/types"
+	"github.com/bittoy/rule/types"
 )
 
 var DefaultCache = NewMemoryCache(time.Minute * 5)
@@ -105,6 +105,45 @@ func (c *MemoryCache) Set(key string, value interface{}, ttl string) error {
 	return nil
 }
 
+// Update atomically replaces the value of the key with the value returned by update, see types.CacheUpdater.
+// update runs under the cache lock, it must be fast and must not call the cache.
+func (c *MemoryCache) Update(key string, ttl string, update func(value any) (newValue any, store bool)) error {
+	var dur time.Duration
+	if ttl != "" {
+		var err error
+		if dur, err = time.ParseDuration(ttl); err != nil {
+			return err
+		}
+	}
+
+	c.mu.Lock()
+	now := time.Now()
+	var current any
+	if it, found := c.items[key]; found && (it.expiration == 0 || now.UnixNano() <= it.expiration) {
+		current = it.value
+	}
+	value, store := update(current)
+	if !store {
+		c.mu.Unlock()
+		return nil
+	}
+	var expiration int64
+	if dur > 0 {
+		expiration = now.Add(dur).UnixNano()
+	}
+	c.items[key] = item{
+		value:      value,
+		expiration: expiration,
+	}
+	shouldStartGC := expiration > 0 && c.ticker == nil
+	c.mu.Unlock()
+
+	if shouldStartGC {
+		c.StartGC()
+	}
+	return nil
+}
+
 // Get retrieves a value from the cache by its key.
 // Parameters:
 //   - key: The cache key to retrieve (string)
@@ -434,6 +473,21 @@ func (c *NamespaceCache) DeleteByPrefix(prefix string) error {
 	return c.Cache.DeleteByPrefix(c.Namespace + prefix)
 }
 
+// Update updates the value of a prefixed key, atomically when the underlying cache implements
+// types.CacheUpdater, otherwise with a separate Get and Set.
+func (c *NamespaceCache) Update(key string, ttl string, update func(value any) (newValue any, store bool)) error {
+	if c == nil || c.Cache == nil {
+		return types.ErrCacheNotInitialized
+	}
+	if updater, ok := c.Cache.(types.CacheUpdater); ok {
+		return updater.Update(c.Namespace+key, ttl, update)
+	}
+	if value, store := update(c.Cache.Get(c.Namespace + key)); store {
+		return c.Cache.Set(c.Namespace+key, value, ttl)
+	}
+	return nil
+}
+
 func (c *NamespaceCache) GetByPrefix(prefix string) map[string]interface{} {
 	if c == nil || c.Cache == nil {
 		return map[string]interface{}{}
@@ -453,3 +507,9 @@ var _ types.Cache = (*NamespaceCache)(nil)
 
 // Ensure MemoryCache implements the Cache interface.
 var _ types.Cache = (*MemoryCache)(nil)
+
+// Ensure MemoryCache and NamespaceCache implement the CacheUpdater interface.
+var (
+	_ types.CacheUpdater = (*MemoryCache)(nil)
+	_ types.CacheUpdater = (*NamespaceCache)(nil)
+)
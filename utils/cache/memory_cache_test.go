@@ -18,6 +18,7 @@ package cache
 
 import (
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -305,3 +306,31 @@ func TestNamespaceCache(t *testing.T) {
 	})
 
 }
+
+func TestMemoryCache_Update(t *testing.T) {
+	c := NewMemoryCache(time.Minute)
+	defer c.StopGC()
+	increment := func(value any) (any, bool) {
+		n, _ := value.(int)
+		return n + 1, true
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Nil(t, c.Update("n", "1m", increment))
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 100, c.Get("n"))
+
+	// A value not stored leaves the key unchanged
+	assert.Nil(t, c.Update("n", "", func(value any) (any, bool) { return nil, false }))
+	assert.Equal(t, 100, c.Get("n"))
+	assert.NotNil(t, c.Update("n", "bad", increment))
+
+	ns := NewNamespaceCache(c, "ns:")
+	assert.Nil(t, ns.Update("n", "", increment))
+	assert.Equal(t, 1, c.Get("ns:n"))
+}
@@ -155,6 +155,24 @@ func ToDurationE(value interface{}) (time.Duration, error) {
 	}
 }
 
+// ToTimeE converts an interface{} to time.Time with error handling.
+// A string is parsed as an RFC 3339 time, other values as a Unix timestamp in milliseconds.
+func ToTimeE(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			return t, nil
+		}
+	}
+	ms, err := ToInt64E(value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.UnixMilli(ms), nil
+}
+
 // ToBool converts an interface{} to bool.
 // It returns false if conversion fails.
 func ToBool(value interface{}) bool {
@@ -126,6 +126,35 @@ func TestToDurationE(t *testing.T) {
 	}
 }
 
+func TestToTimeE(t *testing.T) {
+	at := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name   string
+		input  interface{}
+		expect time.Time
+		hasErr bool
+	}{
+		{"time", at, at, false},
+		{"millis", at.UnixMilli(), at, false},
+		{"float millis", float64(at.UnixMilli()), at, false},
+		{"rfc3339", "2025-01-02T03:04:05Z", at, false},
+		{"invalid string", "tomorrow", time.Time{}, true},
+		{"invalid type", []int{1}, time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToTimeE(tt.input)
+			if (err != nil) != tt.hasErr {
+				t.Errorf("ToTimeE() error = %v, wantErr %v", err, tt.hasErr)
+			}
+			if !tt.hasErr && !got.Equal(tt.expect) {
+				t.Errorf("ToTimeE() = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
 func TestToBool(t *testing.T) {
 	tests := []struct {
 		name   string
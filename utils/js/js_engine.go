@@ -47,8 +47,10 @@ import (
 )
 
 const (
-	GlobalKey = "global"
-	MetaData  = "metadata"
+	// GlobalKey is the default variable name of the global properties, see types.Config.ScriptGlobalKey
+	GlobalKey = types.DefaultScriptGlobalKey
+	// MetaData is the default variable name of the metadata, see types.Config.ScriptMetadataKey
+	MetaData = types.DefaultScriptMetadataKey
 )
 
 // GojaJsEngine goja js engine
@@ -61,23 +63,22 @@ type GojaJsEngine struct {
 // NewGojaJsEngine Create a new instance of the JavaScript engine
 func NewGojaJsEngine(config types.Config, jsScript string, fromVars map[string]any) (*GojaJsEngine, error) {
 	vm := goja.New()
-	_, err := vm.RunString(jsScript)
-	if err != nil {
+	// Set global properties and metadata under their configured names
+	// 按配置的名称设置全局属性和元数据
+	if len(config.Properties.Values()) != 0 {
+		if err := vm.Set(config.GetScriptGlobalKey(), config.Properties.Values()); err != nil {
+			config.Logger.Printf("set global properties error: %s", err.Error())
+		}
+	}
+	if len(fromVars) != 0 {
+		if err := vm.Set(config.GetScriptMetadataKey(), fromVars); err != nil {
+			config.Logger.Printf("set fromVars %v error: %s", fromVars, err.Error())
+		}
+	}
+	if _, err := vm.RunString(jsScript); err != nil {
 		return nil, err
 	}
 
-	// Set global properties directly
-	// if len(config.Properties.Values()) != 0 {
-	// 	if err := vm.Set(GlobalKey, config.Properties.Values()); err != nil {
-	// 		config.Logger.Printf("set global properties error: %s", err.Error())
-	// 	}
-	// }
-	// if len(fromVars) != 0 {
-	// 	if err := vm.Set(MetaData, fromVars); err != nil {
-	// 		config.Logger.Printf("set fromVars %v error: %s", fromVars, err.Error())
-	// 	}
-	// }
-
 	return &GojaJsEngine{
 		config: config,
 		vm:     vm,
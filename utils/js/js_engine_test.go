@@ -0,0 +1,46 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package js
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bittoy/rule/types"
+	"github.com/rulego/rulego/test/assert"
+)
+
+func TestGojaJsEngineGlobalVars(t *testing.T) {
+	config := types.NewConfig()
+	config.Properties.PutValue("env", "prod")
+	script := "function route(msg) { return global.env === 'prod' && metadata.region === 'eu' ? msg.name : 'default'; }"
+
+	jsEngine, err := NewGojaJsEngine(config, script, map[string]any{"region": "eu"})
+	assert.Nil(t, err)
+	out, err := jsEngine.Execute(context.Background(), nil, "route", map[string]any{"name": "prodEu"})
+	assert.Nil(t, err)
+	assert.Equal(t, "prodEu", out)
+
+	config = types.NewConfig(types.WithScriptEnvKeys("props", "meta"))
+	config.Properties.PutValue("env", "prod")
+	script = "function route(msg) { return props.env + '-' + meta.region; }"
+	jsEngine, err = NewGojaJsEngine(config, script, map[string]any{"region": "eu"})
+	assert.Nil(t, err)
+	out, err = jsEngine.Execute(context.Background(), nil, "route")
+	assert.Nil(t, err)
+	assert.Equal(t, "prod-eu", out)
+}
@@ -0,0 +1,255 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package js
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/bittoy/rule/types"
+
+	"github.com/dop251/goja"
+)
+
+// DefaultMaxIdleVMs is the default maximum number of idle VMs kept by a VMPool.
+// DefaultMaxIdleVMs 是 VMPool 默认保留的最大空闲 VM 数。
+const DefaultMaxIdleVMs = 256
+
+// DefaultVMPool is the pool used by configs without a JsVMPool.
+// DefaultVMPool 是未设置 JsVMPool 的配置使用的池。
+var DefaultVMPool = NewVMPool(DefaultMaxIdleVMs)
+
+var _ types.JsVMPool = (*VMPool)(nil)
+
+// VMPool is a size-bounded pool of goja VMs keyed by the script source.
+// Nodes with identical scripts share the compiled program and the warm VMs, and a reloaded
+// node picks up the VMs of its previous instance.
+//
+// VMPool 是按脚本源码区分的有界 goja VM 池。相同脚本的节点共享编译后的程序和已预热的 VM，
+// 重新加载的节点可以继续使用之前实例的 VM。
+//
+// At most maxIdle VMs are kept idle across all scripts, the VMs of the least recently used
+// scripts are evicted first. The global object of each VM is frozen once the script has run, so a call
+// cannot leave global state behind for the next one: assigning a global throws a TypeError.
+//
+// 所有脚本合计最多保留 maxIdle 个空闲 VM，最久未使用的脚本的 VM 最先被淘汰。脚本执行后每个 VM 的
+// 全局对象会被冻结，调用无法为下一次调用遗留全局状态：对全局变量赋值会抛出 TypeError。
+type VMPool struct {
+	mu sync.Mutex
+	// maxIdle is the maximum number of idle VMs  最大空闲 VM 数
+	maxIdle int
+	// idle is the current number of idle VMs  当前空闲 VM 数
+	idle int
+	// programs maps the script source to its element in lru  脚本源码到其在 lru 中元素的映射
+	programs map[string]*list.Element
+	// lru orders the scripts from the most to the least recently used  按最近使用顺序排列的脚本
+	lru *list.List
+}
+
+// pooledProgram is a compiled script and its idle VMs.
+type pooledProgram struct {
+	key     string
+	program *goja.Program
+	vms     []*goja.Runtime
+}
+
+// NewVMPool creates a VM pool keeping at most maxIdle idle VMs, VMs are not kept when it is not positive.
+// NewVMPool 创建最多保留 maxIdle 个空闲 VM 的池，非正数时不保留 VM。
+func NewVMPool(maxIdle int) *VMPool {
+	return &VMPool{
+		maxIdle:  maxIdle,
+		programs: make(map[string]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+// Compile compiles the script, a script compiled before is not compiled again.
+// Compile 编译脚本，已编译过的脚本不会重复编译。
+func (p *VMPool) Compile(name, source string) error {
+	_, err := p.program(name, source)
+	return err
+}
+
+// Call runs the function fnName defined by the script on a pooled VM and returns the exported result.
+// The script is interrupted when ctx is done, Call then returns the context error.
+// Call 在池中的 VM 上执行脚本定义的 fnName 函数并返回导出的结果。
+// ctx 结束时脚本被中断，Call 返回上下文的错误。
+func (p *VMPool) Call(ctx context.Context, source, fnName string, args ...any) (any, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	entry, err := p.program("", source)
+	if err != nil {
+		return nil, err
+	}
+	vm, err := p.get(entry)
+	if err != nil {
+		return nil, err
+	}
+	stop := context.AfterFunc(ctx, func() {
+		vm.Interrupt(ctx.Err())
+	})
+	defer func() {
+		// 中断可能在调用返回后才发生，已触发中断的 VM 不再放回池中
+		// The interrupt may land after the call returned, a VM whose interrupt fired is not pooled again
+		if stop() {
+			p.put(entry, vm)
+		}
+	}()
+
+	f, ok := goja.AssertFunction(vm.Get(fnName))
+	if !ok {
+		return nil, errors.New(fnName + " is not a function")
+	}
+	params := make([]goja.Value, len(args))
+	for i, v := range args {
+		if reader, ok := v.(types.FieldReader); ok {
+			params[i] = vm.NewDynamicObject(&fieldObject{vm: vm, reader: reader})
+		} else {
+			params[i] = vm.ToValue(v)
+		}
+	}
+	res, err := f(goja.Undefined(), params...)
+	if err != nil {
+		var interrupted *goja.InterruptedError
+		if errors.As(err, &interrupted) && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	return res.Export(), nil
+}
+
+// fieldObject exposes a types.FieldReader to scripts as a read-only object.
+type fieldObject struct {
+	vm     *goja.Runtime
+	reader types.FieldReader
+}
+
+func (o *fieldObject) Get(key string) goja.Value {
+	if v, ok := o.reader.Field(key); ok {
+		return o.vm.ToValue(v)
+	}
+	return nil
+}
+
+func (o *fieldObject) Set(string, goja.Value) bool {
+	return false
+}
+
+func (o *fieldObject) Has(key string) bool {
+	_, ok := o.reader.Field(key)
+	return ok
+}
+
+func (o *fieldObject) Delete(string) bool {
+	return false
+}
+
+func (o *fieldObject) Keys() []string {
+	return o.reader.FieldNames()
+}
+
+// Len returns the number of idle VMs.
+// Len 返回空闲 VM 数。
+func (p *VMPool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.idle
+}
+
+// program returns the compiled script, compiling it when it is not in the pool.
+func (p *VMPool) program(name, source string) (*pooledProgram, error) {
+	key := source
+	p.mu.Lock()
+	if element, ok := p.programs[key]; ok {
+		p.lru.MoveToFront(element)
+		p.mu.Unlock()
+		return element.Value.(*pooledProgram), nil
+	}
+	p.mu.Unlock()
+
+	program, err := goja.Compile(name, source, true)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if element, ok := p.programs[key]; ok {
+		return element.Value.(*pooledProgram), nil
+	}
+	entry := &pooledProgram{key: key, program: program}
+	p.programs[key] = p.lru.PushFront(entry)
+	return entry, nil
+}
+
+// get takes an idle VM of the script, or creates one.
+func (p *VMPool) get(entry *pooledProgram) (*goja.Runtime, error) {
+	p.mu.Lock()
+	if n := len(entry.vms); n > 0 {
+		vm := entry.vms[n-1]
+		entry.vms = entry.vms[:n-1]
+		p.idle--
+		p.mu.Unlock()
+		return vm, nil
+	}
+	p.mu.Unlock()
+
+	vm := goja.New()
+	if _, err := vm.RunProgram(entry.program); err != nil {
+		return nil, err
+	}
+	// Freeze the global object, so a call can neither add globals nor replace the ones defined by the script
+	// 冻结全局对象，调用既不能添加全局变量，也不能替换脚本定义的全局变量
+	if _, err := vm.RunString("Object.freeze(globalThis);"); err != nil {
+		return nil, err
+	}
+	return vm, nil
+}
+
+// put returns the VM to the pool, evicting the VMs of the least recently used scripts
+// when the pool is full.
+func (p *VMPool) put(entry *pooledProgram, vm *goja.Runtime) {
+	if p.maxIdle <= 0 {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if element, ok := p.programs[entry.key]; !ok || element.Value != entry {
+		// The script was evicted while the VM was in use
+		// 使用 VM 期间脚本已被淘汰
+		return
+	}
+	entry.vms = append(entry.vms, vm)
+	p.idle++
+	for p.idle > p.maxIdle {
+		element := p.lru.Back()
+		oldest := element.Value.(*pooledProgram)
+		if n := len(oldest.vms); n > 0 {
+			oldest.vms[n-1] = nil
+			oldest.vms = oldest.vms[:n-1]
+			p.idle--
+		}
+		if len(oldest.vms) == 0 {
+			p.lru.Remove(element)
+			delete(p.programs, oldest.key)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package js
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bittoy/rule/types"
+	"github.com/dop251/goja"
+	"github.com/rulego/rulego/test/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const testScript = "function jsFilter(msg) { return msg.temperature > 25; } jsFilter;"
+
+func TestVMPoolCall(t *testing.T) {
+	pool := NewVMPool(4)
+	assert.Nil(t, pool.Compile("jsFilter.js", testScript))
+
+	out, err := pool.Call(context.Background(), testScript, "jsFilter", map[string]any{"temperature": 30})
+	assert.Nil(t, err)
+	assert.Equal(t, true, out)
+	out, err = pool.Call(context.Background(), testScript, "jsFilter", map[string]any{"temperature": 20})
+	assert.Nil(t, err)
+	assert.Equal(t, false, out)
+	assert.Equal(t, 1, pool.Len())
+
+	_, err = pool.Call(context.Background(), testScript, "notFound")
+	assert.NotNil(t, err)
+	assert.NotNil(t, pool.Compile("bad.js", "function ("))
+}
+
+func TestVMPoolFieldReader(t *testing.T) {
+	pool := NewVMPool(4)
+	script := "function read(msg) { return [msg.name, msg.number, msg.missing === undefined, 'name' in msg, Object.keys(msg).length > 0]; } read;"
+	msg := types.NewProtoRuleMsg("", 0, &descriptorpb.FieldDescriptorProto{Name: proto.String("id"), Number: proto.Int32(1)})
+
+	out, err := pool.Call(context.Background(), script, "read", &msg)
+	assert.Nil(t, err)
+	assert.Equal(t, []any{"id", int64(1), true, true, true}, out)
+}
+
+func TestVMPoolIsolation(t *testing.T) {
+	pool := NewVMPool(4)
+	script := "function count(msg) { globalThis.counter = (globalThis.counter || 0) + 1; return globalThis.counter; } count;"
+	replace := "function replace(msg) { replace = null; return 1; } replace;"
+
+	for i := 0; i < 3; i++ {
+		_, err := pool.Call(context.Background(), script, "count")
+		assert.NotNil(t, err)
+		_, err = pool.Call(context.Background(), replace, "replace")
+		assert.NotNil(t, err)
+	}
+	assert.Equal(t, 2, pool.Len())
+}
+
+func TestVMPoolEviction(t *testing.T) {
+	pool := NewVMPool(2)
+	for i := 0; i < 5; i++ {
+		script := fmt.Sprintf("function f() { return %d; } f;", i)
+		out, err := pool.Call(context.Background(), script, "f")
+		assert.Nil(t, err)
+		assert.Equal(t, int64(i), out)
+	}
+	assert.Equal(t, 2, pool.Len())
+	assert.Equal(t, 2, len(pool.programs))
+
+	noIdle := NewVMPool(0)
+	_, err := noIdle.Call(context.Background(), testScript, "jsFilter", map[string]any{"temperature": 30})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, noIdle.Len())
+}
+
+func TestVMPoolCancel(t *testing.T) {
+	pool := NewVMPool(4)
+	script := "function loop() { while (true) {} } loop;"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := pool.Call(ctx, script, "loop")
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Equal(t, 0, pool.Len())
+
+	_, err = pool.Call(ctx, testScript, "jsFilter", map[string]any{"temperature": 30})
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	out, err := pool.Call(context.Background(), testScript, "jsFilter", map[string]any{"temperature": 30})
+	assert.Nil(t, err)
+	assert.Equal(t, true, out)
+	assert.Equal(t, 1, pool.Len())
+}
+
+func TestVMPoolConcurrent(t *testing.T) {
+	pool := NewVMPool(8)
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				out, err := pool.Call(context.Background(), testScript, "jsFilter", map[string]any{"temperature": i + j})
+				assert.Nil(t, err)
+				assert.Equal(t, i+j > 25, out)
+			}
+		}(i)
+	}
+	wg.Wait()
+	assert.True(t, pool.Len() <= 8)
+}
+
+// benchmarkNodes is the number of nodes sharing the same script in the benchmarks
+const benchmarkNodes = 50
+
+// newPerNodePool creates a per-node sync.Pool of VMs, as the JavaScript nodes did before the shared pool
+func newPerNodePool(program *goja.Program) *sync.Pool {
+	return &sync.Pool{
+		New: func() any {
+			vm := goja.New()
+			if _, err := vm.RunProgram(program); err != nil {
+				panic(err)
+			}
+			return vm
+		},
+	}
+}
+
+func BenchmarkPerNodeVMPool(b *testing.B) {
+	base := heapAlloc()
+	var pools []*sync.Pool
+	for i := 0; i < benchmarkNodes; i++ {
+		program, err := goja.Compile("jsFilter.js", testScript, true)
+		if err != nil {
+			b.Fatal(err)
+		}
+		pools = append(pools, newPerNodePool(program))
+	}
+	input := map[string]any{"temperature": 30}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pool := pools[i%benchmarkNodes]
+		vm := pool.Get().(*goja.Runtime)
+		f, _ := goja.AssertFunction(vm.Get("jsFilter"))
+		if _, err := f(goja.Undefined(), vm.ToValue(input)); err != nil {
+			b.Fatal(err)
+		}
+		pool.Put(vm)
+	}
+	b.StopTimer()
+	b.ReportMetric(float64(heapAlloc()-base)/1024, "heap-KB")
+	runtime.KeepAlive(pools)
+}
+
+func BenchmarkSharedVMPool(b *testing.B) {
+	base := heapAlloc()
+	pool := NewVMPool(DefaultMaxIdleVMs)
+	for i := 0; i < benchmarkNodes; i++ {
+		if err := pool.Compile("jsFilter.js", testScript); err != nil {
+			b.Fatal(err)
+		}
+	}
+	input := map[string]any{"temperature": 30}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := pool.Call(context.Background(), testScript, "jsFilter", input); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.StopTimer()
+	b.ReportMetric(float64(heapAlloc()-base)/1024, "heap-KB")
+	runtime.KeepAlive(pool)
+}
+
+// heapAlloc returns the live heap after a single GC, which keeps the VMs of the sync.Pool victim cache
+func heapAlloc() int64 {
+	runtime.GC()
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return int64(stats.HeapAlloc)
+}
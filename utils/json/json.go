@@ -31,6 +31,30 @@ import (
 	"encoding/json"
 )
 
+// Codec is the encoding/json based codec used by default, see types.JSONCodec.
+// It marshals without HTML escaping and formats with two space indentation.
+// Codec 是默认使用的基于 encoding/json 的编解码器，参见 types.JSONCodec。序列化时不转义 HTML，格式化时使用两个空格缩进。
+type Codec struct{}
+
+// DefaultCodec is the codec used when no types.JSONCodec is configured.
+// DefaultCodec 是未配置 types.JSONCodec 时使用的编解码器。
+var DefaultCodec = Codec{}
+
+// Marshal calls Marshal.
+func (Codec) Marshal(v interface{}) ([]byte, error) {
+	return Marshal(v)
+}
+
+// Unmarshal calls Unmarshal.
+func (Codec) Unmarshal(b []byte, v interface{}) error {
+	return Unmarshal(b, v)
+}
+
+// Format calls Format.
+func (Codec) Format(b []byte) ([]byte, error) {
+	return Format(b)
+}
+
 // Marshal marshals the struct to json data.
 // escapeHTML=false
 // disables this behavior.escape &, <, and > to \u0026, \u003c, and \u003e
@@ -56,14 +56,32 @@
 package maps
 
 import (
+	"errors"
+	"reflect"
 	"strings"
 
 	"github.com/fatih/structs"
 	"github.com/mitchellh/mapstructure"
 )
 
+// DecodeError is returned by Map2Struct when values of the input cannot be decoded into the output,
+// e.g. a string where an int is expected. The fields are named by their json tag when they have one,
+// so they match the keys of a node configuration.
+type DecodeError struct {
+	// Fields are the paths of the fields failing to decode, e.g. timeout or headers.auth
+	Fields []string
+	// Errors describe the failures, one per field,
+	// e.g. 'timeout' expected type 'int', got unconvertible type 'map[string]interface {}'
+	Errors []string
+}
+
+func (e *DecodeError) Error() string {
+	return strings.Join(e.Errors, "; ")
+}
+
 // Map2Struct Decode takes an input structure and uses reflection to translate it to
 // the output structure. output must be a pointer to a map or struct.
+// Values that cannot be decoded return a *DecodeError.
 func Map2Struct(input any, output any) error {
 	cfg := &mapstructure.DecoderConfig{
 		DecodeHook: mapstructure.ComposeDecodeHookFunc(
@@ -77,13 +95,63 @@ func Map2Struct(input any, output any) error {
 	if d, err := mapstructure.NewDecoder(cfg); err != nil {
 		return err
 	} else if err := d.Decode(input); err != nil {
+		var decodeErr *mapstructure.Error
+		if errors.As(err, &decodeErr) {
+			return newDecodeError(reflect.TypeOf(output), decodeErr.Errors)
+		}
 		return err
 	}
 	return nil
 }
 
+// newDecodeError creates the DecodeError of the mapstructure errors decoding into output,
+// naming the fields by their json tag
+func newDecodeError(output reflect.Type, errs []string) *DecodeError {
+	e := &DecodeError{Fields: make([]string, len(errs)), Errors: make([]string, len(errs))}
+	for i, msg := range errs {
+		// mapstructure quotes the path of the field first, e.g. cannot parse 'Timeout' as int
+		path := msg
+		if _, rest, ok := strings.Cut(msg, "'"); ok {
+			path, _, _ = strings.Cut(rest, "'")
+		}
+		e.Fields[i] = jsonPath(output, path)
+		e.Errors[i] = strings.Replace(msg, "'"+path+"'", "'"+e.Fields[i]+"'", 1)
+	}
+	return e
+}
+
+// jsonPath translates a path of struct field names of t, e.g. Headers[0].Name, into their json names
+func jsonPath(t reflect.Type, path string) string {
+	parts := strings.Split(path, ".")
+	for i, part := range parts {
+		for t != nil && (t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map) {
+			t = t.Elem()
+		}
+		if t == nil || t.Kind() != reflect.Struct {
+			break
+		}
+		name, index, indexed := strings.Cut(part, "[")
+		field, ok := t.FieldByName(name)
+		if !ok {
+			break
+		}
+		if tag, _, _ := strings.Cut(field.Tag.Get("json"), ","); tag != "" && tag != "-" {
+			parts[i] = tag
+			if indexed {
+				parts[i] += "[" + index
+			}
+		}
+		t = field.Type
+	}
+	return strings.Join(parts, ".")
+}
+
+// Struct2Map converts a struct to a map keyed by field names.
+// output must be a *map[string]any, other types are ignored.
 func Struct2Map(input any, output any) {
-	output = structs.Map(input)
+	if m, ok := output.(*map[string]any); ok && m != nil {
+		*m = structs.Map(input)
+	}
 }
 
 // Get 获取map中的字段，支持嵌套结构获取，例如fieldName.subFieldName.xx
@@ -17,7 +17,9 @@
 package maps
 
 import (
+	"errors"
 	"github.com/rulego/rulego/test/assert"
+	"strings"
 	"testing"
 	"time"
 )
@@ -85,6 +87,45 @@ func TestMap2Struct(t *testing.T) {
 	assert.NotNil(t, err)
 }
 
+// TestMap2StructDecodeError 测试类型不兼容时返回的DecodeError
+func TestMap2StructDecodeError(t *testing.T) {
+	type Header struct {
+		Name string `json:"name"`
+	}
+	type Config struct {
+		Timeout int      `json:"timeout"`
+		Headers []Header `json:"headers"`
+		Retry   bool
+	}
+	var cfg Config
+	err := Map2Struct(map[string]any{
+		"timeout": map[string]any{},
+		"headers": []any{map[string]any{"name": []any{"a", "b"}}},
+	}, &cfg)
+	var decodeErr *DecodeError
+	assert.True(t, errors.As(err, &decodeErr))
+	assert.Equal(t, 2, len(decodeErr.Fields))
+	assert.True(t, strings.Contains(err.Error(), "'timeout' expected type 'int'"))
+	assert.True(t, strings.Contains(err.Error(), "'headers[0].name'"))
+
+	err = Map2Struct(map[string]any{"Retry": "maybe"}, &cfg)
+	assert.True(t, errors.As(err, &decodeErr))
+	assert.Equal(t, []string{"Retry"}, decodeErr.Fields)
+}
+
+// TestStruct2Map 测试Struct2Map函数
+func TestStruct2Map(t *testing.T) {
+	var m map[string]any
+	Struct2Map(User{Username: "lala", Age: 5}, &m)
+	assert.Equal(t, "lala", m["Username"])
+	assert.Equal(t, 5, m["Age"])
+
+	// Test with non-pointer output
+	var ignored map[string]any
+	Struct2Map(User{Username: "lala"}, ignored)
+	assert.Nil(t, ignored)
+}
+
 // TestGet 测试Get函数
 func TestGet(t *testing.T) {
 	// 定义一个map，包含嵌套结构
@@ -0,0 +1,123 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package pb converts protobuf message fields to the plain Go values used by scripts,
+// one field at a time or the whole message.
+//
+// Package pb 将 protobuf 消息字段转换为脚本使用的普通 Go 值，可以逐个字段转换，也可以转换整个消息。
+//
+// Fields are keyed by their proto name. Messages become map[string]any, repeated fields []any,
+// map fields map[string]any and enums their value name. A field with presence that is not set
+// (message fields, oneof members, proto3 optional) is absent.
+//
+// 字段以 proto 名称为键。消息转换为 map[string]any，repeated 字段转换为 []any，map 字段转换为
+// map[string]any，枚举转换为枚举值名称。未设置的有存在性语义的字段（消息字段、oneof 成员、proto3 optional）不存在。
+package pb
+
+import (
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ToMap converts the whole message to a map.
+// ToMap 将整个消息转换为映射。
+func ToMap(m proto.Message) map[string]any {
+	if m == nil {
+		return nil
+	}
+	return messageMap(m.ProtoReflect())
+}
+
+// Field returns the value of the field name of the message, converting only that field.
+// Field 返回消息中名为 name 的字段的值，只转换该字段。
+func Field(m proto.Message, name string) (any, bool) {
+	if m == nil {
+		return nil, false
+	}
+	rm := m.ProtoReflect()
+	fd := rm.Descriptor().Fields().ByName(protoreflect.Name(name))
+	if fd == nil || (fd.HasPresence() && !rm.Has(fd)) {
+		return nil, false
+	}
+	return value(fd, rm.Get(fd)), true
+}
+
+// FieldNames returns the names of the fields present in the message.
+// FieldNames 返回消息中存在的字段名称。
+func FieldNames(m proto.Message) []string {
+	if m == nil {
+		return nil
+	}
+	rm := m.ProtoReflect()
+	fields := rm.Descriptor().Fields()
+	names := make([]string, 0, fields.Len())
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if fd.HasPresence() && !rm.Has(fd) {
+			continue
+		}
+		names = append(names, string(fd.Name()))
+	}
+	return names
+}
+
+func messageMap(m protoreflect.Message) map[string]any {
+	fields := m.Descriptor().Fields()
+	values := make(map[string]any, fields.Len())
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if fd.HasPresence() && !m.Has(fd) {
+			continue
+		}
+		values[string(fd.Name())] = value(fd, m.Get(fd))
+	}
+	return values
+}
+
+func value(fd protoreflect.FieldDescriptor, v protoreflect.Value) any {
+	switch {
+	case fd.IsList():
+		list := v.List()
+		values := make([]any, list.Len())
+		for i := range values {
+			values[i] = singular(fd, list.Get(i))
+		}
+		return values
+	case fd.IsMap():
+		values := make(map[string]any, v.Map().Len())
+		v.Map().Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+			values[k.String()] = singular(fd.MapValue(), v)
+			return true
+		})
+		return values
+	default:
+		return singular(fd, v)
+	}
+}
+
+func singular(fd protoreflect.FieldDescriptor, v protoreflect.Value) any {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return messageMap(v.Message())
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return string(ev.Name())
+		}
+		return int32(v.Enum())
+	default:
+		return v.Interface()
+	}
+}
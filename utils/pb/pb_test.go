@@ -0,0 +1,94 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pb
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/rulego/rulego/test/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func testMessage() *descriptorpb.DescriptorProto {
+	msg := &descriptorpb.DescriptorProto{Name: proto.String("Order")}
+	for i := 0; i < 20; i++ {
+		msg.Field = append(msg.Field, &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(fmt.Sprintf("field%d", i)),
+			Number:   proto.Int32(int32(i + 1)),
+			Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+			JsonName: proto.String(fmt.Sprintf("field%d", i)),
+		})
+	}
+	msg.ReservedName = []string{"a", "b"}
+	return msg
+}
+
+func TestToMap(t *testing.T) {
+	values := ToMap(testMessage())
+	assert.Equal(t, "Order", values["name"])
+	assert.Equal(t, []any{"a", "b"}, values["reserved_name"])
+	fields := values["field"].([]any)
+	assert.Equal(t, 20, len(fields))
+	field := fields[1].(map[string]any)
+	assert.Equal(t, "field1", field["name"])
+	assert.Equal(t, int32(2), field["number"])
+	assert.Equal(t, "TYPE_STRING", field["type"])
+	_, ok := field["type_name"]
+	assert.False(t, ok)
+	_, ok = values["options"]
+	assert.False(t, ok)
+	assert.Nil(t, ToMap(nil))
+}
+
+func TestField(t *testing.T) {
+	msg := testMessage()
+	v, ok := Field(msg, "name")
+	assert.True(t, ok)
+	assert.Equal(t, "Order", v)
+	_, ok = Field(msg, "options")
+	assert.False(t, ok)
+	_, ok = Field(msg, "notExist")
+	assert.False(t, ok)
+	names := FieldNames(msg)
+	assert.Equal(t, 9, len(names))
+	assert.Equal(t, "name", names[0])
+}
+
+// BenchmarkToMap converts the whole message, as needed by map based nodes.
+func BenchmarkToMap(b *testing.B) {
+	msg := testMessage()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		values := ToMap(msg)
+		if values["name"] != "Order" {
+			b.Fatal(values["name"])
+		}
+	}
+}
+
+// BenchmarkField converts only the field read by a script, as done for expr and js nodes.
+func BenchmarkField(b *testing.B) {
+	msg := testMessage()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if v, _ := Field(msg, "name"); v != "Order" {
+			b.Fatal(v)
+		}
+	}
+}